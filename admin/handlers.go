@@ -0,0 +1,538 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/util/response"
+	"github.com/RichardKnop/uuid"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// defaultClientsPerPage is used when the limit is not set
+	defaultClientsPerPage = 25
+	// maxClientsPerPage is the highest limit
+	maxClientsPerPage = 100
+)
+
+var (
+	errStatusCodeMap = map[error]int{
+		oauth.ErrClientNotFound:           http.StatusNotFound,
+		oauth.ErrClientIDTaken:            http.StatusConflict,
+		oauth.ErrInvalidClientMetadata:    http.StatusBadRequest,
+		oauth.ErrInvalidClientRedirectURI: http.StatusBadRequest,
+		oauth.ErrTooManyRedirectURIs:      http.StatusBadRequest,
+		oauth.ErrInvalidScope:             http.StatusBadRequest,
+		oauth.ErrNoSecondaryClientSecret:  http.StatusConflict,
+		oauth.ErrInvalidRateLimit:         http.StatusBadRequest,
+		oauth.ErrUserNotFound:             http.StatusNotFound,
+		oauth.ErrRoleNotFound:             http.StatusNotFound,
+		oauth.ErrInvalidUserAttributeName: http.StatusBadRequest,
+	}
+)
+
+// listClients returns a page of clients
+// (GET /v1/admin/clients?page=1&limit=25)
+func (s *Service) listClients(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePositiveInt(r.URL.Query().Get("page"), 1)
+	if err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit, err := parsePositiveInt(r.URL.Query().Get("limit"), defaultClientsPerPage)
+	if err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if limit > maxClientsPerPage {
+		limit = maxClientsPerPage
+	}
+
+	clients, count, err := s.oauthService.ListClients((page-1)*limit, limit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	clientsURL := r.URL.Path
+	items := make([]*ClientResponse, len(clients))
+	for i, client := range clients {
+		items[i] = NewClientResponse(client, clientURL(clientsURL, client.Key))
+	}
+
+	// Link the neighbouring pages
+	lastPage := (count + limit - 1) / limit
+	if lastPage < 1 {
+		lastPage = 1
+	}
+	pageURL := func(page int) string {
+		return fmt.Sprintf("%s?page=%d&limit=%d", clientsURL, page, limit)
+	}
+	var previous, next string
+	if page > 1 {
+		previous = pageURL(page - 1)
+	}
+	if page < lastPage {
+		next = pageURL(page + 1)
+	}
+
+	response.WriteJSON(w, response.NewListResponse(
+		count,
+		page,
+		pageURL(page),
+		pageURL(1),
+		pageURL(lastPage),
+		previous,
+		next,
+		clientsResource,
+		items,
+	), 200)
+}
+
+// createClient creates a client with a generated secret
+// (POST /v1/admin/clients)
+func (s *Service) createClient(w http.ResponseWriter, r *http.Request) {
+	clientRequest := new(ClientRequest)
+	if err := json.NewDecoder(r.Body).Decode(clientRequest); err != nil {
+		writeError(w, oauth.ErrInvalidClientMetadata)
+		return
+	}
+	if clientRequest.ClientID == "" {
+		clientRequest.ClientID = uuid.New()
+	}
+
+	client, secret, err := s.oauthService.CreateClientWithMetadata(clientRequest.ClientID, &clientRequest.ClientMetadata)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp := NewClientResponse(client, clientURL(r.URL.Path, client.Key))
+	resp.ClientSecret = secret
+	response.WriteJSON(w, resp, http.StatusCreated)
+}
+
+// getClient returns a client
+// (GET /v1/admin/clients/{client_id})
+func (s *Service) getClient(w http.ResponseWriter, r *http.Request) {
+	client, err := s.oauthService.GetClient(mux.Vars(r)["client_id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, NewClientResponse(client, clientURLOf(r, client.Key)), 200)
+}
+
+// updateClient replaces the metadata of a client
+// (PUT /v1/admin/clients/{client_id})
+func (s *Service) updateClient(w http.ResponseWriter, r *http.Request) {
+	client, err := s.oauthService.GetClient(mux.Vars(r)["client_id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	metadata := new(oauth.ClientMetadata)
+	if err := json.NewDecoder(r.Body).Decode(metadata); err != nil {
+		writeError(w, oauth.ErrInvalidClientMetadata)
+		return
+	}
+	if err := s.oauthService.UpdateClient(client, metadata); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, NewClientResponse(client, clientURLOf(r, client.Key)), 200)
+}
+
+// deleteClient deletes a client and revokes its tokens
+// (DELETE /v1/admin/clients/{client_id})
+func (s *Service) deleteClient(w http.ResponseWriter, r *http.Request) {
+	client, err := s.oauthService.GetClient(mux.Vars(r)["client_id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if err := s.oauthService.DeleteClient(client); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// getClientScopes returns the scopes granted to a client
+// (GET /v1/admin/clients/{client_id}/scopes)
+func (s *Service) getClientScopes(w http.ResponseWriter, r *http.Request) {
+	client, err := s.oauthService.GetClient(mux.Vars(r)["client_id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	scopes, err := s.oauthService.GetClientScopes(client)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, NewClientScopesResponse(scopes, r.URL.Path, clientURLOf(r, client.Key)), 200)
+}
+
+// setClientScopes replaces the scopes granted to a client
+// (PUT /v1/admin/clients/{client_id}/scopes)
+func (s *Service) setClientScopes(w http.ResponseWriter, r *http.Request) {
+	client, err := s.oauthService.GetClient(mux.Vars(r)["client_id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	scopesRequest := new(ClientScopesRequest)
+	if err := json.NewDecoder(r.Body).Decode(scopesRequest); err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.oauthService.SetClientScopes(client, scopesRequest.Scopes); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, NewClientScopesResponse(scopesRequest.Scopes, r.URL.Path, clientURLOf(r, client.Key)), 200)
+}
+
+// rotateClientSecret issues a new secret to a client
+// (POST /v1/admin/clients/{client_id}/secret)
+func (s *Service) rotateClientSecret(w http.ResponseWriter, r *http.Request) {
+	client, err := s.oauthService.GetClient(mux.Vars(r)["client_id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	secret, err := s.oauthService.RotateClientSecret(client)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp := NewClientResponse(client, clientURLOf(r, client.Key))
+	resp.ClientSecret = secret
+	response.WriteJSON(w, resp, 200)
+}
+
+// addSecondaryClientSecret issues a secondary secret to a client,
+// which it can authenticate with alongside its secret
+// (POST /v1/admin/clients/{client_id}/secondary-secret)
+func (s *Service) addSecondaryClientSecret(w http.ResponseWriter, r *http.Request) {
+	client, err := s.oauthService.GetClient(mux.Vars(r)["client_id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	secret, err := s.oauthService.AddSecondaryClientSecret(client)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp := NewClientResponse(client, clientURLOf(r, client.Key))
+	resp.SecondaryClientSecret = secret
+	response.WriteJSON(w, resp, 200)
+}
+
+// promoteSecondaryClientSecret makes the secondary secret of a client its
+// secret, the old one keeps working for the grace period in seconds
+// (POST /v1/admin/clients/{client_id}/secondary-secret/promote)
+func (s *Service) promoteSecondaryClientSecret(w http.ResponseWriter, r *http.Request) {
+	client, err := s.oauthService.GetClient(mux.Vars(r)["client_id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	promoteRequest := new(PromoteSecretRequest)
+	if err := json.NewDecoder(r.Body).Decode(promoteRequest); err != nil && err != io.EOF {
+		response.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if promoteRequest.GracePeriod < 0 {
+		response.Error(w, fmt.Sprintf("Invalid grace period %d", promoteRequest.GracePeriod), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.oauthService.PromoteSecondaryClientSecret(client, promoteRequest.GracePeriod); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, NewClientResponse(client, clientURLOf(r, client.Key)), 200)
+}
+
+// expireSecondaryClientSecret stops the secondary secret of a client working
+// (DELETE /v1/admin/clients/{client_id}/secondary-secret)
+func (s *Service) expireSecondaryClientSecret(w http.ResponseWriter, r *http.Request) {
+	client, err := s.oauthService.GetClient(mux.Vars(r)["client_id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if err := s.oauthService.ExpireSecondaryClientSecret(client); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// setClientRateLimit caps the requests per minute a client can send to the
+// token endpoint (PUT /v1/admin/clients/{client_id}/rate-limit)
+func (s *Service) setClientRateLimit(w http.ResponseWriter, r *http.Request) {
+	client, err := s.oauthService.GetClient(mux.Vars(r)["client_id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	rateLimitRequest := new(RateLimitRequest)
+	if err := json.NewDecoder(r.Body).Decode(rateLimitRequest); err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.oauthService.SetClientTokenRateLimit(client, rateLimitRequest.TokenRequestsPerMinute); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, NewClientResponse(client, clientURLOf(r, client.Key)), 200)
+}
+
+// disableClient disables a client and revokes its tokens
+// (POST /v1/admin/clients/{client_id}/disable)
+func (s *Service) disableClient(w http.ResponseWriter, r *http.Request) {
+	s.setClientDisabled(w, r, true)
+}
+
+// enableClient enables a disabled client again
+// (POST /v1/admin/clients/{client_id}/enable)
+func (s *Service) enableClient(w http.ResponseWriter, r *http.Request) {
+	s.setClientDisabled(w, r, false)
+}
+
+func (s *Service) setClientDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	client, err := s.oauthService.GetClient(mux.Vars(r)["client_id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if err := s.oauthService.SetClientDisabled(client, disabled); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, NewClientResponse(client, clientURLOf(r, client.Key)), 200)
+}
+
+// unlockUser lets a user locked out after too many failed logins
+// log in again (POST /v1/admin/users/{user_id}/unlock)
+func (s *Service) unlockUser(w http.ResponseWriter, r *http.Request) {
+	user, err := s.oauthService.FindUserByID(mux.Vars(r)["user_id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if err := s.oauthService.UnlockUser(user); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// getUserRoles returns the roles of a user
+// (GET /v1/admin/users/{user_id}/roles)
+func (s *Service) getUserRoles(w http.ResponseWriter, r *http.Request) {
+	user, err := s.oauthService.FindUserByID(mux.Vars(r)["user_id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	roleIDs, err := s.oauthService.GetUserRoles(user)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, NewUserRolesResponse(roleIDs, r.URL.Path), 200)
+}
+
+// setUserRoles replaces the roles a user has besides the role of the user
+// (PUT /v1/admin/users/{user_id}/roles)
+func (s *Service) setUserRoles(w http.ResponseWriter, r *http.Request) {
+	user, err := s.oauthService.FindUserByID(mux.Vars(r)["user_id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	rolesRequest := new(UserRolesRequest)
+	if err := json.NewDecoder(r.Body).Decode(rolesRequest); err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = s.oauthService.SetUserRoles(user, rolesRequest.Roles)
+	if err == oauth.ErrRoleNotFound {
+		// The user was found, it is a role of the request which was not
+		response.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	roleIDs, err := s.oauthService.GetUserRoles(user)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, NewUserRolesResponse(roleIDs, r.URL.Path), 200)
+}
+
+// getUserAttributes returns the custom attributes of a user
+// (GET /v1/admin/users/{user_id}/attributes)
+func (s *Service) getUserAttributes(w http.ResponseWriter, r *http.Request) {
+	user, err := s.oauthService.FindUserByID(mux.Vars(r)["user_id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	attributes, err := s.oauthService.GetUserAttributes(user)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, NewUserAttributesResponse(attributes, r.URL.Path), 200)
+}
+
+// setUserAttributes replaces the custom attributes of a user
+// (PUT /v1/admin/users/{user_id}/attributes)
+func (s *Service) setUserAttributes(w http.ResponseWriter, r *http.Request) {
+	user, err := s.oauthService.FindUserByID(mux.Vars(r)["user_id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	attributesRequest := new(UserAttributesRequest)
+	if err := json.NewDecoder(r.Body).Decode(attributesRequest); err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.oauthService.SetUserAttributes(user, attributesRequest.Attributes); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	attributes, err := s.oauthService.GetUserAttributes(user)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, NewUserAttributesResponse(attributes, r.URL.Path), 200)
+}
+
+// getRoleScopes returns the scopes a role is mapped to
+// (GET /v1/admin/roles/{role_id}/scopes)
+func (s *Service) getRoleScopes(w http.ResponseWriter, r *http.Request) {
+	role, err := s.oauthService.FindRoleByID(mux.Vars(r)["role_id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	scopes, err := s.oauthService.GetRoleScopes(role)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, NewRoleScopesResponse(scopes, r.URL.Path), 200)
+}
+
+// setRoleScopes replaces the scopes a role is mapped to
+// (PUT /v1/admin/roles/{role_id}/scopes)
+func (s *Service) setRoleScopes(w http.ResponseWriter, r *http.Request) {
+	role, err := s.oauthService.FindRoleByID(mux.Vars(r)["role_id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	scopesRequest := new(RoleScopesRequest)
+	if err := json.NewDecoder(r.Body).Decode(scopesRequest); err != nil {
+		response.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.oauthService.SetRoleScopes(role, scopesRequest.Scopes); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, NewRoleScopesResponse(scopesRequest.Scopes, r.URL.Path), 200)
+}
+
+// purgeTokens deletes the tokens which expired more than the retention
+// period ago straight away (POST /v1/admin/tokens/purge)
+func (s *Service) purgeTokens(w http.ResponseWriter, r *http.Request) {
+	purged, err := s.oauthService.PurgeExpiredTokens()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response.WriteJSON(w, NewPurgeTokensResponse(purged, r.URL.Path), 200)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	code, ok := errStatusCodeMap[err]
+	if !ok {
+		code = http.StatusInternalServerError
+	}
+	response.Error(w, err.Error(), code)
+}
+
+// parsePositiveInt parses a query string parameter, which defaults to def
+func parsePositiveInt(value string, def int) (int, error) {
+	if value == "" {
+		return def, nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil || i < 1 {
+		return 0, fmt.Errorf("Invalid number %s", value)
+	}
+	return i, nil
+}
+
+// clientURLOf returns the URL of the client of a request to one of its resources
+func clientURLOf(r *http.Request, clientID string) string {
+	path := r.URL.Path
+	return clientURL(path[:strings.Index(path, clientsPath)+len(clientsPath)], clientID)
+}
@@ -0,0 +1,324 @@
+package admin
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/mocks"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var testClient = &models.OauthClient{
+	Key:               "test_client",
+	AllowedGrantTypes: "client_credentials",
+	Scope:             "read",
+}
+
+// newTestRouter returns a router serving the admin routes to
+// requests authenticated with the access token of a user of the role,
+// granted the admin scope by a client granted the admin scope
+func newTestRouter(role string) (*mux.Router, *mocks.ServiceInterface) {
+	return newTestRouterWithScopes(role, "admin", []string{"admin"})
+}
+
+// newTestRouterWithScopes returns a router serving the admin routes to
+// requests authenticated with the access token of a user of the role,
+// granted the scope by a client granted the client scopes
+func newTestRouterWithScopes(role, scope string, clientScopes []string) (*mux.Router, *mocks.ServiceInterface) {
+	cnf := &config.Config{}
+	oauthService := new(mocks.ServiceInterface)
+	oauthService.On("GetConfig").Return(cnf)
+	oauthService.On("Authenticate", "test_token").Return(&models.OauthAccessToken{
+		ClientID: util.StringOrNull("test_client"),
+		UserID:   sql.NullString{String: "test_user", Valid: true},
+		Scope:    scope,
+	}, nil)
+	oauthService.On("FindUserByID", "test_user").Return(&models.OauthUser{
+		RoleID: sql.NullString{String: role, Valid: true},
+	}, nil)
+	oauthService.On("GetClientScopes", mock.MatchedBy(func(client *models.OauthClient) bool {
+		return client.ID == "test_client"
+	})).Return(clientScopes, nil)
+
+	router := mux.NewRouter()
+	NewService(cnf, oauthService).RegisterRoutes(router, "/v1/admin")
+	return router, oauthService
+}
+
+func serve(router *mux.Router, method, url, body string) *httptest.ResponseRecorder {
+	r, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+	r.Header.Set("Authorization", "Bearer test_token")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+func TestSuperuserRequired(t *testing.T) {
+	router, _ := newTestRouter(roles.User)
+
+	w := serve(router, "GET", "http://1.2.3.4/v1/admin/clients", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestAdminScopeRequired(t *testing.T) {
+	// A superuser token without the admin scope is refused
+	router, _ := newTestRouterWithScopes(roles.Superuser, "read", []string{"admin"})
+	w := serve(router, "GET", "http://1.2.3.4/v1/admin/clients", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), ErrScopeRequired.Error())
+
+	// The scope must also have been granted to the client by an admin,
+	// a client registered for it is not enough
+	router, _ = newTestRouterWithScopes(roles.Superuser, "admin", []string{"read"})
+	w = serve(router, "GET", "http://1.2.3.4/v1/admin/clients", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), ErrClientScopeRequired.Error())
+}
+
+func TestListClients(t *testing.T) {
+	router, oauthService := newTestRouter(roles.Superuser)
+	oauthService.On("ListClients", 2, 2).Return([]*models.OauthClient{testClient}, 3, nil)
+
+	w := serve(router, "GET", "http://1.2.3.4/v1/admin/clients?page=2&limit=2", "")
+	if !assert.Equal(t, http.StatusOK, w.Code) {
+		return
+	}
+	var resp struct {
+		Count    int                              `json:"count"`
+		Page     int                              `json:"page"`
+		Links    map[string]struct{ Href string } `json:"_links"`
+		Embedded struct {
+			Clients []*ClientResponse `json:"clients"`
+		} `json:"_embedded"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 3, resp.Count)
+	assert.Equal(t, 2, resp.Page)
+	assert.Equal(t, "/v1/admin/clients?page=1&limit=2", resp.Links["prev"].Href)
+	assert.Equal(t, "/v1/admin/clients?page=2&limit=2", resp.Links["last"].Href)
+	assert.Empty(t, resp.Links["next"].Href)
+	if assert.Len(t, resp.Embedded.Clients, 1) {
+		assert.Equal(t, "test_client", resp.Embedded.Clients[0].ClientID)
+		assert.Equal(t, []string{"client_credentials"}, resp.Embedded.Clients[0].GrantTypes)
+	}
+}
+
+func TestCreateClient(t *testing.T) {
+	router, oauthService := newTestRouter(roles.Superuser)
+	oauthService.On("CreateClientWithMetadata", "test_client", &oauth.ClientMetadata{
+		GrantTypes: []string{"client_credentials"},
+		Scope:      "read",
+	}).Return(testClient, "test_secret", nil)
+
+	w := serve(router, "POST", "http://1.2.3.4/v1/admin/clients", `{
+		"client_id": "test_client",
+		"grant_types": ["client_credentials"],
+		"scope": "read"
+	}`)
+	if assert.Equal(t, http.StatusCreated, w.Code) {
+		resp := new(ClientResponse)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+		assert.Equal(t, "test_secret", resp.ClientSecret)
+		assert.Equal(t, "/v1/admin/clients/test_client", resp.Links["self"].Href)
+	}
+}
+
+func TestCreateClientFails(t *testing.T) {
+	router, oauthService := newTestRouter(roles.Superuser)
+	oauthService.On("CreateClientWithMetadata", "test_client", mock.Anything).
+		Return(nil, "", oauth.ErrClientIDTaken)
+
+	w := serve(router, "POST", "http://1.2.3.4/v1/admin/clients", `{"client_id": "test_client"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = serve(router, "POST", "http://1.2.3.4/v1/admin/clients", `not json`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestManageClient(t *testing.T) {
+	router, oauthService := newTestRouter(roles.Superuser)
+	oauthService.On("GetClient", "test_client").Return(testClient, nil)
+	oauthService.On("GetClient", "bogus").Return(nil, oauth.ErrClientNotFound)
+	oauthService.On("RotateClientSecret", testClient).Return("new_secret", nil)
+	oauthService.On("SetClientDisabled", testClient, true).Return(nil)
+	oauthService.On("DeleteClient", testClient).Return(nil)
+
+	w := serve(router, "GET", "http://1.2.3.4/v1/admin/clients/bogus", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serve(router, "POST", "http://1.2.3.4/v1/admin/clients/test_client/secret", "")
+	if assert.Equal(t, http.StatusOK, w.Code) {
+		resp := new(ClientResponse)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+		assert.Equal(t, "new_secret", resp.ClientSecret)
+		assert.Equal(t, "/v1/admin/clients/test_client", resp.Links["self"].Href)
+	}
+
+	w = serve(router, "POST", "http://1.2.3.4/v1/admin/clients/test_client/disable", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	oauthService.AssertCalled(t, "SetClientDisabled", testClient, true)
+
+	w = serve(router, "DELETE", "http://1.2.3.4/v1/admin/clients/test_client", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	oauthService.AssertCalled(t, "DeleteClient", testClient)
+}
+
+func TestClientScopes(t *testing.T) {
+	router, oauthService := newTestRouter(roles.Superuser)
+	oauthService.On("GetClient", "test_client").Return(testClient, nil)
+	oauthService.On("GetClientScopes", testClient).Return([]string{"read"}, nil)
+	oauthService.On("SetClientScopes", testClient, []string{"bogus"}).Return(oauth.ErrInvalidScope)
+	oauthService.On("SetClientScopes", testClient, []string{"read_write"}).Return(nil)
+
+	w := serve(router, "GET", "http://1.2.3.4/v1/admin/clients/test_client/scopes", "")
+	if assert.Equal(t, http.StatusOK, w.Code) {
+		resp := new(ClientScopesResponse)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+		assert.Equal(t, []string{"read"}, resp.Scopes)
+		assert.Equal(t, "/v1/admin/clients/test_client", resp.Links["client"].Href)
+	}
+
+	w = serve(router, "PUT", "http://1.2.3.4/v1/admin/clients/test_client/scopes", `{"scopes": ["bogus"]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serve(router, "PUT", "http://1.2.3.4/v1/admin/clients/test_client/scopes", `{"scopes": ["read_write"]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	oauthService.AssertCalled(t, "SetClientScopes", testClient, []string{"read_write"})
+}
+
+func TestSecondaryClientSecret(t *testing.T) {
+	router, oauthService := newTestRouter(roles.Superuser)
+	oauthService.On("GetClient", "test_client").Return(testClient, nil)
+	oauthService.On("AddSecondaryClientSecret", testClient).Return("secondary_secret", nil)
+	oauthService.On("PromoteSecondaryClientSecret", testClient, 3600).Return(nil)
+	oauthService.On("ExpireSecondaryClientSecret", testClient).Return(oauth.ErrNoSecondaryClientSecret)
+
+	w := serve(router, "POST", "http://1.2.3.4/v1/admin/clients/test_client/secondary-secret", "")
+	if assert.Equal(t, http.StatusOK, w.Code) {
+		resp := new(ClientResponse)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+		assert.Equal(t, "secondary_secret", resp.SecondaryClientSecret)
+		assert.Empty(t, resp.ClientSecret)
+	}
+
+	w = serve(router, "POST", "http://1.2.3.4/v1/admin/clients/test_client/secondary-secret/promote", `{"grace_period": -1}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serve(router, "POST", "http://1.2.3.4/v1/admin/clients/test_client/secondary-secret/promote", `{"grace_period": 3600}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	oauthService.AssertCalled(t, "PromoteSecondaryClientSecret", testClient, 3600)
+
+	w = serve(router, "DELETE", "http://1.2.3.4/v1/admin/clients/test_client/secondary-secret", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestClientRateLimit(t *testing.T) {
+	router, oauthService := newTestRouter(roles.Superuser)
+	oauthService.On("GetClient", "test_client").Return(testClient, nil)
+	oauthService.On("SetClientTokenRateLimit", testClient, -1).Return(oauth.ErrInvalidRateLimit)
+	oauthService.On("SetClientTokenRateLimit", testClient, 60).Return(nil)
+
+	w := serve(router, "PUT", "http://1.2.3.4/v1/admin/clients/test_client/rate-limit", `{"token_requests_per_minute": -1}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serve(router, "PUT", "http://1.2.3.4/v1/admin/clients/test_client/rate-limit", `{"token_requests_per_minute": 60}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	oauthService.AssertCalled(t, "SetClientTokenRateLimit", testClient, 60)
+}
+
+func TestUnlockUser(t *testing.T) {
+	router, oauthService := newTestRouter(roles.Superuser)
+	lockedUser := &models.OauthUser{Username: "test@user"}
+	oauthService.On("FindUserByID", "locked_user").Return(lockedUser, nil)
+	oauthService.On("FindUserByID", "bogus").Return(nil, oauth.ErrUserNotFound)
+	oauthService.On("UnlockUser", lockedUser).Return(nil)
+
+	w := serve(router, "POST", "http://1.2.3.4/v1/admin/users/bogus/unlock", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serve(router, "POST", "http://1.2.3.4/v1/admin/users/locked_user/unlock", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	oauthService.AssertCalled(t, "UnlockUser", lockedUser)
+}
+
+func TestRoles(t *testing.T) {
+	router, oauthService := newTestRouter(roles.Superuser)
+	user := &models.OauthUser{Username: "test@user"}
+	role := &models.OauthRole{ID: "auditor"}
+	oauthService.On("FindUserByID", "test_user2").Return(user, nil)
+	oauthService.On("SetUserRoles", user, []string{"bogus"}).Return(oauth.ErrRoleNotFound)
+	oauthService.On("SetUserRoles", user, []string{"auditor"}).Return(nil)
+	oauthService.On("GetUserRoles", user).Return([]string{roles.User, "auditor"}, nil)
+	oauthService.On("FindRoleByID", "auditor").Return(role, nil)
+	oauthService.On("FindRoleByID", "bogus").Return(nil, oauth.ErrRoleNotFound)
+	oauthService.On("SetRoleScopes", role, []string{"read"}).Return(nil)
+
+	w := serve(router, "PUT", "http://1.2.3.4/v1/admin/users/test_user2/roles", `{"roles": ["bogus"]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serve(router, "PUT", "http://1.2.3.4/v1/admin/users/test_user2/roles", `{"roles": ["auditor"]}`)
+	if assert.Equal(t, http.StatusOK, w.Code) {
+		resp := new(UserRolesResponse)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+		assert.Equal(t, []string{roles.User, "auditor"}, resp.Roles)
+	}
+
+	w = serve(router, "PUT", "http://1.2.3.4/v1/admin/roles/bogus/scopes", `{"scopes": ["read"]}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serve(router, "PUT", "http://1.2.3.4/v1/admin/roles/auditor/scopes", `{"scopes": ["read"]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	oauthService.AssertCalled(t, "SetRoleScopes", role, []string{"read"})
+}
+
+func TestUserAttributes(t *testing.T) {
+	router, oauthService := newTestRouter(roles.Superuser)
+	user := &models.OauthUser{Username: "test@user"}
+	oauthService.On("FindUserByID", "test_user2").Return(user, nil)
+	oauthService.On("SetUserAttributes", user, map[string]interface{}{"": "bogus"}).
+		Return(oauth.ErrInvalidUserAttributeName)
+	oauthService.On("SetUserAttributes", user, map[string]interface{}{"department": "Engineering"}).
+		Return(nil)
+	oauthService.On("GetUserAttributes", user).
+		Return(map[string]interface{}{"department": "Engineering"}, nil)
+
+	w := serve(router, "PUT", "http://1.2.3.4/v1/admin/users/test_user2/attributes", `{"attributes": {"": "bogus"}}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serve(router, "PUT", "http://1.2.3.4/v1/admin/users/test_user2/attributes", `{"attributes": {"department": "Engineering"}}`)
+	if assert.Equal(t, http.StatusOK, w.Code) {
+		resp := new(UserAttributesResponse)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+		assert.Equal(t, map[string]interface{}{"department": "Engineering"}, resp.Attributes)
+	}
+}
+
+func TestPurgeTokens(t *testing.T) {
+	router, oauthService := newTestRouter(roles.Superuser)
+	oauthService.On("PurgeExpiredTokens").Return(int64(3), nil)
+
+	w := serve(router, "POST", "http://1.2.3.4/v1/admin/tokens/purge", "")
+	if assert.Equal(t, http.StatusOK, w.Code) {
+		resp := new(PurgeTokensResponse)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+		assert.Equal(t, int64(3), resp.Purged)
+	}
+	oauthService.AssertCalled(t, "PurgeExpiredTokens")
+
+	// Only superusers can purge tokens
+	router, oauthService = newTestRouter(roles.User)
+	w = serve(router, "POST", "http://1.2.3.4/v1/admin/tokens/purge", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	oauthService.AssertNotCalled(t, "PurgeExpiredTokens")
+}
@@ -0,0 +1,80 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/RichardKnop/go-oauth2-server/util/response"
+)
+
+const (
+	// adminScope must be granted to the operator's client by an admin
+	// and to its access token
+	adminScope = "admin"
+)
+
+var (
+	// ErrSuperuserRequired ...
+	ErrSuperuserRequired = errors.New("Superuser role required")
+	// ErrScopeRequired ...
+	ErrScopeRequired = errors.New("admin scope required")
+	// ErrClientScopeRequired ...
+	ErrClientScopeRequired = errors.New("admin scope must be granted to the client")
+)
+
+// superuserMiddleware only lets requests authenticated with an access
+// token of a superuser through, and only if the token was granted the
+// admin scope and an admin granted the scope to the token's client. A
+// superuser logging in to any other client cannot use the admin API.
+type superuserMiddleware struct {
+	service ServiceInterface
+}
+
+// newSuperuserMiddleware creates a new superuserMiddleware instance
+func newSuperuserMiddleware(service ServiceInterface) *superuserMiddleware {
+	return &superuserMiddleware{service: service}
+}
+
+// ServeHTTP as per the negroni.Handler interface
+func (m *superuserMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	// Get the authenticated access token
+	accessToken, err := oauth.GetAuthenticatedAccessToken(r)
+	if err != nil {
+		response.UnauthorizedError(w, err.Error())
+		return
+	}
+
+	// Client credentials tokens have no user
+	if !accessToken.UserID.Valid {
+		response.Error(w, ErrSuperuserRequired.Error(), http.StatusForbidden)
+		return
+	}
+	user, err := m.service.GetOauthService().FindUserByID(accessToken.UserID.String)
+	if err != nil || user.RoleID.String != roles.Superuser {
+		response.Error(w, ErrSuperuserRequired.Error(), http.StatusForbidden)
+		return
+	}
+
+	if !util.StringInSlice(adminScope, strings.Fields(accessToken.Scope)) {
+		response.InsufficientScopeError(w, ErrScopeRequired.Error())
+		return
+	}
+
+	client := &models.OauthClient{MyGormModel: models.MyGormModel{ID: accessToken.ClientID.String}}
+	granted, err := m.service.GetOauthService().GetClientScopes(client)
+	if err != nil {
+		response.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !util.StringInSlice(adminScope, granted) {
+		response.InsufficientScopeError(w, ErrClientScopeRequired.Error())
+		return
+	}
+
+	next(w, r)
+}
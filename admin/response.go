@@ -0,0 +1,195 @@
+package admin
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/jsonhal"
+)
+
+// ClientRequest creates a client, the client ID is generated when empty
+type ClientRequest struct {
+	ClientID string `json:"client_id,omitempty"`
+	oauth.ClientMetadata
+}
+
+// ClientResponse ...
+type ClientResponse struct {
+	jsonhal.Hal
+	ClientID              string `json:"client_id"`
+	ClientSecret          string `json:"client_secret,omitempty"`
+	SecondaryClientSecret string `json:"secondary_client_secret,omitempty"`
+	ClientType            string `json:"client_type"`
+	SubjectType           string `json:"subject_type"`
+	SectorIdentifierURI   string `json:"sector_identifier_uri,omitempty"`
+	oauth.ClientMetadata
+	Disabled   bool `json:"disabled"`
+	FirstParty bool `json:"first_party"`
+	// TokenRequestsPerMinute is zero when the client is not rate limited
+	TokenRequestsPerMinute int `json:"token_requests_per_minute"`
+	// SecondarySecretExpiresAt is empty when the client has no secondary
+	// secret or it does not expire
+	HasSecondarySecret       bool   `json:"has_secondary_secret"`
+	SecondarySecretExpiresAt string `json:"secondary_secret_expires_at,omitempty"`
+	CreatedAt                string `json:"created_at"`
+	UpdatedAt                string `json:"updated_at"`
+}
+
+// NewClientResponse creates new ClientResponse instance, the client secret
+// is only known when it is generated
+func NewClientResponse(client *models.OauthClient, self string) *ClientResponse {
+	response := &ClientResponse{
+		ClientID:            client.Key,
+		ClientType:          client.ClientType,
+		SubjectType:         client.SubjectType,
+		SectorIdentifierURI: client.SectorIdentifierURI,
+		ClientMetadata: oauth.ClientMetadata{
+			GrantTypes:              strings.Fields(client.AllowedGrantTypes),
+			Scope:                   client.Scope,
+			TokenEndpointAuthMethod: client.TokenEndpointAuthMethod,
+			ClientName:              client.Name.String,
+		},
+		Disabled:               client.Disabled,
+		FirstParty:             client.FirstParty,
+		TokenRequestsPerMinute: client.TokenRateLimit,
+		CreatedAt:              client.CreatedAt.UTC().Format(time.RFC3339),
+		UpdatedAt:              client.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+	if client.RedirectURI.Valid {
+		response.RedirectURIs = []string{client.RedirectURI.String}
+	}
+	if oauth.HasSecondaryClientSecret(client) {
+		response.HasSecondarySecret = true
+		if client.SecondarySecretExpiresAt != nil {
+			response.SecondarySecretExpiresAt = client.SecondarySecretExpiresAt.UTC().Format(time.RFC3339)
+		}
+	}
+
+	response.SetLink("self", self, "")
+
+	return response
+}
+
+// PromoteSecretRequest promotes the secondary secret of a client, the old
+// secret keeps working for the grace period in seconds
+type PromoteSecretRequest struct {
+	GracePeriod int `json:"grace_period"`
+}
+
+// RateLimitRequest caps the requests per minute a client can send
+// to the token endpoint, zero lifts the cap
+type RateLimitRequest struct {
+	TokenRequestsPerMinute int `json:"token_requests_per_minute"`
+}
+
+// ClientScopesRequest grants scopes to a client, no scopes
+// let the client be issued tokens for any scope again
+type ClientScopesRequest struct {
+	Scopes []string `json:"scopes"`
+}
+
+// ClientScopesResponse ...
+type ClientScopesResponse struct {
+	jsonhal.Hal
+	Scopes []string `json:"scopes"`
+}
+
+// NewClientScopesResponse creates new ClientScopesResponse instance
+func NewClientScopesResponse(scopes []string, self, client string) *ClientScopesResponse {
+	if scopes == nil {
+		scopes = []string{}
+	}
+	response := &ClientScopesResponse{Scopes: scopes}
+
+	response.SetLink("self", self, "")
+	response.SetLink("client", client, "")
+
+	return response
+}
+
+// RoleScopesRequest maps a role to scopes, no scopes let
+// users with the role be issued tokens for any scope again
+type RoleScopesRequest struct {
+	Scopes []string `json:"scopes"`
+}
+
+// RoleScopesResponse ...
+type RoleScopesResponse struct {
+	jsonhal.Hal
+	Scopes []string `json:"scopes"`
+}
+
+// NewRoleScopesResponse creates new RoleScopesResponse instance
+func NewRoleScopesResponse(scopes []string, self string) *RoleScopesResponse {
+	if scopes == nil {
+		scopes = []string{}
+	}
+	response := &RoleScopesResponse{Scopes: scopes}
+
+	response.SetLink("self", self, "")
+
+	return response
+}
+
+// UserRolesRequest gives a user roles besides the role of the user
+type UserRolesRequest struct {
+	Roles []string `json:"roles"`
+}
+
+// UserRolesResponse lists the role of a user first
+type UserRolesResponse struct {
+	jsonhal.Hal
+	Roles []string `json:"roles"`
+}
+
+// NewUserRolesResponse creates new UserRolesResponse instance
+func NewUserRolesResponse(roles []string, self string) *UserRolesResponse {
+	response := &UserRolesResponse{Roles: roles}
+
+	response.SetLink("self", self, "")
+
+	return response
+}
+
+// UserAttributesRequest replaces the custom attributes of a user
+type UserAttributesRequest struct {
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// UserAttributesResponse holds the custom attributes of a user
+type UserAttributesResponse struct {
+	jsonhal.Hal
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// NewUserAttributesResponse creates new UserAttributesResponse instance
+func NewUserAttributesResponse(attributes map[string]interface{}, self string) *UserAttributesResponse {
+	response := &UserAttributesResponse{Attributes: attributes}
+
+	response.SetLink("self", self, "")
+
+	return response
+}
+
+// PurgeTokensResponse tells how many expired tokens were deleted
+type PurgeTokensResponse struct {
+	jsonhal.Hal
+	Purged int64 `json:"purged"`
+}
+
+// NewPurgeTokensResponse creates new PurgeTokensResponse instance
+func NewPurgeTokensResponse(purged int64, self string) *PurgeTokensResponse {
+	response := &PurgeTokensResponse{Purged: purged}
+
+	response.SetLink("self", self, "")
+
+	return response
+}
+
+// clientURL returns the URL of a client under the clients resource
+func clientURL(clientsURL, clientID string) string {
+	return fmt.Sprintf("%s/%s", clientsURL, clientID)
+}
@@ -0,0 +1,196 @@
+package admin
+
+import (
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/util/response"
+	"github.com/RichardKnop/go-oauth2-server/util/routes"
+	"github.com/gorilla/mux"
+	"github.com/urfave/negroni"
+)
+
+const (
+	clientsResource = "clients"
+	clientsPath     = "/" + clientsResource
+	clientPath      = clientsPath + "/{client_id}"
+	usersResource   = "users"
+	usersPath       = "/" + usersResource
+	userPath        = usersPath + "/{user_id}"
+	rolesResource   = "roles"
+	rolesPath       = "/" + rolesResource
+	rolePath        = rolesPath + "/{role_id}"
+	tokensResource  = "tokens"
+	tokensPath      = "/" + tokensResource
+)
+
+// RegisterRoutes registers route handlers for the admin service
+func (s *Service) RegisterRoutes(router *mux.Router, prefix string) {
+	subRouter := router.PathPrefix(prefix).Subrouter()
+	routes.AddRoutes(s.GetRoutes(), subRouter)
+}
+
+// GetRoutes returns []routes.Route slice for the admin service,
+// all of them are restricted to superusers
+func (s *Service) GetRoutes() []routes.Route {
+	middlewares := []negroni.Handler{
+		response.NewSecureHeadersMiddleware(),
+		oauth.NewAuthenticationMiddleware(s.oauthService),
+		newSuperuserMiddleware(s),
+	}
+
+	return []routes.Route{
+		{
+			Name:        "admin_list_clients",
+			Method:      "GET",
+			Pattern:     clientsPath,
+			HandlerFunc: s.listClients,
+			Middlewares: middlewares,
+		},
+		{
+			Name:        "admin_create_client",
+			Method:      "POST",
+			Pattern:     clientsPath,
+			HandlerFunc: s.createClient,
+			Middlewares: middlewares,
+		},
+		{
+			Name:        "admin_get_client",
+			Method:      "GET",
+			Pattern:     clientPath,
+			HandlerFunc: s.getClient,
+			Middlewares: middlewares,
+		},
+		{
+			Name:        "admin_update_client",
+			Method:      "PUT",
+			Pattern:     clientPath,
+			HandlerFunc: s.updateClient,
+			Middlewares: middlewares,
+		},
+		{
+			Name:        "admin_delete_client",
+			Method:      "DELETE",
+			Pattern:     clientPath,
+			HandlerFunc: s.deleteClient,
+			Middlewares: middlewares,
+		},
+		{
+			Name:        "admin_get_client_scopes",
+			Method:      "GET",
+			Pattern:     clientPath + "/scopes",
+			HandlerFunc: s.getClientScopes,
+			Middlewares: middlewares,
+		},
+		{
+			Name:        "admin_set_client_scopes",
+			Method:      "PUT",
+			Pattern:     clientPath + "/scopes",
+			HandlerFunc: s.setClientScopes,
+			Middlewares: middlewares,
+		},
+		{
+			Name:        "admin_rotate_client_secret",
+			Method:      "POST",
+			Pattern:     clientPath + "/secret",
+			HandlerFunc: s.rotateClientSecret,
+			Middlewares: middlewares,
+		},
+		{
+			Name:        "admin_add_secondary_client_secret",
+			Method:      "POST",
+			Pattern:     clientPath + "/secondary-secret",
+			HandlerFunc: s.addSecondaryClientSecret,
+			Middlewares: middlewares,
+		},
+		{
+			Name:        "admin_promote_secondary_client_secret",
+			Method:      "POST",
+			Pattern:     clientPath + "/secondary-secret/promote",
+			HandlerFunc: s.promoteSecondaryClientSecret,
+			Middlewares: middlewares,
+		},
+		{
+			Name:        "admin_expire_secondary_client_secret",
+			Method:      "DELETE",
+			Pattern:     clientPath + "/secondary-secret",
+			HandlerFunc: s.expireSecondaryClientSecret,
+			Middlewares: middlewares,
+		},
+		{
+			Name:        "admin_set_client_rate_limit",
+			Method:      "PUT",
+			Pattern:     clientPath + "/rate-limit",
+			HandlerFunc: s.setClientRateLimit,
+			Middlewares: middlewares,
+		},
+		{
+			Name:        "admin_disable_client",
+			Method:      "POST",
+			Pattern:     clientPath + "/disable",
+			HandlerFunc: s.disableClient,
+			Middlewares: middlewares,
+		},
+		{
+			Name:        "admin_enable_client",
+			Method:      "POST",
+			Pattern:     clientPath + "/enable",
+			HandlerFunc: s.enableClient,
+			Middlewares: middlewares,
+		},
+		{
+			Name:        "admin_unlock_user",
+			Method:      "POST",
+			Pattern:     userPath + "/unlock",
+			HandlerFunc: s.unlockUser,
+			Middlewares: middlewares,
+		},
+		{
+			Name:        "admin_get_user_roles",
+			Method:      "GET",
+			Pattern:     userPath + "/roles",
+			HandlerFunc: s.getUserRoles,
+			Middlewares: middlewares,
+		},
+		{
+			Name:        "admin_set_user_roles",
+			Method:      "PUT",
+			Pattern:     userPath + "/roles",
+			HandlerFunc: s.setUserRoles,
+			Middlewares: middlewares,
+		},
+		{
+			Name:        "admin_get_user_attributes",
+			Method:      "GET",
+			Pattern:     userPath + "/attributes",
+			HandlerFunc: s.getUserAttributes,
+			Middlewares: middlewares,
+		},
+		{
+			Name:        "admin_set_user_attributes",
+			Method:      "PUT",
+			Pattern:     userPath + "/attributes",
+			HandlerFunc: s.setUserAttributes,
+			Middlewares: middlewares,
+		},
+		{
+			Name:        "admin_get_role_scopes",
+			Method:      "GET",
+			Pattern:     rolePath + "/scopes",
+			HandlerFunc: s.getRoleScopes,
+			Middlewares: middlewares,
+		},
+		{
+			Name:        "admin_set_role_scopes",
+			Method:      "PUT",
+			Pattern:     rolePath + "/scopes",
+			HandlerFunc: s.setRoleScopes,
+			Middlewares: middlewares,
+		},
+		{
+			Name:        "admin_purge_tokens",
+			Method:      "POST",
+			Pattern:     tokensPath + "/purge",
+			HandlerFunc: s.purgeTokens,
+			Middlewares: middlewares,
+		},
+	}
+}
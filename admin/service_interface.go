@@ -0,0 +1,18 @@
+package admin
+
+import (
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/util/routes"
+	"github.com/gorilla/mux"
+)
+
+// ServiceInterface defines exported methods
+type ServiceInterface interface {
+	// Exported methods
+	GetConfig() *config.Config
+	GetOauthService() oauth.ServiceInterface
+	GetRoutes() []routes.Route
+	RegisterRoutes(router *mux.Router, prefix string)
+	Close()
+}
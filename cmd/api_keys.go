@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+)
+
+// CreateAPIKey creates an API key for a client, optionally on behalf
+// of a user, and prints it as it cannot be retrieved again
+func CreateAPIKey(clientID, username, scope, configBackend string) error {
+	cnf, db, err := initConfigDB(true, false, configBackend)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	oauthService := oauth.NewService(cnf, db)
+
+	client, err := oauthService.FindClientByClientID(clientID)
+	if err != nil {
+		return err
+	}
+
+	var user *models.OauthUser
+	if username != "" {
+		user, err = oauthService.FindUserByUsername(username)
+		if err != nil {
+			return err
+		}
+	}
+
+	key, err := oauthService.CreateAPIKey(client, user, scope)
+	if err != nil {
+		return err
+	}
+	fmt.Println(key)
+	return nil
+}
+
+// RevokeAPIKey revokes an API key
+func RevokeAPIKey(key, configBackend string) error {
+	cnf, db, err := initConfigDB(true, false, configBackend)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return oauth.NewService(cnf, db).RevokeAPIKey(key)
+}
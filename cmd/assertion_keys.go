@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"io/ioutil"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+)
+
+// AddAssertionKey registers the PEM encoded public key in publicKeyFile for
+// a client, so it can exchange JWT assertions from the issuer for tokens
+func AddAssertionKey(clientID, issuer, algorithm, publicKeyFile, configBackend string) error {
+	publicKey, err := ioutil.ReadFile(publicKeyFile)
+	if err != nil {
+		return err
+	}
+
+	cnf, db, err := initConfigDB(true, false, configBackend)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	oauthService := oauth.NewService(cnf, db)
+
+	client, err := oauthService.FindClientByClientID(clientID)
+	if err != nil {
+		return err
+	}
+
+	_, err = oauthService.AddAssertionKey(client, issuer, algorithm, string(publicKey))
+	return err
+}
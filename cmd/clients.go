@@ -0,0 +1,259 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+)
+
+// SetAllowedGrantTypes restricts a client to the grant types,
+// passing no grant types allows the client to use all of them
+func SetAllowedGrantTypes(clientID string, grantTypes []string, configBackend string) error {
+	cnf, db, err := initConfigDB(true, false, configBackend)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	oauthService := oauth.NewService(cnf, db)
+
+	client, err := oauthService.FindClientByClientID(clientID)
+	if err != nil {
+		return err
+	}
+
+	return oauthService.SetAllowedGrantTypes(client, grantTypes)
+}
+
+// SetAllowedResponseTypes restricts a client to the response types, passing
+// no response types allows the client to use all of them. Response types with
+// several values such as "code id_token" must be quoted.
+func SetAllowedResponseTypes(clientID string, responseTypes []string, configBackend string) error {
+	cnf, db, err := initConfigDB(true, false, configBackend)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	oauthService := oauth.NewService(cnf, db)
+
+	client, err := oauthService.FindClientByClientID(clientID)
+	if err != nil {
+		return err
+	}
+
+	return oauthService.SetAllowedResponseTypes(client, responseTypes)
+}
+
+// SetTLSClientAuthSubjectDN lets a client authenticate with TLS client
+// certificates issued to the subject, passing no subject disables it
+func SetTLSClientAuthSubjectDN(clientID, subjectDN, configBackend string) error {
+	cnf, db, err := initConfigDB(true, false, configBackend)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	oauthService := oauth.NewService(cnf, db)
+
+	client, err := oauthService.FindClientByClientID(clientID)
+	if err != nil {
+		return err
+	}
+
+	return oauthService.SetTLSClientAuthSubjectDN(client, subjectDN)
+}
+
+// SetTLSClientAuthSAN lets a client authenticate with TLS client
+// certificates carrying the subject alternative name, passing no name disables it
+func SetTLSClientAuthSAN(clientID, san, configBackend string) error {
+	cnf, db, err := initConfigDB(true, false, configBackend)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	oauthService := oauth.NewService(cnf, db)
+
+	client, err := oauthService.FindClientByClientID(clientID)
+	if err != nil {
+		return err
+	}
+
+	return oauthService.SetTLSClientAuthSAN(client, san)
+}
+
+// SetBackchannelNotificationEndpoint makes a client use the ping mode of
+// backchannel authentication, passing no endpoint switches it to poll mode
+func SetBackchannelNotificationEndpoint(clientID, endpoint, configBackend string) error {
+	cnf, db, err := initConfigDB(true, false, configBackend)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	oauthService := oauth.NewService(cnf, db)
+
+	client, err := oauthService.FindClientByClientID(clientID)
+	if err != nil {
+		return err
+	}
+
+	return oauthService.SetBackchannelNotificationEndpoint(client, endpoint)
+}
+
+// SetTokenFormat makes a client be issued opaque or JWT access tokens,
+// passing no format makes it use the configured one
+func SetTokenFormat(clientID, format, configBackend string) error {
+	cnf, db, err := initConfigDB(true, false, configBackend)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	oauthService := oauth.NewService(cnf, db)
+
+	client, err := oauthService.FindClientByClientID(clientID)
+	if err != nil {
+		return err
+	}
+
+	return oauthService.SetTokenFormat(client, format)
+}
+
+// SetClientScopes restricts a client to the scopes, passing
+// no scopes lets the client be issued tokens for any scope
+func SetClientScopes(clientID string, scopes []string, configBackend string) error {
+	cnf, db, err := initConfigDB(true, false, configBackend)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	oauthService := oauth.NewService(cnf, db)
+
+	client, err := oauthService.FindClientByClientID(clientID)
+	if err != nil {
+		return err
+	}
+
+	return oauthService.SetClientScopes(client, scopes)
+}
+
+// SetClientTokenLifetimes overrides the lifetimes in seconds of tokens issued
+// to a client, a lifetime of zero makes the client use the configured one
+func SetClientTokenLifetimes(clientID, accessTokenLifetime, refreshTokenLifetime, configBackend string) error {
+	accessLifetime, err := parseLifetime(accessTokenLifetime)
+	if err != nil {
+		return err
+	}
+	refreshLifetime, err := parseLifetime(refreshTokenLifetime)
+	if err != nil {
+		return err
+	}
+
+	cnf, db, err := initConfigDB(true, false, configBackend)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	oauthService := oauth.NewService(cnf, db)
+
+	client, err := oauthService.FindClientByClientID(clientID)
+	if err != nil {
+		return err
+	}
+
+	return oauthService.SetClientTokenLifetimes(client, accessLifetime, refreshLifetime)
+}
+
+// SetClientType makes a client either public or confidential
+func SetClientType(clientID, clientType, configBackend string) error {
+	cnf, db, err := initConfigDB(true, false, configBackend)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	oauthService := oauth.NewService(cnf, db)
+
+	client, err := oauthService.FindClientByClientID(clientID)
+	if err != nil {
+		return err
+	}
+
+	return oauthService.SetClientType(client, clientType)
+}
+
+// SetClientFirstParty marks a client as one of our own apps, so users are
+// not asked to consent to it, or as a third-party integration
+func SetClientFirstParty(clientID, firstParty, configBackend string) error {
+	isFirstParty, err := strconv.ParseBool(firstParty)
+	if err != nil {
+		return fmt.Errorf("Invalid first party flag %s", firstParty)
+	}
+
+	cnf, db, err := initConfigDB(true, false, configBackend)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	oauthService := oauth.NewService(cnf, db)
+
+	client, err := oauthService.FindClientByClientID(clientID)
+	if err != nil {
+		return err
+	}
+
+	return oauthService.SetClientFirstParty(client, isFirstParty)
+}
+
+// SetClientSubjectType makes a client identify users by public or pairwise
+// subject identifiers, pairwise clients can share a sector identifier URI
+func SetClientSubjectType(clientID, subjectType, sectorIdentifierURI, configBackend string) error {
+	cnf, db, err := initConfigDB(true, false, configBackend)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	oauthService := oauth.NewService(cnf, db)
+
+	client, err := oauthService.FindClientByClientID(clientID)
+	if err != nil {
+		return err
+	}
+
+	return oauthService.SetClientSubjectType(client, subjectType, sectorIdentifierURI)
+}
+
+// SetClientTokenRateLimit caps the requests per minute a client can send
+// to the token endpoint, passing no limit lifts the cap
+func SetClientTokenRateLimit(clientID, limit, configBackend string) error {
+	requestsPerMinute := 0
+	if limit != "" {
+		var err error
+		requestsPerMinute, err = strconv.Atoi(limit)
+		if err != nil || requestsPerMinute < 0 {
+			return fmt.Errorf("Invalid rate limit %s", limit)
+		}
+	}
+
+	cnf, db, err := initConfigDB(true, false, configBackend)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	oauthService := oauth.NewService(cnf, db)
+
+	client, err := oauthService.FindClientByClientID(clientID)
+	if err != nil {
+		return err
+	}
+
+	return oauthService.SetClientTokenRateLimit(client, requestsPerMinute)
+}
+
+// parseLifetime parses a lifetime in seconds, which defaults to zero
+func parseLifetime(lifetime string) (int, error) {
+	if lifetime == "" {
+		return 0, nil
+	}
+	seconds, err := strconv.Atoi(lifetime)
+	if err != nil || seconds < 0 {
+		return 0, fmt.Errorf("Invalid lifetime %s", lifetime)
+	}
+	return seconds, nil
+}
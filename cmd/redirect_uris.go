@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+)
+
+// AddRedirectURI registers another redirect URI for a client
+func AddRedirectURI(clientID, redirectURI, configBackend string) error {
+	cnf, db, err := initConfigDB(true, false, configBackend)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	oauthService := oauth.NewService(cnf, db)
+
+	client, err := oauthService.FindClientByClientID(clientID)
+	if err != nil {
+		return err
+	}
+
+	_, err = oauthService.AddRedirectURI(client, redirectURI)
+	return err
+}
+
+// RemoveRedirectURI unregisters a redirect URI added for a client
+func RemoveRedirectURI(clientID, redirectURI, configBackend string) error {
+	cnf, db, err := initConfigDB(true, false, configBackend)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	oauthService := oauth.NewService(cnf, db)
+
+	client, err := oauthService.FindClientByClientID(clientID)
+	if err != nil {
+		return err
+	}
+
+	return oauthService.RemoveRedirectURI(client, redirectURI)
+}
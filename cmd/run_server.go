@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/RichardKnop/go-oauth2-server/models"
 	"github.com/RichardKnop/go-oauth2-server/services"
 	"github.com/gorilla/mux"
 	"github.com/phyber/negroni-gzip/gzip"
@@ -19,12 +20,20 @@ func RunServer(configBackend string) error {
 	}
 	defer db.Close()
 
+	// Auto-migrate or make sure migrations have been run
+	if err := models.InitSchema(db, cnf.MigrationMode); err != nil {
+		return err
+	}
+
 	// start the services
 	if err := services.Init(cnf, db); err != nil {
 		return err
 	}
 	defer services.Close()
 
+	// Purge expired tokens in the background
+	services.OauthService.StartTokenCleanup()
+
 	// Start a classic negroni app
 	app := negroni.New()
 	app.Use(negroni.NewRecovery())
@@ -39,6 +48,8 @@ func RunServer(configBackend string) error {
 	services.HealthService.RegisterRoutes(router, "/v1")
 	services.OauthService.RegisterRoutes(router, "/v1/oauth")
 	services.WebService.RegisterRoutes(router, "/web")
+	services.AdminService.RegisterRoutes(router, "/v1/admin")
+	services.ScimService.RegisterRoutes(router, "/scim/v2")
 
 	// Set the router
 	app.UseHandler(router)
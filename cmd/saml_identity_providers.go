@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"io/ioutil"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+)
+
+// AddSAMLIdentityProvider registers the identity provider described by the
+// SAML 2.0 metadata in metadataFile for a client, so it can exchange
+// assertions issued by the identity provider for tokens
+func AddSAMLIdentityProvider(clientID, metadataFile, configBackend string) error {
+	metadata, err := ioutil.ReadFile(metadataFile)
+	if err != nil {
+		return err
+	}
+
+	cnf, db, err := initConfigDB(true, false, configBackend)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	oauthService := oauth.NewService(cnf, db)
+
+	client, err := oauthService.FindClientByClientID(clientID)
+	if err != nil {
+		return err
+	}
+
+	_, err = oauthService.AddSAMLIdentityProvider(client, metadata)
+	return err
+}
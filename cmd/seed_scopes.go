@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+)
+
+// SeedScopes creates or updates scopes defined in a JSON file
+func SeedScopes(path, configBackend string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var scopes []oauth.ScopeDef
+	if err := json.Unmarshal(data, &scopes); err != nil {
+		return err
+	}
+
+	_, db, err := initConfigDB(true, false, configBackend)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return oauth.SeedDefaultScopes(db, scopes)
+}
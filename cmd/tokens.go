@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+)
+
+// PurgeExpiredTokens deletes expired access and refresh tokens
+// and prints how many were deleted
+func PurgeExpiredTokens(configBackend string) error {
+	cnf, db, err := initConfigDB(true, false, configBackend)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	purged, err := oauth.NewService(cnf, db).PurgeExpiredTokens()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Purged %d expired tokens\n", purged)
+	return nil
+}
+
+// PrintTokenLineage prints the tokens of the lineage of a token
+func PrintTokenLineage(token, configBackend string) error {
+	cnf, db, err := initConfigDB(true, false, configBackend)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	lineage, err := oauth.NewService(cnf, db).GetTokenLineage(token)
+	if err != nil {
+		return err
+	}
+	printTokenLineage(lineage)
+	return nil
+}
+
+// RevokeTokenLineage revokes every token of the lineage of a compromised
+// token and prints the revoked tokens
+func RevokeTokenLineage(token, configBackend string) error {
+	cnf, db, err := initConfigDB(true, false, configBackend)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	lineage, err := oauth.NewService(cnf, db).RevokeTokenLineage(token)
+	if err != nil {
+		return err
+	}
+	printTokenLineage(lineage)
+	return nil
+}
+
+func printTokenLineage(lineage *oauth.TokenLineage) {
+	fmt.Printf("Lineage %s\n", lineage.ID)
+	for _, refreshToken := range lineage.RefreshTokens {
+		used := "unused"
+		if refreshToken.UsedAt != nil {
+			used = "used " + refreshToken.UsedAt.Format(time.RFC3339)
+		}
+		fmt.Printf(
+			"%s refresh_token %s scope=%q %s\n",
+			refreshToken.CreatedAt.Format(time.RFC3339),
+			refreshToken.ID,
+			refreshToken.Scope,
+			used,
+		)
+	}
+	for _, accessToken := range lineage.AccessTokens {
+		fmt.Printf(
+			"%s access_token %s scope=%q from=%s\n",
+			accessToken.CreatedAt.Format(time.RFC3339),
+			accessToken.ID,
+			accessToken.Scope,
+			accessToken.RefreshTokenID,
+		)
+	}
+}
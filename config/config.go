@@ -12,11 +12,305 @@ type DatabaseConfig struct {
 	MaxOpenConns int
 }
 
+// TokenLifetimes stores token lifetimes in seconds
+type TokenLifetimes struct {
+	AccessTokenLifetime  int
+	RefreshTokenLifetime int
+}
+
+// PasswordPolicyConfig stores the rules new passwords must follow
+type PasswordPolicyConfig struct {
+	// MinLength is the minimum number of characters, 6 by default
+	MinLength        int
+	RequireUppercase bool
+	RequireLowercase bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	// BannedPasswords are refused whatever their case
+	BannedPasswords []string
+	// CheckPwnedPasswords refuses passwords found in data breaches by
+	// looking them up in the Pwned Passwords API at PwnedPasswordsURL,
+	// https://api.pwnedpasswords.com/range/ by default. Passwords are
+	// accepted when the lookup fails.
+	CheckPwnedPasswords bool
+	PwnedPasswordsURL   string
+}
+
+// PasswordHashingConfig stores the algorithm and cost parameters user
+// passwords are hashed with. Parameters left at zero use the defaults
+// of the algorithm.
+type PasswordHashingConfig struct {
+	// Algorithm is one of bcrypt (the default), argon2id or scrypt
+	Algorithm string
+	// BcryptCost defaults to 10
+	BcryptCost int
+	// Argon2Memory is in KiB, 65536 by default, Argon2Iterations
+	// defaults to 3 and Argon2Parallelism to 4
+	Argon2Memory      uint32
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+	// ScryptN is a power of two, 32768 by default, ScryptR defaults
+	// to 8 and ScryptP to 1
+	ScryptN int
+	ScryptR int
+	ScryptP int
+}
+
+// LDAPConfig stores the directory users log in against, such as Active
+// Directory. LDAP login is disabled when URL is empty.
+type LDAPConfig struct {
+	// URL of the server, e.g. ldaps://ldap.example.com
+	URL string
+	// StartTLS upgrades ldap:// connections to TLS
+	StartTLS bool
+	// CACertificate is the PEM encoded certificate the certificate of the
+	// server must chain to, the system roots are used when empty
+	CACertificate string
+	// BindDN and BindPassword are the service account searching for
+	// users, searches are anonymous when BindDN is empty
+	BindDN       string
+	BindPassword string
+	BaseDN       string
+	// UserFilter finds the entry of a user, %s is replaced with the
+	// username. Defaults to (uid=%s), use (sAMAccountName=%s) for
+	// Active Directory.
+	UserFilter string
+	// UsernameAttribute holds the username of the local user created on
+	// the first login, e.g. mail. The username logged in with is used
+	// when empty.
+	UsernameAttribute string
+	// Timeout is in seconds, 10 by default
+	Timeout int
+}
+
+// FederationProviderConfig stores an upstream OpenID Connect provider
+// users can log in with, such as Google or Azure AD
+type FederationProviderConfig struct {
+	// Name identifies the provider in URLs and linked identities,
+	// e.g. google, it must not change once users have logged in
+	Name string
+	// DisplayName is shown on the login page, Name is used when empty
+	DisplayName string
+	// Issuer of the provider, its configuration is discovered from
+	// Issuer/.well-known/openid-configuration, e.g.
+	// https://accounts.google.com or
+	// https://login.microsoftonline.com/{tenant}/v2.0
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// Scopes to request, openid email profile by default
+	Scopes []string
+	// LinkByEmail links the first login to the local user whose username
+	// is the email the provider asserts as verified
+	LinkByEmail bool
+	// CreateUsers creates local users for first logins no local user is
+	// linked to, which requires a verified email
+	CreateUsers bool
+}
+
+// UserAttributeClaimConfig releases a custom attribute of users as a claim
+type UserAttributeClaimConfig struct {
+	// Attribute is the name the attribute of users is stored under
+	Attribute string
+	// Claim is the name of the claim, Attribute is used when empty
+	Claim string
+	// Scope a token must be granted for the claim to be released,
+	// the claim is released whatever the scope when empty
+	Scope string
+	// UserInfo, IDToken and AccessToken choose whether the claim is
+	// included in userinfo responses, ID tokens and access tokens
+	UserInfo    bool
+	IDToken     bool
+	AccessToken bool
+}
+
 // OauthConfig stores oauth service configuration options
 type OauthConfig struct {
 	AccessTokenLifetime  int
 	RefreshTokenLifetime int
 	AuthCodeLifetime     int
+	// HandoffCodeLifetime is how many seconds a mobile handoff code
+	// stays valid. Defaults to 30 seconds when not set.
+	HandoffCodeLifetime int
+	// MinClientSecretLength is the minimum length of secrets supplied
+	// when creating clients. Defaults to 8 characters when not set.
+	MinClientSecretLength int
+	// EnableDeviceSecret issues a device_secret alongside tokens granted to
+	// users, which native apps can exchange for fresh tokens for
+	// DeviceSecretLifetime seconds (defaults to RefreshTokenLifetime)
+	EnableDeviceSecret   bool
+	DeviceSecretLifetime int
+	// UserTokenLimit caps how many access tokens can be issued to a single
+	// user within UserTokenLimitWindow seconds across all grant types.
+	// Leave at zero to disable the limit.
+	UserTokenLimit       int
+	UserTokenLimitWindow int
+	// LoginLockoutThreshold locks a username out of the password grant after
+	// as many failed logins within LoginLockoutWindow seconds (15 minutes by
+	// default), until enough of them fall out of the window or an operator
+	// unlocks the user. LoginIPLockoutThreshold does the same for failed
+	// logins from a single IP address, whichever usernames they were for.
+	// Leave at zero to disable the lockouts.
+	LoginLockoutThreshold   int
+	LoginIPLockoutThreshold int
+	LoginLockoutWindow      int
+	// ExpectedAudience is the identifier of the resource server protected by
+	// the authentication middleware. When set, tokens whose audience does not
+	// include it are rejected. Leave empty to disable the check.
+	ExpectedAudience string
+	// IntrospectClientName includes the name of the client a token
+	// was granted to as client_name in introspection responses
+	IntrospectClientName bool
+	// Exchanged authorization codes are kept, and the tokens issued from one,
+	// and refreshed since, are revoked when it is presented again, as
+	// recommended by the OAuth 2.0 Security BCP. DisableCodeReplayRevocation
+	// deletes exchanged codes instead, so replays are merely refused.
+	DisableCodeReplayRevocation bool
+	// SessionClaims ties tokens to the login they were issued for, ID tokens
+	// and introspection responses then include sid and auth_time claims
+	SessionClaims bool
+	// AllowedRedirectSchemes lists schemes clients can use in redirect URIs,
+	// such as custom schemes of native apps, defaults to https only.
+	// Plain http is always allowed for loopback redirect URIs.
+	AllowedRedirectSchemes []string
+	// StrictRedirectURIs requires https redirect URIs, only native apps
+	// can use loopback or other allowed schemes
+	StrictRedirectURIs bool
+	// UseCookies lets browser clients opt in to receiving tokens as
+	// Secure, HttpOnly, SameSite cookies instead of in the response body
+	// by sending token_delivery=cookie with the token request
+	UseCookies bool
+	// EnableValidationCache keeps validated access tokens in memory for
+	// ValidationCacheTTL seconds (5 by default) to reduce database load.
+	// Revoked tokens are removed from the cache of this instance only.
+	// The cache holds up to ValidationCacheMaxEntries tokens (10000 by
+	// default).
+	EnableValidationCache     bool
+	ValidationCacheTTL        int
+	ValidationCacheMaxEntries int
+	// EnableImplicitGrant allows response_type=token for legacy browser
+	// clients, it is disabled by default as recommended by OAuth 2.1
+	EnableImplicitGrant bool
+	// EnableScopeCache keeps all scopes in memory for ScopeCacheTTL seconds
+	// (60 by default) instead of querying them on every grant
+	EnableScopeCache bool
+	ScopeCacheTTL    int
+	// APIKeyTokenLifetime is the lifetime in seconds of access tokens
+	// issued for API keys, 300 by default
+	APIKeyTokenLifetime int
+	// DisabledGrantTypes are rejected by the token endpoint, and so are
+	// refresh tokens originally issued by them
+	DisabledGrantTypes []string
+	// DisabledClientAuthMethods are not accepted for authenticating clients,
+	// such as client_secret_post
+	DisabledClientAuthMethods []string
+	// DeviceCodeLifetime is how many seconds a device authorization request
+	// stays valid (600 by default), devices must wait DeviceCodeInterval
+	// seconds (5 by default) between polls of the token endpoint
+	DeviceCodeLifetime int
+	DeviceCodeInterval int
+	// PushedRequestLifetime is how many seconds the request URI of a pushed
+	// authorization request stays valid, 300 by default
+	PushedRequestLifetime int
+	// RequirePushedAuthorizationRequests makes the authorization endpoint
+	// only accept requests pushed to the pushed authorization request endpoint
+	RequirePushedAuthorizationRequests bool
+	// BackchannelRequestLifetime is how many seconds a backchannel authentication
+	// request stays valid (120 by default), clients polling for the decision
+	// must wait BackchannelInterval seconds (5 by default) between polls
+	BackchannelRequestLifetime int
+	BackchannelInterval        int
+	// RequireOfflineAccess only issues refresh tokens to clients granted the
+	// offline_access scope, the scope must be added to the scopes table
+	RequireOfflineAccess bool
+	// GrantTypeLifetimes override AccessTokenLifetime and RefreshTokenLifetime
+	// for tokens issued by the grant types they are keyed by, lifetimes left
+	// at zero fall back to the global ones
+	GrantTypeLifetimes map[string]TokenLifetimes
+	// ResourceServers lists the absolute URIs clients can request tokens for
+	// with resource parameters, tokens are then only issued for them
+	ResourceServers []string
+	// AuthorizationDetailsTypes lists the types of authorization_details
+	// clients can request, authorization details are refused when empty
+	AuthorizationDetailsTypes []string
+	// CertificateBoundAccessTokens binds access tokens issued to clients
+	// presenting a TLS client certificate to the certificate, resource
+	// servers then only accept them over connections using it
+	CertificateBoundAccessTokens bool
+	// AccessTokenFormat is either opaque (default) or jwt, JWT access tokens
+	// are signed with the JWT signing key so resource servers can validate
+	// them without introspecting them
+	AccessTokenFormat string
+	// TokenLength is how many characters of TokenAlphabet random access
+	// and refresh tokens have, 32 alphanumeric characters by default.
+	// AccessTokenPrefix and RefreshTokenPrefix, such as at_ and rt_,
+	// are prepended to them so secret scanners can recognise them.
+	TokenLength        int
+	TokenAlphabet      string
+	AccessTokenPrefix  string
+	RefreshTokenPrefix string
+	// TokenCleanupInterval makes the server purge access and refresh tokens
+	// which expired more than TokenCleanupRetention seconds ago every so
+	// many seconds, TokenCleanupBatchSize (1000 by default) rows at a time.
+	// Leave at zero to keep expired tokens.
+	TokenCleanupInterval  int
+	TokenCleanupRetention int
+	TokenCleanupBatchSize int
+	// EnableClientRegistration lets clients register themselves at the
+	// dynamic client registration endpoint, see RFC 7591. When
+	// RegistrationInitialAccessToken is set, only requests carrying it
+	// as a bearer token can register clients.
+	EnableClientRegistration       bool
+	RegistrationInitialAccessToken string
+	// RegistrationGrantTypes are the grant types clients can register
+	// themselves for, the grants acting for a user (authorization_code,
+	// implicit, refresh_token and device_code) by default. Registered
+	// clients are only ever granted the default scopes.
+	RegistrationGrantTypes []string
+	// EnableUserRegistration lets clients sign users up at the user
+	// registration endpoint instead of writing to the users table
+	EnableUserRegistration bool
+	// PasswordResetLifetime is how many seconds a password reset token
+	// stays valid, 3600 by default. PasswordResetURL is the page users set
+	// a new password on, reset emails link to it with the token appended
+	// as the token query parameter.
+	PasswordResetLifetime int
+	PasswordResetURL      string
+	// EmailVerificationLifetime is how many seconds an email verification
+	// token stays valid, a day by default. EmailVerificationURL is the page
+	// confirming the email address, verification emails link to it with the
+	// token appended as the token query parameter. RequireEmailVerification
+	// refuses the password grant to users who have not verified their email.
+	EmailVerificationLifetime int
+	EmailVerificationURL      string
+	RequireEmailVerification  bool
+	// PairwiseSubjectSalt is mixed into the subject identifiers of clients
+	// using pairwise ones, changing it changes all of them
+	PairwiseSubjectSalt string
+	// WebAuthnRPID is the domain passkeys are registered for, users can log
+	// in with passkeys once it is set. WebAuthnRPName is shown to users when
+	// registering one, and WebAuthnOrigins lists the origins of the pages
+	// using them, https:// followed by the RP ID by default.
+	WebAuthnRPID    string
+	WebAuthnRPName  string
+	WebAuthnOrigins []string
+	// PasswordPolicy is enforced whenever a password is set
+	PasswordPolicy PasswordPolicyConfig
+	// PasswordHashing chooses how user passwords are hashed, existing
+	// hashes are replaced the next time their user logs in
+	PasswordHashing PasswordHashingConfig
+	// LDAP authenticates users against a directory instead of the local
+	// users table, local users are created on their first login
+	LDAP LDAPConfig
+	// LocalUserFallback lets users the directory does not know log in
+	// with the password of their local user
+	LocalUserFallback bool
+	// FederationProviders users can log in with instead of a password,
+	// they are redirected back to JWT.Issuer/web/federation/{name}/callback
+	FederationProviders []FederationProviderConfig
+	// UserAttributeClaims map custom attributes of users to claims,
+	// attributes no claim is mapped to are never released
+	UserAttributeClaims []UserAttributeClaimConfig
 }
 
 // SessionConfig stores session configuration for the web app
@@ -31,12 +325,54 @@ type SessionConfig struct {
 	// this particular cookie should only be accessed by the server.
 	// Any attempt to access the cookie from client script is strictly forbidden.
 	HTTPOnly bool
+	// Insecure also sends the session cookie over plain http, the cookie
+	// is only sent over https unless it is set during development
+	Insecure bool
+	// RememberMeMaxAge is how many seconds the sessions of users who tick
+	// remember me on the login page last, 30 days by default. Other
+	// sessions last MaxAge.
+	RememberMeMaxAge int
 }
 
+// JWTConfig stores options used to sign JSON Web Tokens such as ID tokens
+type JWTConfig struct {
+	// Issuer is used as the iss claim, it should be the public URL of the server
+	Issuer string
+	// Secret is the shared key used to sign tokens with HS256
+	Secret string
+	// Algorithm is one of HS256 (default), RS256, RS384, RS512 or ES256
+	Algorithm string
+	// PrivateKey is the PEM encoded RSA or P-256 EC private key
+	// required by the RS* and ES256 algorithms
+	PrivateKey string
+	// KeyRotationInterval makes the server generate its own RS* or ES256
+	// signing keys and replace them every so many seconds, retired keys are
+	// published until the tokens they have signed have expired
+	KeyRotationInterval int
+}
+
+const (
+	// MigrationModeAuto makes the server auto-migrate models on startup,
+	// a convenience for development which cannot make destructive changes
+	MigrationModeAuto = "auto"
+	// MigrationModeExplicit makes the server refuse to start unless all
+	// versioned migrations have been run with the migrate command
+	MigrationModeExplicit = "explicit"
+
+	// AccessTokenFormatOpaque issues random access tokens, resource
+	// servers have to introspect them
+	AccessTokenFormatOpaque = "opaque"
+	// AccessTokenFormatJWT issues self-contained signed access tokens
+	AccessTokenFormatJWT = "jwt"
+)
+
 // Config stores all configuration options
 type Config struct {
 	Database      DatabaseConfig
 	Oauth         OauthConfig
 	Session       SessionConfig
+	JWT           JWTConfig
 	IsDevelopment bool
+	// MigrationMode is either auto or explicit, defaults to explicit
+	MigrationMode string
 }
@@ -30,7 +30,8 @@ var Cnf = &Config{
 	Oauth: OauthConfig{
 		AccessTokenLifetime:  3600,    // 1 hour
 		RefreshTokenLifetime: 1209600, // 14 days
-		AuthCodeLifetime:     3600,    // 1 hour
+		AuthCodeLifetime:     600,     // 10 minutes
+		HandoffCodeLifetime:  30,      // 30 seconds
 	},
 	Session: SessionConfig{
 		Secret:   "test_secret",
@@ -38,7 +39,12 @@ var Cnf = &Config{
 		MaxAge:   86400 * 7, // 7 days
 		HTTPOnly: true,
 	},
+	JWT: JWTConfig{
+		Issuer: "http://localhost:8080",
+		Secret: "test_secret",
+	},
 	IsDevelopment: true,
+	MigrationMode: MigrationModeExplicit,
 }
 
 // NewConfig loads configuration from etcd and returns *Config struct
@@ -6,6 +6,8 @@ import (
 
 	"github.com/RichardKnop/go-oauth2-server/config"
 	"github.com/RichardKnop/go-oauth2-server/database"
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -21,3 +23,15 @@ func TestNewDatabaseTypeNotSupported(t *testing.T) {
 		assert.Equal(t, errors.New("Database type bogus not suppported"), err)
 	}
 }
+
+func TestIsReadOnlyError(t *testing.T) {
+	readOnly := &pq.Error{Code: "25006", Message: "cannot execute INSERT in a read-only transaction"}
+
+	assert.True(t, database.IsReadOnlyError(readOnly))
+	assert.True(t, database.IsReadOnlyError(gorm.Errors{errors.New("bogus"), readOnly}))
+	assert.True(t, database.IsReadOnlyError(errors.New("attempt to write a readonly database")))
+
+	assert.False(t, database.IsReadOnlyError(nil))
+	assert.False(t, database.IsReadOnlyError(&pq.Error{Code: "23505", Message: "duplicate key value"}))
+	assert.False(t, database.IsReadOnlyError(errors.New("bogus")))
+}
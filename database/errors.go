@@ -0,0 +1,40 @@
+package database
+
+import (
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+)
+
+const (
+	// pqReadOnlyTransaction is the read_only_sql_transaction SQLSTATE
+	pqReadOnlyTransaction = "25006"
+)
+
+// IsReadOnlyError returns true if err is caused by a write to a database
+// which only accepts reads, such as a replica or one under maintenance
+func IsReadOnlyError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	// Gorm may collect several errors, e.g. from callbacks
+	if errs, ok := err.(gorm.Errors); ok {
+		for _, err := range errs.GetErrors() {
+			if IsReadOnlyError(err) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if pqErr, ok := err.(*pq.Error); ok {
+		return pqErr.Code == pqReadOnlyTransaction
+	}
+
+	// Sqlite only reports the error as a message
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "read-only transaction") ||
+		strings.Contains(msg, "readonly database")
+}
@@ -19,7 +19,7 @@ exec etcdctl put /config/go_oauth2_server.json '{
   "Oauth": {
     "AccessTokenLifetime": 3600,
     "RefreshTokenLifetime": 1209600,
-    "AuthCodeLifetime": 3600
+    "AuthCodeLifetime": 600
   },
   "Session": {
       "Secret": "test_secret",
@@ -27,5 +27,9 @@ exec etcdctl put /config/go_oauth2_server.json '{
       "MaxAge": 604800,
       "HTTPOnly": true
   },
+  "JWT": {
+    "Issuer": "http://localhost:8080",
+    "Secret": "test_secret"
+  },
   "IsDevelopment": true
 }'
@@ -47,6 +47,186 @@ func main() {
 				return cmd.LoadData(c.Args(), configBackend)
 			},
 		},
+		{
+			Name:  "seedscopes",
+			Usage: "create or update scopes defined in a JSON file",
+			Action: func(c *cli.Context) error {
+				return cmd.SeedScopes(c.Args().First(), configBackend)
+			},
+		},
+		{
+			Name:      "createapikey",
+			Usage:     "create an API key for a client",
+			ArgsUsage: "client_id [scope]",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "username",
+					Usage: "issue tokens on behalf of the user",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				return cmd.CreateAPIKey(c.Args().Get(0), c.String("username"), c.Args().Get(1), configBackend)
+			},
+		},
+		{
+			Name:      "revokeapikey",
+			Usage:     "revoke an API key",
+			ArgsUsage: "api_key",
+			Action: func(c *cli.Context) error {
+				return cmd.RevokeAPIKey(c.Args().First(), configBackend)
+			},
+		},
+		{
+			Name:      "addassertionkey",
+			Usage:     "register a public key verifying JWT assertions of a client",
+			ArgsUsage: "client_id issuer algorithm public_key_file",
+			Action: func(c *cli.Context) error {
+				return cmd.AddAssertionKey(c.Args().Get(0), c.Args().Get(1), c.Args().Get(2), c.Args().Get(3), configBackend)
+			},
+		},
+		{
+			Name:      "addsamlidp",
+			Usage:     "register a SAML 2.0 identity provider issuing assertions for a client from its metadata",
+			ArgsUsage: "client_id metadata_file",
+			Action: func(c *cli.Context) error {
+				return cmd.AddSAMLIdentityProvider(c.Args().Get(0), c.Args().Get(1), configBackend)
+			},
+		},
+		{
+			Name:      "addredirecturi",
+			Usage:     "register another redirect URI for a client",
+			ArgsUsage: "client_id redirect_uri",
+			Action: func(c *cli.Context) error {
+				return cmd.AddRedirectURI(c.Args().Get(0), c.Args().Get(1), configBackend)
+			},
+		},
+		{
+			Name:      "removeredirecturi",
+			Usage:     "unregister a redirect URI added for a client",
+			ArgsUsage: "client_id redirect_uri",
+			Action: func(c *cli.Context) error {
+				return cmd.RemoveRedirectURI(c.Args().Get(0), c.Args().Get(1), configBackend)
+			},
+		},
+		{
+			Name:      "setgranttypes",
+			Usage:     "restrict the grant types a client can use, or allow all of them when none are given",
+			ArgsUsage: "client_id [grant_type...]",
+			Action: func(c *cli.Context) error {
+				return cmd.SetAllowedGrantTypes(c.Args().First(), c.Args().Tail(), configBackend)
+			},
+		},
+		{
+			Name:      "setscopes",
+			Usage:     "restrict the scopes a client can be issued tokens for, or allow all of them when none are given",
+			ArgsUsage: "client_id [scope...]",
+			Action: func(c *cli.Context) error {
+				return cmd.SetClientScopes(c.Args().First(), c.Args().Tail(), configBackend)
+			},
+		},
+		{
+			Name:      "settokenlifetimes",
+			Usage:     "override the lifetimes in seconds of tokens issued to a client, or use the configured ones when none are given",
+			ArgsUsage: "client_id [access_token_lifetime] [refresh_token_lifetime]",
+			Action: func(c *cli.Context) error {
+				return cmd.SetClientTokenLifetimes(c.Args().Get(0), c.Args().Get(1), c.Args().Get(2), configBackend)
+			},
+		},
+		{
+			Name:      "setclienttype",
+			Usage:     "make a client public, so it authenticates by client ID and uses PKCE, or confidential",
+			ArgsUsage: "client_id public|confidential",
+			Action: func(c *cli.Context) error {
+				return cmd.SetClientType(c.Args().Get(0), c.Args().Get(1), configBackend)
+			},
+		},
+		{
+			Name:      "setfirstparty",
+			Usage:     "mark a client as one of our own apps users are not asked to consent to, or as a third-party integration",
+			ArgsUsage: "client_id true|false",
+			Action: func(c *cli.Context) error {
+				return cmd.SetClientFirstParty(c.Args().Get(0), c.Args().Get(1), configBackend)
+			},
+		},
+		{
+			Name:      "setsubjecttype",
+			Usage:     "make a client identify users by public or pairwise subject identifiers",
+			ArgsUsage: "client_id public|pairwise [sector_identifier_uri]",
+			Action: func(c *cli.Context) error {
+				return cmd.SetClientSubjectType(c.Args().Get(0), c.Args().Get(1), c.Args().Get(2), configBackend)
+			},
+		},
+		{
+			Name:      "setratelimit",
+			Usage:     "cap the token requests per minute a client can send, or lift the cap when none is given",
+			ArgsUsage: "client_id [requests_per_minute]",
+			Action: func(c *cli.Context) error {
+				return cmd.SetClientTokenRateLimit(c.Args().Get(0), c.Args().Get(1), configBackend)
+			},
+		},
+		{
+			Name:      "setresponsetypes",
+			Usage:     "restrict the response types a client can use, or allow all of them when none are given",
+			ArgsUsage: "client_id [response_type...]",
+			Action: func(c *cli.Context) error {
+				return cmd.SetAllowedResponseTypes(c.Args().First(), c.Args().Tail(), configBackend)
+			},
+		},
+		{
+			Name:      "settlssubject",
+			Usage:     "let a client authenticate with TLS client certificates issued to the subject, or disable it when none is given",
+			ArgsUsage: "client_id [subject_dn]",
+			Action: func(c *cli.Context) error {
+				return cmd.SetTLSClientAuthSubjectDN(c.Args().Get(0), c.Args().Get(1), configBackend)
+			},
+		},
+		{
+			Name:      "settlssan",
+			Usage:     "let a client authenticate with TLS client certificates carrying the dns, uri, ip or email subject alternative name, or disable it when none is given",
+			ArgsUsage: "client_id [type:value]",
+			Action: func(c *cli.Context) error {
+				return cmd.SetTLSClientAuthSAN(c.Args().Get(0), c.Args().Get(1), configBackend)
+			},
+		},
+		{
+			Name:      "settokenformat",
+			Usage:     "issue opaque or jwt access tokens to a client, or the configured format when none is given",
+			ArgsUsage: "client_id [format]",
+			Action: func(c *cli.Context) error {
+				return cmd.SetTokenFormat(c.Args().Get(0), c.Args().Get(1), configBackend)
+			},
+		},
+		{
+			Name:      "setbackchannelendpoint",
+			Usage:     "ping the endpoint when backchannel authentication requests of a client are decided",
+			ArgsUsage: "client_id [notification_endpoint]",
+			Action: func(c *cli.Context) error {
+				return cmd.SetBackchannelNotificationEndpoint(c.Args().Get(0), c.Args().Get(1), configBackend)
+			},
+		},
+		{
+			Name:  "purgetokens",
+			Usage: "delete expired access and refresh tokens",
+			Action: func(c *cli.Context) error {
+				return cmd.PurgeExpiredTokens(configBackend)
+			},
+		},
+		{
+			Name:      "tokenlineage",
+			Usage:     "list the tokens descending from the same authorization code or refresh token as a token",
+			ArgsUsage: "token",
+			Action: func(c *cli.Context) error {
+				return cmd.PrintTokenLineage(c.Args().First(), configBackend)
+			},
+		},
+		{
+			Name:      "revokelineage",
+			Usage:     "revoke every token descending from the same authorization code or refresh token as a compromised token",
+			ArgsUsage: "token",
+			Action: func(c *cli.Context) error {
+				return cmd.RevokeTokenLineage(c.Args().First(), configBackend)
+			},
+		},
 		{
 			Name:  "runserver",
 			Usage: "run web server",
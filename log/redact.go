@@ -0,0 +1,65 @@
+package log
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// SensitiveParams lists request parameters whose values must never be logged
+var SensitiveParams = []string{
+	"access_token",
+	"refresh_token",
+	"id_token",
+	"token",
+	"code",
+	"code_verifier",
+	"client_secret",
+	"client_assertion",
+	"assertion",
+	"password",
+}
+
+// Secrets shorter than this are redacted without revealing a prefix
+const minPrefixedLength = 16
+
+// Redact returns a loggable representation of a secret which
+// only reveals a short prefix and the length of the original value
+func Redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	if len(secret) < minPrefixedLength {
+		return fmt.Sprintf("[REDACTED len=%d]", len(secret))
+	}
+	return fmt.Sprintf("%s...[REDACTED len=%d]", secret[:4], len(secret))
+}
+
+// RedactURL returns the URL path and query string with sensitive parameters redacted
+func RedactURL(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.Path
+	}
+	pairs := strings.Split(u.RawQuery, "&")
+	for i, pair := range pairs {
+		parts := strings.SplitN(pair, "=", 2)
+		key, err := url.QueryUnescape(parts[0])
+		if err != nil || isSensitive(key) {
+			val := ""
+			if len(parts) == 2 {
+				val, _ = url.QueryUnescape(parts[1])
+			}
+			pairs[i] = parts[0] + "=" + Redact(val)
+		}
+	}
+	return u.Path + "?" + strings.Join(pairs, "&")
+}
+
+func isSensitive(key string) bool {
+	for _, param := range SensitiveParams {
+		if strings.EqualFold(key, param) {
+			return true
+		}
+	}
+	return false
+}
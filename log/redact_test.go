@@ -0,0 +1,37 @@
+package log_test
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/RichardKnop/go-oauth2-server/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedact(t *testing.T) {
+	assert.Equal(t, "", log.Redact(""))
+	assert.Equal(t, "[REDACTED len=13]", log.Redact("test_password"))
+	assert.Equal(
+		t,
+		"00cc...[REDACTED len=36]",
+		log.Redact("00ccd40e-72ca-4e79-a4b6-67c95e2e3f1c"),
+	)
+}
+
+func TestRedactURL(t *testing.T) {
+	u, err := url.Parse("/v1/oauth/tokens?grant_type=password&username=test%40user&password=test_password&Code=00ccd40e-72ca-4e79-a4b6-67c95e2e3f1c")
+	assert.NoError(t, err)
+
+	redacted := log.RedactURL(u)
+	assert.True(t, strings.HasPrefix(redacted, "/v1/oauth/tokens?"))
+	assert.NotContains(t, redacted, "test_password")
+	assert.NotContains(t, redacted, "00ccd40e-72ca-4e79-a4b6-67c95e2e3f1c")
+	assert.Contains(t, redacted, "password=[REDACTED len=13]")
+	assert.Contains(t, redacted, "grant_type=password")
+	assert.Contains(t, redacted, "username=test%40user")
+
+	u, err = url.Parse("/v1/oauth/tokens")
+	assert.NoError(t, err)
+	assert.Equal(t, "/v1/oauth/tokens", log.RedactURL(u))
+}
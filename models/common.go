@@ -1,6 +1,8 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"time"
 )
 
@@ -28,3 +30,11 @@ type EmailTokenModel struct {
 	EmailSentAt *time.Time
 	ExpiresAt   time.Time `sql:"index;not null"`
 }
+
+// HashToken returns the hex encoded SHA-256 hash tokens, device secrets,
+// API keys and recovery codes are stored as, they are random so a plain
+// hash cannot be reversed
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,18 +1,270 @@
 package models
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/log"
 	"github.com/RichardKnop/go-oauth2-server/util/migrations"
 	"github.com/jinzhu/gorm"
 )
 
+const (
+	// hashTokensBatchSize is the number of tokens hashed per query
+	hashTokensBatchSize = 1000
+)
+
 var (
+	// ErrSchemaVersionMismatch ...
+	ErrSchemaVersionMismatch = errors.New("Database schema version does not match, run the migrate command")
+
 	list = []migrations.MigrationStage{
 		{
 			Name:     "initial",
 			Function: migrate0001,
 		},
+		{
+			Name:     "access_token_audience",
+			Function: migrate0002,
+		},
+		{
+			Name:     "client_native_app",
+			Function: migrate0003,
+		},
+		{
+			Name:     "client_name",
+			Function: migrate0004,
+		},
+		{
+			Name:     "user_failed_login_attempts",
+			Function: migrate0005,
+		},
+		{
+			Name:     "authorization_code_exchanged_at",
+			Function: migrate0006,
+		},
+		{
+			Name:     "scope_implies",
+			Function: migrate0007,
+		},
+		{
+			Name:     "scope_audience",
+			Function: migrate0008,
+		},
+		{
+			Name:     "access_token_audience_not_null",
+			Function: migrate0009,
+		},
+		{
+			Name:     "mfa",
+			Function: migrate0010,
+		},
+		{
+			Name:     "device_secrets",
+			Function: migrate0011,
+		},
+		{
+			Name:     "recovery_codes",
+			Function: migrate0012,
+		},
+		{
+			Name:     "sessions",
+			Function: migrate0013,
+		},
+		{
+			Name:     "scope_indexes",
+			Function: migrate0014,
+		},
+		{
+			Name:     "consents",
+			Function: migrate0015,
+		},
+		{
+			Name:     "api_keys",
+			Function: migrate0016,
+		},
+		{
+			Name:     "refresh_token_grant_types",
+			Function: migrate0017,
+		},
+		{
+			Name:     "pkce",
+			Function: migrate0018,
+		},
+		{
+			Name:     "device_codes",
+			Function: migrate0019,
+		},
+		{
+			Name:     "refresh_token_grant_type_length",
+			Function: migrate0020,
+		},
+		{
+			Name:     "assertion_keys",
+			Function: migrate0021,
+		},
+		{
+			Name:     "access_token_actors",
+			Function: migrate0022,
+		},
+		{
+			Name:     "client_grant_types",
+			Function: migrate0023,
+		},
+		{
+			Name:     "refresh_token_rotation",
+			Function: migrate0024,
+		},
+		{
+			Name:     "pushed_authorization_requests",
+			Function: migrate0025,
+		},
+		{
+			Name:     "backchannel_authentication",
+			Function: migrate0026,
+		},
+		{
+			Name:     "client_response_types",
+			Function: migrate0027,
+		},
+		{
+			Name:     "authorization_details",
+			Function: migrate0028,
+		},
+		{
+			Name:     "client_tls_auth",
+			Function: migrate0029,
+		},
+		{
+			Name:     "saml_identity_providers",
+			Function: migrate0030,
+		},
+		{
+			Name:     "signing_keys",
+			Function: migrate0031,
+		},
+		{
+			Name:     "authorization_code_nonce",
+			Function: migrate0032,
+		},
+		{
+			Name:     "client_token_format",
+			Function: migrate0033,
+		},
+		{
+			Name:     "certificate_bound_access_tokens",
+			Function: migrate0034,
+		},
+		{
+			Name:     "dpop_bound_tokens",
+			Function: migrate0035,
+		},
+		{
+			Name:     "hash_tokens",
+			Function: migrate0036,
+		},
+		{
+			Name:     "access_token_claims",
+			Function: migrate0037,
+		},
+		{
+			Name:     "token_lineage",
+			Function: migrate0038,
+		},
+		{
+			Name:     "client_registration",
+			Function: migrate0039,
+		},
+		{
+			Name:     "client_disabled",
+			Function: migrate0040,
+		},
+		{
+			Name:     "redirect_uris",
+			Function: migrate0041,
+		},
+		{
+			Name:     "client_scopes",
+			Function: migrate0042,
+		},
+		{
+			Name:     "client_token_lifetimes",
+			Function: migrate0043,
+		},
+		{
+			Name:     "client_secondary_secret",
+			Function: migrate0044,
+		},
+		{
+			Name:     "client_type",
+			Function: migrate0045,
+		},
+		{
+			Name:     "client_assertions",
+			Function: migrate0046,
+		},
+		{
+			Name:     "tls_client_auth_san",
+			Function: migrate0047,
+		},
+		{
+			Name:     "first_party_clients",
+			Function: migrate0048,
+		},
+		{
+			Name:     "client_token_rate_limit",
+			Function: migrate0049,
+		},
+		{
+			Name:     "client_subject_type",
+			Function: migrate0050,
+		},
+		{
+			Name:     "password_resets",
+			Function: migrate0051,
+		},
+		{
+			Name:     "email_verification",
+			Function: migrate0052,
+		},
+		{
+			Name:     "webauthn",
+			Function: migrate0053,
+		},
+		{
+			Name:     "login_failures",
+			Function: migrate0054,
+		},
+		{
+			Name:     "password_hashing",
+			Function: migrate0055,
+		},
+		{
+			Name:     "federation",
+			Function: migrate0056,
+		},
+		{
+			Name:     "scim",
+			Function: migrate0057,
+		},
+		{
+			Name:     "role_scopes",
+			Function: migrate0058,
+		},
+		{
+			Name:     "user_attributes",
+			Function: migrate0059,
+		},
+		{
+			Name:     "user_totp_last_step",
+			Function: migrate0060,
+		},
+		{
+			Name:     "device_secret_hashes",
+			Function: migrate0061,
+		},
 	}
 )
 
@@ -21,6 +273,71 @@ func MigrateAll(db *gorm.DB) error {
 	return migrations.Migrate(db, list)
 }
 
+// VerifyAll checks all migrations have already been executed
+func VerifyAll(db *gorm.DB) error {
+	pending, err := migrations.Pending(db, list)
+	if err != nil {
+		return fmt.Errorf("Error reading migrations table: %s", err)
+	}
+	if len(pending) > 0 {
+		log.ERROR.Printf("Pending migrations: %s", strings.Join(pending, ", "))
+		return ErrSchemaVersionMismatch
+	}
+	return nil
+}
+
+// AutoMigrateAll creates missing tables and columns for all models,
+// it never drops or alters existing columns
+func AutoMigrateAll(db *gorm.DB) error {
+	return db.AutoMigrate(
+		new(OauthClient),
+		new(OauthScope),
+		new(OauthRole),
+		new(OauthUser),
+		new(OauthRefreshToken),
+		new(OauthAccessToken),
+		new(OauthAuthorizationCode),
+		new(OauthDeviceSecret),
+		new(OauthRecoveryCode),
+		new(OauthSession),
+		new(OauthConsent),
+		new(OauthAPIKey),
+		new(OauthDeviceCode),
+		new(OauthAssertionKey),
+		new(OauthPushedRequest),
+		new(OauthBackchannelRequest),
+		new(OauthSAMLIdentityProvider),
+		new(OauthSigningKey),
+		new(OauthRedirectURI),
+		new(OauthClientScope),
+		new(OauthClientAssertion),
+		new(OauthPasswordReset),
+		new(OauthEmailVerification),
+		new(OauthWebAuthnCredential),
+		new(OauthWebAuthnChallenge),
+		new(OauthLoginFailure),
+		new(OauthFederatedIdentity),
+		new(OauthFederationState),
+		new(OauthGroup),
+		new(OauthGroupMember),
+		new(OauthRoleScope),
+		new(OauthUserRole),
+		new(OauthUserAttribute),
+	).Error
+}
+
+// InitSchema prepares the database schema on startup depending on the migration mode
+func InitSchema(db *gorm.DB, mode string) error {
+	switch mode {
+	case config.MigrationModeAuto:
+		return AutoMigrateAll(db)
+	case config.MigrationModeExplicit, "":
+		return VerifyAll(db)
+	default:
+		return fmt.Errorf("Migration mode %s not supported", mode)
+	}
+}
+
 func migrate0001(db *gorm.DB, name string) error {
 	//-------------
 	// OAUTH models
@@ -107,3 +424,961 @@ func migrate0001(db *gorm.DB, name string) error {
 
 	return nil
 }
+
+func migrate0002(db *gorm.DB, name string) error {
+	// Add the audience column to oauth_access_tokens
+	if err := db.AutoMigrate(new(OauthAccessToken)).Error; err != nil {
+		return fmt.Errorf("Error migrating oauth_access_tokens table: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0003(db *gorm.DB, name string) error {
+	// Add the native_app column to oauth_clients
+	if err := db.AutoMigrate(new(OauthClient)).Error; err != nil {
+		return fmt.Errorf("Error migrating oauth_clients table: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0004(db *gorm.DB, name string) error {
+	// Add the name column to oauth_clients
+	if err := db.AutoMigrate(new(OauthClient)).Error; err != nil {
+		return fmt.Errorf("Error migrating oauth_clients table: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0005(db *gorm.DB, name string) error {
+	// Add the failed_login_attempts column to oauth_users
+	if err := db.AutoMigrate(new(OauthUser)).Error; err != nil {
+		return fmt.Errorf("Error migrating oauth_users table: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0006(db *gorm.DB, name string) error {
+	// Add the exchanged_at column to oauth_authorization_codes
+	if err := db.AutoMigrate(new(OauthAuthorizationCode)).Error; err != nil {
+		return fmt.Errorf("Error migrating oauth_authorization_codes table: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0007(db *gorm.DB, name string) error {
+	// Add the implies column to oauth_scopes
+	if err := db.AutoMigrate(new(OauthScope)).Error; err != nil {
+		return fmt.Errorf("Error migrating oauth_scopes table: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0008(db *gorm.DB, name string) error {
+	// Add the audience column to oauth_scopes
+	if err := db.AutoMigrate(new(OauthScope)).Error; err != nil {
+		return fmt.Errorf("Error migrating oauth_scopes table: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0009(db *gorm.DB, name string) error {
+	// Tokens issued before the audience column was added have no audience
+	err := db.Model(new(OauthAccessToken)).Unscoped().Where("audience IS NULL").
+		UpdateColumn("audience", "").Error
+	if err != nil {
+		return fmt.Errorf("Error updating oauth_access_tokens table: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0010(db *gorm.DB, name string) error {
+	// Add the totp_secret column to oauth_users
+	if err := db.AutoMigrate(new(OauthUser)).Error; err != nil {
+		return fmt.Errorf("Error migrating oauth_users table: %s", err)
+	}
+	// Add the acr column to oauth_access_tokens
+	if err := db.AutoMigrate(new(OauthAccessToken)).Error; err != nil {
+		return fmt.Errorf("Error migrating oauth_access_tokens table: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0011(db *gorm.DB, name string) error {
+	// Create the oauth_device_secrets table
+	if err := db.CreateTable(new(OauthDeviceSecret)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_device_secrets table: %s", err)
+	}
+	err := db.Model(new(OauthDeviceSecret)).AddForeignKey(
+		"client_id", "oauth_clients(id)",
+		"RESTRICT", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_device_secrets.client_id for oauth_clients(id): %s", err)
+	}
+	err = db.Model(new(OauthDeviceSecret)).AddForeignKey(
+		"user_id", "oauth_users(id)",
+		"RESTRICT", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_device_secrets.user_id for oauth_users(id): %s", err)
+	}
+
+	return nil
+}
+
+func migrate0012(db *gorm.DB, name string) error {
+	// Create the oauth_recovery_codes table
+	if err := db.CreateTable(new(OauthRecoveryCode)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_recovery_codes table: %s", err)
+	}
+	err := db.Model(new(OauthRecoveryCode)).AddForeignKey(
+		"user_id", "oauth_users(id)",
+		"RESTRICT", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_recovery_codes.user_id for oauth_users(id): %s", err)
+	}
+
+	return nil
+}
+
+func migrate0013(db *gorm.DB, name string) error {
+	// Create the oauth_sessions table
+	if err := db.CreateTable(new(OauthSession)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_sessions table: %s", err)
+	}
+	err := db.Model(new(OauthSession)).AddForeignKey(
+		"user_id", "oauth_users(id)",
+		"RESTRICT", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_sessions.user_id for oauth_users(id): %s", err)
+	}
+
+	// Add session_id columns to tokens
+	if err := db.AutoMigrate(new(OauthAccessToken), new(OauthRefreshToken)).Error; err != nil {
+		return fmt.Errorf("Error adding session_id columns: %s", err)
+	}
+	err = db.Model(new(OauthAccessToken)).AddForeignKey(
+		"session_id", "oauth_sessions(id)",
+		"SET NULL", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_access_tokens.session_id for oauth_sessions(id): %s", err)
+	}
+	err = db.Model(new(OauthRefreshToken)).AddForeignKey(
+		"session_id", "oauth_sessions(id)",
+		"SET NULL", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_refresh_tokens.session_id for oauth_sessions(id): %s", err)
+	}
+
+	return nil
+}
+
+func migrate0014(db *gorm.DB, name string) error {
+	// Index default scopes, scope names are already unique
+	if err := db.AutoMigrate(new(OauthScope)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_scopes.is_default index: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0015(db *gorm.DB, name string) error {
+	// Create the oauth_consents table
+	if err := db.CreateTable(new(OauthConsent)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_consents table: %s", err)
+	}
+	err := db.Model(new(OauthConsent)).AddForeignKey(
+		"client_id", "oauth_clients(id)",
+		"RESTRICT", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_consents.client_id for oauth_clients(id): %s", err)
+	}
+	err = db.Model(new(OauthConsent)).AddForeignKey(
+		"user_id", "oauth_users(id)",
+		"RESTRICT", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_consents.user_id for oauth_users(id): %s", err)
+	}
+
+	return nil
+}
+
+func migrate0016(db *gorm.DB, name string) error {
+	// Create the oauth_api_keys table
+	if err := db.CreateTable(new(OauthAPIKey)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_api_keys table: %s", err)
+	}
+	err := db.Model(new(OauthAPIKey)).AddForeignKey(
+		"client_id", "oauth_clients(id)",
+		"RESTRICT", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_api_keys.client_id for oauth_clients(id): %s", err)
+	}
+	err = db.Model(new(OauthAPIKey)).AddForeignKey(
+		"user_id", "oauth_users(id)",
+		"RESTRICT", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_api_keys.user_id for oauth_users(id): %s", err)
+	}
+
+	return nil
+}
+
+func migrate0017(db *gorm.DB, name string) error {
+	// Add grant_type column to refresh tokens
+	if err := db.AutoMigrate(new(OauthRefreshToken)).Error; err != nil {
+		return fmt.Errorf("Error adding oauth_refresh_tokens.grant_type column: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0018(db *gorm.DB, name string) error {
+	// Add code challenge columns to authorization codes
+	if err := db.AutoMigrate(new(OauthAuthorizationCode)).Error; err != nil {
+		return fmt.Errorf("Error adding oauth_authorization_codes.code_challenge columns: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0019(db *gorm.DB, name string) error {
+	// Create the oauth_device_codes table
+	if err := db.CreateTable(new(OauthDeviceCode)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_device_codes table: %s", err)
+	}
+	err := db.Model(new(OauthDeviceCode)).AddForeignKey(
+		"client_id", "oauth_clients(id)",
+		"RESTRICT", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_device_codes.client_id for oauth_clients(id): %s", err)
+	}
+	err = db.Model(new(OauthDeviceCode)).AddForeignKey(
+		"user_id", "oauth_users(id)",
+		"RESTRICT", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_device_codes.user_id for oauth_users(id): %s", err)
+	}
+
+	return nil
+}
+
+func migrate0020(db *gorm.DB, name string) error {
+	// Widen grant_type to fit extension grant type URNs
+	err := db.Model(new(OauthRefreshToken)).ModifyColumn("grant_type", "varchar(60)").Error
+	if err != nil {
+		return fmt.Errorf("Error modifying oauth_refresh_tokens.grant_type column: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0021(db *gorm.DB, name string) error {
+	// Create the oauth_assertion_keys table
+	if err := db.CreateTable(new(OauthAssertionKey)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_assertion_keys table: %s", err)
+	}
+	err := db.Model(new(OauthAssertionKey)).AddForeignKey(
+		"client_id", "oauth_clients(id)",
+		"RESTRICT", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_assertion_keys.client_id for oauth_clients(id): %s", err)
+	}
+
+	return nil
+}
+
+func migrate0022(db *gorm.DB, name string) error {
+	// Add actor column to access tokens
+	if err := db.AutoMigrate(new(OauthAccessToken)).Error; err != nil {
+		return fmt.Errorf("Error adding oauth_access_tokens.actor column: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0023(db *gorm.DB, name string) error {
+	// Add allowed_grant_types column to clients
+	if err := db.AutoMigrate(new(OauthClient)).Error; err != nil {
+		return fmt.Errorf("Error adding oauth_clients.allowed_grant_types column: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0024(db *gorm.DB, name string) error {
+	// Add family_id and used_at columns to refresh tokens
+	if err := db.AutoMigrate(new(OauthRefreshToken)).Error; err != nil {
+		return fmt.Errorf("Error adding oauth_refresh_tokens rotation columns: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0025(db *gorm.DB, name string) error {
+	// Create the oauth_pushed_requests table
+	if err := db.CreateTable(new(OauthPushedRequest)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_pushed_requests table: %s", err)
+	}
+	err := db.Model(new(OauthPushedRequest)).AddForeignKey(
+		"client_id", "oauth_clients(id)",
+		"RESTRICT", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_pushed_requests.client_id for oauth_clients(id): %s", err)
+	}
+
+	return nil
+}
+
+func migrate0026(db *gorm.DB, name string) error {
+	// Add backchannel_notification_endpoint column to clients
+	if err := db.AutoMigrate(new(OauthClient)).Error; err != nil {
+		return fmt.Errorf("Error adding oauth_clients.backchannel_notification_endpoint column: %s", err)
+	}
+
+	// Create the oauth_backchannel_requests table
+	if err := db.CreateTable(new(OauthBackchannelRequest)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_backchannel_requests table: %s", err)
+	}
+	err := db.Model(new(OauthBackchannelRequest)).AddForeignKey(
+		"client_id", "oauth_clients(id)",
+		"RESTRICT", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_backchannel_requests.client_id for oauth_clients(id): %s", err)
+	}
+	err = db.Model(new(OauthBackchannelRequest)).AddForeignKey(
+		"user_id", "oauth_users(id)",
+		"RESTRICT", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_backchannel_requests.user_id for oauth_users(id): %s", err)
+	}
+
+	return nil
+}
+
+func migrate0027(db *gorm.DB, name string) error {
+	// Add allowed_response_types column to clients
+	if err := db.AutoMigrate(new(OauthClient)).Error; err != nil {
+		return fmt.Errorf("Error adding oauth_clients.allowed_response_types column: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0028(db *gorm.DB, name string) error {
+	// Add authorization_details columns to tokens and authorization codes
+	if err := db.AutoMigrate(new(OauthRefreshToken)).Error; err != nil {
+		return fmt.Errorf("Error adding oauth_refresh_tokens.authorization_details column: %s", err)
+	}
+	if err := db.AutoMigrate(new(OauthAccessToken)).Error; err != nil {
+		return fmt.Errorf("Error adding oauth_access_tokens.authorization_details column: %s", err)
+	}
+	if err := db.AutoMigrate(new(OauthAuthorizationCode)).Error; err != nil {
+		return fmt.Errorf("Error adding oauth_authorization_codes.authorization_details column: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0029(db *gorm.DB, name string) error {
+	// Add tls_client_auth_subject_dn column to clients
+	if err := db.AutoMigrate(new(OauthClient)).Error; err != nil {
+		return fmt.Errorf("Error adding oauth_clients.tls_client_auth_subject_dn column: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0030(db *gorm.DB, name string) error {
+	// Create the oauth_saml_identity_providers table
+	if err := db.CreateTable(new(OauthSAMLIdentityProvider)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_saml_identity_providers table: %s", err)
+	}
+	err := db.Model(new(OauthSAMLIdentityProvider)).AddForeignKey(
+		"client_id", "oauth_clients(id)",
+		"RESTRICT", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_saml_identity_providers.client_id for oauth_clients(id): %s", err)
+	}
+
+	return nil
+}
+
+func migrate0031(db *gorm.DB, name string) error {
+	// Create the oauth_signing_keys table
+	if err := db.CreateTable(new(OauthSigningKey)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_signing_keys table: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0032(db *gorm.DB, name string) error {
+	// Add nonce column to authorization codes
+	if err := db.AutoMigrate(new(OauthAuthorizationCode)).Error; err != nil {
+		return fmt.Errorf("Error adding oauth_authorization_codes.nonce column: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0033(db *gorm.DB, name string) error {
+	// Add token_format column to clients
+	if err := db.AutoMigrate(new(OauthClient)).Error; err != nil {
+		return fmt.Errorf("Error adding oauth_clients.token_format column: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0034(db *gorm.DB, name string) error {
+	// Add certificate_thumbprint column to access tokens
+	if err := db.AutoMigrate(new(OauthAccessToken)).Error; err != nil {
+		return fmt.Errorf("Error adding oauth_access_tokens.certificate_thumbprint column: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0035(db *gorm.DB, name string) error {
+	// Add jwk_thumbprint column to access tokens
+	if err := db.AutoMigrate(new(OauthAccessToken)).Error; err != nil {
+		return fmt.Errorf("Error adding oauth_access_tokens.jwk_thumbprint column: %s", err)
+	}
+
+	// Add jwk_thumbprint column to refresh tokens
+	if err := db.AutoMigrate(new(OauthRefreshToken)).Error; err != nil {
+		return fmt.Errorf("Error adding oauth_refresh_tokens.jwk_thumbprint column: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0036(db *gorm.DB, name string) error {
+	// Widen the token columns to fit hashes and hash the existing tokens
+	for _, model := range []interface{}{new(OauthAccessToken), new(OauthRefreshToken)} {
+		tableName := db.NewScope(model).TableName()
+		if err := db.Model(model).ModifyColumn("token", "varchar(64)").Error; err != nil {
+			return fmt.Errorf("Error modifying %s.token column: %s", tableName, err)
+		}
+		if err := hashTokens(db, tableName, "token"); err != nil {
+			return fmt.Errorf("Error hashing %s.token column: %s", tableName, err)
+		}
+	}
+
+	return nil
+}
+
+// hashTokens replaces the plain tokens stored in the column with their hashes.
+// Tokens are hashed in batches within one transaction, tokens which already
+// are hashes are skipped so the migration can be run again after a failure.
+func hashTokens(db *gorm.DB, tableName, column string) error {
+	// Begin a transaction
+	tx := db.Begin()
+
+	for lastID := ""; ; {
+		rows, err := tx.Table(tableName).Select("id, "+column).
+			Where("id > ?", lastID).Order("id").Limit(hashTokensBatchSize).Rows()
+		if err != nil {
+			tx.Rollback() // rollback the transaction
+			return err
+		}
+		tokens := make(map[string]string)
+		var count int
+		for rows.Next() {
+			var id, token string
+			if err := rows.Scan(&id, &token); err != nil {
+				rows.Close()
+				tx.Rollback() // rollback the transaction
+				return err
+			}
+			count++
+			lastID = id
+			if !isTokenHash(token) {
+				tokens[id] = token
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			tx.Rollback() // rollback the transaction
+			return err
+		}
+
+		for id, token := range tokens {
+			err := tx.Table(tableName).Where("id = ?", id).UpdateColumn(column, HashToken(token)).Error
+			if err != nil {
+				tx.Rollback() // rollback the transaction
+				return err
+			}
+		}
+
+		if count < hashTokensBatchSize {
+			break
+		}
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	return nil
+}
+
+// isTokenHash returns true if the token is a hex encoded SHA-256 hash
+func isTokenHash(token string) bool {
+	if len(token) != 64 {
+		return false
+	}
+	for _, c := range token {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func migrate0037(db *gorm.DB, name string) error {
+	// Add claims column to access tokens
+	if err := db.AutoMigrate(new(OauthAccessToken)).Error; err != nil {
+		return fmt.Errorf("Error adding oauth_access_tokens.claims column: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0038(db *gorm.DB, name string) error {
+	// Add lineage_id and refresh_token_id columns to access tokens
+	if err := db.AutoMigrate(new(OauthAccessToken)).Error; err != nil {
+		return fmt.Errorf("Error adding oauth_access_tokens.lineage_id column: %s", err)
+	}
+
+	// Add lineage_id column to refresh tokens
+	if err := db.AutoMigrate(new(OauthRefreshToken)).Error; err != nil {
+		return fmt.Errorf("Error adding oauth_refresh_tokens.lineage_id column: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0039(db *gorm.DB, name string) error {
+	// Add scope, token_endpoint_auth_method and registration_access_token columns to clients
+	if err := db.AutoMigrate(new(OauthClient)).Error; err != nil {
+		return fmt.Errorf("Error adding oauth_clients.scope column: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0040(db *gorm.DB, name string) error {
+	// Add disabled column to clients
+	if err := db.AutoMigrate(new(OauthClient)).Error; err != nil {
+		return fmt.Errorf("Error adding oauth_clients.disabled column: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0041(db *gorm.DB, name string) error {
+	// Create the oauth_redirect_uris table
+	if err := db.CreateTable(new(OauthRedirectURI)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_redirect_uris table: %s", err)
+	}
+	err := db.Model(new(OauthRedirectURI)).AddForeignKey(
+		"client_id", "oauth_clients(id)",
+		"RESTRICT", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_redirect_uris.client_id for oauth_clients(id): %s", err)
+	}
+
+	return nil
+}
+
+func migrate0042(db *gorm.DB, name string) error {
+	// Create the oauth_client_scopes table
+	if err := db.CreateTable(new(OauthClientScope)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_client_scopes table: %s", err)
+	}
+	err := db.Model(new(OauthClientScope)).AddForeignKey(
+		"client_id", "oauth_clients(id)",
+		"RESTRICT", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_client_scopes.client_id for oauth_clients(id): %s", err)
+	}
+	err = db.Model(new(OauthClientScope)).AddForeignKey(
+		"scope_id", "oauth_scopes(id)",
+		"RESTRICT", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_client_scopes.scope_id for oauth_scopes(id): %s", err)
+	}
+
+	return nil
+}
+
+func migrate0043(db *gorm.DB, name string) error {
+	// Add access_token_lifetime and refresh_token_lifetime columns to clients
+	if err := db.AutoMigrate(new(OauthClient)).Error; err != nil {
+		return fmt.Errorf("Error adding oauth_clients token lifetime columns: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0044(db *gorm.DB, name string) error {
+	// Add secondary_secret and secondary_secret_expires_at columns to clients
+	if err := db.AutoMigrate(new(OauthClient)).Error; err != nil {
+		return fmt.Errorf("Error adding oauth_clients secondary secret columns: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0045(db *gorm.DB, name string) error {
+	// Add client_type column to clients
+	if err := db.AutoMigrate(new(OauthClient)).Error; err != nil {
+		return fmt.Errorf("Error adding oauth_clients.client_type column: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0046(db *gorm.DB, name string) error {
+	// Create the oauth_client_assertions table
+	if err := db.CreateTable(new(OauthClientAssertion)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_client_assertions table: %s", err)
+	}
+	err := db.Model(new(OauthClientAssertion)).AddForeignKey(
+		"client_id", "oauth_clients(id)",
+		"RESTRICT", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_client_assertions.client_id for oauth_clients(id): %s", err)
+	}
+
+	return nil
+}
+
+func migrate0047(db *gorm.DB, name string) error {
+	// Add tls_client_auth_san column to clients
+	if err := db.AutoMigrate(new(OauthClient)).Error; err != nil {
+		return fmt.Errorf("Error adding oauth_clients.tls_client_auth_san column: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0048(db *gorm.DB, name string) error {
+	// Add first_party column to clients
+	if err := db.AutoMigrate(new(OauthClient)).Error; err != nil {
+		return fmt.Errorf("Error adding oauth_clients.first_party column: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0049(db *gorm.DB, name string) error {
+	// Add token_rate_limit column to clients
+	if err := db.AutoMigrate(new(OauthClient)).Error; err != nil {
+		return fmt.Errorf("Error adding oauth_clients.token_rate_limit column: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0050(db *gorm.DB, name string) error {
+	// Add subject_type and sector_identifier_uri columns to clients
+	if err := db.AutoMigrate(new(OauthClient)).Error; err != nil {
+		return fmt.Errorf("Error adding oauth_clients.subject_type column: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0051(db *gorm.DB, name string) error {
+	// Create the oauth_password_resets table
+	if err := db.CreateTable(new(OauthPasswordReset)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_password_resets table: %s", err)
+	}
+	err := db.Model(new(OauthPasswordReset)).AddForeignKey(
+		"user_id", "oauth_users(id)",
+		"RESTRICT", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_password_resets.user_id for oauth_users(id): %s", err)
+	}
+
+	return nil
+}
+
+func migrate0052(db *gorm.DB, name string) error {
+	// Add email_verified column to users
+	if err := db.AutoMigrate(new(OauthUser)).Error; err != nil {
+		return fmt.Errorf("Error adding oauth_users.email_verified column: %s", err)
+	}
+
+	// Create the oauth_email_verifications table
+	if err := db.CreateTable(new(OauthEmailVerification)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_email_verifications table: %s", err)
+	}
+	err := db.Model(new(OauthEmailVerification)).AddForeignKey(
+		"user_id", "oauth_users(id)",
+		"RESTRICT", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_email_verifications.user_id for oauth_users(id): %s", err)
+	}
+
+	return nil
+}
+
+func migrate0053(db *gorm.DB, name string) error {
+	// Create the oauth_webauthn_credentials table
+	if err := db.CreateTable(new(OauthWebAuthnCredential)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_webauthn_credentials table: %s", err)
+	}
+	err := db.Model(new(OauthWebAuthnCredential)).AddForeignKey(
+		"user_id", "oauth_users(id)",
+		"RESTRICT", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_webauthn_credentials.user_id for oauth_users(id): %s", err)
+	}
+
+	// Create the oauth_webauthn_challenges table
+	if err := db.CreateTable(new(OauthWebAuthnChallenge)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_webauthn_challenges table: %s", err)
+	}
+	err = db.Model(new(OauthWebAuthnChallenge)).AddForeignKey(
+		"user_id", "oauth_users(id)",
+		"RESTRICT", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_webauthn_challenges.user_id for oauth_users(id): %s", err)
+	}
+
+	return nil
+}
+
+func migrate0054(db *gorm.DB, name string) error {
+	// Create the oauth_login_failures table
+	if err := db.CreateTable(new(OauthLoginFailure)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_login_failures table: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0055(db *gorm.DB, name string) error {
+	// Widen the password column to fit Argon2id and scrypt hashes
+	err := db.Model(new(OauthUser)).ModifyColumn("password", "varchar(255)").Error
+	if err != nil {
+		return fmt.Errorf("Error modifying oauth_users.password column: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0056(db *gorm.DB, name string) error {
+	// Create the oauth_federated_identities table
+	if err := db.CreateTable(new(OauthFederatedIdentity)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_federated_identities table: %s", err)
+	}
+	err := db.Model(new(OauthFederatedIdentity)).AddForeignKey(
+		"user_id", "oauth_users(id)",
+		"RESTRICT", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_federated_identities.user_id for oauth_users(id): %s", err)
+	}
+
+	// Create the oauth_federation_states table
+	if err := db.CreateTable(new(OauthFederationState)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_federation_states table: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0057(db *gorm.DB, name string) error {
+	// Add disabled and external_id columns to users
+	if err := db.AutoMigrate(new(OauthUser)).Error; err != nil {
+		return fmt.Errorf("Error adding oauth_users columns: %s", err)
+	}
+
+	// Create the oauth_groups table
+	if err := db.CreateTable(new(OauthGroup)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_groups table: %s", err)
+	}
+
+	// Create the oauth_group_members table
+	if err := db.CreateTable(new(OauthGroupMember)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_group_members table: %s", err)
+	}
+	err := db.Model(new(OauthGroupMember)).AddForeignKey(
+		"group_id", "oauth_groups(id)",
+		"RESTRICT", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_group_members.group_id for oauth_groups(id): %s", err)
+	}
+	err = db.Model(new(OauthGroupMember)).AddForeignKey(
+		"user_id", "oauth_users(id)",
+		"RESTRICT", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_group_members.user_id for oauth_users(id): %s", err)
+	}
+
+	return nil
+}
+
+func migrate0058(db *gorm.DB, name string) error {
+	// Create the oauth_role_scopes table
+	if err := db.CreateTable(new(OauthRoleScope)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_role_scopes table: %s", err)
+	}
+	err := db.Model(new(OauthRoleScope)).AddForeignKey(
+		"role_id", "oauth_roles(id)",
+		"RESTRICT", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_role_scopes.role_id for oauth_roles(id): %s", err)
+	}
+	err = db.Model(new(OauthRoleScope)).AddForeignKey(
+		"scope_id", "oauth_scopes(id)",
+		"RESTRICT", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_role_scopes.scope_id for oauth_scopes(id): %s", err)
+	}
+
+	// Create the oauth_user_roles table
+	if err := db.CreateTable(new(OauthUserRole)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_user_roles table: %s", err)
+	}
+	err = db.Model(new(OauthUserRole)).AddForeignKey(
+		"user_id", "oauth_users(id)",
+		"RESTRICT", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_user_roles.user_id for oauth_users(id): %s", err)
+	}
+	err = db.Model(new(OauthUserRole)).AddForeignKey(
+		"role_id", "oauth_roles(id)",
+		"RESTRICT", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_user_roles.role_id for oauth_roles(id): %s", err)
+	}
+
+	return nil
+}
+
+func migrate0059(db *gorm.DB, name string) error {
+	// Create the oauth_user_attributes table
+	if err := db.CreateTable(new(OauthUserAttribute)).Error; err != nil {
+		return fmt.Errorf("Error creating oauth_user_attributes table: %s", err)
+	}
+	err := db.Model(new(OauthUserAttribute)).AddForeignKey(
+		"user_id", "oauth_users(id)",
+		"RESTRICT", "RESTRICT",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating foreign key on "+
+			"oauth_user_attributes.user_id for oauth_users(id): %s", err)
+	}
+	// A user has one value of each attribute
+	err = db.Model(new(OauthUserAttribute)).AddUniqueIndex(
+		"idx_oauth_user_attributes_user_id_name",
+		"user_id", "name",
+	).Error
+	if err != nil {
+		return fmt.Errorf("Error creating unique index on "+
+			"oauth_user_attributes(user_id, name): %s", err)
+	}
+
+	return nil
+}
+
+func migrate0060(db *gorm.DB, name string) error {
+	// Add the totp_last_step column to oauth_users
+	if err := db.AutoMigrate(new(OauthUser)).Error; err != nil {
+		return fmt.Errorf("Error migrating oauth_users table: %s", err)
+	}
+
+	return nil
+}
+
+func migrate0061(db *gorm.DB, name string) error {
+	// Widen the secret column to fit hashes and hash the existing secrets
+	model := new(OauthDeviceSecret)
+	tableName := db.NewScope(model).TableName()
+	if err := db.Model(model).ModifyColumn("secret", "varchar(64)").Error; err != nil {
+		return fmt.Errorf("Error modifying %s.secret column: %s", tableName, err)
+	}
+	if err := hashTokens(db, tableName, "secret"); err != nil {
+		return fmt.Errorf("Error hashing %s.secret column: %s", tableName, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/test-util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInitSchemaExplicitModeMismatch(t *testing.T) {
+	// Only the migrations table exists, no migrations have been run
+	db, err := testutil.CreateTestDatabase("/tmp/models_testdb.sqlite", nil, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+
+	// The server should refuse to start in explicit mode
+	assert.Equal(
+		t,
+		models.ErrSchemaVersionMismatch,
+		models.InitSchema(db, config.MigrationModeExplicit),
+	)
+
+	// An auto-migration does not mark migrations as run
+	assert.NoError(t, models.InitSchema(db, config.MigrationModeAuto))
+	assert.True(t, db.HasTable(new(models.OauthAccessToken)))
+	assert.Equal(
+		t,
+		models.ErrSchemaVersionMismatch,
+		models.InitSchema(db, config.MigrationModeExplicit),
+	)
+}
+
+func TestInitSchemaModeNotSupported(t *testing.T) {
+	db, err := testutil.CreateTestDatabase("/tmp/models_testdb.sqlite", nil, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+
+	err = models.InitSchema(db, "bogus")
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "Migration mode bogus not supported", err.Error())
+	}
+}
@@ -13,8 +13,63 @@ import (
 type OauthClient struct {
 	MyGormModel
 	Key         string         `sql:"type:varchar(254);unique;not null"`
+	Name        sql.NullString `sql:"type:varchar(200)"`
 	Secret      string         `sql:"type:varchar(60);not null"`
 	RedirectURI sql.NullString `sql:"type:varchar(200)"`
+	NativeApp   bool           `sql:"default:false"`
+	// AllowedGrantTypes is a space delimited list of grant types
+	// the client can use, it can use all of them when empty
+	AllowedGrantTypes string `sql:"type:varchar(500);not null;default:''"`
+	// BackchannelNotificationEndpoint is pinged when a backchannel authentication
+	// request has been decided, the client polls the token endpoint when empty
+	BackchannelNotificationEndpoint string `sql:"type:varchar(200);not null;default:''"`
+	// AllowedResponseTypes is a comma delimited list of response types the
+	// client can use at the authorization endpoint, all of them when empty
+	AllowedResponseTypes string `sql:"type:varchar(200);not null;default:''"`
+	// TLSClientAuthSubjectDN is the subject of the TLS client certificates
+	// the client can authenticate with, it cannot use them when empty
+	TLSClientAuthSubjectDN string `sql:"type:varchar(500);not null;default:''"`
+	// TLSClientAuthSAN is a subject alternative name of the TLS client
+	// certificates the client can authenticate with, as type:value where the
+	// type is dns, uri, ip or email, it cannot use them when empty
+	TLSClientAuthSAN string `sql:"type:varchar(500);not null;default:''"`
+	// TokenFormat is either opaque or jwt, the client is issued
+	// access tokens of the configured format when empty
+	TokenFormat string `sql:"type:varchar(10);not null;default:''"`
+	// Scope is a space delimited list of the scopes the client
+	// can be granted, it can be granted any scope when empty
+	Scope string `sql:"type:varchar(500);not null;default:''"`
+	// TokenEndpointAuthMethod is the only method the client can
+	// authenticate with, it can use any of them when empty
+	TokenEndpointAuthMethod string `sql:"type:varchar(40);not null;default:''"`
+	// RegistrationAccessToken is the hash of the token a dynamically
+	// registered client reads its registration with
+	RegistrationAccessToken string `sql:"type:varchar(64);not null;default:''"`
+	// Disabled clients cannot authenticate or be used to log in
+	Disabled bool `sql:"not null;default:false"`
+	// FirstParty clients are our own apps, users are not asked to consent to them
+	FirstParty bool `sql:"not null;default:false"`
+	// TokenRateLimit caps the requests per minute the client can send
+	// to the token endpoint, it is not limited when zero
+	TokenRateLimit int `sql:"not null;default:0"`
+	// AccessTokenLifetime and RefreshTokenLifetime override the configured
+	// lifetimes of the tokens issued to the client when set
+	AccessTokenLifetime  sql.NullInt64
+	RefreshTokenLifetime sql.NullInt64
+	// SecondarySecret is the hash of a second secret the client can
+	// authenticate with while its secret is rolled, it expires at
+	// SecondarySecretExpiresAt unless that is empty
+	SecondarySecret          string `sql:"type:varchar(60);not null;default:''"`
+	SecondarySecretExpiresAt *time.Time
+	// ClientType is either confidential or public, public clients
+	// cannot keep a secret and identify themselves by key only
+	ClientType string `sql:"type:varchar(20);not null;default:'confidential'"`
+	// SubjectType is either public or pairwise, pairwise clients identify
+	// users by subject identifiers of their own
+	SubjectType string `sql:"type:varchar(20);not null;default:'public'"`
+	// SectorIdentifierURI groups pairwise clients which identify users by
+	// the same subject identifiers, each client is a sector of its own when empty
+	SectorIdentifierURI string `sql:"type:varchar(500);not null;default:''"`
 }
 
 // TableName specifies table name
@@ -27,7 +82,11 @@ type OauthScope struct {
 	MyGormModel
 	Scope       string `sql:"type:varchar(200);unique;not null"`
 	Description sql.NullString
-	IsDefault   bool `sql:"default:false"`
+	IsDefault   bool `sql:"default:false;index"`
+	// Implies is a space delimited list of scopes implied by this scope
+	Implies string `sql:"type:varchar(200);not null;default:''"`
+	// Audience restricts tokens carrying this scope to a resource server
+	Audience string `sql:"type:varchar(200);not null;default:''"`
 }
 
 // TableName specifies table name
@@ -53,7 +112,20 @@ type OauthUser struct {
 	RoleID   sql.NullString `sql:"type:varchar(20);index;not null"`
 	Role     *OauthRole
 	Username string         `sql:"type:varchar(254);unique;not null"`
-	Password sql.NullString `sql:"type:varchar(60)"`
+	Password sql.NullString `sql:"type:varchar(255)"`
+	// TOTPSecret is the base32 encoded secret of the user's authenticator
+	TOTPSecret sql.NullString `sql:"type:varchar(64)"`
+	// TOTPLastStep is the time step of the last one-time password accepted,
+	// codes of the same or earlier steps cannot be used again
+	TOTPLastStep int64 `sql:"not null;default:0"`
+	// EmailVerified is set once the user confirmed their username,
+	// an email address, by following the link emailed to them
+	EmailVerified bool `sql:"not null;default:false"`
+	// Disabled users cannot be issued tokens
+	Disabled bool `sql:"not null;default:false"`
+	// ExternalID identifies the user in the system provisioning it,
+	// such as an HR system
+	ExternalID string `sql:"type:varchar(255);index;not null;default:''"`
 }
 
 // TableName specifies table name
@@ -64,13 +136,34 @@ func (u *OauthUser) TableName() string {
 // OauthRefreshToken ...
 type OauthRefreshToken struct {
 	MyGormModel
-	ClientID  sql.NullString `sql:"index;not null"`
-	UserID    sql.NullString `sql:"index"`
-	Client    *OauthClient
-	User      *OauthUser
-	Token     string    `sql:"type:varchar(40);unique;not null"`
+	ClientID sql.NullString `sql:"index;not null"`
+	UserID   sql.NullString `sql:"index"`
+	Client   *OauthClient
+	User     *OauthUser
+	// Token is only known when the token is issued, the database only
+	// keeps its hash so a dump of it does not hand out live tokens
+	Token     string    `sql:"-"`
+	TokenHash string    `gorm:"column:token" sql:"type:varchar(64);unique;not null"`
 	ExpiresAt time.Time `sql:"not null"`
 	Scope     string    `sql:"type:varchar(200);not null"`
+	// SessionID is the login the token was issued for
+	SessionID sql.NullString `sql:"index"`
+	// GrantType is the grant type the token was originally issued by
+	GrantType string `sql:"type:varchar(60);not null;default:''"`
+	// FamilyID is the ID of the first refresh token of a rotation chain,
+	// it is empty for the first refresh token itself
+	FamilyID string `sql:"type:varchar(36);index;not null;default:''"`
+	// UsedAt is set once the token has been rotated
+	UsedAt *time.Time
+	// AuthorizationDetails is the JSON array of authorization_details
+	// granted on top of the scope, see RFC 9396
+	AuthorizationDetails string `sql:"type:text;not null;default:''"`
+	// JWKThumbprint is the thumbprint of the DPoP key the token is bound to,
+	// see RFC 9449 section 5
+	JWKThumbprint string `sql:"type:varchar(64);not null;default:''"`
+	// LineageID is the ID of the authorization code or first refresh token
+	// the token descends from, it is empty for the first refresh token itself
+	LineageID string `sql:"type:varchar(36);index;not null;default:''"`
 }
 
 // TableName specifies table name
@@ -78,16 +171,52 @@ func (rt *OauthRefreshToken) TableName() string {
 	return "oauth_refresh_tokens"
 }
 
+// BeforeCreate stores the hash of the token instead of the token itself
+func (rt *OauthRefreshToken) BeforeCreate() error {
+	if rt.TokenHash == "" {
+		rt.TokenHash = HashToken(rt.Token)
+	}
+	return nil
+}
+
 // OauthAccessToken ...
 type OauthAccessToken struct {
 	MyGormModel
-	ClientID  sql.NullString `sql:"index;not null"`
-	UserID    sql.NullString `sql:"index"`
-	Client    *OauthClient
-	User      *OauthUser
-	Token     string    `sql:"type:varchar(40);unique;not null"`
+	ClientID sql.NullString `sql:"index;not null"`
+	UserID   sql.NullString `sql:"index"`
+	Client   *OauthClient
+	User     *OauthUser
+	// Token is only known when the token is issued, the database only
+	// keeps its hash so a dump of it does not hand out live tokens
+	Token     string    `sql:"-"`
+	TokenHash string    `gorm:"column:token" sql:"type:varchar(64);unique;not null"`
 	ExpiresAt time.Time `sql:"not null"`
 	Scope     string    `sql:"type:varchar(200);not null"`
+	Audience  string    `sql:"type:varchar(200);not null;default:''"`
+	// Acr is the authentication context class achieved by the user
+	Acr string `sql:"type:varchar(20);not null;default:''"`
+	// SessionID is the login the token was issued for
+	SessionID sql.NullString `sql:"index"`
+	// Actor lists the IDs of the clients a token obtained by token exchange
+	// was delegated to, space delimited and starting with the current actor
+	Actor string `sql:"type:varchar(200);not null;default:''"`
+	// AuthorizationDetails is the JSON array of authorization_details
+	// granted on top of the scope, see RFC 9396
+	AuthorizationDetails string `sql:"type:text;not null;default:''"`
+	// CertificateThumbprint is the SHA-256 thumbprint of the TLS client
+	// certificate the token is bound to, see RFC 8705 section 3
+	CertificateThumbprint string `sql:"type:varchar(64);not null;default:''"`
+	// JWKThumbprint is the thumbprint of the DPoP key the token is bound to,
+	// see RFC 9449 section 6
+	JWKThumbprint string `sql:"type:varchar(64);not null;default:''"`
+	// Claims is the JSON object of custom claims added by token claims
+	// providers, such as the tenant of the user
+	Claims string `sql:"type:text;not null;default:''"`
+	// LineageID is the ID of the authorization code or first refresh token
+	// the token descends from, it is empty for tokens issued on their own
+	LineageID string `sql:"type:varchar(36);index;not null;default:''"`
+	// RefreshTokenID is the refresh token the token was obtained with
+	RefreshTokenID string `sql:"type:varchar(36);not null;default:''"`
 }
 
 // TableName specifies table name
@@ -95,6 +224,14 @@ func (at *OauthAccessToken) TableName() string {
 	return "oauth_access_tokens"
 }
 
+// BeforeCreate stores the hash of the token instead of the token itself
+func (at *OauthAccessToken) BeforeCreate() error {
+	if at.TokenHash == "" {
+		at.TokenHash = HashToken(at.Token)
+	}
+	return nil
+}
+
 // OauthAuthorizationCode ...
 type OauthAuthorizationCode struct {
 	MyGormModel
@@ -106,6 +243,16 @@ type OauthAuthorizationCode struct {
 	RedirectURI sql.NullString `sql:"type:varchar(200)"`
 	ExpiresAt   time.Time      `sql:"not null"`
 	Scope       string         `sql:"type:varchar(200);not null"`
+	// ExchangedAt is set once the code has been exchanged for tokens
+	ExchangedAt *time.Time
+	// CodeChallenge and CodeChallengeMethod are set when the client uses PKCE
+	CodeChallenge       string `sql:"type:varchar(128);not null;default:''"`
+	CodeChallengeMethod string `sql:"type:varchar(10);not null;default:''"`
+	// AuthorizationDetails is the JSON array of authorization_details
+	// granted on top of the scope, see RFC 9396
+	AuthorizationDetails string `sql:"type:text;not null;default:''"`
+	// Nonce of the OpenID Connect authentication request, echoed in the ID token
+	Nonce string `sql:"type:varchar(255);not null;default:''"`
 }
 
 // TableName specifies table name
@@ -113,6 +260,441 @@ func (ac *OauthAuthorizationCode) TableName() string {
 	return "oauth_authorization_codes"
 }
 
+// OauthDeviceSecret lets a native app on the same device obtain fresh
+// tokens for the user without a full reauthentication
+type OauthDeviceSecret struct {
+	MyGormModel
+	ClientID   sql.NullString `sql:"index;not null"`
+	UserID     sql.NullString `sql:"index;not null"`
+	Client     *OauthClient
+	User       *OauthUser
+	Secret     string    `sql:"-"`
+	SecretHash string    `gorm:"column:secret" sql:"type:varchar(64);unique;not null"`
+	ExpiresAt  time.Time `sql:"not null"`
+	Scope      string    `sql:"type:varchar(200);not null"`
+}
+
+// TableName specifies table name
+func (ds *OauthDeviceSecret) TableName() string {
+	return "oauth_device_secrets"
+}
+
+// BeforeCreate stores the hash of the secret instead of the secret itself
+func (ds *OauthDeviceSecret) BeforeCreate() error {
+	if ds.SecretHash == "" {
+		ds.SecretHash = HashToken(ds.Secret)
+	}
+	return nil
+}
+
+// OauthSession records a login, tokens issued for it share its ID
+// so they can be revoked together when the session ends
+type OauthSession struct {
+	MyGormModel
+	UserID   sql.NullString `sql:"index;not null"`
+	User     *OauthUser
+	AuthTime time.Time `sql:"not null"`
+}
+
+// TableName specifies table name
+func (s *OauthSession) TableName() string {
+	return "oauth_sessions"
+}
+
+// OauthRecoveryCode is a single-use backup code a user can provide
+// in place of a one-time password, only its hash is stored
+type OauthRecoveryCode struct {
+	MyGormModel
+	UserID   sql.NullString `sql:"index;not null"`
+	User     *OauthUser
+	CodeHash string `sql:"type:varchar(64);unique;not null"`
+}
+
+// TableName specifies table name
+func (rc *OauthRecoveryCode) TableName() string {
+	return "oauth_recovery_codes"
+}
+
+// OauthConsent records the scope a user has approved for a client,
+// so the authorization form can be skipped next time
+type OauthConsent struct {
+	MyGormModel
+	ClientID sql.NullString `sql:"index;not null"`
+	UserID   sql.NullString `sql:"index;not null"`
+	Client   *OauthClient
+	User     *OauthUser
+	Scope    string `sql:"type:varchar(200);not null"`
+}
+
+// TableName specifies table name
+func (c *OauthConsent) TableName() string {
+	return "oauth_consents"
+}
+
+// OauthAPIKey is a long-lived key a client can exchange for short-lived
+// access tokens with the key's scope, only its hash is stored
+type OauthAPIKey struct {
+	MyGormModel
+	ClientID  sql.NullString `sql:"index;not null"`
+	UserID    sql.NullString `sql:"index"`
+	Client    *OauthClient
+	User      *OauthUser
+	KeyHash   string `sql:"type:varchar(64);unique;not null"`
+	Scope     string `sql:"type:varchar(200);not null"`
+	RevokedAt *time.Time
+}
+
+// TableName specifies table name
+func (k *OauthAPIKey) TableName() string {
+	return "oauth_api_keys"
+}
+
+// OauthDeviceCode is issued to an input constrained device, which polls
+// the token endpoint with it until the user approves the request
+type OauthDeviceCode struct {
+	MyGormModel
+	ClientID     sql.NullString `sql:"index;not null"`
+	UserID       sql.NullString `sql:"index"`
+	Client       *OauthClient
+	User         *OauthUser
+	DeviceCode   string    `sql:"type:varchar(40);unique;not null"`
+	UserCode     string    `sql:"type:varchar(9);unique;not null"`
+	Scope        string    `sql:"type:varchar(200);not null"`
+	ExpiresAt    time.Time `sql:"not null"`
+	AuthorizedAt *time.Time
+	DeniedAt     *time.Time
+	LastPolledAt *time.Time
+}
+
+// TableName specifies table name
+func (dc *OauthDeviceCode) TableName() string {
+	return "oauth_device_codes"
+}
+
+// OauthAssertionKey is a public key registered for a client, tokens can be
+// obtained with JWT assertions from the issuer signed by the matching private key
+type OauthAssertionKey struct {
+	MyGormModel
+	ClientID  sql.NullString `sql:"index;not null"`
+	Client    *OauthClient
+	Issuer    string `sql:"type:varchar(200);not null"`
+	Algorithm string `sql:"type:varchar(10);not null"`
+	PublicKey string `sql:"type:text;not null"`
+}
+
+// TableName specifies table name
+func (k *OauthAssertionKey) TableName() string {
+	return "oauth_assertion_keys"
+}
+
+// OauthSAMLIdentityProvider is a SAML 2.0 identity provider trusted by a client,
+// tokens can be obtained with assertions of the entity signed by one of its certificates
+type OauthSAMLIdentityProvider struct {
+	MyGormModel
+	ClientID     sql.NullString `sql:"index;not null"`
+	Client       *OauthClient
+	EntityID     string `sql:"type:varchar(200);not null"`
+	Certificates string `sql:"type:text;not null"`
+}
+
+// TableName specifies table name
+func (p *OauthSAMLIdentityProvider) TableName() string {
+	return "oauth_saml_identity_providers"
+}
+
+// OauthSigningKey is a key generated by the server to sign JSON Web Tokens,
+// it signs tokens until it expires and is published for verifying them
+// until the tokens it has signed have expired too
+type OauthSigningKey struct {
+	MyGormModel
+	KeyID      string    `sql:"type:varchar(100);unique;not null"`
+	Algorithm  string    `sql:"type:varchar(10);not null"`
+	PrivateKey string    `sql:"type:text;not null"`
+	ExpiresAt  time.Time `sql:"index;not null"`
+}
+
+// TableName specifies table name
+func (k *OauthSigningKey) TableName() string {
+	return "oauth_signing_keys"
+}
+
+// OauthBackchannelRequest is a backchannel authentication request, the user
+// approves it on their own device while the client waits for the decision
+type OauthBackchannelRequest struct {
+	MyGormModel
+	ClientID  sql.NullString `sql:"index;not null"`
+	UserID    sql.NullString `sql:"index;not null"`
+	Client    *OauthClient
+	User      *OauthUser
+	AuthReqID string    `sql:"type:varchar(40);unique;not null"`
+	Scope     string    `sql:"type:varchar(200);not null"`
+	ExpiresAt time.Time `sql:"not null"`
+	// BindingMessage is shown to the user on both devices
+	BindingMessage string `sql:"type:varchar(100);not null;default:''"`
+	// ClientNotificationToken authenticates the ping to the client
+	ClientNotificationToken string `sql:"type:varchar(200);not null;default:''"`
+	AuthorizedAt            *time.Time
+	DeniedAt                *time.Time
+	LastPolledAt            *time.Time
+}
+
+// TableName specifies table name
+func (br *OauthBackchannelRequest) TableName() string {
+	return "oauth_backchannel_requests"
+}
+
+// OauthPushedRequest holds the parameters of an authorization request pushed
+// by a client, the client then refers to them with the request URI
+type OauthPushedRequest struct {
+	MyGormModel
+	ClientID   sql.NullString `sql:"index;not null"`
+	Client     *OauthClient
+	RequestURI string    `sql:"type:varchar(100);unique;not null"`
+	Parameters string    `sql:"type:text;not null"`
+	ExpiresAt  time.Time `sql:"not null"`
+}
+
+// TableName specifies table name
+func (pr *OauthPushedRequest) TableName() string {
+	return "oauth_pushed_requests"
+}
+
+// OauthRedirectURI is one of the redirect URIs registered for a client
+// besides its own redirect URI, requested redirect URIs must match one exactly
+type OauthRedirectURI struct {
+	MyGormModel
+	ClientID sql.NullString `sql:"index;not null"`
+	Client   *OauthClient
+	URI      string `sql:"type:varchar(200);not null"`
+}
+
+// TableName specifies table name
+func (ru *OauthRedirectURI) TableName() string {
+	return "oauth_redirect_uris"
+}
+
+// OauthClientScope is a scope an admin granted to a client, a client
+// which has been granted scopes can only be issued tokens for them
+type OauthClientScope struct {
+	MyGormModel
+	ClientID sql.NullString `sql:"index;not null"`
+	Client   *OauthClient
+	ScopeID  sql.NullString `sql:"index;not null"`
+	Scope    *OauthScope
+}
+
+// TableName specifies table name
+func (cs *OauthClientScope) TableName() string {
+	return "oauth_client_scopes"
+}
+
+// OauthRoleScope is a scope users with the role can be issued tokens for,
+// users whose roles have been mapped to scopes can only be issued tokens
+// for those
+type OauthRoleScope struct {
+	MyGormModel
+	RoleID  sql.NullString `sql:"type:varchar(20);index;not null"`
+	Role    *OauthRole
+	ScopeID sql.NullString `sql:"index;not null"`
+	Scope   *OauthScope
+}
+
+// TableName specifies table name
+func (rs *OauthRoleScope) TableName() string {
+	return "oauth_role_scopes"
+}
+
+// OauthUserRole is a role a user has besides the role of the user
+type OauthUserRole struct {
+	MyGormModel
+	UserID sql.NullString `sql:"index;not null"`
+	User   *OauthUser
+	RoleID sql.NullString `sql:"type:varchar(20);index;not null"`
+	Role   *OauthRole
+}
+
+// TableName specifies table name
+func (ur *OauthUserRole) TableName() string {
+	return "oauth_user_roles"
+}
+
+// OauthUserAttribute is a custom attribute of a user, such as its
+// department, its value is stored as JSON so it can be any JSON value
+type OauthUserAttribute struct {
+	MyGormModel
+	UserID sql.NullString `sql:"index;not null"`
+	User   *OauthUser
+	Name   string `sql:"type:varchar(100);index;not null"`
+	Value  string `sql:"type:text;not null"`
+}
+
+// TableName specifies table name
+func (ua *OauthUserAttribute) TableName() string {
+	return "oauth_user_attributes"
+}
+
+// OauthClientAssertion records the jti of a JWT assertion a client
+// authenticated with, so the assertion cannot be replayed
+type OauthClientAssertion struct {
+	MyGormModel
+	ClientID  sql.NullString `sql:"not null;unique_index:idx_oauth_client_assertions_jti"`
+	Client    *OauthClient
+	JTI       string    `gorm:"column:jti" sql:"type:varchar(200);not null;unique_index:idx_oauth_client_assertions_jti"`
+	ExpiresAt time.Time `sql:"not null"`
+}
+
+// TableName specifies table name
+func (ca *OauthClientAssertion) TableName() string {
+	return "oauth_client_assertions"
+}
+
+// OauthPasswordReset is a single-use token a user who forgot their password
+// sets a new one with, only its hash is stored
+type OauthPasswordReset struct {
+	MyGormModel
+	UserID    sql.NullString `sql:"index;not null"`
+	User      *OauthUser
+	TokenHash string    `sql:"type:varchar(64);unique;not null"`
+	ExpiresAt time.Time `sql:"not null"`
+}
+
+// TableName specifies table name
+func (pr *OauthPasswordReset) TableName() string {
+	return "oauth_password_resets"
+}
+
+// OauthEmailVerification is a single-use token a user confirms their
+// email address with, only its hash is stored
+type OauthEmailVerification struct {
+	MyGormModel
+	UserID    sql.NullString `sql:"index;not null"`
+	User      *OauthUser
+	TokenHash string    `sql:"type:varchar(64);unique;not null"`
+	ExpiresAt time.Time `sql:"not null"`
+}
+
+// TableName specifies table name
+func (ev *OauthEmailVerification) TableName() string {
+	return "oauth_email_verifications"
+}
+
+// OauthWebAuthnCredential is a passkey or security key a user logs in with
+// instead of a password, see https://www.w3.org/TR/webauthn-2/
+type OauthWebAuthnCredential struct {
+	MyGormModel
+	UserID sql.NullString `sql:"index;not null"`
+	User   *OauthUser
+	// CredentialID is the base64url encoded ID chosen by the authenticator
+	CredentialID string `sql:"type:varchar(1400);unique;not null"`
+	// PublicKey is the base64url encoded COSE key verifying assertions
+	PublicKey string `sql:"type:text;not null"`
+	// SignCount is the last signature counter reported by the authenticator
+	SignCount  int64  `sql:"not null;default:0"`
+	Name       string `sql:"type:varchar(100);not null;default:''"`
+	LastUsedAt *time.Time
+}
+
+// TableName specifies table name
+func (wc *OauthWebAuthnCredential) TableName() string {
+	return "oauth_webauthn_credentials"
+}
+
+// OauthWebAuthnChallenge is a single-use challenge issued for registering
+// a credential or logging in with one, only its hash is stored
+type OauthWebAuthnChallenge struct {
+	MyGormModel
+	// UserID is the user registering a credential or expected to log in,
+	// it is empty for logins with a discoverable credential
+	UserID        sql.NullString `sql:"index"`
+	User          *OauthUser
+	ChallengeHash string    `sql:"type:varchar(64);unique;not null"`
+	Type          string    `sql:"type:varchar(20);not null"`
+	ExpiresAt     time.Time `sql:"not null"`
+}
+
+// TableName specifies table name
+func (wc *OauthWebAuthnChallenge) TableName() string {
+	return "oauth_webauthn_challenges"
+}
+
+// OauthLoginFailure is a failed password login, counted towards a lockout
+// until it expires. The username is kept as given, so unknown usernames
+// are locked out the same as existing ones.
+type OauthLoginFailure struct {
+	MyGormModel
+	Username  string    `sql:"type:varchar(254);index;not null"`
+	IPAddress string    `sql:"type:varchar(45);index;not null"`
+	ExpiresAt time.Time `sql:"not null"`
+}
+
+// TableName specifies table name
+func (lf *OauthLoginFailure) TableName() string {
+	return "oauth_login_failures"
+}
+
+// OauthFederatedIdentity links the account of a user at an upstream
+// identity provider, identified by its subject, to a local user
+type OauthFederatedIdentity struct {
+	MyGormModel
+	// Provider is the name of the provider in the config
+	Provider string         `sql:"type:varchar(50);not null;unique_index:idx_oauth_federated_identities_subject"`
+	Subject  string         `sql:"type:varchar(255);not null;unique_index:idx_oauth_federated_identities_subject"`
+	UserID   sql.NullString `sql:"index;not null"`
+	User     *OauthUser
+	// Email is the address the provider last asserted, kept for reference
+	Email string `sql:"type:varchar(254);not null;default:''"`
+}
+
+// TableName specifies table name
+func (fi *OauthFederatedIdentity) TableName() string {
+	return "oauth_federated_identities"
+}
+
+// OauthFederationState is a login in progress at an upstream identity
+// provider, only the hash of the state parameter is stored
+type OauthFederationState struct {
+	MyGormModel
+	StateHash    string `sql:"type:varchar(64);unique;not null"`
+	Provider     string `sql:"type:varchar(50);not null"`
+	Nonce        string `sql:"type:varchar(64);not null"`
+	CodeVerifier string `sql:"type:varchar(64);not null"`
+	// Query is the query string of the login page the user left,
+	// restored when the user comes back
+	Query     string    `sql:"type:text;not null"`
+	ExpiresAt time.Time `sql:"not null"`
+}
+
+// TableName specifies table name
+func (fs *OauthFederationState) TableName() string {
+	return "oauth_federation_states"
+}
+
+// OauthGroup is a group of users, as provisioned by an HR system
+type OauthGroup struct {
+	MyGormModel
+	DisplayName string `sql:"type:varchar(200);unique;not null"`
+	ExternalID  string `sql:"type:varchar(255);index;not null;default:''"`
+}
+
+// TableName specifies table name
+func (g *OauthGroup) TableName() string {
+	return "oauth_groups"
+}
+
+// OauthGroupMember is a user belonging to a group
+type OauthGroupMember struct {
+	MyGormModel
+	GroupID sql.NullString `sql:"not null;unique_index:idx_oauth_group_members_user"`
+	Group   *OauthGroup
+	UserID  sql.NullString `sql:"index;not null;unique_index:idx_oauth_group_members_user"`
+	User    *OauthUser
+}
+
+// TableName specifies table name
+func (gm *OauthGroupMember) TableName() string {
+	return "oauth_group_members"
+}
+
 // NewOauthRefreshToken creates new OauthRefreshToken instance
 func NewOauthRefreshToken(client *OauthClient, user *OauthUser, expiresIn int, scope string) *OauthRefreshToken {
 	refreshToken := &OauthRefreshToken{
@@ -165,6 +747,271 @@ func NewOauthAuthorizationCode(client *OauthClient, user *OauthUser, expiresIn i
 	}
 }
 
+// NewOauthDeviceSecret creates new OauthDeviceSecret instance
+func NewOauthDeviceSecret(client *OauthClient, user *OauthUser, expiresIn int, scope string) *OauthDeviceSecret {
+	return &OauthDeviceSecret{
+		MyGormModel: MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		ClientID:  util.StringOrNull(string(client.ID)),
+		UserID:    util.StringOrNull(string(user.ID)),
+		Secret:    uuid.New(),
+		ExpiresAt: time.Now().UTC().Add(time.Duration(expiresIn) * time.Second),
+		Scope:     scope,
+	}
+}
+
+// NewOauthSession creates new OauthSession instance
+func NewOauthSession(user *OauthUser, authTime time.Time) *OauthSession {
+	return &OauthSession{
+		MyGormModel: MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		UserID:   util.StringOrNull(string(user.ID)),
+		AuthTime: authTime.UTC(),
+	}
+}
+
+// NewOauthConsent creates new OauthConsent instance
+func NewOauthConsent(client *OauthClient, user *OauthUser, scope string) *OauthConsent {
+	return &OauthConsent{
+		MyGormModel: MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		ClientID: util.StringOrNull(string(client.ID)),
+		UserID:   util.StringOrNull(string(user.ID)),
+		Scope:    scope,
+	}
+}
+
+// NewOauthDeviceCode creates new OauthDeviceCode instance
+func NewOauthDeviceCode(client *OauthClient, userCode string, expiresIn int, scope string) *OauthDeviceCode {
+	return &OauthDeviceCode{
+		MyGormModel: MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		ClientID:   util.StringOrNull(string(client.ID)),
+		DeviceCode: uuid.New(),
+		UserCode:   userCode,
+		ExpiresAt:  time.Now().UTC().Add(time.Duration(expiresIn) * time.Second),
+		Scope:      scope,
+	}
+}
+
+// NewOauthBackchannelRequest creates new OauthBackchannelRequest instance
+func NewOauthBackchannelRequest(client *OauthClient, user *OauthUser, expiresIn int, scope string) *OauthBackchannelRequest {
+	return &OauthBackchannelRequest{
+		MyGormModel: MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		ClientID:  util.StringOrNull(string(client.ID)),
+		UserID:    util.StringOrNull(string(user.ID)),
+		AuthReqID: uuid.New(),
+		ExpiresAt: time.Now().UTC().Add(time.Duration(expiresIn) * time.Second),
+		Scope:     scope,
+	}
+}
+
+// NewOauthPushedRequest creates new OauthPushedRequest instance
+func NewOauthPushedRequest(client *OauthClient, requestURI, parameters string, expiresIn int) *OauthPushedRequest {
+	return &OauthPushedRequest{
+		MyGormModel: MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		ClientID:   util.StringOrNull(string(client.ID)),
+		RequestURI: requestURI,
+		Parameters: parameters,
+		ExpiresAt:  time.Now().UTC().Add(time.Duration(expiresIn) * time.Second),
+	}
+}
+
+// NewOauthAssertionKey creates new OauthAssertionKey instance
+func NewOauthAssertionKey(client *OauthClient, issuer, algorithm, publicKey string) *OauthAssertionKey {
+	return &OauthAssertionKey{
+		MyGormModel: MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		ClientID:  util.StringOrNull(string(client.ID)),
+		Issuer:    issuer,
+		Algorithm: algorithm,
+		PublicKey: publicKey,
+	}
+}
+
+// NewOauthSAMLIdentityProvider creates new OauthSAMLIdentityProvider instance
+func NewOauthSAMLIdentityProvider(client *OauthClient, entityID, certificates string) *OauthSAMLIdentityProvider {
+	return &OauthSAMLIdentityProvider{
+		MyGormModel: MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		ClientID:     util.StringOrNull(string(client.ID)),
+		EntityID:     entityID,
+		Certificates: certificates,
+	}
+}
+
+// NewOauthSigningKey creates new OauthSigningKey instance
+func NewOauthSigningKey(keyID, algorithm, privateKey string, expiresIn time.Duration) *OauthSigningKey {
+	return &OauthSigningKey{
+		MyGormModel: MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		KeyID:      keyID,
+		Algorithm:  algorithm,
+		PrivateKey: privateKey,
+		ExpiresAt:  time.Now().UTC().Add(expiresIn),
+	}
+}
+
+// NewOauthAPIKey creates new OauthAPIKey instance
+func NewOauthAPIKey(client *OauthClient, user *OauthUser, keyHash, scope string) *OauthAPIKey {
+	apiKey := &OauthAPIKey{
+		MyGormModel: MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		ClientID: util.StringOrNull(string(client.ID)),
+		KeyHash:  keyHash,
+		Scope:    scope,
+	}
+	if user != nil {
+		apiKey.UserID = util.StringOrNull(string(user.ID))
+	}
+	return apiKey
+}
+
+// NewOauthRecoveryCode creates new OauthRecoveryCode instance
+func NewOauthRecoveryCode(user *OauthUser, codeHash string) *OauthRecoveryCode {
+	return &OauthRecoveryCode{
+		MyGormModel: MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		UserID:   util.StringOrNull(string(user.ID)),
+		CodeHash: codeHash,
+	}
+}
+
+// NewOauthPasswordReset creates new OauthPasswordReset instance
+func NewOauthPasswordReset(user *OauthUser, tokenHash string, expiresIn int) *OauthPasswordReset {
+	return &OauthPasswordReset{
+		MyGormModel: MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		UserID:    util.StringOrNull(string(user.ID)),
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().UTC().Add(time.Duration(expiresIn) * time.Second),
+	}
+}
+
+// NewOauthEmailVerification creates new OauthEmailVerification instance
+func NewOauthEmailVerification(user *OauthUser, tokenHash string, expiresIn int) *OauthEmailVerification {
+	return &OauthEmailVerification{
+		MyGormModel: MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		UserID:    util.StringOrNull(string(user.ID)),
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().UTC().Add(time.Duration(expiresIn) * time.Second),
+	}
+}
+
+// NewOauthWebAuthnChallenge creates new OauthWebAuthnChallenge instance,
+// user is nil for logins with a discoverable credential
+func NewOauthWebAuthnChallenge(user *OauthUser, challengeHash, challengeType string, expiresIn int) *OauthWebAuthnChallenge {
+	challenge := &OauthWebAuthnChallenge{
+		MyGormModel: MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		ChallengeHash: challengeHash,
+		Type:          challengeType,
+		ExpiresAt:     time.Now().UTC().Add(time.Duration(expiresIn) * time.Second),
+	}
+	if user != nil {
+		challenge.UserID = util.StringOrNull(string(user.ID))
+	}
+	return challenge
+}
+
+// NewOauthLoginFailure creates new OauthLoginFailure instance
+func NewOauthLoginFailure(username, ipAddress string, expiresIn int) *OauthLoginFailure {
+	return &OauthLoginFailure{
+		MyGormModel: MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		Username:  username,
+		IPAddress: ipAddress,
+		ExpiresAt: time.Now().UTC().Add(time.Duration(expiresIn) * time.Second),
+	}
+}
+
+// NewOauthFederatedIdentity creates new OauthFederatedIdentity instance
+func NewOauthFederatedIdentity(user *OauthUser, provider, subject, email string) *OauthFederatedIdentity {
+	return &OauthFederatedIdentity{
+		MyGormModel: MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		Provider: provider,
+		Subject:  subject,
+		UserID:   util.StringOrNull(string(user.ID)),
+		Email:    email,
+	}
+}
+
+// NewOauthGroup creates new OauthGroup instance
+func NewOauthGroup(displayName, externalID string) *OauthGroup {
+	return &OauthGroup{
+		MyGormModel: MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		DisplayName: displayName,
+		ExternalID:  externalID,
+	}
+}
+
+// NewOauthGroupMember creates new OauthGroupMember instance
+func NewOauthGroupMember(group *OauthGroup, userID string) *OauthGroupMember {
+	return &OauthGroupMember{
+		MyGormModel: MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		GroupID: util.StringOrNull(string(group.ID)),
+		UserID:  util.StringOrNull(userID),
+	}
+}
+
+// NewOauthFederationState creates new OauthFederationState instance
+func NewOauthFederationState(stateHash, provider, nonce, codeVerifier, query string, expiresIn int) *OauthFederationState {
+	return &OauthFederationState{
+		MyGormModel: MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		StateHash:    stateHash,
+		Provider:     provider,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		Query:        query,
+		ExpiresAt:    time.Now().UTC().Add(time.Duration(expiresIn) * time.Second),
+	}
+}
+
 // OauthAuthorizationCodePreload sets up Gorm preloads for an auth code object
 func OauthAuthorizationCodePreload(db *gorm.DB) *gorm.DB {
 	return OauthAuthorizationCodePreloadWithPrefix(db, "")
@@ -200,3 +1047,125 @@ func OauthRefreshTokenPreloadWithPrefix(db *gorm.DB, prefix string) *gorm.DB {
 	return db.
 		Preload(prefix + "Client").Preload(prefix + "User")
 }
+
+// OauthDeviceSecretPreload sets up Gorm preloads for a device secret object
+func OauthDeviceSecretPreload(db *gorm.DB) *gorm.DB {
+	return OauthDeviceSecretPreloadWithPrefix(db, "")
+}
+
+// OauthDeviceSecretPreloadWithPrefix sets up Gorm preloads for a device secret object,
+// and prefixes with prefix for nested objects
+func OauthDeviceSecretPreloadWithPrefix(db *gorm.DB, prefix string) *gorm.DB {
+	return db.
+		Preload(prefix + "Client").Preload(prefix + "User")
+}
+
+// OauthDeviceCodePreload sets up Gorm preloads for a device code object
+func OauthDeviceCodePreload(db *gorm.DB) *gorm.DB {
+	return OauthDeviceCodePreloadWithPrefix(db, "")
+}
+
+// OauthDeviceCodePreloadWithPrefix sets up Gorm preloads for a device code object,
+// and prefixes with prefix for nested objects
+func OauthDeviceCodePreloadWithPrefix(db *gorm.DB, prefix string) *gorm.DB {
+	return db.
+		Preload(prefix + "Client").Preload(prefix + "User")
+}
+
+// OauthBackchannelRequestPreload sets up Gorm preloads for a backchannel request object
+func OauthBackchannelRequestPreload(db *gorm.DB) *gorm.DB {
+	return OauthBackchannelRequestPreloadWithPrefix(db, "")
+}
+
+// OauthBackchannelRequestPreloadWithPrefix sets up Gorm preloads for a backchannel
+// request object, and prefixes with prefix for nested objects
+func OauthBackchannelRequestPreloadWithPrefix(db *gorm.DB, prefix string) *gorm.DB {
+	return db.
+		Preload(prefix + "Client").Preload(prefix + "User")
+}
+
+// OauthAPIKeyPreload sets up Gorm preloads for an API key object
+func OauthAPIKeyPreload(db *gorm.DB) *gorm.DB {
+	return OauthAPIKeyPreloadWithPrefix(db, "")
+}
+
+// OauthAPIKeyPreloadWithPrefix sets up Gorm preloads for an API key object,
+// and prefixes with prefix for nested objects
+func OauthAPIKeyPreloadWithPrefix(db *gorm.DB, prefix string) *gorm.DB {
+	return db.
+		Preload(prefix + "Client").Preload(prefix + "User")
+}
+
+// NewOauthRedirectURI creates new OauthRedirectURI instance
+func NewOauthRedirectURI(client *OauthClient, uri string) *OauthRedirectURI {
+	return &OauthRedirectURI{
+		MyGormModel: MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		ClientID: util.StringOrNull(string(client.ID)),
+		URI:      uri,
+	}
+}
+
+// NewOauthClientScope creates new OauthClientScope instance
+func NewOauthClientScope(client *OauthClient, scope *OauthScope) *OauthClientScope {
+	return &OauthClientScope{
+		MyGormModel: MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		ClientID: util.StringOrNull(string(client.ID)),
+		ScopeID:  util.StringOrNull(string(scope.ID)),
+	}
+}
+
+// NewOauthRoleScope creates new OauthRoleScope instance
+func NewOauthRoleScope(role *OauthRole, scope *OauthScope) *OauthRoleScope {
+	return &OauthRoleScope{
+		MyGormModel: MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		RoleID:  util.StringOrNull(role.ID),
+		ScopeID: util.StringOrNull(string(scope.ID)),
+	}
+}
+
+// NewOauthUserRole creates new OauthUserRole instance
+func NewOauthUserRole(user *OauthUser, role *OauthRole) *OauthUserRole {
+	return &OauthUserRole{
+		MyGormModel: MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		UserID: util.StringOrNull(string(user.ID)),
+		RoleID: util.StringOrNull(role.ID),
+	}
+}
+
+// NewOauthUserAttribute creates new OauthUserAttribute instance
+func NewOauthUserAttribute(user *OauthUser, name, value string) *OauthUserAttribute {
+	return &OauthUserAttribute{
+		MyGormModel: MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		UserID: util.StringOrNull(string(user.ID)),
+		Name:   name,
+		Value:  value,
+	}
+}
+
+// NewOauthClientAssertion creates new OauthClientAssertion instance
+func NewOauthClientAssertion(client *OauthClient, jti string, expiresAt time.Time) *OauthClientAssertion {
+	return &OauthClientAssertion{
+		MyGormModel: MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		ClientID:  util.StringOrNull(string(client.ID)),
+		JTI:       jti,
+		ExpiresAt: expiresAt,
+	}
+}
@@ -158,3 +158,15 @@ func TestNewAuthorizationCode(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, string("2"), v)
 }
+
+func TestAccessTokenBeforeCreate(t *testing.T) {
+	accessToken := &models.OauthAccessToken{Token: "test_token"}
+	assert.NoError(t, accessToken.BeforeCreate())
+
+	// Only the hash of the token is stored
+	assert.Equal(
+		t,
+		"cc0af97287543b65da2c7e1476426021826cab166f1e063ed012b855ff819656",
+		accessToken.TokenHash,
+	)
+}
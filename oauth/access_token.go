@@ -8,8 +8,53 @@ import (
 
 // GrantAccessToken deletes old tokens and grants a new access token
 func (s *Service) GrantAccessToken(client *models.OauthClient, user *models.OauthUser, expiresIn int, scope string) (*models.OauthAccessToken, error) {
-	// Begin a transaction
-	tx := s.db.Begin()
+	// The client may be registered for, or granted, some scopes only
+	allowed, err := s.clientAllowsScope(client, scope)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrInvalidScope
+	}
+
+	// Deprovisioned users keep their account but cannot use it
+	if user != nil && user.Disabled {
+		return nil, ErrUserDisabled
+	}
+
+	// Users are only granted the scopes their roles permit,
+	// whatever the client requested
+	userScope, err := s.getUserScope(user, scope)
+	if err != nil {
+		return nil, err
+	}
+	if userScope == "" && scope != "" {
+		return nil, ErrInvalidScope
+	}
+	scope = userScope
+
+	// Throttle runaway clients requesting tokens for the same user
+	if err := s.checkUserTokenLimit(user); err != nil {
+		return nil, err
+	}
+
+	// Generate the token handed out to the client
+	token, err := s.getTokenGenerator().GenerateAccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	// Custom claims are added before the token is stored
+	claims, err := s.getCustomClaims(user, client, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	// Begin a transaction, unless the service already runs in one
+	tx := s.db
+	if !s.inTx {
+		tx = s.db.Begin()
+	}
 
 	// Delete expired access tokens
 	query := tx.Unscoped().Where("client_id = ?", client.ID)
@@ -19,23 +64,28 @@ func (s *Service) GrantAccessToken(client *models.OauthClient, user *models.Oaut
 		query = query.Where("user_id IS NULL")
 	}
 	if err := query.Where("expires_at <= ?", time.Now()).Delete(new(models.OauthAccessToken)).Error; err != nil {
-		tx.Rollback() // rollback the transaction
+		s.rollback(tx) // rollback the transaction
 		return nil, err
 	}
 
 	// Create a new access token
 	accessToken := models.NewOauthAccessToken(client, user, expiresIn, scope)
+	accessToken.Token = token
+	accessToken.Claims = claims
+	accessToken.Audience = s.GetScopeAudience(scope)
 	if err := tx.Create(accessToken).Error; err != nil {
-		tx.Rollback() // rollback the transaction
+		s.rollback(tx) // rollback the transaction
 		return nil, err
 	}
 	accessToken.Client = client
 	accessToken.User = user
 
-	// Commit the transaction
-	if err := tx.Commit().Error; err != nil {
-		tx.Rollback() // rollback the transaction
-		return nil, err
+	// Commit the transaction, unless it is the caller's to commit
+	if !s.inTx {
+		if err := tx.Commit().Error; err != nil {
+			tx.Rollback() // rollback the transaction
+			return nil, err
+		}
 	}
 
 	return accessToken, nil
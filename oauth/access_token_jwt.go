@@ -0,0 +1,160 @@
+package oauth
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/RichardKnop/go-oauth2-server/util/jwt"
+)
+
+var (
+	// ErrInvalidTokenFormat ...
+	ErrInvalidTokenFormat = errors.New("Token format must be opaque or jwt")
+)
+
+// EncodeAccessToken returns the access token as handed out to clients. It is
+// the opaque token itself unless the client is issued JWT access tokens, in
+// which case it is a JWT signed with the configured key carrying the opaque
+// token as its jti claim, so resource servers can validate it without a
+// database round trip and the server can still revoke and introspect it.
+func (s *Service) EncodeAccessToken(accessToken *models.OauthAccessToken) (string, error) {
+	client := accessToken.Client
+	if client == nil {
+		client = new(models.OauthClient)
+		if s.db.Select("key, token_format").First(client, accessToken.ClientID.String).RecordNotFound() {
+			return "", ErrClientNotFound
+		}
+	}
+	if s.getTokenFormat(client) != config.AccessTokenFormatJWT {
+		return accessToken.Token, nil
+	}
+
+	signingKey, err := s.getSigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	// Tokens granted to the client itself have the client as their subject
+	subject := client.Key
+	if accessToken.UserID.Valid {
+		subject = accessToken.UserID.String
+	}
+
+	claims := jwt.Claims{
+		"iss":       s.cnf.JWT.Issuer,
+		"sub":       subject,
+		"client_id": client.Key,
+		"scope":     accessToken.Scope,
+		"iat":       accessToken.CreatedAt.Unix(),
+		"exp":       accessToken.ExpiresAt.Unix(),
+		"jti":       accessToken.Token,
+	}
+	switch audiences := strings.Fields(accessToken.Audience); len(audiences) {
+	case 0:
+	case 1:
+		claims["aud"] = audiences[0]
+	default:
+		claims["aud"] = audiences
+	}
+	if accessToken.Acr != "" {
+		claims["acr"] = accessToken.Acr
+	}
+	if act := newActor(accessToken.Actor); act != nil {
+		claims["act"] = act
+	}
+	if cnf := newConfirmation(accessToken); cnf != nil {
+		claims["cnf"] = cnf
+	}
+	for name, value := range customClaims(accessToken) {
+		if !util.StringInSlice(name, reservedTokenClaims) {
+			claims[name] = value
+		}
+	}
+
+	return jwt.Sign(claims, signingKey)
+}
+
+// decodeAccessToken returns the opaque token of a JWT access token, other
+// tokens are returned as they are. JWTs are accepted whatever the configured
+// format so tokens issued before it was changed stay valid.
+func (s *Service) decodeAccessToken(token string) (string, error) {
+	if strings.Count(token, ".") != 2 {
+		return token, nil
+	}
+
+	// Tokens signed with rotated keys are verified with the key they name
+	verificationKey, err := s.getVerificationKey(token)
+	if err != nil {
+		return "", err
+	}
+	if verificationKey == nil {
+		return "", ErrAccessTokenNotFound
+	}
+
+	claims, err := jwt.Parse(token, verificationKey)
+	if err == jwt.ErrTokenExpired {
+		return "", ErrAccessTokenExpired
+	}
+	if err != nil {
+		return "", ErrAccessTokenNotFound
+	}
+	jti, _ := claims.String("jti")
+	if jti == "" {
+		return "", ErrAccessTokenNotFound
+	}
+
+	return jti, nil
+}
+
+// encodeAccessTokenResponse replaces the opaque access token of the
+// response with a JWT access token if the client is issued them
+func (s *Service) encodeAccessTokenResponse(resp *AccessTokenResponse, client *models.OauthClient) error {
+	if s.getTokenFormat(client) != config.AccessTokenFormatJWT {
+		return nil
+	}
+
+	// Encode the access token as stored once the grant has completed it
+	accessToken := new(models.OauthAccessToken)
+	if s.db.Where("token = ?", models.HashToken(resp.AccessToken)).First(accessToken).RecordNotFound() {
+		return ErrAccessTokenNotFound
+	}
+	accessToken.Token = resp.AccessToken
+	accessToken.Client = client
+
+	encoded, err := s.EncodeAccessToken(accessToken)
+	if err != nil {
+		return err
+	}
+	resp.AccessToken = encoded
+
+	return nil
+}
+
+// getTokenFormat returns the format of access tokens issued to the client
+func (s *Service) getTokenFormat(client *models.OauthClient) string {
+	if client.TokenFormat != "" {
+		return client.TokenFormat
+	}
+	return s.cnf.Oauth.AccessTokenFormat
+}
+
+// SetTokenFormat sets the format of access tokens issued to the client,
+// passing an empty format makes the client use the configured one
+func (s *Service) SetTokenFormat(client *models.OauthClient, format string) error {
+	switch format {
+	case "", config.AccessTokenFormatOpaque, config.AccessTokenFormatJWT:
+	default:
+		return ErrInvalidTokenFormat
+	}
+
+	err := s.db.Model(new(models.OauthClient)).Where("id = ?", client.ID).
+		UpdateColumn("token_format", format).Error
+	if err != nil {
+		return err
+	}
+	client.TokenFormat = format
+	return nil
+}
@@ -0,0 +1,141 @@
+package oauth_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/util/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestJWTAccessToken() {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(suite.T(), err)
+	der, err := x509.MarshalECPrivateKey(privateKey)
+	assert.NoError(suite.T(), err)
+	suite.cnf.Oauth.AccessTokenFormat = config.AccessTokenFormatJWT
+	suite.cnf.JWT.Algorithm = jwt.ES256
+	suite.cnf.JWT.PrivateKey = string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+	defer func() {
+		suite.cnf.Oauth.AccessTokenFormat = ""
+		suite.cnf.JWT.Algorithm = ""
+		suite.cnf.JWT.PrivateKey = ""
+	}()
+
+	// Prepare a request
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
+	r.PostForm = url.Values{
+		"grant_type": {"client_credentials"},
+		"scope":      {"read_write"},
+	}
+
+	// Serve the request
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	resp := suite.decodeAccessTokenResponse(w)
+
+	// Resource servers can validate the token with the public key alone
+	verifier, err := jwt.NewVerifier(jwt.ES256, []byte(suite.encodePublicKey(&privateKey.PublicKey)))
+	assert.NoError(suite.T(), err)
+	claims, err := jwt.Parse(resp.AccessToken, verifier)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), suite.cnf.JWT.Issuer, claims["iss"])
+		assert.Equal(suite.T(), "test_client_1", claims["sub"])
+		assert.Equal(suite.T(), "test_client_1", claims["client_id"])
+		assert.Equal(suite.T(), "read_write", claims["scope"])
+		_, ok := claims.Int64("exp")
+		assert.True(suite.T(), ok)
+		_, ok = claims.Int64("iat")
+		assert.True(suite.T(), ok)
+	}
+
+	// The server still keeps track of the token
+	accessToken, err := suite.service.Authenticate(resp.AccessToken)
+	if assert.NoError(suite.T(), err) {
+		jti, _ := claims.String("jti")
+		assert.Equal(suite.T(), models.HashToken(jti), accessToken.TokenHash)
+	}
+
+	// Tampered tokens are not accepted
+	segments := strings.Split(resp.AccessToken, ".")
+	forged := segments[0] + "." + segments[1] + "." + segments[0]
+	_, err = suite.service.Authenticate(forged)
+	assert.Equal(suite.T(), oauth.ErrAccessTokenNotFound, err)
+
+	// Revoking the token makes it invalid even though its signature is still valid
+	w = suite.revoke("test_client_1", url.Values{"token": {resp.AccessToken}})
+	assert.Equal(suite.T(), 200, w.Code)
+	_, err = suite.service.Authenticate(resp.AccessToken)
+	assert.Equal(suite.T(), oauth.ErrAccessTokenNotFound, err)
+}
+
+func (suite *OauthTestSuite) TestEncodeAccessToken() {
+	accessToken, err := suite.service.GrantAccessToken(suite.clients[0], suite.users[0], 3600, "read")
+	assert.NoError(suite.T(), err)
+
+	// Access tokens are opaque by default
+	encoded, err := suite.service.EncodeAccessToken(accessToken)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), accessToken.Token, encoded)
+
+	// JWT access tokens name the user as their subject
+	suite.cnf.Oauth.AccessTokenFormat = config.AccessTokenFormatJWT
+	defer func() { suite.cnf.Oauth.AccessTokenFormat = "" }()
+	encoded, err = suite.service.EncodeAccessToken(accessToken)
+	assert.NoError(suite.T(), err)
+	signingKey, err := oauth.NewSigningKey(&suite.cnf.JWT)
+	assert.NoError(suite.T(), err)
+	claims, err := jwt.Parse(encoded, signingKey)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), suite.users[0].ID, claims["sub"])
+		assert.Equal(suite.T(), accessToken.Token, claims["jti"])
+	}
+}
+
+func (suite *OauthTestSuite) TestSetTokenFormat() {
+	err := suite.service.SetTokenFormat(suite.clients[0], "bogus")
+	assert.Equal(suite.T(), oauth.ErrInvalidTokenFormat, err)
+
+	// Only the client is issued JWT access tokens
+	err = suite.service.SetTokenFormat(suite.clients[0], config.AccessTokenFormatJWT)
+	assert.NoError(suite.T(), err)
+	defer suite.service.SetTokenFormat(suite.clients[0], "")
+	for _, testCase := range []struct {
+		client *models.OauthClient
+		jwt    bool
+	}{
+		{suite.clients[0], true},
+		{suite.clients[1], false},
+	} {
+		accessToken, err := suite.service.GrantAccessToken(testCase.client, suite.users[0], 3600, "read")
+		assert.NoError(suite.T(), err)
+		accessToken.Client = nil // the client is looked up like for stored tokens
+		encoded, err := suite.service.EncodeAccessToken(accessToken)
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), testCase.jwt, encoded != accessToken.Token, testCase.client.Key)
+	}
+
+	// The client keeps being issued opaque tokens if configured
+	suite.cnf.Oauth.AccessTokenFormat = config.AccessTokenFormatJWT
+	defer func() { suite.cnf.Oauth.AccessTokenFormat = "" }()
+	err = suite.service.SetTokenFormat(suite.clients[1], config.AccessTokenFormatOpaque)
+	assert.NoError(suite.T(), err)
+	defer suite.service.SetTokenFormat(suite.clients[1], "")
+	accessToken, err := suite.service.GrantAccessToken(suite.clients[1], suite.users[0], 3600, "read")
+	assert.NoError(suite.T(), err)
+	encoded, err := suite.service.EncodeAccessToken(accessToken)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), accessToken.Token, encoded)
+}
@@ -17,10 +17,10 @@ func (suite *OauthTestSuite) TestGrantAccessToken() {
 
 	// Grant a client only access token
 	accessToken, err = suite.service.GrantAccessToken(
-		suite.clients[0],       // client
-		nil,                    // user
-		3600,                   // expires in
-		"scope doesn't matter", // scope
+		suite.clients[0], // client
+		nil,              // user
+		3600,             // expires in
+		"read",           // scope
 	)
 
 	// Error should be Nil
@@ -35,7 +35,7 @@ func (suite *OauthTestSuite) TestGrantAccessToken() {
 		assert.Equal(suite.T(), 1, len(tokens))
 
 		// And the token should match the one returned by the grant method
-		assert.Equal(suite.T(), tokens[0].Token, accessToken.Token)
+		assert.Equal(suite.T(), tokens[0].TokenHash, models.HashToken(accessToken.Token))
 
 		// Client id should be set
 		assert.True(suite.T(), tokens[0].ClientID.Valid)
@@ -47,10 +47,10 @@ func (suite *OauthTestSuite) TestGrantAccessToken() {
 
 	// Grant a user specific access token
 	accessToken, err = suite.service.GrantAccessToken(
-		suite.clients[0],       // client
-		suite.users[0],         // user
-		3600,                   // expires in
-		"scope doesn't matter", // scope
+		suite.clients[0], // client
+		suite.users[0],   // user
+		3600,             // expires in
+		"read",           // scope
 	)
 
 	// Error should be Nil
@@ -65,7 +65,7 @@ func (suite *OauthTestSuite) TestGrantAccessToken() {
 		assert.Equal(suite.T(), 2, len(tokens))
 
 		// And the second token should match the one returned by the grant method
-		assert.Equal(suite.T(), tokens[1].Token, accessToken.Token)
+		assert.Equal(suite.T(), tokens[1].TokenHash, models.HashToken(accessToken.Token))
 
 		// Client id should be set
 		assert.True(suite.T(), tokens[1].ClientID.Valid)
@@ -136,15 +136,15 @@ func (suite *OauthTestSuite) TestGrantAccessTokenDeletesExpiredTokens() {
 
 	// This should only delete test_token_1
 	_, err = suite.service.GrantAccessToken(
-		suite.clients[0],       // client
-		suite.users[0],         // user
-		3600,                   // expires in
-		"scope doesn't matter", // scope
+		suite.clients[0], // client
+		suite.users[0],   // user
+		3600,             // expires in
+		"read",           // scope
 	)
 	assert.NoError(suite.T(), err)
 
 	// Check the test_token_1 was deleted
-	notFound = suite.db.Unscoped().Where("token = ?", "test_token_1").
+	notFound = suite.db.Unscoped().Where("token = ?", models.HashToken("test_token_1")).
 		First(new(models.OauthAccessToken)).RecordNotFound()
 	assert.True(suite.T(), notFound)
 
@@ -155,22 +155,22 @@ func (suite *OauthTestSuite) TestGrantAccessTokenDeletesExpiredTokens() {
 		"test_token_4",
 	}
 	for _, token := range existingTokens {
-		notFound = suite.db.Unscoped().Where("token = ?", token).
+		notFound = suite.db.Unscoped().Where("token = ?", models.HashToken(token)).
 			First(new(models.OauthAccessToken)).RecordNotFound()
 		assert.False(suite.T(), notFound)
 	}
 
 	// This should only delete test_token_2
 	_, err = suite.service.GrantAccessToken(
-		suite.clients[0],       // client
-		nil,                    // user
-		3600,                   // expires in
-		"scope doesn't matter", // scope
+		suite.clients[0], // client
+		nil,              // user
+		3600,             // expires in
+		"read",           // scope
 	)
 	assert.NoError(suite.T(), err)
 
 	// Check the test_token_2 was deleted
-	notFound = suite.db.Unscoped().Where("token = ?", "test_token_2").
+	notFound = suite.db.Unscoped().Where("token = ?", models.HashToken("test_token_2")).
 		First(new(models.OauthAccessToken)).RecordNotFound()
 	assert.True(suite.T(), notFound)
 
@@ -180,7 +180,7 @@ func (suite *OauthTestSuite) TestGrantAccessTokenDeletesExpiredTokens() {
 		"test_token_4",
 	}
 	for _, token := range existingTokens {
-		notFound := suite.db.Unscoped().Where("token = ?", token).
+		notFound := suite.db.Unscoped().Where("token = ?", models.HashToken(token)).
 			First(new(models.OauthAccessToken)).RecordNotFound()
 		assert.False(suite.T(), notFound)
 	}
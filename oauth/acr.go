@@ -0,0 +1,101 @@
+package oauth
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/RichardKnop/go-oauth2-server/util/totp"
+)
+
+const (
+	// AcrPassword is the authentication context of a password login
+	AcrPassword = "pwd"
+	// AcrMFA is the authentication context of a login with a second factor
+	AcrMFA = "mfa"
+)
+
+var (
+	// ErrAcrNotSatisfiable ...
+	ErrAcrNotSatisfiable = errors.New("Requested authentication context cannot be satisfied")
+	// ErrMFARequired ...
+	ErrMFARequired = errors.New("Multi-factor authentication required")
+	// ErrInvalidOTP ...
+	ErrInvalidOTP = errors.New("Invalid one-time password")
+)
+
+// AuthenticateAcr performs any additional authentication required by the
+// requested acr_values and returns the authentication context achieved.
+// Users with an authenticator always need their second factor, whatever
+// was requested, so the password alone is never enough to log them in.
+func (s *Service) AuthenticateAcr(user *models.OauthUser, acrValues, otp string) (string, error) {
+	requested := strings.Fields(acrValues)
+
+	// A second factor is required or has been requested
+	if totpEnrolled(user) || util.StringInSlice(AcrMFA, requested) {
+		if !totpEnrolled(user) {
+			return "", ErrAcrNotSatisfiable
+		}
+		if otp == "" {
+			return "", ErrMFARequired
+		}
+		valid, err := s.checkOTP(user, otp)
+		if err != nil {
+			return "", err
+		}
+		if !valid {
+			// AuthLogin counts this towards the lockout like a wrong password
+			return "", ErrInvalidOTP
+		}
+		return AcrMFA, nil
+	}
+
+	// Nothing beyond the password has been requested
+	if len(requested) == 0 || util.StringInSlice(AcrPassword, requested) {
+		return AcrPassword, nil
+	}
+
+	return "", ErrAcrNotSatisfiable
+}
+
+// checkOTP returns true if the one-time password is valid and has not been
+// used before, or if it is an unused recovery code. Accepting a one-time
+// password records its time step so it cannot be replayed.
+func (s *Service) checkOTP(user *models.OauthUser, otp string) (bool, error) {
+	if step, ok := totp.ValidateStep(user.TOTPSecret.String, otp, time.Now()); ok {
+		return s.useTOTPStep(user, step)
+	}
+
+	// A recovery code can be used once in place of the one-time password
+	return s.useRecoveryCode(user, otp)
+}
+
+// useTOTPStep records the time step of an accepted one-time password, it
+// returns false if a code of the same or a later step was accepted already.
+// The update is conditional so only one of concurrent logins can use a code.
+func (s *Service) useTOTPStep(user *models.OauthUser, step int64) (bool, error) {
+	result := s.db.Model(new(models.OauthUser)).
+		Where("id = ? AND totp_last_step < ?", user.ID, step).
+		UpdateColumn("totp_last_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	user.TOTPLastStep = step
+	return true, nil
+}
+
+// setAcr records the authentication context achieved on the access token
+func (s *Service) setAcr(accessToken *models.OauthAccessToken, acr string) error {
+	err := s.db.Model(new(models.OauthAccessToken)).Where("id = ?", accessToken.ID).
+		UpdateColumn("acr", acr).Error
+	if err != nil {
+		return err
+	}
+	accessToken.Acr = acr
+	return nil
+}
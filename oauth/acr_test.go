@@ -0,0 +1,82 @@
+package oauth_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	"github.com/RichardKnop/go-oauth2-server/test-util"
+	"github.com/RichardKnop/go-oauth2-server/util/totp"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestPasswordGrantWithMFA() {
+	// Insert a test user with an authenticator
+	secret, err := totp.GenerateSecret()
+	assert.NoError(suite.T(), err)
+	user, err := suite.service.CreateUser(roles.User, "test@user_mfa", "test_password")
+	assert.NoError(suite.T(), err, "Inserting test data failed")
+	err = suite.db.Model(user).UpdateColumn("totp_secret", secret).Error
+	assert.NoError(suite.T(), err, "Inserting test data failed")
+
+	otp, err := totp.Code(secret, time.Now())
+	assert.NoError(suite.T(), err)
+
+	// Requesting mfa without a one-time password signals step-up is needed
+	w := suite.passwordGrantWithAcr("test@user_mfa", "mfa", "")
+	testutil.TestResponseForOauthError(suite.T(), w, "mfa_required", oauth.ErrMFARequired.Error(), 400)
+
+	// An invalid one-time password is rejected
+	w = suite.passwordGrantWithAcr("test@user_mfa", "mfa", "abcdef")
+	testutil.TestResponseForOauthError(suite.T(), w, "invalid_grant", oauth.ErrInvalidOTP.Error(), 400)
+
+	// The second factor is recorded on the access token
+	w = suite.passwordGrantWithAcr("test@user_mfa", "mfa", otp)
+	suite.assertAcr(w, oauth.AcrMFA)
+
+	// Enrolled users need the second factor even without acr_values
+	w = suite.passwordGrantWithAcr("test@user_mfa", "", "")
+	testutil.TestResponseForOauthError(suite.T(), w, "mfa_required", oauth.ErrMFARequired.Error(), 400)
+	w = suite.passwordGrantWithAcr("test@user_mfa", "pwd", "")
+	testutil.TestResponseForOauthError(suite.T(), w, "mfa_required", oauth.ErrMFARequired.Error(), 400)
+}
+
+func (suite *OauthTestSuite) TestPasswordGrantWithMFANotSatisfiable() {
+	// The user has no authenticator
+	w := suite.passwordGrantWithAcr("test@user", "mfa", "123456")
+	testutil.TestResponseForOauthError(suite.T(), w, "unmet_authentication_requirements", oauth.ErrAcrNotSatisfiable.Error(), 400)
+
+	// Unknown authentication contexts cannot be satisfied either
+	w = suite.passwordGrantWithAcr("test@user", "bogus", "")
+	testutil.TestResponseForOauthError(suite.T(), w, "unmet_authentication_requirements", oauth.ErrAcrNotSatisfiable.Error(), 400)
+}
+
+func (suite *OauthTestSuite) passwordGrantWithAcr(username, acrValues, otp string) *httptest.ResponseRecorder {
+	return suite.tokenRequest(url.Values{
+		"grant_type": {"password"},
+		"username":   {username},
+		"password":   {"test_password"},
+		"acr_values": {acrValues},
+		"otp":        {otp},
+	}, nil)
+}
+
+func (suite *OauthTestSuite) assertAcr(w *httptest.ResponseRecorder, acr string) {
+	if !assert.Equal(suite.T(), 200, w.Code) {
+		return
+	}
+	resp := new(oauth.AccessTokenResponse)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), resp))
+
+	accessToken := new(models.OauthAccessToken)
+	assert.False(suite.T(), suite.db.Where("token = ?", models.HashToken(resp.AccessToken)).First(accessToken).RecordNotFound())
+	assert.Equal(suite.T(), acr, accessToken.Acr)
+
+	introspect, err := suite.service.NewIntrospectResponseFromAccessToken(accessToken)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), acr, introspect.Acr)
+}
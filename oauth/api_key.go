@@ -0,0 +1,89 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+)
+
+const (
+	// apiKeyBytes is the number of random bytes in an API key
+	apiKeyBytes = 32
+	// defaultAPIKeyTokenLifetime is used when the lifetime is not configured
+	defaultAPIKeyTokenLifetime = 300
+)
+
+var (
+	// ErrAPIKeyNotFound ...
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	// ErrAPIKeyRevoked ...
+	ErrAPIKeyRevoked = errors.New("API key revoked")
+)
+
+// CreateAPIKey creates a new API key for the client, optionally on behalf of
+// a user, and returns it. The key cannot be retrieved again as only its hash is stored.
+func (s *Service) CreateAPIKey(client *models.OauthClient, user *models.OauthUser, scope string) (string, error) {
+	// Make sure the scope is valid
+	scope, err := s.GetScope(scope)
+	if err != nil {
+		return "", err
+	}
+
+	b := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	key := hex.EncodeToString(b)
+
+	if err := s.db.Create(models.NewOauthAPIKey(client, user, models.HashToken(key), scope)).Error; err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
+
+// RevokeAPIKey revokes an API key so it can no longer be exchanged,
+// tokens already issued for it expire on their own shortly
+func (s *Service) RevokeAPIKey(key string) error {
+	result := s.db.Model(new(models.OauthAPIKey)).
+		Where("key_hash = ?", models.HashToken(key)).Where("revoked_at IS NULL").
+		UpdateColumn("revoked_at", time.Now().UTC())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// getValidAPIKey returns a non revoked API key issued to the client
+func (s *Service) getValidAPIKey(key string, client *models.OauthClient) (*models.OauthAPIKey, error) {
+	// Fetch the API key from the database
+	apiKey := new(models.OauthAPIKey)
+	notFound := models.OauthAPIKeyPreload(s.db).Where("client_id = ?", client.ID).
+		Where("key_hash = ?", models.HashToken(key)).First(apiKey).RecordNotFound()
+
+	// Not found
+	if notFound {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	// Check the API key hasn't been revoked
+	if apiKey.RevokedAt != nil {
+		return nil, ErrAPIKeyRevoked
+	}
+
+	return apiKey, nil
+}
+
+// getAPIKeyTokenLifetime returns the configured lifetime of tokens issued for API keys
+func (s *Service) getAPIKeyTokenLifetime() int {
+	if s.cnf.Oauth.APIKeyTokenLifetime <= 0 {
+		return defaultAPIKeyTokenLifetime
+	}
+	return s.cnf.Oauth.APIKeyTokenLifetime
+}
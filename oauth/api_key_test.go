@@ -0,0 +1,57 @@
+package oauth_test
+
+import (
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/test-util"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestAPIKey() {
+	key, err := suite.service.CreateAPIKey(suite.clients[0], suite.users[1], "read_write")
+	assert.NoError(suite.T(), err)
+
+	// The key is exchanged for a short-lived token with its scope
+	w := suite.exchangeAPIKey("test_client_1", key)
+	assert.Equal(suite.T(), 200, w.Code)
+	resp := suite.decodeAccessTokenResponse(w)
+	assert.NotEmpty(suite.T(), resp.AccessToken)
+	assert.Equal(suite.T(), "read_write", resp.Scope)
+	assert.Equal(suite.T(), suite.users[1].ID, resp.UserID)
+	assert.Equal(suite.T(), 300, resp.ExpiresIn)
+	assert.Empty(suite.T(), resp.RefreshToken)
+
+	// But only by the client it was created for
+	w = suite.exchangeAPIKey("test_client_2", key)
+	testutil.TestResponseForOauthError(suite.T(), w, "invalid_grant", oauth.ErrAPIKeyNotFound.Error(), 400)
+
+	// And not once revoked
+	assert.NoError(suite.T(), suite.service.RevokeAPIKey(key))
+	w = suite.exchangeAPIKey("test_client_1", key)
+	testutil.TestResponseForOauthError(suite.T(), w, "invalid_grant", oauth.ErrAPIKeyRevoked.Error(), 400)
+	assert.Equal(suite.T(), oauth.ErrAPIKeyNotFound, suite.service.RevokeAPIKey(key))
+}
+
+func (suite *OauthTestSuite) TestAPIKeyWithoutUser() {
+	key, err := suite.service.CreateAPIKey(suite.clients[0], nil, "")
+	assert.NoError(suite.T(), err)
+
+	w := suite.exchangeAPIKey("test_client_1", key)
+	assert.Equal(suite.T(), 200, w.Code)
+	resp := suite.decodeAccessTokenResponse(w)
+	assert.Equal(suite.T(), "read", resp.Scope)
+	assert.Empty(suite.T(), resp.UserID)
+
+	// Invalid scopes are rejected when creating keys
+	_, err = suite.service.CreateAPIKey(suite.clients[0], nil, "bogus")
+	assert.Equal(suite.T(), oauth.ErrInvalidScope, err)
+}
+
+func (suite *OauthTestSuite) exchangeAPIKey(clientID, key string) *httptest.ResponseRecorder {
+	return suite.tokenRequest(url.Values{
+		"grant_type": {"api_key"},
+		"api_key":    {key},
+	}, clientAuth(clientID))
+}
@@ -0,0 +1,145 @@
+package oauth
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util/jwt"
+)
+
+const (
+	// JWTBearerGrantType is the grant type clients exchange JWT assertions with
+	JWTBearerGrantType = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+)
+
+var (
+	// ErrInvalidAssertion ...
+	ErrInvalidAssertion = errors.New("Invalid assertion")
+	// ErrAssertionExpired ...
+	ErrAssertionExpired = errors.New("Assertion expired")
+	// ErrAssertionAudienceMismatch ...
+	ErrAssertionAudienceMismatch = errors.New("Assertion audience does not include this server")
+	// ErrAssertionSubjectNotFound ...
+	ErrAssertionSubjectNotFound = errors.New("Assertion subject not found")
+)
+
+// AddAssertionKey registers a public key for the client, JWT assertions from
+// the issuer signed by the matching private key can then be exchanged for tokens.
+// The key is either PEM encoded or a JSON Web Key, and a shared secret instead
+// for the HS256 algorithm.
+func (s *Service) AddAssertionKey(client *models.OauthClient, issuer, algorithm, publicKey string) (*models.OauthAssertionKey, error) {
+	// Make sure the key can verify assertions signed with the algorithm
+	if _, err := newAssertionVerifier(algorithm, publicKey); err != nil {
+		return nil, err
+	}
+
+	assertionKey := models.NewOauthAssertionKey(client, issuer, algorithm, publicKey)
+	if err := s.db.Create(assertionKey).Error; err != nil {
+		return nil, err
+	}
+	assertionKey.Client = client
+
+	return assertionKey, nil
+}
+
+// verifyAssertion returns the claims of an assertion signed with one of
+// the keys registered for the client by the issuer of the assertion,
+// endpoint is the URL of the endpoint the assertion was presented to
+func (s *Service) verifyAssertion(assertion string, client *models.OauthClient, endpoint string) (jwt.Claims, error) {
+	header, err := jwt.ParseHeader(assertion)
+	if err != nil {
+		return nil, ErrInvalidAssertion
+	}
+
+	// Only keys registered for the signing algorithm can verify it
+	var assertionKeys []*models.OauthAssertionKey
+	err = s.db.Where("client_id = ?", client.ID).Where("algorithm = ?", header.Algorithm).
+		Order("created_at").Find(&assertionKeys).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, assertionKey := range assertionKeys {
+		verifier, err := newAssertionVerifier(assertionKey.Algorithm, assertionKey.PublicKey)
+		if err != nil {
+			continue
+		}
+		claims, err := jwt.Parse(assertion, verifier)
+		if err == jwt.ErrTokenExpired {
+			return nil, ErrAssertionExpired
+		}
+		if err != nil {
+			continue
+		}
+		// The key must have been registered for the issuer
+		if issuer, _ := claims.String("iss"); issuer != assertionKey.Issuer {
+			continue
+		}
+		return claims, s.checkAssertionClaims(claims, endpoint)
+	}
+
+	return nil, ErrInvalidAssertion
+}
+
+// newAssertionVerifier returns a verifier of assertions signed with the algorithm
+func newAssertionVerifier(algorithm, key string) (jwt.Verifier, error) {
+	if algorithm == jwt.HS256 {
+		return jwt.NewHS256("", []byte(key))
+	}
+
+	// Public keys can be registered as JSON Web Keys too
+	if strings.HasPrefix(strings.TrimSpace(key), "{") {
+		jsonWebKey := new(jwt.JSONWebKey)
+		if err := json.Unmarshal([]byte(key), jsonWebKey); err != nil {
+			return nil, jwt.ErrInvalidJSONWebKey
+		}
+		return jsonWebKey.Verifier(algorithm)
+	}
+
+	return jwt.NewVerifier(algorithm, []byte(key))
+}
+
+// checkAssertionClaims validates the claims required by RFC 7523 section 3
+func (s *Service) checkAssertionClaims(claims jwt.Claims, endpoint string) error {
+	now := time.Now().Unix()
+
+	// Assertions must expire, the signature check already rejected expired ones
+	if _, ok := claims.Int64("exp"); !ok {
+		return ErrInvalidAssertion
+	}
+	if nbf, ok := claims.Int64("nbf"); ok && now < nbf {
+		return ErrInvalidAssertion
+	}
+	if subject, _ := claims.String("sub"); subject == "" {
+		return ErrInvalidAssertion
+	}
+
+	// The assertion must be intended for this server or the endpoint
+	issuer := strings.TrimSuffix(s.cnf.JWT.Issuer, "/")
+	for _, audience := range assertionAudiences(claims) {
+		if audience := strings.TrimSuffix(audience, "/"); audience == issuer || audience == endpoint {
+			return nil
+		}
+	}
+	return ErrAssertionAudienceMismatch
+}
+
+// assertionAudiences returns the aud claim, which is either a string or an array
+func assertionAudiences(claims jwt.Claims) []string {
+	switch aud := claims["aud"].(type) {
+	case string:
+		return []string{aud}
+	case []interface{}:
+		audiences := make([]string, 0, len(aud))
+		for _, v := range aud {
+			if audience, ok := v.(string); ok {
+				audiences = append(audiences, audience)
+			}
+		}
+		return audiences
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package oauth
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/RichardKnop/go-oauth2-server/util/jwt"
+)
+
+var (
+	// ErrLoginSessionNotFound ...
+	ErrLoginSessionNotFound = errors.New("Login session not found")
+)
+
+// StartLoginSession records a login of the user to the web app, its session
+// cookie refers to it so that revoking the sessions of the user, e.g. when
+// the password is reset or the user is deprovisioned, logs the user out
+func (s *Service) StartLoginSession(user *models.OauthUser) (*models.OauthSession, error) {
+	session := models.NewOauthSession(user, time.Now().UTC())
+	if err := s.db.Create(session).Error; err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// GetLoginSession returns a login session which has not been revoked
+func (s *Service) GetLoginSession(sessionID string) (*models.OauthSession, error) {
+	session := new(models.OauthSession)
+	if sessionID == "" || s.db.Where("id = ?", sessionID).First(session).RecordNotFound() {
+		return nil, ErrLoginSessionNotFound
+	}
+	return session, nil
+}
+
+// EndLoginSession revokes a login session when the user logs out
+func (s *Service) EndLoginSession(sessionID string) error {
+	return s.db.Unscoped().Where("id = ?", sessionID).Delete(new(models.OauthSession)).Error
+}
+
+// startSession records a new login of the user authenticated at authTime,
+// it returns nil if session claims are disabled
+func (s *Service) startSession(user *models.OauthUser, authTime time.Time) (*models.OauthSession, error) {
+	if !s.cnf.Oauth.SessionClaims || user == nil {
+		return nil, nil
+	}
+
+	session := models.NewOauthSession(user, authTime)
+	if err := s.db.Create(session).Error; err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// findSession returns the session a token was issued for,
+// it returns nil if the token has no session or session claims are disabled
+func (s *Service) findSession(sessionID sql.NullString) *models.OauthSession {
+	if !s.cnf.Oauth.SessionClaims || !sessionID.Valid {
+		return nil
+	}
+
+	session := new(models.OauthSession)
+	if s.db.Where("id = ?", sessionID.String).First(session).RecordNotFound() {
+		return nil
+	}
+	return session
+}
+
+// setSession ties the tokens to the session
+func (s *Service) setSession(accessToken *models.OauthAccessToken, refreshToken *models.OauthRefreshToken, session *models.OauthSession) error {
+	if session == nil {
+		return nil
+	}
+
+	sessionID := util.StringOrNull(session.ID)
+	err := s.db.Model(new(models.OauthAccessToken)).Where("id = ?", accessToken.ID).
+		UpdateColumn("session_id", sessionID).Error
+	if err != nil {
+		return err
+	}
+	accessToken.SessionID = sessionID
+
+	if refreshToken == nil {
+		return nil
+	}
+	err = s.db.Model(new(models.OauthRefreshToken)).Where("id = ?", refreshToken.ID).
+		UpdateColumn("session_id", sessionID).Error
+	if err != nil {
+		return err
+	}
+	refreshToken.SessionID = sessionID
+
+	return nil
+}
+
+// addSessionClaims adds sid and auth_time claims of the session, if any
+func addSessionClaims(claims jwt.Claims, session *models.OauthSession) {
+	if session == nil {
+		return
+	}
+	claims["sid"] = session.ID
+	claims["auth_time"] = session.AuthTime.Unix()
+}
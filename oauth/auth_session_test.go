@@ -0,0 +1,159 @@
+package oauth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/RichardKnop/go-oauth2-server/util/jwt"
+	"github.com/RichardKnop/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestSessionClaimsSharedByLogin() {
+	suite.cnf.Oauth.SessionClaims = true
+	defer func() { suite.cnf.Oauth.SessionClaims = false }()
+
+	// Log in
+	before := time.Now().Unix()
+	resp := suite.decodeAccessTokenResponse(suite.passwordGrantWithAcr("test@user", "", ""))
+	after := time.Now().Unix()
+
+	// The access and refresh tokens share the session
+	accessTokenResp := suite.introspectAccessToken(resp.AccessToken)
+	assert.NotEmpty(suite.T(), accessTokenResp.Sid)
+	assert.True(suite.T(), accessTokenResp.AuthTime >= before && accessTokenResp.AuthTime <= after)
+	refreshTokenResp := suite.introspectRefreshToken(resp.RefreshToken)
+	assert.Equal(suite.T(), accessTokenResp.Sid, refreshTokenResp.Sid)
+	assert.Equal(suite.T(), accessTokenResp.AuthTime, refreshTokenResp.AuthTime)
+
+	// Refreshing keeps the session and its authentication time
+	refreshed := suite.decodeAccessTokenResponse(suite.refreshTokenGrant(resp.RefreshToken))
+	refreshedResp := suite.introspectAccessToken(refreshed.AccessToken)
+	assert.Equal(suite.T(), accessTokenResp.Sid, refreshedResp.Sid)
+	assert.Equal(suite.T(), accessTokenResp.AuthTime, refreshedResp.AuthTime)
+
+	// Logging in again starts a new session
+	again := suite.decodeAccessTokenResponse(suite.passwordGrantWithAcr("test@user", "", ""))
+	assert.NotEqual(suite.T(), accessTokenResp.Sid, suite.introspectAccessToken(again.AccessToken).Sid)
+}
+
+func (suite *OauthTestSuite) TestSessionClaimsInIDToken() {
+	suite.cnf.Oauth.SessionClaims = true
+	defer func() { suite.cnf.Oauth.SessionClaims = false }()
+
+	// Insert a test authorization code granted a minute ago
+	authTime := time.Now().UTC().Add(-time.Minute)
+	err := suite.db.Create(&models.OauthAuthorizationCode{
+		MyGormModel: models.MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: authTime,
+		},
+		Code:        "test_code",
+		ExpiresAt:   time.Now().UTC().Add(+10 * time.Second),
+		Client:      suite.clients[0],
+		User:        suite.users[0],
+		RedirectURI: util.StringOrNull("https://www.example.com"),
+		Scope:       "read_write openid",
+	}).Error
+	assert.NoError(suite.T(), err, "Inserting test data failed")
+
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
+	r.PostForm = url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {"test_code"},
+		"redirect_uri": {"https://www.example.com"},
+	}
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	resp := suite.decodeAccessTokenResponse(w)
+
+	// The ID token and the access token share the session
+	signingKey, err := jwt.NewHS256("", []byte(suite.cnf.JWT.Secret))
+	assert.NoError(suite.T(), err)
+	claims, err := jwt.Parse(resp.IDToken, signingKey)
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	sid, _ := claims.String("sid")
+	assert.Equal(suite.T(), suite.introspectAccessToken(resp.AccessToken).Sid, sid)
+
+	// The authentication time is when the user granted the code
+	claimedAuthTime, _ := claims.Int64("auth_time")
+	assert.Equal(suite.T(), authTime.Unix(), claimedAuthTime)
+}
+
+func (suite *OauthTestSuite) TestSessionClaimsDisabled() {
+	resp := suite.decodeAccessTokenResponse(suite.passwordGrantWithAcr("test@user", "", ""))
+
+	introspectResp := suite.introspectAccessToken(resp.AccessToken)
+	assert.Empty(suite.T(), introspectResp.Sid)
+	assert.Empty(suite.T(), introspectResp.AuthTime)
+}
+
+func (suite *OauthTestSuite) TestLoginSession() {
+	user, err := suite.service.CreateUser(roles.User, "test@user_session", "test_password")
+	if !assert.NoError(suite.T(), err, "Inserting test data failed") {
+		return
+	}
+
+	// Login sessions last until the user logs out
+	loginSession, err := suite.service.StartLoginSession(user)
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	_, err = suite.service.GetLoginSession(loginSession.ID)
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), suite.service.EndLoginSession(loginSession.ID))
+	_, err = suite.service.GetLoginSession(loginSession.ID)
+	assert.Equal(suite.T(), oauth.ErrLoginSessionNotFound, err)
+	_, err = suite.service.GetLoginSession("")
+	assert.Equal(suite.T(), oauth.ErrLoginSessionNotFound, err)
+
+	// Or the user is deprovisioned
+	loginSession, err = suite.service.StartLoginSession(user)
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	assert.NoError(suite.T(), suite.service.SetUserDisabled(user, true))
+	_, err = suite.service.GetLoginSession(loginSession.ID)
+	assert.Equal(suite.T(), oauth.ErrLoginSessionNotFound, err)
+}
+
+func (suite *OauthTestSuite) decodeAccessTokenResponse(w *httptest.ResponseRecorder) *oauth.AccessTokenResponse {
+	assert.Equal(suite.T(), 200, w.Code)
+	resp := new(oauth.AccessTokenResponse)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), resp))
+	return resp
+}
+
+func (suite *OauthTestSuite) introspectAccessToken(token string) *oauth.IntrospectResponse {
+	accessToken, err := suite.service.Authenticate(token)
+	assert.NoError(suite.T(), err)
+	resp, err := suite.service.NewIntrospectResponseFromAccessToken(accessToken)
+	assert.NoError(suite.T(), err)
+	return resp
+}
+
+func (suite *OauthTestSuite) introspectRefreshToken(token string) *oauth.IntrospectResponse {
+	refreshToken, err := suite.service.GetValidRefreshToken(token, suite.clients[0])
+	assert.NoError(suite.T(), err)
+	resp, err := suite.service.NewIntrospectResponseFromRefreshToken(refreshToken)
+	assert.NoError(suite.T(), err)
+	return resp
+}
+
+func (suite *OauthTestSuite) refreshTokenGrant(token string) *httptest.ResponseRecorder {
+	return suite.tokenRequest(url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {token},
+	}, nil)
+}
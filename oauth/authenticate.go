@@ -18,9 +18,27 @@ var (
 
 // Authenticate checks the access token is valid
 func (s *Service) Authenticate(token string) (*models.OauthAccessToken, error) {
+	return s.authenticate(token, s.cnf.Oauth.EnableValidationCache)
+}
+
+// authenticate checks the access token is valid, using the validation
+// cache if useCache is true
+func (s *Service) authenticate(token string, useCache bool) (*models.OauthAccessToken, error) {
+	// JWT access tokens are stored by their jti claim
+	token, err := s.decodeAccessToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if useCache {
+		if accessToken, ok := s.validationCache.Get(token); ok {
+			return accessToken, nil
+		}
+	}
+
 	// Fetch the access token from the database
 	accessToken := new(models.OauthAccessToken)
-	notFound := s.db.Where("token = ?", token).First(accessToken).RecordNotFound()
+	notFound := s.db.Where("token = ?", models.HashToken(token)).First(accessToken).RecordNotFound()
 
 	// Not found
 	if notFound {
@@ -32,6 +50,17 @@ func (s *Service) Authenticate(token string) (*models.OauthAccessToken, error) {
 		return nil, ErrAccessTokenExpired
 	}
 
+	// Tokens of a disabled client stop working straight away, including
+	// any issued while the client was being disabled
+	if accessToken.ClientID.Valid && s.clientDisabled(accessToken.ClientID.String) {
+		return nil, ErrClientDisabled
+	}
+
+	// The same goes for the tokens of a disabled user
+	if accessToken.UserID.Valid && s.userDisabled(accessToken.UserID.String) {
+		return nil, ErrUserDisabled
+	}
+
 	// Extend refresh token expiration database
 	query := s.db.Model(new(models.OauthRefreshToken)).Where("client_id = ?", accessToken.ClientID.String)
 	if accessToken.UserID.Valid {
@@ -40,12 +69,16 @@ func (s *Service) Authenticate(token string) (*models.OauthAccessToken, error) {
 		query = query.Where("user_id IS NULL")
 	}
 	increasedExpiresAt := gorm.NowFunc().Add(
-		time.Duration(s.cnf.Oauth.RefreshTokenLifetime) * time.Second,
+		time.Duration(s.getSlidingRefreshTokenLifetime(accessToken.ClientID.String)) * time.Second,
 	)
 	if err := query.UpdateColumn("expires_at", increasedExpiresAt).Error; err != nil {
 		return nil, err
 	}
 
+	if useCache {
+		s.validationCache.Set(accessToken, s.getValidationCacheTTL())
+	}
+
 	return accessToken, nil
 }
 
@@ -53,15 +86,16 @@ func (s *Service) Authenticate(token string) (*models.OauthAccessToken, error) {
 func (s *Service) ClearUserTokens(userSession *session.UserSession) {
 	// Clear all refresh tokens with user_id and client_id
 	refreshToken := new(models.OauthRefreshToken)
-	found := !models.OauthRefreshTokenPreload(s.db).Where("token = ?", userSession.RefreshToken).First(refreshToken).RecordNotFound()
+	found := !models.OauthRefreshTokenPreload(s.db).Where("token = ?", models.HashToken(userSession.RefreshToken)).First(refreshToken).RecordNotFound()
 	if found {
 		s.db.Unscoped().Where("client_id = ? AND user_id = ?", refreshToken.ClientID, refreshToken.UserID).Delete(models.OauthRefreshToken{})
 	}
 
 	// Clear all access tokens with user_id and client_id
 	accessToken := new(models.OauthAccessToken)
-	found = !models.OauthAccessTokenPreload(s.db).Where("token = ?", userSession.AccessToken).First(accessToken).RecordNotFound()
+	found = !models.OauthAccessTokenPreload(s.db).Where("token = ?", models.HashToken(userSession.AccessToken)).First(accessToken).RecordNotFound()
 	if found {
 		s.db.Unscoped().Where("client_id = ? AND user_id = ?", accessToken.ClientID, accessToken.UserID).Delete(models.OauthAccessToken{})
+		s.validationCache.Invalidate(accessToken.ClientID, accessToken.UserID)
 	}
 }
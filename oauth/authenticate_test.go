@@ -95,7 +95,7 @@ func (suite *OauthTestSuite) TestAuthenticate() {
 
 	// Correct access token should be returned
 	if assert.NotNil(suite.T(), accessToken) {
-		assert.Equal(suite.T(), "test_client_token", accessToken.Token)
+		assert.Equal(suite.T(), models.HashToken("test_client_token"), accessToken.TokenHash)
 		assert.EqualValues(suite.T(), suite.clients[0].ID, accessToken.ClientID.String)
 		assert.False(suite.T(), accessToken.UserID.Valid)
 	}
@@ -108,7 +108,7 @@ func (suite *OauthTestSuite) TestAuthenticate() {
 
 	// Correct access token should be returned
 	if assert.NotNil(suite.T(), accessToken) {
-		assert.Equal(suite.T(), "test_user_token", accessToken.Token)
+		assert.Equal(suite.T(), models.HashToken("test_user_token"), accessToken.TokenHash)
 		assert.EqualValues(suite.T(), suite.clients[0].ID, accessToken.ClientID.String)
 		assert.EqualValues(suite.T(), suite.users[0].ID, accessToken.UserID.String)
 	}
@@ -126,6 +126,10 @@ func (suite *OauthTestSuite) TestAuthenticateRollingRefreshToken() {
 		refreshTokens     []*models.OauthRefreshToken
 	)
 
+	// The clock is frozen below, restore it for the tests that follow
+	nowFunc := gorm.NowFunc
+	defer func() { gorm.NowFunc = nowFunc }()
+
 	// Insert some test access tokens
 	testAccessTokens = []*models.OauthAccessToken{
 		{
@@ -207,29 +211,31 @@ func (suite *OauthTestSuite) TestAuthenticateRollingRefreshToken() {
 	}
 	accessToken, err = suite.service.Authenticate("test_token_1")
 	assert.Nil(suite.T(), err)
-	assert.Equal(suite.T(), "test_token_1", accessToken.Token)
+	assert.Equal(suite.T(), models.HashToken("test_token_1"), accessToken.TokenHash)
 	assert.EqualValues(suite.T(), suite.clients[0].ID, accessToken.ClientID.String)
 	assert.EqualValues(suite.T(), suite.users[0].ID, accessToken.UserID.String)
 
 	// First refresh token expiration date should be extended
 	refreshTokens = make([]*models.OauthRefreshToken, len(testRefreshTokens))
-	err = suite.db.Where(
-		"token IN ('test_token_1', 'test_token_2', 'test_token_3')",
-	).Order("created_at").Find(&refreshTokens).Error
+	err = suite.db.Where("token IN (?)", []string{
+		models.HashToken("test_token_1"),
+		models.HashToken("test_token_2"),
+		models.HashToken("test_token_3"),
+	}).Order("created_at").Find(&refreshTokens).Error
 	assert.Nil(suite.T(), err)
-	assert.Equal(suite.T(), "test_token_1", refreshTokens[0].Token)
+	assert.Equal(suite.T(), models.HashToken("test_token_1"), refreshTokens[0].TokenHash)
 	assert.Equal(
 		suite.T(),
 		now1.Unix()+int64(suite.cnf.Oauth.RefreshTokenLifetime),
 		refreshTokens[0].ExpiresAt.Unix(),
 	)
-	assert.Equal(suite.T(), "test_token_2", refreshTokens[1].Token)
+	assert.Equal(suite.T(), models.HashToken("test_token_2"), refreshTokens[1].TokenHash)
 	assert.Equal(
 		suite.T(),
 		testRefreshTokens[1].ExpiresAt.Unix(),
 		refreshTokens[1].ExpiresAt.Unix(),
 	)
-	assert.Equal(suite.T(), "test_token_3", refreshTokens[2].Token)
+	assert.Equal(suite.T(), models.HashToken("test_token_3"), refreshTokens[2].TokenHash)
 	assert.Equal(
 		suite.T(),
 		testRefreshTokens[2].ExpiresAt.Unix(),
@@ -243,29 +249,31 @@ func (suite *OauthTestSuite) TestAuthenticateRollingRefreshToken() {
 	}
 	accessToken, err = suite.service.Authenticate("test_token_2")
 	assert.Nil(suite.T(), err)
-	assert.Equal(suite.T(), "test_token_2", accessToken.Token)
+	assert.Equal(suite.T(), models.HashToken("test_token_2"), accessToken.TokenHash)
 	assert.EqualValues(suite.T(), suite.clients[0].ID, accessToken.ClientID.String)
 	assert.False(suite.T(), accessToken.UserID.Valid)
 
 	// Second refresh token expiration date should be extended
 	refreshTokens = make([]*models.OauthRefreshToken, len(testRefreshTokens))
-	err = suite.db.Where(
-		"token IN ('test_token_1', 'test_token_2', 'test_token_3')",
-	).Order("created_at").Find(&refreshTokens).Error
+	err = suite.db.Where("token IN (?)", []string{
+		models.HashToken("test_token_1"),
+		models.HashToken("test_token_2"),
+		models.HashToken("test_token_3"),
+	}).Order("created_at").Find(&refreshTokens).Error
 	assert.Nil(suite.T(), err)
-	assert.Equal(suite.T(), "test_token_1", refreshTokens[0].Token)
+	assert.Equal(suite.T(), models.HashToken("test_token_1"), refreshTokens[0].TokenHash)
 	assert.Equal(
 		suite.T(),
 		now1.Unix()+int64(suite.cnf.Oauth.RefreshTokenLifetime),
 		refreshTokens[0].ExpiresAt.Unix(),
 	)
-	assert.Equal(suite.T(), "test_token_2", refreshTokens[1].Token)
+	assert.Equal(suite.T(), models.HashToken("test_token_2"), refreshTokens[1].TokenHash)
 	assert.Equal(
 		suite.T(),
 		now2.Unix()+int64(suite.cnf.Oauth.RefreshTokenLifetime),
 		refreshTokens[1].ExpiresAt.Unix(),
 	)
-	assert.Equal(suite.T(), "test_token_3", refreshTokens[2].Token)
+	assert.Equal(suite.T(), models.HashToken("test_token_3"), refreshTokens[2].TokenHash)
 	assert.Equal(
 		suite.T(),
 		testRefreshTokens[2].ExpiresAt.Unix(),
@@ -279,29 +287,31 @@ func (suite *OauthTestSuite) TestAuthenticateRollingRefreshToken() {
 	}
 	accessToken, err = suite.service.Authenticate("test_token_3")
 	assert.Nil(suite.T(), err)
-	assert.Equal(suite.T(), "test_token_3", accessToken.Token)
+	assert.Equal(suite.T(), models.HashToken("test_token_3"), accessToken.TokenHash)
 	assert.EqualValues(suite.T(), suite.clients[0].ID, accessToken.ClientID.String)
 	assert.EqualValues(suite.T(), suite.users[1].ID, accessToken.UserID.String)
 
 	// First refresh token expiration date should be extended
 	refreshTokens = make([]*models.OauthRefreshToken, len(testRefreshTokens))
-	err = suite.db.Where(
-		"token IN ('test_token_1', 'test_token_2', 'test_token_3')",
-	).Order("created_at").Find(&refreshTokens).Error
+	err = suite.db.Where("token IN (?)", []string{
+		models.HashToken("test_token_1"),
+		models.HashToken("test_token_2"),
+		models.HashToken("test_token_3"),
+	}).Order("created_at").Find(&refreshTokens).Error
 	assert.Nil(suite.T(), err)
-	assert.Equal(suite.T(), "test_token_1", refreshTokens[0].Token)
+	assert.Equal(suite.T(), models.HashToken("test_token_1"), refreshTokens[0].TokenHash)
 	assert.Equal(
 		suite.T(),
 		now1.Unix()+int64(suite.cnf.Oauth.RefreshTokenLifetime),
 		refreshTokens[0].ExpiresAt.Unix(),
 	)
-	assert.Equal(suite.T(), "test_token_2", refreshTokens[1].Token)
+	assert.Equal(suite.T(), models.HashToken("test_token_2"), refreshTokens[1].TokenHash)
 	assert.Equal(
 		suite.T(),
 		now2.Unix()+int64(suite.cnf.Oauth.RefreshTokenLifetime),
 		refreshTokens[1].ExpiresAt.Unix(),
 	)
-	assert.Equal(suite.T(), "test_token_3", refreshTokens[2].Token)
+	assert.Equal(suite.T(), models.HashToken("test_token_3"), refreshTokens[2].TokenHash)
 	assert.Equal(
 		suite.T(),
 		now3.Unix()+int64(suite.cnf.Oauth.RefreshTokenLifetime),
@@ -404,24 +414,24 @@ func (suite *OauthTestSuite) TestClearUserTokens() {
 	suite.service.ClearUserTokens(testUserSession)
 
 	// Assert that the refresh token was removed
-	found := !models.OauthRefreshTokenPreload(suite.db).Where("token = ?", testUserSession.RefreshToken).First(&models.OauthRefreshToken{}).RecordNotFound()
+	found := !models.OauthRefreshTokenPreload(suite.db).Where("token = ?", models.HashToken(testUserSession.RefreshToken)).First(&models.OauthRefreshToken{}).RecordNotFound()
 	assert.Equal(suite.T(), false, found)
 
 	// Assert that the access token was removed
-	found = !models.OauthAccessTokenPreload(suite.db).Where("token = ?", testUserSession.AccessToken).First(&models.OauthAccessToken{}).RecordNotFound()
+	found = !models.OauthAccessTokenPreload(suite.db).Where("token = ?", models.HashToken(testUserSession.AccessToken)).First(&models.OauthAccessToken{}).RecordNotFound()
 	assert.Equal(suite.T(), false, found)
 
 	// Assert that the other two tokens are still there
 	// Refresh tokens
-	found = !models.OauthRefreshTokenPreload(suite.db).Where("token = ?", "test_token_2").First(&models.OauthRefreshToken{}).RecordNotFound()
+	found = !models.OauthRefreshTokenPreload(suite.db).Where("token = ?", models.HashToken("test_token_2")).First(&models.OauthRefreshToken{}).RecordNotFound()
 	assert.Equal(suite.T(), true, found)
-	found = !models.OauthRefreshTokenPreload(suite.db).Where("token = ?", "test_token_3").First(&models.OauthRefreshToken{}).RecordNotFound()
+	found = !models.OauthRefreshTokenPreload(suite.db).Where("token = ?", models.HashToken("test_token_3")).First(&models.OauthRefreshToken{}).RecordNotFound()
 	assert.Equal(suite.T(), true, found)
 
 	// Access tokens
-	found = !models.OauthAccessTokenPreload(suite.db).Where("token = ?", "test_token_2").First(&models.OauthAccessToken{}).RecordNotFound()
+	found = !models.OauthAccessTokenPreload(suite.db).Where("token = ?", models.HashToken("test_token_2")).First(&models.OauthAccessToken{}).RecordNotFound()
 	assert.Equal(suite.T(), true, found)
-	found = !models.OauthAccessTokenPreload(suite.db).Where("token = ?", "test_token_3").First(&models.OauthAccessToken{}).RecordNotFound()
+	found = !models.OauthAccessTokenPreload(suite.db).Where("token = ?", models.HashToken("test_token_3")).First(&models.OauthAccessToken{}).RecordNotFound()
 	assert.Equal(suite.T(), true, found)
 
 }
@@ -12,12 +12,27 @@ var (
 	ErrAuthorizationCodeNotFound = errors.New("Authorization code not found")
 	// ErrAuthorizationCodeExpired ...
 	ErrAuthorizationCodeExpired = errors.New("Authorization code expired")
+	// ErrAuthorizationCodeUsed ...
+	ErrAuthorizationCodeUsed = errors.New("Authorization code already used")
 )
 
-// GrantAuthorizationCode grants a new authorization code
-func (s *Service) GrantAuthorizationCode(client *models.OauthClient, user *models.OauthUser, expiresIn int, redirectURI, scope string) (*models.OauthAuthorizationCode, error) {
+// GrantAuthorizationCode grants a new authorization code, the code challenge
+// is optional and binds the code to the client's PKCE code verifier, the
+// authorization details are optional and must have been parsed already, the
+// nonce is optional and echoed in the ID token the code is exchanged for
+func (s *Service) GrantAuthorizationCode(client *models.OauthClient, user *models.OauthUser, expiresIn int, redirectURI, scope, codeChallenge, codeChallengeMethod, authorizationDetails, nonce string) (*models.OauthAuthorizationCode, error) {
+	// Validate the code challenge before anything is stored
+	codeChallengeMethod, err := getCodeChallengeMethod(codeChallenge, codeChallengeMethod)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create a new authorization code
 	authorizationCode := models.NewOauthAuthorizationCode(client, user, expiresIn, redirectURI, scope)
+	authorizationCode.CodeChallenge = codeChallenge
+	authorizationCode.CodeChallengeMethod = codeChallengeMethod
+	authorizationCode.AuthorizationDetails = authorizationDetails
+	authorizationCode.Nonce = nonce
 	if err := s.db.Create(authorizationCode).Error; err != nil {
 		return nil, err
 	}
@@ -39,11 +54,26 @@ func (s *Service) getValidAuthorizationCode(code, redirectURI string, client *mo
 		return nil, ErrAuthorizationCodeNotFound
 	}
 
+	// A code presented again after being exchanged is rejected
+	if authorizationCode.ExchangedAt != nil {
+		if err := s.handleAuthorizationCodeReplay(authorizationCode); err != nil {
+			return nil, err
+		}
+		return nil, ErrAuthorizationCodeUsed
+	}
+
 	// Redirect URI must match if it was used to obtain the authorization code
 	if redirectURI != authorizationCode.RedirectURI.String {
 		return nil, ErrInvalidRedirectURI
 	}
 
+	// And still be registered for the client, it may have been removed since
+	if redirectURI != "" {
+		if err := s.ValidateRedirectURI(client, redirectURI); err != nil {
+			return nil, ErrInvalidRedirectURI
+		}
+	}
+
 	// Check the authorization code hasn't expired
 	if time.Now().After(authorizationCode.ExpiresAt) {
 		return nil, ErrAuthorizationCodeExpired
@@ -51,3 +81,48 @@ func (s *Service) getValidAuthorizationCode(code, redirectURI string, client *mo
 
 	return authorizationCode, nil
 }
+
+// exchangeAuthorizationCode makes sure the authorization code can only be
+// exchanged once, only one of concurrent exchanges can succeed. Codes are
+// kept around to detect replays unless replay revocation is disabled.
+func (s *Service) exchangeAuthorizationCode(authorizationCode *models.OauthAuthorizationCode) error {
+	if s.cnf.Oauth.DisableCodeReplayRevocation {
+		result := s.db.Unscoped().Delete(authorizationCode)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrAuthorizationCodeNotFound
+		}
+		return nil
+	}
+
+	now := time.Now().UTC()
+	result := s.db.Model(new(models.OauthAuthorizationCode)).
+		Where("id = ? AND exchanged_at IS NULL", authorizationCode.ID).
+		UpdateColumn("exchanged_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAuthorizationCodeUsed
+	}
+	authorizationCode.ExchangedAt = &now
+	return nil
+}
+
+// handleAuthorizationCodeReplay revokes the tokens issued from the code and
+// refreshed since, unless disabled, as a replayed code suggests it has been stolen.
+// Tokens the user was granted through other codes or logins are left alone.
+func (s *Service) handleAuthorizationCodeReplay(authorizationCode *models.OauthAuthorizationCode) error {
+	if s.cnf.Oauth.DisableCodeReplayRevocation {
+		return nil
+	}
+
+	// Tokens exchanged for the code descend from it
+	lineage, err := s.getTokenLineageByID(authorizationCode.ID)
+	if err != nil {
+		return err
+	}
+	return s.revokeTokenLineage(lineage)
+}
@@ -19,6 +19,10 @@ func (suite *OauthTestSuite) TestGrantAuthorizationCode() {
 		3600,                          // expires in
 		"redirect URI doesn't matter", // redirect URI
 		"scope doesn't matter",        // scope
+		"",                            // code challenge
+		"",                            // code challenge method
+		"",                            // authorization details
+		"",                            // nonce
 	)
 
 	// Error should be Nil
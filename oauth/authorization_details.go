@@ -0,0 +1,86 @@
+package oauth
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util"
+)
+
+var (
+	// ErrInvalidAuthorizationDetails ...
+	ErrInvalidAuthorizationDetails = errors.New("Invalid authorization details")
+)
+
+// ParseAuthorizationDetails validates the authorization_details parameter, a JSON
+// array of objects with one of the types, and returns it compacted or empty if
+// not requested, see https://tools.ietf.org/html/rfc9396#section-2
+func ParseAuthorizationDetails(authorizationDetails string, types []string) (string, error) {
+	if strings.TrimSpace(authorizationDetails) == "" {
+		return "", nil
+	}
+
+	var details []map[string]interface{}
+	if err := json.Unmarshal([]byte(authorizationDetails), &details); err != nil {
+		return "", ErrInvalidAuthorizationDetails
+	}
+	if len(details) == 0 {
+		return "", ErrInvalidAuthorizationDetails
+	}
+	for _, detail := range details {
+		detailType, ok := detail["type"].(string)
+		if !ok || !util.StringInSlice(detailType, types) {
+			return "", ErrInvalidAuthorizationDetails
+		}
+	}
+
+	compacted, err := json.Marshal(details)
+	if err != nil {
+		return "", err
+	}
+	return string(compacted), nil
+}
+
+// getAuthorizationDetails returns the authorization details requested
+// from the token endpoint, validated against the configured types
+func (s *Service) getAuthorizationDetails(authorizationDetails string) (string, error) {
+	return ParseAuthorizationDetails(authorizationDetails, s.cnf.Oauth.AuthorizationDetailsTypes)
+}
+
+// setAuthorizationDetails records the authorization details granted on the
+// access token and on the refresh token, if any, issued alongside it
+func (s *Service) setAuthorizationDetails(accessToken *models.OauthAccessToken, refreshToken *models.OauthRefreshToken, authorizationDetails string) error {
+	if authorizationDetails == "" {
+		return nil
+	}
+
+	err := s.db.Model(new(models.OauthAccessToken)).Where("id = ?", accessToken.ID).
+		UpdateColumn("authorization_details", authorizationDetails).Error
+	if err != nil {
+		return err
+	}
+	accessToken.AuthorizationDetails = authorizationDetails
+
+	if refreshToken == nil {
+		return nil
+	}
+	err = s.db.Model(new(models.OauthRefreshToken)).Where("id = ?", refreshToken.ID).
+		UpdateColumn("authorization_details", authorizationDetails).Error
+	if err != nil {
+		return err
+	}
+	refreshToken.AuthorizationDetails = authorizationDetails
+
+	return nil
+}
+
+// rawAuthorizationDetails returns authorization details to be included
+// in a JSON response as they are, or nil to leave them out
+func rawAuthorizationDetails(authorizationDetails string) json.RawMessage {
+	if authorizationDetails == "" {
+		return nil
+	}
+	return json.RawMessage(authorizationDetails)
+}
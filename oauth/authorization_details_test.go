@@ -0,0 +1,102 @@
+package oauth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/stretchr/testify/assert"
+)
+
+const testAuthorizationDetails = `[{"amount":"123.50","currency":"EUR","type":"payment_initiation"}]`
+
+func (suite *OauthTestSuite) TestParseAuthorizationDetails() {
+	types := []string{"payment_initiation", "account_information"}
+	for _, testCase := range []struct {
+		authorizationDetails string
+		expected             string
+		err                  error
+	}{
+		{"", "", nil},
+		{
+			`[ {"type": "payment_initiation", "currency": "EUR", "amount": "123.50"} ]`,
+			testAuthorizationDetails,
+			nil,
+		},
+		{
+			`[{"type":"payment_initiation"},{"type":"account_information"}]`,
+			`[{"type":"payment_initiation"},{"type":"account_information"}]`,
+			nil,
+		},
+		{"bogus", "", oauth.ErrInvalidAuthorizationDetails},
+		{"[]", "", oauth.ErrInvalidAuthorizationDetails},
+		{`{"type":"payment_initiation"}`, "", oauth.ErrInvalidAuthorizationDetails},
+		{`[{"currency":"EUR"}]`, "", oauth.ErrInvalidAuthorizationDetails},
+		{`[{"type":"bogus"}]`, "", oauth.ErrInvalidAuthorizationDetails},
+	} {
+		authorizationDetails, err := oauth.ParseAuthorizationDetails(testCase.authorizationDetails, types)
+		assert.Equal(suite.T(), testCase.err, err, testCase.authorizationDetails)
+		assert.Equal(suite.T(), testCase.expected, authorizationDetails, testCase.authorizationDetails)
+	}
+}
+
+func (suite *OauthTestSuite) TestClientCredentialsGrantWithAuthorizationDetails() {
+	suite.cnf.Oauth.AuthorizationDetailsTypes = []string{"payment_initiation"}
+	defer func() { suite.cnf.Oauth.AuthorizationDetailsTypes = nil }()
+
+	for _, testCase := range []struct {
+		authorizationDetails string
+		errorCode            string
+	}{
+		{testAuthorizationDetails, ""},
+		{`[{"type":"bogus"}]`, "invalid_authorization_details"},
+	} {
+		r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+		assert.NoError(suite.T(), err, "Request setup should not get an error")
+		r.SetBasicAuth("test_client_1", "test_secret")
+		r.PostForm = url.Values{
+			"grant_type":            {"client_credentials"},
+			"authorization_details": {testCase.authorizationDetails},
+		}
+		w := httptest.NewRecorder()
+		suite.router.ServeHTTP(w, r)
+
+		if testCase.errorCode != "" {
+			suite.assertErrorCode(w, testCase.errorCode)
+			continue
+		}
+
+		resp := suite.decodeAccessTokenResponse(w)
+		assert.JSONEq(suite.T(), testAuthorizationDetails, string(resp.AuthorizationDetails))
+		introspect := suite.introspectAccessToken(resp.AccessToken)
+		assert.JSONEq(suite.T(), testAuthorizationDetails, string(introspect.AuthorizationDetails))
+	}
+}
+
+func (suite *OauthTestSuite) TestAuthorizationCodeGrantWithAuthorizationDetails() {
+	authorizationCode, err := suite.service.GrantAuthorizationCode(
+		suite.clients[0],          // client
+		suite.users[0],            // user
+		3600,                      // expires in
+		"https://www.example.com", // redirect URI
+		"read_write",              // scope
+		"",                        // code challenge
+		"",                        // code challenge method
+		testAuthorizationDetails,  // authorization details
+		"",                        // nonce
+	)
+	assert.NoError(suite.T(), err)
+
+	// The tokens carry the authorization details the user approved
+	resp := suite.decodeAccessTokenResponse(suite.exchangeAuthorizationCodeWithVerifier(authorizationCode.Code, ""))
+	assert.JSONEq(suite.T(), testAuthorizationDetails, string(resp.AuthorizationDetails))
+	introspect := suite.introspectRefreshToken(resp.RefreshToken)
+	assert.JSONEq(suite.T(), testAuthorizationDetails, string(introspect.AuthorizationDetails))
+
+	// And keep them when refreshed
+	resp = suite.decodeAccessTokenResponse(suite.refreshTokenGrant(resp.RefreshToken))
+	assert.JSONEq(suite.T(), testAuthorizationDetails, string(resp.AuthorizationDetails))
+	introspect = suite.introspectAccessToken(resp.AccessToken)
+	assert.JSONEq(suite.T(), testAuthorizationDetails, string(introspect.AuthorizationDetails))
+}
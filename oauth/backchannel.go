@@ -0,0 +1,279 @@
+package oauth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/log"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util"
+)
+
+const (
+	// CIBAGrantType is the grant type clients exchange backchannel authentication requests with
+	CIBAGrantType = "urn:openid:params:grant-type:ciba"
+	// defaultBackchannelRequestLifetime is used when the lifetime is not configured
+	defaultBackchannelRequestLifetime = 120
+	// defaultBackchannelInterval is used when the interval is not configured
+	defaultBackchannelInterval = 5
+	// maxBindingMessageLength is the longest binding message shown to the user
+	maxBindingMessageLength = 100
+)
+
+var (
+	// ErrAuthReqIDNotFound ...
+	ErrAuthReqIDNotFound = errors.New("Backchannel authentication request not found")
+	// ErrAuthReqIDExpired ...
+	ErrAuthReqIDExpired = errors.New("Backchannel authentication request expired")
+	// ErrBackchannelRequestDenied ...
+	ErrBackchannelRequestDenied = errors.New("Backchannel authentication denied")
+	// ErrBackchannelSlowDown ...
+	ErrBackchannelSlowDown = errors.New("Polling too frequently, slow down")
+	// ErrOpenIDScopeRequired ...
+	ErrOpenIDScopeRequired = errors.New("Backchannel authentication requires the openid scope")
+	// ErrLoginHintRequired ...
+	ErrLoginHintRequired = errors.New("Login hint required")
+	// ErrUnknownUserID ...
+	ErrUnknownUserID = errors.New("Login hint does not identify a user")
+	// ErrInvalidBindingMessage ...
+	ErrInvalidBindingMessage = errors.New("Binding message is too long")
+	// ErrNotificationTokenRequired ...
+	ErrNotificationTokenRequired = errors.New("Client notification token required")
+
+	// backchannelNotificationClient pings clients once requests are decided
+	backchannelNotificationClient = &http.Client{Timeout: 10 * time.Second}
+)
+
+// BackchannelAuthenticationHook asks the user to approve a backchannel
+// authentication request on their own device, for example with a push
+// notification. The decision is then passed to AuthorizeBackchannelRequest
+// or DenyBackchannelRequest. The request has its client and user loaded.
+type BackchannelAuthenticationHook func(ctx context.Context, request *models.OauthBackchannelRequest) error
+
+// SetBackchannelAuthenticationHook sets the hook asking users to approve
+// backchannel authentication requests, the flow is disabled until it is set
+func (s *Service) SetBackchannelAuthenticationHook(hook BackchannelAuthenticationHook) {
+	s.backchannelHook = hook
+}
+
+// SetBackchannelNotificationEndpoint makes the client use ping mode, the endpoint
+// is notified once the user has made a decision, an empty endpoint means poll mode
+func (s *Service) SetBackchannelNotificationEndpoint(client *models.OauthClient, endpoint string) error {
+	err := s.db.Model(new(models.OauthClient)).Where("id = ?", client.ID).
+		UpdateColumn("backchannel_notification_endpoint", endpoint).Error
+	if err != nil {
+		return err
+	}
+	client.BackchannelNotificationEndpoint = endpoint
+	return nil
+}
+
+// FindBackchannelRequest returns a pending backchannel authentication request
+func (s *Service) FindBackchannelRequest(authReqID string) (*models.OauthBackchannelRequest, error) {
+	request := new(models.OauthBackchannelRequest)
+	notFound := models.OauthBackchannelRequestPreload(s.db).Where("auth_req_id = ?", authReqID).
+		Where("authorized_at IS NULL AND denied_at IS NULL").First(request).RecordNotFound()
+
+	// Not found
+	if notFound {
+		return nil, ErrAuthReqIDNotFound
+	}
+
+	// Check the request hasn't expired
+	if time.Now().UTC().After(request.ExpiresAt) {
+		return nil, ErrAuthReqIDNotFound
+	}
+
+	return request, nil
+}
+
+// AuthorizeBackchannelRequest records the user approved the request,
+// the client receives tokens for the user the next time it polls
+func (s *Service) AuthorizeBackchannelRequest(request *models.OauthBackchannelRequest) error {
+	return s.decideBackchannelRequest(request, map[string]interface{}{
+		"authorized_at": time.Now().UTC(),
+	})
+}
+
+// DenyBackchannelRequest records the user rejected the request,
+// the client is told access was denied the next time it polls
+func (s *Service) DenyBackchannelRequest(request *models.OauthBackchannelRequest) error {
+	return s.decideBackchannelRequest(request, map[string]interface{}{
+		"denied_at": time.Now().UTC(),
+	})
+}
+
+// decideBackchannelRequest updates a request which has not been decided yet
+// and pings the client if it uses ping mode
+func (s *Service) decideBackchannelRequest(request *models.OauthBackchannelRequest, columns map[string]interface{}) error {
+	result := s.db.Model(new(models.OauthBackchannelRequest)).Where("id = ?", request.ID).
+		Where("authorized_at IS NULL AND denied_at IS NULL").UpdateColumns(columns)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAuthReqIDNotFound
+	}
+
+	s.notifyBackchannelClient(request)
+	return nil
+}
+
+// notifyBackchannelClient tells a client using ping mode the request has been
+// decided, failures are only logged as the client can still poll
+func (s *Service) notifyBackchannelClient(request *models.OauthBackchannelRequest) {
+	client := request.Client
+	if client == nil {
+		client = new(models.OauthClient)
+		if err := s.db.First(client, request.ClientID.String).Error; err != nil {
+			log.WARNING.Printf("Backchannel notification for %s failed: %s", request.AuthReqID, err)
+			return
+		}
+	}
+	if client.BackchannelNotificationEndpoint == "" {
+		return
+	}
+
+	body, err := json.Marshal(map[string]string{"auth_req_id": request.AuthReqID})
+	if err != nil {
+		log.WARNING.Printf("Backchannel notification for %s failed: %s", request.AuthReqID, err)
+		return
+	}
+	r, err := http.NewRequest("POST", client.BackchannelNotificationEndpoint, bytes.NewReader(body))
+	if err != nil {
+		log.WARNING.Printf("Backchannel notification for %s failed: %s", request.AuthReqID, err)
+		return
+	}
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Authorization", "Bearer "+request.ClientNotificationToken)
+
+	resp, err := backchannelNotificationClient.Do(r)
+	if err != nil {
+		log.WARNING.Printf("Backchannel notification for %s failed: %s", request.AuthReqID, err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		log.WARNING.Printf("Backchannel notification for %s failed: %s", request.AuthReqID, resp.Status)
+	}
+}
+
+// backchannelAuthentication starts the backchannel flow for the client
+func (s *Service) backchannelAuthentication(r *http.Request, client *models.OauthClient) (*BackchannelAuthenticationResponse, error) {
+	// Get the scope string, the openid scope is mandatory
+	scope, err := s.getClientScope(client, r.Form.Get("scope"))
+	if err != nil {
+		return nil, err
+	}
+	if !util.StringInSlice(OpenIDScope, strings.Fields(scope)) {
+		return nil, ErrOpenIDScopeRequired
+	}
+
+	// The user is identified by their username
+	loginHint := r.Form.Get("login_hint")
+	if loginHint == "" {
+		return nil, ErrLoginHintRequired
+	}
+	user, err := s.FindUserByUsername(loginHint)
+	if err != nil {
+		return nil, ErrUnknownUserID
+	}
+
+	bindingMessage := r.Form.Get("binding_message")
+	if len([]rune(bindingMessage)) > maxBindingMessageLength {
+		return nil, ErrInvalidBindingMessage
+	}
+
+	// Clients using ping mode authenticate the ping with their token
+	notificationToken := r.Form.Get("client_notification_token")
+	if client.BackchannelNotificationEndpoint != "" && notificationToken == "" {
+		return nil, ErrNotificationTokenRequired
+	}
+
+	// Create a new backchannel request
+	request := models.NewOauthBackchannelRequest(client, user, s.getBackchannelRequestLifetime(), scope)
+	request.BindingMessage = bindingMessage
+	request.ClientNotificationToken = notificationToken
+	if err := s.db.Create(request).Error; err != nil {
+		return nil, err
+	}
+	request.Client = client
+	request.User = user
+
+	// Ask the user to approve it
+	if err := s.backchannelHook(r.Context(), request); err != nil {
+		s.db.Unscoped().Delete(request)
+		return nil, err
+	}
+
+	return &BackchannelAuthenticationResponse{
+		AuthReqID: request.AuthReqID,
+		ExpiresIn: s.getBackchannelRequestLifetime(),
+		Interval:  s.getBackchannelInterval(),
+	}, nil
+}
+
+// pollBackchannelRequest returns the request once the user has approved it,
+// otherwise an error telling the client whether to keep polling
+func (s *Service) pollBackchannelRequest(authReqID string, client *models.OauthClient) (*models.OauthBackchannelRequest, error) {
+	// Fetch the request from the database
+	request := new(models.OauthBackchannelRequest)
+	notFound := models.OauthBackchannelRequestPreload(s.db).Where("client_id = ?", client.ID).
+		Where("auth_req_id = ?", authReqID).First(request).RecordNotFound()
+
+	// Not found
+	if notFound {
+		return nil, ErrAuthReqIDNotFound
+	}
+
+	// Check the request hasn't expired
+	now := time.Now().UTC()
+	if now.After(request.ExpiresAt) {
+		return nil, ErrAuthReqIDExpired
+	}
+
+	// Check the user hasn't denied the request
+	if request.DeniedAt != nil {
+		return nil, ErrBackchannelRequestDenied
+	}
+
+	// Clients polling more often than the interval are told to slow down
+	interval := time.Duration(s.getBackchannelInterval()) * time.Second
+	tooSoon := request.LastPolledAt != nil && now.Before(request.LastPolledAt.Add(interval))
+	err := s.db.Model(new(models.OauthBackchannelRequest)).Where("id = ?", request.ID).
+		UpdateColumn("last_polled_at", now).Error
+	if err != nil {
+		return nil, err
+	}
+	if tooSoon {
+		return nil, ErrBackchannelSlowDown
+	}
+
+	// Keep polling until the user has made a decision
+	if request.AuthorizedAt == nil {
+		return nil, ErrAuthorizationPending
+	}
+
+	return request, nil
+}
+
+// getBackchannelRequestLifetime returns the configured request lifetime
+func (s *Service) getBackchannelRequestLifetime() int {
+	if s.cnf.Oauth.BackchannelRequestLifetime <= 0 {
+		return defaultBackchannelRequestLifetime
+	}
+	return s.cnf.Oauth.BackchannelRequestLifetime
+}
+
+// getBackchannelInterval returns the configured polling interval
+func (s *Service) getBackchannelInterval() int {
+	if s.cnf.Oauth.BackchannelInterval <= 0 {
+		return defaultBackchannelInterval
+	}
+	return s.cnf.Oauth.BackchannelInterval
+}
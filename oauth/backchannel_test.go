@@ -0,0 +1,201 @@
+package oauth_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/test-util"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestBackchannelAuthenticationDisabled() {
+	// Without a hook nobody could approve the request
+	w := suite.postBackchannelAuthentication(url.Values{
+		"scope":      {"openid read"},
+		"login_hint": {"test@user"},
+	})
+	testutil.TestResponseForError(suite.T(), w, oauth.ErrInvalidGrantType.Error(), 400)
+	assert.NotContains(suite.T(), suite.getMetadata().GrantTypesSupported, oauth.CIBAGrantType)
+}
+
+func (suite *OauthTestSuite) TestCIBAGrant() {
+	var hookRequest *models.OauthBackchannelRequest
+	suite.setBackchannelHook(&hookRequest)
+	defer suite.service.SetBackchannelAuthenticationHook(nil)
+
+	resp := suite.backchannelAuthentication(url.Values{
+		"scope":           {"openid read"},
+		"login_hint":      {"test@user"},
+		"binding_message": {"W4SCT"},
+	})
+	assert.NotEmpty(suite.T(), resp.AuthReqID)
+	assert.Equal(suite.T(), 120, resp.ExpiresIn)
+	assert.Equal(suite.T(), 5, resp.Interval)
+
+	// The hook asks the user to approve the request
+	if assert.NotNil(suite.T(), hookRequest) {
+		assert.Equal(suite.T(), resp.AuthReqID, hookRequest.AuthReqID)
+		assert.Equal(suite.T(), "test@user", hookRequest.User.Username)
+		assert.Equal(suite.T(), "test_client_1", hookRequest.Client.Key)
+		assert.Equal(suite.T(), "W4SCT", hookRequest.BindingMessage)
+	}
+
+	// The client keeps polling until the user has made a decision
+	suite.assertErrorCode(suite.pollBackchannelRequest(resp.AuthReqID), "authorization_pending")
+	suite.assertErrorCode(suite.pollBackchannelRequest(resp.AuthReqID), "slow_down")
+
+	// The user approves the request
+	request, err := suite.service.FindBackchannelRequest(resp.AuthReqID)
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), suite.service.AuthorizeBackchannelRequest(request))
+	assert.Equal(suite.T(), oauth.ErrAuthReqIDNotFound, suite.service.DenyBackchannelRequest(request))
+
+	// The client gets tokens on its next poll after the interval
+	suite.waitBackchannelInterval(resp.AuthReqID)
+	accessTokenResponse := suite.decodeAccessTokenResponse(suite.pollBackchannelRequest(resp.AuthReqID))
+	assert.Equal(suite.T(), "openid read", accessTokenResponse.Scope)
+	assert.Equal(suite.T(), string(suite.users[1].ID), accessTokenResponse.UserID)
+	suite.assertValidIDToken(accessTokenResponse.IDToken, suite.clients[0], suite.users[1])
+	assert.Equal(
+		suite.T(),
+		oauth.CIBAGrantType,
+		suite.introspectRefreshToken(accessTokenResponse.RefreshToken).GrantType,
+	)
+
+	// The request can only be exchanged once
+	suite.assertErrorCode(suite.pollBackchannelRequest(resp.AuthReqID), "invalid_grant")
+}
+
+func (suite *OauthTestSuite) TestCIBAGrantDeniedOrExpired() {
+	var hookRequest *models.OauthBackchannelRequest
+	suite.setBackchannelHook(&hookRequest)
+	defer suite.service.SetBackchannelAuthenticationHook(nil)
+
+	parameters := url.Values{
+		"scope":      {"openid"},
+		"login_hint": {"test@user"},
+	}
+
+	resp := suite.backchannelAuthentication(parameters)
+	assert.NoError(suite.T(), suite.service.DenyBackchannelRequest(hookRequest))
+	suite.assertErrorCode(suite.pollBackchannelRequest(resp.AuthReqID), "access_denied")
+
+	resp = suite.backchannelAuthentication(parameters)
+	err := suite.db.Model(new(models.OauthBackchannelRequest)).Where("auth_req_id = ?", resp.AuthReqID).
+		UpdateColumn("expires_at", time.Now().UTC().Add(-10*time.Second)).Error
+	assert.NoError(suite.T(), err)
+	suite.assertErrorCode(suite.pollBackchannelRequest(resp.AuthReqID), "expired_token")
+	_, err = suite.service.FindBackchannelRequest(resp.AuthReqID)
+	assert.Equal(suite.T(), oauth.ErrAuthReqIDNotFound, err)
+}
+
+func (suite *OauthTestSuite) TestBackchannelAuthenticationInvalid() {
+	var hookRequest *models.OauthBackchannelRequest
+	suite.setBackchannelHook(&hookRequest)
+	defer suite.service.SetBackchannelAuthenticationHook(nil)
+
+	for _, testCase := range []struct {
+		parameters url.Values
+		code       string
+	}{
+		{url.Values{"scope": {"read"}, "login_hint": {"test@user"}}, "invalid_scope"},
+		{url.Values{"scope": {"openid"}}, "invalid_request"},
+		{url.Values{"scope": {"openid"}, "login_hint": {"bogus"}}, "unknown_user_id"},
+		{url.Values{
+			"scope":           {"openid"},
+			"login_hint":      {"test@user"},
+			"binding_message": {strings.Repeat("x", 101)},
+		}, "invalid_binding_message"},
+	} {
+		suite.assertErrorCode(suite.postBackchannelAuthentication(testCase.parameters), testCase.code)
+	}
+	assert.Nil(suite.T(), hookRequest)
+}
+
+func (suite *OauthTestSuite) TestCIBAGrantPingMode() {
+	var hookRequest *models.OauthBackchannelRequest
+	suite.setBackchannelHook(&hookRequest)
+	defer suite.service.SetBackchannelAuthenticationHook(nil)
+
+	// The client is pinged once the user has made a decision
+	var authorization, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		data, _ := ioutil.ReadAll(r.Body)
+		body = string(data)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	assert.NoError(suite.T(), suite.service.SetBackchannelNotificationEndpoint(suite.clients[0], server.URL))
+	defer suite.service.SetBackchannelNotificationEndpoint(suite.clients[0], "")
+
+	// The ping is authenticated with the client notification token
+	w := suite.postBackchannelAuthentication(url.Values{
+		"scope":      {"openid"},
+		"login_hint": {"test@user"},
+	})
+	suite.assertErrorCode(w, "invalid_request")
+
+	resp := suite.backchannelAuthentication(url.Values{
+		"scope":                     {"openid"},
+		"login_hint":                {"test@user"},
+		"client_notification_token": {"test_notification_token"},
+	})
+	assert.NoError(suite.T(), suite.service.AuthorizeBackchannelRequest(hookRequest))
+	assert.Equal(suite.T(), "Bearer test_notification_token", authorization)
+	assert.JSONEq(suite.T(), `{"auth_req_id":"`+resp.AuthReqID+`"}`, body)
+
+	// Then the client fetches the tokens
+	accessTokenResponse := suite.decodeAccessTokenResponse(suite.pollBackchannelRequest(resp.AuthReqID))
+	assert.Equal(suite.T(), "openid", accessTokenResponse.Scope)
+}
+
+// setBackchannelHook makes the backchannel flow available, storing
+// the last request the user is asked to approve in request
+func (suite *OauthTestSuite) setBackchannelHook(request **models.OauthBackchannelRequest) {
+	suite.service.SetBackchannelAuthenticationHook(func(ctx context.Context, r *models.OauthBackchannelRequest) error {
+		*request = r
+		return nil
+	})
+}
+
+func (suite *OauthTestSuite) backchannelAuthentication(parameters url.Values) *oauth.BackchannelAuthenticationResponse {
+	w := suite.postBackchannelAuthentication(parameters)
+	assert.Equal(suite.T(), 200, w.Code)
+	resp := new(oauth.BackchannelAuthenticationResponse)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), resp))
+	return resp
+}
+
+func (suite *OauthTestSuite) postBackchannelAuthentication(parameters url.Values) *httptest.ResponseRecorder {
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/bc-authorize", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
+	r.PostForm = parameters
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	return w
+}
+
+func (suite *OauthTestSuite) pollBackchannelRequest(authReqID string) *httptest.ResponseRecorder {
+	return suite.tokenRequest(url.Values{
+		"grant_type":  {oauth.CIBAGrantType},
+		"auth_req_id": {authReqID},
+	}, nil)
+}
+
+// waitBackchannelInterval moves the last poll back so the next poll is not too fast
+func (suite *OauthTestSuite) waitBackchannelInterval(authReqID string) {
+	err := suite.db.Model(new(models.OauthBackchannelRequest)).Where("auth_req_id = ?", authReqID).
+		UpdateColumn("last_polled_at", time.Now().UTC().Add(-time.Minute)).Error
+	assert.NoError(suite.T(), err)
+}
@@ -0,0 +1,76 @@
+package oauth
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"net/http"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+)
+
+var (
+	// ErrCertificateMismatch ...
+	ErrCertificateMismatch = errors.New("Access token is bound to another client certificate")
+)
+
+// bindAccessTokenResponse binds the access token of the response to the
+// TLS client certificate of the request if enabled, see RFC 8705 section 3
+func (s *Service) bindAccessTokenResponse(r *http.Request, resp *AccessTokenResponse) error {
+	if !s.cnf.Oauth.CertificateBoundAccessTokens {
+		return nil
+	}
+	certificate := getClientCertificate(r)
+	if certificate == nil {
+		return nil
+	}
+
+	return s.db.Model(new(models.OauthAccessToken)).Where("token = ?", models.HashToken(resp.AccessToken)).
+		UpdateColumn("certificate_thumbprint", certificateThumbprint(certificate)).Error
+}
+
+// checkCertificateBinding makes sure a certificate bound access token
+// is presented over a connection using the certificate
+func checkCertificateBinding(r *http.Request, accessToken *models.OauthAccessToken) error {
+	if accessToken.CertificateThumbprint == "" {
+		return nil
+	}
+	certificate := getClientCertificate(r)
+	if certificate == nil || certificateThumbprint(certificate) != accessToken.CertificateThumbprint {
+		return ErrCertificateMismatch
+	}
+	return nil
+}
+
+// newConfirmation returns the cnf claim of the access token, nil if unbound
+func newConfirmation(accessToken *models.OauthAccessToken) *Confirmation {
+	if accessToken.CertificateThumbprint == "" && accessToken.JWKThumbprint == "" {
+		return nil
+	}
+	return &Confirmation{
+		CertificateThumbprint: accessToken.CertificateThumbprint,
+		JWKThumbprint:         accessToken.JWKThumbprint,
+	}
+}
+
+// getClientCertificate returns the certificate the client presented
+// when establishing the TLS connection, if any
+func getClientCertificate(r *http.Request) *x509.Certificate {
+	if r.TLS == nil {
+		return nil
+	}
+	if len(r.TLS.PeerCertificates) > 0 {
+		return r.TLS.PeerCertificates[0]
+	}
+	if len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		return r.TLS.VerifiedChains[0][0]
+	}
+	return nil
+}
+
+// certificateThumbprint returns the base64url encoded SHA-256 hash of the certificate
+func certificateThumbprint(certificate *x509.Certificate) string {
+	sum := sha256.Sum256(certificate.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,91 @@
+package oauth_test
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestCertificateBoundAccessToken() {
+	suite.cnf.Oauth.CertificateBoundAccessTokens = true
+	defer func() { suite.cnf.Oauth.CertificateBoundAccessTokens = false }()
+	err := suite.service.SetTLSClientAuthSubjectDN(suite.clients[0], "CN=client.example.com,O=Example")
+	assert.NoError(suite.T(), err)
+	defer suite.service.SetTLSClientAuthSubjectDN(suite.clients[0], "")
+
+	connectionState := newClientCertificateConnection("client.example.com")
+	w := suite.clientCredentialsGrantWith(connectionState, url.Values{"client_id": {"test_client_1"}})
+	resp := suite.decodeAccessTokenResponse(w)
+
+	// Introspection tells resource servers which certificate the token is bound to
+	sum := sha256.Sum256(connectionState.VerifiedChains[0][0].Raw)
+	introspectResponse := suite.introspectAccessToken(resp.AccessToken)
+	if assert.NotNil(suite.T(), introspectResponse.Cnf) {
+		assert.Equal(suite.T(), base64.RawURLEncoding.EncodeToString(sum[:]), introspectResponse.Cnf.CertificateThumbprint)
+	}
+
+	// The token is only accepted over connections using the certificate
+	for _, testCase := range []struct {
+		connectionState *tls.ConnectionState
+		accepted        bool
+	}{
+		{connectionState, true},
+		{nil, false},
+		{newClientCertificateConnection("other.example.com"), false},
+	} {
+		w := suite.requestWithBoundToken(testCase.connectionState, resp.AccessToken)
+		if testCase.accepted {
+			assert.Equal(suite.T(), 200, w.Code)
+		} else {
+			assert.Equal(suite.T(), 401, w.Code)
+		}
+	}
+}
+
+func (suite *OauthTestSuite) TestCertificateBoundAccessTokenDisabled() {
+	err := suite.service.SetTLSClientAuthSubjectDN(suite.clients[0], "CN=client.example.com,O=Example")
+	assert.NoError(suite.T(), err)
+	defer suite.service.SetTLSClientAuthSubjectDN(suite.clients[0], "")
+
+	w := suite.clientCredentialsGrantWith(
+		newClientCertificateConnection("client.example.com"),
+		url.Values{"client_id": {"test_client_1"}},
+	)
+	resp := suite.decodeAccessTokenResponse(w)
+
+	// Tokens are not bound unless enabled
+	assert.Nil(suite.T(), suite.introspectAccessToken(resp.AccessToken).Cnf)
+	assert.Equal(suite.T(), 200, suite.requestWithBoundToken(nil, resp.AccessToken).Code)
+}
+
+// newClientCertificateConnection returns the state of a TLS connection
+// established with a verified client certificate issued to the host
+func newClientCertificateConnection(host string) *tls.ConnectionState {
+	return &tls.ConnectionState{
+		VerifiedChains: [][]*x509.Certificate{{{
+			Raw:     []byte("certificate of " + host),
+			Subject: pkix.Name{CommonName: host, Organization: []string{"Example"}},
+		}}},
+	}
+}
+
+func (suite *OauthTestSuite) requestWithBoundToken(connectionState *tls.ConnectionState, token string) *httptest.ResponseRecorder {
+	r, err := http.NewRequest("GET", "http://1.2.3.4/resource", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.TLS = connectionState
+	r.Header.Set("Authorization", "Bearer "+token)
+
+	w := httptest.NewRecorder()
+	oauth.NewAuthenticationMiddleware(suite.service).ServeHTTP(w, r, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	})
+	return w
+}
@@ -1,6 +1,8 @@
 package oauth
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"errors"
 	"strings"
 	"time"
@@ -19,16 +21,74 @@ var (
 	ErrInvalidClientSecret = errors.New("Invalid client secret")
 	// ErrClientIDTaken ...
 	ErrClientIDTaken = errors.New("Client ID taken")
+	// ErrClientSecretTooShort ...
+	ErrClientSecretTooShort = errors.New("Client secret too short")
+	// ErrClientDisabled ...
+	ErrClientDisabled = errors.New("Client disabled")
 )
 
+const (
+	// defaultMinClientSecretLength is used when the minimum is not configured
+	defaultMinClientSecretLength = 8
+	// generatedClientSecretBytes is the entropy of generated client secrets
+	generatedClientSecretBytes = 32
+	// maxClientsPerPage limits how many clients are listed at once
+	maxClientsPerPage = 100
+)
+
+// GenerateClientSecret returns a random high-entropy client secret,
+// preferable to secrets supplied when registering clients
+func GenerateClientSecret() (string, error) {
+	b := make([]byte, generatedClientSecretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// ValidateClientSecret checks a supplied client secret is long enough
+func (s *Service) ValidateClientSecret(secret string) error {
+	minLength := s.cnf.Oauth.MinClientSecretLength
+	if minLength <= 0 {
+		minLength = defaultMinClientSecretLength
+	}
+	if len(secret) < minLength {
+		return ErrClientSecretTooShort
+	}
+	return nil
+}
+
 // ClientExists returns true if client exists
 func (s *Service) ClientExists(clientID string) bool {
-	_, err := s.FindClientByClientID(clientID)
+	_, err := s.GetClient(clientID)
 	return err == nil
 }
 
-// FindClientByClientID looks up a client by client ID
+// FindClientByClientID looks up a client by client ID,
+// disabled clients cannot be used
 func (s *Service) FindClientByClientID(clientID string) (*models.OauthClient, error) {
+	client, err := s.GetClient(clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Disabled
+	if client.Disabled {
+		return nil, ErrClientDisabled
+	}
+
+	return client, nil
+}
+
+// clientDisabled returns true if the client with the primary key is disabled
+func (s *Service) clientDisabled(id string) bool {
+	var count int
+	s.db.Model(new(models.OauthClient)).Where("id = ? AND disabled = ?", id, true).Count(&count)
+	return count > 0
+}
+
+// GetClient looks up a client by client ID, including disabled clients
+func (s *Service) GetClient(clientID string) (*models.OauthClient, error) {
 	// Client IDs are case insensitive
 	client := new(models.OauthClient)
 	notFound := s.db.Where("key = LOWER(?)", clientID).
@@ -42,6 +102,27 @@ func (s *Service) FindClientByClientID(clientID string) (*models.OauthClient, er
 	return client, nil
 }
 
+// ListClients returns a page of clients in the order they were created
+// and how many clients there are, including disabled clients
+func (s *Service) ListClients(offset, limit int) ([]*models.OauthClient, int, error) {
+	if limit <= 0 || limit > maxClientsPerPage {
+		limit = maxClientsPerPage
+	}
+
+	var count int
+	if err := s.db.Model(new(models.OauthClient)).Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var clients []*models.OauthClient
+	err := s.db.Order("created_at").Offset(offset).Limit(limit).Find(&clients).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return clients, count, nil
+}
+
 // CreateClient saves a new client to database
 func (s *Service) CreateClient(clientID, secret, redirectURI string) (*models.OauthClient, error) {
 	return s.createClientCommon(s.db, clientID, secret, redirectURI)
@@ -60,9 +141,9 @@ func (s *Service) AuthClient(clientID, secret string) (*models.OauthClient, erro
 		return nil, ErrClientNotFound
 	}
 
-	// Verify the secret
-	if password.VerifyPassword(client.Secret, secret) != nil {
-		return nil, ErrInvalidClientSecret
+	// Verify the secret, the secondary one is accepted while it is rolled
+	if err := verifyClientSecret(client, secret); err != nil {
+		return nil, err
 	}
 
 	return client, nil
@@ -74,6 +155,18 @@ func (s *Service) createClientCommon(db *gorm.DB, clientID, secret, redirectURI
 		return nil, ErrClientIDTaken
 	}
 
+	// Reject weak secrets
+	if err := s.ValidateClientSecret(secret); err != nil {
+		return nil, err
+	}
+
+	// Reject dangerous redirect URIs, clients created here are not native apps
+	if redirectURI != "" {
+		if err := s.validateRegisteredRedirectURI(redirectURI, false); err != nil {
+			return nil, err
+		}
+	}
+
 	// Hash password
 	secretHash, err := password.HashPassword(secret)
 	if err != nil {
@@ -88,9 +181,248 @@ func (s *Service) createClientCommon(db *gorm.DB, clientID, secret, redirectURI
 		Key:         strings.ToLower(clientID),
 		Secret:      string(secretHash),
 		RedirectURI: util.StringOrNull(redirectURI),
+		ClientType:  ClientTypeConfidential,
+		SubjectType: SubjectTypePublic,
 	}
 	if err := db.Create(client).Error; err != nil {
 		return nil, err
 	}
 	return client, nil
 }
+
+// CreateClientWithMetadata saves a new client with the metadata and
+// returns it along with its generated secret
+func (s *Service) CreateClientWithMetadata(clientID string, metadata *ClientMetadata) (*models.OauthClient, string, error) {
+	redirectURI, err := s.validateClientMetadata(metadata, s.getClientAuthMethods())
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret, err := GenerateClientSecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	client, err := s.createClientWithMetadata(clientID, secret, redirectURI, metadata, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return client, secret, nil
+}
+
+// UpdateClient replaces the metadata of a client
+func (s *Service) UpdateClient(client *models.OauthClient, metadata *ClientMetadata) error {
+	redirectURI, err := s.validateClientMetadata(metadata, s.getClientAuthMethods())
+	if err != nil {
+		return err
+	}
+	return s.updateClientMetadata(client, redirectURI, metadata)
+}
+
+// RotateClientSecret issues a new secret to the client, the old one and
+// any secondary secret stop working
+func (s *Service) RotateClientSecret(client *models.OauthClient) (string, error) {
+	secret, err := GenerateClientSecret()
+	if err != nil {
+		return "", err
+	}
+	secretHash, err := password.HashPassword(secret)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.db.Model(new(models.OauthClient)).Where("id = ?", client.ID).
+		UpdateColumns(map[string]interface{}{
+			"secret":                      string(secretHash),
+			"secondary_secret":            "",
+			"secondary_secret_expires_at": nil,
+		}).Error
+	if err != nil {
+		return "", err
+	}
+	client.Secret = string(secretHash)
+	client.SecondarySecret = ""
+	client.SecondarySecretExpiresAt = nil
+
+	return secret, nil
+}
+
+// SetClientDisabled disables or re-enables a client, the tokens
+// of a disabled client are revoked
+func (s *Service) SetClientDisabled(client *models.OauthClient, disabled bool) error {
+	var accessTokens []*models.OauthAccessToken
+	if err := s.db.Where("client_id = ?", client.ID).Find(&accessTokens).Error; err != nil {
+		return err
+	}
+
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	if disabled {
+		if err := revokeClientTokens(tx, client); err != nil {
+			tx.Rollback() // rollback the transaction
+			return err
+		}
+	}
+
+	err := tx.Model(new(models.OauthClient)).Where("id = ?", client.ID).
+		UpdateColumn("disabled", disabled).Error
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+	client.Disabled = disabled
+
+	if disabled {
+		s.invalidateAccessTokens(accessTokens)
+	}
+
+	return nil
+}
+
+// SetClientFirstParty marks the client as one of our own apps, users are not
+// asked to consent to first-party clients, or as a third-party integration
+func (s *Service) SetClientFirstParty(client *models.OauthClient, firstParty bool) error {
+	err := s.db.Model(new(models.OauthClient)).Where("id = ?", client.ID).
+		UpdateColumn("first_party", firstParty).Error
+	if err != nil {
+		return err
+	}
+	client.FirstParty = firstParty
+	return nil
+}
+
+// DeleteClient deletes a client and revokes the tokens issued to it
+func (s *Service) DeleteClient(client *models.OauthClient) error {
+	var accessTokens []*models.OauthAccessToken
+	if err := s.db.Where("client_id = ?", client.ID).Find(&accessTokens).Error; err != nil {
+		return err
+	}
+
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	if err := revokeClientTokens(tx, client); err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	// The client itself is soft deleted
+	if err := tx.Delete(client).Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	s.invalidateAccessTokens(accessTokens)
+
+	return nil
+}
+
+// createClientWithMetadata saves a new client with the metadata
+// and any extra columns in a single transaction
+func (s *Service) createClientWithMetadata(clientID, secret, redirectURI string, metadata *ClientMetadata, columns map[string]interface{}) (*models.OauthClient, error) {
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	client, err := s.CreateClientTx(tx, clientID, secret, redirectURI)
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+
+	updates := clientMetadataColumns(redirectURI, metadata)
+	for column, value := range columns {
+		updates[column] = value
+	}
+	err = tx.Model(new(models.OauthClient)).Where("id = ?", client.ID).
+		UpdateColumns(updates).Error
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+
+	setClientMetadata(client, redirectURI, metadata)
+	if token, ok := columns["registration_access_token"].(string); ok {
+		client.RegistrationAccessToken = token
+	}
+
+	return client, nil
+}
+
+// updateClientMetadata replaces the metadata of a client
+func (s *Service) updateClientMetadata(client *models.OauthClient, redirectURI string, metadata *ClientMetadata) error {
+	err := s.db.Model(new(models.OauthClient)).Where("id = ?", client.ID).
+		UpdateColumns(clientMetadataColumns(redirectURI, metadata)).Error
+	if err != nil {
+		return err
+	}
+	setClientMetadata(client, redirectURI, metadata)
+	return nil
+}
+
+// clientMetadataColumns returns the columns the metadata is stored in
+func clientMetadataColumns(redirectURI string, metadata *ClientMetadata) map[string]interface{} {
+	return map[string]interface{}{
+		"name":                       util.StringOrNull(metadata.ClientName),
+		"redirect_uri":               util.StringOrNull(redirectURI),
+		"allowed_grant_types":        strings.Join(metadata.GrantTypes, " "),
+		"scope":                      metadata.Scope,
+		"token_endpoint_auth_method": metadata.TokenEndpointAuthMethod,
+	}
+}
+
+// setClientMetadata sets the fields the metadata is stored in
+func setClientMetadata(client *models.OauthClient, redirectURI string, metadata *ClientMetadata) {
+	client.Name = util.StringOrNull(metadata.ClientName)
+	client.RedirectURI = util.StringOrNull(redirectURI)
+	client.AllowedGrantTypes = strings.Join(metadata.GrantTypes, " ")
+	client.Scope = metadata.Scope
+	client.TokenEndpointAuthMethod = metadata.TokenEndpointAuthMethod
+}
+
+// revokeClientTokens deletes the tokens and authorization codes issued to the client
+func revokeClientTokens(tx *gorm.DB, client *models.OauthClient) error {
+	for _, model := range []interface{}{
+		new(models.OauthAccessToken),
+		new(models.OauthRefreshToken),
+		new(models.OauthAuthorizationCode),
+	} {
+		if err := tx.Unscoped().Where("client_id = ?", client.ID).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// invalidateAccessTokens makes sure revoked access tokens are not served
+// from the validation cache, invalidating each client and user once
+func (s *Service) invalidateAccessTokens(accessTokens []*models.OauthAccessToken) {
+	invalidated := make(map[validationCacheOwner]bool)
+	for _, accessToken := range accessTokens {
+		owner := validationCacheOwner{clientID: accessToken.ClientID, userID: accessToken.UserID}
+		if invalidated[owner] {
+			continue
+		}
+		invalidated[owner] = true
+		s.validationCache.Invalidate(accessToken.ClientID, accessToken.UserID)
+	}
+}
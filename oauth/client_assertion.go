@@ -0,0 +1,34 @@
+package oauth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util/jwt"
+)
+
+var (
+	// ErrAssertionReplayed ...
+	ErrAssertionReplayed = errors.New("Assertion already used")
+)
+
+// useClientAssertion records the jti of an assertion the client authenticated
+// with until it expires, an assertion can only be used once so a captured
+// one cannot be replayed, see https://tools.ietf.org/html/rfc7523#section-3
+func (s *Service) useClientAssertion(client *models.OauthClient, claims jwt.Claims) error {
+	jti, _ := claims.String("jti")
+	exp, _ := claims.Int64("exp")
+	if jti == "" || len(jti) > 200 {
+		return ErrInvalidAssertion
+	}
+
+	notFound := s.db.Where("client_id = ?", client.ID).Where("jti = ?", jti).
+		First(new(models.OauthClientAssertion)).RecordNotFound()
+	if !notFound {
+		return ErrAssertionReplayed
+	}
+
+	assertion := models.NewOauthClientAssertion(client, jti, time.Unix(exp, 0).UTC())
+	return s.db.Create(assertion).Error
+}
@@ -0,0 +1,350 @@
+package oauth
+
+import (
+	"crypto/subtle"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/log"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/RichardKnop/go-oauth2-server/util/jwt"
+)
+
+const (
+	// ClientSecretBasic authenticates clients with HTTP basic authentication
+	ClientSecretBasic = "client_secret_basic"
+	// ClientSecretPost authenticates clients with credentials in the request body
+	ClientSecretPost = "client_secret_post"
+	// PrivateKeyJWT authenticates clients with a JWT assertion signed
+	// with a private key matching a registered public key
+	PrivateKeyJWT = "private_key_jwt"
+	// ClientSecretJWT authenticates clients with a JWT assertion signed
+	// with a registered HS256 shared secret
+	ClientSecretJWT = "client_secret_jwt"
+	// TLSClientAuth authenticates clients with a TLS client certificate
+	// issued to a registered subject, see https://tools.ietf.org/html/rfc8705#section-2.1
+	TLSClientAuth = "tls_client_auth"
+	// ClientAuthNone identifies public clients by client ID only
+	ClientAuthNone = "none"
+	// ClientAssertionType is the client_assertion_type of JWT client assertions
+	ClientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+	// Types of subject alternative names clients can register
+	sanDNS   = "dns"
+	sanURI   = "uri"
+	sanIP    = "ip"
+	sanEmail = "email"
+)
+
+var (
+	// ErrClientCredentialsMissing is returned by authenticators when the
+	// request does not use their method, the next one is tried then
+	ErrClientCredentialsMissing = errors.New("Client credentials missing")
+	// ErrInvalidClientCertificate ...
+	ErrInvalidClientCertificate = errors.New("Invalid client certificate")
+	// ErrInvalidSubjectAlternativeName ...
+	ErrInvalidSubjectAlternativeName = errors.New("Invalid subject alternative name")
+
+	// clientCredentialParameters authenticate the client rather than
+	// being part of the request it makes
+	clientCredentialParameters = []string{
+		"client_id",
+		"client_secret",
+		"client_assertion",
+		"client_assertion_type",
+	}
+)
+
+// ClientAuthenticator authenticates the client of a request to one of the
+// endpoints clients call directly, such as the token endpoint. It returns
+// ErrClientCredentialsMissing if the request does not use its method, any
+// other error fails the request with a general invalid client error.
+type ClientAuthenticator interface {
+	AuthenticateClient(r *http.Request) (*models.OauthClient, error)
+}
+
+// ClientAuthenticatorFunc allows an ordinary function to be used as a ClientAuthenticator
+type ClientAuthenticatorFunc func(r *http.Request) (*models.OauthClient, error)
+
+// AuthenticateClient calls f(r)
+func (f ClientAuthenticatorFunc) AuthenticateClient(r *http.Request) (*models.OauthClient, error) {
+	return f(r)
+}
+
+// clientAuthMethod is an authentication method of the chain
+type clientAuthMethod struct {
+	name          string
+	authenticator ClientAuthenticator
+}
+
+// RegisterClientAuthenticator adds an authentication method to the end of the
+// chain, replacing a built-in method in place if one has the same name
+func (s *Service) RegisterClientAuthenticator(method string, authenticator ClientAuthenticator) {
+	for i, clientAuth := range s.clientAuthMethods {
+		if clientAuth.name == method {
+			s.clientAuthMethods[i].authenticator = authenticator
+			return
+		}
+	}
+	s.clientAuthMethods = append(s.clientAuthMethods, clientAuthMethod{method, authenticator})
+}
+
+// registerBuiltinClientAuthenticators registers the authentication methods
+// this server implements, in the order they are tried
+func (s *Service) registerBuiltinClientAuthenticators() {
+	s.RegisterClientAuthenticator(ClientSecretBasic, ClientAuthenticatorFunc(s.clientSecretBasic))
+	s.RegisterClientAuthenticator(ClientSecretPost, ClientAuthenticatorFunc(s.clientSecretPost))
+	s.RegisterClientAuthenticator(PrivateKeyJWT, s.clientAssertionAuthenticator(false))
+	s.RegisterClientAuthenticator(ClientSecretJWT, s.clientAssertionAuthenticator(true))
+	s.RegisterClientAuthenticator(TLSClientAuth, ClientAuthenticatorFunc(s.tlsClientAuth))
+	s.RegisterClientAuthenticator(ClientAuthNone, ClientAuthenticatorFunc(s.clientAuthNone))
+}
+
+// clientAuthMethodEnabled returns false for methods disabled by the configuration
+func (s *Service) clientAuthMethodEnabled(method string) bool {
+	return !util.StringInSlice(method, s.cnf.Oauth.DisabledClientAuthMethods)
+}
+
+// getClientAuthMethods returns the enabled authentication methods
+func (s *Service) getClientAuthMethods() []string {
+	var methods []string
+	for _, clientAuth := range s.clientAuthMethods {
+		if s.clientAuthMethodEnabled(clientAuth.name) {
+			methods = append(methods, clientAuth.name)
+		}
+	}
+	return methods
+}
+
+// authenticateClient authenticates the client with the first enabled
+// method of the chain the request carries credentials for
+func (s *Service) authenticateClient(r *http.Request) (*models.OauthClient, error) {
+	for _, clientAuth := range s.clientAuthMethods {
+		if !s.clientAuthMethodEnabled(clientAuth.name) {
+			continue
+		}
+
+		client, err := clientAuth.authenticator.AuthenticateClient(r)
+		if err == ErrClientCredentialsMissing {
+			continue
+		}
+		if err != nil {
+			log.INFO.Printf("Client authentication with %s failed: %s", clientAuth.name, err)
+			// For security reasons, return a general error message
+			return nil, ErrInvalidClientIDOrSecret
+		}
+
+		// Public clients have no credentials to authenticate with
+		if IsPublicClient(client) && clientAuth.name != ClientAuthNone {
+			log.INFO.Printf("Public client %s cannot authenticate with %s", client.Key, clientAuth.name)
+			return nil, ErrInvalidClientIDOrSecret
+		}
+
+		// The client may be registered for one method only
+		if client.TokenEndpointAuthMethod != "" && client.TokenEndpointAuthMethod != clientAuth.name {
+			log.INFO.Printf("Client %s cannot authenticate with %s", client.Key, clientAuth.name)
+			return nil, ErrInvalidClientIDOrSecret
+		}
+		return client, nil
+	}
+
+	return nil, ErrInvalidClientIDOrSecret
+}
+
+// clientSecretBasic authenticates the client with basic auth credentials
+func (s *Service) clientSecretBasic(r *http.Request) (*models.OauthClient, error) {
+	clientID, secret, ok := r.BasicAuth()
+	if !ok {
+		return nil, ErrClientCredentialsMissing
+	}
+	return s.AuthClient(clientID, secret)
+}
+
+// clientSecretPost authenticates the client with credentials in the request body
+func (s *Service) clientSecretPost(r *http.Request) (*models.OauthClient, error) {
+	clientID, secret := r.PostFormValue("client_id"), r.PostFormValue("client_secret")
+	if clientID == "" || secret == "" {
+		return nil, ErrClientCredentialsMissing
+	}
+	return s.AuthClient(clientID, secret)
+}
+
+// clientAssertionAuthenticator authenticates the client with a JWT assertion
+// signed with one of its assertion keys, see https://tools.ietf.org/html/rfc7523#section-2.2.
+// Assertions signed with a shared secret are left to the client_secret_jwt
+// authenticator and the others to the private_key_jwt one.
+func (s *Service) clientAssertionAuthenticator(sharedSecret bool) ClientAuthenticatorFunc {
+	return func(r *http.Request) (*models.OauthClient, error) {
+		assertion := r.PostFormValue("client_assertion")
+		if assertion == "" || r.PostFormValue("client_assertion_type") != ClientAssertionType {
+			return nil, ErrClientCredentialsMissing
+		}
+
+		header, err := jwt.ParseHeader(assertion)
+		if err != nil {
+			return nil, ErrInvalidAssertion
+		}
+		if (header.Algorithm == jwt.HS256) != sharedSecret {
+			return nil, ErrClientCredentialsMissing
+		}
+
+		// The client is both the issuer and the subject of the assertion
+		unverified, err := jwt.ParseUnverified(assertion)
+		if err != nil {
+			return nil, ErrInvalidAssertion
+		}
+		clientID, _ := unverified.String("sub")
+		if issuer, _ := unverified.String("iss"); issuer != clientID {
+			return nil, ErrInvalidAssertion
+		}
+		if r.PostFormValue("client_id") != "" && r.PostFormValue("client_id") != clientID {
+			return nil, ErrInvalidAssertion
+		}
+		client, err := s.FindClientByClientID(clientID)
+		if err != nil {
+			return nil, err
+		}
+
+		// Keys the client registered for itself sign its assertions
+		claims, err := s.verifyAssertion(assertion, client, s.endpointURL(r))
+		if err != nil {
+			return nil, err
+		}
+		if issuer, _ := claims.String("iss"); !strings.EqualFold(issuer, client.Key) {
+			return nil, ErrInvalidAssertion
+		}
+
+		// Each assertion authenticates a single request
+		if err := s.useClientAssertion(client, claims); err != nil {
+			return nil, err
+		}
+
+		return client, nil
+	}
+}
+
+// tlsClientAuth authenticates the client with the certificate of the TLS
+// connection, which must have been verified and issued to the subject or the
+// subject alternative name the client registered, see
+// https://tools.ietf.org/html/rfc8705#section-2.1.2. The client is
+// identified by the client_id parameter.
+func (s *Service) tlsClientAuth(r *http.Request) (*models.OauthClient, error) {
+	clientID := r.PostFormValue("client_id")
+	if clientID == "" || r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return nil, ErrClientCredentialsMissing
+	}
+
+	client, err := s.FindClientByClientID(clientID)
+	if err != nil {
+		return nil, err
+	}
+	if client.TLSClientAuthSubjectDN == "" && client.TLSClientAuthSAN == "" {
+		return nil, ErrClientCredentialsMissing
+	}
+
+	certificate := r.TLS.VerifiedChains[0][0]
+	if client.TLSClientAuthSubjectDN != "" {
+		subjectDN := certificate.Subject.String()
+		if subtle.ConstantTimeCompare([]byte(subjectDN), []byte(client.TLSClientAuthSubjectDN)) == 1 {
+			return client, nil
+		}
+	}
+	if client.TLSClientAuthSAN != "" && certificateHasSAN(certificate, client.TLSClientAuthSAN) {
+		return client, nil
+	}
+
+	return nil, ErrInvalidClientCertificate
+}
+
+// SetTLSClientAuthSubjectDN lets the client authenticate with TLS client
+// certificates issued to the subject distinguished name, such as
+// "CN=client.example.com,O=Example", passing an empty subject disables it
+func (s *Service) SetTLSClientAuthSubjectDN(client *models.OauthClient, subjectDN string) error {
+	err := s.db.Model(new(models.OauthClient)).Where("id = ?", client.ID).
+		UpdateColumn("tls_client_auth_subject_dn", subjectDN).Error
+	if err != nil {
+		return err
+	}
+	client.TLSClientAuthSubjectDN = subjectDN
+	return nil
+}
+
+// SetTLSClientAuthSAN lets the client authenticate with TLS client
+// certificates carrying the subject alternative name, such as
+// "dns:client.example.com" or "ip:10.0.0.1", passing an empty name disables it
+func (s *Service) SetTLSClientAuthSAN(client *models.OauthClient, san string) error {
+	if san != "" {
+		if _, _, err := parseSAN(san); err != nil {
+			return err
+		}
+	}
+
+	err := s.db.Model(new(models.OauthClient)).Where("id = ?", client.ID).
+		UpdateColumn("tls_client_auth_san", san).Error
+	if err != nil {
+		return err
+	}
+	client.TLSClientAuthSAN = san
+	return nil
+}
+
+// parseSAN splits a subject alternative name into its type and value
+func parseSAN(san string) (string, string, error) {
+	parts := strings.SplitN(san, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return "", "", ErrInvalidSubjectAlternativeName
+	}
+
+	switch parts[0] {
+	case sanDNS, sanURI, sanEmail:
+		return parts[0], parts[1], nil
+	case sanIP:
+		if net.ParseIP(parts[1]) == nil {
+			return "", "", ErrInvalidSubjectAlternativeName
+		}
+		return parts[0], parts[1], nil
+	}
+	return "", "", ErrInvalidSubjectAlternativeName
+}
+
+// certificateHasSAN returns true if the certificate
+// carries the subject alternative name
+func certificateHasSAN(certificate *x509.Certificate, san string) bool {
+	sanType, value, err := parseSAN(san)
+	if err != nil {
+		return false
+	}
+
+	switch sanType {
+	case sanDNS:
+		for _, name := range certificate.DNSNames {
+			if strings.EqualFold(name, value) {
+				return true
+			}
+		}
+	case sanURI:
+		for _, uri := range certificate.URIs {
+			if uri.String() == value {
+				return true
+			}
+		}
+	case sanIP:
+		for _, ip := range certificate.IPAddresses {
+			if ip.Equal(net.ParseIP(value)) {
+				return true
+			}
+		}
+	case sanEmail:
+		return util.StringInSlice(value, certificate.EmailAddresses)
+	}
+	return false
+}
+
+// endpointURL returns the URL of the endpoint the request was sent to
+func (s *Service) endpointURL(r *http.Request) string {
+	return strings.TrimSuffix(s.cnf.JWT.Issuer, "/") + r.URL.Path
+}
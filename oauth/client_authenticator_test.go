@@ -0,0 +1,267 @@
+package oauth_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/util/jwt"
+	"github.com/RichardKnop/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestClientSecretPost() {
+	w := suite.clientCredentialsGrantWith(nil, url.Values{
+		"client_id":     {"test_client_1"},
+		"client_secret": {"test_secret"},
+	})
+	assert.Equal(suite.T(), 200, w.Code)
+
+	w = suite.clientCredentialsGrantWith(nil, url.Values{
+		"client_id":     {"test_client_1"},
+		"client_secret": {"bogus"},
+	})
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+
+	// The method can be disabled
+	suite.cnf.Oauth.DisabledClientAuthMethods = []string{oauth.ClientSecretPost}
+	defer func() { suite.cnf.Oauth.DisabledClientAuthMethods = nil }()
+	w = suite.clientCredentialsGrantWith(nil, url.Values{
+		"client_id":     {"test_client_1"},
+		"client_secret": {"test_secret"},
+	})
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+}
+
+func (suite *OauthTestSuite) TestPrivateKeyJWT() {
+	signer := suite.registerAssertionKey("test_client_1")
+	tokenEndpoint := suite.cnf.JWT.Issuer + "/v1/oauth/tokens"
+
+	testCases := []struct {
+		claims jwt.Claims
+		status int
+	}{
+		// The audience is either the issuer or the token endpoint
+		{jwt.Claims{"iss": "test_client_1", "sub": "test_client_1", "aud": tokenEndpoint}, 200},
+		{jwt.Claims{"iss": "test_client_1", "sub": "test_client_1", "aud": suite.cnf.JWT.Issuer}, 200},
+		{jwt.Claims{"iss": "test_client_1", "sub": "test_client_1", "aud": "https://other.example.com"}, http.StatusUnauthorized},
+		// The client must be both the issuer and the subject
+		{jwt.Claims{"iss": "test_client_1", "sub": "test_client_2", "aud": tokenEndpoint}, http.StatusUnauthorized},
+	}
+	for _, testCase := range testCases {
+		testCase.claims["exp"] = time.Now().Add(time.Minute).Unix()
+		testCase.claims["jti"] = uuid.New()
+		w := suite.clientCredentialsGrantWith(nil, url.Values{
+			"client_assertion_type": {oauth.ClientAssertionType},
+			"client_assertion":      {suite.signAssertion(signer, testCase.claims)},
+		})
+		assert.Equal(suite.T(), testCase.status, w.Code, testCase.claims)
+	}
+
+	// Keys the client registered for other issuers cannot authenticate it
+	otherSigner := suite.registerAssertionKey("https://backend.example.com")
+	w := suite.clientCredentialsGrantWith(nil, url.Values{
+		"client_assertion_type": {oauth.ClientAssertionType},
+		"client_assertion": {suite.signAssertion(otherSigner, jwt.Claims{
+			"iss": "https://backend.example.com",
+			"sub": "https://backend.example.com",
+			"aud": tokenEndpoint,
+			"exp": time.Now().Add(time.Minute).Unix(),
+			"jti": uuid.New(),
+		})},
+	})
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+}
+
+func (suite *OauthTestSuite) TestClientSecretJWT() {
+	_, err := suite.service.AddAssertionKey(suite.clients[0], "test_client_1", jwt.HS256, "test_shared_secret")
+	assert.NoError(suite.T(), err)
+
+	for _, testCase := range []struct {
+		secret string
+		status int
+	}{
+		{"test_shared_secret", 200},
+		{"bogus", http.StatusUnauthorized},
+	} {
+		signer, err := jwt.NewHS256("", []byte(testCase.secret))
+		assert.NoError(suite.T(), err)
+		w := suite.clientCredentialsGrantWith(nil, url.Values{
+			"client_id":             {"test_client_1"},
+			"client_assertion_type": {oauth.ClientAssertionType},
+			"client_assertion": {suite.signAssertion(signer, jwt.Claims{
+				"iss": "test_client_1",
+				"sub": "test_client_1",
+				"aud": suite.cnf.JWT.Issuer,
+				"exp": time.Now().Add(time.Minute).Unix(),
+				"jti": uuid.New(),
+			})},
+		})
+		assert.Equal(suite.T(), testCase.status, w.Code)
+	}
+}
+
+func (suite *OauthTestSuite) TestClientAssertionReplay() {
+	signer := suite.registerAssertionKey("test_client_1")
+	claims := jwt.Claims{
+		"iss": "test_client_1",
+		"sub": "test_client_1",
+		"aud": suite.cnf.JWT.Issuer,
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}
+
+	// Assertions without a jti cannot be told apart from replays
+	w := suite.clientCredentialsGrantWith(nil, url.Values{
+		"client_assertion_type": {oauth.ClientAssertionType},
+		"client_assertion":      {suite.signAssertion(signer, claims)},
+	})
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+
+	// An assertion authenticates a single request
+	claims["jti"] = uuid.New()
+	credentials := url.Values{
+		"client_assertion_type": {oauth.ClientAssertionType},
+		"client_assertion":      {suite.signAssertion(signer, claims)},
+	}
+	w = suite.clientCredentialsGrantWith(nil, credentials)
+	assert.Equal(suite.T(), 200, w.Code)
+	w = suite.clientCredentialsGrantWith(nil, credentials)
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+}
+
+func (suite *OauthTestSuite) TestJSONWebKeyAssertionKey() {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(suite.T(), err)
+	signer, err := jwt.NewES256("", privateKey)
+	assert.NoError(suite.T(), err)
+	jsonWebKey, err := jwt.NewJSONWebKey(signer)
+	assert.NoError(suite.T(), err)
+	encoded, err := json.Marshal(jsonWebKey)
+	assert.NoError(suite.T(), err)
+
+	_, err = suite.service.AddAssertionKey(suite.clients[0], "test_client_1", jwt.ES256, string(encoded))
+	assert.NoError(suite.T(), err)
+	_, err = suite.service.AddAssertionKey(suite.clients[0], "test_client_1", jwt.ES256, `{"kty": "bogus"}`)
+	assert.Error(suite.T(), err)
+
+	w := suite.clientCredentialsGrantWith(nil, url.Values{
+		"client_assertion_type": {oauth.ClientAssertionType},
+		"client_assertion": {suite.signAssertion(signer, jwt.Claims{
+			"iss": "test_client_1",
+			"sub": "test_client_1",
+			"aud": suite.cnf.JWT.Issuer,
+			"exp": time.Now().Add(time.Minute).Unix(),
+			"jti": uuid.New(),
+		})},
+	})
+	assert.Equal(suite.T(), 200, w.Code)
+}
+
+func (suite *OauthTestSuite) TestTLSClientAuth() {
+	connectionState := &tls.ConnectionState{
+		VerifiedChains: [][]*x509.Certificate{{{
+			Subject: pkix.Name{CommonName: "client.example.com", Organization: []string{"Example"}},
+		}}},
+	}
+	form := url.Values{"client_id": {"test_client_1"}}
+
+	// Clients cannot use certificates until they registered the subject
+	w := suite.clientCredentialsGrantWith(connectionState, form)
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+
+	err := suite.service.SetTLSClientAuthSubjectDN(suite.clients[0], "CN=client.example.com,O=Example")
+	assert.NoError(suite.T(), err)
+	defer suite.service.SetTLSClientAuthSubjectDN(suite.clients[0], "")
+	w = suite.clientCredentialsGrantWith(connectionState, form)
+	assert.Equal(suite.T(), 200, w.Code)
+
+	// Certificates issued to other subjects are rejected
+	connectionState.VerifiedChains[0][0].Subject.CommonName = "other.example.com"
+	w = suite.clientCredentialsGrantWith(connectionState, form)
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+}
+
+func (suite *OauthTestSuite) TestTLSClientAuthSAN() {
+	connectionState := &tls.ConnectionState{
+		VerifiedChains: [][]*x509.Certificate{{{
+			Subject:     pkix.Name{CommonName: "client.example.com"},
+			DNSNames:    []string{"client.example.com"},
+			IPAddresses: []net.IP{net.ParseIP("10.0.0.1")},
+		}}},
+	}
+	form := url.Values{"client_id": {"test_client_1"}}
+
+	assert.Equal(suite.T(), oauth.ErrInvalidSubjectAlternativeName, suite.service.SetTLSClientAuthSAN(suite.clients[0], "bogus"))
+	assert.Equal(suite.T(), oauth.ErrInvalidSubjectAlternativeName, suite.service.SetTLSClientAuthSAN(suite.clients[0], "ip:bogus"))
+	defer suite.service.SetTLSClientAuthSAN(suite.clients[0], "")
+
+	testCases := []struct {
+		san    string
+		status int
+	}{
+		{"dns:client.example.com", 200},
+		{"dns:CLIENT.example.com", 200},
+		{"ip:10.0.0.1", 200},
+		{"dns:other.example.com", http.StatusUnauthorized},
+		{"email:client@example.com", http.StatusUnauthorized},
+	}
+	for _, testCase := range testCases {
+		assert.NoError(suite.T(), suite.service.SetTLSClientAuthSAN(suite.clients[0], testCase.san))
+		w := suite.clientCredentialsGrantWith(connectionState, form)
+		assert.Equal(suite.T(), testCase.status, w.Code, testCase.san)
+	}
+}
+
+func (suite *OauthTestSuite) TestRegisterClientAuthenticator() {
+	// Use a separate service so the custom method does not affect other tests
+	service := oauth.NewService(suite.cnf, suite.db)
+	router := mux.NewRouter()
+	service.RegisterRoutes(router, "/v1/oauth")
+
+	service.RegisterClientAuthenticator("test_header", oauth.ClientAuthenticatorFunc(func(r *http.Request) (*models.OauthClient, error) {
+		if r.Header.Get("X-Client") == "" {
+			return nil, oauth.ErrClientCredentialsMissing
+		}
+		return service.FindClientByClientID(r.Header.Get("X-Client"))
+	}))
+
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.Header.Set("X-Client", "test_client_1")
+	r.PostForm = url.Values{
+		"grant_type": {"client_credentials"},
+		"scope":      {"read"},
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	assert.Equal(suite.T(), 200, w.Code)
+}
+
+func (suite *OauthTestSuite) clientCredentialsGrantWith(connectionState *tls.ConnectionState, credentials url.Values) *httptest.ResponseRecorder {
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.TLS = connectionState
+	r.PostForm = url.Values{
+		"grant_type": {"client_credentials"},
+		"scope":      {"read"},
+	}
+	for name, values := range credentials {
+		r.PostForm[name] = values
+	}
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	return w
+}
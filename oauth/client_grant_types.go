@@ -0,0 +1,45 @@
+package oauth
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util"
+)
+
+var (
+	// ErrUnauthorizedClient ...
+	ErrUnauthorizedClient = errors.New("Client is not allowed to use this grant type")
+	// ErrUnknownGrantType ...
+	ErrUnknownGrantType = errors.New("Unknown grant type")
+)
+
+// ClientAllowsGrantType returns false if the client has been registered
+// for other grant types only, public clients cannot use client credentials
+func ClientAllowsGrantType(client *models.OauthClient, grantType string) bool {
+	if grantType == "client_credentials" && IsPublicClient(client) {
+		return false
+	}
+	allowed := strings.Fields(client.AllowedGrantTypes)
+	return len(allowed) == 0 || util.StringInSlice(grantType, allowed)
+}
+
+// SetAllowedGrantTypes restricts the grant types the client can use,
+// an empty list allows all of them again
+func (s *Service) SetAllowedGrantTypes(client *models.OauthClient, grantTypes []string) error {
+	for _, grantType := range grantTypes {
+		if _, ok := s.grantTypes()[grantType]; !ok && grantType != "implicit" {
+			return ErrUnknownGrantType
+		}
+	}
+
+	allowedGrantTypes := strings.Join(grantTypes, " ")
+	err := s.db.Model(new(models.OauthClient)).Where("id = ?", client.ID).
+		UpdateColumn("allowed_grant_types", allowedGrantTypes).Error
+	if err != nil {
+		return err
+	}
+	client.AllowedGrantTypes = allowedGrantTypes
+	return nil
+}
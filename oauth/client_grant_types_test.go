@@ -0,0 +1,80 @@
+package oauth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestClientAllowsGrantType() {
+	client := new(models.OauthClient)
+	assert.True(suite.T(), oauth.ClientAllowsGrantType(client, "password"))
+
+	client.AllowedGrantTypes = "client_credentials refresh_token"
+	assert.True(suite.T(), oauth.ClientAllowsGrantType(client, "client_credentials"))
+	assert.True(suite.T(), oauth.ClientAllowsGrantType(client, "refresh_token"))
+	assert.False(suite.T(), oauth.ClientAllowsGrantType(client, "password"))
+	assert.False(suite.T(), oauth.ClientAllowsGrantType(client, ""))
+}
+
+func (suite *OauthTestSuite) TestSetAllowedGrantTypes() {
+	err := suite.service.SetAllowedGrantTypes(suite.clients[1], []string{"bogus"})
+	assert.Equal(suite.T(), oauth.ErrUnknownGrantType, err)
+
+	err = suite.service.SetAllowedGrantTypes(suite.clients[1], []string{"client_credentials", "implicit"})
+	assert.NoError(suite.T(), err)
+	defer suite.service.SetAllowedGrantTypes(suite.clients[1], nil)
+
+	client, err := suite.service.FindClientByClientID("test_client_2")
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), "client_credentials implicit", client.AllowedGrantTypes)
+	}
+}
+
+func (suite *OauthTestSuite) TestTokensHandlerClientNotAllowedGrantType() {
+	err := suite.service.SetAllowedGrantTypes(suite.clients[1], []string{"client_credentials"})
+	assert.NoError(suite.T(), err)
+	defer suite.service.SetAllowedGrantTypes(suite.clients[1], nil)
+
+	for _, testCase := range []struct {
+		grantType string
+		status    int
+	}{
+		{"client_credentials", 200},
+		{"password", 400},
+	} {
+		r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+		assert.NoError(suite.T(), err, "Request setup should not get an error")
+		r.SetBasicAuth("test_client_2", "test_secret")
+		r.PostForm = url.Values{
+			"grant_type": {testCase.grantType},
+			"username":   {"test@user"},
+			"password":   {"test_password"},
+			"scope":      {"read_write"},
+		}
+
+		w := httptest.NewRecorder()
+		suite.router.ServeHTTP(w, r)
+
+		if testCase.status == 200 {
+			assert.Equal(suite.T(), 200, w.Code)
+		} else {
+			suite.assertErrorCode(w, "unauthorized_client")
+		}
+	}
+
+	// The device flow is refused before a device code is issued
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/device_authorization", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_2", "test_secret")
+	r.PostForm = url.Values{"scope": {"read_write"}}
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	suite.assertErrorCode(w, "unauthorized_client")
+	assert.True(suite.T(), suite.db.First(new(models.OauthDeviceCode)).RecordNotFound())
+}
@@ -0,0 +1,265 @@
+package oauth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/RichardKnop/uuid"
+	"github.com/gorilla/mux"
+)
+
+var (
+	// ErrClientRegistrationDisabled ...
+	ErrClientRegistrationDisabled = errors.New("Client registration is disabled")
+	// ErrInvalidInitialAccessToken ...
+	ErrInvalidInitialAccessToken = errors.New("Invalid initial access token")
+	// ErrInvalidRegistrationAccessToken ...
+	ErrInvalidRegistrationAccessToken = errors.New("Invalid registration access token")
+	// ErrInvalidClientMetadata ...
+	ErrInvalidClientMetadata = errors.New("Invalid client metadata")
+	// ErrInvalidClientRedirectURI ...
+	ErrInvalidClientRedirectURI = errors.New("Invalid redirect URI")
+	// ErrTooManyRedirectURIs ...
+	ErrTooManyRedirectURIs = errors.New("Only one redirect URI can be registered, an admin can add others")
+
+	// registrationAuthMethods are the methods registered clients can
+	// authenticate with, they all use the issued client secret
+	registrationAuthMethods = []string{ClientSecretBasic, ClientSecretPost}
+
+	// defaultRegistrationGrantTypes only let registered clients act for
+	// users who approved them
+	defaultRegistrationGrantTypes = []string{
+		"authorization_code",
+		"implicit",
+		"refresh_token",
+		DeviceCodeGrantType,
+	}
+)
+
+// ClientMetadata is the metadata a client registers itself with,
+// see https://tools.ietf.org/html/rfc7591#section-2
+type ClientMetadata struct {
+	RedirectURIs            []string `json:"redirect_uris,omitempty"`
+	GrantTypes              []string `json:"grant_types,omitempty"`
+	Scope                   string   `json:"scope,omitempty"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method,omitempty"`
+	ClientName              string   `json:"client_name,omitempty"`
+}
+
+// ClientUpdateRequest replaces the metadata of a registered client,
+// see https://tools.ietf.org/html/rfc7592#section-2.2
+type ClientUpdateRequest struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	ClientMetadata
+}
+
+// RegisterClient creates a client with the metadata it registered with and
+// returns its generated credentials. Omitted metadata defaults as per
+// RFC 7591 section 2: the authorization code grant and basic authentication.
+func (s *Service) RegisterClient(metadata *ClientMetadata) (*ClientRegistrationResponse, error) {
+	s.setRegistrationDefaults(metadata)
+	redirectURI, err := s.validateClientMetadata(metadata, registrationAuthMethods)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkRegistrationMetadata(metadata); err != nil {
+		return nil, err
+	}
+
+	// Generate the credentials
+	secret, err := GenerateClientSecret()
+	if err != nil {
+		return nil, err
+	}
+	registrationAccessToken, err := GenerateClientSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := s.createClientWithMetadata(uuid.New(), secret, redirectURI, metadata, map[string]interface{}{
+		"registration_access_token": models.HashToken(registrationAccessToken),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := NewClientRegistrationResponse(client)
+	resp.ClientSecret = secret
+	resp.RegistrationAccessToken = registrationAccessToken
+	return resp, nil
+}
+
+// UpdateRegisteredClient replaces the metadata of a dynamically registered
+// client, see https://tools.ietf.org/html/rfc7592#section-2.2. The client keeps
+// its secret if it sends it along, otherwise a new secret is issued.
+func (s *Service) UpdateRegisteredClient(client *models.OauthClient, metadata *ClientMetadata, secret string) (*ClientRegistrationResponse, error) {
+	s.setRegistrationDefaults(metadata)
+	redirectURI, err := s.validateClientMetadata(metadata, registrationAuthMethods)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkRegistrationMetadata(metadata); err != nil {
+		return nil, err
+	}
+
+	// Check the secret the client sent is its current one
+	if secret != "" && verifyClientSecret(client, secret) != nil {
+		return nil, ErrInvalidClientMetadata
+	}
+
+	if err := s.updateClientMetadata(client, redirectURI, metadata); err != nil {
+		return nil, err
+	}
+
+	// Issue a new secret unless the client sent its current one
+	if secret == "" {
+		secret, err = s.RotateClientSecret(client)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resp := NewClientRegistrationResponse(client)
+	resp.ClientSecret = secret
+	return resp, nil
+}
+
+// DeleteRegisteredClient deletes a dynamically registered client and
+// revokes the tokens issued to it, see https://tools.ietf.org/html/rfc7592#section-2.3
+func (s *Service) DeleteRegisteredClient(client *models.OauthClient) error {
+	return s.DeleteClient(client)
+}
+
+// FindRegisteredClient returns a dynamically registered client
+// if the registration access token is the one issued to it
+func (s *Service) FindRegisteredClient(clientID, registrationAccessToken string) (*models.OauthClient, error) {
+	client, err := s.FindClientByClientID(clientID)
+	if err != nil || client.RegistrationAccessToken == "" {
+		return nil, ErrInvalidRegistrationAccessToken
+	}
+
+	tokenHash := models.HashToken(registrationAccessToken)
+	if subtle.ConstantTimeCompare([]byte(tokenHash), []byte(client.RegistrationAccessToken)) != 1 {
+		return nil, ErrInvalidRegistrationAccessToken
+	}
+
+	return client, nil
+}
+
+// authenticateRegisteredClient returns the client of a client configuration
+// request, authenticated with the registration access token it carries
+func (s *Service) authenticateRegisteredClient(r *http.Request) (*models.OauthClient, error) {
+	token, err := util.ParseBearerToken(r)
+	if err != nil {
+		return nil, ErrInvalidRegistrationAccessToken
+	}
+	return s.FindRegisteredClient(mux.Vars(r)["client_id"], string(token))
+}
+
+// setRegistrationDefaults fills in the metadata a client registering
+// itself omitted, see https://tools.ietf.org/html/rfc7591#section-2.
+// Clients registering for no scope are registered for the default ones.
+func (s *Service) setRegistrationDefaults(metadata *ClientMetadata) {
+	if len(metadata.GrantTypes) == 0 {
+		metadata.GrantTypes = []string{"authorization_code"}
+	}
+	if metadata.TokenEndpointAuthMethod == "" {
+		metadata.TokenEndpointAuthMethod = ClientSecretBasic
+	}
+	if len(parseScope(metadata.Scope)) == 0 {
+		metadata.Scope = s.GetDefaultScope()
+	}
+}
+
+// checkRegistrationMetadata refuses grant types clients cannot register
+// themselves for and scopes other than the default ones, which only an
+// admin can grant
+func (s *Service) checkRegistrationMetadata(metadata *ClientMetadata) error {
+	grantTypes := s.cnf.Oauth.RegistrationGrantTypes
+	if len(grantTypes) == 0 {
+		grantTypes = defaultRegistrationGrantTypes
+	}
+	for _, grantType := range metadata.GrantTypes {
+		if !util.StringInSlice(grantType, grantTypes) {
+			return ErrInvalidClientMetadata
+		}
+	}
+
+	defaultScopes := parseScope(s.GetDefaultScope())
+	scopes := parseScope(metadata.Scope)
+	if len(scopes) == 0 {
+		return ErrInvalidClientMetadata
+	}
+	for _, scope := range scopes {
+		if !util.StringInSlice(scope, defaultScopes) {
+			return ErrInvalidClientMetadata
+		}
+	}
+	return nil
+}
+
+// validateClientMetadata checks the metadata of a client, which can
+// authenticate with one of the methods only, and returns its redirect URI
+func (s *Service) validateClientMetadata(metadata *ClientMetadata, authMethods []string) (string, error) {
+	for _, grantType := range metadata.GrantTypes {
+		if _, ok := s.grantTypes()[grantType]; !ok && grantType != "implicit" {
+			return "", ErrInvalidClientMetadata
+		}
+	}
+
+	if metadata.TokenEndpointAuthMethod != "" {
+		if !util.StringInSlice(metadata.TokenEndpointAuthMethod, authMethods) ||
+			!s.clientAuthMethodEnabled(metadata.TokenEndpointAuthMethod) {
+			return "", ErrInvalidClientMetadata
+		}
+	}
+
+	// Clients are granted any scope unless they are restricted
+	if metadata.Scope != "" {
+		scope, err := s.GetScope(metadata.Scope)
+		if err != nil {
+			return "", ErrInvalidClientMetadata
+		}
+		metadata.Scope = scope
+	}
+
+	// Metadata only carries the redirect URI a client is created with, other
+	// redirect URIs are added by an admin and are kept when it is replaced.
+	// Accepting more here would silently drop them, so they are refused.
+	if len(metadata.RedirectURIs) > 1 {
+		return "", ErrTooManyRedirectURIs
+	}
+
+	// Clients registered here are not native apps
+	var redirectURI string
+	if len(metadata.RedirectURIs) == 1 {
+		redirectURI = metadata.RedirectURIs[0]
+		if err := s.validateRegisteredRedirectURI(redirectURI, false); err != nil {
+			return "", ErrInvalidClientRedirectURI
+		}
+	}
+	redirectGrant := util.StringInSlice("authorization_code", metadata.GrantTypes) ||
+		util.StringInSlice("implicit", metadata.GrantTypes)
+	if redirectURI == "" && redirectGrant {
+		return "", ErrInvalidClientRedirectURI
+	}
+
+	return redirectURI, nil
+}
+
+// checkInitialAccessToken checks the request carries the configured
+// initial access token, if registration requires one
+func (s *Service) checkInitialAccessToken(token []byte) error {
+	initialAccessToken := s.cnf.Oauth.RegistrationInitialAccessToken
+	if initialAccessToken == "" {
+		return nil
+	}
+	if subtle.ConstantTimeCompare(token, []byte(initialAccessToken)) != 1 {
+		return ErrInvalidInitialAccessToken
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package oauth_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/test-util"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestRegisterClient() {
+	suite.cnf.Oauth.EnableClientRegistration = true
+	suite.cnf.Oauth.RegistrationGrantTypes = []string{"authorization_code", "client_credentials"}
+	defer func() {
+		suite.cnf.Oauth.EnableClientRegistration = false
+		suite.cnf.Oauth.RegistrationGrantTypes = nil
+	}()
+
+	w := suite.registerClient(`{
+		"client_name": "Partner",
+		"redirect_uris": ["https://partner.example.com/callback"],
+		"grant_types": ["authorization_code", "client_credentials"],
+		"scope": "read",
+		"token_endpoint_auth_method": "client_secret_post"
+	}`, "")
+	if !assert.Equal(suite.T(), http.StatusCreated, w.Code) {
+		return
+	}
+	resp := new(oauth.ClientRegistrationResponse)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), resp))
+	assert.NotEmpty(suite.T(), resp.ClientSecret)
+	assert.NotEmpty(suite.T(), resp.RegistrationAccessToken)
+	assert.True(suite.T(), strings.HasSuffix(resp.RegistrationClientURI, "/v1/oauth/register/"+resp.ClientID))
+	assert.Equal(suite.T(), []string{"authorization_code", "client_credentials"}, resp.GrantTypes)
+
+	// The client is stored with its metadata
+	client, err := suite.service.FindClientByClientID(resp.ClientID)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), "Partner", client.Name.String)
+		assert.Equal(suite.T(), "https://partner.example.com/callback", client.RedirectURI.String)
+		assert.Equal(suite.T(), "read", client.Scope)
+		assert.Equal(suite.T(), oauth.ClientSecretPost, client.TokenEndpointAuthMethod)
+		assert.NotEqual(suite.T(), resp.RegistrationAccessToken, client.RegistrationAccessToken)
+	}
+
+	// The client can only use the registered scope
+	for scope, code := range map[string]int{"read": http.StatusOK, "read_write": http.StatusBadRequest} {
+		w := suite.clientCredentialsGrantWith(nil, url.Values{
+			"client_id":     {resp.ClientID},
+			"client_secret": {resp.ClientSecret},
+			"scope":         {scope},
+		})
+		assert.Equal(suite.T(), code, w.Code, w.Body.String())
+	}
+
+	// And the registered authentication method
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth(resp.ClientID, resp.ClientSecret)
+	r.PostForm = url.Values{"grant_type": {"client_credentials"}}
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+
+	// The registration can be read with the registration access token
+	r, err = http.NewRequest("GET", resp.RegistrationClientURI, nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.Header.Set("Authorization", "Bearer "+resp.RegistrationAccessToken)
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	if assert.Equal(suite.T(), http.StatusOK, w.Code) {
+		read := new(oauth.ClientRegistrationResponse)
+		assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), read))
+		assert.Equal(suite.T(), resp.ClientID, read.ClientID)
+		assert.Empty(suite.T(), read.ClientSecret)
+		assert.Equal(suite.T(), resp.ClientMetadata, read.ClientMetadata)
+	}
+
+	// But not with another token
+	r.Header.Set("Authorization", "Bearer bogus")
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+}
+
+func (suite *OauthTestSuite) TestRegisterClientFails() {
+	// Registration is disabled by default
+	w := suite.registerClient(`{"grant_types": ["client_credentials"]}`, "")
+	testutil.TestResponseForError(suite.T(), w, oauth.ErrClientRegistrationDisabled.Error(), 403)
+
+	suite.cnf.Oauth.EnableClientRegistration = true
+	suite.cnf.Oauth.RegistrationInitialAccessToken = "initial_token"
+	defer func() {
+		suite.cnf.Oauth.EnableClientRegistration = false
+		suite.cnf.Oauth.RegistrationInitialAccessToken = ""
+	}()
+
+	// The initial access token is required
+	w = suite.registerClient(`{"grant_types": ["client_credentials"]}`, "")
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+
+	for _, testCase := range []struct {
+		body      string
+		errorCode string
+		err       error
+	}{
+		{`not json`, "invalid_client_metadata", oauth.ErrInvalidClientMetadata},
+		{`{"grant_types": ["bogus"]}`, "invalid_client_metadata", oauth.ErrInvalidClientMetadata},
+		{`{"grant_types": ["client_credentials"], "scope": "bogus"}`, "invalid_client_metadata", oauth.ErrInvalidClientMetadata},
+		{`{"grant_types": ["client_credentials"], "token_endpoint_auth_method": "none"}`, "invalid_client_metadata", oauth.ErrInvalidClientMetadata},
+		{`{"grant_types": ["authorization_code"]}`, "invalid_redirect_uri", oauth.ErrInvalidClientRedirectURI},
+		{`{"redirect_uris": ["javascript:alert(1)"]}`, "invalid_redirect_uri", oauth.ErrInvalidClientRedirectURI},
+		{`{"redirect_uris": ["https://a.example.com", "https://b.example.com"]}`, "invalid_redirect_uri", oauth.ErrTooManyRedirectURIs},
+		// Clients can only register themselves to act for users
+		// and for the default scopes
+		{`{"grant_types": ["client_credentials"]}`, "invalid_client_metadata", oauth.ErrInvalidClientMetadata},
+		{`{"grant_types": ["password"]}`, "invalid_client_metadata", oauth.ErrInvalidClientMetadata},
+		{`{"redirect_uris": ["https://a.example.com"], "scope": "read read_write"}`, "invalid_client_metadata", oauth.ErrInvalidClientMetadata},
+	} {
+		w := suite.registerClient(testCase.body, "initial_token")
+		testutil.TestResponseForOauthError(suite.T(), w, testCase.errorCode, testCase.err.Error(), 400)
+	}
+}
+
+func (suite *OauthTestSuite) registerClient(body, initialAccessToken string) *httptest.ResponseRecorder {
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/register", bytes.NewBufferString(body))
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.Header.Set("Content-Type", "application/json")
+	if initialAccessToken != "" {
+		r.Header.Set("Authorization", "Bearer "+initialAccessToken)
+	}
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	return w
+}
+
+func (suite *OauthTestSuite) TestUpdateRegisteredClient() {
+	suite.cnf.Oauth.EnableClientRegistration = true
+	suite.cnf.Oauth.RegistrationGrantTypes = []string{"authorization_code", "client_credentials"}
+	defer func() {
+		suite.cnf.Oauth.EnableClientRegistration = false
+		suite.cnf.Oauth.RegistrationGrantTypes = nil
+	}()
+
+	registered := suite.registeredClient()
+	if registered == nil {
+		return
+	}
+
+	// The client must name itself and send its current secret, if any
+	for _, body := range []string{
+		`{"client_id": "bogus", "grant_types": ["client_credentials"]}`,
+		`{"client_id": "` + registered.ClientID + `", "client_secret": "bogus", "grant_types": ["client_credentials"]}`,
+	} {
+		w := suite.clientConfigurationRequest("PUT", registered, body)
+		testutil.TestResponseForOauthError(suite.T(), w, "invalid_client_metadata", oauth.ErrInvalidClientMetadata.Error(), 400)
+	}
+
+	// Clients cannot grant themselves more than the default scopes
+	w := suite.clientConfigurationRequest("PUT", registered, `{
+		"client_id": "`+registered.ClientID+`",
+		"client_secret": "`+registered.ClientSecret+`",
+		"grant_types": ["client_credentials"],
+		"scope": "read_write"
+	}`)
+	testutil.TestResponseForOauthError(suite.T(), w, "invalid_client_metadata", oauth.ErrInvalidClientMetadata.Error(), 400)
+
+	// Sending the current secret keeps it
+	w = suite.clientConfigurationRequest("PUT", registered, `{
+		"client_id": "`+registered.ClientID+`",
+		"client_secret": "`+registered.ClientSecret+`",
+		"grant_types": ["client_credentials"],
+		"scope": "read"
+	}`)
+	if assert.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String()) {
+		resp := new(oauth.ClientRegistrationResponse)
+		assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), resp))
+		assert.Equal(suite.T(), registered.ClientSecret, resp.ClientSecret)
+		assert.Equal(suite.T(), "read", resp.Scope)
+		assert.Empty(suite.T(), resp.RedirectURIs)
+	}
+
+	// Omitting it rotates the secret
+	w = suite.clientConfigurationRequest("PUT", registered, `{
+		"client_id": "`+registered.ClientID+`",
+		"grant_types": ["client_credentials"]
+	}`)
+	if assert.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String()) {
+		resp := new(oauth.ClientRegistrationResponse)
+		assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), resp))
+		assert.NotEqual(suite.T(), registered.ClientSecret, resp.ClientSecret)
+
+		_, err := suite.service.AuthClient(registered.ClientID, registered.ClientSecret)
+		assert.Equal(suite.T(), oauth.ErrInvalidClientSecret, err)
+		_, err = suite.service.AuthClient(registered.ClientID, resp.ClientSecret)
+		assert.NoError(suite.T(), err)
+	}
+}
+
+func (suite *OauthTestSuite) TestDeleteRegisteredClient() {
+	suite.cnf.Oauth.EnableClientRegistration = true
+	suite.cnf.Oauth.RegistrationGrantTypes = []string{"authorization_code", "client_credentials"}
+	defer func() {
+		suite.cnf.Oauth.EnableClientRegistration = false
+		suite.cnf.Oauth.RegistrationGrantTypes = nil
+	}()
+
+	registered := suite.registeredClient()
+	if registered == nil {
+		return
+	}
+	w := suite.clientCredentialsGrantWith(nil, url.Values{
+		"client_id":     {registered.ClientID},
+		"client_secret": {registered.ClientSecret},
+	})
+	tokenResp := suite.decodeAccessTokenResponse(w)
+
+	w = suite.clientConfigurationRequest("DELETE", registered, "")
+	assert.Equal(suite.T(), http.StatusNoContent, w.Code)
+
+	// The client and its tokens are gone
+	assert.False(suite.T(), suite.service.ClientExists(registered.ClientID))
+	_, err := suite.service.Authenticate(tokenResp.AccessToken)
+	assert.Equal(suite.T(), oauth.ErrAccessTokenNotFound, err)
+
+	// And so is its registration
+	w = suite.clientConfigurationRequest("GET", registered, "")
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+}
+
+// registeredClient registers a client using the client credentials grant
+func (suite *OauthTestSuite) registeredClient() *oauth.ClientRegistrationResponse {
+	w := suite.registerClient(`{"grant_types": ["client_credentials"], "token_endpoint_auth_method": "client_secret_post"}`, "")
+	if !assert.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String()) {
+		return nil
+	}
+	resp := new(oauth.ClientRegistrationResponse)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), resp))
+	return resp
+}
+
+func (suite *OauthTestSuite) clientConfigurationRequest(method string, registered *oauth.ClientRegistrationResponse, body string) *httptest.ResponseRecorder {
+	r, err := http.NewRequest(method, "http://1.2.3.4/v1/oauth/register/"+registered.ClientID, bytes.NewBufferString(body))
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.Header.Set("Authorization", "Bearer "+registered.RegistrationAccessToken)
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	return w
+}
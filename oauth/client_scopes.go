@@ -0,0 +1,123 @@
+package oauth
+
+import (
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util"
+)
+
+// GetClientScopes returns the scopes an admin granted to the client,
+// a client which has not been granted any can be issued the scopes it
+// registered for, or the default scopes if it registered for none
+func (s *Service) GetClientScopes(client *models.OauthClient) ([]string, error) {
+	var scopes []string
+	err := s.db.Table("oauth_client_scopes").
+		Joins("JOIN oauth_scopes ON oauth_scopes.id = oauth_client_scopes.scope_id").
+		Where("oauth_client_scopes.client_id = ?", client.ID).
+		Order("oauth_scopes.scope").Pluck("oauth_scopes.scope", &scopes).Error
+	if err != nil {
+		return nil, err
+	}
+	return scopes, nil
+}
+
+// SetClientScopes sets the scopes the client can be issued tokens for,
+// an empty list falls back to the scopes the client registered for
+func (s *Service) SetClientScopes(client *models.OauthClient, scopes []string) error {
+	// Every scope granted must exist
+	var granted []*models.OauthScope
+	if len(scopes) > 0 {
+		if err := s.db.Where("scope in (?)", scopes).Find(&granted).Error; err != nil {
+			return err
+		}
+		for _, scope := range scopes {
+			if !scopeFound(granted, scope) {
+				return ErrInvalidScope
+			}
+		}
+	}
+
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	err := tx.Unscoped().Where("client_id = ?", client.ID).
+		Delete(new(models.OauthClientScope)).Error
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+	for _, scope := range granted {
+		if err := tx.Create(models.NewOauthClientScope(client, scope)).Error; err != nil {
+			tx.Rollback() // rollback the transaction
+			return err
+		}
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	return nil
+}
+
+// clientAllowsScope returns false if the client has been registered for,
+// or granted by an admin, other scopes only. Clients neither registered
+// for nor granted any scope are only allowed the default scopes. Scopes
+// implied by the scopes a client is allowed are allowed too.
+func (s *Service) clientAllowsScope(client *models.OauthClient, scope string) (bool, error) {
+	registered := s.expandScope(parseScope(client.Scope))
+
+	granted, err := s.GetClientScopes(client)
+	if err != nil {
+		return false, err
+	}
+	if len(granted) == 0 && len(registered) == 0 {
+		granted = parseScope(s.GetDefaultScope())
+	}
+	granted = s.expandScope(granted)
+
+	for _, requested := range parseScope(scope) {
+		if len(registered) > 0 && !util.StringInSlice(requested, registered) {
+			return false, nil
+		}
+		if len(granted) > 0 && !util.StringInSlice(requested, granted) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// getClientScope returns the requested scope like GetScope, but a client
+// which requests none is only given the default scopes it is allowed
+func (s *Service) getClientScope(client *models.OauthClient, requestedScope string) (string, error) {
+	scope, err := s.GetScope(requestedScope)
+	if err != nil || len(parseScope(requestedScope)) > 0 {
+		return scope, err
+	}
+
+	var allowed []string
+	for _, defaultScope := range parseScope(scope) {
+		ok, err := s.clientAllowsScope(client, defaultScope)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			allowed = append(allowed, defaultScope)
+		}
+	}
+
+	return strings.Join(allowed, " "), nil
+}
+
+// scopeFound returns true if the scope is one of the scopes
+func scopeFound(scopes []*models.OauthScope, scope string) bool {
+	for _, s := range scopes {
+		if s.Scope == scope {
+			return true
+		}
+	}
+	return false
+}
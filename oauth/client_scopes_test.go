@@ -0,0 +1,89 @@
+package oauth_test
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestSetClientScopes() {
+	err := suite.service.SetClientScopes(suite.clients[1], []string{"read", "bogus"})
+	assert.Equal(suite.T(), oauth.ErrInvalidScope, err)
+
+	err = suite.service.SetClientScopes(suite.clients[1], []string{"read_write", "openid"})
+	assert.NoError(suite.T(), err)
+
+	scopes, err := suite.service.GetClientScopes(suite.clients[1])
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), []string{"openid", "read_write"}, scopes)
+	}
+
+	// Granting no scopes lifts the restriction
+	assert.NoError(suite.T(), suite.service.SetClientScopes(suite.clients[1], nil))
+	scopes, err = suite.service.GetClientScopes(suite.clients[1])
+	if assert.NoError(suite.T(), err) {
+		assert.Empty(suite.T(), scopes)
+	}
+}
+
+func (suite *OauthTestSuite) TestTokensHandlerClientNotGrantedScope() {
+	err := suite.service.SetClientScopes(suite.clients[1], []string{"read_write"})
+	assert.NoError(suite.T(), err)
+
+	credentials := url.Values{
+		"client_id":     {"test_client_2"},
+		"client_secret": {"test_secret"},
+	}
+
+	// Scopes the client has not been granted are refused
+	for scope, status := range map[string]int{"read_write": 200, "read": 400, "read read_write": 400} {
+		credentials.Set("scope", scope)
+		w := suite.clientCredentialsGrantWith(nil, credentials)
+		assert.Equal(suite.T(), status, w.Code, scope)
+	}
+
+	// The default scope is not granted to the client either
+	credentials.Set("scope", "")
+	resp := suite.decodeAccessTokenResponse(suite.clientCredentialsGrantWith(nil, credentials))
+	assert.Empty(suite.T(), resp.Scope)
+
+	// Other clients can still be granted any scope
+	w := suite.clientCredentialsGrantWith(nil, url.Values{
+		"client_id":     {"test_client_1"},
+		"client_secret": {"test_secret"},
+	})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+}
+
+func (suite *OauthTestSuite) TestClientAllowsDefaultScopesOnly() {
+	client, err := suite.service.CreateClient("test_unrestricted_client", "test_secret", "https://www.example.com")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+
+	// A client neither registered for nor granted any scope only gets
+	// the default scopes
+	_, err = suite.service.GrantAccessToken(client, nil, 3600, "read")
+	assert.NoError(suite.T(), err)
+	_, err = suite.service.GrantAccessToken(client, nil, 3600, "read_write")
+	assert.Equal(suite.T(), oauth.ErrInvalidScope, err)
+
+	// Until an admin grants it others
+	assert.NoError(suite.T(), suite.service.SetClientScopes(client, []string{"read_write"}))
+	_, err = suite.service.GrantAccessToken(client, nil, 3600, "read_write")
+	assert.NoError(suite.T(), err)
+}
+
+// allowClientScope registers the client for one more scope, the returned
+// function restores the scopes it was registered for
+func (suite *OauthTestSuite) allowClientScope(client *models.OauthClient, scope string) func() {
+	registered := client.Scope
+	err := suite.db.Model(client).UpdateColumn("scope", registered+" "+scope).Error
+	assert.NoError(suite.T(), err, "Updating test data failed")
+	return func() {
+		suite.db.Model(client).UpdateColumn("scope", registered)
+	}
+}
@@ -0,0 +1,111 @@
+package oauth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util/password"
+)
+
+var (
+	// ErrNoSecondaryClientSecret ...
+	ErrNoSecondaryClientSecret = errors.New("Client has no secondary secret")
+)
+
+// AddSecondaryClientSecret issues a secondary secret to the client, which it
+// can authenticate with alongside its secret until the secondary one is
+// promoted or expired. An existing secondary secret is replaced.
+func (s *Service) AddSecondaryClientSecret(client *models.OauthClient) (string, error) {
+	secret, err := GenerateClientSecret()
+	if err != nil {
+		return "", err
+	}
+	secretHash, err := password.HashPassword(secret)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.setSecondaryClientSecret(client, string(secretHash), nil); err != nil {
+		return "", err
+	}
+
+	return secret, nil
+}
+
+// PromoteSecondaryClientSecret makes the secondary secret of the client its
+// secret. The old secret becomes the secondary one for the grace period in
+// seconds so the client can be rolled over without downtime, it stops
+// working straight away when the grace period is zero.
+func (s *Service) PromoteSecondaryClientSecret(client *models.OauthClient, gracePeriod int) error {
+	if !HasSecondaryClientSecret(client) {
+		return ErrNoSecondaryClientSecret
+	}
+
+	secret, secondarySecret := client.SecondarySecret, ""
+	var expiresAt *time.Time
+	if gracePeriod > 0 {
+		secondarySecret = client.Secret
+		t := time.Now().UTC().Add(time.Duration(gracePeriod) * time.Second)
+		expiresAt = &t
+	}
+
+	err := s.db.Model(new(models.OauthClient)).Where("id = ?", client.ID).
+		UpdateColumns(map[string]interface{}{
+			"secret":                      secret,
+			"secondary_secret":            secondarySecret,
+			"secondary_secret_expires_at": expiresAt,
+		}).Error
+	if err != nil {
+		return err
+	}
+	client.Secret = secret
+	client.SecondarySecret = secondarySecret
+	client.SecondarySecretExpiresAt = expiresAt
+
+	return nil
+}
+
+// ExpireSecondaryClientSecret stops the secondary secret of the client working
+func (s *Service) ExpireSecondaryClientSecret(client *models.OauthClient) error {
+	if !HasSecondaryClientSecret(client) {
+		return ErrNoSecondaryClientSecret
+	}
+	return s.setSecondaryClientSecret(client, "", nil)
+}
+
+func (s *Service) setSecondaryClientSecret(client *models.OauthClient, secretHash string, expiresAt *time.Time) error {
+	err := s.db.Model(new(models.OauthClient)).Where("id = ?", client.ID).
+		UpdateColumns(map[string]interface{}{
+			"secondary_secret":            secretHash,
+			"secondary_secret_expires_at": expiresAt,
+		}).Error
+	if err != nil {
+		return err
+	}
+	client.SecondarySecret = secretHash
+	client.SecondarySecretExpiresAt = expiresAt
+	return nil
+}
+
+// HasSecondaryClientSecret checks the client has a secondary secret
+// which has not expired yet
+func HasSecondaryClientSecret(client *models.OauthClient) bool {
+	if client.SecondarySecret == "" {
+		return false
+	}
+	return client.SecondarySecretExpiresAt == nil ||
+		time.Now().UTC().Before(*client.SecondarySecretExpiresAt)
+}
+
+// verifyClientSecret checks the secret is either the secret
+// or the unexpired secondary secret of the client
+func verifyClientSecret(client *models.OauthClient, secret string) error {
+	if password.VerifyPassword(client.Secret, secret) == nil {
+		return nil
+	}
+	if HasSecondaryClientSecret(client) && password.VerifyPassword(client.SecondarySecret, secret) == nil {
+		return nil
+	}
+	return ErrInvalidClientSecret
+}
@@ -0,0 +1,49 @@
+package oauth_test
+
+import (
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestSecondaryClientSecret() {
+	client, err := suite.service.CreateClient("test_client_secrets", "test_secret_long_enough_1A!", "")
+	assert.NoError(suite.T(), err)
+
+	// There is nothing to promote or expire yet
+	assert.Equal(suite.T(), oauth.ErrNoSecondaryClientSecret, suite.service.PromoteSecondaryClientSecret(client, 0))
+	assert.Equal(suite.T(), oauth.ErrNoSecondaryClientSecret, suite.service.ExpireSecondaryClientSecret(client))
+
+	// Both secrets work once a secondary one is added
+	secondary, err := suite.service.AddSecondaryClientSecret(client)
+	assert.NoError(suite.T(), err)
+	_, err = suite.service.AuthClient("test_client_secrets", "test_secret_long_enough_1A!")
+	assert.NoError(suite.T(), err)
+	_, err = suite.service.AuthClient("test_client_secrets", secondary)
+	assert.NoError(suite.T(), err)
+
+	// The old secret keeps working during the grace period after promotion
+	assert.NoError(suite.T(), suite.service.PromoteSecondaryClientSecret(client, 3600))
+	_, err = suite.service.AuthClient("test_client_secrets", "test_secret_long_enough_1A!")
+	assert.NoError(suite.T(), err)
+	if assert.NotNil(suite.T(), client.SecondarySecretExpiresAt) {
+		assert.WithinDuration(suite.T(), time.Now().UTC().Add(time.Hour), *client.SecondarySecretExpiresAt, 5*time.Second)
+	}
+
+	// The old secret stops working once the secondary secret expires
+	expiresAt := time.Now().UTC().Add(-time.Second)
+	assert.NoError(suite.T(), suite.db.Model(client).UpdateColumn("secondary_secret_expires_at", expiresAt).Error)
+	_, err = suite.service.AuthClient("test_client_secrets", "test_secret_long_enough_1A!")
+	assert.Equal(suite.T(), oauth.ErrInvalidClientSecret, err)
+	_, err = suite.service.AuthClient("test_client_secrets", secondary)
+	assert.NoError(suite.T(), err)
+
+	// Expiring the secondary secret straight away
+	_, err = suite.service.AddSecondaryClientSecret(client)
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), suite.service.PromoteSecondaryClientSecret(client, 0))
+	_, err = suite.service.AuthClient("test_client_secrets", secondary)
+	assert.Equal(suite.T(), oauth.ErrInvalidClientSecret, err)
+	assert.Equal(suite.T(), oauth.ErrNoSecondaryClientSecret, suite.service.ExpireSecondaryClientSecret(client))
+}
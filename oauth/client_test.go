@@ -111,3 +111,106 @@ func (suite *OauthTestSuite) TestAuthClient() {
 		assert.Equal(suite.T(), "test_client_1", client.Key)
 	}
 }
+
+func (suite *OauthTestSuite) TestCreateClientWeakSecret() {
+	suite.cnf.Oauth.MinClientSecretLength = 32
+	defer func() { suite.cnf.Oauth.MinClientSecretLength = 0 }()
+
+	// We try to insert a client with a too short secret
+	client, err := suite.service.CreateClient(
+		"test_client_weak",        // client ID
+		"test_secret",             // secret
+		"https://www.example.com", // redirect URI
+	)
+
+	// Client object should be nil
+	assert.Nil(suite.T(), client)
+
+	// Correct error should be returned
+	if assert.NotNil(suite.T(), err) {
+		assert.Equal(suite.T(), oauth.ErrClientSecretTooShort, err)
+	}
+
+	// A server generated secret meets the requirement
+	secret, err := oauth.GenerateClientSecret()
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), suite.service.ValidateClientSecret(secret))
+	client, err = suite.service.CreateClient(
+		"test_client_weak",        // client ID
+		secret,                    // secret
+		"https://www.example.com", // redirect URI
+	)
+	assert.NoError(suite.T(), err)
+
+	// And can be used to authenticate
+	_, err = suite.service.AuthClient("test_client_weak", secret)
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *OauthTestSuite) TestListClients() {
+	var total int
+	suite.db.Model(new(models.OauthClient)).Count(&total)
+
+	// All clients fit a page
+	clients, count, err := suite.service.ListClients(0, 0)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), total, count)
+		assert.Len(suite.T(), clients, total)
+	}
+
+	// Pages are limited and offset
+	clients, count, err = suite.service.ListClients(1, 1)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), total, count)
+		assert.Len(suite.T(), clients, 1)
+	}
+}
+
+func (suite *OauthTestSuite) TestSetClientDisabled() {
+	client, secret, err := suite.service.CreateClientWithMetadata("test_client_disabled", &oauth.ClientMetadata{
+		GrantTypes: []string{"client_credentials"},
+	})
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	accessToken, err := suite.service.GrantAccessToken(client, nil, 3600, "read")
+	assert.NoError(suite.T(), err)
+
+	// A disabled client cannot authenticate and its tokens are revoked
+	assert.NoError(suite.T(), suite.service.SetClientDisabled(client, true))
+	_, err = suite.service.AuthClient("test_client_disabled", secret)
+	assert.Equal(suite.T(), oauth.ErrClientNotFound, err)
+	_, err = suite.service.FindClientByClientID("test_client_disabled")
+	assert.Equal(suite.T(), oauth.ErrClientDisabled, err)
+	_, err = suite.service.Authenticate(accessToken.Token)
+	assert.Equal(suite.T(), oauth.ErrAccessTokenNotFound, err)
+
+	// It can still be administered
+	disabled, err := suite.service.GetClient("test_client_disabled")
+	if assert.NoError(suite.T(), err) {
+		assert.True(suite.T(), disabled.Disabled)
+	}
+
+	// Until it is enabled again
+	assert.NoError(suite.T(), suite.service.SetClientDisabled(client, false))
+	_, err = suite.service.AuthClient("test_client_disabled", secret)
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *OauthTestSuite) TestDisabledClientTokens() {
+	client, _, err := suite.service.CreateClientWithMetadata("test_client_suspended", &oauth.ClientMetadata{
+		GrantTypes: []string{"client_credentials"},
+	})
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+
+	// A token issued while the client is being disabled is not revoked
+	assert.NoError(suite.T(), suite.service.SetClientDisabled(client, true))
+	accessToken, err := suite.service.GrantAccessToken(client, nil, 3600, "read")
+	assert.NoError(suite.T(), err)
+
+	// But it cannot be used
+	_, err = suite.service.Authenticate(accessToken.Token)
+	assert.Equal(suite.T(), oauth.ErrClientDisabled, err)
+}
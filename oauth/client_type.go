@@ -0,0 +1,62 @@
+package oauth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+)
+
+const (
+	// ClientTypeConfidential clients keep their credentials secret and
+	// authenticate on every request to the token endpoint
+	ClientTypeConfidential = "confidential"
+	// ClientTypePublic clients cannot keep a secret, such as native and
+	// browser apps, they identify themselves by client ID and must use PKCE.
+	// See https://tools.ietf.org/html/rfc6749#section-2.1
+	ClientTypePublic = "public"
+)
+
+var (
+	// ErrInvalidClientType ...
+	ErrInvalidClientType = errors.New("Invalid client type")
+)
+
+// IsPublicClient returns true for clients which cannot keep a secret
+func IsPublicClient(client *models.OauthClient) bool {
+	return client.ClientType == ClientTypePublic
+}
+
+// SetClientType makes the client either public or confidential
+func (s *Service) SetClientType(client *models.OauthClient, clientType string) error {
+	if clientType != ClientTypeConfidential && clientType != ClientTypePublic {
+		return ErrInvalidClientType
+	}
+
+	err := s.db.Model(new(models.OauthClient)).Where("id = ?", client.ID).
+		UpdateColumn("client_type", clientType).Error
+	if err != nil {
+		return err
+	}
+	client.ClientType = clientType
+	return nil
+}
+
+// clientAuthNone identifies a public client by the client_id parameter,
+// confidential clients have to authenticate with one of the other methods
+func (s *Service) clientAuthNone(r *http.Request) (*models.OauthClient, error) {
+	clientID := r.PostFormValue("client_id")
+	if clientID == "" {
+		return nil, ErrClientCredentialsMissing
+	}
+
+	client, err := s.FindClientByClientID(clientID)
+	if err != nil {
+		return nil, err
+	}
+	if !IsPublicClient(client) {
+		return nil, ErrClientCredentialsMissing
+	}
+
+	return client, nil
+}
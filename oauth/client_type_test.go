@@ -0,0 +1,72 @@
+package oauth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestPublicClient() {
+	assert.Equal(suite.T(), oauth.ErrInvalidClientType, suite.service.SetClientType(suite.clients[1], "bogus"))
+
+	// Confidential clients must authenticate
+	w := suite.publicClientTokenRequest(false, url.Values{
+		"grant_type": {"password"},
+		"username":   {"test@user"},
+		"password":   {"test_password"},
+		"scope":      {"read"},
+	})
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+
+	assert.NoError(suite.T(), suite.service.SetClientType(suite.clients[1], oauth.ClientTypePublic))
+	defer suite.service.SetClientType(suite.clients[1], oauth.ClientTypeConfidential)
+
+	// Public clients identify themselves by client ID only
+	w = suite.publicClientTokenRequest(false, url.Values{
+		"grant_type": {"password"},
+		"username":   {"test@user"},
+		"password":   {"test_password"},
+		"scope":      {"read"},
+	})
+	resp := suite.decodeAccessTokenResponse(w)
+	w = suite.publicClientTokenRequest(false, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {resp.RefreshToken},
+	})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	// They cannot authenticate with a secret
+	w = suite.publicClientTokenRequest(true, url.Values{
+		"grant_type": {"password"},
+		"username":   {"test@user"},
+		"password":   {"test_password"},
+		"scope":      {"read"},
+	})
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+
+	// Nor use the client credentials grant
+	w = suite.publicClientTokenRequest(false, url.Values{
+		"grant_type": {"client_credentials"},
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
+
+// publicClientTokenRequest sends a token request as test_client_2,
+// either with its secret or with its client ID only
+func (suite *OauthTestSuite) publicClientTokenRequest(withSecret bool, form url.Values) *httptest.ResponseRecorder {
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	if withSecret {
+		r.SetBasicAuth("test_client_2", "test_secret")
+	} else {
+		form.Set("client_id", "test_client_2")
+	}
+	r.PostForm = form
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	return w
+}
@@ -0,0 +1,61 @@
+package oauth
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util"
+)
+
+var (
+	// ErrConsentNotFound ...
+	ErrConsentNotFound = errors.New("Consent not found")
+)
+
+// HasConsent returns true if the user has already approved
+// every requested scope for the client
+func (s *Service) HasConsent(client *models.OauthClient, user *models.OauthUser, scope string) bool {
+	consent, err := s.findConsent(client, user)
+	if err != nil {
+		return false
+	}
+
+	granted := strings.Fields(consent.Scope)
+	for _, requested := range strings.Fields(scope) {
+		if !util.StringInSlice(requested, granted) {
+			return false
+		}
+	}
+	return true
+}
+
+// SaveConsent stores the approved scope, adding it to the scope
+// the user has approved for the client before
+func (s *Service) SaveConsent(client *models.OauthClient, user *models.OauthUser, scope string) error {
+	consent, err := s.findConsent(client, user)
+	if err != nil {
+		return s.db.Create(models.NewOauthConsent(client, user, scope)).Error
+	}
+
+	granted := strings.Fields(consent.Scope)
+	for _, requested := range strings.Fields(scope) {
+		if !util.StringInSlice(requested, granted) {
+			granted = append(granted, requested)
+		}
+	}
+	return s.db.Model(consent).UpdateColumn("scope", strings.Join(granted, " ")).Error
+}
+
+// findConsent looks up the consent the user has given to the client
+func (s *Service) findConsent(client *models.OauthClient, user *models.OauthUser) (*models.OauthConsent, error) {
+	consent := new(models.OauthConsent)
+	notFound := s.db.Where(models.OauthConsent{
+		ClientID: util.StringOrNull(string(client.ID)),
+		UserID:   util.StringOrNull(string(user.ID)),
+	}).First(consent).RecordNotFound()
+	if notFound {
+		return nil, ErrConsentNotFound
+	}
+	return consent, nil
+}
@@ -0,0 +1,19 @@
+package oauth_test
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestConsent() {
+	client, user := suite.clients[0], suite.users[1]
+	assert.False(suite.T(), suite.service.HasConsent(client, user, "read"))
+
+	assert.NoError(suite.T(), suite.service.SaveConsent(client, user, "read"))
+	assert.True(suite.T(), suite.service.HasConsent(client, user, "read"))
+	assert.False(suite.T(), suite.service.HasConsent(client, user, "read read_write"))
+	assert.False(suite.T(), suite.service.HasConsent(suite.clients[1], user, "read"))
+
+	// Approving more scope adds to the stored consent
+	assert.NoError(suite.T(), suite.service.SaveConsent(client, user, "read_write"))
+	assert.True(suite.T(), suite.service.HasConsent(client, user, "read read_write"))
+}
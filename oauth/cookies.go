@@ -0,0 +1,63 @@
+package oauth
+
+import (
+	"net/http"
+)
+
+const (
+	// AccessTokenCookie is the name of the cookie holding the access token
+	AccessTokenCookie = "access_token"
+	// RefreshTokenCookie is the name of the cookie holding the refresh token
+	RefreshTokenCookie = "refresh_token"
+	// cookieTokenDelivery is the token_delivery value opting in to cookies
+	cookieTokenDelivery = "cookie"
+)
+
+// wantsTokenCookies returns true if cookies are enabled and the
+// client has opted in to receiving tokens as cookies
+func (s *Service) wantsTokenCookies(r *http.Request) bool {
+	return s.cnf.Oauth.UseCookies && r.Form.Get("token_delivery") == cookieTokenDelivery
+}
+
+// setTokenCookies sets the access and refresh tokens as cookies scripts
+// cannot read and removes them from the response body. The refresh token
+// cookie is only sent back to the token endpoint.
+func (s *Service) setTokenCookies(w http.ResponseWriter, r *http.Request, resp *AccessTokenResponse) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     AccessTokenCookie,
+		Value:    resp.AccessToken,
+		Path:     "/",
+		MaxAge:   resp.ExpiresIn,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	resp.AccessToken = ""
+
+	if resp.RefreshToken == "" {
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     RefreshTokenCookie,
+		Value:    resp.RefreshToken,
+		Path:     r.URL.Path,
+		MaxAge:   s.cnf.Oauth.RefreshTokenLifetime,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	resp.RefreshToken = ""
+}
+
+// getRefreshTokenParam returns the refresh token from the form,
+// or from its cookie if cookies are enabled
+func (s *Service) getRefreshTokenParam(r *http.Request) string {
+	if token := r.Form.Get("refresh_token"); token != "" || !s.cnf.Oauth.UseCookies {
+		return token
+	}
+	cookie, err := r.Cookie(RefreshTokenCookie)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
@@ -0,0 +1,91 @@
+package oauth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestTokenCookies() {
+	suite.cnf.Oauth.UseCookies = true
+	defer func() { suite.cnf.Oauth.UseCookies = false }()
+
+	w := suite.passwordGrantWithTokenDelivery("cookie")
+	assert.Equal(suite.T(), 200, w.Code)
+
+	// The tokens are set as secure cookies
+	cookies := map[string]*http.Cookie{}
+	for _, cookie := range w.Result().Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+	accessTokenCookie, refreshTokenCookie := cookies[oauth.AccessTokenCookie], cookies[oauth.RefreshTokenCookie]
+	if !assert.NotNil(suite.T(), accessTokenCookie) || !assert.NotNil(suite.T(), refreshTokenCookie) {
+		return
+	}
+	for _, cookie := range []*http.Cookie{accessTokenCookie, refreshTokenCookie} {
+		assert.NotEmpty(suite.T(), cookie.Value)
+		assert.True(suite.T(), cookie.Secure)
+		assert.True(suite.T(), cookie.HttpOnly)
+		assert.Equal(suite.T(), http.SameSiteStrictMode, cookie.SameSite)
+	}
+	assert.Equal(suite.T(), "/", accessTokenCookie.Path)
+	assert.Equal(suite.T(), suite.cnf.Oauth.AccessTokenLifetime, accessTokenCookie.MaxAge)
+	assert.Equal(suite.T(), "/v1/oauth/tokens", refreshTokenCookie.Path)
+
+	// And omitted from the body
+	resp := map[string]interface{}{}
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.NotContains(suite.T(), resp, "access_token")
+	assert.NotContains(suite.T(), resp, "refresh_token")
+	assert.Equal(suite.T(), "Bearer", resp["token_type"])
+
+	// The access token is valid
+	_, err := suite.service.Authenticate(accessTokenCookie.Value)
+	assert.NoError(suite.T(), err)
+
+	// The refresh token cookie can be used to refresh
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
+	r.AddCookie(refreshTokenCookie)
+	r.PostForm = url.Values{
+		"grant_type":     {"refresh_token"},
+		"token_delivery": {"cookie"},
+	}
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.NotEmpty(suite.T(), w.Result().Cookies())
+}
+
+func (suite *OauthTestSuite) TestTokenCookiesOptIn() {
+	// Cookies are not set unless enabled
+	w := suite.passwordGrantWithTokenDelivery("cookie")
+	suite.assertTokensInBody(w)
+
+	// Or when enabled but not requested
+	suite.cnf.Oauth.UseCookies = true
+	defer func() { suite.cnf.Oauth.UseCookies = false }()
+	w = suite.passwordGrantWithTokenDelivery("")
+	suite.assertTokensInBody(w)
+}
+
+func (suite *OauthTestSuite) assertTokensInBody(w *httptest.ResponseRecorder) {
+	assert.Empty(suite.T(), w.Result().Cookies())
+	resp := suite.decodeAccessTokenResponse(w)
+	assert.NotEmpty(suite.T(), resp.AccessToken)
+	assert.NotEmpty(suite.T(), resp.RefreshToken)
+}
+
+func (suite *OauthTestSuite) passwordGrantWithTokenDelivery(tokenDelivery string) *httptest.ResponseRecorder {
+	return suite.tokenRequest(url.Values{
+		"grant_type":     {"password"},
+		"username":       {"test@user"},
+		"password":       {"test_password"},
+		"token_delivery": {tokenDelivery},
+	}, nil)
+}
@@ -0,0 +1,230 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+)
+
+const (
+	// DeviceCodeGrantType is the grant type devices poll the token endpoint with
+	DeviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+	// defaultDeviceCodeLifetime is used when the lifetime is not configured
+	defaultDeviceCodeLifetime = 600
+	// defaultDeviceCodeInterval is used when the interval is not configured
+	defaultDeviceCodeInterval = 5
+	// deviceVerificationPath is served by the web service
+	deviceVerificationPath = "/web/device"
+	// userCodeCharset has no vowels or easily confused characters,
+	// see https://tools.ietf.org/html/rfc8628#section-6.1
+	userCodeCharset = "BCDFGHJKLMNPQRSTVWXZ"
+	// userCodeLength is the number of characters in a user code
+	userCodeLength = 8
+)
+
+var (
+	// ErrDeviceCodeNotFound ...
+	ErrDeviceCodeNotFound = errors.New("Device code not found")
+	// ErrDeviceCodeExpired ...
+	ErrDeviceCodeExpired = errors.New("Device code expired")
+	// ErrDeviceCodeDenied ...
+	ErrDeviceCodeDenied = errors.New("Device authorization denied")
+	// ErrAuthorizationPending ...
+	ErrAuthorizationPending = errors.New("Device authorization pending")
+	// ErrDeviceCodeSlowDown ...
+	ErrDeviceCodeSlowDown = errors.New("Device polling too frequently, slow down")
+	// ErrUserCodeNotFound ...
+	ErrUserCodeNotFound = errors.New("User code not found")
+)
+
+// GrantDeviceCode grants a new device code and the user code
+// the user enters on the verification page to approve it
+func (s *Service) GrantDeviceCode(client *models.OauthClient, scope string) (*models.OauthDeviceCode, error) {
+	userCode, err := generateUserCode()
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a new device code
+	deviceCode := models.NewOauthDeviceCode(client, userCode, s.getDeviceCodeLifetime(), scope)
+	if err := s.db.Create(deviceCode).Error; err != nil {
+		return nil, err
+	}
+	deviceCode.Client = client
+
+	return deviceCode, nil
+}
+
+// FindDeviceCodeByUserCode returns a pending device code for the user code
+func (s *Service) FindDeviceCodeByUserCode(userCode string) (*models.OauthDeviceCode, error) {
+	deviceCode := new(models.OauthDeviceCode)
+	notFound := models.OauthDeviceCodePreload(s.db).Where("user_code = ?", normalizeUserCode(userCode)).
+		Where("authorized_at IS NULL AND denied_at IS NULL").First(deviceCode).RecordNotFound()
+
+	// Not found
+	if notFound {
+		return nil, ErrUserCodeNotFound
+	}
+
+	// Check the device code hasn't expired
+	if time.Now().UTC().After(deviceCode.ExpiresAt) {
+		return nil, ErrUserCodeNotFound
+	}
+
+	return deviceCode, nil
+}
+
+// AuthorizeDeviceCode approves the device code on behalf of the user,
+// the device receives tokens for the user the next time it polls
+func (s *Service) AuthorizeDeviceCode(deviceCode *models.OauthDeviceCode, user *models.OauthUser) error {
+	return s.decideDeviceCode(deviceCode, map[string]interface{}{
+		"user_id":       user.ID,
+		"authorized_at": time.Now().UTC(),
+	})
+}
+
+// DenyDeviceCode rejects the device code, the device is told
+// access was denied the next time it polls
+func (s *Service) DenyDeviceCode(deviceCode *models.OauthDeviceCode) error {
+	return s.decideDeviceCode(deviceCode, map[string]interface{}{
+		"denied_at": time.Now().UTC(),
+	})
+}
+
+// decideDeviceCode updates a device code which has not been decided yet
+func (s *Service) decideDeviceCode(deviceCode *models.OauthDeviceCode, columns map[string]interface{}) error {
+	result := s.db.Model(new(models.OauthDeviceCode)).Where("id = ?", deviceCode.ID).
+		Where("authorized_at IS NULL AND denied_at IS NULL").UpdateColumns(columns)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDeviceCodeNotFound
+	}
+	return nil
+}
+
+// deviceAuthorization starts the device flow for the client
+func (s *Service) deviceAuthorization(r *http.Request, client *models.OauthClient) (*DeviceAuthorizationResponse, error) {
+	// Get the scope string
+	scope, err := s.getClientScope(client, r.Form.Get("scope"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Grant the device code
+	deviceCode, err := s.GrantDeviceCode(client, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	verificationURI := strings.TrimSuffix(s.cnf.JWT.Issuer, "/") + deviceVerificationPath
+	return &DeviceAuthorizationResponse{
+		DeviceCode:              deviceCode.DeviceCode,
+		UserCode:                deviceCode.UserCode,
+		VerificationURI:         verificationURI,
+		VerificationURIComplete: verificationURI + "?user_code=" + deviceCode.UserCode,
+		ExpiresIn:               s.getDeviceCodeLifetime(),
+		Interval:                s.getDeviceCodeInterval(),
+	}, nil
+}
+
+// pollDeviceCode returns the device code once the user has approved it,
+// otherwise an error telling the device whether to keep polling
+func (s *Service) pollDeviceCode(code string, client *models.OauthClient) (*models.OauthDeviceCode, error) {
+	// Fetch the device code from the database
+	deviceCode := new(models.OauthDeviceCode)
+	notFound := models.OauthDeviceCodePreload(s.db).Where("client_id = ?", client.ID).
+		Where("device_code = ?", code).First(deviceCode).RecordNotFound()
+
+	// Not found
+	if notFound {
+		return nil, ErrDeviceCodeNotFound
+	}
+
+	// Check the device code hasn't expired
+	now := time.Now().UTC()
+	if now.After(deviceCode.ExpiresAt) {
+		return nil, ErrDeviceCodeExpired
+	}
+
+	// Check the user hasn't denied the request
+	if deviceCode.DeniedAt != nil {
+		return nil, ErrDeviceCodeDenied
+	}
+
+	// Devices polling more often than the interval are told to slow down
+	interval := time.Duration(s.getDeviceCodeInterval()) * time.Second
+	tooSoon := deviceCode.LastPolledAt != nil && now.Before(deviceCode.LastPolledAt.Add(interval))
+	err := s.db.Model(new(models.OauthDeviceCode)).Where("id = ?", deviceCode.ID).
+		UpdateColumn("last_polled_at", now).Error
+	if err != nil {
+		return nil, err
+	}
+	if tooSoon {
+		return nil, ErrDeviceCodeSlowDown
+	}
+
+	// Keep polling until the user has made a decision
+	if deviceCode.AuthorizedAt == nil {
+		return nil, ErrAuthorizationPending
+	}
+
+	return deviceCode, nil
+}
+
+// getDeviceCodeLifetime returns the configured device code lifetime
+func (s *Service) getDeviceCodeLifetime() int {
+	if s.cnf.Oauth.DeviceCodeLifetime <= 0 {
+		return defaultDeviceCodeLifetime
+	}
+	return s.cnf.Oauth.DeviceCodeLifetime
+}
+
+// getDeviceCodeInterval returns the configured polling interval
+func (s *Service) getDeviceCodeInterval() int {
+	if s.cnf.Oauth.DeviceCodeInterval <= 0 {
+		return defaultDeviceCodeInterval
+	}
+	return s.cnf.Oauth.DeviceCodeInterval
+}
+
+// generateUserCode returns a random user code formatted as XXXX-XXXX
+func generateUserCode() (string, error) {
+	code := make([]byte, 0, userCodeLength)
+	b := make([]byte, 1)
+	for len(code) < userCodeLength {
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		// Skip bytes which would bias the modulo towards some characters
+		if int(b[0]) >= 256-256%len(userCodeCharset) {
+			continue
+		}
+		code = append(code, userCodeCharset[int(b[0])%len(userCodeCharset)])
+	}
+	return formatUserCode(string(code)), nil
+}
+
+// normalizeUserCode makes user codes entered in lower case
+// or without the separator match the stored user code
+func normalizeUserCode(userCode string) string {
+	userCode = strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(userCode))
+	if len(userCode) != userCodeLength {
+		return userCode
+	}
+	return formatUserCode(userCode)
+}
+
+func formatUserCode(userCode string) string {
+	return userCode[:userCodeLength/2] + "-" + userCode[userCodeLength/2:]
+}
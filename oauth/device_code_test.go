@@ -0,0 +1,155 @@
+package oauth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/test-util"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestDeviceAuthorization() {
+	resp := suite.deviceAuthorization("read")
+
+	// The device is told where the user should go and how often to poll
+	assert.NotEmpty(suite.T(), resp.DeviceCode)
+	assert.Regexp(suite.T(), "^[B-Z]{4}-[B-Z]{4}$", resp.UserCode)
+	assert.Equal(suite.T(), suite.cnf.JWT.Issuer+"/web/device", resp.VerificationURI)
+	assert.Equal(suite.T(), resp.VerificationURI+"?user_code="+resp.UserCode, resp.VerificationURIComplete)
+	assert.Equal(suite.T(), 600, resp.ExpiresIn)
+	assert.Equal(suite.T(), 5, resp.Interval)
+
+	// The user code can be entered in lower case and without the separator
+	deviceCode, err := suite.service.FindDeviceCodeByUserCode(
+		strings.ToLower(strings.Replace(resp.UserCode, "-", "", 1)),
+	)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), resp.DeviceCode, deviceCode.DeviceCode)
+		assert.Equal(suite.T(), "read", deviceCode.Scope)
+		assert.Equal(suite.T(), suite.clients[0].Key, deviceCode.Client.Key)
+	}
+
+	_, err = suite.service.FindDeviceCodeByUserCode("BCDF-GHJK")
+	assert.Equal(suite.T(), oauth.ErrUserCodeNotFound, err)
+}
+
+func (suite *OauthTestSuite) TestDeviceAuthorizationDisabled() {
+	suite.cnf.Oauth.DisabledGrantTypes = []string{oauth.DeviceCodeGrantType}
+	defer func() { suite.cnf.Oauth.DisabledGrantTypes = nil }()
+
+	w := suite.postDeviceAuthorization("read")
+	testutil.TestResponseForError(
+		suite.T(),
+		w,
+		oauth.ErrInvalidGrantType.Error(),
+		400,
+	)
+}
+
+func (suite *OauthTestSuite) TestDeviceCodeGrant() {
+	resp := suite.deviceAuthorization("read")
+
+	// The device keeps polling until the user has made a decision
+	suite.assertErrorCode(suite.pollDeviceCode(resp.DeviceCode), "authorization_pending")
+
+	// Polling again straight away is too fast
+	suite.assertErrorCode(suite.pollDeviceCode(resp.DeviceCode), "slow_down")
+
+	// The user approves the device
+	deviceCode, err := suite.service.FindDeviceCodeByUserCode(resp.UserCode)
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), suite.service.AuthorizeDeviceCode(deviceCode, suite.users[1]))
+
+	// An approved code cannot be decided again
+	_, err = suite.service.FindDeviceCodeByUserCode(resp.UserCode)
+	assert.Equal(suite.T(), oauth.ErrUserCodeNotFound, err)
+	assert.Equal(suite.T(), oauth.ErrDeviceCodeNotFound, suite.service.DenyDeviceCode(deviceCode))
+
+	// The device gets tokens on its next poll after the interval
+	suite.waitDeviceCodeInterval(resp.DeviceCode)
+	accessTokenResponse := suite.decodeAccessTokenResponse(suite.pollDeviceCode(resp.DeviceCode))
+	assert.Equal(suite.T(), "read", accessTokenResponse.Scope)
+	assert.Equal(suite.T(), string(suite.users[1].ID), accessTokenResponse.UserID)
+	assert.NotEmpty(suite.T(), accessTokenResponse.RefreshToken)
+	assert.Equal(
+		suite.T(),
+		oauth.DeviceCodeGrantType,
+		suite.introspectRefreshToken(accessTokenResponse.RefreshToken).GrantType,
+	)
+
+	// The device code can only be exchanged once
+	testutil.TestResponseForOauthError(
+		suite.T(),
+		suite.pollDeviceCode(resp.DeviceCode),
+		"invalid_grant",
+		oauth.ErrDeviceCodeNotFound.Error(),
+		400,
+	)
+}
+
+func (suite *OauthTestSuite) TestDeviceCodeGrantDenied() {
+	resp := suite.deviceAuthorization("read")
+
+	deviceCode, err := suite.service.FindDeviceCodeByUserCode(resp.UserCode)
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), suite.service.DenyDeviceCode(deviceCode))
+
+	suite.assertErrorCode(suite.pollDeviceCode(resp.DeviceCode), "access_denied")
+}
+
+func (suite *OauthTestSuite) TestDeviceCodeGrantExpired() {
+	resp := suite.deviceAuthorization("read")
+	err := suite.db.Model(new(models.OauthDeviceCode)).Where("device_code = ?", resp.DeviceCode).
+		UpdateColumn("expires_at", time.Now().UTC().Add(-10*time.Second)).Error
+	assert.NoError(suite.T(), err)
+
+	suite.assertErrorCode(suite.pollDeviceCode(resp.DeviceCode), "expired_token")
+	_, err = suite.service.FindDeviceCodeByUserCode(resp.UserCode)
+	assert.Equal(suite.T(), oauth.ErrUserCodeNotFound, err)
+}
+
+func (suite *OauthTestSuite) postDeviceAuthorization(scope string) *httptest.ResponseRecorder {
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/device_authorization", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
+	r.PostForm = url.Values{"scope": {scope}}
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	return w
+}
+
+func (suite *OauthTestSuite) deviceAuthorization(scope string) *oauth.DeviceAuthorizationResponse {
+	w := suite.postDeviceAuthorization(scope)
+	assert.Equal(suite.T(), 200, w.Code)
+	resp := new(oauth.DeviceAuthorizationResponse)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), resp))
+	return resp
+}
+
+func (suite *OauthTestSuite) pollDeviceCode(deviceCode string) *httptest.ResponseRecorder {
+	return suite.tokenRequest(url.Values{
+		"grant_type":  {oauth.DeviceCodeGrantType},
+		"device_code": {deviceCode},
+	}, nil)
+}
+
+// waitDeviceCodeInterval moves the last poll back so the next poll is not too fast
+func (suite *OauthTestSuite) waitDeviceCodeInterval(deviceCode string) {
+	err := suite.db.Model(new(models.OauthDeviceCode)).Where("device_code = ?", deviceCode).
+		UpdateColumn("last_polled_at", time.Now().UTC().Add(-time.Minute)).Error
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *OauthTestSuite) assertErrorCode(w *httptest.ResponseRecorder, code string) {
+	assert.Equal(suite.T(), 400, w.Code)
+	resp := map[string]string{}
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(suite.T(), code, resp["error"])
+}
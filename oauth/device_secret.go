@@ -0,0 +1,94 @@
+package oauth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+)
+
+var (
+	// ErrDeviceSecretNotFound ...
+	ErrDeviceSecretNotFound = errors.New("Device secret not found")
+	// ErrDeviceSecretExpired ...
+	ErrDeviceSecretExpired = errors.New("Device secret expired")
+)
+
+// GrantDeviceSecret grants a new device secret
+func (s *Service) GrantDeviceSecret(client *models.OauthClient, user *models.OauthUser, scope string) (*models.OauthDeviceSecret, error) {
+	// Create a new device secret
+	deviceSecret := models.NewOauthDeviceSecret(client, user, s.getDeviceSecretLifetime(), scope)
+	if err := s.db.Create(deviceSecret).Error; err != nil {
+		return nil, err
+	}
+	deviceSecret.Client = client
+	deviceSecret.User = user
+
+	return deviceSecret, nil
+}
+
+// getValidDeviceSecret returns a valid non expired device secret
+func (s *Service) getValidDeviceSecret(secret string, client *models.OauthClient) (*models.OauthDeviceSecret, error) {
+	// Fetch the device secret from the database
+	deviceSecret := new(models.OauthDeviceSecret)
+	notFound := models.OauthDeviceSecretPreload(s.db).Where("client_id = ?", client.ID).
+		Where("secret = ?", models.HashToken(secret)).First(deviceSecret).RecordNotFound()
+
+	// Not found
+	if notFound {
+		return nil, ErrDeviceSecretNotFound
+	}
+
+	// Check the device secret hasn't expired
+	if time.Now().UTC().After(deviceSecret.ExpiresAt) {
+		return nil, ErrDeviceSecretExpired
+	}
+
+	return deviceSecret, nil
+}
+
+// rotateDeviceSecret replaces a used device secret with a new one expiring
+// at the same time, a secret used concurrently is only rotated once
+func (s *Service) rotateDeviceSecret(deviceSecret *models.OauthDeviceSecret) (*models.OauthDeviceSecret, error) {
+	result := s.db.Unscoped().Delete(deviceSecret)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrDeviceSecretNotFound
+	}
+
+	rotated := models.NewOauthDeviceSecret(deviceSecret.Client, deviceSecret.User, 0, deviceSecret.Scope)
+	rotated.ExpiresAt = deviceSecret.ExpiresAt
+	if err := s.db.Create(rotated).Error; err != nil {
+		return nil, err
+	}
+	rotated.Client = deviceSecret.Client
+	rotated.User = deviceSecret.User
+
+	return rotated, nil
+}
+
+// addDeviceSecret includes a device secret in the response, but only if
+// enabled and the tokens have been granted to a user
+func (s *Service) addDeviceSecret(response *AccessTokenResponse, client *models.OauthClient, user *models.OauthUser, scope string) error {
+	if !s.cnf.Oauth.EnableDeviceSecret || user == nil {
+		return nil
+	}
+
+	deviceSecret, err := s.GrantDeviceSecret(client, user, scope)
+	if err != nil {
+		return err
+	}
+	response.DeviceSecret = deviceSecret.Secret
+
+	return nil
+}
+
+// getDeviceSecretLifetime returns the configured device secret lifetime
+func (s *Service) getDeviceSecretLifetime() int {
+	if s.cnf.Oauth.DeviceSecretLifetime <= 0 {
+		return s.cnf.Oauth.RefreshTokenLifetime
+	}
+	return s.cnf.Oauth.DeviceSecretLifetime
+}
@@ -0,0 +1,80 @@
+package oauth_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/test-util"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestDeviceSecret() {
+	suite.cnf.Oauth.EnableDeviceSecret = true
+	defer func() { suite.cnf.Oauth.EnableDeviceSecret = false }()
+
+	// A device secret is issued alongside the tokens
+	w := suite.passwordGrantWithAcr("test@user", "", "")
+	assert.Equal(suite.T(), 200, w.Code)
+	resp := new(oauth.AccessTokenResponse)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), resp))
+	assert.NotEmpty(suite.T(), resp.DeviceSecret)
+
+	// It can be exchanged for new tokens
+	w = suite.exchangeDeviceSecret("test_client_1", resp.DeviceSecret)
+	assert.Equal(suite.T(), 200, w.Code)
+	exchanged := new(oauth.AccessTokenResponse)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), exchanged))
+	assert.Equal(suite.T(), suite.users[1].ID, exchanged.UserID)
+	assert.Equal(suite.T(), resp.Scope, exchanged.Scope)
+	assert.NotEmpty(suite.T(), exchanged.AccessToken)
+	assert.NotEqual(suite.T(), resp.AccessToken, exchanged.AccessToken)
+
+	// The secret is rotated, only its hash is stored
+	assert.NotEmpty(suite.T(), exchanged.DeviceSecret)
+	assert.NotEqual(suite.T(), resp.DeviceSecret, exchanged.DeviceSecret)
+	w = suite.exchangeDeviceSecret("test_client_1", resp.DeviceSecret)
+	testutil.TestResponseForOauthError(suite.T(), w, "invalid_grant", oauth.ErrDeviceSecretNotFound.Error(), 400)
+	deviceSecret := new(models.OauthDeviceSecret)
+	err := suite.db.Where("secret = ?", models.HashToken(exchanged.DeviceSecret)).First(deviceSecret).Error
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), "", deviceSecret.Secret)
+	}
+
+	// But only by the client it was issued to
+	w = suite.exchangeDeviceSecret("test_client_2", exchanged.DeviceSecret)
+	testutil.TestResponseForOauthError(suite.T(), w, "invalid_grant", oauth.ErrDeviceSecretNotFound.Error(), 400)
+
+	// And not once expired
+	err = suite.db.Model(new(models.OauthDeviceSecret)).Where("secret = ?", models.HashToken(exchanged.DeviceSecret)).
+		UpdateColumn("expires_at", time.Now().UTC().Add(-time.Second)).Error
+	assert.NoError(suite.T(), err)
+	w = suite.exchangeDeviceSecret("test_client_1", exchanged.DeviceSecret)
+	testutil.TestResponseForOauthError(suite.T(), w, "invalid_grant", oauth.ErrDeviceSecretExpired.Error(), 400)
+}
+
+func (suite *OauthTestSuite) TestDeviceSecretDisabled() {
+	deviceSecret, err := suite.service.GrantDeviceSecret(suite.clients[0], suite.users[1], "read")
+	assert.NoError(suite.T(), err)
+
+	// No device secret is issued
+	w := suite.passwordGrantWithAcr("test@user", "", "")
+	assert.Equal(suite.T(), 200, w.Code)
+	resp := new(oauth.AccessTokenResponse)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), resp))
+	assert.Empty(suite.T(), resp.DeviceSecret)
+
+	// And existing ones cannot be exchanged
+	w = suite.exchangeDeviceSecret("test_client_1", deviceSecret.Secret)
+	testutil.TestResponseForOauthError(suite.T(), w, "unsupported_grant_type", oauth.ErrInvalidGrantType.Error(), 400)
+}
+
+func (suite *OauthTestSuite) exchangeDeviceSecret(clientID, deviceSecret string) *httptest.ResponseRecorder {
+	return suite.tokenRequest(url.Values{
+		"grant_type":    {"device_secret"},
+		"device_secret": {deviceSecret},
+	}, clientAuth(clientID))
+}
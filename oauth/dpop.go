@@ -0,0 +1,196 @@
+package oauth
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth/tokentypes"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/RichardKnop/go-oauth2-server/util/jwt"
+	"github.com/gorilla/context"
+)
+
+const (
+	// dpopThumbprintKey stores the thumbprint of the DPoP key of a token request
+	dpopThumbprintKey contextKey = 1
+	// dpopProofType is the typ header of DPoP proofs
+	dpopProofType = "dpop+jwt"
+	// dpopProofMaxAge is how long after being issued a DPoP proof is accepted,
+	// proofs are not remembered so this bounds the window to replay them
+	dpopProofMaxAge = 60 * time.Second
+)
+
+var (
+	// ErrInvalidDPoPProof ...
+	ErrInvalidDPoPProof = errors.New("Invalid DPoP proof")
+	// ErrDPoPKeyMismatch ...
+	ErrDPoPKeyMismatch = errors.New("Token is bound to another DPoP key")
+
+	// dpopSigningAlgorithms are the algorithms DPoP proofs can be signed with,
+	// proofs are signed with a private key so shared secrets are refused
+	dpopSigningAlgorithms = []string{jwt.RS256, jwt.RS384, jwt.RS512, jwt.ES256}
+)
+
+// checkDPoPProof validates the DPoP proof of a token request, if any, and
+// keeps the thumbprint of its key for binding the tokens to it
+func (s *Service) checkDPoPProof(r *http.Request) error {
+	if len(r.Header[http.CanonicalHeaderKey("DPoP")]) == 0 {
+		return nil
+	}
+	thumbprint, err := validateDPoPProof(r, s.cnf.JWT.Issuer, "")
+	if err != nil {
+		return err
+	}
+	context.Set(r, dpopThumbprintKey, thumbprint)
+	return nil
+}
+
+// getDPoPThumbprint returns the thumbprint of the DPoP key of a token
+// request, empty if the client did not send a proof
+func getDPoPThumbprint(r *http.Request) string {
+	thumbprint, _ := context.Get(r, dpopThumbprintKey).(string)
+	return thumbprint
+}
+
+// bindDPoPAccessTokenResponse binds the tokens of the response to the DPoP
+// key of the request, refresh tokens are bound too so a stolen refresh
+// token cannot be used without the private key, see RFC 9449 section 5
+func (s *Service) bindDPoPAccessTokenResponse(r *http.Request, resp *AccessTokenResponse) error {
+	thumbprint := getDPoPThumbprint(r)
+	if thumbprint == "" {
+		return nil
+	}
+
+	err := s.db.Model(new(models.OauthAccessToken)).Where("token = ?", models.HashToken(resp.AccessToken)).
+		UpdateColumn("jwk_thumbprint", thumbprint).Error
+	if err != nil {
+		return err
+	}
+	if resp.RefreshToken != "" {
+		err := s.db.Model(new(models.OauthRefreshToken)).Where("token = ?", models.HashToken(resp.RefreshToken)).
+			UpdateColumn("jwk_thumbprint", thumbprint).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	resp.TokenType = tokentypes.DPoP
+	return nil
+}
+
+// checkRefreshTokenDPoPBinding makes sure a bound refresh token is
+// presented with a proof signed with the same key
+func checkRefreshTokenDPoPBinding(r *http.Request, refreshToken *models.OauthRefreshToken) error {
+	if refreshToken.JWKThumbprint != "" && refreshToken.JWKThumbprint != getDPoPThumbprint(r) {
+		return ErrDPoPKeyMismatch
+	}
+	return nil
+}
+
+// parseDPoPToken returns the access token of a DPoP authorization header
+func parseDPoPToken(r *http.Request) (string, bool) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "DPoP ") {
+		return "", false
+	}
+	return strings.TrimPrefix(auth, "DPoP "), true
+}
+
+// checkDPoPBinding makes sure a DPoP bound access token is presented with the
+// DPoP authorization scheme along with a proof signed with the bound key,
+// see RFC 9449 section 7
+func checkDPoPBinding(r *http.Request, issuer, token string, accessToken *models.OauthAccessToken) error {
+	_, dpop := parseDPoPToken(r)
+	if accessToken.JWKThumbprint == "" && !dpop {
+		return nil
+	}
+
+	thumbprint, err := validateDPoPProof(r, issuer, token)
+	if err != nil {
+		return err
+	}
+	if thumbprint != accessToken.JWKThumbprint {
+		return ErrDPoPKeyMismatch
+	}
+	return nil
+}
+
+// validateDPoPProof validates the DPoP proof of the request as per RFC 9449
+// section 4.3 and returns the thumbprint of the key it was signed with,
+// proofs sent to resource servers must also be bound to the access token
+func validateDPoPProof(r *http.Request, issuer, accessToken string) (string, error) {
+	proofs := r.Header[http.CanonicalHeaderKey("DPoP")]
+	if len(proofs) != 1 {
+		return "", ErrInvalidDPoPProof
+	}
+
+	header, err := jwt.ParseHeader(proofs[0])
+	if err != nil || header.Type != dpopProofType || header.JSONWebKey == nil {
+		return "", ErrInvalidDPoPProof
+	}
+	if !util.StringInSlice(header.Algorithm, dpopSigningAlgorithms) {
+		return "", ErrInvalidDPoPProof
+	}
+	verifier, err := header.JSONWebKey.Verifier(header.Algorithm)
+	if err != nil {
+		return "", ErrInvalidDPoPProof
+	}
+	claims, err := jwt.Parse(proofs[0], verifier)
+	if err != nil {
+		return "", ErrInvalidDPoPProof
+	}
+
+	// The proof must be fresh and made for this very request
+	jti, _ := claims.String("jti")
+	htm, _ := claims.String("htm")
+	htu, _ := claims.String("htu")
+	iat, ok := claims.Int64("iat")
+	if jti == "" || htm != r.Method || !dpopTargetMatches(r, issuer, htu) || !ok {
+		return "", ErrInvalidDPoPProof
+	}
+	issuedAt := time.Unix(iat, 0)
+	if time.Since(issuedAt) > dpopProofMaxAge || time.Until(issuedAt) > dpopProofMaxAge {
+		return "", ErrInvalidDPoPProof
+	}
+
+	// Proofs presented with an access token carry a hash of it
+	if accessToken != "" {
+		ath, _ := claims.String("ath")
+		if ath != dpopAccessTokenHash(accessToken) {
+			return "", ErrInvalidDPoPProof
+		}
+	}
+
+	thumbprint, err := header.JSONWebKey.Thumbprint()
+	if err != nil {
+		return "", ErrInvalidDPoPProof
+	}
+	return thumbprint, nil
+}
+
+// dpopTargetMatches returns true if the htu claim of a proof names the
+// requested path on either the requested host or the host of the issuer,
+// the query and fragment are ignored
+func dpopTargetMatches(r *http.Request, issuer, htu string) bool {
+	target, err := url.Parse(htu)
+	if err != nil || target.Path != r.URL.Path {
+		return false
+	}
+	if target.Host == r.Host {
+		return true
+	}
+	issuerURL, err := url.Parse(issuer)
+	return err == nil && target.Host == issuerURL.Host
+}
+
+// dpopAccessTokenHash returns the ath claim of proofs presented with the token
+func dpopAccessTokenHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,173 @@
+package oauth_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/tokentypes"
+	"github.com/RichardKnop/go-oauth2-server/util/jwt"
+	"github.com/RichardKnop/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testResourceURL = "http://1.2.3.4/resource"
+)
+
+func (suite *OauthTestSuite) TestDPoPBoundAccessToken() {
+	key := suite.newDPoPKey()
+	w := suite.passwordGrantWithDPoP(suite.dpopProof(key, jwt.Claims{"htm": "POST", "htu": testTokenURL}))
+	resp := suite.decodeAccessTokenResponse(w)
+	assert.Equal(suite.T(), tokentypes.DPoP, resp.TokenType)
+
+	// Introspection tells resource servers which key the token is bound to
+	introspectResponse := suite.introspectAccessToken(resp.AccessToken)
+	assert.Equal(suite.T(), tokentypes.DPoP, introspectResponse.TokenType)
+	if assert.NotNil(suite.T(), introspectResponse.Cnf) {
+		assert.Equal(suite.T(), key.KeyID(), introspectResponse.Cnf.JWKThumbprint)
+	}
+
+	// The token is only accepted along with a proof signed with the key
+	ath := dpopAccessTokenHash(resp.AccessToken)
+	for _, testCase := range []struct {
+		scheme   string
+		proof    string
+		accepted bool
+	}{
+		{"DPoP", suite.dpopProof(key, jwt.Claims{"htm": "GET", "htu": testResourceURL, "ath": ath}), true},
+		{"Bearer", "", false},
+		{"DPoP", "", false},
+		{"DPoP", suite.dpopProof(key, jwt.Claims{"htm": "GET", "htu": testResourceURL}), false},
+		{"DPoP", suite.dpopProof(key, jwt.Claims{"htm": "POST", "htu": testResourceURL, "ath": ath}), false},
+		{"DPoP", suite.dpopProof(suite.newDPoPKey(), jwt.Claims{"htm": "GET", "htu": testResourceURL, "ath": ath}), false},
+	} {
+		w := suite.requestWithDPoPToken(testCase.scheme, resp.AccessToken, testCase.proof)
+		if testCase.accepted {
+			assert.Equal(suite.T(), 200, w.Code)
+		} else {
+			assert.Equal(suite.T(), 401, w.Code)
+		}
+	}
+}
+
+func (suite *OauthTestSuite) TestDPoPBoundRefreshToken() {
+	key := suite.newDPoPKey()
+	w := suite.passwordGrantWithDPoP(suite.dpopProof(key, jwt.Claims{"htm": "POST", "htu": testTokenURL}))
+	resp := suite.decodeAccessTokenResponse(w)
+
+	refresh := func(proof string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest("POST", testTokenURL, nil)
+		assert.NoError(suite.T(), err, "Request setup should not get an error")
+		r.SetBasicAuth("test_client_1", "test_secret")
+		r.PostForm = url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {resp.RefreshToken},
+		}
+		if proof != "" {
+			r.Header.Set("DPoP", proof)
+		}
+
+		w := httptest.NewRecorder()
+		suite.router.ServeHTTP(w, r)
+		return w
+	}
+
+	// A stolen refresh token is useless without the private key
+	suite.assertErrorCode(refresh(""), "invalid_grant")
+	other := suite.newDPoPKey()
+	suite.assertErrorCode(refresh(suite.dpopProof(other, jwt.Claims{"htm": "POST", "htu": testTokenURL})), "invalid_grant")
+
+	// The new tokens are bound to the same key
+	w = refresh(suite.dpopProof(key, jwt.Claims{"htm": "POST", "htu": testTokenURL}))
+	refreshed := suite.decodeAccessTokenResponse(w)
+	assert.Equal(suite.T(), tokentypes.DPoP, refreshed.TokenType)
+	assert.Equal(suite.T(), key.KeyID(), suite.introspectAccessToken(refreshed.AccessToken).Cnf.JWKThumbprint)
+}
+
+func (suite *OauthTestSuite) TestDPoPInvalidProof() {
+	key := suite.newDPoPKey()
+	secret, err := jwt.NewHS256("", []byte("test_secret"))
+	assert.NoError(suite.T(), err)
+	jwk, err := jwt.NewJSONWebKey(key)
+	assert.NoError(suite.T(), err)
+	signedWithSecret, err := jwt.SignWithHeader(
+		&jwt.Header{Type: "dpop+jwt", JSONWebKey: jwk},
+		jwt.Claims{"jti": uuid.New(), "htm": "POST", "htu": testTokenURL, "iat": time.Now().Unix()},
+		secret,
+	)
+	assert.NoError(suite.T(), err)
+
+	for _, proof := range []string{
+		"bogus",
+		signedWithSecret,
+		suite.dpopProof(key, jwt.Claims{"htm": "GET", "htu": testTokenURL}),
+		suite.dpopProof(key, jwt.Claims{"htm": "POST", "htu": "http://1.2.3.4/v1/oauth/introspect"}),
+		suite.dpopProof(key, jwt.Claims{"htm": "POST", "htu": testTokenURL, "iat": time.Now().Add(-time.Hour).Unix()}),
+		suite.dpopProof(key, jwt.Claims{"htm": "POST", "htu": testTokenURL, "jti": ""}),
+	} {
+		suite.assertErrorCode(suite.passwordGrantWithDPoP(proof), "invalid_dpop_proof")
+	}
+}
+
+func (suite *OauthTestSuite) TestDPoPMetadata() {
+	assert.Contains(suite.T(), suite.getMetadata().DPoPSigningAlgValuesSupported, jwt.ES256)
+	assert.NotContains(suite.T(), suite.getMetadata().DPoPSigningAlgValuesSupported, jwt.HS256)
+}
+
+func (suite *OauthTestSuite) newDPoPKey() jwt.Key {
+	privateKey, err := jwt.GenerateKey(jwt.ES256)
+	assert.NoError(suite.T(), err)
+	key, err := jwt.NewKey(jwt.ES256, "", nil, privateKey)
+	assert.NoError(suite.T(), err)
+	return key
+}
+
+// dpopProof returns a proof signed with the key, the jti and iat
+// claims are filled in unless given
+func (suite *OauthTestSuite) dpopProof(key jwt.Key, claims jwt.Claims) string {
+	if _, ok := claims["jti"]; !ok {
+		claims["jti"] = uuid.New()
+	}
+	if _, ok := claims["iat"]; !ok {
+		claims["iat"] = time.Now().Unix()
+	}
+	jwk, err := jwt.NewJSONWebKey(key)
+	assert.NoError(suite.T(), err)
+	proof, err := jwt.SignWithHeader(&jwt.Header{Type: "dpop+jwt", JSONWebKey: jwk}, claims, key)
+	assert.NoError(suite.T(), err)
+	return proof
+}
+
+func (suite *OauthTestSuite) passwordGrantWithDPoP(proof string) *httptest.ResponseRecorder {
+	return suite.tokenRequest(url.Values{
+		"grant_type": {"password"},
+		"username":   {"test@user"},
+		"password":   {"test_password"},
+		"scope":      {"read_write"},
+	}, http.Header{"DPoP": {proof}})
+}
+
+func (suite *OauthTestSuite) requestWithDPoPToken(scheme, token, proof string) *httptest.ResponseRecorder {
+	r, err := http.NewRequest("GET", testResourceURL, nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.Header.Set("Authorization", scheme+" "+token)
+	if proof != "" {
+		r.Header.Set("DPoP", proof)
+	}
+
+	w := httptest.NewRecorder()
+	oauth.NewAuthenticationMiddleware(suite.service).ServeHTTP(w, r, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	})
+	return w
+}
+
+func dpopAccessTokenHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
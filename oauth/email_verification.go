@@ -0,0 +1,134 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/log"
+	"github.com/RichardKnop/go-oauth2-server/models"
+)
+
+const (
+	// defaultEmailVerificationLifetime is used when the lifetime is not configured
+	defaultEmailVerificationLifetime = 86400
+	// emailVerificationSubject is the subject of email verification emails
+	emailVerificationSubject = "Verify your email address"
+)
+
+var (
+	// ErrEmailVerificationDisabled ...
+	ErrEmailVerificationDisabled = errors.New("Email verification is disabled")
+	// ErrInvalidEmailVerificationToken ...
+	ErrInvalidEmailVerificationToken = errors.New("Invalid or expired email verification token")
+	// ErrEmailNotVerified ...
+	ErrEmailNotVerified = errors.New("Email address not verified")
+)
+
+// EmailVerificationRequest asks for a verification email to be sent to the user
+type EmailVerificationRequest struct {
+	Username string `json:"username"`
+}
+
+// EmailVerificationConfirmRequest confirms the email address with the emailed token
+type EmailVerificationConfirmRequest struct {
+	Token string `json:"token"`
+}
+
+// SendEmailVerification emails the user a single-use token to confirm their
+// email address with, users who are already verified are not sent one
+func (s *Service) SendEmailVerification(ctx context.Context, user *models.OauthUser) error {
+	if s.mailer == nil {
+		return ErrEmailVerificationDisabled
+	}
+	if user.EmailVerified {
+		return nil
+	}
+
+	token, err := newEmailedToken()
+	if err != nil {
+		return err
+	}
+	emailVerification := models.NewOauthEmailVerification(user, models.HashToken(token), s.getEmailVerificationLifetime())
+	if err := s.db.Create(emailVerification).Error; err != nil {
+		return err
+	}
+
+	body := emailedTokenBody(s.cnf.Oauth.EmailVerificationURL, token, "verify your email address")
+	if err := s.mailer.SendMail(ctx, user.Username, emailVerificationSubject, body); err != nil {
+		log.ERROR.Printf("Sending email verification email failed: %s", err)
+		return err
+	}
+	return nil
+}
+
+// RequestEmailVerification emails a verification token to the user with the
+// username. Nothing is sent if there is no such user, but no error is
+// returned either so the response does not tell whether the user exists.
+func (s *Service) RequestEmailVerification(ctx context.Context, username string) error {
+	if s.mailer == nil {
+		return ErrEmailVerificationDisabled
+	}
+
+	user, err := s.FindUserByUsername(username)
+	if err == ErrUserNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.SendEmailVerification(ctx, user)
+}
+
+// VerifyEmail marks the email address of the user the token was issued to
+// as verified, the token and any other sent to the user stop working
+func (s *Service) VerifyEmail(token string) error {
+	emailVerification := new(models.OauthEmailVerification)
+	notFound := s.db.Where("token_hash = ?", models.HashToken(token)).
+		First(emailVerification).RecordNotFound()
+	if notFound || time.Now().UTC().After(emailVerification.ExpiresAt) {
+		return ErrInvalidEmailVerificationToken
+	}
+
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	err := tx.Unscoped().Where("user_id = ?", emailVerification.UserID.String).
+		Delete(new(models.OauthEmailVerification)).Error
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	err = tx.Model(new(models.OauthUser)).Where("id = ?", emailVerification.UserID.String).
+		UpdateColumn("email_verified", true).Error
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	return nil
+}
+
+// checkEmailVerified refuses users who have not verified their email
+// address if verification is required
+func (s *Service) checkEmailVerified(user *models.OauthUser) error {
+	if s.cnf.Oauth.RequireEmailVerification && !user.EmailVerified {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
+// getEmailVerificationLifetime returns the configured email verification token lifetime
+func (s *Service) getEmailVerificationLifetime() int {
+	if s.cnf.Oauth.EmailVerificationLifetime <= 0 {
+		return defaultEmailVerificationLifetime
+	}
+	return s.cnf.Oauth.EmailVerificationLifetime
+}
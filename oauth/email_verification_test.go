@@ -0,0 +1,76 @@
+package oauth_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/test-util"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestEmailVerification() {
+	suite.cnf.Oauth.EnableUserRegistration = true
+	suite.cnf.Oauth.RequireEmailVerification = true
+	suite.cnf.Oauth.EmailVerificationURL = "https://app.example.com/verify"
+	defer func() {
+		suite.cnf.Oauth.EnableUserRegistration = false
+		suite.cnf.Oauth.RequireEmailVerification = false
+		suite.cnf.Oauth.EmailVerificationURL = ""
+	}()
+	mailer := new(testMailer)
+	suite.service.SetMailer(mailer)
+	defer suite.service.SetMailer(nil)
+
+	// New users are sent a verification email
+	w := suite.registerUser(`{"username": "test@user_unverified", "password": "test_password"}`)
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+	if !assert.Len(suite.T(), mailer.sent, 1) {
+		return
+	}
+	assert.Equal(suite.T(), "test@user_unverified", mailer.sent[0].to)
+	link := mailer.sent[0].body[strings.Index(mailer.sent[0].body, "https://"):]
+	assert.True(suite.T(), strings.HasPrefix(link, "https://app.example.com/verify?token="))
+	token := strings.TrimPrefix(link, "https://app.example.com/verify?token=")
+
+	// They cannot use the password grant until they verify their email
+	w = suite.passwordGrantForThrottling("test@user_unverified")
+	testutil.TestResponseForOauthError(suite.T(), w, "invalid_grant", oauth.ErrEmailNotVerified.Error(), 400)
+
+	// A new email can be requested
+	w = suite.emailVerification("", `{"username": "test@user_unverified"}`)
+	assert.Equal(suite.T(), http.StatusNoContent, w.Code)
+	assert.Len(suite.T(), mailer.sent, 2)
+
+	// The token verifies the email address once
+	w = suite.emailVerification("/confirm", `{"token": "bogus"}`)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	w = suite.emailVerification("/confirm", `{"token": "`+token+`"}`)
+	assert.Equal(suite.T(), http.StatusNoContent, w.Code)
+	w = suite.emailVerification("/confirm", `{"token": "`+token+`"}`)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	user, err := suite.service.FindUserByUsername("test@user_unverified")
+	if assert.NoError(suite.T(), err) {
+		assert.True(suite.T(), user.EmailVerified)
+	}
+	w = suite.passwordGrantForThrottling("test@user_unverified")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	// Verified users are not sent another email
+	w = suite.emailVerification("", `{"username": "test@user_unverified"}`)
+	assert.Equal(suite.T(), http.StatusNoContent, w.Code)
+	assert.Len(suite.T(), mailer.sent, 2)
+}
+
+func (suite *OauthTestSuite) emailVerification(path, body string) *httptest.ResponseRecorder {
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/email-verification"+path, bytes.NewBufferString(body))
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.Header.Set("Content-Type", "application/json")
+	r.SetBasicAuth("test_client_1", "test_secret")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	return w
+}
@@ -1,13 +1,29 @@
 package oauth
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+
+	"github.com/RichardKnop/go-oauth2-server/database"
+	pass "github.com/RichardKnop/go-oauth2-server/util/password"
+	"github.com/RichardKnop/go-oauth2-server/util/response"
+)
+
+const (
+	// readOnlyRetryAfter is the number of seconds clients are asked
+	// to wait before retrying a write the read-only database refused
+	readOnlyRetryAfter = 30
 )
 
 var (
+	// ErrTemporarilyUnavailable ...
+	ErrTemporarilyUnavailable = errors.New("temporarily_unavailable")
+
 	errStatusCodeMap = map[error]int{
 		ErrAuthorizationCodeNotFound:     http.StatusNotFound,
 		ErrAuthorizationCodeExpired:      http.StatusBadRequest,
+		ErrAuthorizationCodeUsed:         http.StatusBadRequest,
 		ErrInvalidRedirectURI:            http.StatusBadRequest,
 		ErrInvalidScope:                  http.StatusBadRequest,
 		ErrInvalidUsernameOrPassword:     http.StatusBadRequest,
@@ -16,11 +32,186 @@ var (
 		ErrRequestedScopeCannotBeGreater: http.StatusBadRequest,
 		ErrTokenMissing:                  http.StatusNotFound,
 		ErrTokenHintInvalid:              http.StatusBadRequest,
+		ErrTokenNotIssuedToClient:        http.StatusBadRequest,
 		ErrAccessTokenNotFound:           http.StatusNotFound,
 		ErrRefreshTokenNotFound:          http.StatusNotFound,
 		ErrTokenMissing:                  http.StatusBadRequest,
 		ErrTokenHintInvalid:              http.StatusBadRequest,
 		ErrInvalidUsernameOrPassword:     http.StatusUnauthorized,
+		ErrPasswordLoginNotAvailable:     http.StatusBadRequest,
+		ErrHandoffRequiresUser:           http.StatusBadRequest,
+		ErrHandoffClientNotFound:         http.StatusBadRequest,
+		ErrClientSecretTooShort:          http.StatusBadRequest,
+		ErrScopeAudienceMismatch:         http.StatusBadRequest,
+		ErrInvalidResource:               http.StatusBadRequest,
+		ErrAcrNotSatisfiable:             http.StatusBadRequest,
+		ErrMFARequired:                   http.StatusUnauthorized,
+		ErrInvalidOTP:                    http.StatusUnauthorized,
+		ErrSlowDown:                      http.StatusTooManyRequests,
+		ErrClientRateLimited:             http.StatusTooManyRequests,
+		ErrTooManyFailedLogins:           http.StatusTooManyRequests,
+		ErrDeviceSecretNotFound:          http.StatusNotFound,
+		ErrDeviceSecretExpired:           http.StatusBadRequest,
+		ErrInvalidGrantType:              http.StatusBadRequest,
+		ErrRecoveryCodesRequireUser:      http.StatusBadRequest,
+		ErrTOTPRequiresUser:              http.StatusBadRequest,
+		ErrTOTPAlreadyEnrolled:           http.StatusConflict,
+		ErrTOTPNotEnrolled:               http.StatusNotFound,
+		ErrWebAuthnDisabled:              http.StatusForbidden,
+		ErrInvalidWebAuthnChallenge:      http.StatusBadRequest,
+		ErrInvalidWebAuthnCredential:     http.StatusBadRequest,
+		ErrWebAuthnCredentialExists:      http.StatusConflict,
+		ErrInvalidWebAuthnAssertion:      http.StatusBadRequest,
+		ErrRedirectURIMismatch:           http.StatusBadRequest,
+		ErrRedirectURISchemeNotAllowed:   http.StatusBadRequest,
+		ErrInsecureRedirectURI:           http.StatusBadRequest,
+		ErrRedirectURINotRegistered:      http.StatusBadRequest,
+		ErrWildcardRedirectURI:           http.StatusBadRequest,
+		ErrRedirectURIFragment:           http.StatusBadRequest,
+		ErrRedirectURIAlreadyRegistered:  http.StatusBadRequest,
+		ErrAPIKeyNotFound:                http.StatusNotFound,
+		ErrAPIKeyRevoked:                 http.StatusBadRequest,
+		ErrRefreshTokenGrantTypeDisabled: http.StatusBadRequest,
+		ErrTemporarilyUnavailable:        http.StatusServiceUnavailable,
+		ErrInvalidCodeChallenge:          http.StatusBadRequest,
+		ErrInvalidCodeChallengeMethod:    http.StatusBadRequest,
+		ErrInvalidCodeVerifier:           http.StatusBadRequest,
+		ErrDeviceCodeNotFound:            http.StatusNotFound,
+		ErrDeviceCodeExpired:             http.StatusBadRequest,
+		ErrDeviceCodeDenied:              http.StatusBadRequest,
+		ErrAuthorizationPending:          http.StatusBadRequest,
+		ErrDeviceCodeSlowDown:            http.StatusBadRequest,
+		ErrUserCodeNotFound:              http.StatusNotFound,
+		ErrInvalidAssertion:              http.StatusBadRequest,
+		ErrAssertionExpired:              http.StatusBadRequest,
+		ErrAssertionAudienceMismatch:     http.StatusBadRequest,
+		ErrAssertionSubjectNotFound:      http.StatusBadRequest,
+		ErrInvalidSubjectToken:           http.StatusBadRequest,
+		ErrSubjectTokenNotForClient:      http.StatusBadRequest,
+		ErrSubjectTokenProofRequired:     http.StatusBadRequest,
+		ErrUnsupportedTokenType:          http.StatusBadRequest,
+		ErrDelegationChainTooLong:        http.StatusBadRequest,
+		ErrUnauthorizedClient:            http.StatusBadRequest,
+		ErrUnknownGrantType:              http.StatusBadRequest,
+		ErrRefreshTokenReused:            http.StatusBadRequest,
+		ErrPushedRequestNotFound:         http.StatusBadRequest,
+		ErrPushedRequestExpired:          http.StatusBadRequest,
+		ErrPushedRequestRequired:         http.StatusBadRequest,
+		ErrRequestURINotAllowed:          http.StatusBadRequest,
+		ErrInvalidResponseType:           http.StatusBadRequest,
+		ErrResponseTypeNotAllowed:        http.StatusBadRequest,
+		ErrInvalidAuthorizationDetails:   http.StatusBadRequest,
+		ErrAuthReqIDNotFound:             http.StatusBadRequest,
+		ErrAuthReqIDExpired:              http.StatusBadRequest,
+		ErrBackchannelRequestDenied:      http.StatusBadRequest,
+		ErrBackchannelSlowDown:           http.StatusBadRequest,
+		ErrOpenIDScopeRequired:           http.StatusBadRequest,
+		ErrLoginHintRequired:             http.StatusBadRequest,
+		ErrUnknownUserID:                 http.StatusBadRequest,
+		ErrInvalidBindingMessage:         http.StatusBadRequest,
+		ErrNotificationTokenRequired:     http.StatusBadRequest,
+		ErrInvalidTokenFormat:            http.StatusBadRequest,
+		ErrTokenLineageNotFound:          http.StatusNotFound,
+		ErrInvalidDPoPProof:              http.StatusBadRequest,
+		ErrGrantTypeMissing:              http.StatusBadRequest,
+		ErrDPoPKeyMismatch:               http.StatusBadRequest,
+		ErrClientRegistrationDisabled:    http.StatusForbidden,
+		ErrInvalidClientMetadata:         http.StatusBadRequest,
+		ErrInvalidClientRedirectURI:      http.StatusBadRequest,
+		ErrTooManyRedirectURIs:           http.StatusBadRequest,
+		ErrUserRegistrationDisabled:      http.StatusForbidden,
+		ErrUsernameTaken:                 http.StatusConflict,
+		ErrCannotSetEmptyUsername:        http.StatusBadRequest,
+		ErrPasswordResetDisabled:         http.StatusForbidden,
+		ErrInvalidPasswordResetToken:     http.StatusBadRequest,
+		ErrEmailVerificationDisabled:     http.StatusForbidden,
+		ErrInvalidEmailVerificationToken: http.StatusBadRequest,
+		ErrEmailNotVerified:              http.StatusBadRequest,
+		ErrUserDisabled:                  http.StatusBadRequest,
+	}
+
+	// errorCodes are the OAuth 2.0 error codes of errors clients need to tell
+	// apart, such as devices polling the token endpoint which need the codes of
+	// https://tools.ietf.org/html/rfc8628#section-3.5 to know what to do
+	errorCodes = map[error]string{
+		ErrUnauthorizedClient:   "unauthorized_client",
+		ErrRefreshTokenReused:   "invalid_grant",
+		ErrAuthorizationPending: "authorization_pending",
+		ErrDeviceCodeSlowDown:   "slow_down",
+		ErrDeviceCodeDenied:     "access_denied",
+		ErrDeviceCodeExpired:    "expired_token",
+		// Backchannel authentication errors, see
+		// https://openid.net/specs/openid-client-initiated-backchannel-authentication-core-1_0.html#rfc.section.13
+		ErrAuthReqIDNotFound:         "invalid_grant",
+		ErrAuthReqIDExpired:          "expired_token",
+		ErrBackchannelRequestDenied:  "access_denied",
+		ErrBackchannelSlowDown:       "slow_down",
+		ErrOpenIDScopeRequired:       "invalid_scope",
+		ErrLoginHintRequired:         "invalid_request",
+		ErrUnknownUserID:             "unknown_user_id",
+		ErrInvalidBindingMessage:     "invalid_binding_message",
+		ErrNotificationTokenRequired: "invalid_request",
+		// Clients registered for other response types, see
+		// https://tools.ietf.org/html/rfc6749#section-4.1.2.1
+		ErrResponseTypeNotAllowed: "unauthorized_client",
+		// Clients revoking tokens of other clients, see
+		// https://tools.ietf.org/html/rfc7009#section-2.2.1
+		ErrTokenNotIssuedToClient: "unauthorized_client",
+		// Resource indicator errors, see https://tools.ietf.org/html/rfc8707#section-2
+		ErrInvalidResource: "invalid_target",
+		// Rich authorization request errors, see https://tools.ietf.org/html/rfc9396#section-5
+		ErrInvalidAuthorizationDetails: "invalid_authorization_details",
+		// DPoP errors, see https://tools.ietf.org/html/rfc9449#section-5
+		ErrInvalidDPoPProof: "invalid_dpop_proof",
+		ErrDPoPKeyMismatch:  "invalid_grant",
+		// Client registration errors, see https://tools.ietf.org/html/rfc7591#section-3.2.2
+		ErrInvalidClientMetadata:    "invalid_client_metadata",
+		ErrInvalidClientRedirectURI: "invalid_redirect_uri",
+		ErrTooManyRedirectURIs:      "invalid_redirect_uri",
+	}
+
+	// grantErrorCodes are the error codes of the remaining errors of the token
+	// endpoint, see https://tools.ietf.org/html/rfc6749#section-5.2, any other
+	// error of the request is reported as invalid_request
+	grantErrorCodes = map[error]string{
+		ErrInvalidGrantType:              "unsupported_grant_type",
+		ErrInvalidScope:                  "invalid_scope",
+		ErrRequestedScopeCannotBeGreater: "invalid_scope",
+		ErrScopeAudienceMismatch:         "invalid_scope",
+		ErrAuthorizationCodeNotFound:     "invalid_grant",
+		ErrAuthorizationCodeExpired:      "invalid_grant",
+		ErrAuthorizationCodeUsed:         "invalid_grant",
+		ErrInvalidRedirectURI:            "invalid_grant",
+		ErrRedirectURIMismatch:           "invalid_grant",
+		ErrInvalidCodeVerifier:           "invalid_grant",
+		ErrInvalidUsernameOrPassword:     "invalid_grant",
+		ErrPasswordLoginNotAvailable:     "invalid_grant",
+		ErrEmailNotVerified:              "invalid_grant",
+		ErrUserDisabled:                  "invalid_grant",
+		ErrInvalidOTP:                    "invalid_grant",
+		ErrInvalidWebAuthnChallenge:      "invalid_grant",
+		ErrInvalidWebAuthnAssertion:      "invalid_grant",
+		ErrRefreshTokenNotFound:          "invalid_grant",
+		ErrRefreshTokenExpired:           "invalid_grant",
+		ErrRefreshTokenGrantTypeDisabled: "invalid_grant",
+		ErrDeviceSecretNotFound:          "invalid_grant",
+		ErrDeviceSecretExpired:           "invalid_grant",
+		ErrDeviceCodeNotFound:            "invalid_grant",
+		ErrAPIKeyNotFound:                "invalid_grant",
+		ErrAPIKeyRevoked:                 "invalid_grant",
+		ErrInvalidAssertion:              "invalid_grant",
+		ErrAssertionExpired:              "invalid_grant",
+		ErrAssertionAudienceMismatch:     "invalid_grant",
+		ErrAssertionSubjectNotFound:      "invalid_grant",
+		ErrInvalidSubjectToken:           "invalid_grant",
+		ErrSubjectTokenNotForClient:      "invalid_grant",
+		ErrSubjectTokenProofRequired:     "invalid_grant",
+		ErrMFARequired:                   "mfa_required",
+		ErrAcrNotSatisfiable:             "unmet_authentication_requirements",
+		ErrSlowDown:                      "slow_down",
+		ErrClientRateLimited:             "slow_down",
+		ErrTooManyFailedLogins:           "slow_down",
+		ErrTemporarilyUnavailable:        "temporarily_unavailable",
 	}
 )
 
@@ -32,3 +223,67 @@ func getErrStatusCode(err error) int {
 
 	return http.StatusInternalServerError
 }
+
+// writeError writes a JSON error response with the status code of err,
+// a write refused by a read-only database is reported as temporarily unavailable,
+// errors clients need to tell apart carry their error code and scopes not granted are named in an invalid_scope error,
+// passwords breaking the password policy get an invalid_password error listing the rules they break
+func writeError(w http.ResponseWriter, err error) {
+	if scopeErr, ok := err.(*ScopeNotGrantedError); ok {
+		response.ErrorWithDescription(w, "invalid_scope", scopeErr.Error(), http.StatusBadRequest)
+		return
+	}
+	if policyErr, ok := err.(*pass.PolicyError); ok {
+		response.WriteJSON(w, &PasswordPolicyErrorResponse{
+			Error:            "invalid_password",
+			ErrorDescription: policyErr.Error(),
+			Violations:       policyErr.Violations,
+		}, http.StatusBadRequest)
+		return
+	}
+	if code, ok := errorCodes[err]; ok {
+		response.ErrorWithDescription(w, code, err.Error(), getErrStatusCode(err))
+		return
+	}
+	if database.IsReadOnlyError(err) {
+		w.Header().Set("Retry-After", strconv.Itoa(readOnlyRetryAfter))
+		err = ErrTemporarilyUnavailable
+	}
+	response.Error(w, err.Error(), getErrStatusCode(err))
+}
+
+// writeTokenError writes an error response of the token endpoint as per
+// RFC 6749 section 5.2, every error carries an error code and is a 400
+// unless the server failed or asks the client to slow down
+func writeTokenError(w http.ResponseWriter, err error) {
+	if scopeErr, ok := err.(*ScopeNotGrantedError); ok {
+		response.ErrorWithDescription(w, "invalid_scope", scopeErr.Error(), http.StatusBadRequest)
+		return
+	}
+	if database.IsReadOnlyError(err) {
+		w.Header().Set("Retry-After", strconv.Itoa(readOnlyRetryAfter))
+		err = ErrTemporarilyUnavailable
+	}
+
+	code := getErrStatusCode(err)
+	switch code {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusServiceUnavailable:
+	default:
+		code = http.StatusBadRequest
+	}
+	response.ErrorWithDescription(w, getTokenErrorCode(err, code), err.Error(), code)
+}
+
+// getTokenErrorCode returns the OAuth 2.0 error code of a token endpoint error
+func getTokenErrorCode(err error, code int) string {
+	if errorCode, ok := errorCodes[err]; ok {
+		return errorCode
+	}
+	if errorCode, ok := grantErrorCodes[err]; ok {
+		return errorCode
+	}
+	if code == http.StatusInternalServerError {
+		return "server_error"
+	}
+	return "invalid_request"
+}
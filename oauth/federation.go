@@ -0,0 +1,477 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/log"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/RichardKnop/go-oauth2-server/util/jwt"
+)
+
+const (
+	// federationStateLifetime is how many seconds users have to log in
+	// with the identity provider
+	federationStateLifetime = 600
+	// federationMetadataTTL is how many seconds the configuration and keys
+	// of identity providers are cached, keys are fetched again sooner when
+	// an ID token is signed with an unknown one
+	federationMetadataTTL = 3600
+	// maxFederationResponseLength limits the size of identity provider responses
+	maxFederationResponseLength = 1 << 20
+)
+
+var (
+	// ErrFederationProviderNotFound ...
+	ErrFederationProviderNotFound = errors.New("Identity provider not found")
+	// ErrInvalidFederationState ...
+	ErrInvalidFederationState = errors.New("Invalid or expired identity provider login")
+	// ErrFederationFailed ...
+	ErrFederationFailed = errors.New("Logging in with the identity provider failed")
+	// ErrInvalidFederatedIDToken ...
+	ErrInvalidFederatedIDToken = errors.New("Invalid identity provider ID token")
+	// ErrFederatedUserNotLinked ...
+	ErrFederatedUserNotLinked = errors.New("No user is linked to this identity provider account")
+	// ErrFederatedIdentityLinked ...
+	ErrFederatedIdentityLinked = errors.New("Identity provider account is linked to another user")
+
+	// federationClient talks to identity providers
+	federationClient = &http.Client{Timeout: 10 * time.Second}
+)
+
+// federationCache keeps the discovered configuration of identity providers
+type federationCache struct {
+	mu        sync.Mutex
+	providers map[string]*federationMetadata
+}
+
+// federationMetadata is the part of the OpenID Provider Metadata logins
+// need, see https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderMetadata
+type federationMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	keys                  []*jwt.JSONWebKey
+	loadedAt              time.Time
+}
+
+// federationTokenResponse is the response of the token endpoint of an
+// identity provider
+type federationTokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// BeginFederatedLogin returns the URL of the identity provider users log in
+// at and the state the login is identified by when they come back. The
+// query of the login page is restored by FinishFederatedLogin.
+func (s *Service) BeginFederatedLogin(providerName string, query url.Values) (string, string, error) {
+	provider, err := s.findFederationProvider(providerName)
+	if err != nil {
+		return "", "", err
+	}
+	metadata, err := s.getFederationMetadata(provider, false)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := randomFederationValue()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomFederationValue()
+	if err != nil {
+		return "", "", err
+	}
+	codeVerifier, err := randomFederationValue()
+	if err != nil {
+		return "", "", err
+	}
+
+	record := models.NewOauthFederationState(
+		models.HashToken(state),
+		provider.Name,
+		nonce,
+		codeVerifier,
+		query.Encode(),
+		federationStateLifetime,
+	)
+	if err := s.db.Create(record).Error; err != nil {
+		return "", "", err
+	}
+
+	scopes := provider.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	authQuery := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {provider.ClientID},
+		"redirect_uri":          {s.federationRedirectURI(provider)},
+		"scope":                 {strings.Join(scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {CodeChallengeMethodS256},
+	}
+	if loginHint := query.Get("login_hint"); loginHint != "" {
+		authQuery.Set("login_hint", loginHint)
+	}
+
+	authURL, err := url.Parse(metadata.AuthorizationEndpoint)
+	if err != nil {
+		return "", "", ErrFederationFailed
+	}
+	for name, values := range authURL.Query() {
+		if _, ok := authQuery[name]; !ok {
+			authQuery[name] = values
+		}
+	}
+	authURL.RawQuery = authQuery.Encode()
+
+	return authURL.String(), state, nil
+}
+
+// FinishFederatedLogin exchanges the code the identity provider redirected
+// the user back with and returns the local user linked to the account of
+// the user at the provider. An account not linked yet is linked to the
+// current user if logged in, then by email or to a new user as the provider
+// is configured. The query of the login page is returned once the state
+// is found, even if the login fails.
+func (s *Service) FinishFederatedLogin(providerName, state, code string, currentUser *models.OauthUser) (*models.OauthUser, url.Values, error) {
+	provider, err := s.findFederationProvider(providerName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	record, err := s.consumeFederationState(provider, state)
+	if err != nil {
+		return nil, nil, err
+	}
+	query, err := url.ParseQuery(record.Query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Users declining to log in come back with an error instead of a code
+	if code == "" {
+		return nil, query, ErrFederationFailed
+	}
+
+	metadata, err := s.getFederationMetadata(provider, false)
+	if err != nil {
+		return nil, query, err
+	}
+	idToken, err := s.exchangeFederationCode(provider, metadata, code, record.CodeVerifier)
+	if err != nil {
+		return nil, query, err
+	}
+	claims, err := s.verifyFederatedIDToken(provider, metadata, idToken, record.Nonce)
+	if err != nil {
+		return nil, query, err
+	}
+
+	user, err := s.linkFederatedIdentity(provider, claims, currentUser)
+	return user, query, err
+}
+
+// findFederationProvider returns the configuration of the identity provider
+func (s *Service) findFederationProvider(name string) (*config.FederationProviderConfig, error) {
+	for i := range s.cnf.Oauth.FederationProviders {
+		if provider := &s.cnf.Oauth.FederationProviders[i]; provider.Name == name {
+			return provider, nil
+		}
+	}
+	return nil, ErrFederationProviderNotFound
+}
+
+// federationRedirectURI is where the identity provider sends users back to
+func (s *Service) federationRedirectURI(provider *config.FederationProviderConfig) string {
+	return fmt.Sprintf(
+		"%s/web/federation/%s/callback",
+		strings.TrimSuffix(s.cnf.JWT.Issuer, "/"),
+		url.PathEscape(provider.Name),
+	)
+}
+
+// consumeFederationState deletes the state so a login can only finish once
+func (s *Service) consumeFederationState(provider *config.FederationProviderConfig, state string) (*models.OauthFederationState, error) {
+	record := new(models.OauthFederationState)
+	notFound := s.db.Where("state_hash = ? AND provider = ?", models.HashToken(state), provider.Name).
+		First(record).RecordNotFound()
+	if notFound {
+		return nil, ErrInvalidFederationState
+	}
+
+	// Consuming the state fails if the login finished concurrently
+	result := s.db.Unscoped().Where("id = ?", record.ID).Delete(new(models.OauthFederationState))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 || time.Now().UTC().After(record.ExpiresAt) {
+		return nil, ErrInvalidFederationState
+	}
+	return record, nil
+}
+
+// getFederationMetadata returns the configuration and keys of the identity
+// provider, discovering them again if the cache expired or reload is true
+func (s *Service) getFederationMetadata(provider *config.FederationProviderConfig, reload bool) (*federationMetadata, error) {
+	s.federationCache.mu.Lock()
+	metadata := s.federationCache.providers[provider.Name]
+	s.federationCache.mu.Unlock()
+	if metadata != nil && !reload && time.Since(metadata.loadedAt) < federationMetadataTTL*time.Second {
+		return metadata, nil
+	}
+
+	metadata = new(federationMetadata)
+	issuer := strings.TrimSuffix(provider.Issuer, "/")
+	if err := getFederationJSON(issuer+"/.well-known/openid-configuration", metadata); err != nil {
+		log.ERROR.Printf("Discovering identity provider %s failed: %s", provider.Name, err)
+		return nil, ErrFederationFailed
+	}
+	// The issuer must be the one configured, see section 4.3 of the spec
+	if metadata.Issuer != provider.Issuer {
+		log.ERROR.Printf("Identity provider %s has issuer %s", provider.Name, metadata.Issuer)
+		return nil, ErrFederationFailed
+	}
+
+	keySet := new(jwt.JSONWebKeySet)
+	if err := getFederationJSON(metadata.JWKSURI, keySet); err != nil {
+		log.ERROR.Printf("Fetching keys of identity provider %s failed: %s", provider.Name, err)
+		return nil, ErrFederationFailed
+	}
+	metadata.keys = keySet.Keys
+	metadata.loadedAt = time.Now()
+
+	s.federationCache.mu.Lock()
+	s.federationCache.providers[provider.Name] = metadata
+	s.federationCache.mu.Unlock()
+
+	return metadata, nil
+}
+
+// exchangeFederationCode exchanges the code for tokens and returns the ID token
+func (s *Service) exchangeFederationCode(provider *config.FederationProviderConfig, metadata *federationMetadata, code, codeVerifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {s.federationRedirectURI(provider)},
+		"client_id":     {provider.ClientID},
+		"client_secret": {provider.ClientSecret},
+		"code_verifier": {codeVerifier},
+	}
+	resp, err := federationClient.PostForm(metadata.TokenEndpoint, form)
+	if err != nil {
+		log.ERROR.Printf("Exchanging code with identity provider %s failed: %s", provider.Name, err)
+		return "", ErrFederationFailed
+	}
+	defer resp.Body.Close()
+
+	tokenResponse := new(federationTokenResponse)
+	err = json.NewDecoder(io.LimitReader(resp.Body, maxFederationResponseLength)).Decode(tokenResponse)
+	if resp.StatusCode != http.StatusOK || err != nil || tokenResponse.IDToken == "" {
+		log.WARNING.Printf(
+			"Identity provider %s refused code: %d %s %s",
+			provider.Name, resp.StatusCode, tokenResponse.Error, tokenResponse.ErrorDescription,
+		)
+		return "", ErrFederationFailed
+	}
+	return tokenResponse.IDToken, nil
+}
+
+// verifyFederatedIDToken returns the claims of an ID token the identity
+// provider issued to us for the login, see section 3.1.3.7 of
+// https://openid.net/specs/openid-connect-core-1_0.html
+func (s *Service) verifyFederatedIDToken(provider *config.FederationProviderConfig, metadata *federationMetadata, idToken, nonce string) (jwt.Claims, error) {
+	header, err := jwt.ParseHeader(idToken)
+	if err != nil {
+		return nil, ErrInvalidFederatedIDToken
+	}
+
+	// Keys are rotated, fetch them again if the token is signed with a new one
+	keys := federationKeys(metadata, header.KeyID)
+	if len(keys) == 0 {
+		if metadata, err = s.getFederationMetadata(provider, true); err != nil {
+			return nil, err
+		}
+		keys = federationKeys(metadata, header.KeyID)
+	}
+
+	var claims jwt.Claims
+	for _, key := range keys {
+		// Only asymmetric algorithms are supported, so the client
+		// secret cannot be used to forge tokens
+		verifier, err := key.Verifier(header.Algorithm)
+		if err != nil {
+			continue
+		}
+		if claims, err = jwt.Parse(idToken, verifier); err == nil {
+			break
+		}
+	}
+	if claims == nil {
+		return nil, ErrInvalidFederatedIDToken
+	}
+
+	if issuer, _ := claims.String("iss"); issuer != metadata.Issuer {
+		return nil, ErrInvalidFederatedIDToken
+	}
+	audiences := assertionAudiences(claims)
+	if !util.StringInSlice(provider.ClientID, audiences) {
+		return nil, ErrInvalidFederatedIDToken
+	}
+	if azp, ok := claims.String("azp"); (ok || len(audiences) > 1) && azp != provider.ClientID {
+		return nil, ErrInvalidFederatedIDToken
+	}
+	if _, ok := claims.Int64("exp"); !ok {
+		return nil, ErrInvalidFederatedIDToken
+	}
+	if tokenNonce, _ := claims.String("nonce"); tokenNonce != nonce {
+		return nil, ErrInvalidFederatedIDToken
+	}
+	if subject, _ := claims.String("sub"); subject == "" || len(subject) > 255 {
+		return nil, ErrInvalidFederatedIDToken
+	}
+	return claims, nil
+}
+
+// linkFederatedIdentity returns the local user the account at the identity
+// provider is linked to, linking it first if needed
+func (s *Service) linkFederatedIdentity(provider *config.FederationProviderConfig, claims jwt.Claims, currentUser *models.OauthUser) (*models.OauthUser, error) {
+	subject, _ := claims.String("sub")
+	email, _ := claims.String("email")
+	if len(email) > 254 {
+		email = ""
+	}
+
+	identity := new(models.OauthFederatedIdentity)
+	notFound := s.db.Preload("User").Where("provider = ? AND subject = ?", provider.Name, subject).
+		First(identity).RecordNotFound()
+	if !notFound {
+		if currentUser != nil && identity.UserID.String != string(currentUser.ID) {
+			return nil, ErrFederatedIdentityLinked
+		}
+		if identity.Email != email {
+			err := s.db.Model(identity).UpdateColumn("email", email).Error
+			if err != nil {
+				return nil, err
+			}
+		}
+		return identity.User, nil
+	}
+
+	user, err := s.findFederatedUser(provider, email, federatedEmailVerified(claims), currentUser)
+	if err != nil {
+		return nil, err
+	}
+
+	identity = models.NewOauthFederatedIdentity(user, provider.Name, subject, email)
+	if err := s.db.Create(identity).Error; err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// findFederatedUser returns the local user to link an account at the
+// identity provider to: the current user, else the user with the verified
+// email as username or a new user if the provider is configured to
+func (s *Service) findFederatedUser(provider *config.FederationProviderConfig, email string, emailVerified bool, currentUser *models.OauthUser) (*models.OauthUser, error) {
+	if currentUser != nil {
+		return currentUser, nil
+	}
+	if email == "" || !emailVerified {
+		return nil, ErrFederatedUserNotLinked
+	}
+
+	if provider.LinkByEmail {
+		user, err := s.FindUserByUsername(email)
+		if err != ErrUserNotFound {
+			return user, err
+		}
+	}
+	if !provider.CreateUsers {
+		return nil, ErrFederatedUserNotLinked
+	}
+
+	// Taking over an existing user requires LinkByEmail
+	user, err := s.CreateUser(roles.User, email, "")
+	if err == ErrUsernameTaken {
+		return nil, ErrFederatedUserNotLinked
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = s.db.Model(new(models.OauthUser)).Where("id = ?", user.ID).
+		UpdateColumn("email_verified", true).Error
+	if err != nil {
+		return nil, err
+	}
+	user.EmailVerified = true
+	return user, nil
+}
+
+// federatedEmailVerified returns the email_verified claim, which some
+// identity providers send as a string
+func federatedEmailVerified(claims jwt.Claims) bool {
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		return verified
+	case string:
+		return verified == "true"
+	}
+	return false
+}
+
+// federationKeys returns the keys of the identity provider with the key ID,
+// or all of them if the token does not name one
+func federationKeys(metadata *federationMetadata, keyID string) []*jwt.JSONWebKey {
+	if keyID == "" {
+		return metadata.keys
+	}
+	var keys []*jwt.JSONWebKey
+	for _, key := range metadata.keys {
+		if key.KeyID == keyID {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// getFederationJSON fetches and decodes a JSON document of an identity provider
+func getFederationJSON(rawURL string, v interface{}) error {
+	resp, err := federationClient.Get(rawURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %d", rawURL, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxFederationResponseLength)).Decode(v)
+}
+
+// randomFederationValue returns a random state, nonce or code verifier
+func randomFederationValue() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
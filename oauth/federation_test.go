@@ -0,0 +1,244 @@
+package oauth_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	"github.com/RichardKnop/go-oauth2-server/util/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+// testIdentityProvider is an OpenID Connect provider issuing ID tokens
+// with the claims of the test
+type testIdentityProvider struct {
+	server *httptest.Server
+	mu     sync.Mutex
+	key    jwt.Key
+	// signer signs ID tokens, key by default
+	signer jwt.Signer
+	// challenges are the PKCE challenges of the codes handed out
+	challenges map[string]string
+	claims     jwt.Claims
+}
+
+func newTestIdentityProvider() (*testIdentityProvider, error) {
+	p := &testIdentityProvider{challenges: make(map[string]string)}
+	if err := p.rotateKey("key_1"); err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.server.URL,
+			"authorization_endpoint": p.server.URL + "/authorize?prompt=select_account",
+			"token_endpoint":         p.server.URL + "/token",
+			"jwks_uri":               p.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		jsonWebKey, _ := jwt.NewJSONWebKey(p.key)
+		json.NewEncoder(w).Encode(&jwt.JSONWebKeySet{Keys: []*jwt.JSONWebKey{jsonWebKey}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		r.ParseForm()
+		sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		challenge, ok := p.challenges[r.Form.Get("code")]
+		if !ok || challenge != base64.RawURLEncoding.EncodeToString(sum[:]) ||
+			r.Form.Get("client_id") != "test_upstream_client" ||
+			r.Form.Get("client_secret") != "test_upstream_secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		delete(p.challenges, r.Form.Get("code"))
+		idToken, _ := jwt.Sign(p.claims, p.signer)
+		json.NewEncoder(w).Encode(map[string]string{"id_token": idToken})
+	})
+	p.server = httptest.NewServer(mux)
+	return p, nil
+}
+
+// rotateKey replaces the signing key of the provider
+func (p *testIdentityProvider) rotateKey(keyID string) error {
+	privateKey, err := jwt.GenerateKey(jwt.RS256)
+	if err != nil {
+		return err
+	}
+	key, err := jwt.NewKey(jwt.RS256, keyID, nil, privateKey)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.key, p.signer = key, key
+	p.mu.Unlock()
+	return nil
+}
+
+// authorize plays the user logging in at the authorization URL, it returns
+// a code for an ID token with the claims of the user
+func (p *testIdentityProvider) authorize(authURL string, claims jwt.Claims) string {
+	u, _ := url.Parse(authURL)
+	query := u.Query()
+
+	idTokenClaims := jwt.Claims{
+		"iss":   p.server.URL,
+		"aud":   "test_upstream_client",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": query.Get("nonce"),
+	}
+	for name, value := range claims {
+		idTokenClaims[name] = value
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.claims = idTokenClaims
+	p.challenges["test_code"] = query.Get("code_challenge")
+	return "test_code"
+}
+
+func (suite *OauthTestSuite) TestFederatedLogin() {
+	provider, err := newTestIdentityProvider()
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	defer provider.server.Close()
+
+	suite.cnf.Oauth.FederationProviders = []config.FederationProviderConfig{{
+		Name:         "test",
+		Issuer:       provider.server.URL,
+		ClientID:     "test_upstream_client",
+		ClientSecret: "test_upstream_secret",
+	}}
+	defer func() { suite.cnf.Oauth.FederationProviders = nil }()
+	providerConfig := &suite.cnf.Oauth.FederationProviders[0]
+
+	login := func(claims jwt.Claims, currentUser *models.OauthUser) (*models.OauthUser, url.Values, error) {
+		authURL, state, err := suite.service.BeginFederatedLogin("test", url.Values{
+			"client_id":  {"test_client_1"},
+			"login_hint": {"jdoe@example.com"},
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return suite.service.FinishFederatedLogin("test", state, provider.authorize(authURL, claims), currentUser)
+	}
+
+	// Users are sent to the provider with PKCE and come back to us
+	authURL, _, err := suite.service.BeginFederatedLogin("test", url.Values{"login_hint": {"jdoe@example.com"}})
+	if assert.NoError(suite.T(), err) {
+		u, _ := url.Parse(authURL)
+		assert.Equal(suite.T(), provider.server.URL+"/authorize", u.Scheme+"://"+u.Host+u.Path)
+		assert.Equal(suite.T(), "test_upstream_client", u.Query().Get("client_id"))
+		assert.Equal(suite.T(), suite.cnf.JWT.Issuer+"/web/federation/test/callback", u.Query().Get("redirect_uri"))
+		assert.Equal(suite.T(), "openid email profile", u.Query().Get("scope"))
+		assert.Equal(suite.T(), "S256", u.Query().Get("code_challenge_method"))
+		assert.Equal(suite.T(), "jdoe@example.com", u.Query().Get("login_hint"))
+		assert.Equal(suite.T(), "select_account", u.Query().Get("prompt"))
+	}
+
+	_, _, err = suite.service.BeginFederatedLogin("bogus", url.Values{})
+	assert.Equal(suite.T(), oauth.ErrFederationProviderNotFound, err)
+
+	// Accounts are not linked to anyone by default, the query is restored
+	_, query, err := login(jwt.Claims{"sub": "upstream_1", "email": "test@user", "email_verified": true}, nil)
+	assert.Equal(suite.T(), oauth.ErrFederatedUserNotLinked, err)
+	assert.Equal(suite.T(), "test_client_1", query.Get("client_id"))
+
+	// Verified emails link accounts to the user with the email
+	providerConfig.LinkByEmail = true
+	_, _, err = login(jwt.Claims{"sub": "upstream_1", "email": "test@user", "email_verified": false}, nil)
+	assert.Equal(suite.T(), oauth.ErrFederatedUserNotLinked, err)
+	user, _, err := login(jwt.Claims{"sub": "upstream_1", "email": "TEST@user", "email_verified": "true"}, nil)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), "test@user", user.Username)
+	}
+
+	// Linked accounts stay linked whatever the email
+	user, _, err = login(jwt.Claims{"sub": "upstream_1", "email": "test@user2"}, nil)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), "test@user", user.Username)
+	}
+	identity := new(models.OauthFederatedIdentity)
+	assert.False(suite.T(), suite.db.Where("provider = ? AND subject = ?", "test", "upstream_1").
+		First(identity).RecordNotFound())
+	assert.Equal(suite.T(), "test@user2", identity.Email)
+
+	// Logged in users link accounts to theirs, but not linked ones
+	otherUser, err := suite.service.CreateUser(roles.User, "jsmith@example.com", "test_password")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	_, _, err = login(jwt.Claims{"sub": "upstream_1"}, otherUser)
+	assert.Equal(suite.T(), oauth.ErrFederatedIdentityLinked, err)
+	user, _, err = login(jwt.Claims{"sub": "upstream_2"}, otherUser)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), "jsmith@example.com", user.Username)
+	}
+
+	// Users can be created for first logins, but existing ones
+	// are only taken over by email when configured to
+	providerConfig.LinkByEmail = false
+	providerConfig.CreateUsers = true
+	_, _, err = login(jwt.Claims{"sub": "upstream_3", "email": "test@superuser", "email_verified": true}, nil)
+	assert.Equal(suite.T(), oauth.ErrFederatedUserNotLinked, err)
+	user, _, err = login(jwt.Claims{"sub": "upstream_4", "email": "jdoe@example.com", "email_verified": true}, nil)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), "jdoe@example.com", user.Username)
+		assert.True(suite.T(), user.EmailVerified)
+		assert.False(suite.T(), user.Password.Valid)
+	}
+
+	// ID tokens must be issued to us for the login
+	for _, claims := range []jwt.Claims{
+		{"sub": "upstream_4", "nonce": "bogus"},
+		{"sub": "upstream_4", "aud": "bogus"},
+		{"sub": "upstream_4", "aud": []string{"test_upstream_client", "bogus"}},
+		{"sub": "upstream_4", "iss": "https://bogus"},
+		{"sub": ""},
+	} {
+		_, _, err = login(claims, nil)
+		assert.Equal(suite.T(), oauth.ErrInvalidFederatedIDToken, err, claims)
+	}
+
+	// The client secret cannot sign ID tokens
+	provider.signer, _ = jwt.NewHS256("key_1", []byte("test_upstream_secret"))
+	_, _, err = login(jwt.Claims{"sub": "upstream_4"}, nil)
+	assert.Equal(suite.T(), oauth.ErrInvalidFederatedIDToken, err)
+
+	// Rotated keys are fetched
+	assert.NoError(suite.T(), provider.rotateKey("key_2"))
+	user, _, err = login(jwt.Claims{"sub": "upstream_4"}, nil)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), "jdoe@example.com", user.Username)
+	}
+
+	// Logins finish once, with the provider they started with
+	authURL, state, err := suite.service.BeginFederatedLogin("test", url.Values{})
+	if assert.NoError(suite.T(), err) {
+		code := provider.authorize(authURL, jwt.Claims{"sub": "upstream_4"})
+		_, query, err = suite.service.FinishFederatedLogin("test", "bogus", code, nil)
+		assert.Equal(suite.T(), oauth.ErrInvalidFederationState, err)
+		assert.Nil(suite.T(), query)
+		_, _, err = suite.service.FinishFederatedLogin("test", state, "", nil)
+		assert.Equal(suite.T(), oauth.ErrFederationFailed, err)
+		_, _, err = suite.service.FinishFederatedLogin("test", state, code, nil)
+		assert.Equal(suite.T(), oauth.ErrInvalidFederationState, err)
+	}
+}
@@ -0,0 +1,54 @@
+package oauth
+
+import (
+	"net/http"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+)
+
+// GrantHandler issues tokens for a grant type. The client has already
+// been authenticated and is allowed to use the grant type. Errors should be
+// one of the errors of this package, for example ErrInvalidUsernameOrPassword,
+// any other error is reported as an internal server error.
+type GrantHandler interface {
+	Grant(r *http.Request, client *models.OauthClient) (*AccessTokenResponse, error)
+}
+
+// GrantHandlerFunc allows an ordinary function to be used as a GrantHandler
+type GrantHandlerFunc func(r *http.Request, client *models.OauthClient) (*AccessTokenResponse, error)
+
+// Grant calls f(r, client)
+func (f GrantHandlerFunc) Grant(r *http.Request, client *models.OauthClient) (*AccessTokenResponse, error) {
+	return f(r, client)
+}
+
+// RegisterGrantHandler registers a handler for a grant type, replacing
+// the handler of a built-in grant type if one has the same name
+func (s *Service) RegisterGrantHandler(grantType string, handler GrantHandler) {
+	s.grantHandlers[grantType] = handler
+}
+
+// registerBuiltinGrantHandlers registers the grant types this server implements
+func (s *Service) registerBuiltinGrantHandlers() {
+	for grantType, handler := range map[string]GrantHandlerFunc{
+		"authorization_code":   s.authorizationCodeGrant,
+		"password":             s.passwordGrant,
+		"client_credentials":   s.clientCredentialsGrant,
+		"refresh_token":        s.refreshTokenGrant,
+		"device_secret":        s.deviceSecretGrant,
+		"api_key":              s.apiKeyGrant,
+		DeviceCodeGrantType:    s.deviceCodeGrant,
+		JWTBearerGrantType:     s.jwtBearerGrant,
+		SAML2BearerGrantType:   s.saml2BearerGrant,
+		TokenExchangeGrantType: s.tokenExchangeGrant,
+		CIBAGrantType:          s.cibaGrant,
+		WebAuthnGrantType:      s.webauthnGrant,
+	} {
+		s.RegisterGrantHandler(grantType, handler)
+	}
+}
+
+// grantTypes returns a map of grant types against their handlers
+func (s *Service) grantTypes() map[string]GrantHandler {
+	return s.grantHandlers
+}
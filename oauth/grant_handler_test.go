@@ -0,0 +1,77 @@
+package oauth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/tokentypes"
+	"github.com/RichardKnop/go-oauth2-server/test-util"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestRegisterGrantHandler() {
+	// Use a separate service so the custom grant does not affect other tests
+	service := oauth.NewService(suite.cnf, suite.db)
+	router := mux.NewRouter()
+	service.RegisterRoutes(router, "/v1/oauth")
+
+	service.RegisterGrantHandler("urn:example:otp", oauth.GrantHandlerFunc(func(r *http.Request, client *models.OauthClient) (*oauth.AccessTokenResponse, error) {
+		if r.Form.Get("otp") != "123456" {
+			return nil, oauth.ErrInvalidUsernameOrPassword
+		}
+		user, err := service.FindUserByUsername(r.Form.Get("username"))
+		if err != nil {
+			return nil, err
+		}
+		accessToken, err := service.GrantAccessToken(client, user, 3600, "read")
+		if err != nil {
+			return nil, err
+		}
+		return oauth.NewAccessTokenResponse(accessToken, nil, 3600, tokentypes.Bearer)
+	}))
+
+	otpGrant := func(otp string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+		assert.NoError(suite.T(), err, "Request setup should not get an error")
+		r.SetBasicAuth("test_client_1", "test_secret")
+		r.PostForm = url.Values{
+			"grant_type": {"urn:example:otp"},
+			"username":   {"test@user"},
+			"otp":        {otp},
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	// The custom grant issues tokens like the built-in ones
+	w := otpGrant("123456")
+	resp := suite.decodeAccessTokenResponse(w)
+	assert.Equal(suite.T(), "read", resp.Scope)
+	accessToken, err := suite.service.Authenticate(resp.AccessToken)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), suite.users[1].ID, accessToken.UserID.String)
+	}
+
+	w = otpGrant("000000")
+	testutil.TestResponseForOauthError(suite.T(), w, "invalid_grant", oauth.ErrInvalidUsernameOrPassword.Error(), 400)
+
+	// The shared service does not know the grant type
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
+	r.PostForm = url.Values{"grant_type": {"urn:example:otp"}}
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	testutil.TestResponseForOauthError(suite.T(), w, "unsupported_grant_type", oauth.ErrInvalidGrantType.Error(), 400)
+
+	// And it can be disabled like the built-in grant types
+	suite.cnf.Oauth.DisabledGrantTypes = []string{"urn:example:otp"}
+	defer func() { suite.cnf.Oauth.DisabledGrantTypes = nil }()
+	w = otpGrant("123456")
+	testutil.TestResponseForOauthError(suite.T(), w, "unsupported_grant_type", oauth.ErrInvalidGrantType.Error(), 400)
+}
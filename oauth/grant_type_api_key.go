@@ -0,0 +1,42 @@
+package oauth
+
+import (
+	"net/http"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth/tokentypes"
+)
+
+func (s *Service) apiKeyGrant(r *http.Request, client *models.OauthClient) (*AccessTokenResponse, error) {
+	// Fetch the API key
+	apiKey, err := s.getValidAPIKey(r.Form.Get("api_key"), client)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a new short-lived access token, but never a refresh token
+	// as the client is expected to exchange the key again instead
+	lifetime := s.getAPIKeyTokenLifetime()
+	accessToken, err := s.GrantAccessToken(
+		apiKey.Client,
+		apiKey.User,
+		lifetime,
+		apiKey.Scope,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create response
+	accessTokenResponse, err := NewAccessTokenResponse(
+		accessToken,
+		nil, // refresh token
+		lifetime,
+		tokentypes.Bearer,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return accessTokenResponse, nil
+}
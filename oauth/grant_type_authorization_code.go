@@ -24,29 +24,103 @@ func (s *Service) authorizationCodeGrant(r *http.Request, client *models.OauthCl
 		return nil, err
 	}
 
+	// The scope may be restricted to an audience other than requested
+	if err := s.checkRequestedAudience(r.Form.Get("audience"), authorizationCode.Scope); err != nil {
+		return nil, err
+	}
+
+	// Tokens can be requested for specific resource servers
+	resources, err := s.getRequestedResources(r, authorizationCode.Scope)
+	if err != nil {
+		return nil, err
+	}
+
+	// A code granted with a PKCE code challenge needs the matching verifier
+	if err := verifyCodeVerifier(
+		authorizationCode.CodeChallenge,
+		authorizationCode.CodeChallengeMethod,
+		r.Form.Get("code_verifier"),
+	); err != nil {
+		return nil, err
+	}
+
+	// Mark the authorization code as exchanged before issuing any tokens
+	if err := s.exchangeAuthorizationCode(authorizationCode); err != nil {
+		return nil, err
+	}
+
 	// Log in the user
-	accessToken, refreshToken, err := s.Login(
+	accessToken, refreshToken, err := s.login(
 		authorizationCode.Client,
 		authorizationCode.User,
 		authorizationCode.Scope,
+		"authorization_code",
 	)
 	if err != nil {
 		return nil, err
 	}
+	if err := s.setRefreshTokenGrantType(refreshToken, "authorization_code"); err != nil {
+		return nil, err
+	}
+
+	// The tokens descend from the authorization code
+	if err := s.setLineage(accessToken, refreshToken, authorizationCode.ID, ""); err != nil {
+		return nil, err
+	}
+
+	// Issue the access token for the requested resource servers only
+	if err := s.setResourceAudience(accessToken, resources); err != nil {
+		return nil, err
+	}
+
+	// The tokens carry the authorization details the user approved
+	err = s.setAuthorizationDetails(accessToken, refreshToken, authorizationCode.AuthorizationDetails)
+	if err != nil {
+		return nil, err
+	}
 
-	// Delete the authorization code
-	s.db.Unscoped().Delete(&authorizationCode)
+	// Tie the tokens to this login, the user authenticated when the code was granted
+	session, err := s.startSession(authorizationCode.User, authorizationCode.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.setSession(accessToken, refreshToken, session); err != nil {
+		return nil, err
+	}
 
 	// Create response
 	accessTokenResponse, err := NewAccessTokenResponse(
 		accessToken,
 		refreshToken,
-		s.cnf.Oauth.AccessTokenLifetime,
+		s.getAccessTokenLifetime(client, "authorization_code"),
 		tokentypes.Bearer,
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	// Include an ID token if the openid scope has been granted
+	if err := s.addIDToken(
+		accessTokenResponse,
+		authorizationCode.Client,
+		authorizationCode.User,
+		authorizationCode.Scope,
+		session,
+		authorizationCode.CreatedAt,
+		authorizationCode.Nonce,
+	); err != nil {
+		return nil, err
+	}
+
+	// Include a device secret if enabled
+	if err := s.addDeviceSecret(
+		accessTokenResponse,
+		authorizationCode.Client,
+		authorizationCode.User,
+		authorizationCode.Scope,
+	); err != nil {
+		return nil, err
+	}
+
 	return accessTokenResponse, nil
 }
@@ -1,6 +1,7 @@
 package oauth_test
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -30,11 +31,12 @@ func (suite *OauthTestSuite) TestAuthorizationCodeGrantEmptyNotFound() {
 	suite.router.ServeHTTP(w, r)
 
 	// Check the response
-	testutil.TestResponseForError(
+	testutil.TestResponseForOauthError(
 		suite.T(),
 		w,
+		"invalid_grant",
 		oauth.ErrAuthorizationCodeNotFound.Error(),
-		404,
+		400,
 	)
 }
 
@@ -53,11 +55,12 @@ func (suite *OauthTestSuite) TestAuthorizationCodeGrantBogusNotFound() {
 	suite.router.ServeHTTP(w, r)
 
 	// Check the response
-	testutil.TestResponseForError(
+	testutil.TestResponseForOauthError(
 		suite.T(),
 		w,
+		"invalid_grant",
 		oauth.ErrAuthorizationCodeNotFound.Error(),
-		404,
+		400,
 	)
 }
 
@@ -92,9 +95,10 @@ func (suite *OauthTestSuite) TestAuthorizationCodeGrantExpired() {
 	suite.router.ServeHTTP(w, r)
 
 	// Check the response
-	testutil.TestResponseForError(
+	testutil.TestResponseForOauthError(
 		suite.T(),
 		w,
+		"invalid_grant",
 		oauth.ErrAuthorizationCodeExpired.Error(),
 		400,
 	)
@@ -131,9 +135,10 @@ func (suite *OauthTestSuite) TestAuthorizationCodeGrantInvalidRedirectURI() {
 	suite.router.ServeHTTP(w, r)
 
 	// Check the response
-	testutil.TestResponseForError(
+	testutil.TestResponseForOauthError(
 		suite.T(),
 		w,
+		"invalid_grant",
 		oauth.ErrInvalidRedirectURI.Error(),
 		400,
 	)
@@ -176,18 +181,103 @@ func (suite *OauthTestSuite) TestAuthorizationCodeGrant() {
 	assert.False(suite.T(), models.OauthRefreshTokenPreload(suite.db).
 		Last(refreshToken).RecordNotFound())
 
+	// Only hashes of the tokens are stored
+	resp := suite.decodeAccessTokenResponse(w)
+	assert.Equal(suite.T(), accessToken.TokenHash, models.HashToken(resp.AccessToken))
+	assert.Equal(suite.T(), refreshToken.TokenHash, models.HashToken(resp.RefreshToken))
+
 	// Check the response
 	expected := &oauth.AccessTokenResponse{
 		UserID:       accessToken.UserID.String,
-		AccessToken:  accessToken.Token,
+		AccessToken:  resp.AccessToken,
 		ExpiresIn:    3600,
 		TokenType:    tokentypes.Bearer,
 		Scope:        "read_write",
-		RefreshToken: refreshToken.Token,
+		RefreshToken: resp.RefreshToken,
 	}
 	testutil.TestResponseObject(suite.T(), w, expected, 200)
 
-	// The authorization code should get deleted after use
-	assert.True(suite.T(), suite.db.Unscoped().
-		First(new(models.OauthAuthorizationCode)).RecordNotFound())
+	// The authorization code is kept to detect replays, but cannot be used again
+	authorizationCode := new(models.OauthAuthorizationCode)
+	if assert.NoError(suite.T(), suite.db.Unscoped().First(authorizationCode).Error) {
+		assert.NotNil(suite.T(), authorizationCode.ExchangedAt)
+	}
+}
+
+func (suite *OauthTestSuite) TestAuthorizationCodeGrantReplay() {
+	for _, revokeOnReplay := range []bool{true, false} {
+		suite.cnf.Oauth.DisableCodeReplayRevocation = !revokeOnReplay
+
+		// Insert a test authorization code
+		code := uuid.New()
+		err := suite.db.Create(&models.OauthAuthorizationCode{
+			MyGormModel: models.MyGormModel{
+				ID:        uuid.New(),
+				CreatedAt: time.Now().UTC(),
+			},
+			Code:        code,
+			ExpiresAt:   time.Now().UTC().Add(+10 * time.Second),
+			Client:      suite.clients[0],
+			User:        suite.users[0],
+			RedirectURI: util.StringOrNull("https://www.example.com"),
+			Scope:       "read_write",
+		}).Error
+		assert.NoError(suite.T(), err, "Inserting test data failed")
+
+		// Exchange the code
+		w := suite.exchangeAuthorizationCode(code)
+		assert.Equal(suite.T(), 200, w.Code)
+		resp := new(oauth.AccessTokenResponse)
+		assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), resp))
+
+		// The user also logged in to the client another way
+		otherAccessToken, otherRefreshToken, err := suite.service.Login(suite.clients[0], suite.users[0], "read_write")
+		assert.NoError(suite.T(), err, "Inserting test data failed")
+
+		// Replay the code
+		w = suite.exchangeAuthorizationCode(code)
+		if revokeOnReplay {
+			testutil.TestResponseForOauthError(
+				suite.T(),
+				w,
+				"invalid_grant",
+				oauth.ErrAuthorizationCodeUsed.Error(),
+				400,
+			)
+		} else {
+			testutil.TestResponseForOauthError(
+				suite.T(),
+				w,
+				"invalid_grant",
+				oauth.ErrAuthorizationCodeNotFound.Error(),
+				400,
+			)
+		}
+
+		// Tokens from the first exchange are revoked unless disabled
+		_, err = suite.service.Authenticate(resp.AccessToken)
+		_, refreshErr := suite.service.GetValidRefreshToken(resp.RefreshToken, suite.clients[0])
+		if revokeOnReplay {
+			assert.Equal(suite.T(), oauth.ErrAccessTokenNotFound, err)
+			assert.Equal(suite.T(), oauth.ErrRefreshTokenNotFound, refreshErr)
+		} else {
+			assert.NoError(suite.T(), err)
+			assert.NoError(suite.T(), refreshErr)
+		}
+
+		// Tokens which do not descend from the code are never revoked
+		_, err = suite.service.Authenticate(otherAccessToken.Token)
+		assert.NoError(suite.T(), err)
+		_, err = suite.service.GetValidRefreshToken(otherRefreshToken.Token, suite.clients[0])
+		assert.NoError(suite.T(), err)
+	}
+	suite.cnf.Oauth.DisableCodeReplayRevocation = false
+}
+
+func (suite *OauthTestSuite) exchangeAuthorizationCode(code string) *httptest.ResponseRecorder {
+	return suite.tokenRequest(url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {"https://www.example.com"},
+	}, nil)
 }
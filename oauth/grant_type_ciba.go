@@ -0,0 +1,67 @@
+package oauth
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth/tokentypes"
+)
+
+func (s *Service) cibaGrant(r *http.Request, client *models.OauthClient) (*AccessTokenResponse, error) {
+	// Fetch the request, unless the client should keep polling
+	request, err := s.pollBackchannelRequest(r.Form.Get("auth_req_id"), client)
+	if err != nil {
+		return nil, err
+	}
+
+	// Requests can only be exchanged once, only one of concurrent
+	// exchanges deletes the request
+	result := s.db.Unscoped().Delete(request)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrAuthReqIDNotFound
+	}
+
+	// Log in the user
+	accessToken, refreshToken, err := s.login(
+		request.Client,
+		request.User,
+		request.Scope,
+		CIBAGrantType,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.setRefreshTokenGrantType(refreshToken, CIBAGrantType); err != nil {
+		return nil, err
+	}
+
+	// Create response
+	accessTokenResponse, err := NewAccessTokenResponse(
+		accessToken,
+		refreshToken,
+		s.getAccessTokenLifetime(client, CIBAGrantType),
+		tokentypes.Bearer,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// The openid scope is always granted, so include an ID token
+	if err := s.addIDToken(
+		accessTokenResponse,
+		request.Client,
+		request.User,
+		request.Scope,
+		nil,
+		time.Time{},
+		"",
+	); err != nil {
+		return nil, err
+	}
+
+	return accessTokenResponse, nil
+}
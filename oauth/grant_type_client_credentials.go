@@ -9,27 +9,55 @@ import (
 
 func (s *Service) clientCredentialsGrant(r *http.Request, client *models.OauthClient) (*AccessTokenResponse, error) {
 	// Get the scope string
-	scope, err := s.GetScope(r.Form.Get("scope"))
+	scope, err := s.getClientScope(client, r.Form.Get("scope"))
+	if err != nil {
+		return nil, err
+	}
+
+	// The scope may be restricted to an audience other than requested
+	if err := s.checkRequestedAudience(r.Form.Get("audience"), scope); err != nil {
+		return nil, err
+	}
+
+	// Tokens can be requested for specific resource servers
+	resources, err := s.getRequestedResources(r, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the requested authorization details
+	authorizationDetails, err := s.getAuthorizationDetails(r.Form.Get("authorization_details"))
 	if err != nil {
 		return nil, err
 	}
 
 	// Create a new access token
+	lifetime := s.getAccessTokenLifetime(client, "client_credentials")
 	accessToken, err := s.GrantAccessToken(
 		client,
-		nil,                             // empty user
-		s.cnf.Oauth.AccessTokenLifetime, // expires in
+		nil,      // empty user
+		lifetime, // expires in
 		scope,
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	// Issue the access token for the requested resource servers only
+	if err := s.setResourceAudience(accessToken, resources); err != nil {
+		return nil, err
+	}
+
+	// Record the authorization details granted to the client
+	if err := s.setAuthorizationDetails(accessToken, nil, authorizationDetails); err != nil {
+		return nil, err
+	}
+
 	// Create response
 	accessTokenResponse, err := NewAccessTokenResponse(
 		accessToken,
 		nil, // refresh token
-		s.cnf.Oauth.AccessTokenLifetime,
+		lifetime,
 		tokentypes.Bearer,
 	)
 	if err != nil {
@@ -31,9 +31,13 @@ func (suite *OauthTestSuite) TestClientCredentialsGrant() {
 	assert.False(suite.T(), models.OauthAccessTokenPreload(suite.db).
 		Last(accessToken).RecordNotFound())
 
+	// Only the hash of the token is stored
+	resp := suite.decodeAccessTokenResponse(w)
+	assert.Equal(suite.T(), accessToken.TokenHash, models.HashToken(resp.AccessToken))
+
 	// Check the response
 	expected := &oauth.AccessTokenResponse{
-		AccessToken: accessToken.Token,
+		AccessToken: resp.AccessToken,
 		ExpiresIn:   3600,
 		TokenType:   tokentypes.Bearer,
 		Scope:       "read_write",
@@ -44,3 +48,52 @@ func (suite *OauthTestSuite) TestClientCredentialsGrant() {
 	assert.True(suite.T(), models.OauthRefreshTokenPreload(suite.db).
 		First(new(models.OauthRefreshToken)).RecordNotFound())
 }
+
+func (suite *OauthTestSuite) TestClientCredentialsGrantScopeAudience() {
+	defer suite.allowClientScope(suite.clients[0], "test_payments:write")()
+
+	err := oauth.SeedDefaultScopes(suite.db, []oauth.ScopeDef{
+		{Scope: "test_payments:write", Audience: "payments"},
+	})
+	assert.NoError(suite.T(), err)
+	defer suite.db.Unscoped().Where("scope LIKE ?", "test_%").Delete(new(models.OauthScope))
+
+	testCases := []struct {
+		audience string
+		status   int
+	}{
+		{"", 200},         // audience is forced by the scope
+		{"payments", 200}, // matching audience
+		{"orders", 400},   // mismatched audience
+	}
+
+	for _, testCase := range testCases {
+		r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+		assert.NoError(suite.T(), err, "Request setup should not get an error")
+		r.SetBasicAuth("test_client_1", "test_secret")
+		r.PostForm = url.Values{
+			"grant_type": {"client_credentials"},
+			"scope":      {"read test_payments:write"},
+			"audience":   {testCase.audience},
+		}
+
+		w := httptest.NewRecorder()
+		suite.router.ServeHTTP(w, r)
+
+		if testCase.status != 200 {
+			testutil.TestResponseForOauthError(
+				suite.T(),
+				w,
+				"invalid_scope",
+				oauth.ErrScopeAudienceMismatch.Error(),
+				testCase.status,
+			)
+			continue
+		}
+
+		assert.Equal(suite.T(), 200, w.Code)
+		accessToken := new(models.OauthAccessToken)
+		assert.False(suite.T(), suite.db.Order("created_at desc").First(accessToken).RecordNotFound())
+		assert.Equal(suite.T(), "payments", accessToken.Audience)
+	}
+}
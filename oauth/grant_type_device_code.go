@@ -0,0 +1,67 @@
+package oauth
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth/tokentypes"
+)
+
+func (s *Service) deviceCodeGrant(r *http.Request, client *models.OauthClient) (*AccessTokenResponse, error) {
+	// Fetch the device code, unless the device should keep polling
+	deviceCode, err := s.pollDeviceCode(r.Form.Get("device_code"), client)
+	if err != nil {
+		return nil, err
+	}
+
+	// Device codes can only be exchanged once, only one of concurrent
+	// exchanges deletes the code
+	result := s.db.Unscoped().Delete(deviceCode)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrDeviceCodeNotFound
+	}
+
+	// Log in the user
+	accessToken, refreshToken, err := s.login(
+		deviceCode.Client,
+		deviceCode.User,
+		deviceCode.Scope,
+		DeviceCodeGrantType,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.setRefreshTokenGrantType(refreshToken, DeviceCodeGrantType); err != nil {
+		return nil, err
+	}
+
+	// Create response
+	accessTokenResponse, err := NewAccessTokenResponse(
+		accessToken,
+		refreshToken,
+		s.getAccessTokenLifetime(client, DeviceCodeGrantType),
+		tokentypes.Bearer,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Include an ID token if the openid scope has been granted
+	if err := s.addIDToken(
+		accessTokenResponse,
+		deviceCode.Client,
+		deviceCode.User,
+		deviceCode.Scope,
+		nil,
+		time.Time{},
+		"",
+	); err != nil {
+		return nil, err
+	}
+
+	return accessTokenResponse, nil
+}
@@ -0,0 +1,63 @@
+package oauth
+
+import (
+	"net/http"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth/tokentypes"
+)
+
+func (s *Service) deviceSecretGrant(r *http.Request, client *models.OauthClient) (*AccessTokenResponse, error) {
+	// Fetch the device secret
+	deviceSecret, err := s.getValidDeviceSecret(r.Form.Get("device_secret"), client)
+	if err != nil {
+		return nil, err
+	}
+
+	// Begin a transaction
+	tx := s.db.Begin()
+	txService := s.withTx(tx)
+
+	// The device secret is replaced by a new one on every exchange
+	rotated, err := txService.rotateDeviceSecret(deviceSecret)
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+
+	// Log in the user
+	accessToken, refreshToken, err := txService.login(
+		deviceSecret.Client,
+		deviceSecret.User,
+		deviceSecret.Scope,
+		"device_secret",
+	)
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+	if err := txService.setRefreshTokenGrantType(refreshToken, "device_secret"); err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+
+	// Create response
+	accessTokenResponse, err := NewAccessTokenResponse(
+		accessToken,
+		refreshToken,
+		s.getAccessTokenLifetime(client, "device_secret"),
+		tokentypes.Bearer,
+	)
+	if err != nil {
+		return nil, err
+	}
+	accessTokenResponse.DeviceSecret = rotated.Secret
+
+	return accessTokenResponse, nil
+}
@@ -0,0 +1,158 @@
+package oauth_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/test-util"
+	"github.com/RichardKnop/go-oauth2-server/util/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestAddAssertionKeyIncompatible() {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(suite.T(), err)
+	publicKey := suite.encodePublicKey(&privateKey.PublicKey)
+
+	_, err = suite.service.AddAssertionKey(suite.clients[0], "https://backend.example.com", jwt.ES256, publicKey)
+	assert.Equal(suite.T(), jwt.ErrIncompatiblePublicKey, err)
+
+	_, err = suite.service.AddAssertionKey(suite.clients[0], "https://backend.example.com", jwt.RS256, "bogus")
+	assert.Equal(suite.T(), jwt.ErrInvalidPublicKey, err)
+}
+
+func (suite *OauthTestSuite) TestJWTBearerGrant() {
+	signer := suite.registerAssertionKey("https://backend.example.com")
+
+	// The backend can get a token for the client itself or for one of the users
+	for _, testCase := range []struct {
+		subject string
+		userID  string
+	}{
+		{"test_client_1", ""},
+		{"test@user", string(suite.users[1].ID)},
+	} {
+		assertion := suite.signAssertion(signer, jwt.Claims{
+			"iss": "https://backend.example.com",
+			"sub": testCase.subject,
+			"aud": suite.cnf.JWT.Issuer,
+			"exp": time.Now().Add(time.Minute).Unix(),
+		})
+
+		resp := suite.decodeAccessTokenResponse(suite.exchangeAssertion(assertion))
+		assert.Equal(suite.T(), testCase.userID, resp.UserID)
+		assert.Equal(suite.T(), "read", resp.Scope)
+		assert.NotEmpty(suite.T(), resp.AccessToken)
+		assert.Empty(suite.T(), resp.RefreshToken)
+	}
+}
+
+func (suite *OauthTestSuite) TestJWTBearerGrantInvalidAssertion() {
+	signer := suite.registerAssertionKey("https://backend.example.com")
+
+	// A key registered for the client but signing for another issuer is not trusted
+	otherIssuer := suite.signAssertion(signer, jwt.Claims{
+		"iss": "https://other.example.com",
+		"sub": "test_client_1",
+		"aud": suite.cnf.JWT.Issuer,
+		"exp": time.Now().Add(time.Minute).Unix(),
+	})
+
+	// Nor is a key which has not been registered
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(suite.T(), err)
+	otherSigner, err := jwt.NewES256("", otherKey)
+	assert.NoError(suite.T(), err)
+	unregistered := suite.signAssertion(otherSigner, jwt.Claims{
+		"iss": "https://backend.example.com",
+		"sub": "test_client_1",
+		"aud": suite.cnf.JWT.Issuer,
+		"exp": time.Now().Add(time.Minute).Unix(),
+	})
+
+	for _, testCase := range []struct {
+		assertion string
+		err       error
+	}{
+		{"bogus", oauth.ErrInvalidAssertion},
+		{otherIssuer, oauth.ErrInvalidAssertion},
+		{unregistered, oauth.ErrInvalidAssertion},
+		{suite.signAssertion(signer, jwt.Claims{
+			"iss": "https://backend.example.com",
+			"sub": "test_client_1",
+			"aud": suite.cnf.JWT.Issuer,
+		}), oauth.ErrInvalidAssertion},
+		{suite.signAssertion(signer, jwt.Claims{
+			"iss": "https://backend.example.com",
+			"sub": "test_client_1",
+			"aud": suite.cnf.JWT.Issuer,
+			"exp": time.Now().Add(-time.Minute).Unix(),
+		}), oauth.ErrAssertionExpired},
+		{suite.signAssertion(signer, jwt.Claims{
+			"iss": "https://backend.example.com",
+			"sub": "test_client_1",
+			"aud": []string{"https://other.example.com"},
+			"exp": time.Now().Add(time.Minute).Unix(),
+		}), oauth.ErrAssertionAudienceMismatch},
+		{suite.signAssertion(signer, jwt.Claims{
+			"iss": "https://backend.example.com",
+			"sub": "bogus@user",
+			"aud": suite.cnf.JWT.Issuer,
+			"exp": time.Now().Add(time.Minute).Unix(),
+		}), oauth.ErrAssertionSubjectNotFound},
+	} {
+		testutil.TestResponseForOauthError(
+			suite.T(),
+			suite.exchangeAssertion(testCase.assertion),
+			"invalid_grant",
+			testCase.err.Error(),
+			400,
+		)
+	}
+}
+
+// registerAssertionKey registers a new RSA key of the issuer for the
+// first test client and returns the private key signing its assertions
+func (suite *OauthTestSuite) registerAssertionKey(issuer string) jwt.Key {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(suite.T(), err)
+	_, err = suite.service.AddAssertionKey(
+		suite.clients[0],
+		issuer,
+		jwt.RS256,
+		suite.encodePublicKey(&privateKey.PublicKey),
+	)
+	assert.NoError(suite.T(), err)
+
+	signer, err := jwt.NewRS256("", privateKey)
+	assert.NoError(suite.T(), err)
+	return signer
+}
+
+func (suite *OauthTestSuite) encodePublicKey(publicKey interface{}) string {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	assert.NoError(suite.T(), err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func (suite *OauthTestSuite) signAssertion(signer jwt.Signer, claims jwt.Claims) string {
+	assertion, err := jwt.Sign(claims, signer)
+	assert.NoError(suite.T(), err)
+	return assertion
+}
+
+func (suite *OauthTestSuite) exchangeAssertion(assertion string) *httptest.ResponseRecorder {
+	return suite.tokenRequest(url.Values{
+		"grant_type": {oauth.JWTBearerGrantType},
+		"assertion":  {assertion},
+		"scope":      {"read"},
+	}, nil)
+}
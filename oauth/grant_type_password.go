@@ -3,6 +3,7 @@ package oauth
 import (
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/RichardKnop/go-oauth2-server/models"
 	"github.com/RichardKnop/go-oauth2-server/oauth/tokentypes"
@@ -11,38 +12,88 @@ import (
 var (
 	// ErrInvalidUsernameOrPassword ...
 	ErrInvalidUsernameOrPassword = errors.New("Invalid username or password")
+	// ErrPasswordLoginNotAvailable ...
+	ErrPasswordLoginNotAvailable = errors.New("Password login not available for this account")
 )
 
 func (s *Service) passwordGrant(r *http.Request, client *models.OauthClient) (*AccessTokenResponse, error) {
 	// Get the scope string
-	scope, err := s.GetScope(r.Form.Get("scope"))
+	scope, err := s.getClientScope(client, r.Form.Get("scope"))
 	if err != nil {
 		return nil, err
 	}
 
-	// Authenticate the user
-	user, err := s.AuthUser(r.Form.Get("username"), r.Form.Get("password"))
+	// The scope may be restricted to an audience other than requested
+	if err := s.checkRequestedAudience(r.Form.Get("audience"), scope); err != nil {
+		return nil, err
+	}
+
+	// Tokens can be requested for specific resource servers
+	resources, err := s.getRequestedResources(r, scope)
 	if err != nil {
-		// For security reasons, return a general error message
-		return nil, ErrInvalidUsernameOrPassword
+		return nil, err
+	}
+
+	// Authenticate the user and any second factor required
+	user, acr, err := s.AuthLogin(
+		r.Form.Get("username"),
+		r.Form.Get("password"),
+		r.Form.Get("acr_values"),
+		r.Form.Get("otp"),
+		RemoteIP(r),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Users may have to verify their email address first
+	if err := s.checkEmailVerified(user); err != nil {
+		return nil, err
 	}
 
 	// Log in the user
-	accessToken, refreshToken, err := s.Login(client, user, scope)
+	accessToken, refreshToken, err := s.login(client, user, scope, "password")
 	if err != nil {
 		return nil, err
 	}
+	if err := s.setRefreshTokenGrantType(refreshToken, "password"); err != nil {
+		return nil, err
+	}
+
+	// Issue the access token for the requested resource servers only
+	if err := s.setResourceAudience(accessToken, resources); err != nil {
+		return nil, err
+	}
+
+	// Record the authentication context on the access token
+	if err := s.setAcr(accessToken, acr); err != nil {
+		return nil, err
+	}
+
+	// Tie the tokens to this login
+	session, err := s.startSession(user, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.setSession(accessToken, refreshToken, session); err != nil {
+		return nil, err
+	}
 
 	// Create response
 	accessTokenResponse, err := NewAccessTokenResponse(
 		accessToken,
 		refreshToken,
-		s.cnf.Oauth.AccessTokenLifetime,
+		s.getAccessTokenLifetime(client, "password"),
 		tokentypes.Bearer,
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	// Include a device secret if enabled
+	if err := s.addDeviceSecret(accessTokenResponse, client, user, scope); err != nil {
+		return nil, err
+	}
+
 	return accessTokenResponse, nil
 }
@@ -4,12 +4,15 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"time"
 
 	"github.com/RichardKnop/go-oauth2-server/models"
 	"github.com/RichardKnop/go-oauth2-server/oauth"
 	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
 	"github.com/RichardKnop/go-oauth2-server/oauth/tokentypes"
 	"github.com/RichardKnop/go-oauth2-server/test-util"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/RichardKnop/uuid"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -36,14 +39,19 @@ func (suite *OauthTestSuite) TestPasswordGrant() {
 	assert.False(suite.T(), models.OauthRefreshTokenPreload(suite.db).
 		Last(refreshToken).RecordNotFound())
 
+	// Only hashes of the tokens are stored
+	resp := suite.decodeAccessTokenResponse(w)
+	assert.Equal(suite.T(), accessToken.TokenHash, models.HashToken(resp.AccessToken))
+	assert.Equal(suite.T(), refreshToken.TokenHash, models.HashToken(resp.RefreshToken))
+
 	// Check the response
 	expected := &oauth.AccessTokenResponse{
 		UserID:       accessToken.UserID.String,
-		AccessToken:  accessToken.Token,
+		AccessToken:  resp.AccessToken,
 		ExpiresIn:    3600,
 		TokenType:    tokentypes.Bearer,
 		Scope:        "read_write",
-		RefreshToken: refreshToken.Token,
+		RefreshToken: resp.RefreshToken,
 	}
 	testutil.TestResponseObject(suite.T(), w, expected, 200)
 }
@@ -67,12 +75,59 @@ func (suite *OauthTestSuite) TestPasswordGrantWithRoleRestriction() {
 	suite.router.ServeHTTP(w, r)
 
 	// Check the response
-	testutil.TestResponseForError(
+	testutil.TestResponseForOauthError(
 		suite.T(),
 		w,
+		"invalid_grant",
 		oauth.ErrInvalidUsernameOrPassword.Error(),
-		401,
+		400,
 	)
 
 	suite.service.RestrictToRoles(roles.Superuser, roles.User)
 }
+
+func (suite *OauthTestSuite) TestPasswordGrantUserWithoutPassword() {
+	// Insert test users without a usable password
+	for username, password := range map[string]string{
+		"test@user_nopass":      "",
+		"test@user_placeholder": "!",
+	} {
+		err := suite.db.Create(&models.OauthUser{
+			MyGormModel: models.MyGormModel{
+				ID:        uuid.New(),
+				CreatedAt: time.Now().UTC(),
+			},
+			RoleID:   util.StringOrNull(roles.User),
+			Username: username,
+			Password: util.StringOrNull(password),
+		}).Error
+		assert.NoError(suite.T(), err, "Inserting test data failed")
+
+		// Prepare a request
+		r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+		assert.NoError(suite.T(), err, "Request setup should not get an error")
+		r.SetBasicAuth("test_client_1", "test_secret")
+		r.PostForm = url.Values{
+			"grant_type": {"password"},
+			"username":   {username},
+			"password":   {"test_password"},
+			"scope":      {"read_write"},
+		}
+
+		// Serve the request
+		w := httptest.NewRecorder()
+		suite.router.ServeHTTP(w, r)
+
+		// Check the response
+		testutil.TestResponseForOauthError(
+			suite.T(),
+			w,
+			"invalid_grant",
+			oauth.ErrPasswordLoginNotAvailable.Error(),
+			400,
+		)
+	}
+
+	// No tokens should have been issued
+	assert.True(suite.T(), suite.db.First(new(models.OauthAccessToken)).RecordNotFound())
+}
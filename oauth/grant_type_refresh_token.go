@@ -2,6 +2,7 @@ package oauth
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/RichardKnop/go-oauth2-server/models"
 	"github.com/RichardKnop/go-oauth2-server/oauth/tokentypes"
@@ -9,37 +10,138 @@ import (
 
 func (s *Service) refreshTokenGrant(r *http.Request, client *models.OauthClient) (*AccessTokenResponse, error) {
 	// Fetch the refresh token
-	theRefreshToken, err := s.GetValidRefreshToken(r.Form.Get("refresh_token"), client)
+	theRefreshToken, err := s.GetValidRefreshToken(s.getRefreshTokenParam(r), client)
+	if err == ErrRefreshTokenReused {
+		return nil, s.detectRefreshTokenReuse(s.getRefreshTokenParam(r), client)
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	// A refresh token bound to a DPoP key is only usable by the holder of the key
+	if err := checkRefreshTokenDPoPBinding(r, theRefreshToken); err != nil {
+		return nil, err
+	}
+
+	// The grant type which issued the refresh token must still be enabled
+	if err := s.checkRefreshTokenGrantType(theRefreshToken); err != nil {
+		return nil, err
+	}
+
 	// Get the scope
 	scope, err := s.getRefreshTokenScope(theRefreshToken, r.Form.Get("scope"))
 	if err != nil {
 		return nil, err
 	}
 
-	// Log in the user
-	accessToken, refreshToken, err := s.Login(
-		theRefreshToken.Client,
-		theRefreshToken.User,
-		scope,
-	)
+	// The scope may be restricted to an audience other than requested
+	if err := s.checkRequestedAudience(r.Form.Get("audience"), scope); err != nil {
+		return nil, err
+	}
+
+	// Tokens can be requested for specific resource servers
+	resources, err := s.getRequestedResources(r, scope)
 	if err != nil {
 		return nil, err
 	}
 
+	// The refresh token can only be used once, it is used up in the same
+	// transaction issuing the new tokens so a failed exchange can be retried
+	tx := s.db.Begin()
+	rotated, err := rotateRefreshToken(tx, theRefreshToken)
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+	if !rotated {
+		tx.Rollback() // rollback the transaction
+		// It has been used concurrently, so the whole family is revoked
+		return nil, s.revokeRefreshTokenFamily(refreshTokenFamily(theRefreshToken))
+	}
+	accessToken, refreshToken, session, err := s.withTx(tx).issueRefreshedTokens(theRefreshToken, scope, resources)
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+
 	// Create response
 	accessTokenResponse, err := NewAccessTokenResponse(
 		accessToken,
 		refreshToken,
-		s.cnf.Oauth.AccessTokenLifetime,
+		s.getAccessTokenLifetime(client, theRefreshToken.GrantType),
 		tokentypes.Bearer,
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	// Re-issue the ID token if the openid scope is still granted
+	if err := s.addIDToken(
+		accessTokenResponse,
+		theRefreshToken.Client,
+		theRefreshToken.User,
+		scope,
+		session,
+		time.Time{},
+		"",
+	); err != nil {
+		return nil, err
+	}
+
 	return accessTokenResponse, nil
 }
+
+// issueRefreshedTokens issues the tokens a refresh token is exchanged for,
+// they keep the authorization details, lineage and login of the refresh token
+func (s *Service) issueRefreshedTokens(theRefreshToken *models.OauthRefreshToken, scope, resources string) (*models.OauthAccessToken, *models.OauthRefreshToken, *models.OauthSession, error) {
+	// Log in the user, the new tokens have the lifetimes of the grant type
+	// originally issuing the refresh token and it keeps the original scope
+	accessToken, refreshToken, err := s.grantTokens(
+		theRefreshToken.Client,
+		theRefreshToken.User,
+		scope,
+		theRefreshToken.GrantType,
+		s.offlineAccessGranted(theRefreshToken.Scope),
+	)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Issue the access token for the requested resource servers only
+	if err := s.setResourceAudience(accessToken, resources); err != nil {
+		return nil, nil, nil, err
+	}
+
+	// The new tokens keep the authorization details of the refresh token
+	err = s.setAuthorizationDetails(accessToken, refreshToken, theRefreshToken.AuthorizationDetails)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// The new refresh token replaces the rotated one
+	if refreshToken != nil {
+		if err := s.inheritRefreshTokenFamily(refreshToken, theRefreshToken); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	// The new tokens descend from the refresh token
+	err = s.setLineage(accessToken, refreshToken, refreshTokenLineage(theRefreshToken), theRefreshToken.ID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// New tokens belong to the same login as the refresh token
+	session := s.findSession(theRefreshToken.SessionID)
+	if err := s.setSession(accessToken, refreshToken, session); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return accessToken, refreshToken, session, nil
+}
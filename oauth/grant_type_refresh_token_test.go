@@ -29,11 +29,12 @@ func (suite *OauthTestSuite) TestRefreshTokenGrantEmptyNotFound() {
 	suite.router.ServeHTTP(w, r)
 
 	// Check the response
-	testutil.TestResponseForError(
+	testutil.TestResponseForOauthError(
 		suite.T(),
 		w,
+		"invalid_grant",
 		oauth.ErrRefreshTokenNotFound.Error(),
-		404,
+		400,
 	)
 }
 
@@ -52,11 +53,12 @@ func (suite *OauthTestSuite) TestRefreshTokenGrantBogusNotFound() {
 	suite.router.ServeHTTP(w, r)
 
 	// Check the response
-	testutil.TestResponseForError(
+	testutil.TestResponseForOauthError(
 		suite.T(),
 		w,
+		"invalid_grant",
 		oauth.ErrRefreshTokenNotFound.Error(),
-		404,
+		400,
 	)
 }
 
@@ -90,9 +92,10 @@ func (suite *OauthTestSuite) TestRefreshTokenGrantExipired() {
 	suite.router.ServeHTTP(w, r)
 
 	// Check the response
-	testutil.TestResponseForError(
+	testutil.TestResponseForOauthError(
 		suite.T(),
 		w,
+		"invalid_grant",
 		oauth.ErrRefreshTokenExpired.Error(),
 		400,
 	)
@@ -127,13 +130,115 @@ func (suite *OauthTestSuite) TestRefreshTokenGrantScopeCannotBeGreater() {
 	w := httptest.NewRecorder()
 	suite.router.ServeHTTP(w, r)
 
-	// Check the response
-	testutil.TestResponseForError(
-		suite.T(),
-		w,
-		oauth.ErrRequestedScopeCannotBeGreater.Error(),
-		400,
-	)
+	// Check the response names the scope not originally granted
+	testutil.TestResponseObject(suite.T(), w, map[string]string{
+		"error":             "invalid_scope",
+		"error_description": "Requested scope cannot be greater, not originally granted: read",
+	}, 400)
+}
+
+func (suite *OauthTestSuite) TestRefreshTokenGrantScopeSubset() {
+	// Insert a test refresh token
+	err := suite.db.Create(&models.OauthRefreshToken{
+		MyGormModel: models.MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		Token:     "test_token",
+		ExpiresAt: time.Now().UTC().Add(+10 * time.Second),
+		Client:    suite.clients[0],
+		User:      suite.users[0],
+		Scope:     "read read_write",
+	}).Error
+	assert.NoError(suite.T(), err, "Inserting test data failed")
+
+	// Refresh tokens are rotated, so keep using the newest
+	token := "test_token"
+	for _, testCase := range []struct {
+		requestedScope string
+		expectedScope  string
+	}{
+		{"read", "read"},                       // subset
+		{"read_write read", "read_write read"}, // exact match
+		{"", "read read_write"},                // original scope
+	} {
+		r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+		assert.NoError(suite.T(), err, "Request setup should not get an error")
+		r.SetBasicAuth("test_client_1", "test_secret")
+		r.PostForm = url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {token},
+			"scope":         {testCase.requestedScope},
+		}
+
+		w := httptest.NewRecorder()
+		suite.router.ServeHTTP(w, r)
+
+		resp := suite.decodeAccessTokenResponse(w)
+		assert.Equal(suite.T(), testCase.expectedScope, resp.Scope)
+		token = resp.RefreshToken
+	}
+
+	// Every scope not originally granted is named, even if it does not exist
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
+	r.PostForm = url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {token},
+		"scope":         {"read openid bogus"},
+	}
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+
+	testutil.TestResponseObject(suite.T(), w, map[string]string{
+		"error":             "invalid_scope",
+		"error_description": "Requested scope cannot be greater, not originally granted: openid bogus",
+	}, 400)
+}
+
+func (suite *OauthTestSuite) TestRefreshTokenGrantScopeNarrowing() {
+	_, refreshToken, err := suite.service.Login(suite.clients[0], suite.users[0], "read read_write")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+
+	// The access token is narrowed to the requested subset
+	w := suite.tokenRequest(url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken.Token},
+		"scope":         {"read"},
+	}, nil)
+	resp := suite.decodeAccessTokenResponse(w)
+	assert.Equal(suite.T(), "read", resp.Scope)
+
+	// But the rotated refresh token keeps the original scope (RFC 6749 §6)
+	rotated := new(models.OauthRefreshToken)
+	err = suite.db.Where("token = ?", models.HashToken(resp.RefreshToken)).First(rotated).Error
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), "read read_write", rotated.Scope)
+	}
+
+	// So the original scope can be requested again
+	w = suite.tokenRequest(url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {resp.RefreshToken},
+		"scope":         {"read_write"},
+	}, nil)
+	resp = suite.decodeAccessTokenResponse(w)
+	assert.Equal(suite.T(), "read_write", resp.Scope)
+
+	// While a broader scope is rejected
+	w = suite.tokenRequest(url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {resp.RefreshToken},
+		"scope":         {"read read_write openid"},
+	}, nil)
+	testutil.TestResponseObject(suite.T(), w, map[string]string{
+		"error":             "invalid_scope",
+		"error_description": "Requested scope cannot be greater, not originally granted: openid",
+	}, 400)
 }
 
 func (suite *OauthTestSuite) TestRefreshTokenGrantDefaultsToOriginalScope() {
@@ -168,15 +273,23 @@ func (suite *OauthTestSuite) TestRefreshTokenGrantDefaultsToOriginalScope() {
 	accessToken := new(models.OauthAccessToken)
 	assert.False(suite.T(), models.OauthAccessTokenPreload(suite.db).
 		Last(accessToken).RecordNotFound())
+	refreshToken := new(models.OauthRefreshToken)
+	assert.False(suite.T(), models.OauthRefreshTokenPreload(suite.db).
+		Where("used_at IS NULL").First(refreshToken).RecordNotFound())
+
+	// Only hashes of the tokens are stored
+	resp := suite.decodeAccessTokenResponse(w)
+	assert.Equal(suite.T(), accessToken.TokenHash, models.HashToken(resp.AccessToken))
+	assert.Equal(suite.T(), refreshToken.TokenHash, models.HashToken(resp.RefreshToken))
 
 	// Check the response body
 	expected := &oauth.AccessTokenResponse{
 		UserID:       accessToken.UserID.String,
-		AccessToken:  accessToken.Token,
+		AccessToken:  resp.AccessToken,
 		ExpiresIn:    3600,
 		TokenType:    tokentypes.Bearer,
 		Scope:        "read_write",
-		RefreshToken: "test_token",
+		RefreshToken: resp.RefreshToken,
 	}
 	testutil.TestResponseObject(suite.T(), w, expected, 200)
 }
@@ -214,15 +327,70 @@ func (suite *OauthTestSuite) TestRefreshTokenGrant() {
 	accessToken := new(models.OauthAccessToken)
 	assert.False(suite.T(), models.OauthAccessTokenPreload(suite.db).
 		Last(accessToken).RecordNotFound())
+	refreshToken := new(models.OauthRefreshToken)
+	assert.False(suite.T(), models.OauthRefreshTokenPreload(suite.db).
+		Where("used_at IS NULL").First(refreshToken).RecordNotFound())
+
+	// Only hashes of the tokens are stored
+	resp := suite.decodeAccessTokenResponse(w)
+	assert.Equal(suite.T(), accessToken.TokenHash, models.HashToken(resp.AccessToken))
+	assert.Equal(suite.T(), refreshToken.TokenHash, models.HashToken(resp.RefreshToken))
 
 	// Check the response
 	expected := &oauth.AccessTokenResponse{
 		UserID:       accessToken.UserID.String,
-		AccessToken:  accessToken.Token,
+		AccessToken:  resp.AccessToken,
 		ExpiresIn:    3600,
 		TokenType:    tokentypes.Bearer,
 		Scope:        "read_write",
-		RefreshToken: "test_token",
+		RefreshToken: resp.RefreshToken,
 	}
 	testutil.TestResponseObject(suite.T(), w, expected, 200)
 }
+
+func (suite *OauthTestSuite) TestRefreshTokenGrantTypeLineage() {
+	w := suite.passwordGrantWithAcr("test@user", "", "")
+	resp := suite.decodeAccessTokenResponse(w)
+
+	// The grant type is recorded on the refresh token
+	refreshToken := new(models.OauthRefreshToken)
+	assert.NoError(suite.T(), suite.db.Where("token = ?", models.HashToken(resp.RefreshToken)).First(refreshToken).Error)
+	assert.Equal(suite.T(), "password", refreshToken.GrantType)
+	assert.Equal(suite.T(), "password", suite.introspectRefreshToken(resp.RefreshToken).GrantType)
+
+	// And kept when the token is refreshed
+	w = suite.refreshTokenGrant(resp.RefreshToken)
+	refreshed := suite.decodeAccessTokenResponse(w)
+	assert.Equal(suite.T(), "password", suite.introspectRefreshToken(refreshed.RefreshToken).GrantType)
+}
+
+func (suite *OauthTestSuite) TestRefreshTokenGrantTypeDisabled() {
+	w := suite.passwordGrantWithAcr("test@user", "", "")
+	resp := suite.decodeAccessTokenResponse(w)
+
+	suite.cnf.Oauth.DisabledGrantTypes = []string{"password"}
+	defer func() { suite.cnf.Oauth.DisabledGrantTypes = nil }()
+
+	// The grant type itself is rejected
+	w = suite.passwordGrantWithAcr("test@user", "", "")
+	testutil.TestResponseForOauthError(suite.T(), w, "unsupported_grant_type", oauth.ErrInvalidGrantType.Error(), 400)
+
+	// And so are refresh tokens it has issued
+	w = suite.refreshTokenGrant(resp.RefreshToken)
+	testutil.TestResponseForOauthError(suite.T(), w, "invalid_grant", oauth.ErrRefreshTokenGrantTypeDisabled.Error(), 400)
+
+	// Client credentials never issues refresh tokens
+	suite.cnf.Oauth.DisabledGrantTypes = nil
+	err := suite.db.Model(new(models.OauthRefreshToken)).Where("token = ?", models.HashToken(resp.RefreshToken)).
+		UpdateColumn("grant_type", "client_credentials").Error
+	assert.NoError(suite.T(), err)
+	w = suite.refreshTokenGrant(resp.RefreshToken)
+	testutil.TestResponseForOauthError(suite.T(), w, "invalid_grant", oauth.ErrRefreshTokenGrantTypeDisabled.Error(), 400)
+
+	// Tokens issued before grant types were recorded are still accepted
+	err = suite.db.Model(new(models.OauthRefreshToken)).Where("token = ?", models.HashToken(resp.RefreshToken)).
+		UpdateColumn("grant_type", "").Error
+	assert.NoError(suite.T(), err)
+	w = suite.refreshTokenGrant(resp.RefreshToken)
+	assert.Equal(suite.T(), 200, w.Code)
+}
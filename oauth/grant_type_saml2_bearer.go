@@ -0,0 +1,74 @@
+package oauth
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth/tokentypes"
+)
+
+func (s *Service) saml2BearerGrant(r *http.Request, client *models.OauthClient) (*AccessTokenResponse, error) {
+	// Verify the assertion
+	assertion, err := s.verifySAMLAssertion(r.Form.Get("assertion"), client, s.endpointURL(r))
+	if err != nil {
+		return nil, err
+	}
+
+	// The subject is either the client itself or one of the users
+	var user *models.OauthUser
+	if subject := strings.TrimSpace(assertion.Subject.NameID); subject != client.Key {
+		user, err = s.FindUserByUsername(subject)
+		if err != nil {
+			return nil, ErrAssertionSubjectNotFound
+		}
+	}
+
+	// Get the scope string
+	scope, err := s.getClientScope(client, r.Form.Get("scope"))
+	if err != nil {
+		return nil, err
+	}
+
+	// The scope may be restricted to an audience other than requested
+	if err := s.checkRequestedAudience(r.Form.Get("audience"), scope); err != nil {
+		return nil, err
+	}
+
+	// Tokens can be requested for specific resource servers
+	resources, err := s.getRequestedResources(r, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a new access token, but never a refresh token
+	// as the client can get a new assertion instead
+	lifetime := s.getAccessTokenLifetime(client, SAML2BearerGrantType)
+	accessToken, err := s.GrantAccessToken(
+		client,
+		user,
+		lifetime, // expires in
+		scope,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Issue the access token for the requested resource servers only
+	if err := s.setResourceAudience(accessToken, resources); err != nil {
+		return nil, err
+	}
+
+	// Create response
+	accessTokenResponse, err := NewAccessTokenResponse(
+		accessToken,
+		nil, // refresh token
+		lifetime,
+		tokentypes.Bearer,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return accessTokenResponse, nil
+}
@@ -0,0 +1,183 @@
+package oauth_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/test-util"
+	"github.com/RichardKnop/go-oauth2-server/util/saml"
+	"github.com/stretchr/testify/assert"
+)
+
+// samlAssertion are the parts of a test assertion that vary between test cases
+type samlAssertion struct {
+	issuer       string
+	subject      string
+	audience     string
+	recipient    string
+	notOnOrAfter time.Time
+}
+
+func (suite *OauthTestSuite) TestAddSAMLIdentityProviderInvalidMetadata() {
+	_, err := suite.service.AddSAMLIdentityProvider(suite.clients[0], []byte("bogus"))
+	assert.Equal(suite.T(), saml.ErrInvalidMetadata, err)
+
+	_, err = suite.service.AddSAMLIdentityProvider(suite.clients[0], []byte(
+		`<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" entityID="https://idp.example.com"><md:IDPSSODescriptor/></md:EntityDescriptor>`,
+	))
+	assert.Equal(suite.T(), saml.ErrNoSigningCertificate, err)
+}
+
+func (suite *OauthTestSuite) TestSAML2BearerGrant() {
+	privateKey := suite.registerSAMLIdentityProvider("https://idp.example.com")
+
+	// The client can get a token for itself or for one of the users
+	for _, testCase := range []struct {
+		subject string
+		userID  string
+	}{
+		{"test_client_1", ""},
+		{"test@user", string(suite.users[1].ID)},
+	} {
+		assertion := suite.signSAMLAssertion(privateKey, samlAssertion{
+			issuer:       "https://idp.example.com",
+			subject:      testCase.subject,
+			audience:     suite.cnf.JWT.Issuer,
+			recipient:    suite.cnf.JWT.Issuer + "/v1/oauth/tokens",
+			notOnOrAfter: time.Now().Add(time.Minute),
+		})
+
+		resp := suite.decodeAccessTokenResponse(suite.exchangeSAMLAssertion(assertion))
+		assert.Equal(suite.T(), testCase.userID, resp.UserID)
+		assert.Equal(suite.T(), "read", resp.Scope)
+		assert.NotEmpty(suite.T(), resp.AccessToken)
+		assert.Empty(suite.T(), resp.RefreshToken)
+	}
+}
+
+func (suite *OauthTestSuite) TestSAML2BearerGrantInvalidAssertion() {
+	privateKey := suite.registerSAMLIdentityProvider("https://idp.example.com")
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(suite.T(), err)
+
+	valid := samlAssertion{
+		issuer:       "https://idp.example.com",
+		subject:      "test_client_1",
+		audience:     suite.cnf.JWT.Issuer,
+		recipient:    suite.cnf.JWT.Issuer + "/v1/oauth/tokens",
+		notOnOrAfter: time.Now().Add(time.Minute),
+	}
+	otherIssuer := valid
+	otherIssuer.issuer = "https://other.example.com"
+	expired := valid
+	expired.notOnOrAfter = time.Now().Add(-time.Minute)
+	otherAudience := valid
+	otherAudience.audience = "https://other.example.com"
+	otherRecipient := valid
+	otherRecipient.recipient = "https://other.example.com/token"
+	unknownSubject := valid
+	unknownSubject.subject = "bogus@user"
+
+	tampered := suite.signSAMLAssertion(privateKey, valid)
+	document, err := saml.Decode(tampered)
+	assert.NoError(suite.T(), err)
+	tampered = saml.Encode([]byte(strings.Replace(string(document), ">test_client_1<", ">test@user<", 1)))
+
+	// Canonicalization drops comments, so a comment splitting the subject
+	// does not break the signature, but the assertion is refused anyway
+	commented := saml.Encode([]byte(strings.Replace(string(document), ">test_client_1<", ">test_client<!---->_1<", 1)))
+
+	// The signed assertion nested in a forged one
+	wrapped := saml.Encode([]byte(`<saml:Assertion xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="_forged" Version="2.0">` +
+		`<saml:Issuer>https://idp.example.com</saml:Issuer>` +
+		`<saml:Subject><saml:NameID>test@user</saml:NameID></saml:Subject>` +
+		`<saml:Advice>` + string(document) + `</saml:Advice></saml:Assertion>`))
+
+	for _, testCase := range []struct {
+		assertion string
+		err       error
+	}{
+		{"bogus", oauth.ErrInvalidAssertion},
+		{tampered, oauth.ErrInvalidAssertion},
+		{commented, oauth.ErrInvalidAssertion},
+		{wrapped, oauth.ErrInvalidAssertion},
+		{suite.signSAMLAssertion(otherKey, valid), oauth.ErrInvalidAssertion},
+		{suite.signSAMLAssertion(privateKey, otherIssuer), oauth.ErrInvalidAssertion},
+		{suite.signSAMLAssertion(privateKey, otherRecipient), oauth.ErrInvalidAssertion},
+		{suite.signSAMLAssertion(privateKey, expired), oauth.ErrAssertionExpired},
+		{suite.signSAMLAssertion(privateKey, otherAudience), oauth.ErrAssertionAudienceMismatch},
+		{suite.signSAMLAssertion(privateKey, unknownSubject), oauth.ErrAssertionSubjectNotFound},
+	} {
+		testutil.TestResponseForOauthError(
+			suite.T(),
+			suite.exchangeSAMLAssertion(testCase.assertion),
+			"invalid_grant",
+			testCase.err.Error(),
+			400,
+		)
+	}
+}
+
+// registerSAMLIdentityProvider registers a new identity provider of the entity
+// for the first test client and returns the private key signing its assertions
+func (suite *OauthTestSuite) registerSAMLIdentityProvider(entityID string) *rsa.PrivateKey {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(suite.T(), err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "idp.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	assert.NoError(suite.T(), err)
+
+	metadata := `<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" xmlns:ds="http://www.w3.org/2000/09/xmldsig#" entityID="` + entityID + `">
+  <md:IDPSSODescriptor protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">
+    <md:KeyDescriptor use="signing"><ds:KeyInfo><ds:X509Data><ds:X509Certificate>` +
+		base64.StdEncoding.EncodeToString(der) +
+		`</ds:X509Certificate></ds:X509Data></ds:KeyInfo></md:KeyDescriptor>
+  </md:IDPSSODescriptor>
+</md:EntityDescriptor>`
+	identityProvider, err := suite.service.AddSAMLIdentityProvider(suite.clients[0], []byte(metadata))
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), entityID, identityProvider.EntityID)
+	}
+
+	return privateKey
+}
+
+func (suite *OauthTestSuite) signSAMLAssertion(privateKey *rsa.PrivateKey, a samlAssertion) string {
+	now := time.Now().UTC()
+	document := `<saml:Assertion xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="_` + strings.Replace(a.subject, "@", "_", -1) + `" Version="2.0" IssueInstant="` + now.Format(time.RFC3339) + `">` +
+		`<saml:Issuer>` + a.issuer + `</saml:Issuer>` +
+		`<saml:Subject><saml:NameID>` + a.subject + `</saml:NameID>` +
+		`<saml:SubjectConfirmation Method="` + saml.BearerConfirmation + `">` +
+		`<saml:SubjectConfirmationData NotOnOrAfter="` + a.notOnOrAfter.UTC().Format(time.RFC3339) + `" Recipient="` + a.recipient + `"/>` +
+		`</saml:SubjectConfirmation></saml:Subject>` +
+		`<saml:Conditions NotBefore="` + now.Add(-time.Minute).Format(time.RFC3339) + `">` +
+		`<saml:AudienceRestriction><saml:Audience>` + a.audience + `</saml:Audience></saml:AudienceRestriction>` +
+		`</saml:Conditions></saml:Assertion>`
+
+	signed, err := saml.Sign([]byte(document), privateKey)
+	assert.NoError(suite.T(), err)
+	return saml.Encode(signed)
+}
+
+func (suite *OauthTestSuite) exchangeSAMLAssertion(assertion string) *httptest.ResponseRecorder {
+	return suite.tokenRequest(url.Values{
+		"grant_type": {oauth.SAML2BearerGrantType},
+		"assertion":  {assertion},
+		"scope":      {"read"},
+	}, nil)
+}
@@ -0,0 +1,90 @@
+package oauth
+
+import (
+	"net/http"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth/tokentypes"
+	"github.com/RichardKnop/go-oauth2-server/util"
+)
+
+func (s *Service) tokenExchangeGrant(r *http.Request, client *models.OauthClient) (*AccessTokenResponse, error) {
+	// Fetch the token the client received and wants to trade
+	subjectToken, err := s.getValidSubjectToken(
+		r,
+		client,
+		r.Form.Get("subject_token"),
+		r.Form.Get("subject_token_type"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Downstream tokens can only narrow the scope of the subject token
+	scope := subjectToken.Scope
+	if requestedScope := r.Form.Get("scope"); requestedScope != "" {
+		if notGranted := util.SpaceDelimitedStringDifference(requestedScope, subjectToken.Scope); len(notGranted) > 0 {
+			return nil, &ScopeNotGrantedError{Scopes: notGranted}
+		}
+		scope, err = s.getNarrowedScope(requestedScope, subjectToken.Scope)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The scope may be restricted to an audience other than requested
+	if err := s.checkRequestedAudience(r.Form.Get("audience"), scope); err != nil {
+		return nil, err
+	}
+
+	// Tokens can be requested for specific resource servers
+	resources, err := s.getRequestedResources(r, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	// The client acts on behalf of the subject
+	actor, err := delegateActor(subjectToken, client)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a new access token for the subject, but never a refresh token
+	// as the client is expected to exchange a fresh subject token instead.
+	// The token expires with the subject token at the latest.
+	lifetime := getSubjectTokenLifetime(
+		subjectToken,
+		s.getAccessTokenLifetime(client, TokenExchangeGrantType),
+	)
+	accessToken, err := s.GrantAccessToken(
+		client,
+		subjectToken.User,
+		lifetime, // expires in
+		scope,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.setAccessTokenActor(accessToken, actor); err != nil {
+		return nil, err
+	}
+
+	// Issue the access token for the requested resource servers only
+	if err := s.setResourceAudience(accessToken, resources); err != nil {
+		return nil, err
+	}
+
+	// Create response
+	accessTokenResponse, err := NewAccessTokenResponse(
+		accessToken,
+		nil, // refresh token
+		lifetime,
+		tokentypes.Bearer,
+	)
+	if err != nil {
+		return nil, err
+	}
+	accessTokenResponse.IssuedTokenType = AccessTokenType
+
+	return accessTokenResponse, nil
+}
@@ -0,0 +1,163 @@
+package oauth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/test-util"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestTokenExchangeGrant() {
+	// The user has a token for the first service
+	subjectToken, err := suite.service.GrantAccessToken(suite.clients[0], suite.users[1], 3600, "read read_write")
+	assert.NoError(suite.T(), err)
+	suite.setAccessTokenAudience(subjectToken.Token, "test_client_2")
+
+	// Which the second service trades for a narrower token for a downstream call
+	w := suite.exchangeToken("test_client_2", subjectToken.Token, "read")
+	resp := suite.decodeAccessTokenResponse(w)
+	assert.Equal(suite.T(), "read", resp.Scope)
+	assert.Equal(suite.T(), string(suite.users[1].ID), resp.UserID)
+	assert.Equal(suite.T(), oauth.AccessTokenType, resp.IssuedTokenType)
+	assert.Empty(suite.T(), resp.RefreshToken)
+
+	// The token is for the user, with the second service acting on their behalf
+	introspectResponse := suite.introspectAccessToken(resp.AccessToken)
+	assert.Equal(suite.T(), "test@user", introspectResponse.Username)
+	assert.Equal(suite.T(), "test_client_2", introspectResponse.ClientID)
+	assert.Equal(suite.T(), &oauth.Actor{Subject: "test_client_2"}, introspectResponse.Act)
+
+	// Exchanging the downstream token again extends the delegation chain
+	suite.setAccessTokenAudience(resp.AccessToken, "test_client_1")
+	w = suite.exchangeToken("test_client_1", resp.AccessToken, "")
+	resp = suite.decodeAccessTokenResponse(w)
+	assert.Equal(suite.T(), "read", resp.Scope)
+	assert.Equal(
+		suite.T(),
+		&oauth.Actor{Subject: "test_client_1", Act: &oauth.Actor{Subject: "test_client_2"}},
+		suite.introspectAccessToken(resp.AccessToken).Act,
+	)
+
+	// Tokens which were not exchanged have no actor
+	assert.Nil(suite.T(), suite.introspectAccessToken(subjectToken.Token).Act)
+}
+
+func (suite *OauthTestSuite) TestTokenExchangeGrantScopeCannotBeGreater() {
+	subjectToken, err := suite.service.GrantAccessToken(suite.clients[0], suite.users[1], 3600, "read")
+	assert.NoError(suite.T(), err)
+	suite.setAccessTokenAudience(subjectToken.Token, "test_client_2")
+
+	w := suite.exchangeToken("test_client_2", subjectToken.Token, "read read_write")
+	assert.Equal(suite.T(), 400, w.Code)
+	resp := map[string]string{}
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(suite.T(), "invalid_scope", resp["error"])
+}
+
+func (suite *OauthTestSuite) TestTokenExchangeGrantInvalidSubjectToken() {
+	testutil.TestResponseForOauthError(
+		suite.T(),
+		suite.exchangeToken("test_client_2", "bogus", ""),
+		"invalid_grant",
+		oauth.ErrInvalidSubjectToken.Error(),
+		400,
+	)
+
+	// Only access tokens can be exchanged
+	subjectToken, err := suite.service.GrantAccessToken(suite.clients[0], suite.users[1], 3600, "read")
+	assert.NoError(suite.T(), err)
+	suite.setAccessTokenAudience(subjectToken.Token, "test_client_2")
+	r := suite.newTokenExchangeRequest("test_client_2", subjectToken.Token, "")
+	r.PostForm.Set("subject_token_type", "urn:ietf:params:oauth:token-type:id_token")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	testutil.TestResponseForOauthError(
+		suite.T(),
+		w,
+		"invalid_request",
+		oauth.ErrUnsupportedTokenType.Error(),
+		400,
+	)
+}
+
+func (suite *OauthTestSuite) TestTokenExchangeGrantSubjectTokenClient() {
+	// Tokens issued to another client and not for this one cannot be traded
+	subjectToken, err := suite.service.GrantAccessToken(suite.clients[0], suite.users[1], 3600, "read")
+	assert.NoError(suite.T(), err)
+	testutil.TestResponseForOauthError(
+		suite.T(),
+		suite.exchangeToken("test_client_2", subjectToken.Token, ""),
+		"invalid_grant",
+		oauth.ErrSubjectTokenNotForClient.Error(),
+		400,
+	)
+
+	// The client the token was issued to can trade it
+	w := suite.exchangeToken("test_client_1", subjectToken.Token, "")
+	assert.Equal(suite.T(), 200, w.Code)
+}
+
+func (suite *OauthTestSuite) TestTokenExchangeGrantBoundSubjectToken() {
+	// Tokens bound to a DPoP key or a client certificate
+	// cannot be traded without proving possession of it
+	for _, column := range []string{"jwk_thumbprint", "certificate_thumbprint"} {
+		subjectToken, err := suite.service.GrantAccessToken(suite.clients[0], suite.users[1], 3600, "read")
+		assert.NoError(suite.T(), err)
+		err = suite.db.Model(new(models.OauthAccessToken)).Where("token = ?", models.HashToken(subjectToken.Token)).
+			UpdateColumn(column, "test_thumbprint").Error
+		assert.NoError(suite.T(), err, "Updating test data failed")
+
+		testutil.TestResponseForOauthError(
+			suite.T(),
+			suite.exchangeToken("test_client_1", subjectToken.Token, ""),
+			"invalid_grant",
+			oauth.ErrSubjectTokenProofRequired.Error(),
+			400,
+		)
+	}
+}
+
+func (suite *OauthTestSuite) TestTokenExchangeGrantLifetime() {
+	// The new token does not outlive the subject token
+	subjectToken, err := suite.service.GrantAccessToken(suite.clients[0], suite.users[1], 60, "read")
+	assert.NoError(suite.T(), err)
+
+	resp := suite.decodeAccessTokenResponse(suite.exchangeToken("test_client_1", subjectToken.Token, ""))
+	assert.True(suite.T(), resp.ExpiresIn > 0 && resp.ExpiresIn <= 60)
+	accessToken := new(models.OauthAccessToken)
+	err = suite.db.Where("token = ?", models.HashToken(resp.AccessToken)).First(accessToken).Error
+	if assert.NoError(suite.T(), err) {
+		assert.False(suite.T(), accessToken.ExpiresAt.After(subjectToken.ExpiresAt))
+	}
+}
+
+// setAccessTokenAudience issues the access token for the audience
+func (suite *OauthTestSuite) setAccessTokenAudience(token, audience string) {
+	err := suite.db.Model(new(models.OauthAccessToken)).Where("token = ?", models.HashToken(token)).
+		UpdateColumn("audience", audience).Error
+	assert.NoError(suite.T(), err, "Updating test data failed")
+}
+
+func (suite *OauthTestSuite) newTokenExchangeRequest(clientID, subjectToken, scope string) *http.Request {
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth(clientID, "test_secret")
+	r.PostForm = url.Values{
+		"grant_type":         {oauth.TokenExchangeGrantType},
+		"subject_token":      {subjectToken},
+		"subject_token_type": {oauth.AccessTokenType},
+		"scope":              {scope},
+	}
+	return r
+}
+
+func (suite *OauthTestSuite) exchangeToken(clientID, subjectToken, scope string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, suite.newTokenExchangeRequest(clientID, subjectToken, scope))
+	return w
+}
@@ -0,0 +1,91 @@
+package oauth
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth/tokentypes"
+)
+
+func (s *Service) webauthnGrant(r *http.Request, client *models.OauthClient) (*AccessTokenResponse, error) {
+	// Get the scope string
+	scope, err := s.getClientScope(client, r.Form.Get("scope"))
+	if err != nil {
+		return nil, err
+	}
+
+	// The scope may be restricted to an audience other than requested
+	if err := s.checkRequestedAudience(r.Form.Get("audience"), scope); err != nil {
+		return nil, err
+	}
+
+	// Tokens can be requested for specific resource servers
+	resources, err := s.getRequestedResources(r, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	// Authenticate the user with the passkey assertion
+	user, err := s.authWebAuthnUser(
+		r.Form.Get("credential_id"),
+		r.Form.Get("client_data_json"),
+		r.Form.Get("authenticator_data"),
+		r.Form.Get("signature"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Users may have to verify their email address first
+	if err := s.checkEmailVerified(user); err != nil {
+		return nil, err
+	}
+
+	// Log in the user
+	accessToken, refreshToken, err := s.login(client, user, scope, WebAuthnGrantType)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.setRefreshTokenGrantType(refreshToken, WebAuthnGrantType); err != nil {
+		return nil, err
+	}
+
+	// Issue the access token for the requested resource servers only
+	if err := s.setResourceAudience(accessToken, resources); err != nil {
+		return nil, err
+	}
+
+	// The passkey is something the user has, unlocked by something they
+	// know or are, so the login satisfies multi-factor authentication
+	if err := s.setAcr(accessToken, AcrMFA); err != nil {
+		return nil, err
+	}
+
+	// Tie the tokens to this login
+	session, err := s.startSession(user, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.setSession(accessToken, refreshToken, session); err != nil {
+		return nil, err
+	}
+
+	// Create response
+	accessTokenResponse, err := NewAccessTokenResponse(
+		accessToken,
+		refreshToken,
+		s.getAccessTokenLifetime(client, WebAuthnGrantType),
+		tokentypes.Bearer,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Include a device secret if enabled
+	if err := s.addDeviceSecret(accessTokenResponse, client, user, scope); err != nil {
+		return nil, err
+	}
+
+	return accessTokenResponse, nil
+}
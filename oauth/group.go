@@ -0,0 +1,264 @@
+package oauth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// maxGroupsPerPage limits how many groups are listed at once
+	maxGroupsPerPage = 100
+)
+
+var (
+	// ErrGroupNotFound ...
+	ErrGroupNotFound = errors.New("Group not found")
+	// ErrGroupNameTaken ...
+	ErrGroupNameTaken = errors.New("Group name taken")
+	// ErrCannotSetEmptyGroupName ...
+	ErrCannotSetEmptyGroupName = errors.New("Cannot set empty group name")
+)
+
+// FindGroupByID looks up a group by ID
+func (s *Service) FindGroupByID(id string) (*models.OauthGroup, error) {
+	group := new(models.OauthGroup)
+	notFound := s.db.Where("id = ?", id).First(group).RecordNotFound()
+
+	// Not found
+	if notFound {
+		return nil, ErrGroupNotFound
+	}
+
+	return group, nil
+}
+
+// ListGroups returns a page of groups in the order they were created and
+// how many groups there are, filtered by display name unless it is empty
+func (s *Service) ListGroups(displayName string, offset, limit int) ([]*models.OauthGroup, int, error) {
+	if limit <= 0 || limit > maxGroupsPerPage {
+		limit = maxGroupsPerPage
+	}
+
+	query := s.db.Model(new(models.OauthGroup))
+	if displayName != "" {
+		query = query.Where("display_name = ?", displayName)
+	}
+
+	var count int
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var groups []*models.OauthGroup
+	err := query.Order("created_at").Offset(offset).Limit(limit).Find(&groups).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return groups, count, nil
+}
+
+// CreateGroup saves a new group with the users as its members
+func (s *Service) CreateGroup(displayName, externalID string, userIDs []string) (*models.OauthGroup, error) {
+	if displayName == "" {
+		return nil, ErrCannotSetEmptyGroupName
+	}
+	taken, err := groupNameTaken(s.db, displayName, "")
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, ErrGroupNameTaken
+	}
+
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	group := models.NewOauthGroup(displayName, externalID)
+	if err := tx.Create(group).Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+
+	if err := addGroupMembers(tx, group, userIDs); err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+
+	return group, nil
+}
+
+// UpdateGroup renames a group and sets its external ID
+func (s *Service) UpdateGroup(group *models.OauthGroup, displayName, externalID string) error {
+	if displayName == "" {
+		return ErrCannotSetEmptyGroupName
+	}
+	taken, err := groupNameTaken(s.db, displayName, group.ID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrGroupNameTaken
+	}
+
+	err = s.db.Model(new(models.OauthGroup)).Where("id = ?", group.ID).
+		UpdateColumns(map[string]interface{}{
+			"display_name": displayName,
+			"external_id":  externalID,
+			"updated_at":   time.Now().UTC(),
+		}).Error
+	if err != nil {
+		return err
+	}
+	group.DisplayName = displayName
+	group.ExternalID = externalID
+	return nil
+}
+
+// DeleteGroup deletes a group, its members stay as they are
+func (s *Service) DeleteGroup(group *models.OauthGroup) error {
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	err := tx.Unscoped().Where("group_id = ?", group.ID).Delete(new(models.OauthGroupMember)).Error
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	// Groups are deleted for good so their names can be reused
+	if err := tx.Unscoped().Delete(group).Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	return nil
+}
+
+// ListGroupMembers returns the members of a group
+func (s *Service) ListGroupMembers(group *models.OauthGroup) ([]*models.OauthUser, error) {
+	var users []*models.OauthUser
+	err := s.db.
+		Joins("JOIN oauth_group_members ON oauth_group_members.user_id = oauth_users.id").
+		Where("oauth_group_members.group_id = ?", group.ID).
+		Order("oauth_users.created_at").Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// ListUserGroups returns the groups a user is a member of
+func (s *Service) ListUserGroups(user *models.OauthUser) ([]*models.OauthGroup, error) {
+	var groups []*models.OauthGroup
+	err := s.db.
+		Joins("JOIN oauth_group_members ON oauth_group_members.group_id = oauth_groups.id").
+		Where("oauth_group_members.user_id = ?", user.ID).
+		Order("oauth_groups.created_at").Find(&groups).Error
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// AddGroupMembers adds users to a group, users already in it are skipped
+func (s *Service) AddGroupMembers(group *models.OauthGroup, userIDs []string) error {
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	if err := addGroupMembers(tx, group, userIDs); err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	return nil
+}
+
+// RemoveGroupMembers removes users from a group
+func (s *Service) RemoveGroupMembers(group *models.OauthGroup, userIDs []string) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	return s.db.Unscoped().Where("group_id = ? AND user_id IN (?)", group.ID, userIDs).
+		Delete(new(models.OauthGroupMember)).Error
+}
+
+// SetGroupMembers replaces the members of a group with the users
+func (s *Service) SetGroupMembers(group *models.OauthGroup, userIDs []string) error {
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	err := tx.Unscoped().Where("group_id = ?", group.ID).Delete(new(models.OauthGroupMember)).Error
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	if err := addGroupMembers(tx, group, userIDs); err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	return nil
+}
+
+// addGroupMembers adds the users to the group unless they are members
+// already, all of them must exist
+func addGroupMembers(tx *gorm.DB, group *models.OauthGroup, userIDs []string) error {
+	for _, userID := range userIDs {
+		if tx.Where("id = ?", userID).First(new(models.OauthUser)).RecordNotFound() {
+			return ErrUserNotFound
+		}
+
+		var count int
+		err := tx.Model(new(models.OauthGroupMember)).
+			Where("group_id = ? AND user_id = ?", group.ID, userID).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		if err := tx.Create(models.NewOauthGroupMember(group, userID)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// groupNameTaken returns true if a group other than the one with the ID
+// has the display name
+func groupNameTaken(db *gorm.DB, displayName, exceptID string) (bool, error) {
+	var count int
+	err := db.Model(new(models.OauthGroup)).
+		Where("display_name = ? AND id != ?", displayName, exceptID).Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,69 @@
+package oauth_test
+
+import (
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestGroups() {
+	user, err := suite.service.CreateUser(roles.User, "jdoe@example.com", "test_password")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	memberIDs := func(group *models.OauthGroup) []string {
+		members, err := suite.service.ListGroupMembers(group)
+		assert.NoError(suite.T(), err)
+		ids := make([]string, len(members))
+		for i, member := range members {
+			ids[i] = member.ID
+		}
+		return ids
+	}
+
+	group, err := suite.service.CreateGroup("Engineering", "hr_engineering", []string{"1", user.ID})
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	assert.ElementsMatch(suite.T(), []string{"1", user.ID}, memberIDs(group))
+
+	// Groups have unique names and existing members
+	_, err = suite.service.CreateGroup("Engineering", "", nil)
+	assert.Equal(suite.T(), oauth.ErrGroupNameTaken, err)
+	_, err = suite.service.CreateGroup("", "", nil)
+	assert.Equal(suite.T(), oauth.ErrCannotSetEmptyGroupName, err)
+	_, err = suite.service.CreateGroup("Sales", "", []string{"bogus"})
+	assert.Equal(suite.T(), oauth.ErrUserNotFound, err)
+	_, count, err := suite.service.ListGroups("", 0, 10)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), 1, count)
+	}
+
+	// Members are added once and removed
+	assert.NoError(suite.T(), suite.service.AddGroupMembers(group, []string{"2", user.ID}))
+	assert.ElementsMatch(suite.T(), []string{"1", "2", user.ID}, memberIDs(group))
+	assert.NoError(suite.T(), suite.service.RemoveGroupMembers(group, []string{"1", "2"}))
+	assert.ElementsMatch(suite.T(), []string{user.ID}, memberIDs(group))
+	assert.NoError(suite.T(), suite.service.SetGroupMembers(group, []string{"2"}))
+	assert.ElementsMatch(suite.T(), []string{"2"}, memberIDs(group))
+	assert.Equal(suite.T(), oauth.ErrUserNotFound, suite.service.SetGroupMembers(group, []string{"bogus"}))
+	assert.ElementsMatch(suite.T(), []string{"2"}, memberIDs(group))
+
+	groups, err := suite.service.ListUserGroups(&models.OauthUser{MyGormModel: models.MyGormModel{ID: "2"}})
+	if assert.NoError(suite.T(), err) && assert.Len(suite.T(), groups, 1) {
+		assert.Equal(suite.T(), "Engineering", groups[0].DisplayName)
+	}
+
+	// Groups are renamed and deleted, their names can be reused
+	assert.NoError(suite.T(), suite.service.UpdateGroup(group, "Platform", ""))
+	groups, _, err = suite.service.ListGroups("Platform", 0, 10)
+	if assert.NoError(suite.T(), err) && assert.Len(suite.T(), groups, 1) {
+		assert.Equal(suite.T(), "", groups[0].ExternalID)
+	}
+	assert.NoError(suite.T(), suite.service.DeleteGroup(group))
+	_, err = suite.service.FindGroupByID(group.ID)
+	assert.Equal(suite.T(), oauth.ErrGroupNotFound, err)
+	_, err = suite.service.CreateGroup("Platform", "", []string{"2"})
+	assert.NoError(suite.T(), err)
+}
@@ -1,14 +1,19 @@
 package oauth
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
 
-	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/log"
+	"github.com/RichardKnop/go-oauth2-server/util"
 	"github.com/RichardKnop/go-oauth2-server/util/response"
 )
 
 var (
+	// ErrGrantTypeMissing ...
+	ErrGrantTypeMissing = errors.New("Grant type missing")
 	// ErrInvalidGrantType ...
 	ErrInvalidGrantType = errors.New("Invalid grant type")
 	// ErrInvalidClientIDOrSecret ...
@@ -20,36 +25,222 @@ var (
 func (s *Service) tokensHandler(w http.ResponseWriter, r *http.Request) {
 	// Parse the form so r.Form becomes available
 	if err := r.ParseForm(); err != nil {
+		response.ErrorWithDescription(w, "invalid_request", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Check the grant type
+	if r.Form.Get("grant_type") == "" {
+		writeTokenError(w, ErrGrantTypeMissing)
+		return
+	}
+	grantHandler, ok := s.grantTypes()[r.Form.Get("grant_type")]
+	if !ok || !s.grantTypeEnabled(r.Form.Get("grant_type")) {
+		writeTokenError(w, ErrInvalidGrantType)
+		return
+	}
+
+	// Client auth
+	client, err := s.authenticateClient(r)
+	if err != nil {
+		response.InvalidClientError(w, err.Error())
+		return
+	}
+
+	// Throttle clients sending more token requests than they are allowed to
+	if retryAfter, err := s.checkClientRateLimit(client); err != nil {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		writeTokenError(w, err)
+		return
+	}
+
+	// The client may be registered for some grant types only
+	if !ClientAllowsGrantType(client, r.Form.Get("grant_type")) {
+		writeTokenError(w, ErrUnauthorizedClient)
+		return
+	}
+
+	// Validate the DPoP proof the tokens are to be bound to, if any
+	if err := s.checkDPoPProof(r); err != nil {
+		writeTokenError(w, err)
+		return
+	}
+
+	// Grant processing
+	resp, err := grantHandler.Grant(r, client)
+	if err != nil {
+		code := getErrStatusCode(err)
+		if code == http.StatusInternalServerError {
+			log.ERROR.Printf("Grant %s failed: %s", r.Form.Get("grant_type"), err)
+		}
+		writeTokenError(w, err)
+		return
+	}
+
+	// Bind the access token to the client certificate if enabled
+	if err := s.bindAccessTokenResponse(r, resp); err != nil {
+		writeTokenError(w, err)
+		return
+	}
+
+	// Bind the tokens to the DPoP key if the client sent a proof
+	if err := s.bindDPoPAccessTokenResponse(r, resp); err != nil {
+		writeTokenError(w, err)
+		return
+	}
+
+	// Hand out a JWT instead of the opaque access token if enabled
+	if err := s.encodeAccessTokenResponse(resp, client); err != nil {
+		writeTokenError(w, err)
+		return
+	}
+
+	// Let the embedding application customise the response
+	resp, err = s.transformResponse(r, resp, client)
+	if err != nil {
+		writeTokenError(w, err)
+		return
+	}
+
+	// Deliver the tokens as cookies if requested
+	if s.wantsTokenCookies(r) {
+		s.setTokenCookies(w, r, resp)
+	}
+
+	// Write response to json
+	response.WriteJSON(w, resp, 200)
+}
+
+// grantTypeEnabled returns false for grant types disabled by the configuration
+func (s *Service) grantTypeEnabled(grantType string) bool {
+	switch grantType {
+	case "device_secret":
+		if !s.cnf.Oauth.EnableDeviceSecret {
+			return false
+		}
+	case WebAuthnGrantType:
+		if !s.webAuthnEnabled() {
+			return false
+		}
+	case "implicit":
+		if !s.cnf.Oauth.EnableImplicitGrant {
+			return false
+		}
+	case CIBAGrantType:
+		// Nobody could approve backchannel requests without the hook
+		if s.backchannelHook == nil {
+			return false
+		}
+	}
+	return !util.StringInSlice(grantType, s.cnf.Oauth.DisabledGrantTypes)
+}
+
+// metadataHandler serves the authorization server metadata, which doubles
+// as the OpenID Connect discovery document
+// (GET /.well-known/oauth-authorization-server)
+// (GET /.well-known/openid-configuration)
+func (s *Service) metadataHandler(prefix string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response.WriteJSON(w, s.getMetadata(prefix), 200)
+	}
+}
+
+// jwksHandler serves the public keys tokens signed by the server can be verified with
+// (GET /.well-known/jwks.json)
+func (s *Service) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	keySet, err := s.getJSONWebKeySet()
+	if err != nil {
 		response.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Map of grant types against handler functions
-	grantTypes := map[string]func(r *http.Request, client *models.OauthClient) (*AccessTokenResponse, error){
-		"authorization_code": s.authorizationCodeGrant,
-		"password":           s.passwordGrant,
-		"client_credentials": s.clientCredentialsGrant,
-		"refresh_token":      s.refreshTokenGrant,
+	response.WriteJSON(w, keySet, 200)
+}
+
+// deviceAuthorizationHandler starts the device flow for an input constrained device
+// (POST /v1/oauth/device_authorization)
+func (s *Service) deviceAuthorizationHandler(w http.ResponseWriter, r *http.Request) {
+	// Parse the form so r.Form becomes available
+	if err := r.ParseForm(); err != nil {
+		response.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	// Check the grant type
-	grantHandler, ok := grantTypes[r.Form.Get("grant_type")]
-	if !ok {
+	// The device flow can be disabled like any other grant type
+	if !s.grantTypeEnabled(DeviceCodeGrantType) {
 		response.Error(w, ErrInvalidGrantType.Error(), http.StatusBadRequest)
 		return
 	}
 
 	// Client auth
-	client, err := s.basicAuthClient(r)
+	client, err := s.authenticateClient(r)
 	if err != nil {
 		response.UnauthorizedError(w, err.Error())
 		return
 	}
 
-	// Grant processing
-	resp, err := grantHandler(r, client)
+	// The client may be registered for some grant types only
+	if !ClientAllowsGrantType(client, DeviceCodeGrantType) {
+		writeError(w, ErrUnauthorizedClient)
+		return
+	}
+
+	// Issue the device and user codes
+	resp, err := s.deviceAuthorization(r, client)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Write response to json
+	response.WriteJSON(w, resp, 200)
+}
+
+// handoffHandler issues a short-lived code a mobile app can exchange for tokens
+// (POST /v1/oauth/handoff)
+func (s *Service) handoffHandler(w http.ResponseWriter, r *http.Request) {
+	// Parse the form so r.Form becomes available
+	if err := r.ParseForm(); err != nil {
+		response.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Get the authenticated access token of the web session
+	accessToken, err := GetAuthenticatedAccessToken(r)
+	if err != nil {
+		response.UnauthorizedError(w, err.Error())
+		return
+	}
+
+	// Issue the handoff code
+	resp, err := s.handoff(r, accessToken)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Write response to json
+	response.WriteJSON(w, resp, 200)
+}
+
+// userInfoHandler returns claims about the user of the access token
+// (GET|POST /v1/oauth/userinfo)
+func (s *Service) userInfoHandler(w http.ResponseWriter, r *http.Request) {
+	// Get the authenticated access token
+	accessToken, err := GetAuthenticatedAccessToken(r)
+	if err != nil {
+		response.UnauthorizedError(w, err.Error())
+		return
+	}
+
+	// Release the claims the granted scopes allow
+	resp, err := s.getUserInfo(accessToken)
+	if err == ErrUserInfoScopeRequired {
+		response.InsufficientScopeError(w, err.Error())
+		return
+	}
 	if err != nil {
-		response.Error(w, err.Error(), getErrStatusCode(err))
+		writeError(w, err)
 		return
 	}
 
@@ -57,11 +248,202 @@ func (s *Service) tokensHandler(w http.ResponseWriter, r *http.Request) {
 	response.WriteJSON(w, resp, 200)
 }
 
+// recoveryCodesHandler regenerates the recovery codes of the authenticated user
+// (POST /v1/oauth/recovery-codes)
+func (s *Service) recoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	// Get the authenticated access token
+	accessToken, err := GetAuthenticatedAccessToken(r)
+	if err != nil {
+		response.UnauthorizedError(w, err.Error())
+		return
+	}
+
+	// Regenerate the recovery codes
+	resp, err := s.regenerateRecoveryCodes(accessToken)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Write response to json
+	response.WriteJSON(w, resp, 200)
+}
+
+// totpEnrollHandler generates an authenticator secret for the authenticated
+// user to confirm (POST /v1/oauth/totp)
+func (s *Service) totpEnrollHandler(w http.ResponseWriter, r *http.Request) {
+	// Get the authenticated access token
+	accessToken, err := GetAuthenticatedAccessToken(r)
+	if err != nil {
+		response.UnauthorizedError(w, err.Error())
+		return
+	}
+
+	user, err := s.totpUser(accessToken)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp, err := s.BeginTOTPEnrollment(user)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Write response to json
+	response.WriteJSON(w, resp, 200)
+}
+
+// totpConfirmHandler enables the authenticator of the authenticated user
+// (POST /v1/oauth/totp/confirm)
+func (s *Service) totpConfirmHandler(w http.ResponseWriter, r *http.Request) {
+	// Get the authenticated access token
+	accessToken, err := GetAuthenticatedAccessToken(r)
+	if err != nil {
+		response.UnauthorizedError(w, err.Error())
+		return
+	}
+
+	confirmRequest := new(TOTPConfirmRequest)
+	if err := json.NewDecoder(r.Body).Decode(confirmRequest); err != nil {
+		response.ErrorWithDescription(w, "invalid_request", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := s.totpUser(accessToken)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	codes, err := s.ConfirmTOTPEnrollment(user, confirmRequest.Secret, confirmRequest.OTP)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Write response to json
+	response.WriteJSON(w, &RecoveryCodesResponse{RecoveryCodes: codes}, 200)
+}
+
+// totpDisableHandler removes the authenticator of the authenticated user
+// (DELETE /v1/oauth/totp)
+func (s *Service) totpDisableHandler(w http.ResponseWriter, r *http.Request) {
+	// Get the authenticated access token
+	accessToken, err := GetAuthenticatedAccessToken(r)
+	if err != nil {
+		response.UnauthorizedError(w, err.Error())
+		return
+	}
+
+	user, err := s.totpUser(accessToken)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if err := s.DisableTOTP(user); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// webAuthnRegisterHandler returns the options for creating a passkey of the
+// authenticated user (POST /v1/oauth/webauthn/register)
+func (s *Service) webAuthnRegisterHandler(w http.ResponseWriter, r *http.Request) {
+	// Get the authenticated access token
+	accessToken, err := GetAuthenticatedAccessToken(r)
+	if err != nil {
+		response.UnauthorizedError(w, err.Error())
+		return
+	}
+
+	// A passkey replaces the password, so the session must satisfy
+	// the same second factor as managing the authenticator
+	user, err := s.totpUser(accessToken)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	options, err := s.BeginWebAuthnRegistration(user)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Write response to json
+	response.WriteJSON(w, options, 200)
+}
+
+// webAuthnRegisterConfirmHandler stores the passkey created by the
+// authenticator of the authenticated user (POST /v1/oauth/webauthn/register/confirm)
+func (s *Service) webAuthnRegisterConfirmHandler(w http.ResponseWriter, r *http.Request) {
+	// Get the authenticated access token
+	accessToken, err := GetAuthenticatedAccessToken(r)
+	if err != nil {
+		response.UnauthorizedError(w, err.Error())
+		return
+	}
+
+	registrationRequest := new(WebAuthnRegistrationRequest)
+	if err := json.NewDecoder(r.Body).Decode(registrationRequest); err != nil {
+		response.ErrorWithDescription(w, "invalid_request", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := s.totpUser(accessToken)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	credential, err := s.FinishWebAuthnRegistration(user, registrationRequest)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Write response to json
+	response.WriteJSON(w, &WebAuthnRegistrationResponse{
+		CredentialID: credential.CredentialID,
+		Name:         credential.Name,
+	}, http.StatusCreated)
+}
+
+// webAuthnLoginHandler returns the options for logging in with a passkey
+// using the webauthn grant (POST /v1/oauth/webauthn/login)
+func (s *Service) webAuthnLoginHandler(w http.ResponseWriter, r *http.Request) {
+	// Client auth
+	if _, err := s.authenticateClient(r); err != nil {
+		response.InvalidClientError(w, err.Error())
+		return
+	}
+
+	loginRequest := new(WebAuthnLoginRequest)
+	if err := json.NewDecoder(r.Body).Decode(loginRequest); err != nil {
+		response.ErrorWithDescription(w, "invalid_request", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	options, err := s.BeginWebAuthnLogin(loginRequest.Username)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Write response to json
+	response.WriteJSON(w, options, 200)
+}
+
 // introspectHandler handles OAuth 2.0 introspect request
 // (POST /v1/oauth/introspect)
 func (s *Service) introspectHandler(w http.ResponseWriter, r *http.Request) {
 	// Client auth
-	client, err := s.basicAuthClient(r)
+	client, err := s.authenticateClient(r)
 	if err != nil {
 		response.UnauthorizedError(w, err.Error())
 		return
@@ -70,28 +452,343 @@ func (s *Service) introspectHandler(w http.ResponseWriter, r *http.Request) {
 	// Introspect the token
 	resp, err := s.introspectToken(r, client)
 	if err != nil {
-		response.Error(w, err.Error(), getErrStatusCode(err))
+		writeError(w, err)
+		return
+	}
+
+	// Write response to json
+	response.WriteJSON(w, resp, 200)
+}
+
+// revokeHandler handles token revocation requests
+// (POST /v1/oauth/revoke)
+func (s *Service) revokeHandler(w http.ResponseWriter, r *http.Request) {
+	// Client auth
+	client, err := s.authenticateClient(r)
+	if err != nil {
+		response.UnauthorizedError(w, err.Error())
+		return
+	}
+
+	// Revoke the token
+	if err := s.revokeToken(r, client); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// The response has no content, see RFC 7009 section 2.2
+	w.WriteHeader(http.StatusOK)
+}
+
+// pushedAuthorizationHandler stores an authorization request pushed by a client
+// (POST /v1/oauth/par)
+func (s *Service) pushedAuthorizationHandler(w http.ResponseWriter, r *http.Request) {
+	// Parse the form so r.PostForm becomes available
+	if err := r.ParseForm(); err != nil {
+		response.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Client auth
+	client, err := s.authenticateClient(r)
+	if err != nil {
+		response.UnauthorizedError(w, err.Error())
+		return
+	}
+
+	// Validate and store the authorization request
+	resp, err := s.pushedAuthorizationRequest(r, client)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Write response to json
+	response.WriteJSON(w, resp, http.StatusCreated)
+}
+
+// backchannelAuthenticationHandler starts the backchannel flow for a client
+// (POST /v1/oauth/bc-authorize)
+func (s *Service) backchannelAuthenticationHandler(w http.ResponseWriter, r *http.Request) {
+	// Parse the form so r.Form becomes available
+	if err := r.ParseForm(); err != nil {
+		response.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// The backchannel flow can be disabled like any other grant type
+	if !s.grantTypeEnabled(CIBAGrantType) {
+		response.Error(w, ErrInvalidGrantType.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Client auth
+	client, err := s.authenticateClient(r)
+	if err != nil {
+		response.UnauthorizedError(w, err.Error())
+		return
+	}
+
+	// The client may be registered for some grant types only
+	if !ClientAllowsGrantType(client, CIBAGrantType) {
+		writeError(w, ErrUnauthorizedClient)
+		return
+	}
+
+	// Ask the user to approve the request
+	resp, err := s.backchannelAuthentication(r, client)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Write response to json
+	response.WriteJSON(w, resp, 200)
+}
+
+// registerClientHandler registers a client with the metadata it sent
+// (POST /v1/oauth/register)
+func (s *Service) registerClientHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.cnf.Oauth.EnableClientRegistration {
+		writeError(w, ErrClientRegistrationDisabled)
+		return
+	}
+
+	// Registration may be limited to holders of the initial access token
+	token, _ := util.ParseBearerToken(r)
+	if err := s.checkInitialAccessToken(token); err != nil {
+		response.UnauthorizedError(w, err.Error())
+		return
+	}
+
+	// The metadata is sent as JSON, see RFC 7591 section 3.1
+	metadata := new(ClientMetadata)
+	if err := json.NewDecoder(r.Body).Decode(metadata); err != nil {
+		writeError(w, ErrInvalidClientMetadata)
+		return
+	}
+
+	// Create the client
+	resp, err := s.RegisterClient(metadata)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	resp.RegistrationClientURI = s.endpointURL(r) + "/" + resp.ClientID
+
+	// Write response to json
+	response.WriteJSON(w, resp, http.StatusCreated)
+}
+
+// registerUserHandler signs up a user on behalf of the authenticated client
+// (POST /v1/oauth/users)
+func (s *Service) registerUserHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.cnf.Oauth.EnableUserRegistration {
+		writeError(w, ErrUserRegistrationDisabled)
+		return
+	}
+
+	// Client auth
+	client, err := s.authenticateClient(r)
+	if err != nil {
+		response.InvalidClientError(w, err.Error())
+		return
+	}
+
+	registrationRequest := new(UserRegistrationRequest)
+	if err := json.NewDecoder(r.Body).Decode(registrationRequest); err != nil {
+		response.ErrorWithDescription(w, "invalid_request", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Create the user
+	resp, err := s.RegisterUser(r.Context(), client, registrationRequest)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Write response to json
+	response.WriteJSON(w, resp, http.StatusCreated)
+}
+
+// passwordResetHandler emails a password reset token to the user
+// (POST /v1/oauth/password-reset)
+func (s *Service) passwordResetHandler(w http.ResponseWriter, r *http.Request) {
+	// Client auth
+	if _, err := s.authenticateClient(r); err != nil {
+		response.InvalidClientError(w, err.Error())
+		return
+	}
+
+	resetRequest := new(PasswordResetRequest)
+	if err := json.NewDecoder(r.Body).Decode(resetRequest); err != nil {
+		response.ErrorWithDescription(w, "invalid_request", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Unknown users get the same response so users cannot be enumerated
+	if err := s.RequestPasswordReset(r.Context(), resetRequest.Username); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// passwordResetConfirmHandler sets a new password with a password reset token
+// (POST /v1/oauth/password-reset/confirm)
+func (s *Service) passwordResetConfirmHandler(w http.ResponseWriter, r *http.Request) {
+	// Client auth
+	if _, err := s.authenticateClient(r); err != nil {
+		response.InvalidClientError(w, err.Error())
+		return
+	}
+
+	confirmRequest := new(PasswordResetConfirmRequest)
+	if err := json.NewDecoder(r.Body).Decode(confirmRequest); err != nil {
+		response.ErrorWithDescription(w, "invalid_request", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.ResetPassword(confirmRequest.Token, confirmRequest.Password); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// emailVerificationHandler emails a verification token to the user
+// (POST /v1/oauth/email-verification)
+func (s *Service) emailVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	// Client auth
+	if _, err := s.authenticateClient(r); err != nil {
+		response.InvalidClientError(w, err.Error())
+		return
+	}
+
+	verificationRequest := new(EmailVerificationRequest)
+	if err := json.NewDecoder(r.Body).Decode(verificationRequest); err != nil {
+		response.ErrorWithDescription(w, "invalid_request", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Unknown users get the same response so users cannot be enumerated
+	if err := s.RequestEmailVerification(r.Context(), verificationRequest.Username); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// emailVerificationConfirmHandler verifies the email address of a user with
+// an email verification token (POST /v1/oauth/email-verification/confirm)
+func (s *Service) emailVerificationConfirmHandler(w http.ResponseWriter, r *http.Request) {
+	// Client auth
+	if _, err := s.authenticateClient(r); err != nil {
+		response.InvalidClientError(w, err.Error())
+		return
+	}
+
+	confirmRequest := new(EmailVerificationConfirmRequest)
+	if err := json.NewDecoder(r.Body).Decode(confirmRequest); err != nil {
+		response.ErrorWithDescription(w, "invalid_request", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.VerifyEmail(confirmRequest.Token); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// clientConfigurationHandler returns the registration of a client
+// authenticated with its registration access token
+// (GET /v1/oauth/register/{client_id})
+func (s *Service) clientConfigurationHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.cnf.Oauth.EnableClientRegistration {
+		writeError(w, ErrClientRegistrationDisabled)
+		return
+	}
+
+	// Client auth
+	client, err := s.authenticateRegisteredClient(r)
+	if err != nil {
+		response.UnauthorizedError(w, err.Error())
 		return
 	}
 
+	resp := NewClientRegistrationResponse(client)
+	resp.RegistrationClientURI = s.endpointURL(r)
+
 	// Write response to json
 	response.WriteJSON(w, resp, 200)
 }
 
-// Get client credentials from basic auth and try to authenticate client
-func (s *Service) basicAuthClient(r *http.Request) (*models.OauthClient, error) {
-	// Get client credentials from basic auth
-	clientID, secret, ok := r.BasicAuth()
-	if !ok {
-		return nil, ErrInvalidClientIDOrSecret
+// updateClientConfigurationHandler replaces the registration of a client
+// authenticated with its registration access token
+// (PUT /v1/oauth/register/{client_id})
+func (s *Service) updateClientConfigurationHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.cnf.Oauth.EnableClientRegistration {
+		writeError(w, ErrClientRegistrationDisabled)
+		return
 	}
 
-	// Authenticate the client
-	client, err := s.AuthClient(clientID, secret)
+	// Client auth
+	client, err := s.authenticateRegisteredClient(r)
 	if err != nil {
-		// For security reasons, return a general error message
-		return nil, ErrInvalidClientIDOrSecret
+		response.UnauthorizedError(w, err.Error())
+		return
+	}
+
+	// The request names the client it updates
+	updateRequest := new(ClientUpdateRequest)
+	if err := json.NewDecoder(r.Body).Decode(updateRequest); err != nil {
+		writeError(w, ErrInvalidClientMetadata)
+		return
+	}
+	if updateRequest.ClientID != client.Key {
+		writeError(w, ErrInvalidClientMetadata)
+		return
+	}
+
+	// Update the client
+	resp, err := s.UpdateRegisteredClient(client, &updateRequest.ClientMetadata, updateRequest.ClientSecret)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	resp.RegistrationClientURI = s.endpointURL(r)
+
+	// Write response to json
+	response.WriteJSON(w, resp, 200)
+}
+
+// deleteClientConfigurationHandler deletes a client authenticated with
+// its registration access token
+// (DELETE /v1/oauth/register/{client_id})
+func (s *Service) deleteClientConfigurationHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.cnf.Oauth.EnableClientRegistration {
+		writeError(w, ErrClientRegistrationDisabled)
+		return
+	}
+
+	// Client auth
+	client, err := s.authenticateRegisteredClient(r)
+	if err != nil {
+		response.UnauthorizedError(w, err.Error())
+		return
+	}
+
+	// Delete the client
+	if err := s.DeleteRegisteredClient(client); err != nil {
+		writeError(w, err)
+		return
 	}
 
-	return client, nil
+	response.NoContent(w)
 }
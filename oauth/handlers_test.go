@@ -7,6 +7,8 @@ import (
 
 	"github.com/RichardKnop/go-oauth2-server/oauth"
 	"github.com/RichardKnop/go-oauth2-server/test-util"
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -21,12 +23,35 @@ func (suite *OauthTestSuite) TestTokensHandlerClientAuthenticationRequired() {
 	suite.router.ServeHTTP(w, r)
 
 	// Check the response
-	testutil.TestResponseForError(
+	testutil.TestResponseForOauthError(
 		suite.T(),
 		w,
+		"invalid_client",
 		oauth.ErrInvalidClientIDOrSecret.Error(),
 		401,
 	)
+	assert.Equal(suite.T(), "Basic realm=go_oauth2_server", w.Header().Get("WWW-Authenticate"))
+}
+
+func (suite *OauthTestSuite) TestTokensHandlerGrantTypeMissing() {
+	// Make a request
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
+	r.PostForm = url.Values{"scope": {"read"}}
+
+	// Serve the request
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+
+	// Check the response
+	testutil.TestResponseForOauthError(
+		suite.T(),
+		w,
+		"invalid_request",
+		oauth.ErrGrantTypeMissing.Error(),
+		400,
+	)
 }
 
 func (suite *OauthTestSuite) TestTokensHandlerInvalidGrantType() {
@@ -41,9 +66,10 @@ func (suite *OauthTestSuite) TestTokensHandlerInvalidGrantType() {
 	suite.router.ServeHTTP(w, r)
 
 	// Check the response
-	testutil.TestResponseForError(
+	testutil.TestResponseForOauthError(
 		suite.T(),
 		w,
+		"unsupported_grant_type",
 		oauth.ErrInvalidGrantType.Error(),
 		400,
 	)
@@ -67,3 +93,59 @@ func (suite *OauthTestSuite) TestIntrospectHandlerClientAuthenticationRequired()
 		401,
 	)
 }
+
+func (suite *OauthTestSuite) TestHandlersSetSecurityHeaders() {
+	accessToken, err := suite.service.GrantAccessToken(suite.clients[0], nil, 3600, "read")
+	assert.NoError(suite.T(), err)
+
+	testCases := []struct {
+		path   string
+		form   url.Values
+		status int
+	}{
+		{"/v1/oauth/tokens", url.Values{"grant_type": {"client_credentials"}}, 200},
+		{"/v1/oauth/introspect", url.Values{"token": {accessToken.Token}}, 200},
+		{"/v1/oauth/tokens", url.Values{"grant_type": {"bogus"}}, 400},
+	}
+
+	for _, testCase := range testCases {
+		r, err := http.NewRequest("POST", "http://1.2.3.4"+testCase.path, nil)
+		assert.NoError(suite.T(), err, "Request setup should not get an error")
+		r.SetBasicAuth("test_client_1", "test_secret")
+		r.PostForm = testCase.form
+
+		w := httptest.NewRecorder()
+		suite.router.ServeHTTP(w, r)
+
+		assert.Equal(suite.T(), testCase.status, w.Code)
+		assert.Equal(suite.T(), "no-store", w.Header().Get("Cache-Control"))
+		assert.Equal(suite.T(), "no-cache", w.Header().Get("Pragma"))
+		assert.Equal(suite.T(), "nosniff", w.Header().Get("X-Content-Type-Options"))
+		assert.Equal(suite.T(), "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	}
+}
+
+func (suite *OauthTestSuite) TestTokensHandlerReadOnlyDatabase() {
+	// Simulate a replica refusing to create the access token
+	suite.db.Callback().Create().Before("gorm:create").Register("test:read_only", func(scope *gorm.Scope) {
+		scope.Err(&pq.Error{Code: "25006", Message: "cannot execute INSERT in a read-only transaction"})
+	})
+	defer suite.db.Callback().Create().Remove("test:read_only")
+
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
+	r.PostForm = url.Values{"grant_type": {"client_credentials"}}
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+
+	testutil.TestResponseForOauthError(
+		suite.T(),
+		w,
+		"temporarily_unavailable",
+		oauth.ErrTemporarilyUnavailable.Error(),
+		503,
+	)
+	assert.Equal(suite.T(), "30", w.Header().Get("Retry-After"))
+}
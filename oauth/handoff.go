@@ -0,0 +1,89 @@
+package oauth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util"
+)
+
+const (
+	// defaultHandoffCodeLifetime is used when the lifetime is not configured
+	defaultHandoffCodeLifetime = 30
+)
+
+var (
+	// ErrHandoffRequiresUser ...
+	ErrHandoffRequiresUser = errors.New("Handoff requires an access token granted to a user")
+	// ErrHandoffClientNotFound ...
+	ErrHandoffClientNotFound = errors.New("Handoff client not found")
+)
+
+// GrantHandoffCode grants a short-lived single-use code the client can
+// exchange for tokens using the authorization_code grant. Handoff codes
+// are not bound to a redirect URI so they must not be exchanged with one.
+func (s *Service) GrantHandoffCode(client *models.OauthClient, user *models.OauthUser, scope string) (*models.OauthAuthorizationCode, error) {
+	return s.GrantAuthorizationCode(
+		client,                     // client
+		user,                       // user
+		s.getHandoffCodeLifetime(), // expires in
+		"",                         // redirect URI
+		scope,                      // scope
+		"",                         // code challenge
+		"",                         // code challenge method
+		"",                         // authorization details
+		"",                         // nonce
+	)
+}
+
+// handoff issues a handoff code for the user of an authenticated web session
+func (s *Service) handoff(r *http.Request, accessToken *models.OauthAccessToken) (*HandoffResponse, error) {
+	// Client credentials tokens have no user to hand off
+	if !accessToken.UserID.Valid {
+		return nil, ErrHandoffRequiresUser
+	}
+
+	// Fetch the client the code will be exchanged by
+	client, err := s.FindClientByClientID(r.Form.Get("client_id"))
+	if err != nil {
+		return nil, ErrHandoffClientNotFound
+	}
+
+	// Fetch the user
+	user := new(models.OauthUser)
+	if s.db.Where("id = ?", accessToken.UserID.String).First(user).RecordNotFound() {
+		return nil, ErrUserNotFound
+	}
+
+	// Requested scope CANNOT include any scope not granted to the session
+	scope := accessToken.Scope
+	if requestedScope := r.Form.Get("scope"); requestedScope != "" {
+		scope, err = s.getNarrowedScope(requestedScope, accessToken.Scope)
+		if err != nil {
+			return nil, err
+		}
+		if !util.SpaceDelimitedStringNotGreater(scope, accessToken.Scope) {
+			return nil, ErrRequestedScopeCannotBeGreater
+		}
+	}
+
+	// Grant the handoff code
+	authorizationCode, err := s.GrantHandoffCode(client, user, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	return &HandoffResponse{
+		Code:      authorizationCode.Code,
+		ExpiresIn: s.getHandoffCodeLifetime(),
+	}, nil
+}
+
+// getHandoffCodeLifetime returns the configured handoff code lifetime
+func (s *Service) getHandoffCodeLifetime() int {
+	if s.cnf.Oauth.HandoffCodeLifetime <= 0 {
+		return defaultHandoffCodeLifetime
+	}
+	return s.cnf.Oauth.HandoffCodeLifetime
+}
@@ -0,0 +1,159 @@
+package oauth_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/test-util"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestHandoffExchange() {
+	// The web app's session token
+	accessToken, err := suite.service.GrantAccessToken(
+		suite.clients[0],
+		suite.users[0],
+		3600,
+		"read read_write",
+	)
+	assert.NoError(suite.T(), err)
+
+	// Request a handoff code for the mobile app
+	w := suite.requestHandoff(accessToken.Token, url.Values{
+		"client_id": {"test_client_2"},
+		"scope":     {"read"},
+	})
+	assert.Equal(suite.T(), 200, w.Code)
+	handoff := new(oauth.HandoffResponse)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), handoff))
+	assert.NotEmpty(suite.T(), handoff.Code)
+	assert.Equal(suite.T(), 30, handoff.ExpiresIn)
+
+	// The mobile app exchanges the code for tokens
+	w = suite.exchangeHandoffCode("test_client_2", handoff.Code)
+	assert.Equal(suite.T(), 200, w.Code)
+	resp := new(oauth.AccessTokenResponse)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), resp))
+	assert.Equal(suite.T(), suite.users[0].ID, resp.UserID)
+	assert.Equal(suite.T(), "read", resp.Scope)
+	assert.NotEmpty(suite.T(), resp.RefreshToken)
+
+	// Tokens are issued to the mobile app
+	issued := new(models.OauthAccessToken)
+	assert.False(suite.T(), suite.db.Where("token = ?", models.HashToken(resp.AccessToken)).First(issued).RecordNotFound())
+	assert.Equal(suite.T(), suite.clients[1].ID, issued.ClientID.String)
+
+	// The code cannot be used again
+	w = suite.exchangeHandoffCode("test_client_2", handoff.Code)
+	testutil.TestResponseForOauthError(
+		suite.T(),
+		w,
+		"invalid_grant",
+		oauth.ErrAuthorizationCodeUsed.Error(),
+		400,
+	)
+}
+
+func (suite *OauthTestSuite) TestHandoffCodeBoundToClient() {
+	authorizationCode, err := suite.service.GrantHandoffCode(suite.clients[1], suite.users[0], "read")
+	assert.NoError(suite.T(), err)
+
+	// Another client cannot exchange the code
+	w := suite.exchangeHandoffCode("test_client_1", authorizationCode.Code)
+	testutil.TestResponseForOauthError(
+		suite.T(),
+		w,
+		"invalid_grant",
+		oauth.ErrAuthorizationCodeNotFound.Error(),
+		400,
+	)
+}
+
+func (suite *OauthTestSuite) TestHandoffCodeExpired() {
+	authorizationCode, err := suite.service.GrantHandoffCode(suite.clients[1], suite.users[0], "read")
+	assert.NoError(suite.T(), err)
+	assert.WithinDuration(
+		suite.T(),
+		time.Now().UTC().Add(30*time.Second),
+		authorizationCode.ExpiresAt,
+		5*time.Second,
+	)
+
+	// Expire the code
+	err = suite.db.Model(authorizationCode).UpdateColumn(
+		"expires_at",
+		time.Now().UTC().Add(-time.Second),
+	).Error
+	assert.NoError(suite.T(), err)
+
+	w := suite.exchangeHandoffCode("test_client_2", authorizationCode.Code)
+	testutil.TestResponseForOauthError(
+		suite.T(),
+		w,
+		"invalid_grant",
+		oauth.ErrAuthorizationCodeExpired.Error(),
+		400,
+	)
+}
+
+func (suite *OauthTestSuite) TestHandoffFailures() {
+	userToken, err := suite.service.GrantAccessToken(suite.clients[0], suite.users[0], 3600, "read")
+	assert.NoError(suite.T(), err)
+	clientToken, err := suite.service.GrantAccessToken(suite.clients[0], nil, 3600, "read")
+	assert.NoError(suite.T(), err)
+
+	testCases := []struct {
+		token  string
+		form   url.Values
+		err    error
+		status int
+	}{
+		{
+			clientToken.Token,
+			url.Values{"client_id": {"test_client_2"}},
+			oauth.ErrHandoffRequiresUser,
+			400,
+		},
+		{
+			userToken.Token,
+			url.Values{"client_id": {"bogus"}},
+			oauth.ErrHandoffClientNotFound,
+			400,
+		},
+		{
+			userToken.Token,
+			url.Values{"client_id": {"test_client_2"}, "scope": {"read_write"}},
+			oauth.ErrRequestedScopeCannotBeGreater,
+			400,
+		},
+	}
+
+	for _, testCase := range testCases {
+		w := suite.requestHandoff(testCase.token, testCase.form)
+		testutil.TestResponseForError(suite.T(), w, testCase.err.Error(), testCase.status)
+	}
+}
+
+func (suite *OauthTestSuite) requestHandoff(token string, form url.Values) *httptest.ResponseRecorder {
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/handoff", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	r.PostForm = form
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	return w
+}
+
+func (suite *OauthTestSuite) exchangeHandoffCode(clientID, code string) *httptest.ResponseRecorder {
+	return suite.tokenRequest(url.Values{
+		"grant_type": {"authorization_code"},
+		"code":       {code},
+	}, clientAuth(clientID))
+}
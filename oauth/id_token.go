@@ -0,0 +1,129 @@
+package oauth
+
+import (
+	"crypto"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/RichardKnop/go-oauth2-server/util/jwt"
+)
+
+const (
+	// OpenIDScope is the scope requesting an OpenID Connect ID token
+	OpenIDScope = "openid"
+)
+
+// GrantIDToken returns a signed OpenID Connect ID token for the user
+func (s *Service) GrantIDToken(client *models.OauthClient, user *models.OauthUser) (string, error) {
+	return s.grantIDToken(client, user, "", nil, time.Time{}, "")
+}
+
+// grantIDToken returns a signed ID token, including sid and auth_time claims
+// if the token is issued for a session, otherwise auth_time is the time the
+// user authenticated at if known. The nonce of the authentication request
+// is included if not empty, and so are the attributes of the user the scope
+// releases in ID tokens.
+func (s *Service) grantIDToken(client *models.OauthClient, user *models.OauthUser, scope string, session *models.OauthSession, authTime time.Time, nonce string) (string, error) {
+	signingKey, err := s.getSigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	attributeClaims, err := s.getUserAttributeClaims(user, scope, releasedInIDToken)
+	if err != nil {
+		return "", err
+	}
+	claims := s.newIDTokenClaims(client, user)
+	for name, value := range attributeClaims {
+		claims[name] = value
+	}
+	if !authTime.IsZero() {
+		claims["auth_time"] = authTime.Unix()
+	}
+	addSessionClaims(claims, session)
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+
+	return jwt.Sign(claims, signingKey)
+}
+
+// GrantFrontChannelIDToken returns a signed ID token for the response of the
+// authorization endpoint, binding it to the nonce of the request and to the
+// code and access token returned alongside it if not empty, see
+// https://openid.net/specs/openid-connect-core-1_0.html#HybridIDToken
+func (s *Service) GrantFrontChannelIDToken(client *models.OauthClient, user *models.OauthUser, nonce, code, accessToken string) (string, error) {
+	signingKey, err := s.getSigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	claims := s.newIDTokenClaims(client, user)
+	claims["nonce"] = nonce
+	if code != "" {
+		claims["c_hash"] = tokenHash(signingKey.Algorithm(), code)
+	}
+	if accessToken != "" {
+		claims["at_hash"] = tokenHash(signingKey.Algorithm(), accessToken)
+	}
+
+	return jwt.Sign(claims, signingKey)
+}
+
+// newIDTokenClaims returns the claims every ID token includes
+func (s *Service) newIDTokenClaims(client *models.OauthClient, user *models.OauthUser) jwt.Claims {
+	now := time.Now().UTC()
+	return jwt.Claims{
+		"iss": s.cnf.JWT.Issuer,
+		"sub": s.subjectIdentifier(client, user.ID),
+		"aud": client.Key,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Duration(s.cnf.Oauth.AccessTokenLifetime) * time.Second).Unix(),
+	}
+}
+
+// tokenHash returns the left half of the hash of the token, hashed with the
+// hash function of the signing algorithm, for c_hash and at_hash claims
+func tokenHash(algorithm, token string) string {
+	hash := crypto.SHA256
+	switch algorithm {
+	case jwt.RS384:
+		hash = crypto.SHA384
+	case jwt.RS512:
+		hash = crypto.SHA512
+	}
+	h := hash.New()
+	h.Write([]byte(token))
+	sum := h.Sum(nil)
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+}
+
+// addIDToken includes an ID token in the response, but only if
+// the openid scope has been granted to a user
+func (s *Service) addIDToken(response *AccessTokenResponse, client *models.OauthClient, user *models.OauthUser, scope string, session *models.OauthSession, authTime time.Time, nonce string) error {
+	if user == nil || !util.StringInSlice(OpenIDScope, strings.Split(scope, " ")) {
+		return nil
+	}
+
+	idToken, err := s.grantIDToken(client, user, scope, session, authTime, nonce)
+	if err != nil {
+		return err
+	}
+	response.IDToken = idToken
+
+	return nil
+}
+
+// NewSigningKey returns the signing key for the configured algorithm,
+// failing if the configured key is not compatible with it
+func NewSigningKey(cnf *config.JWTConfig) (jwt.Key, error) {
+	algorithm := cnf.Algorithm
+	if algorithm == "" {
+		algorithm = jwt.HS256
+	}
+	return jwt.NewKey(algorithm, "", []byte(cnf.Secret), []byte(cnf.PrivateKey))
+}
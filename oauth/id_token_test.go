@@ -0,0 +1,163 @@
+package oauth_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/RichardKnop/go-oauth2-server/util/jwt"
+	"github.com/RichardKnop/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestAuthorizationCodeGrantWithOpenIDScope() {
+	// Insert a test authorization code
+	authTime := time.Now().UTC().Add(-5 * time.Second)
+	err := suite.db.Create(&models.OauthAuthorizationCode{
+		MyGormModel: models.MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: authTime,
+		},
+		Code:        "test_code",
+		ExpiresAt:   time.Now().UTC().Add(+10 * time.Second),
+		Client:      suite.clients[0],
+		User:        suite.users[0],
+		RedirectURI: util.StringOrNull("https://www.example.com"),
+		Scope:       "read_write openid",
+		Nonce:       "test_nonce",
+	}).Error
+	assert.NoError(suite.T(), err, "Inserting test data failed")
+
+	// Prepare a request
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
+	r.PostForm = url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {"test_code"},
+		"redirect_uri": {"https://www.example.com"},
+	}
+
+	// Serve the request
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+
+	// Check the response
+	assert.Equal(suite.T(), 200, w.Code)
+	resp := new(oauth.AccessTokenResponse)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), resp))
+	assert.Equal(suite.T(), "read_write openid", resp.Scope)
+	suite.assertValidIDToken(resp.IDToken, suite.clients[0], suite.users[0])
+
+	// The ID token echoes the nonce and tells when the user authenticated
+	claims, err := jwt.ParseUnverified(resp.IDToken)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), "test_nonce", claims["nonce"])
+		authTimeClaim, _ := claims.Int64("auth_time")
+		assert.Equal(suite.T(), authTime.Unix(), authTimeClaim)
+	}
+}
+
+func (suite *OauthTestSuite) TestRefreshTokenGrantReissuesIDToken() {
+	// Insert a test refresh token
+	err := suite.db.Create(&models.OauthRefreshToken{
+		MyGormModel: models.MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		Token:     "test_token",
+		ExpiresAt: time.Now().UTC().Add(+10 * time.Second),
+		Client:    suite.clients[0],
+		User:      suite.users[0],
+		Scope:     "read_write openid",
+	}).Error
+	assert.NoError(suite.T(), err, "Inserting test data failed")
+
+	// Refresh tokens are rotated, so keep using the newest
+	token := "test_token"
+	for _, testCase := range []struct {
+		scope         string
+		expectIDToken bool
+	}{
+		{"", true},
+		{"read_write openid", true},
+		{"read_write", false},
+	} {
+		// Make a request
+		r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+		assert.NoError(suite.T(), err, "Request setup should not get an error")
+		r.SetBasicAuth("test_client_1", "test_secret")
+		r.PostForm = url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {token},
+			"scope":         {testCase.scope},
+		}
+
+		// Serve the request
+		w := httptest.NewRecorder()
+		suite.router.ServeHTTP(w, r)
+
+		// Check the response
+		assert.Equal(suite.T(), 200, w.Code)
+		resp := new(oauth.AccessTokenResponse)
+		assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), resp))
+		token = resp.RefreshToken
+		if testCase.expectIDToken {
+			suite.assertValidIDToken(resp.IDToken, suite.clients[0], suite.users[0])
+		} else {
+			assert.Empty(suite.T(), resp.IDToken)
+		}
+	}
+}
+
+func (suite *OauthTestSuite) TestIDTokenSignedWithConfiguredAlgorithm() {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(suite.T(), err)
+	suite.cnf.JWT.Algorithm = jwt.RS256
+	suite.cnf.JWT.PrivateKey = string(pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	}))
+	defer func() {
+		suite.cnf.JWT.Algorithm = ""
+		suite.cnf.JWT.PrivateKey = ""
+	}()
+
+	idToken, err := suite.service.GrantIDToken(suite.clients[0], suite.users[0])
+	assert.NoError(suite.T(), err)
+
+	header, err := jwt.ParseHeader(idToken)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), jwt.RS256, header.Algorithm)
+
+	signingKey, err := oauth.NewSigningKey(&suite.cnf.JWT)
+	assert.NoError(suite.T(), err)
+	_, err = jwt.Parse(idToken, signingKey)
+	assert.NoError(suite.T(), err)
+
+	// An incompatible key is rejected
+	suite.cnf.JWT.Algorithm = jwt.ES256
+	_, err = suite.service.GrantIDToken(suite.clients[0], suite.users[0])
+	assert.Equal(suite.T(), jwt.ErrIncompatibleKey, err)
+}
+
+func (suite *OauthTestSuite) assertValidIDToken(idToken string, client *models.OauthClient, user *models.OauthUser) {
+	signingKey, err := jwt.NewHS256("", []byte(suite.cnf.JWT.Secret))
+	assert.NoError(suite.T(), err)
+
+	claims, err := jwt.Parse(idToken, signingKey)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), suite.cnf.JWT.Issuer, claims["iss"])
+		assert.Equal(suite.T(), user.ID, claims["sub"])
+		assert.Equal(suite.T(), client.Key, claims["aud"])
+	}
+}
@@ -42,22 +42,56 @@ func (s *Service) introspectToken(r *http.Request, client *models.OauthClient) (
 		tokenTypeHint = AccessTokenHint
 	}
 
+	// The hint only tells which type of token to look up first
+	var lookups []func(token string, client *models.OauthClient) (*IntrospectResponse, error)
 	switch tokenTypeHint {
 	case AccessTokenHint:
-		accessToken, err := s.Authenticate(token)
-		if err != nil {
-			return nil, err
-		}
-		return s.NewIntrospectResponseFromAccessToken(accessToken)
+		lookups = append(lookups, s.introspectAccessToken, s.introspectRefreshToken)
 	case RefreshTokenHint:
-		refreshToken, err := s.GetValidRefreshToken(token, client)
+		lookups = append(lookups, s.introspectRefreshToken, s.introspectAccessToken)
+	default:
+		return nil, ErrTokenHintInvalid
+	}
+
+	for _, lookup := range lookups {
+		introspectResponse, err := lookup(token, client)
 		if err != nil {
 			return nil, err
 		}
-		return s.NewIntrospectResponseFromRefreshToken(refreshToken)
-	default:
-		return nil, ErrTokenHintInvalid
+		if introspectResponse != nil {
+			return introspectResponse, nil
+		}
 	}
+
+	// Tokens which are unknown, expired or have been revoked are not
+	// told apart, see https://tools.ietf.org/html/rfc7662#section-2.2
+	return &IntrospectResponse{Active: false}, nil
+}
+
+// introspectAccessToken returns nil if the token is not an active access token
+func (s *Service) introspectAccessToken(token string, client *models.OauthClient) (*IntrospectResponse, error) {
+	// Introspection is the token's record, so never use the cache
+	accessToken, err := s.authenticate(token, false)
+	if err == ErrAccessTokenNotFound || err == ErrAccessTokenExpired || err == ErrClientDisabled {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.NewIntrospectResponseFromAccessToken(accessToken)
+}
+
+// introspectRefreshToken returns nil if the token is not an active refresh token
+// of the client, refresh tokens are only ever presented by their own client
+func (s *Service) introspectRefreshToken(token string, client *models.OauthClient) (*IntrospectResponse, error) {
+	refreshToken, err := s.GetValidRefreshToken(token, client)
+	if err == ErrRefreshTokenNotFound || err == ErrRefreshTokenExpired || err == ErrRefreshTokenReused {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.NewIntrospectResponseFromRefreshToken(refreshToken)
 }
 
 // NewIntrospectResponseFromAccessToken ...
@@ -67,26 +101,49 @@ func (s *Service) NewIntrospectResponseFromAccessToken(accessToken *models.Oauth
 		Scope:     accessToken.Scope,
 		TokenType: tokentypes.Bearer,
 		ExpiresAt: int(accessToken.ExpiresAt.Unix()),
+		IssuedAt:  accessToken.CreatedAt.Unix(),
+		Issuer:    s.cnf.JWT.Issuer,
+		Acr:       accessToken.Acr,
+		Audience:  accessToken.Audience,
+		Act:       newActor(accessToken.Actor),
+		Cnf:       newConfirmation(accessToken),
+	}
+	introspectResponse.AuthorizationDetails = rawAuthorizationDetails(accessToken.AuthorizationDetails)
+	introspectResponse.Extra = customClaims(accessToken)
+
+	// Resource servers must validate the DPoP proof of bound tokens
+	if accessToken.JWKThumbprint != "" {
+		introspectResponse.TokenType = tokentypes.DPoP
 	}
 
+	client := new(models.OauthClient)
 	if accessToken.ClientID.Valid {
-		client := new(models.OauthClient)
-		notFound := s.db.Select("key").First(client, accessToken.ClientID.String).
-			RecordNotFound()
+		notFound := s.db.Select("key, name, first_party, subject_type, sector_identifier_uri").
+			Where("id = ?", accessToken.ClientID.String).First(client).RecordNotFound()
 		if notFound {
 			return nil, ErrClientNotFound
 		}
 		introspectResponse.ClientID = client.Key
+		introspectResponse.FirstParty = client.FirstParty
+		if s.cnf.Oauth.IntrospectClientName && client.Name.Valid {
+			introspectResponse.ClientName = client.Name.String
+		}
 	}
 
 	if accessToken.UserID.Valid {
 		user := new(models.OauthUser)
 		notFound := s.db.Select("username").Where("id = ?", accessToken.UserID.String).
-			First(user, accessToken.UserID.String).RecordNotFound()
+			First(user).RecordNotFound()
 		if notFound {
 			return nil, ErrUserNotFound
 		}
 		introspectResponse.Username = user.Username
+		introspectResponse.Subject = s.subjectIdentifier(client, accessToken.UserID.String)
+	}
+
+	if session := s.findSession(accessToken.SessionID); session != nil {
+		introspectResponse.Sid = session.ID
+		introspectResponse.AuthTime = session.AuthTime.Unix()
 	}
 
 	return introspectResponse, nil
@@ -99,26 +156,40 @@ func (s *Service) NewIntrospectResponseFromRefreshToken(refreshToken *models.Oau
 		Scope:     refreshToken.Scope,
 		TokenType: tokentypes.Bearer,
 		ExpiresAt: int(refreshToken.ExpiresAt.Unix()),
+		IssuedAt:  refreshToken.CreatedAt.Unix(),
+		Issuer:    s.cnf.JWT.Issuer,
+		GrantType: refreshToken.GrantType,
 	}
+	introspectResponse.AuthorizationDetails = rawAuthorizationDetails(refreshToken.AuthorizationDetails)
 
+	client := new(models.OauthClient)
 	if refreshToken.ClientID.Valid {
-		client := new(models.OauthClient)
-		notFound := s.db.Select("key").First(client, refreshToken.ClientID.String).
-			RecordNotFound()
+		notFound := s.db.Select("key, name, first_party, subject_type, sector_identifier_uri").
+			Where("id = ?", refreshToken.ClientID.String).First(client).RecordNotFound()
 		if notFound {
 			return nil, ErrClientNotFound
 		}
 		introspectResponse.ClientID = client.Key
+		introspectResponse.FirstParty = client.FirstParty
+		if s.cnf.Oauth.IntrospectClientName && client.Name.Valid {
+			introspectResponse.ClientName = client.Name.String
+		}
 	}
 
 	if refreshToken.UserID.Valid {
 		user := new(models.OauthUser)
 		notFound := s.db.Select("username").Where("id = ?", refreshToken.UserID.String).
-			First(user, refreshToken.UserID.String).RecordNotFound()
+			First(user).RecordNotFound()
 		if notFound {
 			return nil, ErrUserNotFound
 		}
 		introspectResponse.Username = user.Username
+		introspectResponse.Subject = s.subjectIdentifier(client, refreshToken.UserID.String)
+	}
+
+	if session := s.findSession(refreshToken.SessionID); session != nil {
+		introspectResponse.Sid = session.ID
+		introspectResponse.AuthTime = session.AuthTime.Unix()
 	}
 
 	return introspectResponse, nil
@@ -34,8 +34,11 @@ func (suite *OauthTestSuite) TestNewIntrospectResponseFromAccessToken() {
 		Scope:     accessToken.Scope,
 		TokenType: tokentypes.Bearer,
 		ExpiresAt: int(accessToken.ExpiresAt.Unix()),
+		IssuedAt:  accessToken.CreatedAt.Unix(),
+		Issuer:    suite.cnf.JWT.Issuer,
 		ClientID:  suite.clients[0].Key,
 		Username:  suite.users[0].Username,
+		Subject:   string(suite.users[0].ID),
 	}
 
 	actual, err := suite.service.NewIntrospectResponseFromAccessToken(accessToken)
@@ -50,6 +53,7 @@ func (suite *OauthTestSuite) TestNewIntrospectResponseFromAccessToken() {
 
 	accessToken.UserID = util.StringOrNull("")
 	expected.Username = ""
+	expected.Subject = ""
 	actual, err = suite.service.NewIntrospectResponseFromAccessToken(accessToken)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), expected, actual)
@@ -74,8 +78,11 @@ func (suite *OauthTestSuite) TestNewIntrospectResponseFromRefreshToken() {
 		Scope:     refreshToken.Scope,
 		TokenType: tokentypes.Bearer,
 		ExpiresAt: int(refreshToken.ExpiresAt.Unix()),
+		IssuedAt:  refreshToken.CreatedAt.Unix(),
+		Issuer:    suite.cnf.JWT.Issuer,
 		ClientID:  suite.clients[0].Key,
 		Username:  suite.users[0].Username,
+		Subject:   string(suite.users[0].ID),
 	}
 
 	actual, err := suite.service.NewIntrospectResponseFromRefreshToken(refreshToken)
@@ -90,6 +97,7 @@ func (suite *OauthTestSuite) TestNewIntrospectResponseFromRefreshToken() {
 
 	refreshToken.UserID = util.StringOrNull("")
 	expected.Username = ""
+	expected.Subject = ""
 	actual, err = suite.service.NewIntrospectResponseFromRefreshToken(refreshToken)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), expected, actual)
@@ -171,7 +179,7 @@ func (suite *OauthTestSuite) TestHandleIntrospectAccessToken() {
 	assert.NoError(suite.T(), err)
 	testutil.TestResponseObject(suite.T(), w, expected, 200)
 
-	// With incorrect token hint
+	// With incorrect token hint, the other token type is looked up as well
 	r.PostForm = url.Values{
 		"token":           {accessToken.Token},
 		"token_type_hint": {oauth.RefreshTokenHint},
@@ -181,13 +189,8 @@ func (suite *OauthTestSuite) TestHandleIntrospectAccessToken() {
 	w = httptest.NewRecorder()
 	suite.router.ServeHTTP(w, r)
 
-	// Check response
-	testutil.TestResponseForError(
-		suite.T(),
-		w,
-		oauth.ErrRefreshTokenNotFound.Error(),
-		404,
-	)
+	// Check the response
+	testutil.TestResponseObject(suite.T(), w, expected, 200)
 
 	// Without token hint
 	r.PostForm = url.Values{
@@ -240,7 +243,7 @@ func (suite *OauthTestSuite) TestHandleIntrospectRefreshToken() {
 	assert.NoError(suite.T(), err)
 	testutil.TestResponseObject(suite.T(), w, expected, 200)
 
-	// With incorrect token hint
+	// With incorrect token hint, the other token type is looked up as well
 	r.PostForm = url.Values{
 		"token":           {refreshToken.Token},
 		"token_type_hint": {oauth.AccessTokenHint},
@@ -250,13 +253,8 @@ func (suite *OauthTestSuite) TestHandleIntrospectRefreshToken() {
 	w = httptest.NewRecorder()
 	suite.router.ServeHTTP(w, r)
 
-	// Check response
-	testutil.TestResponseForError(
-		suite.T(),
-		w,
-		oauth.ErrAccessTokenNotFound.Error(),
-		404,
-	)
+	// Check the response
+	testutil.TestResponseObject(suite.T(), w, expected, 200)
 
 	// Without token hint
 	r.PostForm = url.Values{
@@ -267,71 +265,117 @@ func (suite *OauthTestSuite) TestHandleIntrospectRefreshToken() {
 	w = httptest.NewRecorder()
 	suite.router.ServeHTTP(w, r)
 
-	// Check response
-	testutil.TestResponseForError(
-		suite.T(),
-		w,
-		oauth.ErrAccessTokenNotFound.Error(),
-		404,
-	)
+	// Check the response
+	testutil.TestResponseObject(suite.T(), w, expected, 200)
+
+	// Refresh tokens of other clients are not disclosed
+	r.SetBasicAuth("test_client_2", "test_secret")
+
+	// Serve the request
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+
+	// Check the response
+	testutil.TestResponseObject(suite.T(), w, &oauth.IntrospectResponse{Active: false}, 200)
 }
 
 func (suite *OauthTestSuite) TestHandleIntrospectInactiveToken() {
-	// Make a request
-	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/introspect", nil)
-	assert.NoError(suite.T(), err, "Request setup should not get an error")
-	r.SetBasicAuth("test_client_1", "test_secret")
+	// Insert an expired test access token
+	expiredToken := &models.OauthAccessToken{
+		MyGormModel: models.MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		Token:     "test_token_introspect_expired",
+		ExpiresAt: time.Now().UTC().Add(-10 * time.Second),
+		Client:    suite.clients[0],
+		Scope:     "read_write",
+	}
+	err := suite.db.Create(expiredToken).Error
+	assert.NoError(suite.T(), err, "Inserting test data failed")
 
-	// With access token hint
-	r.PostForm = url.Values{
-		"token":           {"unexisting_token"},
-		"token_type_hint": {oauth.AccessTokenHint},
+	// Unknown and expired tokens are inactive, whatever the token hint
+	for _, form := range []url.Values{
+		{"token": {"unexisting_token"}, "token_type_hint": {oauth.AccessTokenHint}},
+		{"token": {"unexisting_token"}, "token_type_hint": {oauth.RefreshTokenHint}},
+		{"token": {"unexisting_token"}},
+		{"token": {expiredToken.Token}},
+	} {
+		// Make a request
+		r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/introspect", nil)
+		assert.NoError(suite.T(), err, "Request setup should not get an error")
+		r.SetBasicAuth("test_client_1", "test_secret")
+		r.PostForm = form
+
+		// And serve the request
+		w := httptest.NewRecorder()
+		suite.router.ServeHTTP(w, r)
+
+		// Check response
+		testutil.TestResponseObject(suite.T(), w, &oauth.IntrospectResponse{Active: false}, 200)
 	}
+}
 
-	// And serve the request
-	w := httptest.NewRecorder()
-	suite.router.ServeHTTP(w, r)
+func (suite *OauthTestSuite) TestNewIntrospectResponseIncludesClientName() {
+	suite.cnf.Oauth.IntrospectClientName = true
+	defer func() { suite.cnf.Oauth.IntrospectClientName = false }()
 
-	// Check response
-	testutil.TestResponseForError(
-		suite.T(),
-		w,
-		oauth.ErrAccessTokenNotFound.Error(),
-		404,
-	)
+	// Name the client
+	err := suite.db.Model(suite.clients[1]).UpdateColumn("name", "Test Client 2").Error
+	assert.NoError(suite.T(), err)
+	defer suite.db.Model(suite.clients[1]).UpdateColumn("name", nil)
 
-	// With refresh token hint
-	r.PostForm = url.Values{
-		"token":           {"unexisting_token"},
-		"token_type_hint": {oauth.RefreshTokenHint},
+	accessToken := &models.OauthAccessToken{
+		MyGormModel: models.MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		Token:     "test_token_introspect_name",
+		ExpiresAt: time.Now().UTC().Add(+10 * time.Second),
+		ClientID:  util.StringOrNull(string(suite.clients[1].ID)),
+		Scope:     "read_write",
 	}
+	actual, err := suite.service.NewIntrospectResponseFromAccessToken(accessToken)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), suite.clients[1].Key, actual.ClientID)
+	assert.Equal(suite.T(), "Test Client 2", actual.ClientName)
 
-	// Serve the request
-	w = httptest.NewRecorder()
-	suite.router.ServeHTTP(w, r)
+	// Clients without a name are not affected
+	accessToken.ClientID = util.StringOrNull(string(suite.clients[0].ID))
+	actual, err = suite.service.NewIntrospectResponseFromAccessToken(accessToken)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), suite.clients[0].Key, actual.ClientID)
+	assert.Empty(suite.T(), actual.ClientName)
 
-	// Check response
-	testutil.TestResponseForError(
-		suite.T(),
-		w,
-		oauth.ErrRefreshTokenNotFound.Error(),
-		404,
-	)
+	// The name is only included when enabled
+	suite.cnf.Oauth.IntrospectClientName = false
+	accessToken.ClientID = util.StringOrNull(string(suite.clients[1].ID))
+	actual, err = suite.service.NewIntrospectResponseFromAccessToken(accessToken)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), actual.ClientName)
+}
 
-	// Without token hint
-	r.PostForm = url.Values{
-		"token": {"unexisting_token"},
-	}
+func (suite *OauthTestSuite) TestNewIntrospectResponseIncludesFirstParty() {
+	assert.NoError(suite.T(), suite.service.SetClientFirstParty(suite.clients[1], true))
+	defer suite.service.SetClientFirstParty(suite.clients[1], false)
 
-	// Serve the request
-	w = httptest.NewRecorder()
-	suite.router.ServeHTTP(w, r)
+	accessToken := &models.OauthAccessToken{
+		MyGormModel: models.MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		Token:     "test_token_introspect_first_party",
+		ExpiresAt: time.Now().UTC().Add(+10 * time.Second),
+		ClientID:  util.StringOrNull(string(suite.clients[1].ID)),
+		Scope:     "read_write",
+	}
+	actual, err := suite.service.NewIntrospectResponseFromAccessToken(accessToken)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), actual.FirstParty)
 
-	// Check response
-	testutil.TestResponseForError(
-		suite.T(),
-		w,
-		oauth.ErrAccessTokenNotFound.Error(),
-		404,
-	)
+	// Third-party clients are not flagged
+	accessToken.ClientID = util.StringOrNull(string(suite.clients[0].ID))
+	actual, err = suite.service.NewIntrospectResponseFromAccessToken(accessToken)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), actual.FirstParty)
 }
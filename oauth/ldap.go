@@ -0,0 +1,95 @@
+package oauth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/util/ldap"
+)
+
+const (
+	// defaultLDAPUserFilter is used when the user filter is not configured
+	defaultLDAPUserFilter = "(uid=%s)"
+	// defaultLDAPTimeout is used when the timeout is not configured
+	defaultLDAPTimeout = 10
+	// ldapNoAttributes asks the server for no attributes, see RFC 4511
+	ldapNoAttributes = "1.1"
+)
+
+var (
+	// ErrInvalidLDAPCACertificate ...
+	ErrInvalidLDAPCACertificate = errors.New("Invalid LDAP CA certificate")
+)
+
+// LDAPAuthenticator is a UserAuthenticator binding against an LDAP
+// directory, such as Active Directory
+type LDAPAuthenticator struct {
+	authenticator     *ldap.Authenticator
+	usernameAttribute string
+}
+
+// NewLDAPAuthenticator returns an authenticator for the configured directory
+func NewLDAPAuthenticator(cnf *config.LDAPConfig) (*LDAPAuthenticator, error) {
+	tlsConfig := new(tls.Config)
+	if cnf.CACertificate != "" {
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM([]byte(cnf.CACertificate)) {
+			return nil, ErrInvalidLDAPCACertificate
+		}
+	}
+
+	userFilter := cnf.UserFilter
+	if userFilter == "" {
+		userFilter = defaultLDAPUserFilter
+	}
+	timeout := cnf.Timeout
+	if timeout <= 0 {
+		timeout = defaultLDAPTimeout
+	}
+	attributes := []string{ldapNoAttributes}
+	if cnf.UsernameAttribute != "" {
+		attributes = []string{cnf.UsernameAttribute}
+	}
+
+	return &LDAPAuthenticator{
+		authenticator: &ldap.Authenticator{
+			URL:          cnf.URL,
+			StartTLS:     cnf.StartTLS,
+			TLSConfig:    tlsConfig,
+			BindDN:       cnf.BindDN,
+			BindPassword: cnf.BindPassword,
+			BaseDN:       cnf.BaseDN,
+			UserFilter:   userFilter,
+			Attributes:   attributes,
+			Timeout:      time.Duration(timeout) * time.Second,
+		},
+		usernameAttribute: cnf.UsernameAttribute,
+	}, nil
+}
+
+// Authenticate binds as the entry of the user with the password
+func (a *LDAPAuthenticator) Authenticate(username, password string) (*AuthenticatedUser, error) {
+	entry, err := a.authenticator.Authenticate(username, password)
+	if err == ldap.ErrUserNotFound {
+		return nil, ErrUserNotFound
+	}
+	if err == ldap.ErrInvalidCredentials {
+		return nil, ErrInvalidUserPassword
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if a.usernameAttribute == "" {
+		return &AuthenticatedUser{Username: username}, nil
+	}
+	localUsername := entry.GetAttributeValue(a.usernameAttribute)
+	if localUsername == "" {
+		return nil, fmt.Errorf("LDAP entry %s has no %s attribute", entry.DN, a.usernameAttribute)
+	}
+	return &AuthenticatedUser{Username: localUsername}, nil
+}
@@ -0,0 +1,140 @@
+package oauth
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+)
+
+const (
+	// defaultLoginLockoutWindow is used when the window is not configured
+	defaultLoginLockoutWindow = 900
+)
+
+var (
+	// ErrTooManyFailedLogins ...
+	ErrTooManyFailedLogins = errors.New("Too many failed logins, try again later")
+)
+
+// checkLoginLockout returns ErrTooManyFailedLogins while the username or the
+// IP address has had as many failed logins within the window as allowed.
+// The same error is returned whether the username exists or not.
+func (s *Service) checkLoginLockout(username, ipAddress string) error {
+	now := time.Now().UTC()
+	if threshold := s.cnf.Oauth.LoginLockoutThreshold; threshold > 0 {
+		locked, err := s.countLoginFailures("username = ?", strings.ToLower(username), now, threshold)
+		if err != nil || locked {
+			return lockoutError(err)
+		}
+	}
+	if threshold := s.cnf.Oauth.LoginIPLockoutThreshold; threshold > 0 && ipAddress != "" {
+		locked, err := s.countLoginFailures("ip_address = ?", ipAddress, now, threshold)
+		if err != nil || locked {
+			return lockoutError(err)
+		}
+	}
+	return nil
+}
+
+// countLoginFailures returns true if the unexpired failed logins
+// matching the condition reach the threshold
+func (s *Service) countLoginFailures(condition, value string, now time.Time, threshold int) (bool, error) {
+	var count int
+	err := s.db.Model(new(models.OauthLoginFailure)).
+		Where(condition, value).Where("expires_at > ?", now).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count >= threshold, nil
+}
+
+// lockoutError returns the database error if there is one
+func lockoutError(err error) error {
+	if err != nil {
+		return err
+	}
+	return ErrTooManyFailedLogins
+}
+
+// AuthLogin authenticates a user logging in from the IP address with the
+// password and any second factor the acr_values or their authenticator
+// require, and returns the authentication context achieved. Logins are
+// refused while the username or the IP address is locked out, and wrong
+// passwords and one-time passwords count towards the lockouts. The same
+// error is returned whether the username exists or the password is wrong.
+func (s *Service) AuthLogin(username, password, acrValues, otp, ipAddress string) (*models.OauthUser, string, error) {
+	// Refuse guessing passwords once too many logins have failed
+	if err := s.checkLoginLockout(username, ipAddress); err != nil {
+		return nil, "", err
+	}
+
+	// Authenticate the user
+	user, err := s.AuthUser(username, password)
+	if err == ErrUserPasswordNotSet {
+		return nil, "", ErrPasswordLoginNotAvailable
+	}
+	if err == ErrUserNotFound || err == ErrInvalidUserPassword {
+		if err := s.recordLoginFailure(username, ipAddress); err != nil {
+			return nil, "", err
+		}
+	}
+	if err == ErrTemporarilyUnavailable {
+		return nil, "", err
+	}
+	if err != nil {
+		// For security reasons, return a general error message
+		return nil, "", ErrInvalidUsernameOrPassword
+	}
+
+	// Require a second factor if a higher assurance level is requested
+	acr, err := s.AuthenticateAcr(user, acrValues, otp)
+	if err == ErrInvalidOTP {
+		// Guessing one-time passwords counts towards the lockout as well
+		if err := s.recordLoginFailure(username, ipAddress); err != nil {
+			return nil, "", err
+		}
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	return user, acr, nil
+}
+
+// recordLoginFailure counts a failed login towards the lockouts
+func (s *Service) recordLoginFailure(username, ipAddress string) error {
+	if s.cnf.Oauth.LoginLockoutThreshold <= 0 && s.cnf.Oauth.LoginIPLockoutThreshold <= 0 {
+		return nil
+	}
+	failure := models.NewOauthLoginFailure(strings.ToLower(username), ipAddress, s.getLoginLockoutWindow())
+	return s.db.Create(failure).Error
+}
+
+// UnlockUser forgets the failed logins of the user, so they can
+// log in again straight away
+func (s *Service) UnlockUser(user *models.OauthUser) error {
+	return s.db.Unscoped().Where("username = ?", strings.ToLower(user.Username)).
+		Delete(new(models.OauthLoginFailure)).Error
+}
+
+// getLoginLockoutWindow returns the configured lockout window
+func (s *Service) getLoginLockoutWindow() int {
+	if s.cnf.Oauth.LoginLockoutWindow <= 0 {
+		return defaultLoginLockoutWindow
+	}
+	return s.cnf.Oauth.LoginLockoutWindow
+}
+
+// RemoteIP returns the IP address of the peer of the request. Forwarded
+// headers are ignored as any client can set them to dodge the lockout.
+func RemoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
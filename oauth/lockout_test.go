@@ -0,0 +1,111 @@
+package oauth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	"github.com/RichardKnop/go-oauth2-server/test-util"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestLoginLockout() {
+	suite.cnf.Oauth.LoginLockoutThreshold = 3
+	defer func() {
+		suite.cnf.Oauth.LoginLockoutThreshold = 0
+	}()
+
+	user, err := suite.service.CreateUser(roles.User, "test@user_locked", "test_password")
+	assert.NoError(suite.T(), err, "Inserting test data failed")
+
+	// Failed logins are refused as usual up to the threshold
+	for i := 0; i < 3; i++ {
+		w := suite.passwordGrantFrom("192.0.2.1:1234", "test@user_locked", "bogus")
+		testutil.TestResponseForOauthError(suite.T(), w, "invalid_grant", oauth.ErrInvalidUsernameOrPassword.Error(), 400)
+	}
+
+	// Then even the right password is refused
+	w := suite.passwordGrantFrom("192.0.2.2:1234", "test@user_locked", "test_password")
+	testutil.TestResponseForOauthError(suite.T(), w, "slow_down", oauth.ErrTooManyFailedLogins.Error(), 429)
+
+	// Unknown usernames are locked out the same
+	for i := 0; i < 3; i++ {
+		suite.passwordGrantFrom("192.0.2.1:1234", "test@user_bogus", "bogus")
+	}
+	w = suite.passwordGrantFrom("192.0.2.1:1234", "test@user_bogus", "bogus")
+	testutil.TestResponseForOauthError(suite.T(), w, "slow_down", oauth.ErrTooManyFailedLogins.Error(), 429)
+
+	// Other users are not affected
+	w = suite.passwordGrantFrom("192.0.2.1:1234", "test@user", "test_password")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	// The lockout ends once the failed logins expire
+	err = suite.db.Model(new(models.OauthLoginFailure)).Where("username = ?", "test@user_locked").
+		UpdateColumn("expires_at", time.Now().UTC().Add(-time.Second)).Error
+	assert.NoError(suite.T(), err)
+	w = suite.passwordGrantFrom("192.0.2.1:1234", "test@user_locked", "test_password")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	// Or when an operator unlocks the user
+	for i := 0; i < 3; i++ {
+		suite.passwordGrantFrom("192.0.2.1:1234", "test@user_locked", "bogus")
+	}
+	w = suite.passwordGrantFrom("192.0.2.1:1234", "test@user_locked", "test_password")
+	assert.Equal(suite.T(), http.StatusTooManyRequests, w.Code)
+	assert.NoError(suite.T(), suite.service.UnlockUser(user))
+	w = suite.passwordGrantFrom("192.0.2.1:1234", "test@user_locked", "test_password")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+}
+
+func (suite *OauthTestSuite) TestLoginIPLockout() {
+	suite.cnf.Oauth.LoginIPLockoutThreshold = 2
+	defer func() {
+		suite.cnf.Oauth.LoginIPLockoutThreshold = 0
+	}()
+
+	// Failed logins for any username count towards the address
+	suite.passwordGrantFrom("192.0.2.1:1234", "test@user_bogus", "bogus")
+	suite.passwordGrantFrom("192.0.2.1:5678", "test@user", "bogus")
+
+	w := suite.passwordGrantFrom("192.0.2.1:1234", "test@user", "test_password")
+	testutil.TestResponseForOauthError(suite.T(), w, "slow_down", oauth.ErrTooManyFailedLogins.Error(), 429)
+
+	// Other addresses are not affected
+	w = suite.passwordGrantFrom("192.0.2.2:1234", "test@user", "test_password")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+}
+
+func (suite *OauthTestSuite) TestAuthLoginLockout() {
+	suite.cnf.Oauth.LoginLockoutThreshold = 2
+	defer func() {
+		suite.cnf.Oauth.LoginLockoutThreshold = 0
+	}()
+
+	// Browser logins count towards the same lockout as the password grant
+	_, _, err := suite.service.AuthLogin("test@user", "bogus", "", "", "192.0.2.1")
+	assert.Equal(suite.T(), oauth.ErrInvalidUsernameOrPassword, err)
+	w := suite.passwordGrantFrom("192.0.2.1:1234", "test@user", "bogus")
+	testutil.TestResponseForOauthError(suite.T(), w, "invalid_grant", oauth.ErrInvalidUsernameOrPassword.Error(), 400)
+
+	_, _, err = suite.service.AuthLogin("test@user", "test_password", "", "", "192.0.2.2")
+	assert.Equal(suite.T(), oauth.ErrTooManyFailedLogins, err)
+	w = suite.passwordGrantFrom("192.0.2.2:1234", "test@user", "test_password")
+	testutil.TestResponseForOauthError(suite.T(), w, "slow_down", oauth.ErrTooManyFailedLogins.Error(), 429)
+}
+
+func (suite *OauthTestSuite) passwordGrantFrom(remoteAddr, username, password string) *httptest.ResponseRecorder {
+	r := suite.newTokenRequest(url.Values{
+		"grant_type": {"password"},
+		"username":   {username},
+		"password":   {password},
+	}, nil)
+	r.RemoteAddr = remoteAddr
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	return w
+}
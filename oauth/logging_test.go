@@ -0,0 +1,58 @@
+package oauth_test
+
+import (
+	"bytes"
+	"encoding/json"
+	stdlog "log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/RichardKnop/go-oauth2-server/log"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/util/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/negroni"
+)
+
+func (suite *OauthTestSuite) TestGrantDoesNotLogSecrets() {
+	// Capture all log output for the duration of the test
+	info, warning, errorLogger, fatal := log.INFO, log.WARNING, log.ERROR, log.FATAL
+	defer func() {
+		log.INFO, log.WARNING, log.ERROR, log.FATAL = info, warning, errorLogger, fatal
+	}()
+	buf := new(bytes.Buffer)
+	log.Set(stdlog.New(buf, "", 0))
+
+	app := negroni.New()
+	app.Use(response.NewURLLogger())
+	app.UseHandler(suite.router)
+
+	// Some clients send credentials in the query string as well
+	query := url.Values{
+		"grant_type": {"password"},
+		"username":   {"test@user"},
+		"password":   {"test_password"},
+	}
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens?"+query.Encode(), nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
+	r.PostForm = url.Values{"scope": {"read_write"}}
+
+	// Serve the request
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, r)
+
+	// Check the response
+	assert.Equal(suite.T(), 200, w.Code)
+	resp := new(oauth.AccessTokenResponse)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), resp))
+
+	// Check the logs
+	logged := buf.String()
+	assert.Contains(suite.T(), logged, "/v1/oauth/tokens")
+	assert.Contains(suite.T(), logged, log.Redact("test_password"))
+	for _, secret := range []string{"test_password", "test_secret", resp.AccessToken, resp.RefreshToken} {
+		assert.NotContains(suite.T(), logged, secret)
+	}
+}
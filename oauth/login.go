@@ -1,11 +1,32 @@
 package oauth
 
 import (
+	"strings"
+
 	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util"
+)
+
+const (
+	// OfflineAccessScope is the scope requesting a refresh token
+	// when RequireOfflineAccess is enabled
+	OfflineAccessScope = "offline_access"
 )
 
 // Login creates an access token and refresh token for a user (logs him/her in)
 func (s *Service) Login(client *models.OauthClient, user *models.OauthUser, scope string) (*models.OauthAccessToken, *models.OauthRefreshToken, error) {
+	return s.grantTokens(client, user, scope, "", true)
+}
+
+// login is used by grants of the token endpoint, which only issue
+// a refresh token for offline access if the configuration requires it
+func (s *Service) login(client *models.OauthClient, user *models.OauthUser, scope, grantType string) (*models.OauthAccessToken, *models.OauthRefreshToken, error) {
+	return s.grantTokens(client, user, scope, grantType, s.offlineAccessGranted(scope))
+}
+
+// grantTokens creates an access token and, unless withRefreshToken is false,
+// a refresh token for a user with the lifetimes of the grant type
+func (s *Service) grantTokens(client *models.OauthClient, user *models.OauthUser, scope, grantType string, withRefreshToken bool) (*models.OauthAccessToken, *models.OauthRefreshToken, error) {
 	// Return error if user's role is not allowed to use this service
 	if !s.IsRoleAllowed(user.RoleID.String) {
 		// For security reasons, return a general error message
@@ -16,23 +37,42 @@ func (s *Service) Login(client *models.OauthClient, user *models.OauthUser, scop
 	accessToken, err := s.GrantAccessToken(
 		client,
 		user,
-		s.cnf.Oauth.AccessTokenLifetime, // expires in
+		s.getAccessTokenLifetime(client, grantType), // expires in
 		scope,
 	)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	// Create or retrieve a refresh token
-	refreshToken, err := s.GetOrCreateRefreshToken(
+	if !withRefreshToken {
+		return accessToken, nil, nil
+	}
+
+	// Create a refresh token for the scope granted, which the roles
+	// of the user may have narrowed
+	refreshToken, err := s.CreateRefreshToken(
 		client,
 		user,
-		s.cnf.Oauth.RefreshTokenLifetime, // expires in
-		scope,
+		s.getRefreshTokenLifetime(client, grantType), // expires in
+		accessToken.Scope,
 	)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	// The tokens start a new lineage unless the grant continues one
+	if err := s.setLineage(accessToken, refreshToken, refreshToken.ID, ""); err != nil {
+		return nil, nil, err
+	}
+
 	return accessToken, refreshToken, nil
 }
+
+// offlineAccessGranted returns false if refresh tokens require
+// the offline_access scope and it is not part of the scope
+func (s *Service) offlineAccessGranted(scope string) bool {
+	if !s.cnf.Oauth.RequireOfflineAccess {
+		return true
+	}
+	return util.StringInSlice(OfflineAccessScope, strings.Fields(scope))
+}
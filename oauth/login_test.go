@@ -0,0 +1,56 @@
+package oauth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestRefreshTokenRequiresOfflineAccess() {
+	suite.cnf.Oauth.RequireOfflineAccess = true
+	defer func() { suite.cnf.Oauth.RequireOfflineAccess = false }()
+
+	// Without offline_access only an access token is issued
+	resp := suite.decodeAccessTokenResponse(suite.passwordGrantWithScope("read_write"))
+	assert.NotEmpty(suite.T(), resp.AccessToken)
+	assert.Empty(suite.T(), resp.RefreshToken)
+	var count int
+	suite.db.Model(new(models.OauthRefreshToken)).Count(&count)
+	assert.Equal(suite.T(), 0, count)
+
+	// With offline_access a refresh token is issued as well
+	resp = suite.decodeAccessTokenResponse(suite.passwordGrantWithScope("read_write offline_access"))
+	assert.NotEmpty(suite.T(), resp.RefreshToken)
+
+	// Narrowing the scope on refresh keeps offline access
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
+	r.PostForm = url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {resp.RefreshToken},
+		"scope":         {"read_write"},
+	}
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	resp = suite.decodeAccessTokenResponse(w)
+	assert.Equal(suite.T(), "read_write", resp.Scope)
+	assert.NotEmpty(suite.T(), resp.RefreshToken)
+}
+
+func (suite *OauthTestSuite) TestRefreshTokenIssuedWithoutOfflineAccess() {
+	resp := suite.decodeAccessTokenResponse(suite.passwordGrantWithScope("read_write"))
+	assert.NotEmpty(suite.T(), resp.RefreshToken)
+}
+
+func (suite *OauthTestSuite) passwordGrantWithScope(scope string) *httptest.ResponseRecorder {
+	return suite.tokenRequest(url.Values{
+		"grant_type": {"password"},
+		"username":   {"test@user"},
+		"password":   {"test_password"},
+		"scope":      {scope},
+	}, nil)
+}
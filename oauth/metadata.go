@@ -0,0 +1,139 @@
+package oauth
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util/jwt"
+)
+
+const (
+	// authorizationEndpointPath is served by the web service
+	authorizationEndpointPath = "/web/authorize"
+)
+
+var (
+	// claimsSupported are the claims of ID tokens and UserInfo responses
+	claimsSupported = []string{
+		"iss", "sub", "aud", "iat", "exp", "auth_time", "nonce", "sid",
+		"preferred_username", "updated_at", "email",
+	}
+)
+
+// Metadata is the authorization server metadata (RFC 8414)
+// clients can use to discover what the server supports, it includes
+// the OpenID Provider metadata required by OpenID Connect Discovery
+type Metadata struct {
+	Issuer                                    string   `json:"issuer"`
+	JWKSURI                                   string   `json:"jwks_uri,omitempty"`
+	AuthorizationEndpoint                     string   `json:"authorization_endpoint"`
+	TokenEndpoint                             string   `json:"token_endpoint"`
+	IntrospectionEndpoint                     string   `json:"introspection_endpoint"`
+	RevocationEndpoint                        string   `json:"revocation_endpoint"`
+	UserinfoEndpoint                          string   `json:"userinfo_endpoint"`
+	ScopesSupported                           []string `json:"scopes_supported"`
+	ResponseTypesSupported                    []string `json:"response_types_supported"`
+	GrantTypesSupported                       []string `json:"grant_types_supported"`
+	TokenEndpointAuthMethodsSupported         []string `json:"token_endpoint_auth_methods_supported"`
+	IntrospectionEndpointAuthMethodsSupported []string `json:"introspection_endpoint_auth_methods_supported"`
+	IDTokenSigningAlgValuesSupported          []string `json:"id_token_signing_alg_values_supported"`
+	SubjectTypesSupported                     []string `json:"subject_types_supported"`
+	ClaimsSupported                           []string `json:"claims_supported"`
+	CodeChallengeMethodsSupported             []string `json:"code_challenge_methods_supported,omitempty"`
+	DeviceAuthorizationEndpoint               string   `json:"device_authorization_endpoint,omitempty"`
+	PushedAuthorizationRequestEndpoint        string   `json:"pushed_authorization_request_endpoint"`
+	RequirePushedAuthorizationRequests        bool     `json:"require_pushed_authorization_requests"`
+	BackchannelAuthenticationEndpoint         string   `json:"backchannel_authentication_endpoint,omitempty"`
+	BackchannelTokenDeliveryModesSupported    []string `json:"backchannel_token_delivery_modes_supported,omitempty"`
+	AuthorizationDetailsTypesSupported        []string `json:"authorization_details_types_supported,omitempty"`
+	TLSClientCertificateBoundAccessTokens     bool     `json:"tls_client_certificate_bound_access_tokens,omitempty"`
+	DPoPSigningAlgValuesSupported             []string `json:"dpop_signing_alg_values_supported"`
+	RegistrationEndpoint                      string   `json:"registration_endpoint,omitempty"`
+}
+
+// getMetadata describes the server as currently configured,
+// prefix is the path the oauth routes are registered under
+func (s *Service) getMetadata(prefix string) *Metadata {
+	issuer := strings.TrimSuffix(s.cnf.JWT.Issuer, "/")
+
+	// Response types of the authorization endpoint
+	var responseTypes []string
+	for _, responseType := range supportedResponseTypes {
+		if s.responseTypeEnabled(responseType) {
+			responseTypes = append(responseTypes, responseType)
+		}
+	}
+
+	algorithm := s.cnf.JWT.Algorithm
+	if algorithm == "" {
+		algorithm = jwt.HS256
+	}
+
+	metadata := &Metadata{
+		Issuer:                                    issuer,
+		AuthorizationEndpoint:                     issuer + authorizationEndpointPath,
+		TokenEndpoint:                             issuer + prefix + tokensPath,
+		IntrospectionEndpoint:                     issuer + prefix + introspectPath,
+		RevocationEndpoint:                        issuer + prefix + revokePath,
+		UserinfoEndpoint:                          issuer + prefix + userInfoPath,
+		ScopesSupported:                           s.getSupportedScopes(),
+		ResponseTypesSupported:                    responseTypes,
+		GrantTypesSupported:                       s.getSupportedGrantTypes(),
+		TokenEndpointAuthMethodsSupported:         s.getClientAuthMethods(),
+		IntrospectionEndpointAuthMethodsSupported: s.getClientAuthMethods(),
+		IDTokenSigningAlgValuesSupported:          []string{algorithm},
+		SubjectTypesSupported:                     []string{SubjectTypePublic, SubjectTypePairwise},
+		ClaimsSupported:                           claimsSupported,
+		CodeChallengeMethodsSupported:             []string{CodeChallengeMethodS256, CodeChallengeMethodPlain},
+		PushedAuthorizationRequestEndpoint:        issuer + prefix + parPath,
+		RequirePushedAuthorizationRequests:        s.cnf.Oauth.RequirePushedAuthorizationRequests,
+		AuthorizationDetailsTypesSupported:        s.cnf.Oauth.AuthorizationDetailsTypes,
+		TLSClientCertificateBoundAccessTokens:     s.cnf.Oauth.CertificateBoundAccessTokens,
+		DPoPSigningAlgValuesSupported:             dpopSigningAlgorithms,
+	}
+	if algorithm != jwt.HS256 {
+		metadata.JWKSURI = issuer + jwksPath
+	}
+	if s.grantTypeEnabled(DeviceCodeGrantType) {
+		metadata.DeviceAuthorizationEndpoint = issuer + prefix + devicePath
+	}
+	if s.grantTypeEnabled(CIBAGrantType) {
+		metadata.BackchannelAuthenticationEndpoint = issuer + prefix + cibaPath
+		metadata.BackchannelTokenDeliveryModesSupported = []string{"poll", "ping"}
+	}
+	if s.cnf.Oauth.EnableClientRegistration {
+		metadata.RegistrationEndpoint = issuer + prefix + registerPath
+	}
+
+	return metadata
+}
+
+// getSupportedGrantTypes returns the enabled grant types in alphabetical order
+func (s *Service) getSupportedGrantTypes() []string {
+	var grantTypes []string
+	for grantType := range s.grantTypes() {
+		if s.grantTypeEnabled(grantType) {
+			grantTypes = append(grantTypes, grantType)
+		}
+	}
+	if s.grantTypeEnabled("implicit") {
+		grantTypes = append(grantTypes, "implicit")
+	}
+	sort.Strings(grantTypes)
+	return grantTypes
+}
+
+// getSupportedScopes returns names of all scopes in alphabetical order
+func (s *Service) getSupportedScopes() []string {
+	var scopes []string
+	if cached, ok := s.getCachedScopes(); ok {
+		for name := range cached {
+			scopes = append(scopes, name)
+		}
+	} else {
+		s.db.Model(new(models.OauthScope)).Pluck("scope", &scopes)
+	}
+	sort.Strings(scopes)
+	return scopes
+}
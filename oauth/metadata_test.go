@@ -0,0 +1,88 @@
+package oauth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestMetadata() {
+	metadata := suite.getMetadata()
+	assert.Equal(suite.T(), suite.cnf.JWT.Issuer, metadata.Issuer)
+	assert.Equal(suite.T(), suite.cnf.JWT.Issuer+"/v1/oauth/tokens", metadata.TokenEndpoint)
+	assert.Equal(suite.T(), suite.cnf.JWT.Issuer+"/v1/oauth/introspect", metadata.IntrospectionEndpoint)
+	assert.Equal(suite.T(), suite.cnf.JWT.Issuer+"/v1/oauth/revoke", metadata.RevocationEndpoint)
+	assert.Equal(suite.T(), suite.cnf.JWT.Issuer+"/v1/oauth/userinfo", metadata.UserinfoEndpoint)
+	assert.Equal(suite.T(), suite.cnf.JWT.Issuer+"/web/authorize", metadata.AuthorizationEndpoint)
+	assert.Contains(suite.T(), metadata.ScopesSupported, "read")
+	assert.Contains(suite.T(), metadata.ScopesSupported, "read_write")
+	assert.Equal(suite.T(), []string{"HS256"}, metadata.IDTokenSigningAlgValuesSupported)
+	assert.Empty(suite.T(), metadata.JWKSURI)
+	assert.Equal(suite.T(), []string{"code", "code id_token"}, metadata.ResponseTypesSupported)
+	assert.Equal(suite.T(), []string{"S256", "plain"}, metadata.CodeChallengeMethodsSupported)
+	assert.Equal(suite.T(), []string{
+		"api_key",
+		"authorization_code",
+		"client_credentials",
+		"password",
+		"refresh_token",
+		"urn:ietf:params:oauth:grant-type:device_code",
+		"urn:ietf:params:oauth:grant-type:jwt-bearer",
+		"urn:ietf:params:oauth:grant-type:saml2-bearer",
+		"urn:ietf:params:oauth:grant-type:token-exchange",
+	}, metadata.GrantTypesSupported)
+	assert.Equal(suite.T(), suite.cnf.JWT.Issuer+"/v1/oauth/device_authorization", metadata.DeviceAuthorizationEndpoint)
+}
+
+func (suite *OauthTestSuite) TestMetadataReflectsEnabledGrantTypes() {
+	suite.cnf.Oauth.EnableDeviceSecret = true
+	suite.cnf.Oauth.EnableImplicitGrant = true
+	defer func() {
+		suite.cnf.Oauth.EnableDeviceSecret = false
+		suite.cnf.Oauth.EnableImplicitGrant = false
+	}()
+
+	metadata := suite.getMetadata()
+	assert.Contains(suite.T(), metadata.GrantTypesSupported, "device_secret")
+	assert.Contains(suite.T(), metadata.GrantTypesSupported, "implicit")
+	assert.Equal(
+		suite.T(),
+		[]string{"code", "token", "code id_token", "code token", "code id_token token"},
+		metadata.ResponseTypesSupported,
+	)
+
+	// Disabling a grant type removes it
+	suite.cnf.Oauth.EnableDeviceSecret = false
+	metadata = suite.getMetadata()
+	assert.NotContains(suite.T(), metadata.GrantTypesSupported, "device_secret")
+	assert.Contains(suite.T(), metadata.GrantTypesSupported, "implicit")
+}
+
+func (suite *OauthTestSuite) TestOpenIDConfiguration() {
+	// OpenID Connect clients discover the same metadata
+	metadata := suite.getMetadataAt("/.well-known/openid-configuration")
+	assert.Equal(suite.T(), suite.getMetadata(), metadata)
+	assert.Equal(suite.T(), []string{"public", "pairwise"}, metadata.SubjectTypesSupported)
+	assert.Contains(suite.T(), metadata.ClaimsSupported, "sub")
+	assert.Contains(suite.T(), metadata.ScopesSupported, "openid")
+}
+
+func (suite *OauthTestSuite) getMetadata() *oauth.Metadata {
+	return suite.getMetadataAt("/.well-known/oauth-authorization-server")
+}
+
+func (suite *OauthTestSuite) getMetadataAt(path string) *oauth.Metadata {
+	r, err := http.NewRequest("GET", "http://1.2.3.4"+path, nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	assert.Equal(suite.T(), 200, w.Code)
+
+	metadata := new(oauth.Metadata)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), metadata))
+	return metadata
+}
@@ -0,0 +1,103 @@
+package oauth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/RichardKnop/go-oauth2-server/util/response"
+	"github.com/gorilla/context"
+)
+
+type contextKey int
+
+const (
+	accessTokenKey contextKey = 0
+)
+
+var (
+	// ErrInvalidTokenAudience ...
+	ErrInvalidTokenAudience = errors.New("Invalid token audience")
+	// ErrAccessTokenNotPresent ...
+	ErrAccessTokenNotPresent = errors.New("Access token not present in the request context")
+)
+
+// AuthenticationMiddleware takes the bearer token from the Authorization
+// header, authenticates it and stores the access token in the request context
+type AuthenticationMiddleware struct {
+	service ServiceInterface
+}
+
+// NewAuthenticationMiddleware creates a new AuthenticationMiddleware instance
+func NewAuthenticationMiddleware(service ServiceInterface) *AuthenticationMiddleware {
+	return &AuthenticationMiddleware{service: service}
+}
+
+// ServeHTTP as per the negroni.Handler interface
+func (m *AuthenticationMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	// Get the bearer or DPoP token from the Authorization header
+	token, dpop := parseDPoPToken(r)
+	if !dpop {
+		bearerToken, err := util.ParseBearerToken(r)
+		if err != nil {
+			response.UnauthorizedError(w, err.Error())
+			return
+		}
+		token = string(bearerToken)
+	}
+
+	// Authenticate the access token
+	accessToken, err := m.service.Authenticate(token)
+	if err != nil {
+		response.InvalidTokenError(w, err.Error())
+		return
+	}
+
+	// Bound tokens are only accepted from the holder of the key
+	if err := checkCertificateBinding(r, accessToken); err != nil {
+		response.InvalidTokenError(w, err.Error())
+		return
+	}
+	if err := checkDPoPBinding(r, m.service.GetConfig().JWT.Issuer, token, accessToken); err != nil {
+		response.InvalidTokenError(w, err.Error())
+		return
+	}
+
+	// Reject tokens minted for other resource servers
+	if !m.audienceAllowed(accessToken) {
+		response.InvalidTokenError(w, ErrInvalidTokenAudience.Error())
+		return
+	}
+
+	context.Set(r, accessTokenKey, accessToken)
+
+	next(w, r)
+}
+
+// audienceAllowed returns true if no audience is expected or if the
+// access token's audience includes the expected audience
+func (m *AuthenticationMiddleware) audienceAllowed(accessToken *models.OauthAccessToken) bool {
+	expectedAudience := m.service.GetConfig().Oauth.ExpectedAudience
+	if expectedAudience == "" {
+		return true
+	}
+	return util.StringInSlice(expectedAudience, strings.Split(accessToken.Audience, " "))
+}
+
+// GetAuthenticatedAccessToken returns the access token stored
+// in the request context by AuthenticationMiddleware
+func GetAuthenticatedAccessToken(r *http.Request) (*models.OauthAccessToken, error) {
+	val, ok := context.GetOk(r, accessTokenKey)
+	if !ok {
+		return nil, ErrAccessTokenNotPresent
+	}
+
+	accessToken, ok := val.(*models.OauthAccessToken)
+	if !ok {
+		return nil, ErrAccessTokenNotPresent
+	}
+
+	return accessToken, nil
+}
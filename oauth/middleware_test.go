@@ -0,0 +1,114 @@
+package oauth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/test-util"
+	"github.com/RichardKnop/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestAuthenticationMiddlewareMissingToken() {
+	// Prepare a request
+	r, err := http.NewRequest("GET", "http://1.2.3.4/resource", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+
+	// Serve the request
+	var nextCalled bool
+	w := httptest.NewRecorder()
+	oauth.NewAuthenticationMiddleware(suite.service).ServeHTTP(w, r, func(w http.ResponseWriter, r *http.Request) {
+		nextCalled = true
+	})
+
+	// Check the response
+	assert.False(suite.T(), nextCalled)
+	testutil.TestResponseForError(suite.T(), w, "Bearer token not found", 401)
+}
+
+func (suite *OauthTestSuite) TestAuthenticationMiddlewareMatchingAudience() {
+	suite.cnf.Oauth.ExpectedAudience = "https://api.example.com"
+	defer func() { suite.cnf.Oauth.ExpectedAudience = "" }()
+
+	// Insert a test access token
+	err := suite.db.Create(&models.OauthAccessToken{
+		MyGormModel: models.MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		Token:     "test_token",
+		ExpiresAt: time.Now().UTC().Add(+10 * time.Second),
+		Client:    suite.clients[0],
+		User:      suite.users[0],
+		Scope:     "read_write",
+		Audience:  "https://other.example.com https://api.example.com",
+	}).Error
+	assert.NoError(suite.T(), err, "Inserting test data failed")
+
+	// Prepare a request
+	r, err := http.NewRequest("GET", "http://1.2.3.4/resource", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.Header.Set("Authorization", "Bearer test_token")
+
+	// Serve the request
+	var accessToken *models.OauthAccessToken
+	w := httptest.NewRecorder()
+	oauth.NewAuthenticationMiddleware(suite.service).ServeHTTP(w, r, func(w http.ResponseWriter, r *http.Request) {
+		accessToken, err = oauth.GetAuthenticatedAccessToken(r)
+	})
+
+	// The access token should be stored in the request context
+	assert.NoError(suite.T(), err)
+	if assert.NotNil(suite.T(), accessToken) {
+		assert.Equal(suite.T(), models.HashToken("test_token"), accessToken.TokenHash)
+	}
+}
+
+func (suite *OauthTestSuite) TestAuthenticationMiddlewareDifferentAudience() {
+	suite.cnf.Oauth.ExpectedAudience = "https://api.example.com"
+	defer func() { suite.cnf.Oauth.ExpectedAudience = "" }()
+
+	// Insert a test access token
+	err := suite.db.Create(&models.OauthAccessToken{
+		MyGormModel: models.MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		Token:     "test_token",
+		ExpiresAt: time.Now().UTC().Add(+10 * time.Second),
+		Client:    suite.clients[0],
+		User:      suite.users[0],
+		Scope:     "read_write",
+		Audience:  "https://other.example.com",
+	}).Error
+	assert.NoError(suite.T(), err, "Inserting test data failed")
+
+	// Prepare a request
+	r, err := http.NewRequest("GET", "http://1.2.3.4/resource", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.Header.Set("Authorization", "Bearer test_token")
+
+	// Serve the request
+	var nextCalled bool
+	w := httptest.NewRecorder()
+	oauth.NewAuthenticationMiddleware(suite.service).ServeHTTP(w, r, func(w http.ResponseWriter, r *http.Request) {
+		nextCalled = true
+	})
+
+	// Check the response
+	assert.False(suite.T(), nextCalled)
+	assert.True(suite.T(), strings.Contains(
+		w.Header().Get("WWW-Authenticate"),
+		"error=\"invalid_token\"",
+	))
+	testutil.TestResponseForError(
+		suite.T(),
+		w,
+		oauth.ErrInvalidTokenAudience.Error(),
+		401,
+	)
+}
@@ -5,9 +5,12 @@ import "github.com/stretchr/testify/mock"
 
 import "github.com/RichardKnop/go-oauth2-server/config"
 import "github.com/RichardKnop/go-oauth2-server/models"
+import "github.com/RichardKnop/go-oauth2-server/session"
 import "github.com/RichardKnop/go-oauth2-server/util/routes"
 import "github.com/gorilla/mux"
 import "github.com/jinzhu/gorm"
+import "net/url"
+import "context"
 
 type ServiceInterface struct {
 	mock.Mock
@@ -92,6 +95,55 @@ func (_m *ServiceInterface) FindClientByClientID(clientID string) (*models.Oauth
 
 	return r0, r1
 }
+func (_m *ServiceInterface) GetClient(clientID string) (*models.OauthClient, error) {
+	ret := _m.Called(clientID)
+
+	var r0 *models.OauthClient
+	if rf, ok := ret.Get(0).(func(string) *models.OauthClient); ok {
+		r0 = rf(clientID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthClient)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(clientID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) ListClients(offset int, limit int) ([]*models.OauthClient, int, error) {
+	ret := _m.Called(offset, limit)
+
+	var r0 []*models.OauthClient
+	if rf, ok := ret.Get(0).(func(int, int) []*models.OauthClient); ok {
+		r0 = rf(offset, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.OauthClient)
+		}
+	}
+
+	var r1 int
+	if rf, ok := ret.Get(1).(func(int, int) int); ok {
+		r1 = rf(offset, limit)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(int, int) error); ok {
+		r2 = rf(offset, limit)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
 func (_m *ServiceInterface) CreateClient(clientID string, secret string, redirectURI string) (*models.OauthClient, error) {
 	ret := _m.Called(clientID, secret, redirectURI)
 
@@ -134,6 +186,18 @@ func (_m *ServiceInterface) CreateClientTx(tx *gorm.DB, clientID string, secret
 
 	return r0, r1
 }
+func (_m *ServiceInterface) ValidateClientSecret(secret string) error {
+	ret := _m.Called(secret)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(secret)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
 func (_m *ServiceInterface) AuthClient(clientID string, secret string) (*models.OauthClient, error) {
 	ret := _m.Called(clientID, secret)
 
@@ -155,343 +219,2047 @@ func (_m *ServiceInterface) AuthClient(clientID string, secret string) (*models.
 
 	return r0, r1
 }
-func (_m *ServiceInterface) UserExists(username string) bool {
-	ret := _m.Called(username)
+func (_m *ServiceInterface) CreateClientWithMetadata(clientID string, metadata *oauth.ClientMetadata) (*models.OauthClient, string, error) {
+	ret := _m.Called(clientID, metadata)
 
-	var r0 bool
-	if rf, ok := ret.Get(0).(func(string) bool); ok {
-		r0 = rf(username)
+	var r0 *models.OauthClient
+	if rf, ok := ret.Get(0).(func(string, *oauth.ClientMetadata) *models.OauthClient); ok {
+		r0 = rf(clientID, metadata)
 	} else {
-		r0 = ret.Get(0).(bool)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthClient)
+		}
+	}
+
+	var r1 string
+	if rf, ok := ret.Get(1).(func(string, *oauth.ClientMetadata) string); ok {
+		r1 = rf(clientID, metadata)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, *oauth.ClientMetadata) error); ok {
+		r2 = rf(clientID, metadata)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+func (_m *ServiceInterface) UpdateClient(client *models.OauthClient, metadata *oauth.ClientMetadata) error {
+	ret := _m.Called(client, metadata)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, *oauth.ClientMetadata) error); ok {
+		r0 = rf(client, metadata)
+	} else {
+		r0 = ret.Error(0)
 	}
 
 	return r0
 }
-func (_m *ServiceInterface) FindUserByUsername(username string) (*models.OauthUser, error) {
-	ret := _m.Called(username)
+func (_m *ServiceInterface) RotateClientSecret(client *models.OauthClient) (string, error) {
+	ret := _m.Called(client)
 
-	var r0 *models.OauthUser
-	if rf, ok := ret.Get(0).(func(string) *models.OauthUser); ok {
-		r0 = rf(username)
+	var r0 string
+	if rf, ok := ret.Get(0).(func(*models.OauthClient) string); ok {
+		r0 = rf(client)
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*models.OauthUser)
-		}
+		r0 = ret.Get(0).(string)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(username)
+	if rf, ok := ret.Get(1).(func(*models.OauthClient) error); ok {
+		r1 = rf(client)
 	} else {
 		r1 = ret.Error(1)
 	}
 
 	return r0, r1
 }
-func (_m *ServiceInterface) CreateUser(roleID string, username string, password string) (*models.OauthUser, error) {
-	ret := _m.Called(roleID, username, password)
+func (_m *ServiceInterface) AddSecondaryClientSecret(client *models.OauthClient) (string, error) {
+	ret := _m.Called(client)
 
-	var r0 *models.OauthUser
-	if rf, ok := ret.Get(0).(func(string, string, string) *models.OauthUser); ok {
-		r0 = rf(roleID, username, password)
+	var r0 string
+	if rf, ok := ret.Get(0).(func(*models.OauthClient) string); ok {
+		r0 = rf(client)
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*models.OauthUser)
-		}
+		r0 = ret.Get(0).(string)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string, string, string) error); ok {
-		r1 = rf(roleID, username, password)
+	if rf, ok := ret.Get(1).(func(*models.OauthClient) error); ok {
+		r1 = rf(client)
 	} else {
 		r1 = ret.Error(1)
 	}
 
 	return r0, r1
 }
-func (_m *ServiceInterface) CreateUserTx(tx *gorm.DB, roleID string, username string, password string) (*models.OauthUser, error) {
-	ret := _m.Called(tx, roleID, username, password)
+func (_m *ServiceInterface) PromoteSecondaryClientSecret(client *models.OauthClient, gracePeriod int) error {
+	ret := _m.Called(client, gracePeriod)
 
-	var r0 *models.OauthUser
-	if rf, ok := ret.Get(0).(func(*gorm.DB, string, string, string) *models.OauthUser); ok {
-		r0 = rf(tx, roleID, username, password)
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, int) error); ok {
+		r0 = rf(client, gracePeriod)
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*models.OauthUser)
-		}
+		r0 = ret.Error(0)
 	}
 
-	var r1 error
-	if rf, ok := ret.Get(1).(func(*gorm.DB, string, string, string) error); ok {
-		r1 = rf(tx, roleID, username, password)
+	return r0
+}
+func (_m *ServiceInterface) ExpireSecondaryClientSecret(client *models.OauthClient) error {
+	ret := _m.Called(client)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthClient) error); ok {
+		r0 = rf(client)
 	} else {
-		r1 = ret.Error(1)
+		r0 = ret.Error(0)
 	}
 
-	return r0, r1
+	return r0
 }
-func (_m *ServiceInterface) SetPassword(user *models.OauthUser, password string) error {
-	ret := _m.Called(user, password)
+func (_m *ServiceInterface) SetClientDisabled(client *models.OauthClient, disabled bool) error {
+	ret := _m.Called(client, disabled)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*models.OauthUser, string) error); ok {
-		r0 = rf(user, password)
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, bool) error); ok {
+		r0 = rf(client, disabled)
 	} else {
 		r0 = ret.Error(0)
 	}
 
 	return r0
 }
-func (_m *ServiceInterface) SetPasswordTx(tx *gorm.DB, user *models.OauthUser, password string) error {
-	ret := _m.Called(tx, user, password)
+func (_m *ServiceInterface) SetClientType(client *models.OauthClient, clientType string) error {
+	ret := _m.Called(client, clientType)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*gorm.DB, *models.OauthUser, string) error); ok {
-		r0 = rf(tx, user, password)
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, string) error); ok {
+		r0 = rf(client, clientType)
 	} else {
 		r0 = ret.Error(0)
 	}
 
 	return r0
 }
-func (_m *ServiceInterface) UpdateUsername(user *models.OauthUser, username string) error {
-	ret := _m.Called(user, username)
+func (_m *ServiceInterface) SetClientFirstParty(client *models.OauthClient, firstParty bool) error {
+	ret := _m.Called(client, firstParty)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*models.OauthUser, string) error); ok {
-		r0 = rf(user, username)
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, bool) error); ok {
+		r0 = rf(client, firstParty)
 	} else {
 		r0 = ret.Error(0)
 	}
 
 	return r0
 }
-func (_m *ServiceInterface) UpdateUsernameTx(db *gorm.DB, user *models.OauthUser, username string) error {
-	ret := _m.Called(db, user, username)
+func (_m *ServiceInterface) SetClientSubjectType(client *models.OauthClient, subjectType string, sectorIdentifierURI string) error {
+	ret := _m.Called(client, subjectType, sectorIdentifierURI)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*gorm.DB, *models.OauthUser, string) error); ok {
-		r0 = rf(db, user, username)
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, string, string) error); ok {
+		r0 = rf(client, subjectType, sectorIdentifierURI)
 	} else {
 		r0 = ret.Error(0)
 	}
 
 	return r0
 }
-func (_m *ServiceInterface) AuthUser(username string, thePassword string) (*models.OauthUser, error) {
-	ret := _m.Called(username, thePassword)
+func (_m *ServiceInterface) SetClientTokenRateLimit(client *models.OauthClient, limit int) error {
+	ret := _m.Called(client, limit)
 
-	var r0 *models.OauthUser
-	if rf, ok := ret.Get(0).(func(string, string) *models.OauthUser); ok {
-		r0 = rf(username, thePassword)
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, int) error); ok {
+		r0 = rf(client, limit)
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*models.OauthUser)
-		}
+		r0 = ret.Error(0)
 	}
 
-	var r1 error
-	if rf, ok := ret.Get(1).(func(string, string) error); ok {
-		r1 = rf(username, thePassword)
+	return r0
+}
+func (_m *ServiceInterface) DeleteClient(client *models.OauthClient) error {
+	ret := _m.Called(client)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthClient) error); ok {
+		r0 = rf(client)
 	} else {
-		r1 = ret.Error(1)
+		r0 = ret.Error(0)
 	}
 
-	return r0, r1
+	return r0
 }
-func (_m *ServiceInterface) GetScope(requestedScope string) (string, error) {
-	ret := _m.Called(requestedScope)
+func (_m *ServiceInterface) RegisterClient(metadata *oauth.ClientMetadata) (*oauth.ClientRegistrationResponse, error) {
+	ret := _m.Called(metadata)
 
-	var r0 string
-	if rf, ok := ret.Get(0).(func(string) string); ok {
-		r0 = rf(requestedScope)
+	var r0 *oauth.ClientRegistrationResponse
+	if rf, ok := ret.Get(0).(func(*oauth.ClientMetadata) *oauth.ClientRegistrationResponse); ok {
+		r0 = rf(metadata)
 	} else {
-		r0 = ret.Get(0).(string)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*oauth.ClientRegistrationResponse)
+		}
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(requestedScope)
+	if rf, ok := ret.Get(1).(func(*oauth.ClientMetadata) error); ok {
+		r1 = rf(metadata)
 	} else {
 		r1 = ret.Error(1)
 	}
 
 	return r0, r1
 }
-func (_m *ServiceInterface) Login(client *models.OauthClient, user *models.OauthUser, scope string) (*models.OauthAccessToken, *models.OauthRefreshToken, error) {
-	ret := _m.Called(client, user, scope)
+func (_m *ServiceInterface) UpdateRegisteredClient(client *models.OauthClient, metadata *oauth.ClientMetadata, secret string) (*oauth.ClientRegistrationResponse, error) {
+	ret := _m.Called(client, metadata, secret)
 
-	var r0 *models.OauthAccessToken
-	if rf, ok := ret.Get(0).(func(*models.OauthClient, *models.OauthUser, string) *models.OauthAccessToken); ok {
-		r0 = rf(client, user, scope)
+	var r0 *oauth.ClientRegistrationResponse
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, *oauth.ClientMetadata, string) *oauth.ClientRegistrationResponse); ok {
+		r0 = rf(client, metadata, secret)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*models.OauthAccessToken)
+			r0 = ret.Get(0).(*oauth.ClientRegistrationResponse)
 		}
 	}
 
-	var r1 *models.OauthRefreshToken
-	if rf, ok := ret.Get(1).(func(*models.OauthClient, *models.OauthUser, string) *models.OauthRefreshToken); ok {
-		r1 = rf(client, user, scope)
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthClient, *oauth.ClientMetadata, string) error); ok {
+		r1 = rf(client, metadata, secret)
 	} else {
-		if ret.Get(1) != nil {
-			r1 = ret.Get(1).(*models.OauthRefreshToken)
-		}
+		r1 = ret.Error(1)
 	}
 
-	var r2 error
-	if rf, ok := ret.Get(2).(func(*models.OauthClient, *models.OauthUser, string) error); ok {
-		r2 = rf(client, user, scope)
+	return r0, r1
+}
+func (_m *ServiceInterface) DeleteRegisteredClient(client *models.OauthClient) error {
+	ret := _m.Called(client)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthClient) error); ok {
+		r0 = rf(client)
 	} else {
-		r2 = ret.Error(2)
+		r0 = ret.Error(0)
 	}
 
-	return r0, r1, r2
+	return r0
 }
-func (_m *ServiceInterface) GrantAuthorizationCode(client *models.OauthClient, user *models.OauthUser, expiresIn int, redirectURI string, scope string) (*models.OauthAuthorizationCode, error) {
-	ret := _m.Called(client, user, expiresIn, redirectURI, scope)
+func (_m *ServiceInterface) FindRegisteredClient(clientID string, registrationAccessToken string) (*models.OauthClient, error) {
+	ret := _m.Called(clientID, registrationAccessToken)
 
-	var r0 *models.OauthAuthorizationCode
-	if rf, ok := ret.Get(0).(func(*models.OauthClient, *models.OauthUser, int, string, string) *models.OauthAuthorizationCode); ok {
-		r0 = rf(client, user, expiresIn, redirectURI, scope)
+	var r0 *models.OauthClient
+	if rf, ok := ret.Get(0).(func(string, string) *models.OauthClient); ok {
+		r0 = rf(clientID, registrationAccessToken)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*models.OauthAuthorizationCode)
+			r0 = ret.Get(0).(*models.OauthClient)
 		}
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(*models.OauthClient, *models.OauthUser, int, string, string) error); ok {
-		r1 = rf(client, user, expiresIn, redirectURI, scope)
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(clientID, registrationAccessToken)
 	} else {
 		r1 = ret.Error(1)
 	}
 
 	return r0, r1
 }
-func (_m *ServiceInterface) GrantAccessToken(client *models.OauthClient, user *models.OauthUser, expiresIn int, scope string) (*models.OauthAccessToken, error) {
-	ret := _m.Called(client, user, expiresIn, scope)
+func (_m *ServiceInterface) ValidateRedirectURI(client *models.OauthClient, redirectURI string) error {
+	ret := _m.Called(client, redirectURI)
 
-	var r0 *models.OauthAccessToken
-	if rf, ok := ret.Get(0).(func(*models.OauthClient, *models.OauthUser, int, string) *models.OauthAccessToken); ok {
-		r0 = rf(client, user, expiresIn, scope)
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, string) error); ok {
+		r0 = rf(client, redirectURI)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) GetRedirectURIs(client *models.OauthClient) ([]string, error) {
+	ret := _m.Called(client)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(*models.OauthClient) []string); ok {
+		r0 = rf(client)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*models.OauthAccessToken)
+			r0 = ret.Get(0).([]string)
 		}
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(*models.OauthClient, *models.OauthUser, int, string) error); ok {
-		r1 = rf(client, user, expiresIn, scope)
+	if rf, ok := ret.Get(1).(func(*models.OauthClient) error); ok {
+		r1 = rf(client)
 	} else {
 		r1 = ret.Error(1)
 	}
 
 	return r0, r1
 }
-func (_m *ServiceInterface) GetOrCreateRefreshToken(client *models.OauthClient, user *models.OauthUser, expiresIn int, scope string) (*models.OauthRefreshToken, error) {
-	ret := _m.Called(client, user, expiresIn, scope)
+func (_m *ServiceInterface) AddRedirectURI(client *models.OauthClient, redirectURI string) (*models.OauthRedirectURI, error) {
+	ret := _m.Called(client, redirectURI)
 
-	var r0 *models.OauthRefreshToken
-	if rf, ok := ret.Get(0).(func(*models.OauthClient, *models.OauthUser, int, string) *models.OauthRefreshToken); ok {
-		r0 = rf(client, user, expiresIn, scope)
+	var r0 *models.OauthRedirectURI
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, string) *models.OauthRedirectURI); ok {
+		r0 = rf(client, redirectURI)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*models.OauthRefreshToken)
+			r0 = ret.Get(0).(*models.OauthRedirectURI)
 		}
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(*models.OauthClient, *models.OauthUser, int, string) error); ok {
-		r1 = rf(client, user, expiresIn, scope)
+	if rf, ok := ret.Get(1).(func(*models.OauthClient, string) error); ok {
+		r1 = rf(client, redirectURI)
 	} else {
 		r1 = ret.Error(1)
 	}
 
 	return r0, r1
 }
-func (_m *ServiceInterface) GetValidRefreshToken(token string, client *models.OauthClient) (*models.OauthRefreshToken, error) {
-	ret := _m.Called(token, client)
+func (_m *ServiceInterface) RemoveRedirectURI(client *models.OauthClient, redirectURI string) error {
+	ret := _m.Called(client, redirectURI)
 
-	var r0 *models.OauthRefreshToken
-	if rf, ok := ret.Get(0).(func(string, *models.OauthClient) *models.OauthRefreshToken); ok {
-		r0 = rf(token, client)
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, string) error); ok {
+		r0 = rf(client, redirectURI)
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*models.OauthRefreshToken)
-		}
+		r0 = ret.Error(0)
 	}
 
-	var r1 error
-	if rf, ok := ret.Get(1).(func(string, *models.OauthClient) error); ok {
-		r1 = rf(token, client)
+	return r0
+}
+func (_m *ServiceInterface) UserExists(username string) bool {
+	ret := _m.Called(username)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
+		r0 = rf(username)
 	} else {
-		r1 = ret.Error(1)
+		r0 = ret.Get(0).(bool)
 	}
 
-	return r0, r1
+	return r0
 }
-func (_m *ServiceInterface) Authenticate(token string) (*models.OauthAccessToken, error) {
-	ret := _m.Called(token)
+func (_m *ServiceInterface) FindUserByUsername(username string) (*models.OauthUser, error) {
+	ret := _m.Called(username)
 
-	var r0 *models.OauthAccessToken
-	if rf, ok := ret.Get(0).(func(string) *models.OauthAccessToken); ok {
-		r0 = rf(token)
+	var r0 *models.OauthUser
+	if rf, ok := ret.Get(0).(func(string) *models.OauthUser); ok {
+		r0 = rf(username)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*models.OauthAccessToken)
+			r0 = ret.Get(0).(*models.OauthUser)
 		}
 	}
 
 	var r1 error
 	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(token)
+		r1 = rf(username)
 	} else {
 		r1 = ret.Error(1)
 	}
 
 	return r0, r1
 }
-func (_m *ServiceInterface) NewIntrospectResponseFromAccessToken(accessToken *models.OauthAccessToken) (*oauth.IntrospectResponse, error) {
+func (_m *ServiceInterface) FindUserByID(id string) (*models.OauthUser, error) {
+	ret := _m.Called(id)
+
+	var r0 *models.OauthUser
+	if rf, ok := ret.Get(0).(func(string) *models.OauthUser); ok {
+		r0 = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthUser)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) RegisterUser(ctx context.Context, client *models.OauthClient, request *oauth.UserRegistrationRequest) (*oauth.UserRegistrationResponse, error) {
+	ret := _m.Called(ctx, client, request)
+
+	var r0 *oauth.UserRegistrationResponse
+	if rf, ok := ret.Get(0).(func(context.Context, *models.OauthClient, *oauth.UserRegistrationRequest) *oauth.UserRegistrationResponse); ok {
+		r0 = rf(ctx, client, request)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*oauth.UserRegistrationResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *models.OauthClient, *oauth.UserRegistrationRequest) error); ok {
+		r1 = rf(ctx, client, request)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) SendEmailVerification(ctx context.Context, user *models.OauthUser) error {
+	ret := _m.Called(ctx, user)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.OauthUser) error); ok {
+		r0 = rf(ctx, user)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) RequestEmailVerification(ctx context.Context, username string) error {
+	ret := _m.Called(ctx, username)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, username)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) VerifyEmail(token string) error {
+	ret := _m.Called(token)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(token)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) CreateUser(roleID string, username string, password string) (*models.OauthUser, error) {
+	ret := _m.Called(roleID, username, password)
+
+	var r0 *models.OauthUser
+	if rf, ok := ret.Get(0).(func(string, string, string) *models.OauthUser); ok {
+		r0 = rf(roleID, username, password)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthUser)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, string) error); ok {
+		r1 = rf(roleID, username, password)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) CreateUserTx(tx *gorm.DB, roleID string, username string, password string) (*models.OauthUser, error) {
+	ret := _m.Called(tx, roleID, username, password)
+
+	var r0 *models.OauthUser
+	if rf, ok := ret.Get(0).(func(*gorm.DB, string, string, string) *models.OauthUser); ok {
+		r0 = rf(tx, roleID, username, password)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthUser)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*gorm.DB, string, string, string) error); ok {
+		r1 = rf(tx, roleID, username, password)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) SetPassword(user *models.OauthUser, password string) error {
+	ret := _m.Called(user, password)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthUser, string) error); ok {
+		r0 = rf(user, password)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) SetPasswordTx(tx *gorm.DB, user *models.OauthUser, password string) error {
+	ret := _m.Called(tx, user, password)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*gorm.DB, *models.OauthUser, string) error); ok {
+		r0 = rf(tx, user, password)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) UpdateUsername(user *models.OauthUser, username string) error {
+	ret := _m.Called(user, username)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthUser, string) error); ok {
+		r0 = rf(user, username)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) UpdateUsernameTx(db *gorm.DB, user *models.OauthUser, username string) error {
+	ret := _m.Called(db, user, username)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*gorm.DB, *models.OauthUser, string) error); ok {
+		r0 = rf(db, user, username)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) AuthUser(username string, thePassword string) (*models.OauthUser, error) {
+	ret := _m.Called(username, thePassword)
+
+	var r0 *models.OauthUser
+	if rf, ok := ret.Get(0).(func(string, string) *models.OauthUser); ok {
+		r0 = rf(username, thePassword)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthUser)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(username, thePassword)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) AuthLogin(username string, password string, acrValues string, otp string, ipAddress string) (*models.OauthUser, string, error) {
+	ret := _m.Called(username, password, acrValues, otp, ipAddress)
+
+	var r0 *models.OauthUser
+	if rf, ok := ret.Get(0).(func(string, string, string, string, string) *models.OauthUser); ok {
+		r0 = rf(username, password, acrValues, otp, ipAddress)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthUser)
+		}
+	}
+
+	var r1 string
+	if rf, ok := ret.Get(1).(func(string, string, string, string, string) string); ok {
+		r1 = rf(username, password, acrValues, otp, ipAddress)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, string, string, string, string) error); ok {
+		r2 = rf(username, password, acrValues, otp, ipAddress)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+func (_m *ServiceInterface) UnlockUser(user *models.OauthUser) error {
+	ret := _m.Called(user)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthUser) error); ok {
+		r0 = rf(user)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) ListUsers(username string, externalID string, offset int, limit int) ([]*models.OauthUser, int, error) {
+	ret := _m.Called(username, externalID, offset, limit)
+
+	var r0 []*models.OauthUser
+	if rf, ok := ret.Get(0).(func(string, string, int, int) []*models.OauthUser); ok {
+		r0 = rf(username, externalID, offset, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.OauthUser)
+		}
+	}
+
+	var r1 int
+	if rf, ok := ret.Get(1).(func(string, string, int, int) int); ok {
+		r1 = rf(username, externalID, offset, limit)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, string, int, int) error); ok {
+		r2 = rf(username, externalID, offset, limit)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+func (_m *ServiceInterface) SetUserExternalID(user *models.OauthUser, externalID string) error {
+	ret := _m.Called(user, externalID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthUser, string) error); ok {
+		r0 = rf(user, externalID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) SetUserDisabled(user *models.OauthUser, disabled bool) error {
+	ret := _m.Called(user, disabled)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthUser, bool) error); ok {
+		r0 = rf(user, disabled)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) DeleteUser(user *models.OauthUser) error {
+	ret := _m.Called(user)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthUser) error); ok {
+		r0 = rf(user)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) FindGroupByID(id string) (*models.OauthGroup, error) {
+	ret := _m.Called(id)
+
+	var r0 *models.OauthGroup
+	if rf, ok := ret.Get(0).(func(string) *models.OauthGroup); ok {
+		r0 = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthGroup)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) ListGroups(displayName string, offset int, limit int) ([]*models.OauthGroup, int, error) {
+	ret := _m.Called(displayName, offset, limit)
+
+	var r0 []*models.OauthGroup
+	if rf, ok := ret.Get(0).(func(string, int, int) []*models.OauthGroup); ok {
+		r0 = rf(displayName, offset, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.OauthGroup)
+		}
+	}
+
+	var r1 int
+	if rf, ok := ret.Get(1).(func(string, int, int) int); ok {
+		r1 = rf(displayName, offset, limit)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, int, int) error); ok {
+		r2 = rf(displayName, offset, limit)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+func (_m *ServiceInterface) CreateGroup(displayName string, externalID string, userIDs []string) (*models.OauthGroup, error) {
+	ret := _m.Called(displayName, externalID, userIDs)
+
+	var r0 *models.OauthGroup
+	if rf, ok := ret.Get(0).(func(string, string, []string) *models.OauthGroup); ok {
+		r0 = rf(displayName, externalID, userIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthGroup)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, []string) error); ok {
+		r1 = rf(displayName, externalID, userIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) UpdateGroup(group *models.OauthGroup, displayName string, externalID string) error {
+	ret := _m.Called(group, displayName, externalID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthGroup, string, string) error); ok {
+		r0 = rf(group, displayName, externalID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) DeleteGroup(group *models.OauthGroup) error {
+	ret := _m.Called(group)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthGroup) error); ok {
+		r0 = rf(group)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) ListGroupMembers(group *models.OauthGroup) ([]*models.OauthUser, error) {
+	ret := _m.Called(group)
+
+	var r0 []*models.OauthUser
+	if rf, ok := ret.Get(0).(func(*models.OauthGroup) []*models.OauthUser); ok {
+		r0 = rf(group)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.OauthUser)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthGroup) error); ok {
+		r1 = rf(group)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) ListUserGroups(user *models.OauthUser) ([]*models.OauthGroup, error) {
+	ret := _m.Called(user)
+
+	var r0 []*models.OauthGroup
+	if rf, ok := ret.Get(0).(func(*models.OauthUser) []*models.OauthGroup); ok {
+		r0 = rf(user)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.OauthGroup)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthUser) error); ok {
+		r1 = rf(user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) AddGroupMembers(group *models.OauthGroup, userIDs []string) error {
+	ret := _m.Called(group, userIDs)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthGroup, []string) error); ok {
+		r0 = rf(group, userIDs)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) RemoveGroupMembers(group *models.OauthGroup, userIDs []string) error {
+	ret := _m.Called(group, userIDs)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthGroup, []string) error); ok {
+		r0 = rf(group, userIDs)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) SetGroupMembers(group *models.OauthGroup, userIDs []string) error {
+	ret := _m.Called(group, userIDs)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthGroup, []string) error); ok {
+		r0 = rf(group, userIDs)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) AuthenticateAcr(user *models.OauthUser, acrValues string, otp string) (string, error) {
+	ret := _m.Called(user, acrValues, otp)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(*models.OauthUser, string, string) string); ok {
+		r0 = rf(user, acrValues, otp)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthUser, string, string) error); ok {
+		r1 = rf(user, acrValues, otp)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) GetScope(requestedScope string) (string, error) {
+	ret := _m.Called(requestedScope)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(requestedScope)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(requestedScope)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) Login(client *models.OauthClient, user *models.OauthUser, scope string) (*models.OauthAccessToken, *models.OauthRefreshToken, error) {
+	ret := _m.Called(client, user, scope)
+
+	var r0 *models.OauthAccessToken
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, *models.OauthUser, string) *models.OauthAccessToken); ok {
+		r0 = rf(client, user, scope)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthAccessToken)
+		}
+	}
+
+	var r1 *models.OauthRefreshToken
+	if rf, ok := ret.Get(1).(func(*models.OauthClient, *models.OauthUser, string) *models.OauthRefreshToken); ok {
+		r1 = rf(client, user, scope)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*models.OauthRefreshToken)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(*models.OauthClient, *models.OauthUser, string) error); ok {
+		r2 = rf(client, user, scope)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+func (_m *ServiceInterface) GrantAuthorizationCode(client *models.OauthClient, user *models.OauthUser, expiresIn int, redirectURI string, scope string, codeChallenge string, codeChallengeMethod string, authorizationDetails string, nonce string) (*models.OauthAuthorizationCode, error) {
+	ret := _m.Called(client, user, expiresIn, redirectURI, scope, codeChallenge, codeChallengeMethod, authorizationDetails, nonce)
+
+	var r0 *models.OauthAuthorizationCode
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, *models.OauthUser, int, string, string, string, string, string, string) *models.OauthAuthorizationCode); ok {
+		r0 = rf(client, user, expiresIn, redirectURI, scope, codeChallenge, codeChallengeMethod, authorizationDetails, nonce)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthAuthorizationCode)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthClient, *models.OauthUser, int, string, string, string, string, string, string) error); ok {
+		r1 = rf(client, user, expiresIn, redirectURI, scope, codeChallenge, codeChallengeMethod, authorizationDetails, nonce)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) GrantHandoffCode(client *models.OauthClient, user *models.OauthUser, scope string) (*models.OauthAuthorizationCode, error) {
+	ret := _m.Called(client, user, scope)
+
+	var r0 *models.OauthAuthorizationCode
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, *models.OauthUser, string) *models.OauthAuthorizationCode); ok {
+		r0 = rf(client, user, scope)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthAuthorizationCode)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthClient, *models.OauthUser, string) error); ok {
+		r1 = rf(client, user, scope)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) GrantDeviceSecret(client *models.OauthClient, user *models.OauthUser, scope string) (*models.OauthDeviceSecret, error) {
+	ret := _m.Called(client, user, scope)
+
+	var r0 *models.OauthDeviceSecret
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, *models.OauthUser, string) *models.OauthDeviceSecret); ok {
+		r0 = rf(client, user, scope)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthDeviceSecret)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthClient, *models.OauthUser, string) error); ok {
+		r1 = rf(client, user, scope)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+func (_m *ServiceInterface) GenerateRecoveryCodes(user *models.OauthUser) ([]string, error) {
+	ret := _m.Called(user)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(*models.OauthUser) []string); ok {
+		r0 = rf(user)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthUser) error); ok {
+		r1 = rf(user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) BeginTOTPEnrollment(user *models.OauthUser) (*oauth.TOTPEnrollmentResponse, error) {
+	ret := _m.Called(user)
+
+	var r0 *oauth.TOTPEnrollmentResponse
+	if rf, ok := ret.Get(0).(func(*models.OauthUser) *oauth.TOTPEnrollmentResponse); ok {
+		r0 = rf(user)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*oauth.TOTPEnrollmentResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthUser) error); ok {
+		r1 = rf(user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) ConfirmTOTPEnrollment(user *models.OauthUser, secret string, otp string) ([]string, error) {
+	ret := _m.Called(user, secret, otp)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(*models.OauthUser, string, string) []string); ok {
+		r0 = rf(user, secret, otp)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthUser, string, string) error); ok {
+		r1 = rf(user, secret, otp)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) DisableTOTP(user *models.OauthUser) error {
+	ret := _m.Called(user)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthUser) error); ok {
+		r0 = rf(user)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) BeginWebAuthnRegistration(user *models.OauthUser) (*oauth.WebAuthnCreationOptions, error) {
+	ret := _m.Called(user)
+
+	var r0 *oauth.WebAuthnCreationOptions
+	if rf, ok := ret.Get(0).(func(*models.OauthUser) *oauth.WebAuthnCreationOptions); ok {
+		r0 = rf(user)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*oauth.WebAuthnCreationOptions)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthUser) error); ok {
+		r1 = rf(user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) FinishWebAuthnRegistration(user *models.OauthUser, request *oauth.WebAuthnRegistrationRequest) (*models.OauthWebAuthnCredential, error) {
+	ret := _m.Called(user, request)
+
+	var r0 *models.OauthWebAuthnCredential
+	if rf, ok := ret.Get(0).(func(*models.OauthUser, *oauth.WebAuthnRegistrationRequest) *models.OauthWebAuthnCredential); ok {
+		r0 = rf(user, request)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthWebAuthnCredential)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthUser, *oauth.WebAuthnRegistrationRequest) error); ok {
+		r1 = rf(user, request)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) BeginWebAuthnLogin(username string) (*oauth.WebAuthnRequestOptions, error) {
+	ret := _m.Called(username)
+
+	var r0 *oauth.WebAuthnRequestOptions
+	if rf, ok := ret.Get(0).(func(string) *oauth.WebAuthnRequestOptions); ok {
+		r0 = rf(username)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*oauth.WebAuthnRequestOptions)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(username)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) GrantAccessToken(client *models.OauthClient, user *models.OauthUser, expiresIn int, scope string) (*models.OauthAccessToken, error) {
+	ret := _m.Called(client, user, expiresIn, scope)
+
+	var r0 *models.OauthAccessToken
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, *models.OauthUser, int, string) *models.OauthAccessToken); ok {
+		r0 = rf(client, user, expiresIn, scope)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthAccessToken)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthClient, *models.OauthUser, int, string) error); ok {
+		r1 = rf(client, user, expiresIn, scope)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) EncodeAccessToken(accessToken *models.OauthAccessToken) (string, error) {
+	ret := _m.Called(accessToken)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(*models.OauthAccessToken) string); ok {
+		r0 = rf(accessToken)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthAccessToken) error); ok {
+		r1 = rf(accessToken)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) GrantIDToken(client *models.OauthClient, user *models.OauthUser) (string, error) {
+	ret := _m.Called(client, user)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, *models.OauthUser) string); ok {
+		r0 = rf(client, user)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthClient, *models.OauthUser) error); ok {
+		r1 = rf(client, user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) GrantFrontChannelIDToken(client *models.OauthClient, user *models.OauthUser, nonce string, code string, accessToken string) (string, error) {
+	ret := _m.Called(client, user, nonce, code, accessToken)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, *models.OauthUser, string, string, string) string); ok {
+		r0 = rf(client, user, nonce, code, accessToken)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthClient, *models.OauthUser, string, string, string) error); ok {
+		r1 = rf(client, user, nonce, code, accessToken)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) CreateRefreshToken(client *models.OauthClient, user *models.OauthUser, expiresIn int, scope string) (*models.OauthRefreshToken, error) {
+	ret := _m.Called(client, user, expiresIn, scope)
+
+	var r0 *models.OauthRefreshToken
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, *models.OauthUser, int, string) *models.OauthRefreshToken); ok {
+		r0 = rf(client, user, expiresIn, scope)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthRefreshToken)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthClient, *models.OauthUser, int, string) error); ok {
+		r1 = rf(client, user, expiresIn, scope)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) GetValidRefreshToken(token string, client *models.OauthClient) (*models.OauthRefreshToken, error) {
+	ret := _m.Called(token, client)
+
+	var r0 *models.OauthRefreshToken
+	if rf, ok := ret.Get(0).(func(string, *models.OauthClient) *models.OauthRefreshToken); ok {
+		r0 = rf(token, client)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthRefreshToken)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, *models.OauthClient) error); ok {
+		r1 = rf(token, client)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) Authenticate(token string) (*models.OauthAccessToken, error) {
+	ret := _m.Called(token)
+
+	var r0 *models.OauthAccessToken
+	if rf, ok := ret.Get(0).(func(string) *models.OauthAccessToken); ok {
+		r0 = rf(token)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthAccessToken)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(token)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) NewIntrospectResponseFromAccessToken(accessToken *models.OauthAccessToken) (*oauth.IntrospectResponse, error) {
 	ret := _m.Called(accessToken)
 
-	var r0 *oauth.IntrospectResponse
-	if rf, ok := ret.Get(0).(func(*models.OauthAccessToken) *oauth.IntrospectResponse); ok {
-		r0 = rf(accessToken)
+	var r0 *oauth.IntrospectResponse
+	if rf, ok := ret.Get(0).(func(*models.OauthAccessToken) *oauth.IntrospectResponse); ok {
+		r0 = rf(accessToken)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*oauth.IntrospectResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthAccessToken) error); ok {
+		r1 = rf(accessToken)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) NewIntrospectResponseFromRefreshToken(refreshToken *models.OauthRefreshToken) (*oauth.IntrospectResponse, error) {
+	ret := _m.Called(refreshToken)
+
+	var r0 *oauth.IntrospectResponse
+	if rf, ok := ret.Get(0).(func(*models.OauthRefreshToken) *oauth.IntrospectResponse); ok {
+		r0 = rf(refreshToken)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*oauth.IntrospectResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthRefreshToken) error); ok {
+		r1 = rf(refreshToken)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) GetTokenLineage(token string) (*oauth.TokenLineage, error) {
+	ret := _m.Called(token)
+
+	var r0 *oauth.TokenLineage
+	if rf, ok := ret.Get(0).(func(string) *oauth.TokenLineage); ok {
+		r0 = rf(token)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*oauth.TokenLineage)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(token)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) RevokeTokenLineage(token string) (*oauth.TokenLineage, error) {
+	ret := _m.Called(token)
+
+	var r0 *oauth.TokenLineage
+	if rf, ok := ret.Get(0).(func(string) *oauth.TokenLineage); ok {
+		r0 = rf(token)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*oauth.TokenLineage)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(token)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) UseValidationCache(cache oauth.ValidationCache) {
+	_m.Called(cache)
+}
+func (_m *ServiceInterface) UseTokenGenerator(generator oauth.TokenGenerator) {
+	_m.Called(generator)
+}
+func (_m *ServiceInterface) AddResponseTransformer(transformer oauth.ResponseTransformer) {
+	_m.Called(transformer)
+}
+func (_m *ServiceInterface) AddTokenClaimsProvider(provider oauth.TokenClaimsProvider) {
+	_m.Called(provider)
+}
+func (_m *ServiceInterface) RegisterGrantHandler(grantType string, handler oauth.GrantHandler) {
+	_m.Called(grantType, handler)
+}
+func (_m *ServiceInterface) PurgeExpiredTokens() (int64, error) {
+	ret := _m.Called()
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func() int64); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) StartTokenCleanup() {
+	_m.Called()
+}
+func (_m *ServiceInterface) ValidationCacheStats() oauth.ValidationCacheStats {
+	ret := _m.Called()
+
+	var r0 oauth.ValidationCacheStats
+	if rf, ok := ret.Get(0).(func() oauth.ValidationCacheStats); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(oauth.ValidationCacheStats)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) FindRoleByID(id string) (*models.OauthRole, error) {
+	ret := _m.Called(id)
+
+	var r0 *models.OauthRole
+	if rf, ok := ret.Get(0).(func(string) *models.OauthRole); ok {
+		r0 = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthRole)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) GetRoleScopes(role *models.OauthRole) ([]string, error) {
+	ret := _m.Called(role)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(*models.OauthRole) []string); ok {
+		r0 = rf(role)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthRole) error); ok {
+		r1 = rf(role)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) SetRoleScopes(role *models.OauthRole, scopes []string) error {
+	ret := _m.Called(role, scopes)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthRole, []string) error); ok {
+		r0 = rf(role, scopes)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) GetUserRoles(user *models.OauthUser) ([]string, error) {
+	ret := _m.Called(user)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(*models.OauthUser) []string); ok {
+		r0 = rf(user)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthUser) error); ok {
+		r1 = rf(user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) SetUserRoles(user *models.OauthUser, roleIDs []string) error {
+	ret := _m.Called(user, roleIDs)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthUser, []string) error); ok {
+		r0 = rf(user, roleIDs)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) GetUserAttributes(user *models.OauthUser) (map[string]interface{}, error) {
+	ret := _m.Called(user)
+
+	var r0 map[string]interface{}
+	if rf, ok := ret.Get(0).(func(*models.OauthUser) map[string]interface{}); ok {
+		r0 = rf(user)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]interface{})
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthUser) error); ok {
+		r1 = rf(user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) SetUserAttributes(user *models.OauthUser, attributes map[string]interface{}) error {
+	ret := _m.Called(user, attributes)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthUser, map[string]interface{}) error); ok {
+		r0 = rf(user, attributes)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) GetDefaultScope() string {
+	ret := _m.Called()
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) ScopeExists(requestedScope string) bool {
+	ret := _m.Called(requestedScope)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
+		r0 = rf(requestedScope)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) InvalidateScopeCache() {
+	_m.Called()
+}
+func (_m *ServiceInterface) SeedScopes(scopes []oauth.ScopeDef) error {
+	ret := _m.Called(scopes)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]oauth.ScopeDef) error); ok {
+		r0 = rf(scopes)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) HasConsent(client *models.OauthClient, user *models.OauthUser, scope string) bool {
+	ret := _m.Called(client, user, scope)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, *models.OauthUser, string) bool); ok {
+		r0 = rf(client, user, scope)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) SaveConsent(client *models.OauthClient, user *models.OauthUser, scope string) error {
+	ret := _m.Called(client, user, scope)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, *models.OauthUser, string) error); ok {
+		r0 = rf(client, user, scope)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) CreateAPIKey(client *models.OauthClient, user *models.OauthUser, scope string) (string, error) {
+	ret := _m.Called(client, user, scope)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, *models.OauthUser, string) string); ok {
+		r0 = rf(client, user, scope)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthClient, *models.OauthUser, string) error); ok {
+		r1 = rf(client, user, scope)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) RevokeAPIKey(key string) error {
+	ret := _m.Called(key)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) GrantDeviceCode(client *models.OauthClient, scope string) (*models.OauthDeviceCode, error) {
+	ret := _m.Called(client, scope)
+
+	var r0 *models.OauthDeviceCode
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, string) *models.OauthDeviceCode); ok {
+		r0 = rf(client, scope)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthDeviceCode)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthClient, string) error); ok {
+		r1 = rf(client, scope)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) FindDeviceCodeByUserCode(userCode string) (*models.OauthDeviceCode, error) {
+	ret := _m.Called(userCode)
+
+	var r0 *models.OauthDeviceCode
+	if rf, ok := ret.Get(0).(func(string) *models.OauthDeviceCode); ok {
+		r0 = rf(userCode)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthDeviceCode)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(userCode)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) AuthorizeDeviceCode(deviceCode *models.OauthDeviceCode, user *models.OauthUser) error {
+	ret := _m.Called(deviceCode, user)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthDeviceCode, *models.OauthUser) error); ok {
+		r0 = rf(deviceCode, user)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) DenyDeviceCode(deviceCode *models.OauthDeviceCode) error {
+	ret := _m.Called(deviceCode)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthDeviceCode) error); ok {
+		r0 = rf(deviceCode)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) AddAssertionKey(client *models.OauthClient, issuer string, algorithm string, publicKey string) (*models.OauthAssertionKey, error) {
+	ret := _m.Called(client, issuer, algorithm, publicKey)
+
+	var r0 *models.OauthAssertionKey
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, string, string, string) *models.OauthAssertionKey); ok {
+		r0 = rf(client, issuer, algorithm, publicKey)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*oauth.IntrospectResponse)
+			r0 = ret.Get(0).(*models.OauthAssertionKey)
 		}
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(*models.OauthAccessToken) error); ok {
-		r1 = rf(accessToken)
+	if rf, ok := ret.Get(1).(func(*models.OauthClient, string, string, string) error); ok {
+		r1 = rf(client, issuer, algorithm, publicKey)
 	} else {
 		r1 = ret.Error(1)
 	}
 
 	return r0, r1
 }
-func (_m *ServiceInterface) NewIntrospectResponseFromRefreshToken(refreshToken *models.OauthRefreshToken) (*oauth.IntrospectResponse, error) {
-	ret := _m.Called(refreshToken)
+func (_m *ServiceInterface) AddSAMLIdentityProvider(client *models.OauthClient, metadata []byte) (*models.OauthSAMLIdentityProvider, error) {
+	ret := _m.Called(client, metadata)
 
-	var r0 *oauth.IntrospectResponse
-	if rf, ok := ret.Get(0).(func(*models.OauthRefreshToken) *oauth.IntrospectResponse); ok {
-		r0 = rf(refreshToken)
+	var r0 *models.OauthSAMLIdentityProvider
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, []byte) *models.OauthSAMLIdentityProvider); ok {
+		r0 = rf(client, metadata)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*oauth.IntrospectResponse)
+			r0 = ret.Get(0).(*models.OauthSAMLIdentityProvider)
 		}
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(*models.OauthRefreshToken) error); ok {
-		r1 = rf(refreshToken)
+	if rf, ok := ret.Get(1).(func(*models.OauthClient, []byte) error); ok {
+		r1 = rf(client, metadata)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) SetAllowedGrantTypes(client *models.OauthClient, grantTypes []string) error {
+	ret := _m.Called(client, grantTypes)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, []string) error); ok {
+		r0 = rf(client, grantTypes)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) GetClientScopes(client *models.OauthClient) ([]string, error) {
+	ret := _m.Called(client)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(*models.OauthClient) []string); ok {
+		r0 = rf(client)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthClient) error); ok {
+		r1 = rf(client)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) SetClientScopes(client *models.OauthClient, scopes []string) error {
+	ret := _m.Called(client, scopes)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, []string) error); ok {
+		r0 = rf(client, scopes)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) SetClientTokenLifetimes(client *models.OauthClient, accessTokenLifetime int, refreshTokenLifetime int) error {
+	ret := _m.Called(client, accessTokenLifetime, refreshTokenLifetime)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, int, int) error); ok {
+		r0 = rf(client, accessTokenLifetime, refreshTokenLifetime)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) SetAllowedResponseTypes(client *models.OauthClient, responseTypes []string) error {
+	ret := _m.Called(client, responseTypes)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, []string) error); ok {
+		r0 = rf(client, responseTypes)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) SetTLSClientAuthSubjectDN(client *models.OauthClient, subjectDN string) error {
+	ret := _m.Called(client, subjectDN)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, string) error); ok {
+		r0 = rf(client, subjectDN)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) SetTLSClientAuthSAN(client *models.OauthClient, san string) error {
+	ret := _m.Called(client, san)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, string) error); ok {
+		r0 = rf(client, san)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) SetTokenFormat(client *models.OauthClient, format string) error {
+	ret := _m.Called(client, format)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, string) error); ok {
+		r0 = rf(client, format)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) PushAuthorizationRequest(client *models.OauthClient, parameters url.Values) (*models.OauthPushedRequest, error) {
+	ret := _m.Called(client, parameters)
+
+	var r0 *models.OauthPushedRequest
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, url.Values) *models.OauthPushedRequest); ok {
+		r0 = rf(client, parameters)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthPushedRequest)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthClient, url.Values) error); ok {
+		r1 = rf(client, parameters)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) FindPushedRequest(client *models.OauthClient, requestURI string) (url.Values, error) {
+	ret := _m.Called(client, requestURI)
+
+	var r0 url.Values
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, string) url.Values); ok {
+		r0 = rf(client, requestURI)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(url.Values)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthClient, string) error); ok {
+		r1 = rf(client, requestURI)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) DeletePushedRequest(client *models.OauthClient, requestURI string) error {
+	ret := _m.Called(client, requestURI)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, string) error); ok {
+		r0 = rf(client, requestURI)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) SetBackchannelAuthenticationHook(hook oauth.BackchannelAuthenticationHook) {
+	_m.Called(hook)
+}
+func (_m *ServiceInterface) SetMailer(mailer oauth.Mailer) {
+	_m.Called(mailer)
+}
+func (_m *ServiceInterface) SetUserAuthenticator(authenticator oauth.UserAuthenticator) {
+	_m.Called(authenticator)
+}
+func (_m *ServiceInterface) BeginFederatedLogin(providerName string, query url.Values) (string, string, error) {
+	ret := _m.Called(providerName, query)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string, url.Values) string); ok {
+		r0 = rf(providerName, query)
+	} else {
+		r0 = ret.String(0)
+	}
+
+	var r1 string
+	if rf, ok := ret.Get(1).(func(string, url.Values) string); ok {
+		r1 = rf(providerName, query)
+	} else {
+		r1 = ret.String(1)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, url.Values) error); ok {
+		r2 = rf(providerName, query)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+func (_m *ServiceInterface) FinishFederatedLogin(providerName string, state string, code string, currentUser *models.OauthUser) (*models.OauthUser, url.Values, error) {
+	ret := _m.Called(providerName, state, code, currentUser)
+
+	var r0 *models.OauthUser
+	if rf, ok := ret.Get(0).(func(string, string, string, *models.OauthUser) *models.OauthUser); ok {
+		r0 = rf(providerName, state, code, currentUser)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthUser)
+		}
+	}
+
+	var r1 url.Values
+	if rf, ok := ret.Get(1).(func(string, string, string, *models.OauthUser) url.Values); ok {
+		r1 = rf(providerName, state, code, currentUser)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(url.Values)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, string, string, *models.OauthUser) error); ok {
+		r2 = rf(providerName, state, code, currentUser)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+func (_m *ServiceInterface) RequestPasswordReset(ctx context.Context, username string) error {
+	ret := _m.Called(ctx, username)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, username)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) ResetPassword(token string, password string) error {
+	ret := _m.Called(token, password)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(token, password)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) SetBackchannelNotificationEndpoint(client *models.OauthClient, endpoint string) error {
+	ret := _m.Called(client, endpoint)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthClient, string) error); ok {
+		r0 = rf(client, endpoint)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) FindBackchannelRequest(authReqID string) (*models.OauthBackchannelRequest, error) {
+	ret := _m.Called(authReqID)
+
+	var r0 *models.OauthBackchannelRequest
+	if rf, ok := ret.Get(0).(func(string) *models.OauthBackchannelRequest); ok {
+		r0 = rf(authReqID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthBackchannelRequest)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(authReqID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) AuthorizeBackchannelRequest(request *models.OauthBackchannelRequest) error {
+	ret := _m.Called(request)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthBackchannelRequest) error); ok {
+		r0 = rf(request)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) DenyBackchannelRequest(request *models.OauthBackchannelRequest) error {
+	ret := _m.Called(request)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.OauthBackchannelRequest) error); ok {
+		r0 = rf(request)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) ClearUserTokens(userSession *session.UserSession) {
+	_m.Called(userSession)
+}
+func (_m *ServiceInterface) StartLoginSession(user *models.OauthUser) (*models.OauthSession, error) {
+	ret := _m.Called(user)
+
+	var r0 *models.OauthSession
+	if rf, ok := ret.Get(0).(func(*models.OauthUser) *models.OauthSession); ok {
+		r0 = rf(user)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthSession)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*models.OauthUser) error); ok {
+		r1 = rf(user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+func (_m *ServiceInterface) GetLoginSession(sessionID string) (*models.OauthSession, error) {
+	ret := _m.Called(sessionID)
+
+	var r0 *models.OauthSession
+	if rf, ok := ret.Get(0).(func(string) *models.OauthSession); ok {
+		r0 = rf(sessionID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OauthSession)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(sessionID)
 	} else {
 		r1 = ret.Error(1)
 	}
 
 	return r0, r1
 }
+func (_m *ServiceInterface) EndLoginSession(sessionID string) error {
+	ret := _m.Called(sessionID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(sessionID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+func (_m *ServiceInterface) Close() {
+	_m.Called()
+}
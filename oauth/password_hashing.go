@@ -0,0 +1,56 @@
+package oauth
+
+import (
+	"github.com/RichardKnop/go-oauth2-server/log"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	pass "github.com/RichardKnop/go-oauth2-server/util/password"
+)
+
+// passwordHasher returns the hasher configured for user passwords
+func (s *Service) passwordHasher() (pass.Hasher, error) {
+	cnf := s.cnf.Oauth.PasswordHashing
+	switch cnf.Algorithm {
+	case "", pass.Bcrypt:
+		return &pass.BcryptHasher{Cost: cnf.BcryptCost}, nil
+	case pass.Argon2id:
+		return &pass.Argon2idHasher{
+			Memory:      cnf.Argon2Memory,
+			Iterations:  cnf.Argon2Iterations,
+			Parallelism: cnf.Argon2Parallelism,
+		}, nil
+	case pass.Scrypt:
+		return &pass.ScryptHasher{N: cnf.ScryptN, R: cnf.ScryptR, P: cnf.ScryptP}, nil
+	default:
+		return nil, pass.ErrUnknownAlgorithm
+	}
+}
+
+// hashPassword hashes a user password with the configured hasher
+func (s *Service) hashPassword(password string) (string, error) {
+	hasher, err := s.passwordHasher()
+	if err != nil {
+		return "", err
+	}
+	return hasher.Hash(password)
+}
+
+// upgradedPasswordHash returns a new hash of the password of a user who
+// has just logged in if theirs was made with another algorithm or other
+// cost parameters, or an empty string if it is up to date. Failures are
+// only logged as the login itself succeeded.
+func (s *Service) upgradedPasswordHash(user *models.OauthUser, password string) string {
+	hasher, err := s.passwordHasher()
+	if err != nil {
+		log.WARNING.Printf("Rehashing password failed: %s", err)
+		return ""
+	}
+	if !hasher.NeedsRehash(user.Password.String) {
+		return ""
+	}
+	passwordHash, err := hasher.Hash(password)
+	if err != nil {
+		log.WARNING.Printf("Rehashing password failed: %s", err)
+		return ""
+	}
+	return passwordHash
+}
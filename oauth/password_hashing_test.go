@@ -0,0 +1,110 @@
+package oauth_test
+
+import (
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	pass "github.com/RichardKnop/go-oauth2-server/util/password"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestPasswordHashing() {
+	defer func() {
+		suite.cnf.Oauth.PasswordHashing = config.PasswordHashingConfig{}
+	}()
+
+	// Users created with bcrypt
+	user, err := suite.service.CreateUser(roles.User, "test@user_hashing", "test_password")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	assert.True(suite.T(), strings.HasPrefix(user.Password.String, "$2a$10$"))
+
+	// Switching to Argon2id hashes new passwords with it
+	suite.cnf.Oauth.PasswordHashing = config.PasswordHashingConfig{
+		Algorithm:         pass.Argon2id,
+		Argon2Memory:      64,
+		Argon2Iterations:  1,
+		Argon2Parallelism: 1,
+	}
+	other, err := suite.service.CreateUser(roles.User, "test@user_hashing_2", "test_password")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	assert.True(suite.T(), strings.HasPrefix(other.Password.String, "$argon2id$v=19$m=64,t=1,p=1$"))
+
+	// A failed login leaves the old hash alone
+	_, err = suite.service.AuthUser("test@user_hashing", "bogus")
+	assert.Equal(suite.T(), oauth.ErrInvalidUserPassword, err)
+	suite.assertPasswordHashPrefix(user.ID, "$2a$10$")
+
+	// Logging in rehashes the bcrypt hash
+	_, err = suite.service.AuthUser("test@user_hashing", "test_password")
+	assert.NoError(suite.T(), err)
+	suite.assertPasswordHashPrefix(user.ID, "$argon2id$v=19$m=64,t=1,p=1$")
+
+	// As does changing the cost parameters
+	suite.cnf.Oauth.PasswordHashing = config.PasswordHashingConfig{
+		Algorithm: pass.Scrypt,
+		ScryptN:   16,
+	}
+	_, err = suite.service.AuthUser("test@user_hashing", "test_password")
+	assert.NoError(suite.T(), err)
+	suite.assertPasswordHashPrefix(user.ID, "$scrypt$ln=4,r=8,p=1$")
+
+	// Password changes use the hasher too
+	assert.NoError(suite.T(), suite.service.SetPassword(other, "test_password_2"))
+	suite.assertPasswordHashPrefix(other.ID, "$scrypt$ln=4,r=8,p=1$")
+	_, err = suite.service.AuthUser("test@user_hashing_2", "test_password_2")
+	assert.NoError(suite.T(), err)
+
+	// An unknown algorithm is an error when hashing
+	suite.cnf.Oauth.PasswordHashing = config.PasswordHashingConfig{Algorithm: "md5"}
+	_, err = suite.service.CreateUser(roles.User, "test@user_hashing_3", "test_password")
+	assert.Equal(suite.T(), pass.ErrUnknownAlgorithm, err)
+
+	// But logins still work
+	_, err = suite.service.AuthUser("test@user_hashing", "test_password")
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *OauthTestSuite) TestPasswordHashUpgrade() {
+	defer func() {
+		suite.cnf.Oauth.PasswordHashing = config.PasswordHashingConfig{}
+	}()
+
+	_, err := suite.service.CreateUser(roles.User, "test@user_upgrade", "test_password")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+
+	// Logging in upgrades the hash
+	suite.cnf.Oauth.PasswordHashing = config.PasswordHashingConfig{
+		Algorithm: pass.Scrypt,
+		ScryptN:   16,
+	}
+	user, err := suite.service.AuthUser("test@user_upgrade", "test_password")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	assert.True(suite.T(), strings.HasPrefix(user.Password.String, "$scrypt$ln=4,r=8,p=1$"))
+
+	stored := new(models.OauthUser)
+	assert.NoError(suite.T(), suite.db.Where("id = ?", user.ID).First(stored).Error)
+	assert.Equal(suite.T(), user.Password.String, stored.Password.String)
+
+	// An up to date hash is left alone
+	user, err = suite.service.AuthUser("test@user_upgrade", "test_password")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), stored.Password.String, user.Password.String)
+}
+
+func (suite *OauthTestSuite) assertPasswordHashPrefix(userID, prefix string) {
+	user := new(models.OauthUser)
+	if assert.NoError(suite.T(), suite.db.Where("id = ?", userID).First(user).Error) {
+		assert.True(suite.T(), strings.HasPrefix(user.Password.String, prefix), user.Password.String)
+	}
+}
@@ -0,0 +1,46 @@
+package oauth
+
+import (
+	"github.com/RichardKnop/go-oauth2-server/log"
+	pass "github.com/RichardKnop/go-oauth2-server/util/password"
+)
+
+// PasswordPolicyErrorResponse names the rules of the password policy a
+// password breaks, see the Violation constants of util/password
+type PasswordPolicyErrorResponse struct {
+	Error            string   `json:"error"`
+	ErrorDescription string   `json:"error_description"`
+	Violations       []string `json:"violations"`
+}
+
+// checkPassword returns a *password.PolicyError if the password breaks
+// the configured policy. Passwords are accepted if the breach lookup fails,
+// so users are not stopped from signing up while the API is unavailable.
+func (s *Service) checkPassword(password string) error {
+	err := s.passwordPolicy().Check(password)
+	if _, ok := err.(*pass.PolicyError); err != nil && !ok {
+		log.WARNING.Printf("Looking up password in Pwned Passwords failed: %s", err)
+		return nil
+	}
+	return err
+}
+
+// passwordPolicy returns the configured password policy
+func (s *Service) passwordPolicy() *pass.Policy {
+	cnf := s.cnf.Oauth.PasswordPolicy
+	policy := &pass.Policy{
+		MinLength:        cnf.MinLength,
+		RequireUppercase: cnf.RequireUppercase,
+		RequireLowercase: cnf.RequireLowercase,
+		RequireDigit:     cnf.RequireDigit,
+		RequireSymbol:    cnf.RequireSymbol,
+		BannedPasswords:  cnf.BannedPasswords,
+	}
+	if policy.MinLength <= 0 {
+		policy.MinLength = MinPasswordLength
+	}
+	if cnf.CheckPwnedPasswords {
+		policy.Breached = pass.NewPwnedPasswords(cnf.PwnedPasswordsURL).Breached
+	}
+	return policy
+}
@@ -0,0 +1,84 @@
+package oauth_test
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	pass "github.com/RichardKnop/go-oauth2-server/util/password"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestPasswordPolicy() {
+	// The breached password is the only one the API knows about
+	sum := sha1.Sum([]byte("breached password 1"))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	available := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !available {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/"+hash[:5]) {
+			fmt.Fprintf(w, "%s:42\r\n", hash[5:])
+		}
+	}))
+	defer server.Close()
+
+	suite.cnf.Oauth.EnableUserRegistration = true
+	suite.cnf.Oauth.PasswordPolicy = config.PasswordPolicyConfig{
+		MinLength:           10,
+		RequireDigit:        true,
+		BannedPasswords:     []string{"password123"},
+		CheckPwnedPasswords: true,
+		PwnedPasswordsURL:   server.URL + "/range/",
+	}
+	defer func() {
+		suite.cnf.Oauth.EnableUserRegistration = false
+		suite.cnf.Oauth.PasswordPolicy = config.PasswordPolicyConfig{}
+	}()
+
+	// The response lists every rule the password breaks
+	w := suite.registerUser(`{"username": "test@user_policy", "password": "short"}`)
+	suite.assertPasswordPolicyError(w, pass.ViolationTooShort, pass.ViolationMissingDigit)
+	w = suite.registerUser(`{"username": "test@user_policy", "password": "PASSWORD123"}`)
+	suite.assertPasswordPolicyError(w, pass.ViolationBanned)
+	w = suite.registerUser(`{"username": "test@user_policy", "password": "breached password 1"}`)
+	suite.assertPasswordPolicyError(w, pass.ViolationBreached)
+
+	w = suite.registerUser(`{"username": "test@user_policy", "password": "test_password_1"}`)
+	if !assert.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String()) {
+		return
+	}
+
+	// The policy applies to password changes too
+	user, err := suite.service.FindUserByUsername("test@user_policy")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	err = suite.service.SetPassword(user, "test_password")
+	if assert.IsType(suite.T(), new(pass.PolicyError), err) {
+		assert.Equal(suite.T(), []string{pass.ViolationMissingDigit}, err.(*pass.PolicyError).Violations)
+	}
+
+	// Passwords are accepted when the breach lookup fails
+	available = false
+	assert.NoError(suite.T(), suite.service.SetPassword(user, "breached password 1"))
+}
+
+func (suite *OauthTestSuite) assertPasswordPolicyError(w *httptest.ResponseRecorder, violations ...string) {
+	if !assert.Equal(suite.T(), http.StatusBadRequest, w.Code, w.Body.String()) {
+		return
+	}
+	resp := new(oauth.PasswordPolicyErrorResponse)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), resp))
+	assert.Equal(suite.T(), "invalid_password", resp.Error)
+	assert.Equal(suite.T(), violations, resp.Violations)
+	assert.NotEmpty(suite.T(), resp.ErrorDescription)
+}
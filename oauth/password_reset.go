@@ -0,0 +1,200 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/log"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// defaultPasswordResetLifetime is used when the lifetime is not configured
+	defaultPasswordResetLifetime = 3600
+	// passwordResetSubject is the subject of password reset emails
+	passwordResetSubject = "Reset your password"
+)
+
+var (
+	// ErrPasswordResetDisabled ...
+	ErrPasswordResetDisabled = errors.New("Password reset is disabled")
+	// ErrInvalidPasswordResetToken ...
+	ErrInvalidPasswordResetToken = errors.New("Invalid or expired password reset token")
+)
+
+// Mailer sends emails to users, such as the links users who forgot their
+// password reset it with. Implementations would typically use SMTP or the
+// API of an email delivery service.
+type Mailer interface {
+	SendMail(ctx context.Context, to, subject, body string) error
+}
+
+// PasswordResetRequest asks for a password reset email to be sent to the user
+type PasswordResetRequest struct {
+	Username string `json:"username"`
+}
+
+// PasswordResetConfirmRequest sets a new password with the emailed token
+type PasswordResetConfirmRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// SetMailer sets the mailer emailing users, password
+// reset is disabled until it is set
+func (s *Service) SetMailer(mailer Mailer) {
+	s.mailer = mailer
+}
+
+// RequestPasswordReset emails the user a single-use token to set a new
+// password with. Nothing is sent if there is no such user, but no error is
+// returned either so the response does not tell whether the user exists.
+func (s *Service) RequestPasswordReset(ctx context.Context, username string) error {
+	if s.mailer == nil {
+		return ErrPasswordResetDisabled
+	}
+
+	user, err := s.FindUserByUsername(username)
+	if err == ErrUserNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := newEmailedToken()
+	if err != nil {
+		return err
+	}
+	passwordReset := models.NewOauthPasswordReset(user, models.HashToken(token), s.getPasswordResetLifetime())
+	if err := s.db.Create(passwordReset).Error; err != nil {
+		return err
+	}
+
+	body := emailedTokenBody(s.cnf.Oauth.PasswordResetURL, token, "reset your password")
+	if err := s.mailer.SendMail(ctx, user.Username, passwordResetSubject, body); err != nil {
+		log.ERROR.Printf("Sending password reset email failed: %s", err)
+		return err
+	}
+	return nil
+}
+
+// ResetPassword sets a new password for the user the token was issued to.
+// The token and any other the user requested stop working, and so do the
+// tokens and sessions of the user as whoever knew the old password may
+// have logged in with it.
+func (s *Service) ResetPassword(token, password string) error {
+	passwordReset := new(models.OauthPasswordReset)
+	notFound := s.db.Where("token_hash = ?", models.HashToken(token)).
+		First(passwordReset).RecordNotFound()
+	if notFound || time.Now().UTC().After(passwordReset.ExpiresAt) {
+		return ErrInvalidPasswordResetToken
+	}
+
+	user, err := s.FindUserByID(passwordReset.UserID.String)
+	if err != nil {
+		return err
+	}
+	if err := s.checkPassword(password); err != nil {
+		return err
+	}
+	passwordHash, err := s.hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	var accessTokens []*models.OauthAccessToken
+	if err := s.db.Where("user_id = ?", user.ID).Find(&accessTokens).Error; err != nil {
+		return err
+	}
+
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	// Consuming the token fails if it was used concurrently
+	result := tx.Unscoped().Where("id = ?", passwordReset.ID).Delete(new(models.OauthPasswordReset))
+	if result.Error != nil || result.RowsAffected == 0 {
+		tx.Rollback() // rollback the transaction
+		if result.Error != nil {
+			return result.Error
+		}
+		return ErrInvalidPasswordResetToken
+	}
+
+	err = tx.Model(new(models.OauthUser)).Where("id = ?", user.ID).UpdateColumns(map[string]interface{}{
+		"password":   passwordHash,
+		"updated_at": time.Now().UTC(),
+	}).Error
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	if err := revokeUserSessions(tx, user); err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	s.invalidateAccessTokens(accessTokens)
+
+	return nil
+}
+
+// revokeUserSessions deletes the password reset tokens, tokens, authorization
+// codes and sessions of the user
+func revokeUserSessions(tx *gorm.DB, user *models.OauthUser) error {
+	for _, model := range []interface{}{
+		new(models.OauthPasswordReset),
+		new(models.OauthAccessToken),
+		new(models.OauthRefreshToken),
+		new(models.OauthAuthorizationCode),
+		new(models.OauthSession),
+	} {
+		if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// emailedTokenBody returns the body of an email carrying the token, linking
+// to the page if any with the token as its token query parameter
+func emailedTokenBody(page, token, purpose string) string {
+	pageURL, err := url.Parse(page)
+	if page == "" || err != nil {
+		return fmt.Sprintf("Use this code to %s: %s", purpose, token)
+	}
+	query := pageURL.Query()
+	query.Set("token", token)
+	pageURL.RawQuery = query.Encode()
+	return fmt.Sprintf("Follow this link to %s: %s", purpose, pageURL.String())
+}
+
+// getPasswordResetLifetime returns the configured password reset token lifetime
+func (s *Service) getPasswordResetLifetime() int {
+	if s.cnf.Oauth.PasswordResetLifetime <= 0 {
+		return defaultPasswordResetLifetime
+	}
+	return s.cnf.Oauth.PasswordResetLifetime
+}
+
+// newEmailedToken returns a random token to email to a user
+func newEmailedToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
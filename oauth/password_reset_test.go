@@ -0,0 +1,93 @@
+package oauth_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	"github.com/stretchr/testify/assert"
+)
+
+type testMail struct {
+	to, subject, body string
+}
+
+type testMailer struct {
+	sent []testMail
+}
+
+func (m *testMailer) SendMail(ctx context.Context, to, subject, body string) error {
+	m.sent = append(m.sent, testMail{to, subject, body})
+	return nil
+}
+
+func (suite *OauthTestSuite) TestPasswordReset() {
+	user, err := suite.service.CreateUser(roles.User, "test@user_reset", "test_password")
+	if !assert.NoError(suite.T(), err, "Inserting test data failed") {
+		return
+	}
+	accessToken, err := suite.service.GrantAccessToken(suite.clients[0], user, 3600, "read")
+	assert.NoError(suite.T(), err)
+	loginSession, err := suite.service.StartLoginSession(user)
+	assert.NoError(suite.T(), err)
+
+	// Password reset is disabled without a mailer
+	w := suite.passwordReset("", `{"username": "test@user_reset"}`)
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+	mailer := new(testMailer)
+	suite.service.SetMailer(mailer)
+	defer suite.service.SetMailer(nil)
+
+	// Unknown users get the same response but no email
+	w = suite.passwordReset("", `{"username": "test@user_unknown"}`)
+	assert.Equal(suite.T(), http.StatusNoContent, w.Code)
+	assert.Len(suite.T(), mailer.sent, 0)
+
+	// The user is emailed a token
+	w = suite.passwordReset("", `{"username": "test@user_reset"}`)
+	assert.Equal(suite.T(), http.StatusNoContent, w.Code)
+	if !assert.Len(suite.T(), mailer.sent, 1) {
+		return
+	}
+	assert.Equal(suite.T(), "test@user_reset", mailer.sent[0].to)
+	fields := strings.Fields(mailer.sent[0].body)
+	token := fields[len(fields)-1]
+
+	// Unknown tokens and short passwords are refused
+	w = suite.passwordReset("/confirm", `{"token": "bogus", "password": "new_password"}`)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	w = suite.passwordReset("/confirm", `{"token": "`+token+`", "password": "new"}`)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	// The token sets a new password once
+	w = suite.passwordReset("/confirm", `{"token": "`+token+`", "password": "new_password"}`)
+	assert.Equal(suite.T(), http.StatusNoContent, w.Code)
+	w = suite.passwordReset("/confirm", `{"token": "`+token+`", "password": "other_password"}`)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	_, err = suite.service.AuthUser("test@user_reset", "test_password")
+	assert.Equal(suite.T(), oauth.ErrInvalidUserPassword, err)
+	_, err = suite.service.AuthUser("test@user_reset", "new_password")
+	assert.NoError(suite.T(), err)
+
+	// Existing tokens and login sessions of the user are revoked
+	_, err = suite.service.Authenticate(accessToken.Token)
+	assert.Equal(suite.T(), oauth.ErrAccessTokenNotFound, err)
+	_, err = suite.service.GetLoginSession(loginSession.ID)
+	assert.Equal(suite.T(), oauth.ErrLoginSessionNotFound, err)
+}
+
+func (suite *OauthTestSuite) passwordReset(path, body string) *httptest.ResponseRecorder {
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/password-reset"+path, bytes.NewBufferString(body))
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.Header.Set("Content-Type", "application/json")
+	r.SetBasicAuth("test_client_1", "test_secret")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	return w
+}
@@ -0,0 +1,81 @@
+package oauth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"regexp"
+)
+
+const (
+	// CodeChallengeMethodPlain uses the code verifier as the code challenge
+	CodeChallengeMethodPlain = "plain"
+	// CodeChallengeMethodS256 uses the SHA-256 hash of the code verifier
+	CodeChallengeMethodS256 = "S256"
+)
+
+var (
+	// ErrInvalidCodeChallenge ...
+	ErrInvalidCodeChallenge = errors.New("Invalid code challenge")
+	// ErrInvalidCodeChallengeMethod ...
+	ErrInvalidCodeChallengeMethod = errors.New("Invalid code challenge method")
+	// ErrInvalidCodeVerifier ...
+	ErrInvalidCodeVerifier = errors.New("Invalid code verifier")
+
+	// Code challenges and verifiers are 43 to 128 unreserved characters,
+	// see https://tools.ietf.org/html/rfc7636#section-4.1
+	pkceValuePattern = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+)
+
+// getCodeChallengeMethod validates the code challenge and returns its method,
+// RFC 7636 defaults to plain when the client does not send a method
+func getCodeChallengeMethod(codeChallenge, codeChallengeMethod string) (string, error) {
+	if codeChallenge == "" {
+		if codeChallengeMethod != "" {
+			return "", ErrInvalidCodeChallenge
+		}
+		return "", nil
+	}
+
+	if !pkceValuePattern.MatchString(codeChallenge) {
+		return "", ErrInvalidCodeChallenge
+	}
+
+	switch codeChallengeMethod {
+	case "", CodeChallengeMethodPlain:
+		return CodeChallengeMethodPlain, nil
+	case CodeChallengeMethodS256:
+		return CodeChallengeMethodS256, nil
+	}
+
+	return "", ErrInvalidCodeChallengeMethod
+}
+
+// verifyCodeVerifier checks the code verifier sent to the token endpoint
+// matches the code challenge the authorization code was granted with
+func verifyCodeVerifier(codeChallenge, codeChallengeMethod, codeVerifier string) error {
+	// A verifier without a challenge means the request was tampered with
+	if codeChallenge == "" {
+		if codeVerifier != "" {
+			return ErrInvalidCodeVerifier
+		}
+		return nil
+	}
+
+	if !pkceValuePattern.MatchString(codeVerifier) {
+		return ErrInvalidCodeVerifier
+	}
+
+	expected := codeVerifier
+	if codeChallengeMethod == CodeChallengeMethodS256 {
+		sum := sha256.Sum256([]byte(codeVerifier))
+		expected = base64.RawURLEncoding.EncodeToString(sum[:])
+	}
+
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(codeChallenge)) != 1 {
+		return ErrInvalidCodeVerifier
+	}
+
+	return nil
+}
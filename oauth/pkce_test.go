@@ -0,0 +1,117 @@
+package oauth_test
+
+import (
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/test-util"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testCodeVerifier  = "test_code_verifier_0123456789abcdefghijklmnop"
+	testCodeChallenge = "zccmBUFCsCofEUVRW10CJ7Fn3s4bg_-dQln7fz5r-Lk"
+)
+
+func (suite *OauthTestSuite) TestGrantAuthorizationCodeInvalidCodeChallenge() {
+	for _, testCase := range []struct {
+		codeChallenge       string
+		codeChallengeMethod string
+		err                 error
+	}{
+		{"too_short", "S256", oauth.ErrInvalidCodeChallenge},
+		{"", "S256", oauth.ErrInvalidCodeChallenge},
+		{testCodeChallenge, "S512", oauth.ErrInvalidCodeChallengeMethod},
+	} {
+		_, err := suite.service.GrantAuthorizationCode(
+			suite.clients[0],             // client
+			suite.users[0],               // user
+			3600,                         // expires in
+			"https://www.example.com",    // redirect URI
+			"read_write",                 // scope
+			testCase.codeChallenge,       // code challenge
+			testCase.codeChallengeMethod, // code challenge method
+			"",                           // authorization details
+			"",                           // nonce
+		)
+		assert.Equal(suite.T(), testCase.err, err)
+	}
+}
+
+func (suite *OauthTestSuite) TestAuthorizationCodeGrantWithCodeChallenge() {
+	for _, testCase := range []struct {
+		codeChallenge       string
+		codeChallengeMethod string
+	}{
+		{testCodeChallenge, "S256"},
+		{testCodeVerifier, "plain"},
+		// The method defaults to plain
+		{testCodeVerifier, ""},
+	} {
+		authorizationCode, err := suite.service.GrantAuthorizationCode(
+			suite.clients[0],             // client
+			suite.users[0],               // user
+			3600,                         // expires in
+			"https://www.example.com",    // redirect URI
+			"read_write",                 // scope
+			testCase.codeChallenge,       // code challenge
+			testCase.codeChallengeMethod, // code challenge method
+			"",                           // authorization details
+			"",                           // nonce
+		)
+		if !assert.NoError(suite.T(), err) {
+			continue
+		}
+
+		// A missing or wrong verifier is rejected
+		for _, codeVerifier := range []string{"", testCodeChallenge + "x"} {
+			w := suite.exchangeAuthorizationCodeWithVerifier(authorizationCode.Code, codeVerifier)
+			testutil.TestResponseForOauthError(
+				suite.T(),
+				w,
+				"invalid_grant",
+				oauth.ErrInvalidCodeVerifier.Error(),
+				400,
+			)
+		}
+
+		// The code can still be exchanged with the right verifier
+		w := suite.exchangeAuthorizationCodeWithVerifier(authorizationCode.Code, testCodeVerifier)
+		assert.Equal(suite.T(), 200, w.Code)
+	}
+}
+
+func (suite *OauthTestSuite) TestAuthorizationCodeGrantUnexpectedCodeVerifier() {
+	authorizationCode, err := suite.service.GrantAuthorizationCode(
+		suite.clients[0],          // client
+		suite.users[0],            // user
+		3600,                      // expires in
+		"https://www.example.com", // redirect URI
+		"read_write",              // scope
+		"",                        // code challenge
+		"",                        // code challenge method
+		"",                        // authorization details
+		"",                        // nonce
+	)
+	assert.NoError(suite.T(), err)
+
+	// A verifier for a code granted without a challenge is rejected
+	w := suite.exchangeAuthorizationCodeWithVerifier(authorizationCode.Code, testCodeVerifier)
+	testutil.TestResponseForOauthError(
+		suite.T(),
+		w,
+		"invalid_grant",
+		oauth.ErrInvalidCodeVerifier.Error(),
+		400,
+	)
+}
+
+func (suite *OauthTestSuite) exchangeAuthorizationCodeWithVerifier(code, codeVerifier string) *httptest.ResponseRecorder {
+	return suite.tokenRequest(url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {"https://www.example.com"},
+		"code_verifier": {codeVerifier},
+	}, nil)
+}
@@ -0,0 +1,157 @@
+package oauth
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/RichardKnop/uuid"
+)
+
+const (
+	// requestURIPrefix is the prefix of request URIs
+	// recommended by https://tools.ietf.org/html/rfc9126#section-2.2
+	requestURIPrefix = "urn:ietf:params:oauth:request_uri:"
+	// defaultPushedRequestLifetime is used when the lifetime is not configured
+	defaultPushedRequestLifetime = 300
+)
+
+var (
+	// ErrPushedRequestNotFound ...
+	ErrPushedRequestNotFound = errors.New("Request URI not found")
+	// ErrPushedRequestExpired ...
+	ErrPushedRequestExpired = errors.New("Request URI expired")
+	// ErrPushedRequestRequired ...
+	ErrPushedRequestRequired = errors.New("Authorization requests must be pushed")
+	// ErrRequestURINotAllowed ...
+	ErrRequestURINotAllowed = errors.New("Request URI cannot be pushed")
+)
+
+// PushAuthorizationRequest stores the parameters of an authorization request,
+// the client then sends just the returned request URI to the authorization endpoint
+func (s *Service) PushAuthorizationRequest(client *models.OauthClient, parameters url.Values) (*models.OauthPushedRequest, error) {
+	pushedRequest := models.NewOauthPushedRequest(
+		client,
+		requestURIPrefix+uuid.New(),
+		parameters.Encode(),
+		s.getPushedRequestLifetime(),
+	)
+	if err := s.db.Create(pushedRequest).Error; err != nil {
+		return nil, err
+	}
+	pushedRequest.Client = client
+
+	return pushedRequest, nil
+}
+
+// FindPushedRequest returns the parameters of an authorization request
+// the client pushed, the request URI stays valid until it is deleted
+// or expires so it can be used by both the form and its submission
+func (s *Service) FindPushedRequest(client *models.OauthClient, requestURI string) (url.Values, error) {
+	// Fetch the pushed request from the database
+	pushedRequest := new(models.OauthPushedRequest)
+	notFound := s.db.Where("client_id = ?", client.ID).
+		Where("request_uri = ?", requestURI).First(pushedRequest).RecordNotFound()
+
+	// Not found
+	if notFound {
+		return nil, ErrPushedRequestNotFound
+	}
+
+	// Check the pushed request hasn't expired
+	if time.Now().UTC().After(pushedRequest.ExpiresAt) {
+		return nil, ErrPushedRequestExpired
+	}
+
+	return url.ParseQuery(pushedRequest.Parameters)
+}
+
+// DeletePushedRequest deletes a pushed request once the user has made
+// a decision, so its request URI cannot be used again
+func (s *Service) DeletePushedRequest(client *models.OauthClient, requestURI string) error {
+	return s.db.Unscoped().Where("client_id = ?", client.ID).
+		Where("request_uri = ?", requestURI).Delete(new(models.OauthPushedRequest)).Error
+}
+
+// pushedAuthorizationRequest validates an authorization request
+// the way the authorization endpoint would and stores it
+func (s *Service) pushedAuthorizationRequest(r *http.Request, client *models.OauthClient) (*PushedAuthorizationResponse, error) {
+	// Only the body counts, without the client credentials
+	parameters := url.Values{}
+	for name, values := range r.PostForm {
+		if !util.StringInSlice(name, clientCredentialParameters) {
+			parameters[name] = values
+		}
+	}
+
+	// A pushed request cannot refer to another one
+	if parameters.Get("request_uri") != "" {
+		return nil, ErrRequestURINotAllowed
+	}
+
+	// Check the response type and that the client can use it
+	responseType, err := ParseResponseType(parameters.Get("response_type"))
+	if err != nil {
+		return nil, err
+	}
+	for _, grantType := range ResponseTypeGrantTypes(responseType) {
+		if !s.grantTypeEnabled(grantType) {
+			return nil, ErrInvalidResponseType
+		}
+		if !ClientAllowsGrantType(client, grantType) {
+			return nil, ErrUnauthorizedClient
+		}
+	}
+	if !ClientAllowsResponseType(client, responseType) {
+		return nil, ErrResponseTypeNotAllowed
+	}
+
+	// The redirect URI must match the one registered for the client
+	redirectURI := parameters.Get("redirect_uri")
+	if redirectURI == "" {
+		redirectURI = client.RedirectURI.String
+	}
+	if err := s.ValidateRedirectURI(client, redirectURI); err != nil {
+		return nil, err
+	}
+
+	// Check the requested scope
+	if _, err := s.GetScope(parameters.Get("scope")); err != nil {
+		return nil, err
+	}
+
+	// Check the requested authorization details
+	if _, err := s.getAuthorizationDetails(parameters.Get("authorization_details")); err != nil {
+		return nil, err
+	}
+
+	// Check the code challenge
+	_, err = getCodeChallengeMethod(
+		parameters.Get("code_challenge"),
+		parameters.Get("code_challenge_method"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	pushedRequest, err := s.PushAuthorizationRequest(client, parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PushedAuthorizationResponse{
+		RequestURI: pushedRequest.RequestURI,
+		ExpiresIn:  s.getPushedRequestLifetime(),
+	}, nil
+}
+
+// getPushedRequestLifetime returns the configured pushed request lifetime
+func (s *Service) getPushedRequestLifetime() int {
+	if s.cnf.Oauth.PushedRequestLifetime <= 0 {
+		return defaultPushedRequestLifetime
+	}
+	return s.cnf.Oauth.PushedRequestLifetime
+}
@@ -0,0 +1,116 @@
+package oauth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/test-util"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestPushedAuthorizationRequest() {
+	w := suite.pushAuthorizationRequest(url.Values{
+		"client_id":     {"test_client_1"},
+		"response_type": {"code"},
+		"redirect_uri":  {"https://www.example.com"},
+		"scope":         {"read_write"},
+		"state":         {"test_state"},
+	})
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+	resp := new(oauth.PushedAuthorizationResponse)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), resp))
+	assert.True(suite.T(), strings.HasPrefix(resp.RequestURI, "urn:ietf:params:oauth:request_uri:"))
+	assert.Equal(suite.T(), 300, resp.ExpiresIn)
+
+	// The parameters are stored without the client credentials
+	parameters, err := suite.service.FindPushedRequest(suite.clients[0], resp.RequestURI)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), url.Values{
+			"response_type": {"code"},
+			"redirect_uri":  {"https://www.example.com"},
+			"scope":         {"read_write"},
+			"state":         {"test_state"},
+		}, parameters)
+	}
+
+	// Only for the client which pushed them
+	_, err = suite.service.FindPushedRequest(suite.clients[1], resp.RequestURI)
+	assert.Equal(suite.T(), oauth.ErrPushedRequestNotFound, err)
+
+	// Until the request URI is used
+	assert.NoError(suite.T(), suite.service.DeletePushedRequest(suite.clients[0], resp.RequestURI))
+	_, err = suite.service.FindPushedRequest(suite.clients[0], resp.RequestURI)
+	assert.Equal(suite.T(), oauth.ErrPushedRequestNotFound, err)
+}
+
+func (suite *OauthTestSuite) TestPushedAuthorizationRequestExpired() {
+	pushedRequest, err := suite.service.PushAuthorizationRequest(suite.clients[0], url.Values{
+		"response_type": {"code"},
+	})
+	assert.NoError(suite.T(), err)
+	err = suite.db.Model(new(models.OauthPushedRequest)).Where("id = ?", pushedRequest.ID).
+		UpdateColumn("expires_at", time.Now().UTC().Add(-10*time.Second)).Error
+	assert.NoError(suite.T(), err)
+
+	_, err = suite.service.FindPushedRequest(suite.clients[0], pushedRequest.RequestURI)
+	assert.Equal(suite.T(), oauth.ErrPushedRequestExpired, err)
+}
+
+func (suite *OauthTestSuite) TestPushedAuthorizationRequestInvalid() {
+	for _, testCase := range []struct {
+		parameters url.Values
+		err        error
+	}{
+		{
+			url.Values{"response_type": {"code"}, "request_uri": {"urn:ietf:params:oauth:request_uri:test"}},
+			oauth.ErrRequestURINotAllowed,
+		},
+		{
+			url.Values{"response_type": {"bogus"}},
+			oauth.ErrInvalidResponseType,
+		},
+		{
+			// The implicit grant is disabled
+			url.Values{"response_type": {"token"}},
+			oauth.ErrInvalidResponseType,
+		},
+		{
+			// The implicit grant is needed for the access token
+			url.Values{"response_type": {"code token"}},
+			oauth.ErrInvalidResponseType,
+		},
+		{
+			url.Values{"response_type": {"code"}, "redirect_uri": {"https://www.bogus.com"}},
+			oauth.ErrRedirectURIMismatch,
+		},
+		{
+			url.Values{"response_type": {"code"}, "scope": {"bogus"}},
+			oauth.ErrInvalidScope,
+		},
+		{
+			url.Values{"response_type": {"code"}, "code_challenge": {"bogus"}},
+			oauth.ErrInvalidCodeChallenge,
+		},
+	} {
+		w := suite.pushAuthorizationRequest(testCase.parameters)
+		testutil.TestResponseForError(suite.T(), w, testCase.err.Error(), 400)
+	}
+	assert.True(suite.T(), suite.db.First(new(models.OauthPushedRequest)).RecordNotFound())
+}
+
+func (suite *OauthTestSuite) pushAuthorizationRequest(parameters url.Values) *httptest.ResponseRecorder {
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/par", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
+	r.PostForm = parameters
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	return w
+}
@@ -0,0 +1,109 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+)
+
+const (
+	// recoveryCodeCount is the number of recovery codes generated at once
+	recoveryCodeCount = 10
+)
+
+var (
+	// ErrRecoveryCodesRequireUser ...
+	ErrRecoveryCodesRequireUser = errors.New("Recovery codes require an access token granted to a user")
+)
+
+// GenerateRecoveryCodes replaces any recovery codes of the user with a new set
+// and returns them, the codes cannot be retrieved again as only hashes are stored
+func (s *Service) GenerateRecoveryCodes(user *models.OauthUser) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+	}
+
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	// Invalidate the old codes
+	if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(new(models.OauthRecoveryCode)).Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+
+	// Store hashes of the new codes
+	for _, code := range codes {
+		if err := tx.Create(models.NewOauthRecoveryCode(user, models.HashToken(code))).Error; err != nil {
+			tx.Rollback() // rollback the transaction
+			return nil, err
+		}
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// regenerateRecoveryCodes generates recovery codes for the user of an
+// authenticated session. Once the user has an authenticator the session
+// must have been authenticated with a second factor as well, otherwise
+// the password alone would be enough to bypass it.
+func (s *Service) regenerateRecoveryCodes(accessToken *models.OauthAccessToken) (*RecoveryCodesResponse, error) {
+	// Client credentials tokens have no user
+	if !accessToken.UserID.Valid {
+		return nil, ErrRecoveryCodesRequireUser
+	}
+
+	user, err := s.totpUser(accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, err := s.GenerateRecoveryCodes(user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// useRecoveryCode consumes a recovery code of the user, it returns false
+// if the code does not exist or has already been used
+func (s *Service) useRecoveryCode(user *models.OauthUser, code string) (bool, error) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if code == "" {
+		return false, nil
+	}
+
+	// Deleting the code makes sure it can only be used once
+	result := s.db.Unscoped().Where("user_id = ? AND code_hash = ?", user.ID, models.HashToken(code)).
+		Delete(new(models.OauthRecoveryCode))
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// newRecoveryCode returns a random code formatted as xxxxx-xxxxx
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 5)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := hex.EncodeToString(b)
+	return code[:5] + "-" + code[5:], nil
+}
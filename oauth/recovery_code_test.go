@@ -0,0 +1,92 @@
+package oauth_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	"github.com/RichardKnop/go-oauth2-server/test-util"
+	"github.com/RichardKnop/go-oauth2-server/util/totp"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestRecoveryCodes() {
+	// Insert a test user with an authenticator
+	secret, err := totp.GenerateSecret()
+	assert.NoError(suite.T(), err)
+	user, err := suite.service.CreateUser(roles.User, "test@user_recovery", "test_password")
+	assert.NoError(suite.T(), err, "Inserting test data failed")
+	err = suite.db.Model(user).UpdateColumn("totp_secret", secret).Error
+	assert.NoError(suite.T(), err, "Inserting test data failed")
+
+	// Log in with the second factor
+	otp, err := totp.Code(secret, time.Now())
+	assert.NoError(suite.T(), err)
+	token := suite.accessTokenFromGrant(suite.passwordGrantWithAcr("test@user_recovery", "mfa", otp))
+
+	// Generate recovery codes
+	codes := suite.regenerateRecoveryCodes(token)
+	assert.Equal(suite.T(), 10, len(codes))
+
+	// A recovery code can be used in place of the one-time password
+	w := suite.passwordGrantWithAcr("test@user_recovery", "mfa", codes[0])
+	suite.assertAcr(w, oauth.AcrMFA)
+
+	// But only once
+	w = suite.passwordGrantWithAcr("test@user_recovery", "mfa", codes[0])
+	testutil.TestResponseForOauthError(suite.T(), w, "invalid_grant", oauth.ErrInvalidOTP.Error(), 400)
+
+	// Regenerating the codes invalidates the old ones
+	newCodes := suite.regenerateRecoveryCodes(token)
+	w = suite.passwordGrantWithAcr("test@user_recovery", "mfa", codes[1])
+	testutil.TestResponseForOauthError(suite.T(), w, "invalid_grant", oauth.ErrInvalidOTP.Error(), 400)
+	w = suite.passwordGrantWithAcr("test@user_recovery", "mfa", newCodes[1])
+	suite.assertAcr(w, oauth.AcrMFA)
+}
+
+func (suite *OauthTestSuite) TestRecoveryCodesRequireMFA() {
+	// Insert a test user with an authenticator
+	secret, err := totp.GenerateSecret()
+	assert.NoError(suite.T(), err)
+	user, err := suite.service.CreateUser(roles.User, "test@user_recovery", "test_password")
+	assert.NoError(suite.T(), err, "Inserting test data failed")
+	err = suite.db.Model(user).UpdateColumn("totp_secret", secret).Error
+	assert.NoError(suite.T(), err, "Inserting test data failed")
+
+	// A session without the second factor cannot generate recovery codes
+	accessToken, err := suite.service.GrantAccessToken(suite.clients[0], user, 3600, "read")
+	assert.NoError(suite.T(), err)
+	w := suite.requestRecoveryCodes(accessToken.Token)
+	testutil.TestResponseForError(suite.T(), w, oauth.ErrMFARequired.Error(), 401)
+}
+
+func (suite *OauthTestSuite) accessTokenFromGrant(w *httptest.ResponseRecorder) string {
+	assert.Equal(suite.T(), 200, w.Code)
+	resp := new(oauth.AccessTokenResponse)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), resp))
+	return resp.AccessToken
+}
+
+func (suite *OauthTestSuite) regenerateRecoveryCodes(token string) []string {
+	w := suite.requestRecoveryCodes(token)
+	if !assert.Equal(suite.T(), 200, w.Code) {
+		return nil
+	}
+	resp := new(oauth.RecoveryCodesResponse)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), resp))
+	return resp.RecoveryCodes
+}
+
+func (suite *OauthTestSuite) requestRecoveryCodes(token string) *httptest.ResponseRecorder {
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/recovery-codes", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	return w
+}
@@ -0,0 +1,198 @@
+package oauth
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util"
+)
+
+var (
+	// ErrRedirectURIMismatch ...
+	ErrRedirectURIMismatch = errors.New("Redirect URI does not match the registered redirect URI")
+	// ErrRedirectURISchemeNotAllowed ...
+	ErrRedirectURISchemeNotAllowed = errors.New("Redirect URI scheme is not allowed")
+	// ErrInsecureRedirectURI ...
+	ErrInsecureRedirectURI = errors.New("Redirect URI must use https")
+	// ErrRedirectURINotRegistered ...
+	ErrRedirectURINotRegistered = errors.New("Client has not registered a redirect URI")
+	// ErrWildcardRedirectURI ...
+	ErrWildcardRedirectURI = errors.New("Redirect URI must not use a wildcard host")
+	// ErrRedirectURIFragment ...
+	ErrRedirectURIFragment = errors.New("Redirect URI must not include a fragment")
+	// ErrRedirectURIAlreadyRegistered ...
+	ErrRedirectURIAlreadyRegistered = errors.New("Redirect URI already registered")
+
+	// defaultAllowedRedirectSchemes is used when no schemes are configured
+	defaultAllowedRedirectSchemes = []string{"https"}
+
+	// forbiddenRedirectSchemes can never be used, even if configured
+	forbiddenRedirectSchemes = []string{"javascript", "data", "vbscript", "file"}
+)
+
+// ValidateRedirectURI checks a requested redirect URI against the redirect URIs
+// registered for the client. URIs must match exactly, except for native apps
+// using a loopback redirect URI, where the port may vary (RFC 8252 section 7.3).
+// Clients which have not registered a redirect URI cannot be redirected to.
+func (s *Service) ValidateRedirectURI(client *models.OauthClient, redirectURI string) error {
+	registered, err := s.GetRedirectURIs(client)
+	if err != nil {
+		return err
+	}
+	if len(registered) == 0 {
+		return ErrRedirectURINotRegistered
+	}
+
+	for _, registeredURI := range registered {
+		if redirectURI == registeredURI {
+			return s.validateRedirectURIScheme(redirectURI, client.NativeApp)
+		}
+
+		if client.NativeApp && loopbackURIsMatch(registeredURI, redirectURI) {
+			return s.validateRedirectURIScheme(redirectURI, client.NativeApp)
+		}
+	}
+
+	return ErrRedirectURIMismatch
+}
+
+// GetRedirectURIs returns the redirect URIs registered for the client
+func (s *Service) GetRedirectURIs(client *models.OauthClient) ([]string, error) {
+	var redirectURIs []string
+	if client.RedirectURI.Valid && client.RedirectURI.String != "" {
+		redirectURIs = append(redirectURIs, client.RedirectURI.String)
+	}
+
+	// Unsaved clients have no other redirect URIs
+	if client.ID == "" {
+		return redirectURIs, nil
+	}
+
+	var rows []*models.OauthRedirectURI
+	if err := s.db.Where("client_id = ?", client.ID).Order("created_at").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		redirectURIs = append(redirectURIs, row.URI)
+	}
+
+	return redirectURIs, nil
+}
+
+// AddRedirectURI registers another redirect URI for the client
+func (s *Service) AddRedirectURI(client *models.OauthClient, redirectURI string) (*models.OauthRedirectURI, error) {
+	if err := s.validateRegisteredRedirectURI(redirectURI, client.NativeApp); err != nil {
+		return nil, err
+	}
+
+	registered, err := s.GetRedirectURIs(client)
+	if err != nil {
+		return nil, err
+	}
+	if util.StringInSlice(redirectURI, registered) {
+		return nil, ErrRedirectURIAlreadyRegistered
+	}
+
+	row := models.NewOauthRedirectURI(client, redirectURI)
+	if err := s.db.Create(row).Error; err != nil {
+		return nil, err
+	}
+	row.Client = client
+
+	return row, nil
+}
+
+// RemoveRedirectURI unregisters a redirect URI added for the client,
+// authorization codes issued for it can no longer be exchanged
+func (s *Service) RemoveRedirectURI(client *models.OauthClient, redirectURI string) error {
+	return s.db.Unscoped().Where("client_id = ? AND uri = ?", client.ID, redirectURI).
+		Delete(new(models.OauthRedirectURI)).Error
+}
+
+// validateRegisteredRedirectURI checks a redirect URI can be registered, it
+// must be an absolute URI without a fragment (RFC 6749 section 3.1.2) and
+// cannot use a wildcard host, which would make the server an open redirector
+func (s *Service) validateRegisteredRedirectURI(redirectURI string, nativeApp bool) error {
+	if err := s.validateRedirectURIScheme(redirectURI, nativeApp); err != nil {
+		return err
+	}
+
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return ErrRedirectURISchemeNotAllowed
+	}
+	if u.Fragment != "" || strings.Contains(redirectURI, "#") {
+		return ErrRedirectURIFragment
+	}
+	if strings.Contains(u.Host, "*") {
+		return ErrWildcardRedirectURI
+	}
+
+	return nil
+}
+
+// validateRedirectURIScheme checks the redirect URI uses one of the allowed
+// schemes, or plain http with a loopback IP literal. In strict mode only
+// native apps can use schemes other than https.
+func (s *Service) validateRedirectURIScheme(redirectURI string, nativeApp bool) error {
+	u, err := url.Parse(redirectURI)
+	if err != nil || u.Scheme == "" {
+		return ErrRedirectURISchemeNotAllowed
+	}
+
+	if util.StringInSlice(u.Scheme, forbiddenRedirectSchemes) {
+		return ErrRedirectURISchemeNotAllowed
+	}
+
+	if s.cnf.Oauth.StrictRedirectURIs && !nativeApp {
+		if u.Scheme != "https" {
+			return ErrInsecureRedirectURI
+		}
+		return nil
+	}
+
+	if isLoopbackURL(u) || util.StringInSlice(u.Scheme, s.getAllowedRedirectSchemes()) {
+		return nil
+	}
+
+	return ErrRedirectURISchemeNotAllowed
+}
+
+// getAllowedRedirectSchemes returns the configured redirect URI schemes
+func (s *Service) getAllowedRedirectSchemes() []string {
+	if len(s.cnf.Oauth.AllowedRedirectSchemes) == 0 {
+		return defaultAllowedRedirectSchemes
+	}
+	return s.cnf.Oauth.AllowedRedirectSchemes
+}
+
+// loopbackURIsMatch returns true if both URIs are loopback redirect URIs
+// which only differ in their port
+func loopbackURIsMatch(registered, requested string) bool {
+	registeredURL, err := url.Parse(registered)
+	if err != nil || !isLoopbackURL(registeredURL) {
+		return false
+	}
+
+	requestedURL, err := url.Parse(requested)
+	if err != nil || !isLoopbackURL(requestedURL) {
+		return false
+	}
+
+	return registeredURL.Scheme == requestedURL.Scheme &&
+		registeredURL.Hostname() == requestedURL.Hostname() &&
+		registeredURL.Path == requestedURL.Path &&
+		registeredURL.RawQuery == requestedURL.RawQuery
+}
+
+// isLoopbackURL returns true for http URLs using a loopback IP literal
+func isLoopbackURL(u *url.URL) bool {
+	if u.Scheme != "http" {
+		return false
+	}
+	ip := net.ParseIP(u.Hostname())
+	return ip != nil && ip.IsLoopback()
+}
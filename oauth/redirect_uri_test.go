@@ -0,0 +1,263 @@
+package oauth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/test-util"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/RichardKnop/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestValidateRedirectURINativeAppLoopback() {
+	client := &models.OauthClient{
+		Key:         "test_native_client",
+		RedirectURI: util.StringOrNull("http://127.0.0.1:8080/callback"),
+		NativeApp:   true,
+	}
+
+	// Loopback redirect URIs should match regardless of the port
+	assert.NoError(suite.T(), suite.service.ValidateRedirectURI(client, "http://127.0.0.1:8080/callback"))
+	assert.NoError(suite.T(), suite.service.ValidateRedirectURI(client, "http://127.0.0.1:51234/callback"))
+	assert.NoError(suite.T(), suite.service.ValidateRedirectURI(client, "http://127.0.0.1/callback"))
+
+	// But everything other than the port must still match
+	assert.Equal(
+		suite.T(),
+		oauth.ErrRedirectURIMismatch,
+		suite.service.ValidateRedirectURI(client, "http://127.0.0.1:51234/other"),
+	)
+	assert.Equal(
+		suite.T(),
+		oauth.ErrRedirectURIMismatch,
+		suite.service.ValidateRedirectURI(client, "http://[::1]:51234/callback"),
+	)
+	assert.Equal(
+		suite.T(),
+		oauth.ErrRedirectURIMismatch,
+		suite.service.ValidateRedirectURI(client, "https://127.0.0.1:51234/callback"),
+	)
+
+	// Loopback port flexibility only applies to native apps
+	client.NativeApp = false
+	assert.Equal(
+		suite.T(),
+		oauth.ErrRedirectURIMismatch,
+		suite.service.ValidateRedirectURI(client, "http://127.0.0.1:51234/callback"),
+	)
+}
+
+func (suite *OauthTestSuite) TestValidateRedirectURIExactMatch() {
+	client := &models.OauthClient{
+		Key:         "test_native_client",
+		RedirectURI: util.StringOrNull("https://www.example.com/callback"),
+		NativeApp:   true,
+	}
+
+	// Non loopback redirect URIs must match exactly, even for native apps
+	assert.NoError(suite.T(), suite.service.ValidateRedirectURI(client, "https://www.example.com/callback"))
+	for _, redirectURI := range []string{
+		"https://www.example.com:8443/callback",
+		"https://www.example.com/callback/",
+		"https://www.example.com/callback?foo=bar",
+		"http://www.example.com/callback",
+	} {
+		assert.Equal(
+			suite.T(),
+			oauth.ErrRedirectURIMismatch,
+			suite.service.ValidateRedirectURI(client, redirectURI),
+		)
+	}
+
+	// No redirect URI is accepted when the client has not registered one
+	client.RedirectURI = util.StringOrNull("")
+	assert.Equal(
+		suite.T(),
+		oauth.ErrRedirectURINotRegistered,
+		suite.service.ValidateRedirectURI(client, "https://bogus"),
+	)
+}
+
+func (suite *OauthTestSuite) TestValidateRedirectURIScheme() {
+	defer func() { suite.cnf.Oauth.AllowedRedirectSchemes = nil }()
+
+	client := &models.OauthClient{Key: "test_native_client"}
+
+	// Only https and loopback http are allowed by default
+	assert.NoError(suite.T(), suite.validateOwnRedirectURI(client, "https://www.example.com/callback"))
+	assert.NoError(suite.T(), suite.validateOwnRedirectURI(client, "http://127.0.0.1:51234/callback"))
+	for _, redirectURI := range []string{
+		"http://www.example.com/callback",
+		"com.example.app:/callback",
+		"javascript:alert(1)",
+		"/callback",
+	} {
+		assert.Equal(
+			suite.T(),
+			oauth.ErrRedirectURISchemeNotAllowed,
+			suite.validateOwnRedirectURI(client, redirectURI),
+		)
+	}
+
+	// Custom schemes of native apps can be whitelisted
+	suite.cnf.Oauth.AllowedRedirectSchemes = []string{"https", "com.example.app", "javascript"}
+	assert.NoError(suite.T(), suite.validateOwnRedirectURI(client, "com.example.app:/callback"))
+
+	// But dangerous schemes never
+	assert.Equal(
+		suite.T(),
+		oauth.ErrRedirectURISchemeNotAllowed,
+		suite.validateOwnRedirectURI(client, "javascript:alert(1)"),
+	)
+}
+
+func (suite *OauthTestSuite) TestCreateClientRedirectURIScheme() {
+	_, err := suite.service.CreateClient("test_client_javascript", "test_secret", "javascript:alert(1)")
+	assert.Equal(suite.T(), oauth.ErrRedirectURISchemeNotAllowed, err)
+
+	_, err = suite.service.CreateClient("test_client_custom", "test_secret", "com.example.app:/callback")
+	assert.Equal(suite.T(), oauth.ErrRedirectURISchemeNotAllowed, err)
+
+	client, err := suite.service.CreateClient("test_client_https", "test_secret", "https://www.example.com/callback")
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), "https://www.example.com/callback", client.RedirectURI.String)
+	}
+}
+
+func (suite *OauthTestSuite) TestValidateRedirectURIStrict() {
+	suite.cnf.Oauth.StrictRedirectURIs = true
+	suite.cnf.Oauth.AllowedRedirectSchemes = []string{"https", "com.example.app"}
+	defer func() {
+		suite.cnf.Oauth.StrictRedirectURIs = false
+		suite.cnf.Oauth.AllowedRedirectSchemes = nil
+	}()
+
+	client := &models.OauthClient{Key: "test_client"}
+	nativeClient := &models.OauthClient{Key: "test_native_client", NativeApp: true}
+
+	// https is accepted for all clients
+	assert.NoError(suite.T(), suite.validateOwnRedirectURI(client, "https://www.example.com/cb"))
+	assert.NoError(suite.T(), suite.validateOwnRedirectURI(nativeClient, "https://www.example.com/cb"))
+
+	// Loopback http and custom schemes only for native apps
+	assert.NoError(suite.T(), suite.validateOwnRedirectURI(nativeClient, "http://127.0.0.1:51234/cb"))
+	assert.NoError(suite.T(), suite.validateOwnRedirectURI(nativeClient, "com.example.app:/cb"))
+	for _, redirectURI := range []string{"http://127.0.0.1:51234/cb", "com.example.app:/cb"} {
+		assert.Equal(
+			suite.T(),
+			oauth.ErrInsecureRedirectURI,
+			suite.validateOwnRedirectURI(client, redirectURI),
+		)
+	}
+
+	// Plain http is rejected
+	assert.Equal(
+		suite.T(),
+		oauth.ErrInsecureRedirectURI,
+		suite.validateOwnRedirectURI(client, "http://example.com/cb"),
+	)
+	assert.Equal(
+		suite.T(),
+		oauth.ErrRedirectURISchemeNotAllowed,
+		suite.validateOwnRedirectURI(nativeClient, "http://example.com/cb"),
+	)
+
+	// Including at registration
+	_, err := suite.service.CreateClient("test_client_http", "test_secret", "http://example.com/cb")
+	assert.Equal(suite.T(), oauth.ErrInsecureRedirectURI, err)
+	_, err = suite.service.CreateClient("test_client_https", "test_secret", "https://example.com/cb")
+	assert.NoError(suite.T(), err)
+}
+
+// validateOwnRedirectURI registers the redirect URI for the client and validates
+// it, so only the scheme of the redirect URI is checked
+func (suite *OauthTestSuite) validateOwnRedirectURI(client *models.OauthClient, redirectURI string) error {
+	client.RedirectURI = util.StringOrNull(redirectURI)
+	return suite.service.ValidateRedirectURI(client, redirectURI)
+}
+
+func (suite *OauthTestSuite) TestAddRedirectURI() {
+	client := suite.clients[0]
+
+	_, err := suite.service.AddRedirectURI(client, "https://app.example.com/callback")
+	assert.NoError(suite.T(), err)
+
+	// Both the client redirect URI and the added one can be redirected to
+	redirectURIs, err := suite.service.GetRedirectURIs(client)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), []string{"https://www.example.com", "https://app.example.com/callback"}, redirectURIs)
+	}
+	assert.NoError(suite.T(), suite.service.ValidateRedirectURI(client, "https://www.example.com"))
+	assert.NoError(suite.T(), suite.service.ValidateRedirectURI(client, "https://app.example.com/callback"))
+	assert.Equal(
+		suite.T(),
+		oauth.ErrRedirectURIMismatch,
+		suite.service.ValidateRedirectURI(client, "https://app.example.com/callback/../other"),
+	)
+
+	// Open redirectors cannot be registered
+	for redirectURI, expected := range map[string]error{
+		"https://app.example.com/callback": oauth.ErrRedirectURIAlreadyRegistered,
+		"https://*.example.com/callback":   oauth.ErrWildcardRedirectURI,
+		"https://*/callback":               oauth.ErrWildcardRedirectURI,
+		"https://app.example.com/cb#frag":  oauth.ErrRedirectURIFragment,
+		"javascript:alert(1)":              oauth.ErrRedirectURISchemeNotAllowed,
+	} {
+		_, err := suite.service.AddRedirectURI(client, redirectURI)
+		assert.Equal(suite.T(), expected, err, redirectURI)
+	}
+	_, err = suite.service.CreateClient("test_client_wildcard", "test_secret", "https://*.example.com")
+	assert.Equal(suite.T(), oauth.ErrWildcardRedirectURI, err)
+}
+
+func (suite *OauthTestSuite) TestRemoveRedirectURI() {
+	client := suite.clients[0]
+	_, err := suite.service.AddRedirectURI(client, "https://app.example.com/callback")
+	assert.NoError(suite.T(), err)
+
+	// Insert an authorization code issued for the added redirect URI
+	err = suite.db.Create(&models.OauthAuthorizationCode{
+		MyGormModel: models.MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		Code:        "test_code",
+		ExpiresAt:   time.Now().UTC().Add(+10 * time.Second),
+		Client:      client,
+		User:        suite.users[0],
+		RedirectURI: util.StringOrNull("https://app.example.com/callback"),
+		Scope:       "read_write",
+	}).Error
+	assert.NoError(suite.T(), err, "Inserting test data failed")
+
+	assert.NoError(suite.T(), suite.service.RemoveRedirectURI(client, "https://app.example.com/callback"))
+	assert.Equal(
+		suite.T(),
+		oauth.ErrRedirectURIMismatch,
+		suite.service.ValidateRedirectURI(client, "https://app.example.com/callback"),
+	)
+
+	// The authorization code can no longer be exchanged
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
+	r.PostForm = url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {"test_code"},
+		"redirect_uri": {"https://app.example.com/callback"},
+	}
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	testutil.TestResponseForOauthError(
+		suite.T(),
+		w,
+		"invalid_grant",
+		oauth.ErrInvalidRedirectURI.Error(),
+		400,
+	)
+}
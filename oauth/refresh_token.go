@@ -2,8 +2,11 @@ package oauth
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
+	"github.com/RichardKnop/go-oauth2-server/log"
 	"github.com/RichardKnop/go-oauth2-server/models"
 	"github.com/RichardKnop/go-oauth2-server/util"
 )
@@ -15,51 +18,62 @@ var (
 	ErrRefreshTokenExpired = errors.New("Refresh token expired")
 	// ErrRequestedScopeCannotBeGreater ...
 	ErrRequestedScopeCannotBeGreater = errors.New("Requested scope cannot be greater")
+	// ErrRefreshTokenGrantTypeDisabled ...
+	ErrRefreshTokenGrantTypeDisabled = errors.New("Refresh token was issued by a grant type which is not enabled")
+	// ErrRefreshTokenReused ...
+	ErrRefreshTokenReused = errors.New("Refresh token has already been used")
 )
 
-// GetOrCreateRefreshToken retrieves an existing refresh token, if expired,
-// the token gets deleted and new refresh token is created
-func (s *Service) GetOrCreateRefreshToken(client *models.OauthClient, user *models.OauthUser, expiresIn int, scope string) (*models.OauthRefreshToken, error) {
-	// Try to fetch an existing refresh token first
-	refreshToken := new(models.OauthRefreshToken)
-	query := models.OauthRefreshTokenPreload(s.db).Where("client_id = ?", client.ID)
+// ScopeNotGrantedError is returned when refreshing with scopes
+// not originally granted, it names the offending scopes
+type ScopeNotGrantedError struct {
+	Scopes []string
+}
+
+func (e *ScopeNotGrantedError) Error() string {
+	return fmt.Sprintf("%s, not originally granted: %s",
+		ErrRequestedScopeCannotBeGreater, strings.Join(e.Scopes, " "))
+}
+
+// CreateRefreshToken deletes expired refresh tokens and creates a new
+// one. Existing refresh tokens cannot be handed out again as only their
+// hashes are stored.
+func (s *Service) CreateRefreshToken(client *models.OauthClient, user *models.OauthUser, expiresIn int, scope string) (*models.OauthRefreshToken, error) {
+	// Delete expired refresh tokens
+	query := s.db.Unscoped().Where("client_id = ?", client.ID).
+		Where("expires_at < ?", time.Now().UTC())
 	if user != nil && len([]rune(user.ID)) > 0 {
 		query = query.Where("user_id = ?", user.ID)
 	} else {
 		query = query.Where("user_id IS NULL")
 	}
-	found := !query.First(refreshToken).RecordNotFound()
-
-	// Check if the token is expired, if found
-	var expired bool
-	if found {
-		expired = time.Now().UTC().After(refreshToken.ExpiresAt)
+	if err := query.Delete(new(models.OauthRefreshToken)).Error; err != nil {
+		return nil, err
 	}
 
-	// If the refresh token has expired, delete it
-	if expired {
-		s.db.Unscoped().Delete(refreshToken)
+	// Create a new refresh token
+	refreshToken := models.NewOauthRefreshToken(client, user, expiresIn, scope)
+	token, err := s.getTokenGenerator().GenerateRefreshToken()
+	if err != nil {
+		return nil, err
 	}
-
-	// Create a new refresh token if it expired or was not found
-	if expired || !found {
-		refreshToken = models.NewOauthRefreshToken(client, user, expiresIn, scope)
-		if err := s.db.Create(refreshToken).Error; err != nil {
-			return nil, err
-		}
-		refreshToken.Client = client
-		refreshToken.User = user
+	refreshToken.Token = token
+	if err := s.db.Create(refreshToken).Error; err != nil {
+		return nil, err
 	}
+	refreshToken.Client = client
+	refreshToken.User = user
 
 	return refreshToken, nil
 }
 
-// GetValidRefreshToken returns a valid non expired refresh token
+// GetValidRefreshToken returns a valid non expired refresh token,
+// a refresh token which has been rotated is no longer valid
 func (s *Service) GetValidRefreshToken(token string, client *models.OauthClient) (*models.OauthRefreshToken, error) {
 	// Fetch the refresh token from the database
 	refreshToken := new(models.OauthRefreshToken)
 	notFound := models.OauthRefreshTokenPreload(s.db).Where("client_id = ?", client.ID).
-		Where("token = ?", token).First(refreshToken).RecordNotFound()
+		Where("token = ?", models.HashToken(token)).First(refreshToken).RecordNotFound()
 
 	// Not found
 	if notFound {
@@ -71,6 +85,11 @@ func (s *Service) GetValidRefreshToken(token string, client *models.OauthClient)
 		return nil, ErrRefreshTokenExpired
 	}
 
+	// Check the refresh token hasn't been rotated
+	if refreshToken.UsedAt != nil {
+		return nil, ErrRefreshTokenReused
+	}
+
 	return refreshToken, nil
 }
 
@@ -81,18 +100,50 @@ func (s *Service) getRefreshTokenScope(refreshToken *models.OauthRefreshToken, r
 		err   error
 	)
 
-	// If the scope is specified in the request, get the scope string
+	// If the scope is specified in the request, it CANNOT include
+	// any scope not originally granted
 	if requestedScope != "" {
-		scope, err = s.GetScope(requestedScope)
+		notGranted := util.SpaceDelimitedStringDifference(requestedScope, refreshToken.Scope)
+		if len(notGranted) > 0 {
+			return "", &ScopeNotGrantedError{Scopes: notGranted}
+		}
+
+		scope, err = s.getNarrowedScope(requestedScope, refreshToken.Scope)
 		if err != nil {
 			return "", err
 		}
 	}
 
-	// Requested scope CANNOT include any scope not originally granted
-	if !util.SpaceDelimitedStringNotGreater(scope, refreshToken.Scope) {
-		return "", ErrRequestedScopeCannotBeGreater
+	return scope, nil
+}
+
+// setRefreshTokenGrantType records the grant type a refresh token was issued by,
+// an existing refresh token being reused keeps its original grant type,
+// nothing is recorded if no refresh token has been issued
+func (s *Service) setRefreshTokenGrantType(refreshToken *models.OauthRefreshToken, grantType string) error {
+	if refreshToken == nil || refreshToken.GrantType != "" {
+		return nil
 	}
+	err := s.db.Model(new(models.OauthRefreshToken)).Where("id = ?", refreshToken.ID).
+		UpdateColumn("grant_type", grantType).Error
+	if err != nil {
+		return err
+	}
+	refreshToken.GrantType = grantType
+	return nil
+}
 
-	return scope, nil
+// checkRefreshTokenGrantType rejects refresh tokens issued by a disabled grant type,
+// or by client credentials which never issues refresh tokens. Tokens issued before
+// grant types were recorded have no grant type and are accepted.
+func (s *Service) checkRefreshTokenGrantType(refreshToken *models.OauthRefreshToken) error {
+	grantType := refreshToken.GrantType
+	if grantType == "" {
+		return nil
+	}
+	if grantType == "client_credentials" || !s.grantTypeEnabled(grantType) {
+		log.WARNING.Printf("Rejected refresh token %s issued by %s grant", refreshToken.ID, grantType)
+		return ErrRefreshTokenGrantTypeDisabled
+	}
+	return nil
 }
@@ -0,0 +1,120 @@
+package oauth
+
+import (
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/log"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/jinzhu/gorm"
+)
+
+// rotateRefreshToken uses up a refresh token being exchanged for new tokens
+// in the transaction issuing them, so the token is only used up if they are
+// issued. It returns false if the token has been used concurrently.
+func rotateRefreshToken(tx *gorm.DB, refreshToken *models.OauthRefreshToken) (bool, error) {
+	result := tx.Model(new(models.OauthRefreshToken)).Where("id = ?", refreshToken.ID).
+		Where("used_at IS NULL").UpdateColumn("used_at", time.Now().UTC())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// inheritRefreshTokenFamily adds the refresh token issued by rotation to the
+// family and grant type lineage of the rotated refresh token, it keeps the
+// originally granted scope even if the access token was issued a narrower one
+func (s *Service) inheritRefreshTokenFamily(refreshToken, rotated *models.OauthRefreshToken) error {
+	familyID := refreshTokenFamily(rotated)
+	err := s.db.Model(new(models.OauthRefreshToken)).Where("id = ?", refreshToken.ID).
+		UpdateColumns(map[string]interface{}{
+			"family_id": familyID,
+			"scope":     rotated.Scope,
+		}).Error
+	if err != nil {
+		return err
+	}
+	refreshToken.FamilyID = familyID
+	refreshToken.Scope = rotated.Scope
+	return s.setRefreshTokenGrantType(refreshToken, rotated.GrantType)
+}
+
+// detectRefreshTokenReuse revokes the family of a refresh token replayed after
+// rotation, it has most likely leaked as the client only keeps the newest token
+func (s *Service) detectRefreshTokenReuse(token string, client *models.OauthClient) error {
+	refreshToken := new(models.OauthRefreshToken)
+	notFound := s.db.Where("client_id = ?", client.ID).Where("token = ?", models.HashToken(token)).
+		Where("used_at IS NOT NULL").First(refreshToken).RecordNotFound()
+	if notFound {
+		return ErrRefreshTokenNotFound
+	}
+	return s.revokeRefreshTokenFamily(refreshTokenFamily(refreshToken))
+}
+
+// revokeRefreshTokenFamily deletes every refresh token of the family and
+// the access tokens issued with them, and returns ErrRefreshTokenReused
+// unless the deletion fails
+func (s *Service) revokeRefreshTokenFamily(familyID string) error {
+	log.WARNING.Printf("Refresh token reused, revoking refresh token family %s", familyID)
+
+	var refreshTokens []*models.OauthRefreshToken
+	err := s.db.Where("id = ? OR family_id = ?", familyID, familyID).Find(&refreshTokens).Error
+	if err != nil {
+		return err
+	}
+	if len(refreshTokens) == 0 {
+		return ErrRefreshTokenReused
+	}
+
+	// Access tokens were obtained with a refresh token of the family,
+	// or issued along with its first one as part of the same lineage
+	refreshTokenIDs := make([]string, len(refreshTokens))
+	for i, refreshToken := range refreshTokens {
+		refreshTokenIDs[i] = refreshToken.ID
+	}
+	var accessTokens []*models.OauthAccessToken
+	err = s.db.Where(
+		"lineage_id = ? OR refresh_token_id IN (?)",
+		refreshTokenLineage(refreshTokens[0]),
+		refreshTokenIDs,
+	).Find(&accessTokens).Error
+	if err != nil {
+		return err
+	}
+
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	err = tx.Unscoped().Where("id IN (?)", refreshTokenIDs).Delete(new(models.OauthRefreshToken)).Error
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+	err = tx.Unscoped().Where(
+		"lineage_id = ? OR refresh_token_id IN (?)",
+		refreshTokenLineage(refreshTokens[0]),
+		refreshTokenIDs,
+	).Delete(new(models.OauthAccessToken)).Error
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	// Revoked access tokens must not be served from the cache
+	s.invalidateAccessTokens(accessTokens)
+
+	return ErrRefreshTokenReused
+}
+
+// refreshTokenFamily returns the ID of the first refresh token of the family
+func refreshTokenFamily(refreshToken *models.OauthRefreshToken) string {
+	if refreshToken.FamilyID != "" {
+		return refreshToken.FamilyID
+	}
+	return refreshToken.ID
+}
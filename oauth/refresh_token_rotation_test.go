@@ -0,0 +1,72 @@
+package oauth_test
+
+import (
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestRefreshTokenRotation() {
+	w := suite.passwordGrantWithAcr("test@user", "", "")
+	first := suite.decodeAccessTokenResponse(w)
+
+	// Every refresh issues a new refresh token
+	w = suite.refreshTokenGrant(first.RefreshToken)
+	second := suite.decodeAccessTokenResponse(w)
+	assert.NotEqual(suite.T(), first.RefreshToken, second.RefreshToken)
+
+	w = suite.refreshTokenGrant(second.RefreshToken)
+	third := suite.decodeAccessTokenResponse(w)
+	assert.NotEqual(suite.T(), second.RefreshToken, third.RefreshToken)
+
+	// Rotated tokens belong to the family of the first one
+	refreshToken := new(models.OauthRefreshToken)
+	assert.NoError(suite.T(), suite.db.Where("token = ?", models.HashToken(first.RefreshToken)).First(refreshToken).Error)
+	assert.NotNil(suite.T(), refreshToken.UsedAt)
+	var count int
+	assert.NoError(suite.T(), suite.db.Model(new(models.OauthRefreshToken)).
+		Where("family_id = ?", refreshToken.ID).Count(&count).Error)
+	assert.Equal(suite.T(), 2, count)
+
+	// And keep the grant type lineage
+	assert.Equal(suite.T(), "password", suite.introspectRefreshToken(third.RefreshToken).GrantType)
+}
+
+func (suite *OauthTestSuite) TestRefreshTokenReuseRevokesFamily() {
+	w := suite.passwordGrantWithAcr("test@user", "", "")
+	first := suite.decodeAccessTokenResponse(w)
+	w = suite.refreshTokenGrant(first.RefreshToken)
+	second := suite.decodeAccessTokenResponse(w)
+
+	// Replaying the rotated token is refused
+	w = suite.refreshTokenGrant(first.RefreshToken)
+	suite.assertErrorCode(w, "invalid_grant")
+
+	// And the refresh token issued in its place has been revoked too
+	w = suite.refreshTokenGrant(second.RefreshToken)
+	suite.assertErrorCode(w, "invalid_grant")
+	assert.True(suite.T(), suite.db.First(new(models.OauthRefreshToken)).RecordNotFound())
+
+	// So have the access tokens issued with the family
+	_, err := suite.service.Authenticate(first.AccessToken)
+	assert.Equal(suite.T(), oauth.ErrAccessTokenNotFound, err)
+	_, err = suite.service.Authenticate(second.AccessToken)
+	assert.Equal(suite.T(), oauth.ErrAccessTokenNotFound, err)
+}
+
+func (suite *OauthTestSuite) TestRefreshTokenKeptOnFailedRotation() {
+	w := suite.passwordGrantWithAcr("test@user", "", "")
+	first := suite.decodeAccessTokenResponse(w)
+
+	// The refresh token is not used up if no new tokens can be issued
+	err := suite.db.Model(suite.users[1]).UpdateColumn("disabled", true).Error
+	assert.NoError(suite.T(), err, "Updating test data failed")
+	w = suite.refreshTokenGrant(first.RefreshToken)
+	assert.NotEqual(suite.T(), 200, w.Code)
+	err = suite.db.Model(suite.users[1]).UpdateColumn("disabled", false).Error
+	assert.NoError(suite.T(), err, "Updating test data failed")
+
+	// So it can still be exchanged
+	w = suite.refreshTokenGrant(first.RefreshToken)
+	assert.Equal(suite.T(), 200, w.Code)
+}
@@ -9,7 +9,7 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func (suite *OauthTestSuite) TestGetOrCreateRefreshTokenCreatesNew() {
+func (suite *OauthTestSuite) TestCreateRefreshTokenCreatesNew() {
 	var (
 		refreshToken *models.OauthRefreshToken
 		err          error
@@ -18,7 +18,7 @@ func (suite *OauthTestSuite) TestGetOrCreateRefreshTokenCreatesNew() {
 
 	// Since there is no user specific token,
 	// a new one should be created and returned
-	refreshToken, err = suite.service.GetOrCreateRefreshToken(
+	refreshToken, err = suite.service.CreateRefreshToken(
 		suite.clients[0], // client
 		suite.users[0],   // user
 		3600,             // expires in
@@ -33,9 +33,12 @@ func (suite *OauthTestSuite) TestGetOrCreateRefreshTokenCreatesNew() {
 		// There should be just one token now
 		assert.Equal(suite.T(), 1, len(tokens))
 
-		// Correct refresh token object should be returned
+		// Correct refresh token object should be returned,
+		// only the hash of the token is stored
 		assert.NotNil(suite.T(), refreshToken)
-		assert.Equal(suite.T(), tokens[0].Token, refreshToken.Token)
+		assert.NotEmpty(suite.T(), refreshToken.Token)
+		assert.Equal(suite.T(), models.HashToken(refreshToken.Token), tokens[0].TokenHash)
+		assert.Empty(suite.T(), tokens[0].Token)
 
 		// Client ID should be set
 		assert.True(suite.T(), tokens[0].ClientID.Valid)
@@ -46,8 +49,8 @@ func (suite *OauthTestSuite) TestGetOrCreateRefreshTokenCreatesNew() {
 		assert.Equal(suite.T(), suite.users[0].ID, tokens[0].User.ID)
 	}
 
-	// Valid user specific token exists, new one should NOT be created
-	refreshToken, err = suite.service.GetOrCreateRefreshToken(
+	// Existing tokens cannot be handed out again, another one should be created
+	refreshToken, err = suite.service.CreateRefreshToken(
 		suite.clients[0], // client
 		suite.users[0],   // user
 		3600,             // expires in
@@ -59,53 +62,18 @@ func (suite *OauthTestSuite) TestGetOrCreateRefreshTokenCreatesNew() {
 		// Fetch all refresh tokens
 		models.OauthRefreshTokenPreload(suite.db).Order("created_at").Find(&tokens)
 
-		// There should be just one token now
-		assert.Equal(suite.T(), 1, len(tokens))
+		// There should be 2 tokens now
+		assert.Equal(suite.T(), 2, len(tokens))
 
 		// Correct refresh token object should be returned
 		assert.NotNil(suite.T(), refreshToken)
-		assert.Equal(suite.T(), tokens[0].Token, refreshToken.Token)
-
-		// Client ID should be set
-		assert.True(suite.T(), tokens[0].ClientID.Valid)
-		assert.Equal(suite.T(), suite.clients[0].ID, tokens[0].Client.ID)
-
-		// User ID should be set
-		assert.True(suite.T(), tokens[0].UserID.Valid)
-		assert.Equal(suite.T(), suite.users[0].ID, tokens[0].User.ID)
+		assert.Equal(suite.T(), models.HashToken(refreshToken.Token), tokens[1].TokenHash)
+		assert.NotEqual(suite.T(), tokens[0].TokenHash, tokens[1].TokenHash)
 	}
 
 	// Since there is no client only token,
 	// a new one should be created and returned
-	refreshToken, err = suite.service.GetOrCreateRefreshToken(
-		suite.clients[0], // client
-		nil,              // user
-		3600,             // expires in
-		"read_write",     // scope
-	)
-
-	// Error should be nil
-	if assert.Nil(suite.T(), err) {
-		// Fetch all refresh tokens
-		models.OauthRefreshTokenPreload(suite.db).Order("created_at").Find(&tokens)
-
-		// There should be 2 tokens
-		assert.Equal(suite.T(), 2, len(tokens))
-
-		// Correct refresh token object should be returned
-		assert.NotNil(suite.T(), refreshToken)
-		assert.Equal(suite.T(), tokens[1].Token, refreshToken.Token)
-
-		// Client ID should be set
-		assert.True(suite.T(), tokens[1].ClientID.Valid)
-		assert.Equal(suite.T(), suite.clients[0].ID, tokens[1].Client.ID)
-
-		// User ID should be nil
-		assert.False(suite.T(), tokens[1].UserID.Valid)
-	}
-
-	// Valid client only token exists, new one should NOT be created
-	refreshToken, err = suite.service.GetOrCreateRefreshToken(
+	refreshToken, err = suite.service.CreateRefreshToken(
 		suite.clients[0], // client
 		nil,              // user
 		3600,             // expires in
@@ -117,124 +85,23 @@ func (suite *OauthTestSuite) TestGetOrCreateRefreshTokenCreatesNew() {
 		// Fetch all refresh tokens
 		models.OauthRefreshTokenPreload(suite.db).Order("created_at").Find(&tokens)
 
-		// There should be 2 tokens
-		assert.Equal(suite.T(), 2, len(tokens))
+		// There should be 3 tokens
+		assert.Equal(suite.T(), 3, len(tokens))
 
 		// Correct refresh token object should be returned
 		assert.NotNil(suite.T(), refreshToken)
-		assert.Equal(suite.T(), tokens[1].Token, refreshToken.Token)
+		assert.Equal(suite.T(), models.HashToken(refreshToken.Token), tokens[2].TokenHash)
 
 		// Client ID should be set
-		assert.True(suite.T(), tokens[1].ClientID.Valid)
-		assert.Equal(suite.T(), suite.clients[0].ID, tokens[1].Client.ID)
+		assert.True(suite.T(), tokens[2].ClientID.Valid)
+		assert.Equal(suite.T(), suite.clients[0].ID, tokens[2].Client.ID)
 
 		// User ID should be nil
-		assert.False(suite.T(), tokens[1].UserID.Valid)
-	}
-}
-
-func (suite *OauthTestSuite) TestGetOrCreateRefreshTokenReturnsExisting() {
-	var (
-		refreshToken *models.OauthRefreshToken
-		err          error
-		tokens       []*models.OauthRefreshToken
-	)
-
-	// Insert an access token without a user
-	err = suite.db.Create(&models.OauthRefreshToken{
-		MyGormModel: models.MyGormModel{
-			ID:        uuid.New(),
-			CreatedAt: time.Now().UTC(),
-		},
-		Token:     "test_token",
-		ExpiresAt: time.Now().UTC().Add(+10 * time.Second),
-		Client:    suite.clients[0],
-	}).Error
-	assert.NoError(suite.T(), err, "Inserting test data failed")
-
-	// Since the current client only token is valid, this should just return it
-	refreshToken, err = suite.service.GetOrCreateRefreshToken(
-		suite.clients[0], // client
-		nil,              // user
-		3600,             // expires in
-		"read_write",     // scope
-	)
-
-	// Error should be Nil
-	assert.Nil(suite.T(), err)
-
-	// Correct refresh token should be returned
-	if assert.NotNil(suite.T(), refreshToken) {
-		// Fetch all refresh tokens
-		models.OauthRefreshTokenPreload(suite.db).Order("created_at").Find(&tokens)
-
-		// There should be just one token right now
-		assert.Equal(suite.T(), 1, len(tokens))
-
-		// Correct refresh token object should be returned
-		assert.NotNil(suite.T(), refreshToken)
-		assert.Equal(suite.T(), tokens[0].Token, refreshToken.Token)
-		assert.Equal(suite.T(), "test_token", refreshToken.Token)
-		assert.Equal(suite.T(), "test_token", tokens[0].Token)
-
-		// Client ID should be set
-		assert.True(suite.T(), tokens[0].ClientID.Valid)
-		assert.Equal(suite.T(), string(suite.clients[0].ID), tokens[0].ClientID.String)
-
-		// User ID should be nil
-		assert.False(suite.T(), tokens[0].UserID.Valid)
-	}
-
-	// Insert an access token with a user
-	err = suite.db.Create(&models.OauthRefreshToken{
-		MyGormModel: models.MyGormModel{
-			ID:        uuid.New(),
-			CreatedAt: time.Now().UTC(),
-		},
-		Token:     "test_token2",
-		ExpiresAt: time.Now().UTC().Add(+10 * time.Second),
-		Client:    suite.clients[0],
-		User:      suite.users[0],
-	}).Error
-	assert.NoError(suite.T(), err, "Inserting test data failed")
-
-	// Since the current user specific only token is valid,
-	// this should just return it
-	refreshToken, err = suite.service.GetOrCreateRefreshToken(
-		suite.clients[0], // client
-		suite.users[0],   // user
-		3600,             // expires in
-		"read_write",     // scope
-	)
-
-	// Error should be Nil
-	assert.Nil(suite.T(), err)
-
-	// Correct refresh token should be returned
-	if assert.NotNil(suite.T(), refreshToken) {
-		// Fetch all refresh tokens
-		models.OauthRefreshTokenPreload(suite.db).Order("created_at").Find(&tokens)
-
-		// There should be 2 tokens now
-		assert.Equal(suite.T(), 2, len(tokens))
-
-		// Correct refresh token object should be returned
-		assert.NotNil(suite.T(), refreshToken)
-		assert.Equal(suite.T(), tokens[1].Token, refreshToken.Token)
-		assert.Equal(suite.T(), "test_token2", refreshToken.Token)
-		assert.Equal(suite.T(), "test_token2", tokens[1].Token)
-
-		// Client ID should be set
-		assert.True(suite.T(), tokens[1].ClientID.Valid)
-		assert.Equal(suite.T(), string(suite.clients[0].ID), tokens[1].ClientID.String)
-
-		// User ID should be set
-		assert.True(suite.T(), tokens[1].UserID.Valid)
-		assert.Equal(suite.T(), string(suite.users[0].ID), tokens[1].UserID.String)
+		assert.False(suite.T(), tokens[2].UserID.Valid)
 	}
 }
 
-func (suite *OauthTestSuite) TestGetOrCreateRefreshTokenDeletesExpired() {
+func (suite *OauthTestSuite) TestCreateRefreshTokenDeletesExpired() {
 	var (
 		refreshToken *models.OauthRefreshToken
 		err          error
@@ -255,7 +122,7 @@ func (suite *OauthTestSuite) TestGetOrCreateRefreshTokenDeletesExpired() {
 
 	// Since the current client only token is expired,
 	// this should delete it and create and return a new one
-	refreshToken, err = suite.service.GetOrCreateRefreshToken(
+	refreshToken, err = suite.service.CreateRefreshToken(
 		suite.clients[0], // client
 		nil,              // user
 		3600,             // expires in
@@ -272,9 +139,8 @@ func (suite *OauthTestSuite) TestGetOrCreateRefreshTokenDeletesExpired() {
 
 		// Correct refresh token object should be returned
 		assert.NotNil(suite.T(), refreshToken)
-		assert.Equal(suite.T(), tokens[0].Token, refreshToken.Token)
-		assert.NotEqual(suite.T(), "test_token", refreshToken.Token)
-		assert.NotEqual(suite.T(), "test_token", tokens[0].Token)
+		assert.Equal(suite.T(), models.HashToken(refreshToken.Token), tokens[0].TokenHash)
+		assert.NotEqual(suite.T(), models.HashToken("test_token"), tokens[0].TokenHash)
 
 		// Client ID should be set
 		assert.True(suite.T(), tokens[0].ClientID.Valid)
@@ -299,7 +165,7 @@ func (suite *OauthTestSuite) TestGetOrCreateRefreshTokenDeletesExpired() {
 
 	// Since the current user specific token is expired,
 	// this should delete it and create and return a new one
-	refreshToken, err = suite.service.GetOrCreateRefreshToken(
+	refreshToken, err = suite.service.CreateRefreshToken(
 		suite.clients[0], // client
 		suite.users[0],   // user
 		3600,             // expires in
@@ -316,9 +182,8 @@ func (suite *OauthTestSuite) TestGetOrCreateRefreshTokenDeletesExpired() {
 
 		// Correct refresh token object should be returned
 		assert.NotNil(suite.T(), refreshToken)
-		assert.Equal(suite.T(), tokens[1].Token, refreshToken.Token)
-		assert.NotEqual(suite.T(), "test_token", refreshToken.Token)
-		assert.NotEqual(suite.T(), "test_token", tokens[1].Token)
+		assert.Equal(suite.T(), models.HashToken(refreshToken.Token), tokens[1].TokenHash)
+		assert.NotEqual(suite.T(), models.HashToken("test_token"), tokens[1].TokenHash)
 
 		// Client ID should be set
 		assert.True(suite.T(), tokens[1].ClientID.Valid)
@@ -419,5 +284,5 @@ func (suite *OauthTestSuite) TestGetValidRefreshToken() {
 
 	// Correct refresh token object should be returned
 	assert.NotNil(suite.T(), refreshToken)
-	assert.Equal(suite.T(), "test_token", refreshToken.Token)
+	assert.Equal(suite.T(), models.HashToken("test_token"), refreshToken.TokenHash)
 }
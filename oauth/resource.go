@@ -0,0 +1,57 @@
+package oauth
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util"
+)
+
+var (
+	// ErrInvalidResource ...
+	ErrInvalidResource = errors.New("Invalid resource")
+)
+
+// getRequestedResources returns the space delimited resource servers requested
+// with resource parameters, see https://tools.ietf.org/html/rfc8707#section-2.
+// Each must be registered and, if the scope is restricted to audiences, one of them.
+func (s *Service) getRequestedResources(r *http.Request, scope string) (string, error) {
+	resources := r.Form["resource"]
+	if len(resources) == 0 {
+		return "", nil
+	}
+
+	scopeAudiences := strings.Fields(s.GetScopeAudience(scope))
+	for _, resource := range resources {
+		if !util.StringInSlice(resource, s.cnf.Oauth.ResourceServers) {
+			return "", ErrInvalidResource
+		}
+		if len(scopeAudiences) > 0 && !util.StringInSlice(resource, scopeAudiences) {
+			return "", ErrInvalidResource
+		}
+	}
+
+	// Sort the resources alphabetically like scope audiences
+	resources = append([]string(nil), resources...)
+	sort.Strings(resources)
+
+	return strings.Join(resources, " "), nil
+}
+
+// setResourceAudience issues the access token for the requested resource
+// servers only, so it cannot be replayed at other resource servers
+func (s *Service) setResourceAudience(accessToken *models.OauthAccessToken, resources string) error {
+	if resources == "" {
+		return nil
+	}
+	err := s.db.Model(new(models.OauthAccessToken)).Where("id = ?", accessToken.ID).
+		UpdateColumn("audience", resources).Error
+	if err != nil {
+		return err
+	}
+	accessToken.Audience = resources
+	return nil
+}
@@ -0,0 +1,91 @@
+package oauth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestResourceIndicators() {
+	suite.cnf.Oauth.ResourceServers = []string{
+		"https://a.example.com",
+		"https://b.example.com",
+		"https://payments.example.com",
+	}
+	defer func() { suite.cnf.Oauth.ResourceServers = nil }()
+
+	defer suite.allowClientScope(suite.clients[0], "test_payments:write")()
+
+	err := oauth.SeedDefaultScopes(suite.db, []oauth.ScopeDef{
+		{Scope: "test_payments:write", Audience: "https://payments.example.com"},
+	})
+	assert.NoError(suite.T(), err)
+	defer suite.db.Unscoped().Where("scope LIKE ?", "test_%").Delete(new(models.OauthScope))
+
+	testCases := []struct {
+		scope     string
+		resources []string
+		audience  string
+	}{
+		{"read", nil, ""},
+		{"read", []string{"https://a.example.com"}, "https://a.example.com"},
+		{"read", []string{"https://b.example.com", "https://a.example.com"}, "https://a.example.com https://b.example.com"},
+		{"read test_payments:write", []string{"https://payments.example.com"}, "https://payments.example.com"},
+		// Not registered
+		{"read", []string{"https://c.example.com"}, ""},
+		{"read", []string{"https://a.example.com#fragment"}, ""},
+		// The scope is restricted to another resource server
+		{"read test_payments:write", []string{"https://a.example.com"}, ""},
+	}
+
+	for _, testCase := range testCases {
+		r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+		assert.NoError(suite.T(), err, "Request setup should not get an error")
+		r.SetBasicAuth("test_client_1", "test_secret")
+		r.PostForm = url.Values{
+			"grant_type": {"client_credentials"},
+			"scope":      {testCase.scope},
+			"resource":   testCase.resources,
+		}
+
+		w := httptest.NewRecorder()
+		suite.router.ServeHTTP(w, r)
+
+		if testCase.resources != nil && testCase.audience == "" {
+			suite.assertErrorCode(w, "invalid_target")
+			continue
+		}
+
+		resp := suite.decodeAccessTokenResponse(w)
+		accessToken := new(models.OauthAccessToken)
+		assert.False(suite.T(), suite.db.Where("token = ?", models.HashToken(resp.AccessToken)).First(accessToken).RecordNotFound())
+		assert.Equal(suite.T(), testCase.audience, accessToken.Audience)
+	}
+}
+
+func (suite *OauthTestSuite) TestResourceIndicatorsOnRefresh() {
+	suite.cnf.Oauth.ResourceServers = []string{"https://a.example.com"}
+	defer func() { suite.cnf.Oauth.ResourceServers = nil }()
+
+	resp := suite.decodeAccessTokenResponse(suite.passwordGrantWithScope("read_write"))
+
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
+	r.PostForm = url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {resp.RefreshToken},
+		"resource":      {"https://a.example.com"},
+	}
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+
+	resp = suite.decodeAccessTokenResponse(w)
+	accessToken := new(models.OauthAccessToken)
+	assert.False(suite.T(), suite.db.Where("token = ?", models.HashToken(resp.AccessToken)).First(accessToken).RecordNotFound())
+	assert.Equal(suite.T(), "https://a.example.com", accessToken.Audience)
+}
@@ -1,27 +1,129 @@
 package oauth
 
 import (
+	"encoding/json"
+	"strings"
+
 	"github.com/RichardKnop/go-oauth2-server/models"
 )
 
 // AccessTokenResponse ...
 type AccessTokenResponse struct {
 	UserID       string `json:"user_id,omitempty"`
-	AccessToken  string `json:"access_token"`
+	AccessToken  string `json:"access_token,omitempty"`
 	ExpiresIn    int    `json:"expires_in"`
 	TokenType    string `json:"token_type"`
 	Scope        string `json:"scope"`
 	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	DeviceSecret string `json:"device_secret,omitempty"`
+	// IssuedTokenType is only set by token exchange
+	IssuedTokenType string `json:"issued_token_type,omitempty"`
+	// AuthorizationDetails are the authorization details granted, if any
+	AuthorizationDetails json.RawMessage `json:"authorization_details,omitempty"`
+	// Extra holds custom fields added by response transformers
+	Extra map[string]interface{} `json:"-"`
+}
+
+// HandoffResponse ...
+type HandoffResponse struct {
+	Code      string `json:"code"`
+	ExpiresIn int    `json:"expires_in"`
+}
+
+// DeviceAuthorizationResponse ...
+type DeviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// BackchannelAuthenticationResponse ...
+type BackchannelAuthenticationResponse struct {
+	AuthReqID string `json:"auth_req_id"`
+	ExpiresIn int    `json:"expires_in"`
+	Interval  int    `json:"interval"`
+}
+
+// PushedAuthorizationResponse ...
+type PushedAuthorizationResponse struct {
+	RequestURI string `json:"request_uri"`
+	ExpiresIn  int    `json:"expires_in"`
+}
+
+// RecoveryCodesResponse ...
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// ClientRegistrationResponse is the information of a registered client,
+// see https://tools.ietf.org/html/rfc7591#section-3.2.1
+type ClientRegistrationResponse struct {
+	ClientID                string `json:"client_id"`
+	ClientSecret            string `json:"client_secret,omitempty"`
+	ClientIDIssuedAt        int64  `json:"client_id_issued_at"`
+	ClientSecretExpiresAt   int64  `json:"client_secret_expires_at"`
+	RegistrationAccessToken string `json:"registration_access_token,omitempty"`
+	RegistrationClientURI   string `json:"registration_client_uri,omitempty"`
+	ClientMetadata
 }
 
 // IntrospectResponse ...
 type IntrospectResponse struct {
-	Active    bool   `json:"active"`
-	Scope     string `json:"scope,omitempty"`
-	ClientID  string `json:"client_id,omitempty"`
-	Username  string `json:"username,omitempty"`
-	TokenType string `json:"token_type,omitempty"`
-	ExpiresAt int    `json:"exp,omitempty"`
+	Active     bool   `json:"active"`
+	Scope      string `json:"scope,omitempty"`
+	ClientID   string `json:"client_id,omitempty"`
+	ClientName string `json:"client_name,omitempty"`
+	// FirstParty is set for tokens issued to our own apps
+	FirstParty bool   `json:"first_party,omitempty"`
+	Username   string `json:"username,omitempty"`
+	TokenType  string `json:"token_type,omitempty"`
+	ExpiresAt  int    `json:"exp,omitempty"`
+	IssuedAt   int64  `json:"iat,omitempty"`
+	Subject    string `json:"sub,omitempty"`
+	Audience   string `json:"aud,omitempty"`
+	Issuer     string `json:"iss,omitempty"`
+	Acr        string `json:"acr,omitempty"`
+	Sid        string `json:"sid,omitempty"`
+	AuthTime   int64  `json:"auth_time,omitempty"`
+	GrantType  string `json:"grant_type,omitempty"`
+	Act        *Actor `json:"act,omitempty"`
+	// Cnf names the key the token is bound to, if any
+	Cnf *Confirmation `json:"cnf,omitempty"`
+	// AuthorizationDetails are the authorization details granted, if any
+	AuthorizationDetails json.RawMessage `json:"authorization_details,omitempty"`
+	// Extra holds custom claims added by token claims providers
+	Extra map[string]interface{} `json:"-"`
+}
+
+// UserInfoResponse holds the OpenID Connect standard claims
+// about a user the access token allows to release
+type UserInfoResponse struct {
+	Subject           string `json:"sub"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	UpdatedAt         int64  `json:"updated_at,omitempty"`
+	Email             string `json:"email,omitempty"`
+	// Extra holds the claims the attributes of the user are mapped to
+	Extra map[string]interface{} `json:"-"`
+}
+
+// Confirmation is the cnf claim of a token bound to a key the client
+// holds, see RFC 7800
+type Confirmation struct {
+	// CertificateThumbprint is the thumbprint of the TLS client certificate
+	CertificateThumbprint string `json:"x5t#S256,omitempty"`
+	// JWKThumbprint is the thumbprint of the DPoP key
+	JWKThumbprint string `json:"jkt,omitempty"`
+}
+
+// Actor is the act claim of a delegated token, see RFC 8693 section 4.1,
+// prior actors in a delegation chain are nested within the current actor
+type Actor struct {
+	Subject string `json:"sub"`
+	Act     *Actor `json:"act,omitempty"`
 }
 
 // NewAccessTokenResponse ...
@@ -32,6 +134,7 @@ func NewAccessTokenResponse(accessToken *models.OauthAccessToken, refreshToken *
 		TokenType:   theTokenType,
 		Scope:       accessToken.Scope,
 	}
+	response.AuthorizationDetails = rawAuthorizationDetails(accessToken.AuthorizationDetails)
 	if accessToken.UserID.Valid {
 		response.UserID = accessToken.UserID.String
 	}
@@ -40,3 +143,22 @@ func NewAccessTokenResponse(accessToken *models.OauthAccessToken, refreshToken *
 	}
 	return response, nil
 }
+
+// NewClientRegistrationResponse returns the registered metadata of a client,
+// the client secret and registration access token are only known when issued
+func NewClientRegistrationResponse(client *models.OauthClient) *ClientRegistrationResponse {
+	response := &ClientRegistrationResponse{
+		ClientID:         client.Key,
+		ClientIDIssuedAt: client.CreatedAt.Unix(),
+		ClientMetadata: ClientMetadata{
+			GrantTypes:              strings.Fields(client.AllowedGrantTypes),
+			Scope:                   client.Scope,
+			TokenEndpointAuthMethod: client.TokenEndpointAuthMethod,
+			ClientName:              client.Name.String,
+		},
+	}
+	if client.RedirectURI.Valid {
+		response.RedirectURIs = []string{client.RedirectURI.String}
+	}
+	return response
+}
@@ -0,0 +1,97 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/RichardKnop/go-oauth2-server/log"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util"
+)
+
+var (
+	// reservedResponseFields cannot be set by response transformers
+	reservedResponseFields = []string{
+		"user_id",
+		"access_token",
+		"expires_in",
+		"token_type",
+		"scope",
+		"refresh_token",
+		"id_token",
+		"device_secret",
+		"issued_token_type",
+		"authorization_details",
+		"error",
+	}
+)
+
+// ResponseTransformer customises token responses before they are written.
+// It can add or remove custom fields in resp.Extra, other changes to the
+// response are discarded. User is nil for client credentials tokens.
+type ResponseTransformer func(ctx context.Context, resp *AccessTokenResponse, user *models.OauthUser, client *models.OauthClient) error
+
+// AddResponseTransformer registers a transformer applied to all token responses
+func (s *Service) AddResponseTransformer(transformer ResponseTransformer) {
+	s.transformers = append(s.transformers, transformer)
+}
+
+// transformResponse runs the registered transformers on a copy of the
+// response so they cannot change any of the OAuth fields
+func (s *Service) transformResponse(r *http.Request, resp *AccessTokenResponse, client *models.OauthClient) (*AccessTokenResponse, error) {
+	if len(s.transformers) == 0 {
+		return resp, nil
+	}
+
+	var user *models.OauthUser
+	if resp.UserID != "" {
+		user = new(models.OauthUser)
+		if s.db.Where("id = ?", resp.UserID).First(user).RecordNotFound() {
+			return nil, ErrUserNotFound
+		}
+	}
+
+	extra := resp.Extra
+	for _, transformer := range s.transformers {
+		transformed := *resp
+		transformed.Extra = extra
+		if transformed.Extra == nil {
+			transformed.Extra = make(map[string]interface{})
+		}
+		if err := transformer(r.Context(), &transformed, user, client); err != nil {
+			return nil, err
+		}
+		extra = transformed.Extra
+	}
+
+	for name := range extra {
+		if util.StringInSlice(name, reservedResponseFields) {
+			log.WARNING.Printf("Response transformer cannot set reserved field %s", name)
+			delete(extra, name)
+		}
+	}
+	resp.Extra = extra
+
+	return resp, nil
+}
+
+// MarshalJSON includes custom fields alongside the OAuth fields
+func (r AccessTokenResponse) MarshalJSON() ([]byte, error) {
+	type accessTokenResponse AccessTokenResponse
+	data, err := json.Marshal(accessTokenResponse(r))
+	if err != nil || len(r.Extra) == 0 {
+		return data, err
+	}
+
+	fields := make(map[string]interface{}, len(r.Extra))
+	for name, value := range r.Extra {
+		if !util.StringInSlice(name, reservedResponseFields) {
+			fields[name] = value
+		}
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
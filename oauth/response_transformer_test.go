@@ -0,0 +1,65 @@
+package oauth_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestResponseTransformer() {
+	// Use a separate service so the transformers do not affect other tests
+	service := oauth.NewService(suite.cnf, suite.db)
+	router := mux.NewRouter()
+	service.RegisterRoutes(router, "/v1/oauth")
+
+	service.AddResponseTransformer(func(ctx context.Context, resp *oauth.AccessTokenResponse, user *models.OauthUser, client *models.OauthClient) error {
+		resp.Extra["tenant"] = client.Key + ":" + user.Username
+		resp.Extra["removed"] = true
+		return nil
+	})
+	service.AddResponseTransformer(func(ctx context.Context, resp *oauth.AccessTokenResponse, user *models.OauthUser, client *models.OauthClient) error {
+		// Custom fields can be removed
+		delete(resp.Extra, "removed")
+		// But the OAuth fields cannot be clobbered
+		resp.Extra["access_token"] = "clobbered"
+		resp.Extra["refresh_token"] = "clobbered"
+		resp.Extra["authorization_details"] = []interface{}{map[string]interface{}{"type": "clobbered"}}
+		resp.AccessToken = "clobbered"
+		return nil
+	})
+
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
+	r.PostForm = url.Values{
+		"grant_type": {"password"},
+		"username":   {"test@user"},
+		"password":   {"test_password"},
+		"scope":      {"read_write"},
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	assert.Equal(suite.T(), 200, w.Code)
+
+	resp := map[string]interface{}{}
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(suite.T(), "test_client_1:test@user", resp["tenant"])
+	assert.NotContains(suite.T(), resp, "removed")
+	assert.Equal(suite.T(), "read_write", resp["scope"])
+	assert.NotContains(suite.T(), resp, "authorization_details")
+
+	// The access and refresh tokens are the real ones
+	accessToken, err := suite.service.Authenticate(resp["access_token"].(string))
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), suite.users[1].ID, accessToken.UserID.String)
+	}
+	_, err = suite.service.GetValidRefreshToken(resp["refresh_token"].(string), suite.clients[0])
+	assert.NoError(suite.T(), err)
+}
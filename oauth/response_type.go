@@ -0,0 +1,109 @@
+package oauth
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util"
+)
+
+var (
+	// ErrInvalidResponseType ...
+	ErrInvalidResponseType = errors.New("Invalid response type")
+	// ErrResponseTypeNotAllowed ...
+	ErrResponseTypeNotAllowed = errors.New("Client is not allowed to use this response type")
+
+	// supportedResponseTypes are the response types of the authorization endpoint,
+	// including the hybrid ones of https://openid.net/specs/openid-connect-core-1_0.html#HybridFlowAuth
+	supportedResponseTypes = []string{
+		"code",
+		"token",
+		"code id_token",
+		"code token",
+		"code id_token token",
+	}
+)
+
+// ParseResponseType returns the response type with its values sorted, they can
+// be sent in any order, see https://tools.ietf.org/html/rfc6749#section-3.1.1
+func ParseResponseType(responseType string) (string, error) {
+	values := strings.Fields(responseType)
+	sort.Strings(values)
+	responseType = strings.Join(values, " ")
+	if !util.StringInSlice(responseType, supportedResponseTypes) {
+		return "", ErrInvalidResponseType
+	}
+	return responseType, nil
+}
+
+// ResponseTypeIncludes returns true if the authorization endpoint
+// responds with the value, e.g. an access token for token
+func ResponseTypeIncludes(responseType, value string) bool {
+	return util.StringInSlice(value, strings.Fields(responseType))
+}
+
+// ResponseTypeGrantTypes returns the grant types the client must be able to
+// use, the implicit grant if an access token is returned by the authorization endpoint
+func ResponseTypeGrantTypes(responseType string) []string {
+	var grantTypes []string
+	if ResponseTypeIncludes(responseType, "code") {
+		grantTypes = append(grantTypes, "authorization_code")
+	}
+	if ResponseTypeIncludes(responseType, "token") {
+		grantTypes = append(grantTypes, "implicit")
+	}
+	return grantTypes
+}
+
+// responseTypeEnabled returns false if a grant type
+// the response type needs is not enabled
+func (s *Service) responseTypeEnabled(responseType string) bool {
+	for _, grantType := range ResponseTypeGrantTypes(responseType) {
+		if !s.grantTypeEnabled(grantType) {
+			return false
+		}
+	}
+	return true
+}
+
+// ClientAllowsResponseType returns false if the client has been
+// registered for other response types only
+func ClientAllowsResponseType(client *models.OauthClient, responseType string) bool {
+	allowed := splitResponseTypes(client.AllowedResponseTypes)
+	return len(allowed) == 0 || util.StringInSlice(responseType, allowed)
+}
+
+// SetAllowedResponseTypes restricts the response types the client can use,
+// an empty list allows all of them again
+func (s *Service) SetAllowedResponseTypes(client *models.OauthClient, responseTypes []string) error {
+	parsed := make([]string, len(responseTypes))
+	for i, responseType := range responseTypes {
+		var err error
+		if parsed[i], err = ParseResponseType(responseType); err != nil {
+			return err
+		}
+	}
+
+	allowedResponseTypes := strings.Join(parsed, ",")
+	err := s.db.Model(new(models.OauthClient)).Where("id = ?", client.ID).
+		UpdateColumn("allowed_response_types", allowedResponseTypes).Error
+	if err != nil {
+		return err
+	}
+	client.AllowedResponseTypes = allowedResponseTypes
+	return nil
+}
+
+// splitResponseTypes splits a comma delimited list of response types,
+// they cannot be space delimited as response types contain spaces
+func splitResponseTypes(responseTypes string) []string {
+	var split []string
+	for _, responseType := range strings.Split(responseTypes, ",") {
+		if responseType = strings.TrimSpace(responseType); responseType != "" {
+			split = append(split, responseType)
+		}
+	}
+	return split
+}
@@ -0,0 +1,90 @@
+package oauth_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/util/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestParseResponseType() {
+	for _, testCase := range []struct {
+		responseType string
+		expected     string
+		err          error
+	}{
+		{"code", "code", nil},
+		{"token", "token", nil},
+		{"code id_token", "code id_token", nil},
+		{"id_token  code", "code id_token", nil},
+		{"token code", "code token", nil},
+		{"token id_token code", "code id_token token", nil},
+		{"", "", oauth.ErrInvalidResponseType},
+		{"id_token", "", oauth.ErrInvalidResponseType},
+		{"code code", "", oauth.ErrInvalidResponseType},
+		{"bogus", "", oauth.ErrInvalidResponseType},
+	} {
+		responseType, err := oauth.ParseResponseType(testCase.responseType)
+		assert.Equal(suite.T(), testCase.err, err, testCase.responseType)
+		assert.Equal(suite.T(), testCase.expected, responseType, testCase.responseType)
+	}
+
+	assert.Equal(suite.T(), []string{"authorization_code"}, oauth.ResponseTypeGrantTypes("code id_token"))
+	assert.Equal(suite.T(), []string{"authorization_code", "implicit"}, oauth.ResponseTypeGrantTypes("code token"))
+}
+
+func (suite *OauthTestSuite) TestSetAllowedResponseTypes() {
+	err := suite.service.SetAllowedResponseTypes(suite.clients[1], []string{"bogus"})
+	assert.Equal(suite.T(), oauth.ErrInvalidResponseType, err)
+
+	err = suite.service.SetAllowedResponseTypes(suite.clients[1], []string{"code", "id_token code"})
+	assert.NoError(suite.T(), err)
+	defer suite.service.SetAllowedResponseTypes(suite.clients[1], nil)
+
+	client, err := suite.service.FindClientByClientID("test_client_2")
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), "code,code id_token", client.AllowedResponseTypes)
+		assert.True(suite.T(), oauth.ClientAllowsResponseType(client, "code"))
+		assert.True(suite.T(), oauth.ClientAllowsResponseType(client, "code id_token"))
+		assert.False(suite.T(), oauth.ClientAllowsResponseType(client, "code token"))
+	}
+	assert.True(suite.T(), oauth.ClientAllowsResponseType(new(models.OauthClient), "code token"))
+}
+
+func (suite *OauthTestSuite) TestGrantFrontChannelIDToken() {
+	idToken, err := suite.service.GrantFrontChannelIDToken(
+		suite.clients[0],
+		suite.users[0],
+		"test_nonce",
+		"test_code",
+		"test_token",
+	)
+	assert.NoError(suite.T(), err)
+	suite.assertValidIDToken(idToken, suite.clients[0], suite.users[0])
+
+	signingKey, err := jwt.NewHS256("", []byte(suite.cnf.JWT.Secret))
+	assert.NoError(suite.T(), err)
+	claims, err := jwt.Parse(idToken, signingKey)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), "test_nonce", claims["nonce"])
+		assert.Equal(suite.T(), leftHalfHash("test_code"), claims["c_hash"])
+		assert.Equal(suite.T(), leftHalfHash("test_token"), claims["at_hash"])
+	}
+
+	// Hashes are only included for what is returned alongside the ID token
+	idToken, err = suite.service.GrantFrontChannelIDToken(suite.clients[0], suite.users[0], "test_nonce", "test_code", "")
+	assert.NoError(suite.T(), err)
+	claims, err = jwt.Parse(idToken, signingKey)
+	if assert.NoError(suite.T(), err) {
+		_, ok := claims["at_hash"]
+		assert.False(suite.T(), ok)
+	}
+}
+
+func leftHalfHash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+}
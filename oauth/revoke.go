@@ -0,0 +1,112 @@
+package oauth
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+)
+
+var (
+	// ErrTokenNotIssuedToClient ...
+	ErrTokenNotIssuedToClient = errors.New("Token was not issued to the client")
+)
+
+// revokeToken revokes the access or refresh token presented by the client,
+// along with the other tokens granted to the client for the same user,
+// see https://tools.ietf.org/html/rfc7009#section-2.1
+func (s *Service) revokeToken(r *http.Request, client *models.OauthClient) error {
+	// Parse the form so r.Form becomes available
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	// Get token from the query
+	token := r.Form.Get("token")
+	if token == "" {
+		return ErrTokenMissing
+	}
+
+	// The hint only tells which type of token to look up first
+	var lookups []func(token string) (tokenHash string, clientID, userID sql.NullString, found bool)
+	switch r.Form.Get("token_type_hint") {
+	case AccessTokenHint, "":
+		lookups = append(lookups, s.findAccessTokenOwner, s.findRefreshTokenOwner)
+	case RefreshTokenHint:
+		lookups = append(lookups, s.findRefreshTokenOwner, s.findAccessTokenOwner)
+	default:
+		return ErrTokenHintInvalid
+	}
+
+	for _, lookup := range lookups {
+		tokenHash, clientID, userID, found := lookup(token)
+		if !found {
+			continue
+		}
+		// Clients can only revoke their own tokens
+		if clientID.String != client.ID {
+			return ErrTokenNotIssuedToClient
+		}
+		return s.revokeTokens(tokenHash, clientID, userID)
+	}
+
+	// Unknown tokens need no revoking, see RFC 7009 section 2.2
+	return nil
+}
+
+// findAccessTokenOwner returns the stored hash of the access token and
+// the IDs of the client and user it was granted to
+func (s *Service) findAccessTokenOwner(token string) (string, sql.NullString, sql.NullString, bool) {
+	// JWT access tokens are stored by their jti claim
+	token, err := s.decodeAccessToken(token)
+	if err != nil {
+		return "", sql.NullString{}, sql.NullString{}, false
+	}
+
+	accessToken := new(models.OauthAccessToken)
+	if s.db.Select("token, client_id, user_id").Where("token = ?", models.HashToken(token)).First(accessToken).RecordNotFound() {
+		return "", sql.NullString{}, sql.NullString{}, false
+	}
+	return accessToken.TokenHash, accessToken.ClientID, accessToken.UserID, true
+}
+
+// findRefreshTokenOwner returns the stored hash of the refresh token and
+// the IDs of the client and user it was granted to
+func (s *Service) findRefreshTokenOwner(token string) (string, sql.NullString, sql.NullString, bool) {
+	refreshToken := new(models.OauthRefreshToken)
+	if s.db.Select("token, client_id, user_id").Where("token = ?", models.HashToken(token)).First(refreshToken).RecordNotFound() {
+		return "", sql.NullString{}, sql.NullString{}, false
+	}
+	return refreshToken.TokenHash, refreshToken.ClientID, refreshToken.UserID, true
+}
+
+// revokeTokens deletes the token with the hash, and every access and refresh
+// token granted to the client for the user as refresh tokens are shared by
+// their access tokens. Tokens granted to the client itself are revoked one by one.
+func (s *Service) revokeTokens(tokenHash string, clientID, userID sql.NullString) error {
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	query := tx.Unscoped().Where("token = ?", tokenHash)
+	if userID.Valid {
+		query = tx.Unscoped().Where("client_id = ? AND user_id = ?", clientID, userID)
+	}
+	if err := query.Delete(new(models.OauthAccessToken)).Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+	if err := query.Delete(new(models.OauthRefreshToken)).Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	s.validationCache.Invalidate(clientID, userID)
+	return nil
+}
@@ -0,0 +1,130 @@
+package oauth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/test-util"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestRevokeAccessToken() {
+	accessToken, err := suite.service.GrantAccessToken(suite.clients[0], suite.users[0], 3600, "read")
+	assert.NoError(suite.T(), err)
+	refreshToken, err := suite.service.CreateRefreshToken(suite.clients[0], suite.users[0], 3600, "read")
+	assert.NoError(suite.T(), err)
+
+	// Tokens of another user are not affected
+	otherAccessToken, err := suite.service.GrantAccessToken(suite.clients[0], suite.users[1], 3600, "read")
+	assert.NoError(suite.T(), err)
+
+	w := suite.revoke("test_client_1", url.Values{
+		"token":           {accessToken.Token},
+		"token_type_hint": {oauth.AccessTokenHint},
+	})
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Empty(suite.T(), w.Body.String())
+
+	// Revoking the access token revokes the refresh token as well
+	_, err = suite.service.Authenticate(accessToken.Token)
+	assert.Equal(suite.T(), oauth.ErrAccessTokenNotFound, err)
+	_, err = suite.service.GetValidRefreshToken(refreshToken.Token, suite.clients[0])
+	assert.Equal(suite.T(), oauth.ErrRefreshTokenNotFound, err)
+	_, err = suite.service.Authenticate(otherAccessToken.Token)
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *OauthTestSuite) TestRevokeRefreshToken() {
+	accessToken, err := suite.service.GrantAccessToken(suite.clients[0], suite.users[0], 3600, "read")
+	assert.NoError(suite.T(), err)
+	refreshToken, err := suite.service.CreateRefreshToken(suite.clients[0], suite.users[0], 3600, "read")
+	assert.NoError(suite.T(), err)
+
+	// The hint only tells which type of token to look up first
+	w := suite.revoke("test_client_1", url.Values{
+		"token":           {refreshToken.Token},
+		"token_type_hint": {oauth.AccessTokenHint},
+	})
+	assert.Equal(suite.T(), 200, w.Code)
+
+	// Revoking the refresh token revokes its access tokens as well
+	_, err = suite.service.GetValidRefreshToken(refreshToken.Token, suite.clients[0])
+	assert.Equal(suite.T(), oauth.ErrRefreshTokenNotFound, err)
+	_, err = suite.service.Authenticate(accessToken.Token)
+	assert.Equal(suite.T(), oauth.ErrAccessTokenNotFound, err)
+}
+
+func (suite *OauthTestSuite) TestRevokeClientToken() {
+	accessToken, err := suite.service.GrantAccessToken(suite.clients[0], nil, 3600, "read")
+	assert.NoError(suite.T(), err)
+	otherAccessToken, err := suite.service.GrantAccessToken(suite.clients[0], nil, 3600, "read")
+	assert.NoError(suite.T(), err)
+
+	w := suite.revoke("test_client_1", url.Values{"token": {accessToken.Token}})
+	assert.Equal(suite.T(), 200, w.Code)
+
+	// Tokens granted to the client itself are revoked one by one
+	_, err = suite.service.Authenticate(accessToken.Token)
+	assert.Equal(suite.T(), oauth.ErrAccessTokenNotFound, err)
+	_, err = suite.service.Authenticate(otherAccessToken.Token)
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *OauthTestSuite) TestRevokeTokenOfAnotherClient() {
+	accessToken, err := suite.service.GrantAccessToken(suite.clients[0], suite.users[0], 3600, "read")
+	assert.NoError(suite.T(), err)
+
+	w := suite.revoke("test_client_2", url.Values{"token": {accessToken.Token}})
+	suite.assertErrorCode(w, "unauthorized_client")
+
+	// The token has not been revoked
+	_, err = suite.service.Authenticate(accessToken.Token)
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *OauthTestSuite) TestRevokeInvalidRequest() {
+	// Unknown tokens need no revoking
+	w := suite.revoke("test_client_1", url.Values{"token": {"bogus"}})
+	assert.Equal(suite.T(), 200, w.Code)
+
+	for _, testCase := range []struct {
+		form url.Values
+		err  error
+	}{
+		{url.Values{}, oauth.ErrTokenMissing},
+		{url.Values{"token": {"bogus"}, "token_type_hint": {"bogus"}}, oauth.ErrTokenHintInvalid},
+	} {
+		testutil.TestResponseForError(
+			suite.T(),
+			suite.revoke("test_client_1", testCase.form),
+			testCase.err.Error(),
+			400,
+		)
+	}
+
+	// Clients must authenticate
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/revoke", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.PostForm = url.Values{"token": {"bogus"}}
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	testutil.TestResponseForError(
+		suite.T(),
+		w,
+		oauth.ErrInvalidClientIDOrSecret.Error(),
+		401,
+	)
+}
+
+func (suite *OauthTestSuite) revoke(clientID string, form url.Values) *httptest.ResponseRecorder {
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/revoke", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth(clientID, "test_secret")
+	r.PostForm = form
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	return w
+}
@@ -2,8 +2,10 @@ package oauth
 
 import (
 	"errors"
+	"strings"
 
 	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util"
 )
 
 var (
@@ -19,3 +21,143 @@ func (s *Service) FindRoleByID(id string) (*models.OauthRole, error) {
 	}
 	return role, nil
 }
+
+// GetRoleScopes returns the scopes users with the role can be issued
+// tokens for, a role which has not been mapped to any permits all scopes
+func (s *Service) GetRoleScopes(role *models.OauthRole) ([]string, error) {
+	var scopes []string
+	err := s.db.Table("oauth_role_scopes").
+		Joins("JOIN oauth_scopes ON oauth_scopes.id = oauth_role_scopes.scope_id").
+		Where("oauth_role_scopes.role_id = ?", role.ID).
+		Order("oauth_scopes.scope").Pluck("oauth_scopes.scope", &scopes).Error
+	if err != nil {
+		return nil, err
+	}
+	return scopes, nil
+}
+
+// SetRoleScopes maps a role to the scopes users with the role can be
+// issued tokens for, an empty list permits all of them again
+func (s *Service) SetRoleScopes(role *models.OauthRole, scopes []string) error {
+	// Every scope mapped must exist
+	var mapped []*models.OauthScope
+	if len(scopes) > 0 {
+		if err := s.db.Where("scope in (?)", scopes).Find(&mapped).Error; err != nil {
+			return err
+		}
+		for _, scope := range scopes {
+			if !scopeFound(mapped, scope) {
+				return ErrInvalidScope
+			}
+		}
+	}
+
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	err := tx.Unscoped().Where("role_id = ?", role.ID).
+		Delete(new(models.OauthRoleScope)).Error
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+	for _, scope := range mapped {
+		if err := tx.Create(models.NewOauthRoleScope(role, scope)).Error; err != nil {
+			tx.Rollback() // rollback the transaction
+			return err
+		}
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	return nil
+}
+
+// GetUserRoles returns the role of the user followed by the other roles
+// the user has
+func (s *Service) GetUserRoles(user *models.OauthUser) ([]string, error) {
+	var roleIDs []string
+	err := s.db.Model(new(models.OauthUserRole)).Where("user_id = ?", user.ID).
+		Order("role_id").Pluck("role_id", &roleIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{user.RoleID.String}, roleIDs...), nil
+}
+
+// SetUserRoles replaces the roles a user has besides its role
+func (s *Service) SetUserRoles(user *models.OauthUser, roleIDs []string) error {
+	// Every role must exist
+	var roles []*models.OauthRole
+	for _, roleID := range roleIDs {
+		role, err := s.FindRoleByID(roleID)
+		if err != nil {
+			return err
+		}
+		if role.ID != user.RoleID.String {
+			roles = append(roles, role)
+		}
+	}
+
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	err := tx.Unscoped().Where("user_id = ?", user.ID).
+		Delete(new(models.OauthUserRole)).Error
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+	for _, role := range roles {
+		if err := tx.Create(models.NewOauthUserRole(user, role)).Error; err != nil {
+			tx.Rollback() // rollback the transaction
+			return err
+		}
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	return nil
+}
+
+// getUserScope returns the part of the scope the roles of the user permit.
+// Users are only restricted once all of their roles have been mapped to
+// scopes, a role which has not been mapped to any permits all of them.
+func (s *Service) getUserScope(user *models.OauthUser, scope string) (string, error) {
+	if user == nil {
+		return scope, nil
+	}
+	roleIDs, err := s.GetUserRoles(user)
+	if err != nil {
+		return "", err
+	}
+
+	var permitted []string
+	for _, roleID := range roleIDs {
+		scopes, err := s.GetRoleScopes(&models.OauthRole{ID: roleID})
+		if err != nil {
+			return "", err
+		}
+		if len(scopes) == 0 {
+			return scope, nil
+		}
+		permitted = append(permitted, scopes...)
+	}
+	permitted = s.expandScope(permitted)
+
+	var granted []string
+	for _, requested := range strings.Fields(scope) {
+		if util.StringInSlice(requested, permitted) {
+			granted = append(granted, requested)
+		}
+	}
+	return strings.Join(granted, " "), nil
+}
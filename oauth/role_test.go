@@ -35,3 +35,50 @@ func (suite *OauthTestSuite) TestFindRoleByID() {
 		assert.Equal(suite.T(), roles.User, role.ID)
 	}
 }
+
+func (suite *OauthTestSuite) TestRoleScopes() {
+	auditor := &models.OauthRole{ID: "auditor", Name: "Auditor"}
+	if !assert.NoError(suite.T(), suite.db.Create(auditor).Error) {
+		return
+	}
+	user, err := suite.service.CreateUser(roles.User, "jdoe@example.com", "test_password")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+
+	// Roles can only be mapped to existing scopes
+	assert.Equal(suite.T(), oauth.ErrInvalidScope, suite.service.SetRoleScopes(auditor, []string{"bogus"}))
+	assert.Equal(suite.T(), oauth.ErrRoleNotFound, suite.service.SetUserRoles(user, []string{"bogus"}))
+
+	// Users are not restricted until all of their roles are mapped
+	assert.NoError(suite.T(), suite.service.SetRoleScopes(auditor, []string{"read"}))
+	assert.NoError(suite.T(), suite.service.SetUserRoles(user, []string{"auditor", roles.User}))
+	roleIDs, err := suite.service.GetUserRoles(user)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), []string{roles.User, "auditor"}, roleIDs)
+	}
+	accessToken, _, err := suite.service.Login(suite.clients[0], user, "read_write")
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), "read_write", accessToken.Scope)
+	}
+
+	// Then they are only granted the scopes their roles permit
+	userRole, err := suite.service.FindRoleByID(roles.User)
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	assert.NoError(suite.T(), suite.service.SetRoleScopes(userRole, []string{"openid"}))
+	accessToken, refreshToken, err := suite.service.Login(suite.clients[0], user, "openid read read_write")
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), "openid read", accessToken.Scope)
+		assert.Equal(suite.T(), "openid read", refreshToken.Scope)
+	}
+	_, _, err = suite.service.Login(suite.clients[0], user, "read_write")
+	assert.Equal(suite.T(), oauth.ErrInvalidScope, err)
+
+	// Other users are restricted by their role alone
+	accessToken, _, err = suite.service.Login(suite.clients[0], suite.users[0], "read_write")
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), "read_write", accessToken.Scope)
+	}
+}
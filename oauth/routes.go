@@ -1,8 +1,10 @@
 package oauth
 
 import (
+	"github.com/RichardKnop/go-oauth2-server/util/response"
 	"github.com/RichardKnop/go-oauth2-server/util/routes"
 	"github.com/gorilla/mux"
+	"github.com/urfave/negroni"
 )
 
 const (
@@ -10,12 +12,72 @@ const (
 	tokensPath         = "/" + tokensResource
 	introspectResource = "introspect"
 	introspectPath     = "/" + introspectResource
+	revokeResource     = "revoke"
+	revokePath         = "/" + revokeResource
+	handoffResource    = "handoff"
+	handoffPath        = "/" + handoffResource
+	recoveryResource   = "recovery-codes"
+	recoveryPath       = "/" + recoveryResource
+	totpResource       = "totp"
+	totpPath           = "/" + totpResource
+	webAuthnResource   = "webauthn"
+	webAuthnPath       = "/" + webAuthnResource
+	userInfoResource   = "userinfo"
+	userInfoPath       = "/" + userInfoResource
+	deviceResource     = "device_authorization"
+	devicePath         = "/" + deviceResource
+	parResource        = "par"
+	parPath            = "/" + parResource
+	cibaResource       = "bc-authorize"
+	cibaPath           = "/" + cibaResource
+	registerResource   = "register"
+	registerPath       = "/" + registerResource
+	usersResource      = "users"
+	usersPath          = "/" + usersResource
+	passwordResource   = "password-reset"
+	passwordPath       = "/" + passwordResource
+	emailResource      = "email-verification"
+	emailPath          = "/" + emailResource
+	metadataPath       = "/.well-known/oauth-authorization-server"
+	jwksPath           = "/.well-known/jwks.json"
+	openIDConfigPath   = "/.well-known/openid-configuration"
 )
 
 // RegisterRoutes registers route handlers for the oauth service
 func (s *Service) RegisterRoutes(router *mux.Router, prefix string) {
 	subRouter := router.PathPrefix(prefix).Subrouter()
 	routes.AddRoutes(s.GetRoutes(), subRouter)
+
+	// The metadata and keys are served relative to the issuer, see RFC 8414 section 3
+	routes.AddRoutes([]routes.Route{
+		{
+			Name:        "oauth_metadata",
+			Method:      "GET",
+			Pattern:     metadataPath,
+			HandlerFunc: s.metadataHandler(prefix),
+			Middlewares: []negroni.Handler{
+				response.NewSecureHeadersMiddleware(),
+			},
+		},
+		{
+			Name:        "oauth_openid_configuration",
+			Method:      "GET",
+			Pattern:     openIDConfigPath,
+			HandlerFunc: s.metadataHandler(prefix),
+			Middlewares: []negroni.Handler{
+				response.NewSecureHeadersMiddleware(),
+			},
+		},
+		{
+			Name:        "oauth_jwks",
+			Method:      "GET",
+			Pattern:     jwksPath,
+			HandlerFunc: s.jwksHandler,
+			Middlewares: []negroni.Handler{
+				response.NewSecureHeadersMiddleware(),
+			},
+		},
+	}, router)
 }
 
 // GetRoutes returns []routes.Route slice for the oauth service
@@ -26,12 +88,234 @@ func (s *Service) GetRoutes() []routes.Route {
 			Method:      "POST",
 			Pattern:     tokensPath,
 			HandlerFunc: s.tokensHandler,
+			Middlewares: []negroni.Handler{
+				response.NewSecureHeadersMiddleware(),
+			},
 		},
 		{
 			Name:        "oauth_introspect",
 			Method:      "POST",
 			Pattern:     introspectPath,
 			HandlerFunc: s.introspectHandler,
+			Middlewares: []negroni.Handler{
+				response.NewSecureHeadersMiddleware(),
+			},
+		},
+		{
+			Name:        "oauth_revoke",
+			Method:      "POST",
+			Pattern:     revokePath,
+			HandlerFunc: s.revokeHandler,
+			Middlewares: []negroni.Handler{
+				response.NewSecureHeadersMiddleware(),
+			},
+		},
+		{
+			Name:        "oauth_device_authorization",
+			Method:      "POST",
+			Pattern:     devicePath,
+			HandlerFunc: s.deviceAuthorizationHandler,
+			Middlewares: []negroni.Handler{
+				response.NewSecureHeadersMiddleware(),
+			},
+		},
+		{
+			Name:        "oauth_backchannel_authentication",
+			Method:      "POST",
+			Pattern:     cibaPath,
+			HandlerFunc: s.backchannelAuthenticationHandler,
+			Middlewares: []negroni.Handler{
+				response.NewSecureHeadersMiddleware(),
+			},
+		},
+		{
+			Name:        "oauth_pushed_authorization",
+			Method:      "POST",
+			Pattern:     parPath,
+			HandlerFunc: s.pushedAuthorizationHandler,
+			Middlewares: []negroni.Handler{
+				response.NewSecureHeadersMiddleware(),
+			},
+		},
+		{
+			Name:        "oauth_register",
+			Method:      "POST",
+			Pattern:     registerPath,
+			HandlerFunc: s.registerClientHandler,
+			Middlewares: []negroni.Handler{
+				response.NewSecureHeadersMiddleware(),
+			},
+		},
+		{
+			Name:        "oauth_register_user",
+			Method:      "POST",
+			Pattern:     usersPath,
+			HandlerFunc: s.registerUserHandler,
+			Middlewares: []negroni.Handler{
+				response.NewSecureHeadersMiddleware(),
+			},
+		},
+		{
+			Name:        "oauth_password_reset",
+			Method:      "POST",
+			Pattern:     passwordPath,
+			HandlerFunc: s.passwordResetHandler,
+			Middlewares: []negroni.Handler{
+				response.NewSecureHeadersMiddleware(),
+			},
+		},
+		{
+			Name:        "oauth_password_reset_confirm",
+			Method:      "POST",
+			Pattern:     passwordPath + "/confirm",
+			HandlerFunc: s.passwordResetConfirmHandler,
+			Middlewares: []negroni.Handler{
+				response.NewSecureHeadersMiddleware(),
+			},
+		},
+		{
+			Name:        "oauth_email_verification",
+			Method:      "POST",
+			Pattern:     emailPath,
+			HandlerFunc: s.emailVerificationHandler,
+			Middlewares: []negroni.Handler{
+				response.NewSecureHeadersMiddleware(),
+			},
+		},
+		{
+			Name:        "oauth_email_verification_confirm",
+			Method:      "POST",
+			Pattern:     emailPath + "/confirm",
+			HandlerFunc: s.emailVerificationConfirmHandler,
+			Middlewares: []negroni.Handler{
+				response.NewSecureHeadersMiddleware(),
+			},
+		},
+		{
+			Name:        "oauth_webauthn_login",
+			Method:      "POST",
+			Pattern:     webAuthnPath + "/login",
+			HandlerFunc: s.webAuthnLoginHandler,
+			Middlewares: []negroni.Handler{
+				response.NewSecureHeadersMiddleware(),
+			},
+		},
+		{
+			Name:        "oauth_client_configuration",
+			Method:      "GET",
+			Pattern:     registerPath + "/{client_id}",
+			HandlerFunc: s.clientConfigurationHandler,
+			Middlewares: []negroni.Handler{
+				response.NewSecureHeadersMiddleware(),
+			},
+		},
+		{
+			Name:        "oauth_update_client_configuration",
+			Method:      "PUT",
+			Pattern:     registerPath + "/{client_id}",
+			HandlerFunc: s.updateClientConfigurationHandler,
+			Middlewares: []negroni.Handler{
+				response.NewSecureHeadersMiddleware(),
+			},
+		},
+		{
+			Name:        "oauth_delete_client_configuration",
+			Method:      "DELETE",
+			Pattern:     registerPath + "/{client_id}",
+			HandlerFunc: s.deleteClientConfigurationHandler,
+			Middlewares: []negroni.Handler{
+				response.NewSecureHeadersMiddleware(),
+			},
+		},
+		{
+			Name:        "oauth_handoff",
+			Method:      "POST",
+			Pattern:     handoffPath,
+			HandlerFunc: s.handoffHandler,
+			Middlewares: []negroni.Handler{
+				response.NewSecureHeadersMiddleware(),
+				NewAuthenticationMiddleware(s),
+			},
+		},
+		{
+			Name:        "oauth_recovery_codes",
+			Method:      "POST",
+			Pattern:     recoveryPath,
+			HandlerFunc: s.recoveryCodesHandler,
+			Middlewares: []negroni.Handler{
+				response.NewSecureHeadersMiddleware(),
+				NewAuthenticationMiddleware(s),
+			},
+		},
+		{
+			Name:        "oauth_totp_enroll",
+			Method:      "POST",
+			Pattern:     totpPath,
+			HandlerFunc: s.totpEnrollHandler,
+			Middlewares: []negroni.Handler{
+				response.NewSecureHeadersMiddleware(),
+				NewAuthenticationMiddleware(s),
+			},
+		},
+		{
+			Name:        "oauth_totp_confirm",
+			Method:      "POST",
+			Pattern:     totpPath + "/confirm",
+			HandlerFunc: s.totpConfirmHandler,
+			Middlewares: []negroni.Handler{
+				response.NewSecureHeadersMiddleware(),
+				NewAuthenticationMiddleware(s),
+			},
+		},
+		{
+			Name:        "oauth_totp_disable",
+			Method:      "DELETE",
+			Pattern:     totpPath,
+			HandlerFunc: s.totpDisableHandler,
+			Middlewares: []negroni.Handler{
+				response.NewSecureHeadersMiddleware(),
+				NewAuthenticationMiddleware(s),
+			},
+		},
+		{
+			Name:        "oauth_webauthn_register",
+			Method:      "POST",
+			Pattern:     webAuthnPath + "/register",
+			HandlerFunc: s.webAuthnRegisterHandler,
+			Middlewares: []negroni.Handler{
+				response.NewSecureHeadersMiddleware(),
+				NewAuthenticationMiddleware(s),
+			},
+		},
+		{
+			Name:        "oauth_webauthn_register_confirm",
+			Method:      "POST",
+			Pattern:     webAuthnPath + "/register/confirm",
+			HandlerFunc: s.webAuthnRegisterConfirmHandler,
+			Middlewares: []negroni.Handler{
+				response.NewSecureHeadersMiddleware(),
+				NewAuthenticationMiddleware(s),
+			},
+		},
+		{
+			Name:        "oauth_userinfo",
+			Method:      "GET",
+			Pattern:     userInfoPath,
+			HandlerFunc: s.userInfoHandler,
+			Middlewares: []negroni.Handler{
+				response.NewSecureHeadersMiddleware(),
+				NewAuthenticationMiddleware(s),
+			},
+		},
+		{
+			Name:        "oauth_userinfo_post",
+			Method:      "POST",
+			Pattern:     userInfoPath,
+			HandlerFunc: s.userInfoHandler,
+			Middlewares: []negroni.Handler{
+				response.NewSecureHeadersMiddleware(),
+				NewAuthenticationMiddleware(s),
+			},
 		},
 	}
 }
@@ -38,3 +38,115 @@ func (suite *OauthTestSuite) TestIntrospectRouteIsValid() {
 		assert.Equal(suite.T(), "oauth_introspect", match.Route.GetName(), "Expected route to be matched")
 	}
 }
+
+func (suite *OauthTestSuite) TestHandoffRouteIsValid() {
+	r, err := http.NewRequest(
+		"POST",
+		"http://1.2.3.4/v1/oauth/handoff",
+		nil,
+	)
+	assert.NoError(suite.T(), err, "New request should not cause an error")
+
+	// Check the routing
+	match := new(mux.RouteMatch)
+	suite.router.Match(r, match)
+	if assert.NotNil(suite.T(), match.Route, "Expected to find a route match") {
+		assert.Equal(suite.T(), "oauth_handoff", match.Route.GetName(), "Expected route to be matched")
+	}
+}
+
+func (suite *OauthTestSuite) TestRecoveryCodesRouteIsValid() {
+	r, err := http.NewRequest(
+		"POST",
+		"http://1.2.3.4/v1/oauth/recovery-codes",
+		nil,
+	)
+	assert.NoError(suite.T(), err, "New request should not cause an error")
+
+	// Check the routing
+	match := new(mux.RouteMatch)
+	suite.router.Match(r, match)
+	if assert.NotNil(suite.T(), match.Route, "Expected to find a route match") {
+		assert.Equal(suite.T(), "oauth_recovery_codes", match.Route.GetName(), "Expected route to be matched")
+	}
+}
+
+func (suite *OauthTestSuite) TestMetadataRouteIsValid() {
+	r, err := http.NewRequest(
+		"GET",
+		"http://1.2.3.4/.well-known/oauth-authorization-server",
+		nil,
+	)
+	assert.NoError(suite.T(), err, "New request should not cause an error")
+
+	// Check the routing
+	match := new(mux.RouteMatch)
+	suite.router.Match(r, match)
+	if assert.NotNil(suite.T(), match.Route, "Expected to find a route match") {
+		assert.Equal(suite.T(), "oauth_metadata", match.Route.GetName(), "Expected route to be matched")
+	}
+}
+
+func (suite *OauthTestSuite) TestUserInfoRouteIsValid() {
+	for _, method := range []string{"GET", "POST"} {
+		r, err := http.NewRequest(
+			method,
+			"http://1.2.3.4/v1/oauth/userinfo",
+			nil,
+		)
+		assert.NoError(suite.T(), err, "New request should not cause an error")
+
+		// Check the routing
+		match := new(mux.RouteMatch)
+		suite.router.Match(r, match)
+		assert.NotNil(suite.T(), match.Route, "Expected to find a route match")
+	}
+}
+
+func (suite *OauthTestSuite) TestOpenIDConfigurationRouteIsValid() {
+	r, err := http.NewRequest(
+		"GET",
+		"http://1.2.3.4/.well-known/openid-configuration",
+		nil,
+	)
+	assert.NoError(suite.T(), err, "New request should not cause an error")
+
+	// Check the routing
+	match := new(mux.RouteMatch)
+	suite.router.Match(r, match)
+	if assert.NotNil(suite.T(), match.Route, "Expected to find a route match") {
+		assert.Equal(suite.T(), "oauth_openid_configuration", match.Route.GetName(), "Expected route to be matched")
+	}
+}
+
+func (suite *OauthTestSuite) TestJWKSRouteIsValid() {
+	r, err := http.NewRequest(
+		"GET",
+		"http://1.2.3.4/.well-known/jwks.json",
+		nil,
+	)
+	assert.NoError(suite.T(), err, "New request should not cause an error")
+
+	// Check the routing
+	match := new(mux.RouteMatch)
+	suite.router.Match(r, match)
+	if assert.NotNil(suite.T(), match.Route, "Expected to find a route match") {
+		assert.Equal(suite.T(), "oauth_jwks", match.Route.GetName(), "Expected route to be matched")
+	}
+}
+
+func (suite *OauthTestSuite) TestRevokeRouteIsValid() {
+	r, err := http.NewRequest(
+		"POST",
+		"http://1.2.3.4/v1/oauth/revoke",
+		nil,
+	)
+	assert.NoError(suite.T(), err, "New request should not cause an error")
+
+	// Check the routing
+	match := new(mux.RouteMatch)
+	suite.router.Match(r, match)
+	if assert.NotNil(suite.T(), match.Route, "Expected to find a route match") {
+		assert.Equal(suite.T(), "oauth_revoke", match.Route.GetName(), "Expected route to be matched")
+	}
+}
@@ -0,0 +1,135 @@
+package oauth
+
+import (
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util/saml"
+)
+
+const (
+	// SAML2BearerGrantType is the grant type clients exchange SAML 2.0 assertions with
+	SAML2BearerGrantType = "urn:ietf:params:oauth:grant-type:saml2-bearer"
+)
+
+// AddSAMLIdentityProvider registers an identity provider for the client from
+// its SAML 2.0 metadata, assertions issued by the entity and signed with one
+// of its signing certificates can then be exchanged for tokens
+func (s *Service) AddSAMLIdentityProvider(client *models.OauthClient, metadata []byte) (*models.OauthSAMLIdentityProvider, error) {
+	parsed, err := saml.ParseMetadata(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	identityProvider := models.NewOauthSAMLIdentityProvider(
+		client,
+		parsed.EntityID,
+		saml.EncodeCertificates(parsed.Certificates),
+	)
+	if err := s.db.Create(identityProvider).Error; err != nil {
+		return nil, err
+	}
+	identityProvider.Client = client
+
+	return identityProvider, nil
+}
+
+// verifySAMLAssertion returns the base64url encoded assertion if signed by one of
+// the identity providers registered for the client which issued it,
+// endpoint is the URL of the endpoint the assertion was presented to
+func (s *Service) verifySAMLAssertion(encoded string, client *models.OauthClient, endpoint string) (*saml.Assertion, error) {
+	document, err := saml.Decode(encoded)
+	if err != nil || len(document) == 0 {
+		return nil, ErrInvalidAssertion
+	}
+
+	var identityProviders []*models.OauthSAMLIdentityProvider
+	err = s.db.Where("client_id = ?", client.ID).Order("created_at").Find(&identityProviders).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, identityProvider := range identityProviders {
+		certificates, err := saml.ParseCertificates([]byte(identityProvider.Certificates))
+		if err != nil {
+			continue
+		}
+		assertion, err := saml.Verify(document, certificates)
+		if err != nil {
+			continue
+		}
+		// The identity provider must have been registered for the issuer
+		if strings.TrimSpace(assertion.Issuer) != identityProvider.EntityID {
+			continue
+		}
+		return assertion, s.checkSAMLAssertion(assertion, endpoint)
+	}
+
+	return nil, ErrInvalidAssertion
+}
+
+// checkSAMLAssertion validates the assertion as required by RFC 7522 section 3
+func (s *Service) checkSAMLAssertion(assertion *saml.Assertion, endpoint string) error {
+	now := time.Now()
+
+	if strings.TrimSpace(assertion.Subject.NameID) == "" {
+		return ErrInvalidAssertion
+	}
+
+	if conditions := assertion.Conditions; conditions != nil {
+		if !conditions.NotBefore.IsZero() && now.Before(conditions.NotBefore) {
+			return ErrInvalidAssertion
+		}
+		if !conditions.NotOnOrAfter.IsZero() && !now.Before(conditions.NotOnOrAfter) {
+			return ErrAssertionExpired
+		}
+	}
+
+	// A bearer subject confirmation must expire, and be meant
+	// for the endpoint if it names a recipient
+	confirmed := false
+	for _, confirmation := range assertion.Subject.SubjectConfirmations {
+		data := confirmation.SubjectConfirmationData
+		if confirmation.Method != saml.BearerConfirmation || data == nil || data.NotOnOrAfter.IsZero() {
+			continue
+		}
+		if data.Recipient != "" && strings.TrimSuffix(data.Recipient, "/") != endpoint {
+			continue
+		}
+		if !data.NotBefore.IsZero() && now.Before(data.NotBefore) {
+			continue
+		}
+		if !now.Before(data.NotOnOrAfter) {
+			return ErrAssertionExpired
+		}
+		confirmed = true
+		break
+	}
+	if !confirmed {
+		return ErrInvalidAssertion
+	}
+
+	// Every audience restriction must list this server or the endpoint
+	if assertion.Conditions == nil || len(assertion.Conditions.AudienceRestrictions) == 0 {
+		return ErrAssertionAudienceMismatch
+	}
+	issuer := strings.TrimSuffix(s.cnf.JWT.Issuer, "/")
+	for _, restriction := range assertion.Conditions.AudienceRestrictions {
+		if !samlAudienceIncludes(restriction.Audiences, issuer, endpoint) {
+			return ErrAssertionAudienceMismatch
+		}
+	}
+
+	return nil
+}
+
+// samlAudienceIncludes returns true if one of the audiences is the issuer or the endpoint
+func samlAudienceIncludes(audiences []string, issuer, endpoint string) bool {
+	for _, audience := range audiences {
+		if audience := strings.TrimSuffix(strings.TrimSpace(audience), "/"); audience == issuer || audience == endpoint {
+			return true
+		}
+	}
+	return false
+}
@@ -6,35 +6,104 @@ import (
 	"strings"
 
 	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util"
 )
 
 var (
 	// ErrInvalidScope ...
 	ErrInvalidScope = errors.New("Invalid scope")
+	// ErrScopeAudienceMismatch ...
+	ErrScopeAudienceMismatch = errors.New("Requested scope is restricted to a different audience")
 )
 
 // GetScope takes a requested scope and, if it's empty, returns the default
-// scope, if not empty, it validates the requested scope
+// scope, if not empty, it validates the requested scope and returns it
+// without extra whitespace. Scopes implied by the scope are added to it.
 func (s *Service) GetScope(requestedScope string) (string, error) {
 	// Return the default scope if the requested scope is empty
-	if requestedScope == "" {
-		return s.GetDefaultScope(), nil
+	scopes := parseScope(requestedScope)
+	if len(scopes) == 0 {
+		return strings.Join(s.expandScope(parseScope(s.GetDefaultScope())), " "), nil
 	}
 
 	// If the requested scope exists in the database, return it
-	if s.ScopeExists(requestedScope) {
-		return requestedScope, nil
+	if s.ScopeExists(strings.Join(scopes, " ")) {
+		return strings.Join(s.expandScope(scopes), " "), nil
 	}
 
 	// Otherwise return error
 	return "", ErrInvalidScope
 }
 
+// getNarrowedScope validates a scope requested to narrow a granted scope
+// like GetScope, but the scopes it implies are only kept if granted
+func (s *Service) getNarrowedScope(requestedScope, grantedScope string) (string, error) {
+	scope, err := s.GetScope(requestedScope)
+	if err != nil {
+		return "", err
+	}
+
+	requested, granted := parseScope(requestedScope), parseScope(grantedScope)
+	var narrowed []string
+	for _, name := range parseScope(scope) {
+		if util.StringInSlice(name, requested) || util.StringInSlice(name, granted) {
+			narrowed = append(narrowed, name)
+		}
+	}
+	return strings.Join(narrowed, " "), nil
+}
+
+// expandScope adds the scopes implied by the scopes, and the scopes those
+// imply in turn, after the scopes themselves
+func (s *Service) expandScope(scopes []string) []string {
+	expanded := append([]string(nil), scopes...)
+	for pending := scopes; len(pending) > 0; {
+		var next []string
+		for _, implies := range s.getScopeImplies(pending) {
+			for _, implied := range parseScope(implies) {
+				if !util.StringInSlice(implied, expanded) && !util.StringInSlice(implied, next) {
+					next = append(next, implied)
+				}
+			}
+		}
+
+		// Sort the implied scopes alphabetically
+		sort.Strings(next)
+		expanded = append(expanded, next...)
+		pending = next
+	}
+	return expanded
+}
+
+// getScopeImplies returns the space delimited scopes implied by each scope
+func (s *Service) getScopeImplies(scopes []string) []string {
+	var implies []string
+	if cached, ok := s.getCachedScopes(); ok {
+		for _, name := range scopes {
+			if scope, ok := cached[name]; ok && scope.Implies != "" {
+				implies = append(implies, scope.Implies)
+			}
+		}
+	} else {
+		s.db.Model(new(models.OauthScope)).Where("scope in (?)", scopes).
+			Where("implies <> ''").Pluck("implies", &implies)
+	}
+	return implies
+}
+
 // GetDefaultScope returns the default scope
 func (s *Service) GetDefaultScope() string {
 	// Fetch default scopes
 	var scopes []string
-	s.db.Model(new(models.OauthScope)).Where("is_default = ?", true).Pluck("scope", &scopes)
+	if cached, ok := s.getCachedScopes(); ok {
+		for _, scope := range cached {
+			if scope.IsDefault {
+				scopes = append(scopes, scope.Scope)
+			}
+		}
+	} else {
+		s.db.Model(new(models.OauthScope)).Where("is_default = ?", true).Pluck("scope", &scopes)
+	}
 
 	// Sort the scopes alphabetically
 	sort.Strings(scopes)
@@ -46,12 +115,60 @@ func (s *Service) GetDefaultScope() string {
 // ScopeExists checks if a scope exists
 func (s *Service) ScopeExists(requestedScope string) bool {
 	// Split the requested scope string
-	scopes := strings.Split(requestedScope, " ")
+	scopes := parseScope(requestedScope)
+	if len(scopes) == 0 {
+		return false
+	}
 
 	// Count how many of requested scopes exist in the database
 	var count int
-	s.db.Model(new(models.OauthScope)).Where("scope in (?)", scopes).Count(&count)
+	if cached, ok := s.getCachedScopes(); ok {
+		count = countCachedScopes(cached, scopes)
+	} else {
+		s.db.Model(new(models.OauthScope)).Where("scope in (?)", scopes).Count(&count)
+	}
 
 	// Return true only if all requested scopes found
 	return count == len(scopes)
 }
+
+// GetScopeAudience returns the space delimited audiences the scopes
+// are restricted to, tokens carrying the scope are issued for them
+func (s *Service) GetScopeAudience(scope string) string {
+	// Fetch audiences of the scopes
+	var audiences []string
+	if cached, ok := s.getCachedScopes(); ok {
+		audiences = cachedScopeAudiences(cached, strings.Split(scope, " "))
+	} else {
+		s.db.Model(new(models.OauthScope)).Where("scope in (?)", strings.Split(scope, " ")).
+			Where("audience <> ''").Pluck("DISTINCT audience", &audiences)
+	}
+
+	// Sort the audiences alphabetically
+	sort.Strings(audiences)
+
+	// Return space delimited audience string
+	return strings.Join(audiences, " ")
+}
+
+// checkRequestedAudience makes sure a requested audience does not conflict
+// with the audiences the scopes are restricted to
+func (s *Service) checkRequestedAudience(requestedAudience, scope string) error {
+	if requestedAudience == "" {
+		return nil
+	}
+
+	for _, audience := range strings.Fields(s.GetScopeAudience(scope)) {
+		if audience != requestedAudience {
+			return ErrScopeAudienceMismatch
+		}
+	}
+
+	return nil
+}
+
+// parseScope splits a space delimited scope string, ignoring any extra
+// whitespace, see https://tools.ietf.org/html/rfc6749#section-3.3
+func parseScope(scope string) []string {
+	return strings.Fields(scope)
+}
@@ -0,0 +1,91 @@
+package oauth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+)
+
+const (
+	// defaultScopeCacheTTL is used when the TTL is not configured
+	defaultScopeCacheTTL = 60
+)
+
+// scopeCache keeps all scopes in memory as they rarely change
+type scopeCache struct {
+	mu       sync.RWMutex
+	scopes   map[string]models.OauthScope
+	loadedAt time.Time
+}
+
+// InvalidateScopeCache makes the next grant load scopes from the database
+// again, it should be called after scopes have been written
+func (s *Service) InvalidateScopeCache() {
+	s.scopeCache.mu.Lock()
+	s.scopeCache.scopes = nil
+	s.scopeCache.mu.Unlock()
+}
+
+// getCachedScopes returns all scopes keyed by name, loading them into the
+// cache if needed. It returns false if the cache is disabled or cannot be loaded.
+func (s *Service) getCachedScopes() (map[string]models.OauthScope, bool) {
+	if !s.cnf.Oauth.EnableScopeCache {
+		return nil, false
+	}
+
+	ttl := s.cnf.Oauth.ScopeCacheTTL
+	if ttl <= 0 {
+		ttl = defaultScopeCacheTTL
+	}
+
+	s.scopeCache.mu.RLock()
+	scopes, loadedAt := s.scopeCache.scopes, s.scopeCache.loadedAt
+	s.scopeCache.mu.RUnlock()
+	if scopes != nil && time.Since(loadedAt) < time.Duration(ttl)*time.Second {
+		return scopes, true
+	}
+
+	var rows []models.OauthScope
+	if err := s.db.Find(&rows).Error; err != nil {
+		return nil, false
+	}
+	scopes = make(map[string]models.OauthScope, len(rows))
+	for _, row := range rows {
+		scopes[row.Scope] = row
+	}
+
+	s.scopeCache.mu.Lock()
+	s.scopeCache.scopes, s.scopeCache.loadedAt = scopes, time.Now()
+	s.scopeCache.mu.Unlock()
+
+	return scopes, true
+}
+
+// countCachedScopes counts how many of the distinct requested scopes exist
+func countCachedScopes(cached map[string]models.OauthScope, requested []string) int {
+	seen := make(map[string]bool, len(requested))
+	var count int
+	for _, name := range requested {
+		if _, ok := cached[name]; ok && !seen[name] {
+			count++
+		}
+		seen[name] = true
+	}
+	return count
+}
+
+// cachedScopeAudiences returns the distinct audiences of the requested scopes
+func cachedScopeAudiences(cached map[string]models.OauthScope, requested []string) []string {
+	seen := make(map[string]bool)
+	var audiences []string
+	for _, name := range requested {
+		scope, ok := cached[name]
+		if !ok || scope.Audience == "" || seen[scope.Audience] {
+			continue
+		}
+		seen[scope.Audience] = true
+		audiences = append(audiences, scope.Audience)
+	}
+	return audiences
+}
@@ -0,0 +1,102 @@
+package oauth_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/test-util"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestScopeCacheInvalidation() {
+	suite.cnf.Oauth.EnableScopeCache = true
+	defer func() {
+		suite.cnf.Oauth.EnableScopeCache = false
+		suite.service.InvalidateScopeCache()
+	}()
+	suite.service.InvalidateScopeCache()
+
+	assert.False(suite.T(), suite.service.ScopeExists("read test_cached"))
+
+	// Scopes seeded through the service take effect straight away
+	err := suite.service.SeedScopes([]oauth.ScopeDef{
+		{Scope: "test_cached", Audience: "cached"},
+	})
+	assert.NoError(suite.T(), err)
+	defer suite.db.Unscoped().Where("scope LIKE ?", "test_%").Delete(new(models.OauthScope))
+
+	assert.True(suite.T(), suite.service.ScopeExists("read test_cached"))
+	assert.False(suite.T(), suite.service.ScopeExists("read read"))
+	assert.Equal(suite.T(), "cached", suite.service.GetScopeAudience("read test_cached"))
+	assert.Equal(suite.T(), "read", suite.service.GetDefaultScope())
+}
+
+func (suite *OauthTestSuite) TestScopeCacheTTL() {
+	suite.cnf.Oauth.EnableScopeCache = true
+	suite.cnf.Oauth.ScopeCacheTTL = 1
+	defer func() {
+		suite.cnf.Oauth.EnableScopeCache = false
+		suite.cnf.Oauth.ScopeCacheTTL = 0
+		suite.service.InvalidateScopeCache()
+	}()
+	suite.service.InvalidateScopeCache()
+
+	assert.False(suite.T(), suite.service.ScopeExists("read test_cached"))
+
+	// Scopes seeded by another process, e.g. the seedscopes command
+	err := oauth.SeedDefaultScopes(suite.db, []oauth.ScopeDef{
+		{Scope: "test_cached", Audience: "cached"},
+	})
+	assert.NoError(suite.T(), err)
+	defer suite.db.Unscoped().Where("scope LIKE ?", "test_%").Delete(new(models.OauthScope))
+
+	// Are served from the cache until it expires
+	assert.False(suite.T(), suite.service.ScopeExists("read test_cached"))
+	time.Sleep(1100 * time.Millisecond)
+	assert.True(suite.T(), suite.service.ScopeExists("read test_cached"))
+	assert.Equal(suite.T(), "cached", suite.service.GetScopeAudience("test_cached"))
+}
+
+func BenchmarkClientCredentialsGrant(b *testing.B) {
+	cnf := config.NewConfig(false, false, "etcd")
+	db, err := testutil.CreateTestDatabasePostgres(
+		cnf.Database.Host,
+		testDbUser,
+		"go_oauth2_server_oauth_bench",
+		testMigrations,
+		testFixtures,
+	)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+
+	client := new(models.OauthClient)
+	if err := db.Where("key = ?", "test_client_1").First(client).Error; err != nil {
+		b.Fatal(err)
+	}
+
+	for _, enabled := range []bool{false, true} {
+		name := "NoCache"
+		if enabled {
+			name = "ScopeCache"
+		}
+		cnf.Oauth.EnableScopeCache = enabled
+		service := oauth.NewService(cnf, db)
+
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				scope, err := service.GetScope("read read_write")
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := service.GrantAccessToken(client, nil, 3600, scope); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
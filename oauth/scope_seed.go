@@ -0,0 +1,109 @@
+package oauth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/RichardKnop/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	// ErrInvalidScopeName ...
+	ErrInvalidScopeName = errors.New("Scope name must not be empty or contain spaces")
+)
+
+// ScopeDef declares a scope to be seeded
+type ScopeDef struct {
+	Scope       string   `json:"scope"`
+	Description string   `json:"description"`
+	IsDefault   bool     `json:"is_default"`
+	Implies     []string `json:"implies"`
+	Audience    string   `json:"audience"`
+}
+
+// SeedDefaultScopes creates the declared scopes, or updates them if they
+// already exist, so it is safe to run repeatedly with the same definitions
+func SeedDefaultScopes(db *gorm.DB, scopes []ScopeDef) error {
+	// Validate the definitions first
+	declared := make([]string, len(scopes))
+	for i, def := range scopes {
+		if def.Scope == "" || strings.Contains(def.Scope, " ") {
+			return ErrInvalidScopeName
+		}
+		declared[i] = def.Scope
+	}
+
+	// Begin a transaction
+	tx := db.Begin()
+
+	for _, def := range scopes {
+		if err := seedScope(tx, def, declared); err != nil {
+			tx.Rollback() // rollback the transaction
+			return err
+		}
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	return nil
+}
+
+// SeedScopes seeds the declared scopes like SeedDefaultScopes and invalidates
+// the scope cache so they take effect straight away
+func (s *Service) SeedScopes(scopes []ScopeDef) error {
+	if err := SeedDefaultScopes(s.db, scopes); err != nil {
+		return err
+	}
+	s.InvalidateScopeCache()
+	return nil
+}
+
+func seedScope(tx *gorm.DB, def ScopeDef, declared []string) error {
+	// Implied scopes must be declared or already exist
+	for _, implied := range def.Implies {
+		if util.StringInSlice(implied, declared) {
+			continue
+		}
+		var count int
+		tx.Model(new(models.OauthScope)).Where("scope = ?", implied).Count(&count)
+		if count == 0 {
+			return fmt.Errorf("Scope %s implies unknown scope %s", def.Scope, implied)
+		}
+	}
+
+	scope := new(models.OauthScope)
+	notFound := tx.Where("scope = ?", def.Scope).First(scope).RecordNotFound()
+
+	// Create a new scope
+	if notFound {
+		return tx.Create(&models.OauthScope{
+			MyGormModel: models.MyGormModel{
+				ID:        uuid.New(),
+				CreatedAt: time.Now().UTC(),
+			},
+			Scope:       def.Scope,
+			Description: util.StringOrNull(def.Description),
+			IsDefault:   def.IsDefault,
+			Implies:     strings.Join(def.Implies, " "),
+			Audience:    def.Audience,
+		}).Error
+	}
+
+	// Or update the existing scope in place
+	return tx.Model(scope).UpdateColumns(map[string]interface{}{
+		"description": util.StringOrNull(def.Description),
+		"is_default":  def.IsDefault,
+		"implies":     strings.Join(def.Implies, " "),
+		"audience":    def.Audience,
+		"updated_at":  time.Now().UTC(),
+	}).Error
+}
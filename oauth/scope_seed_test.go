@@ -0,0 +1,61 @@
+package oauth_test
+
+import (
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestSeedDefaultScopes() {
+	defer suite.db.Unscoped().Where("scope LIKE ?", "test_seed_%").Delete(new(models.OauthScope))
+
+	scopes := []oauth.ScopeDef{
+		{Scope: "test_seed_read", Description: "Read access"},
+		{Scope: "test_seed_write", Description: "Write access", Implies: []string{"test_seed_read"}},
+	}
+
+	// Initial seeding
+	err := oauth.SeedDefaultScopes(suite.db, scopes)
+	assert.NoError(suite.T(), err)
+	suite.assertSeededScope("test_seed_read", "Read access", "")
+	suite.assertSeededScope("test_seed_write", "Write access", "test_seed_read")
+
+	// Re-seeding with a modified description updates the scope in place
+	scopes[1].Description = "Read and write access"
+	err = oauth.SeedDefaultScopes(suite.db, scopes)
+	assert.NoError(suite.T(), err)
+	suite.assertSeededScope("test_seed_read", "Read access", "")
+	suite.assertSeededScope("test_seed_write", "Read and write access", "test_seed_read")
+
+	// Existing scopes can be seeded as well
+	err = oauth.SeedDefaultScopes(suite.db, []oauth.ScopeDef{{Scope: "openid"}})
+	assert.NoError(suite.T(), err)
+
+	var count int
+	suite.db.Model(new(models.OauthScope)).Where("scope LIKE ?", "test_seed_%").Count(&count)
+	assert.Equal(suite.T(), 2, count)
+}
+
+func (suite *OauthTestSuite) TestSeedDefaultScopesInvalid() {
+	defer suite.db.Unscoped().Where("scope LIKE ?", "test_seed_%").Delete(new(models.OauthScope))
+
+	err := oauth.SeedDefaultScopes(suite.db, []oauth.ScopeDef{{Scope: ""}})
+	assert.Equal(suite.T(), oauth.ErrInvalidScopeName, err)
+
+	// Nothing is seeded when a scope implies an unknown scope
+	err = oauth.SeedDefaultScopes(suite.db, []oauth.ScopeDef{
+		{Scope: "test_seed_read"},
+		{Scope: "test_seed_write", Implies: []string{"bogus"}},
+	})
+	assert.Error(suite.T(), err)
+	assert.False(suite.T(), suite.service.ScopeExists("test_seed_read"))
+}
+
+func (suite *OauthTestSuite) assertSeededScope(name, description, implies string) {
+	scope := new(models.OauthScope)
+	if assert.False(suite.T(), suite.db.Where("scope = ?", name).First(scope).RecordNotFound()) {
+		assert.Equal(suite.T(), description, scope.Description.String)
+		assert.Equal(suite.T(), implies, scope.Implies)
+		assert.False(suite.T(), scope.IsDefault)
+	}
+}
@@ -1,6 +1,12 @@
 package oauth_test
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
 	"github.com/RichardKnop/go-oauth2-server/oauth"
 	"github.com/stretchr/testify/assert"
 )
@@ -22,6 +28,20 @@ func (suite *OauthTestSuite) TestGetScope() {
 	assert.Nil(suite.T(), err)
 	assert.Equal(suite.T(), "read read_write", scope)
 
+	// Extra whitespace is dropped
+	scope, err = suite.service.GetScope("  read  read_write ")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "read read_write", scope)
+
+	// But repeated scopes are invalid
+	_, err = suite.service.GetScope("read read")
+	assert.Equal(suite.T(), oauth.ErrInvalidScope, err)
+
+	// Whitespace only means no scope was requested
+	scope, err = suite.service.GetScope("  ")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "read", scope)
+
 	// When the requested scope is invalid, an error should be returned
 	_, err = suite.service.GetScope("read_write bogus")
 	if assert.NotNil(suite.T(), err) {
@@ -33,8 +53,70 @@ func (suite *OauthTestSuite) TestGetDefaultScope() {
 	assert.Equal(suite.T(), "read", suite.service.GetDefaultScope())
 }
 
+func (suite *OauthTestSuite) TestGetScopeImplies() {
+	err := oauth.SeedDefaultScopes(suite.db, []oauth.ScopeDef{
+		{Scope: "test_read"},
+		{Scope: "test_list"},
+		{Scope: "test_write", Implies: []string{"test_read", "test_list"}},
+		{Scope: "test_admin", Implies: []string{"test_write"}},
+	})
+	assert.NoError(suite.T(), err)
+	defer suite.db.Unscoped().Where("scope LIKE ?", "test_%").Delete(new(models.OauthScope))
+
+	// Implied scopes are added after the requested ones
+	scope, err := suite.service.GetScope("test_admin")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "test_admin test_write test_list test_read", scope)
+	scope, err = suite.service.GetScope("test_read test_write")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "test_read test_write test_list", scope)
+
+	// Clients allowed a scope are allowed the scopes it implies
+	defer suite.allowClientScope(suite.clients[0], "test_write")()
+	_, err = suite.service.GrantAccessToken(suite.clients[0], nil, 3600, "test_write test_read test_list")
+	assert.NoError(suite.T(), err)
+	_, err = suite.service.GrantAccessToken(suite.clients[0], nil, 3600, "test_admin")
+	assert.Equal(suite.T(), oauth.ErrInvalidScope, err)
+
+	// Narrowing a scope only keeps the implied scopes granted
+	refreshToken, err := suite.service.CreateRefreshToken(suite.clients[0], suite.users[1], 3600, "test_write test_read")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
+	r.PostForm = url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken.Token},
+		"scope":         {"test_write"},
+	}
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	if assert.Equal(suite.T(), 200, w.Code) {
+		resp := new(oauth.AccessTokenResponse)
+		assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), resp))
+		assert.Equal(suite.T(), "test_write test_read", resp.Scope)
+	}
+}
+
 func (suite *OauthTestSuite) TestScopeExists() {
 	assert.True(suite.T(), suite.service.ScopeExists("read read_write"))
 
 	assert.False(suite.T(), suite.service.ScopeExists("read_write bogus"))
+
+	assert.False(suite.T(), suite.service.ScopeExists(""))
+}
+
+func (suite *OauthTestSuite) TestGetScopeAudience() {
+	err := oauth.SeedDefaultScopes(suite.db, []oauth.ScopeDef{
+		{Scope: "test_payments:write", Audience: "payments"},
+		{Scope: "test_orders:write", Audience: "orders"},
+	})
+	assert.NoError(suite.T(), err)
+	defer suite.db.Unscoped().Where("scope LIKE ?", "test_%").Delete(new(models.OauthScope))
+
+	assert.Equal(suite.T(), "", suite.service.GetScopeAudience("read read_write"))
+	assert.Equal(suite.T(), "payments", suite.service.GetScopeAudience("read test_payments:write"))
+	assert.Equal(suite.T(), "orders payments", suite.service.GetScopeAudience("test_payments:write test_orders:write"))
 }
@@ -8,18 +8,41 @@ import (
 
 // Service struct keeps objects to avoid passing them around
 type Service struct {
-	cnf          *config.Config
-	db           *gorm.DB
-	allowedRoles []string
+	cnf               *config.Config
+	db                *gorm.DB
+	allowedRoles      []string
+	validationCache   ValidationCache
+	transformers      []ResponseTransformer
+	claimsProviders   []TokenClaimsProvider
+	scopeCache        *scopeCache
+	grantHandlers     map[string]GrantHandler
+	backchannelHook   BackchannelAuthenticationHook
+	mailer            Mailer
+	userAuthenticator UserAuthenticator
+	clientAuthMethods []clientAuthMethod
+	tokenGenerator    TokenGenerator
+	tokenRateLimiter  *tokenRateLimiter
+	federationCache   *federationCache
+	stopCleanup       chan struct{}
+	// inTx is set on copies of the service running in a transaction
+	inTx bool
 }
 
 // NewService returns a new Service instance
 func NewService(cnf *config.Config, db *gorm.DB) *Service {
-	return &Service{
-		cnf:          cnf,
-		db:           db,
-		allowedRoles: []string{roles.Superuser, roles.User},
+	s := &Service{
+		cnf:              cnf,
+		db:               db,
+		allowedRoles:     []string{roles.Superuser, roles.User},
+		validationCache:  NewMemoryValidationCache(cnf.Oauth.ValidationCacheMaxEntries),
+		scopeCache:       new(scopeCache),
+		grantHandlers:    make(map[string]GrantHandler),
+		tokenRateLimiter: newTokenRateLimiter(),
+		federationCache:  &federationCache{providers: make(map[string]*federationMetadata)},
 	}
+	s.registerBuiltinGrantHandlers()
+	s.registerBuiltinClientAuthenticators()
+	return s
 }
 
 // GetConfig returns config.Config instance
@@ -43,4 +66,26 @@ func (s *Service) IsRoleAllowed(role string) bool {
 }
 
 // Close stops any running services
-func (s *Service) Close() {}
+func (s *Service) Close() {
+	if s.stopCleanup != nil {
+		close(s.stopCleanup)
+		s.stopCleanup = nil
+	}
+}
+
+// withTx returns a copy of the service running its queries in the
+// transaction, so that several steps are committed or rolled back together
+func (s *Service) withTx(tx *gorm.DB) *Service {
+	txService := *s
+	txService.db = tx
+	txService.inTx = true
+	return &txService
+}
+
+// rollback rolls back a transaction the service began, one it runs in
+// is rolled back by whoever began it
+func (s *Service) rollback(tx *gorm.DB) {
+	if !s.inTx {
+		tx.Rollback()
+	}
+}
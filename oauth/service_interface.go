@@ -1,6 +1,9 @@
 package oauth
 
 import (
+	"context"
+	"net/url"
+
 	"github.com/RichardKnop/go-oauth2-server/config"
 	"github.com/RichardKnop/go-oauth2-server/models"
 	"github.com/RichardKnop/go-oauth2-server/session"
@@ -16,33 +19,143 @@ type ServiceInterface interface {
 	RestrictToRoles(allowedRoles ...string)
 	IsRoleAllowed(role string) bool
 	FindRoleByID(id string) (*models.OauthRole, error)
+	GetRoleScopes(role *models.OauthRole) ([]string, error)
+	SetRoleScopes(role *models.OauthRole, scopes []string) error
+	GetUserRoles(user *models.OauthUser) ([]string, error)
+	SetUserRoles(user *models.OauthUser, roleIDs []string) error
+	GetUserAttributes(user *models.OauthUser) (map[string]interface{}, error)
+	SetUserAttributes(user *models.OauthUser, attributes map[string]interface{}) error
 	GetRoutes() []routes.Route
 	RegisterRoutes(router *mux.Router, prefix string)
 	ClientExists(clientID string) bool
 	FindClientByClientID(clientID string) (*models.OauthClient, error)
+	GetClient(clientID string) (*models.OauthClient, error)
+	ListClients(offset, limit int) ([]*models.OauthClient, int, error)
 	CreateClient(clientID, secret, redirectURI string) (*models.OauthClient, error)
 	CreateClientTx(tx *gorm.DB, clientID, secret, redirectURI string) (*models.OauthClient, error)
+	ValidateClientSecret(secret string) error
 	AuthClient(clientID, secret string) (*models.OauthClient, error)
+	CreateClientWithMetadata(clientID string, metadata *ClientMetadata) (*models.OauthClient, string, error)
+	UpdateClient(client *models.OauthClient, metadata *ClientMetadata) error
+	RotateClientSecret(client *models.OauthClient) (string, error)
+	AddSecondaryClientSecret(client *models.OauthClient) (string, error)
+	PromoteSecondaryClientSecret(client *models.OauthClient, gracePeriod int) error
+	ExpireSecondaryClientSecret(client *models.OauthClient) error
+	SetClientDisabled(client *models.OauthClient, disabled bool) error
+	SetClientType(client *models.OauthClient, clientType string) error
+	SetClientFirstParty(client *models.OauthClient, firstParty bool) error
+	SetClientTokenRateLimit(client *models.OauthClient, limit int) error
+	SetClientSubjectType(client *models.OauthClient, subjectType, sectorIdentifierURI string) error
+	DeleteClient(client *models.OauthClient) error
+	RegisterClient(metadata *ClientMetadata) (*ClientRegistrationResponse, error)
+	UpdateRegisteredClient(client *models.OauthClient, metadata *ClientMetadata, secret string) (*ClientRegistrationResponse, error)
+	DeleteRegisteredClient(client *models.OauthClient) error
+	FindRegisteredClient(clientID, registrationAccessToken string) (*models.OauthClient, error)
+	ValidateRedirectURI(client *models.OauthClient, redirectURI string) error
+	GetRedirectURIs(client *models.OauthClient) ([]string, error)
+	AddRedirectURI(client *models.OauthClient, redirectURI string) (*models.OauthRedirectURI, error)
+	RemoveRedirectURI(client *models.OauthClient, redirectURI string) error
 	UserExists(username string) bool
 	FindUserByUsername(username string) (*models.OauthUser, error)
+	FindUserByID(id string) (*models.OauthUser, error)
 	CreateUser(roleID, username, password string) (*models.OauthUser, error)
+	RegisterUser(ctx context.Context, client *models.OauthClient, request *UserRegistrationRequest) (*UserRegistrationResponse, error)
+	SendEmailVerification(ctx context.Context, user *models.OauthUser) error
+	RequestEmailVerification(ctx context.Context, username string) error
+	VerifyEmail(token string) error
 	CreateUserTx(tx *gorm.DB, roleID, username, password string) (*models.OauthUser, error)
 	SetPassword(user *models.OauthUser, password string) error
 	SetPasswordTx(tx *gorm.DB, user *models.OauthUser, password string) error
 	UpdateUsername(user *models.OauthUser, username string) error
 	UpdateUsernameTx(db *gorm.DB, user *models.OauthUser, username string) error
 	AuthUser(username, thePassword string) (*models.OauthUser, error)
+	AuthLogin(username, password, acrValues, otp, ipAddress string) (*models.OauthUser, string, error)
+	UnlockUser(user *models.OauthUser) error
+	ListUsers(username, externalID string, offset, limit int) ([]*models.OauthUser, int, error)
+	SetUserExternalID(user *models.OauthUser, externalID string) error
+	SetUserDisabled(user *models.OauthUser, disabled bool) error
+	DeleteUser(user *models.OauthUser) error
+	FindGroupByID(id string) (*models.OauthGroup, error)
+	ListGroups(displayName string, offset, limit int) ([]*models.OauthGroup, int, error)
+	CreateGroup(displayName, externalID string, userIDs []string) (*models.OauthGroup, error)
+	UpdateGroup(group *models.OauthGroup, displayName, externalID string) error
+	DeleteGroup(group *models.OauthGroup) error
+	ListGroupMembers(group *models.OauthGroup) ([]*models.OauthUser, error)
+	ListUserGroups(user *models.OauthUser) ([]*models.OauthGroup, error)
+	AddGroupMembers(group *models.OauthGroup, userIDs []string) error
+	RemoveGroupMembers(group *models.OauthGroup, userIDs []string) error
+	SetGroupMembers(group *models.OauthGroup, userIDs []string) error
+	AuthenticateAcr(user *models.OauthUser, acrValues, otp string) (string, error)
 	GetScope(requestedScope string) (string, error)
 	GetDefaultScope() string
 	ScopeExists(requestedScope string) bool
+	InvalidateScopeCache()
+	SeedScopes(scopes []ScopeDef) error
+	HasConsent(client *models.OauthClient, user *models.OauthUser, scope string) bool
+	SaveConsent(client *models.OauthClient, user *models.OauthUser, scope string) error
+	CreateAPIKey(client *models.OauthClient, user *models.OauthUser, scope string) (string, error)
+	RevokeAPIKey(key string) error
 	Login(client *models.OauthClient, user *models.OauthUser, scope string) (*models.OauthAccessToken, *models.OauthRefreshToken, error)
-	GrantAuthorizationCode(client *models.OauthClient, user *models.OauthUser, expiresIn int, redirectURI, scope string) (*models.OauthAuthorizationCode, error)
+	GrantAuthorizationCode(client *models.OauthClient, user *models.OauthUser, expiresIn int, redirectURI, scope, codeChallenge, codeChallengeMethod, authorizationDetails, nonce string) (*models.OauthAuthorizationCode, error)
+	GrantHandoffCode(client *models.OauthClient, user *models.OauthUser, scope string) (*models.OauthAuthorizationCode, error)
+	GrantDeviceSecret(client *models.OauthClient, user *models.OauthUser, scope string) (*models.OauthDeviceSecret, error)
+	GrantDeviceCode(client *models.OauthClient, scope string) (*models.OauthDeviceCode, error)
+	FindDeviceCodeByUserCode(userCode string) (*models.OauthDeviceCode, error)
+	AuthorizeDeviceCode(deviceCode *models.OauthDeviceCode, user *models.OauthUser) error
+	DenyDeviceCode(deviceCode *models.OauthDeviceCode) error
+	SetBackchannelAuthenticationHook(hook BackchannelAuthenticationHook)
+	SetMailer(mailer Mailer)
+	SetUserAuthenticator(authenticator UserAuthenticator)
+	BeginFederatedLogin(providerName string, query url.Values) (string, string, error)
+	FinishFederatedLogin(providerName, state, code string, currentUser *models.OauthUser) (*models.OauthUser, url.Values, error)
+	RequestPasswordReset(ctx context.Context, username string) error
+	ResetPassword(token, password string) error
+	SetBackchannelNotificationEndpoint(client *models.OauthClient, endpoint string) error
+	FindBackchannelRequest(authReqID string) (*models.OauthBackchannelRequest, error)
+	AuthorizeBackchannelRequest(request *models.OauthBackchannelRequest) error
+	DenyBackchannelRequest(request *models.OauthBackchannelRequest) error
+	PushAuthorizationRequest(client *models.OauthClient, parameters url.Values) (*models.OauthPushedRequest, error)
+	FindPushedRequest(client *models.OauthClient, requestURI string) (url.Values, error)
+	DeletePushedRequest(client *models.OauthClient, requestURI string) error
+	SetAllowedGrantTypes(client *models.OauthClient, grantTypes []string) error
+	GetClientScopes(client *models.OauthClient) ([]string, error)
+	SetClientScopes(client *models.OauthClient, scopes []string) error
+	SetClientTokenLifetimes(client *models.OauthClient, accessTokenLifetime, refreshTokenLifetime int) error
+	SetAllowedResponseTypes(client *models.OauthClient, responseTypes []string) error
+	SetTLSClientAuthSubjectDN(client *models.OauthClient, subjectDN string) error
+	SetTLSClientAuthSAN(client *models.OauthClient, san string) error
+	SetTokenFormat(client *models.OauthClient, format string) error
+	AddAssertionKey(client *models.OauthClient, issuer, algorithm, publicKey string) (*models.OauthAssertionKey, error)
+	AddSAMLIdentityProvider(client *models.OauthClient, metadata []byte) (*models.OauthSAMLIdentityProvider, error)
+	GenerateRecoveryCodes(user *models.OauthUser) ([]string, error)
+	BeginTOTPEnrollment(user *models.OauthUser) (*TOTPEnrollmentResponse, error)
+	ConfirmTOTPEnrollment(user *models.OauthUser, secret, otp string) ([]string, error)
+	DisableTOTP(user *models.OauthUser) error
+	BeginWebAuthnRegistration(user *models.OauthUser) (*WebAuthnCreationOptions, error)
+	FinishWebAuthnRegistration(user *models.OauthUser, request *WebAuthnRegistrationRequest) (*models.OauthWebAuthnCredential, error)
+	BeginWebAuthnLogin(username string) (*WebAuthnRequestOptions, error)
 	GrantAccessToken(client *models.OauthClient, user *models.OauthUser, expiresIn int, scope string) (*models.OauthAccessToken, error)
-	GetOrCreateRefreshToken(client *models.OauthClient, user *models.OauthUser, expiresIn int, scope string) (*models.OauthRefreshToken, error)
+	EncodeAccessToken(accessToken *models.OauthAccessToken) (string, error)
+	GrantIDToken(client *models.OauthClient, user *models.OauthUser) (string, error)
+	GrantFrontChannelIDToken(client *models.OauthClient, user *models.OauthUser, nonce, code, accessToken string) (string, error)
+	CreateRefreshToken(client *models.OauthClient, user *models.OauthUser, expiresIn int, scope string) (*models.OauthRefreshToken, error)
 	GetValidRefreshToken(token string, client *models.OauthClient) (*models.OauthRefreshToken, error)
 	Authenticate(token string) (*models.OauthAccessToken, error)
 	NewIntrospectResponseFromAccessToken(accessToken *models.OauthAccessToken) (*IntrospectResponse, error)
 	NewIntrospectResponseFromRefreshToken(refreshToken *models.OauthRefreshToken) (*IntrospectResponse, error)
 	ClearUserTokens(userSession *session.UserSession)
+	StartLoginSession(user *models.OauthUser) (*models.OauthSession, error)
+	GetLoginSession(sessionID string) (*models.OauthSession, error)
+	EndLoginSession(sessionID string) error
+	GetTokenLineage(token string) (*TokenLineage, error)
+	RevokeTokenLineage(token string) (*TokenLineage, error)
+	UseValidationCache(cache ValidationCache)
+	UseTokenGenerator(generator TokenGenerator)
+	AddResponseTransformer(transformer ResponseTransformer)
+	AddTokenClaimsProvider(provider TokenClaimsProvider)
+	RegisterGrantHandler(grantType string, handler GrantHandler)
+	ValidationCacheStats() ValidationCacheStats
+	PurgeExpiredTokens() (int64, error)
+	StartTokenCleanup()
 	Close()
 }
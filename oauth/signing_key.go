@@ -0,0 +1,161 @@
+package oauth
+
+import (
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util/jwt"
+)
+
+// IsRotatableAlgorithm returns true if the server can generate
+// keys for the algorithm, HS256 secrets have to be shared instead
+func IsRotatableAlgorithm(algorithm string) bool {
+	switch algorithm {
+	case jwt.RS256, jwt.RS384, jwt.RS512, jwt.ES256:
+		return true
+	}
+	return false
+}
+
+// getSigningKey returns the key used to sign JSON Web Tokens,
+// the configured one unless the server rotates its own keys
+func (s *Service) getSigningKey() (jwt.Key, error) {
+	if s.cnf.JWT.KeyRotationInterval <= 0 {
+		return NewSigningKey(&s.cnf.JWT)
+	}
+
+	// Sign with the newest key, a new one is generated once it expires
+	signingKey := new(models.OauthSigningKey)
+	notFound := s.db.Where("algorithm = ? AND expires_at > ?", s.cnf.JWT.Algorithm, time.Now().UTC()).
+		Order("expires_at desc").First(signingKey).RecordNotFound()
+	if notFound {
+		var err error
+		if signingKey, err = s.rotateSigningKey(); err != nil {
+			return nil, err
+		}
+	}
+
+	return jwt.NewKey(signingKey.Algorithm, signingKey.KeyID, nil, []byte(signingKey.PrivateKey))
+}
+
+// rotateSigningKey generates a new signing key, deleting keys which
+// expired long enough ago that no token they have signed is still valid
+func (s *Service) rotateSigningKey() (*models.OauthSigningKey, error) {
+	privateKey, err := jwt.GenerateKey(s.cnf.JWT.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	key, err := jwt.NewKey(s.cnf.JWT.Algorithm, "", nil, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	signingKey := models.NewOauthSigningKey(
+		key.KeyID(),
+		key.Algorithm(),
+		string(privateKey),
+		time.Duration(s.cnf.JWT.KeyRotationInterval)*time.Second,
+	)
+
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	err = tx.Unscoped().Where("expires_at <= ?", s.getSigningKeyRetirement()).
+		Delete(new(models.OauthSigningKey)).Error
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+	if err := tx.Create(signingKey).Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return nil, err
+	}
+
+	return signingKey, nil
+}
+
+// getSigningKeyRetirement returns the time keys must have expired
+// before to have outlived every token they have signed
+func (s *Service) getSigningKeyRetirement() time.Time {
+	return time.Now().UTC().Add(-time.Duration(s.getMaxAccessTokenLifetime()) * time.Second)
+}
+
+// getVerificationKeys returns the keys tokens signed by the server can be
+// verified with, the configured key along with the generated keys which
+// signed tokens that may not have expired yet
+func (s *Service) getVerificationKeys() ([]jwt.Key, error) {
+	var keys []jwt.Key
+
+	// The configured key is optional if keys are rotated
+	if key, err := NewSigningKey(&s.cnf.JWT); err == nil {
+		keys = append(keys, key)
+	} else if s.cnf.JWT.KeyRotationInterval <= 0 {
+		return nil, err
+	}
+
+	var signingKeys []*models.OauthSigningKey
+	err := s.db.Where("expires_at > ?", s.getSigningKeyRetirement()).
+		Order("expires_at desc").Find(&signingKeys).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, signingKey := range signingKeys {
+		key, err := jwt.NewKey(signingKey.Algorithm, signingKey.KeyID, nil, []byte(signingKey.PrivateKey))
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// getVerificationKey returns the key the token claims to be signed with,
+// nil if the server does not know the key
+func (s *Service) getVerificationKey(token string) (jwt.Key, error) {
+	header, err := jwt.ParseHeader(token)
+	if err != nil {
+		return nil, nil
+	}
+
+	keys, err := s.getVerificationKeys()
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if key.KeyID() == header.KeyID && key.Algorithm() == header.Algorithm {
+			return key, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// getJSONWebKeySet returns the public keys resource servers
+// and clients can verify tokens signed by the server with
+func (s *Service) getJSONWebKeySet() (*jwt.JSONWebKeySet, error) {
+	keys, err := s.getVerificationKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	// Shared secrets are never published
+	keySet := &jwt.JSONWebKeySet{Keys: []*jwt.JSONWebKey{}}
+	for _, key := range keys {
+		jwk, err := jwt.NewJSONWebKey(key)
+		if err == jwt.ErrUnsupportedPublicKey {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		keySet.Keys = append(keySet.Keys, jwk)
+	}
+
+	return keySet, nil
+}
@@ -0,0 +1,139 @@
+package oauth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/util/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestJWKSWithoutPublicKeys() {
+	// The HS256 secret is never published
+	keySet := suite.getJWKS()
+	assert.Empty(suite.T(), keySet.Keys)
+}
+
+func (suite *OauthTestSuite) TestJWKS() {
+	privateKey, err := jwt.GenerateKey(jwt.ES256)
+	assert.NoError(suite.T(), err)
+	suite.cnf.JWT.Algorithm = jwt.ES256
+	suite.cnf.JWT.PrivateKey = string(privateKey)
+	defer func() {
+		suite.cnf.JWT.Algorithm = ""
+		suite.cnf.JWT.PrivateKey = ""
+	}()
+
+	assert.Equal(suite.T(), suite.cnf.JWT.Issuer+"/.well-known/jwks.json", suite.getMetadata().JWKSURI)
+
+	// ID tokens can be verified with the published key they name
+	idToken, err := suite.service.GrantIDToken(suite.clients[0], suite.users[0])
+	assert.NoError(suite.T(), err)
+	header, err := jwt.ParseHeader(idToken)
+	assert.NoError(suite.T(), err)
+
+	keySet := suite.getJWKS()
+	if assert.Len(suite.T(), keySet.Keys, 1) {
+		assert.Equal(suite.T(), "EC", keySet.Keys[0].KeyType)
+		assert.Equal(suite.T(), header.KeyID, keySet.Keys[0].KeyID)
+		suite.assertVerifiedWithJWKS(keySet, idToken)
+	}
+}
+
+func (suite *OauthTestSuite) TestSigningKeyRotation() {
+	suite.cnf.Oauth.AccessTokenFormat = config.AccessTokenFormatJWT
+	suite.cnf.JWT.Algorithm = jwt.ES256
+	suite.cnf.JWT.KeyRotationInterval = 3600
+	defer func() {
+		suite.cnf.Oauth.AccessTokenFormat = ""
+		suite.cnf.JWT.Algorithm = ""
+		suite.cnf.JWT.KeyRotationInterval = 0
+	}()
+
+	// The first token makes the server generate a key
+	first := suite.grantJWTAccessToken()
+	assert.Len(suite.T(), suite.getJWKS().Keys, 1)
+
+	// The key keeps signing tokens until it expires
+	assert.Equal(suite.T(), suite.getKeyID(first), suite.getKeyID(suite.grantJWTAccessToken()))
+	suite.expireSigningKeys(time.Now())
+	second := suite.grantJWTAccessToken()
+	assert.NotEqual(suite.T(), suite.getKeyID(first), suite.getKeyID(second))
+
+	// The expired key is still published as the first token is still valid
+	keySet := suite.getJWKS()
+	assert.Len(suite.T(), keySet.Keys, 2)
+	for _, token := range []string{first, second} {
+		suite.assertVerifiedWithJWKS(keySet, token)
+		_, err := suite.service.Authenticate(token)
+		assert.NoError(suite.T(), err)
+	}
+
+	// Keys are deleted once every token they have signed has expired
+	lifetime := time.Duration(suite.cnf.Oauth.AccessTokenLifetime) * time.Second
+	suite.expireSigningKeys(time.Now().Add(-lifetime))
+	third := suite.grantJWTAccessToken()
+	keySet = suite.getJWKS()
+	assert.Len(suite.T(), keySet.Keys, 1)
+	suite.assertVerifiedWithJWKS(keySet, third)
+	_, err := suite.service.Authenticate(first)
+	assert.Equal(suite.T(), oauth.ErrAccessTokenNotFound, err)
+}
+
+func (suite *OauthTestSuite) getJWKS() *jwt.JSONWebKeySet {
+	r, err := http.NewRequest("GET", "http://1.2.3.4/.well-known/jwks.json", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	assert.Equal(suite.T(), 200, w.Code)
+
+	keySet := new(jwt.JSONWebKeySet)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), keySet))
+	return keySet
+}
+
+func (suite *OauthTestSuite) grantJWTAccessToken() string {
+	accessToken, err := suite.service.GrantAccessToken(suite.clients[0], suite.users[0], 3600, "read")
+	assert.NoError(suite.T(), err)
+	encoded, err := suite.service.EncodeAccessToken(accessToken)
+	assert.NoError(suite.T(), err)
+	return encoded
+}
+
+func (suite *OauthTestSuite) getKeyID(token string) string {
+	header, err := jwt.ParseHeader(token)
+	assert.NoError(suite.T(), err)
+	return header.KeyID
+}
+
+// expireSigningKeys makes all keys generated so far expire at the given time
+func (suite *OauthTestSuite) expireSigningKeys(expiresAt time.Time) {
+	err := suite.db.Model(new(models.OauthSigningKey)).
+		Where("expires_at > ?", expiresAt).
+		UpdateColumn("expires_at", expiresAt.UTC()).Error
+	assert.NoError(suite.T(), err)
+}
+
+// assertVerifiedWithJWKS checks the token verifies with the key it names
+func (suite *OauthTestSuite) assertVerifiedWithJWKS(keySet *jwt.JSONWebKeySet, token string) {
+	header, err := jwt.ParseHeader(token)
+	assert.NoError(suite.T(), err)
+	for _, key := range keySet.Keys {
+		if key.KeyID != header.KeyID {
+			continue
+		}
+		verifier, err := key.Verifier(key.Algorithm)
+		if assert.NoError(suite.T(), err) {
+			_, err = jwt.Parse(token, verifier)
+			assert.NoError(suite.T(), err)
+		}
+		return
+	}
+	assert.Fail(suite.T(), "Key not published", header.KeyID)
+}
@@ -0,0 +1,76 @@
+package oauth
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+)
+
+const (
+	// SubjectTypePublic clients identify users by the same subject
+	// identifier as every other client
+	SubjectTypePublic = "public"
+	// SubjectTypePairwise clients identify users by subject identifiers
+	// of their own, so clients cannot correlate users between them. See
+	// https://openid.net/specs/openid-connect-core-1_0.html#PairwiseAlg
+	SubjectTypePairwise = "pairwise"
+)
+
+var (
+	// ErrInvalidSubjectType ...
+	ErrInvalidSubjectType = errors.New("Invalid subject type")
+	// ErrInvalidSectorIdentifierURI ...
+	ErrInvalidSectorIdentifierURI = errors.New("Invalid sector identifier URI")
+)
+
+// SetClientSubjectType makes the client identify users by either public or
+// pairwise subject identifiers. Pairwise clients registering the same sector
+// identifier URI share subject identifiers, passing an empty URI makes the
+// client a sector of its own.
+func (s *Service) SetClientSubjectType(client *models.OauthClient, subjectType, sectorIdentifierURI string) error {
+	if subjectType != SubjectTypePublic && subjectType != SubjectTypePairwise {
+		return ErrInvalidSubjectType
+	}
+	if subjectType == SubjectTypePublic {
+		sectorIdentifierURI = ""
+	}
+	if sectorIdentifierURI != "" {
+		sectorURI, err := url.Parse(sectorIdentifierURI)
+		if err != nil || sectorURI.Scheme != "https" || sectorURI.Host == "" {
+			return ErrInvalidSectorIdentifierURI
+		}
+	}
+
+	err := s.db.Model(new(models.OauthClient)).Where("id = ?", client.ID).
+		UpdateColumns(map[string]interface{}{
+			"subject_type":          subjectType,
+			"sector_identifier_uri": sectorIdentifierURI,
+		}).Error
+	if err != nil {
+		return err
+	}
+	client.SubjectType = subjectType
+	client.SectorIdentifierURI = sectorIdentifierURI
+	return nil
+}
+
+// subjectIdentifier returns the sub claim identifying the user to the client,
+// pairwise ones are derived from the sector of the client, the user ID and
+// the configured salt so they stay the same for every token
+func (s *Service) subjectIdentifier(client *models.OauthClient, userID string) string {
+	if client == nil || client.SubjectType != SubjectTypePairwise {
+		return userID
+	}
+
+	// The sector is the host of the sector identifier URI
+	sector := client.Key
+	if sectorURI, err := url.Parse(client.SectorIdentifierURI); err == nil && sectorURI.Host != "" {
+		sector = sectorURI.Host
+	}
+
+	sum := sha256.Sum256([]byte(sector + userID + s.cnf.Oauth.PairwiseSubjectSalt))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,73 @@
+package oauth_test
+
+import (
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/RichardKnop/go-oauth2-server/util/jwt"
+	"github.com/RichardKnop/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestSetClientSubjectType() {
+	err := suite.service.SetClientSubjectType(suite.clients[0], "bogus", "")
+	assert.Equal(suite.T(), oauth.ErrInvalidSubjectType, err)
+
+	err = suite.service.SetClientSubjectType(suite.clients[0], oauth.SubjectTypePairwise, "http://example.com/sector.json")
+	assert.Equal(suite.T(), oauth.ErrInvalidSectorIdentifierURI, err)
+	assert.Equal(suite.T(), oauth.SubjectTypePublic, suite.clients[0].SubjectType)
+}
+
+func (suite *OauthTestSuite) TestPairwiseSubjectIdentifiers() {
+	user := suite.users[0]
+	defer suite.service.SetClientSubjectType(suite.clients[0], oauth.SubjectTypePublic, "")
+	defer suite.service.SetClientSubjectType(suite.clients[1], oauth.SubjectTypePublic, "")
+
+	// Public clients are told the user ID
+	assert.Equal(suite.T(), user.ID, suite.idTokenSubject(suite.clients[0], user))
+
+	// Pairwise clients are told a stable subject identifier of their own
+	assert.NoError(suite.T(), suite.service.SetClientSubjectType(suite.clients[0], oauth.SubjectTypePairwise, ""))
+	assert.NoError(suite.T(), suite.service.SetClientSubjectType(suite.clients[1], oauth.SubjectTypePairwise, ""))
+	subject := suite.idTokenSubject(suite.clients[0], user)
+	assert.NotEqual(suite.T(), user.ID, subject)
+	assert.Equal(suite.T(), subject, suite.idTokenSubject(suite.clients[0], user))
+	assert.NotEqual(suite.T(), subject, suite.idTokenSubject(suite.clients[1], user))
+
+	// Introspection responses use the same subject identifier
+	accessToken := &models.OauthAccessToken{
+		MyGormModel: models.MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		Token:     "test_token_pairwise_subject",
+		ExpiresAt: time.Now().UTC().Add(+10 * time.Second),
+		ClientID:  util.StringOrNull(string(suite.clients[0].ID)),
+		UserID:    util.StringOrNull(user.ID),
+		Scope:     "read_write",
+	}
+	introspectResponse, err := suite.service.NewIntrospectResponseFromAccessToken(accessToken)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), subject, introspectResponse.Subject)
+
+	// Clients of the same sector share subject identifiers
+	sectorIdentifierURI := "https://example.com/sector.json"
+	assert.NoError(suite.T(), suite.service.SetClientSubjectType(suite.clients[0], oauth.SubjectTypePairwise, sectorIdentifierURI))
+	assert.NoError(suite.T(), suite.service.SetClientSubjectType(suite.clients[1], oauth.SubjectTypePairwise, sectorIdentifierURI))
+	assert.Equal(
+		suite.T(),
+		suite.idTokenSubject(suite.clients[0], user),
+		suite.idTokenSubject(suite.clients[1], user),
+	)
+}
+
+func (suite *OauthTestSuite) idTokenSubject(client *models.OauthClient, user *models.OauthUser) string {
+	idToken, err := suite.service.GrantIDToken(client, user)
+	assert.NoError(suite.T(), err)
+	claims, err := jwt.ParseUnverified(idToken)
+	assert.NoError(suite.T(), err)
+	subject, _ := claims.String("sub")
+	return subject
+}
@@ -1,6 +1,9 @@
 package oauth_test
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"testing"
 
@@ -8,12 +11,18 @@ import (
 	"github.com/RichardKnop/go-oauth2-server/log"
 	"github.com/RichardKnop/go-oauth2-server/models"
 	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
 	"github.com/RichardKnop/go-oauth2-server/test-util"
 	"github.com/gorilla/mux"
 	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
 
+const (
+	testTokenURL = "http://1.2.3.4/v1/oauth/tokens"
+)
+
 var (
 	testDbUser = "go_oauth2_server"
 	testDbName = "go_oauth2_server_oauth_test"
@@ -104,8 +113,35 @@ func (suite *OauthTestSuite) TearDownTest() {
 	suite.db.Unscoped().Delete(new(models.OauthAuthorizationCode))
 	suite.db.Unscoped().Delete(new(models.OauthRefreshToken))
 	suite.db.Unscoped().Delete(new(models.OauthAccessToken))
+	suite.db.Unscoped().Delete(new(models.OauthDeviceSecret))
+	suite.db.Unscoped().Delete(new(models.OauthRecoveryCode))
+	suite.db.Unscoped().Delete(new(models.OauthSession))
+	suite.db.Unscoped().Delete(new(models.OauthConsent))
+	suite.db.Unscoped().Delete(new(models.OauthAPIKey))
+	suite.db.Unscoped().Delete(new(models.OauthDeviceCode))
+	suite.db.Unscoped().Delete(new(models.OauthAssertionKey))
+	suite.db.Unscoped().Delete(new(models.OauthSAMLIdentityProvider))
+	suite.db.Unscoped().Delete(new(models.OauthSigningKey))
+	suite.db.Unscoped().Delete(new(models.OauthPushedRequest))
+	suite.db.Unscoped().Delete(new(models.OauthBackchannelRequest))
+	suite.db.Unscoped().Delete(new(models.OauthRedirectURI))
+	suite.db.Unscoped().Delete(new(models.OauthClientScope))
+	suite.db.Unscoped().Delete(new(models.OauthClientAssertion))
+	suite.db.Unscoped().Delete(new(models.OauthPasswordReset))
+	suite.db.Unscoped().Delete(new(models.OauthEmailVerification))
+	suite.db.Unscoped().Delete(new(models.OauthWebAuthnChallenge))
+	suite.db.Unscoped().Delete(new(models.OauthWebAuthnCredential))
+	suite.db.Unscoped().Delete(new(models.OauthLoginFailure))
+	suite.db.Unscoped().Delete(new(models.OauthFederatedIdentity))
+	suite.db.Unscoped().Delete(new(models.OauthFederationState))
+	suite.db.Unscoped().Delete(new(models.OauthGroupMember))
+	suite.db.Unscoped().Delete(new(models.OauthGroup))
+	suite.db.Unscoped().Delete(new(models.OauthRoleScope))
+	suite.db.Unscoped().Delete(new(models.OauthUserRole))
+	suite.db.Unscoped().Delete(new(models.OauthUserAttribute))
 	suite.db.Unscoped().Not("id", []string{"1", "2"}).Delete(new(models.OauthUser))
 	suite.db.Unscoped().Not("id", []string{"1", "2", "3"}).Delete(new(models.OauthClient))
+	suite.db.Unscoped().Not("id", []string{roles.Superuser, roles.User}).Delete(new(models.OauthRole))
 }
 
 // TestOauthTestSuite ...
@@ -114,3 +150,30 @@ func (suite *OauthTestSuite) TearDownTest() {
 func TestOauthTestSuite(t *testing.T) {
 	suite.Run(t, new(OauthTestSuite))
 }
+
+// newTokenRequest returns a request posting the form to the token endpoint,
+// authenticated as test_client_1 unless the headers authenticate another client
+func (suite *OauthTestSuite) newTokenRequest(form url.Values, header http.Header) *http.Request {
+	r, err := http.NewRequest("POST", testTokenURL, nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
+	for name, values := range header {
+		r.Header[http.CanonicalHeaderKey(name)] = values
+	}
+	r.PostForm = form
+	return r
+}
+
+// tokenRequest posts the form with the headers to the token endpoint
+func (suite *OauthTestSuite) tokenRequest(form url.Values, header http.Header) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, suite.newTokenRequest(form, header))
+	return w
+}
+
+// clientAuth returns headers authenticating the client with its test secret
+func clientAuth(clientID string) http.Header {
+	r := &http.Request{Header: make(http.Header)}
+	r.SetBasicAuth(clientID, "test_secret")
+	return r.Header
+}
@@ -0,0 +1,115 @@
+package oauth
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+)
+
+const (
+	// tokenRateLimitWindow is the window client token requests are counted in
+	tokenRateLimitWindow = time.Minute
+)
+
+var (
+	// ErrSlowDown ...
+	ErrSlowDown = errors.New("Too many tokens issued for this user, slow down")
+	// ErrClientRateLimited ...
+	ErrClientRateLimited = errors.New("Too many token requests from this client, slow down")
+	// ErrInvalidRateLimit ...
+	ErrInvalidRateLimit = errors.New("Invalid rate limit")
+)
+
+// tokenRateLimiter counts the token requests of each client in fixed one
+// minute windows. The counts are kept in memory, so each server enforces
+// the limit on its own.
+type tokenRateLimiter struct {
+	mu      sync.Mutex
+	windows map[string]*rateLimitWindow
+}
+
+type rateLimitWindow struct {
+	start time.Time
+	count int
+}
+
+func newTokenRateLimiter() *tokenRateLimiter {
+	return &tokenRateLimiter{windows: make(map[string]*rateLimitWindow)}
+}
+
+// allow counts a request of the client, over the limit it returns false
+// and how long the client has to wait until the next window
+func (l *tokenRateLimiter) allow(clientID string, limit int, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	window, ok := l.windows[clientID]
+	if !ok || now.Sub(window.start) >= tokenRateLimitWindow {
+		window = &rateLimitWindow{start: now}
+		l.windows[clientID] = window
+	}
+
+	if window.count >= limit {
+		return false, window.start.Add(tokenRateLimitWindow).Sub(now)
+	}
+	window.count++
+	return true, 0
+}
+
+// checkClientRateLimit returns ErrClientRateLimited and the number of seconds
+// to retry after once the client has sent more token requests this minute
+// than it is allowed to
+func (s *Service) checkClientRateLimit(client *models.OauthClient) (int, error) {
+	if client.TokenRateLimit <= 0 {
+		return 0, nil
+	}
+
+	allowed, wait := s.tokenRateLimiter.allow(string(client.ID), client.TokenRateLimit, time.Now())
+	if allowed {
+		return 0, nil
+	}
+	return int(math.Ceil(wait.Seconds())), ErrClientRateLimited
+}
+
+// SetClientTokenRateLimit caps the requests per minute the client can send
+// to the token endpoint, a limit of zero lifts the cap
+func (s *Service) SetClientTokenRateLimit(client *models.OauthClient, limit int) error {
+	if limit < 0 {
+		return ErrInvalidRateLimit
+	}
+
+	err := s.db.Model(new(models.OauthClient)).Where("id = ?", client.ID).
+		UpdateColumn("token_rate_limit", limit).Error
+	if err != nil {
+		return err
+	}
+	client.TokenRateLimit = limit
+	return nil
+}
+
+// checkUserTokenLimit returns ErrSlowDown once the configured number of
+// access tokens has been issued to the user within the window
+func (s *Service) checkUserTokenLimit(user *models.OauthUser) error {
+	limit, window := s.cnf.Oauth.UserTokenLimit, s.cnf.Oauth.UserTokenLimitWindow
+	if limit <= 0 || window <= 0 || user == nil {
+		return nil
+	}
+
+	// Count access tokens issued within the window, including revoked ones
+	var count int
+	since := time.Now().UTC().Add(-time.Duration(window) * time.Second)
+	err := s.db.Unscoped().Model(new(models.OauthAccessToken)).
+		Where("user_id = ? AND created_at > ?", user.ID, since).Count(&count).Error
+	if err != nil {
+		return err
+	}
+
+	if count >= limit {
+		return ErrSlowDown
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package oauth_test
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	"github.com/RichardKnop/go-oauth2-server/test-util"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestUserTokenLimit() {
+	suite.cnf.Oauth.UserTokenLimit = 3
+	suite.cnf.Oauth.UserTokenLimitWindow = 60
+	defer func() {
+		suite.cnf.Oauth.UserTokenLimit = 0
+		suite.cnf.Oauth.UserTokenLimitWindow = 0
+	}()
+
+	user, err := suite.service.CreateUser(roles.User, "test@user_throttled", "test_password")
+	assert.NoError(suite.T(), err, "Inserting test data failed")
+
+	// Tokens up to the limit are issued
+	for i := 0; i < 3; i++ {
+		w := suite.passwordGrantForThrottling("test@user_throttled")
+		assert.Equal(suite.T(), 200, w.Code)
+	}
+
+	// The next one is throttled
+	w := suite.passwordGrantForThrottling("test@user_throttled")
+	testutil.TestResponseForOauthError(suite.T(), w, "slow_down", oauth.ErrSlowDown.Error(), 429)
+
+	// Other users are not affected
+	w = suite.passwordGrantForThrottling("test@user")
+	assert.Equal(suite.T(), 200, w.Code)
+
+	// The limit resets once the window has passed
+	err = suite.db.Unscoped().Model(new(models.OauthAccessToken)).
+		Where("user_id = ?", user.ID).
+		UpdateColumn("created_at", time.Now().UTC().Add(-61*time.Second)).Error
+	assert.NoError(suite.T(), err)
+	w = suite.passwordGrantForThrottling("test@user_throttled")
+	assert.Equal(suite.T(), 200, w.Code)
+}
+
+func (suite *OauthTestSuite) passwordGrantForThrottling(username string) *httptest.ResponseRecorder {
+	return suite.tokenRequest(url.Values{
+		"grant_type": {"password"},
+		"username":   {username},
+		"password":   {"test_password"},
+	}, nil)
+}
+
+func (suite *OauthTestSuite) TestClientTokenRateLimit() {
+	assert.Equal(suite.T(), oauth.ErrInvalidRateLimit, suite.service.SetClientTokenRateLimit(suite.clients[0], -1))
+
+	err := suite.service.SetClientTokenRateLimit(suite.clients[0], 2)
+	assert.NoError(suite.T(), err)
+	defer suite.service.SetClientTokenRateLimit(suite.clients[0], 0)
+
+	credentials := url.Values{
+		"client_id":     {"test_client_1"},
+		"client_secret": {"test_secret"},
+	}
+
+	// Requests up to the limit are served
+	for i := 0; i < 2; i++ {
+		w := suite.clientCredentialsGrantWith(nil, credentials)
+		assert.Equal(suite.T(), 200, w.Code)
+	}
+
+	// The next one is throttled until the window is over
+	w := suite.clientCredentialsGrantWith(nil, credentials)
+	testutil.TestResponseForOauthError(suite.T(), w, "slow_down", oauth.ErrClientRateLimited.Error(), 429)
+	assert.NotEmpty(suite.T(), w.Header().Get("Retry-After"))
+
+	// Other clients are not affected
+	w = suite.clientCredentialsGrantWith(nil, url.Values{
+		"client_id":     {"test_client_2"},
+		"client_secret": {"test_secret"},
+	})
+	assert.Equal(suite.T(), 200, w.Code)
+}
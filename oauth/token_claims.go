@@ -0,0 +1,115 @@
+package oauth
+
+import (
+	"encoding/json"
+
+	"github.com/RichardKnop/go-oauth2-server/log"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util"
+)
+
+var (
+	// reservedTokenClaims cannot be set by token claims providers
+	reservedTokenClaims = []string{
+		"iss",
+		"sub",
+		"aud",
+		"exp",
+		"nbf",
+		"iat",
+		"jti",
+		"client_id",
+		"client_name",
+		"username",
+		"scope",
+		"acr",
+		"act",
+		"cnf",
+		"sid",
+		"auth_time",
+		"active",
+		"token_type",
+		"grant_type",
+		"authorization_details",
+	}
+)
+
+// TokenClaimsProvider returns custom claims, such as the tenant or plan of
+// the user, to add to an access token before it is stored. The claims are
+// included in JWT access tokens and introspection responses. User is nil
+// for client credentials tokens.
+type TokenClaimsProvider func(user *models.OauthUser, client *models.OauthClient, scope string) (map[string]interface{}, error)
+
+// AddTokenClaimsProvider registers a provider called for all access tokens
+func (s *Service) AddTokenClaimsProvider(provider TokenClaimsProvider) {
+	s.claimsProviders = append(s.claimsProviders, provider)
+}
+
+// getCustomClaims returns the JSON object of the claims the attributes of
+// the user are mapped to and of the claims of all providers, providers
+// override attributes and later providers the claims of earlier ones
+func (s *Service) getCustomClaims(user *models.OauthUser, client *models.OauthClient, scope string) (string, error) {
+	claims, err := s.getUserAttributeClaims(user, scope, releasedInAccessToken)
+	if err != nil {
+		return "", err
+	}
+	if claims == nil {
+		claims = make(map[string]interface{})
+	}
+
+	for _, provider := range s.claimsProviders {
+		provided, err := provider(user, client, scope)
+		if err != nil {
+			return "", err
+		}
+		for name, value := range provided {
+			if util.StringInSlice(name, reservedTokenClaims) {
+				log.WARNING.Printf("Token claims provider cannot set reserved claim %s", name)
+				continue
+			}
+			claims[name] = value
+		}
+	}
+	if len(claims) == 0 {
+		return "", nil
+	}
+
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// customClaims returns the custom claims of the access token
+func customClaims(accessToken *models.OauthAccessToken) map[string]interface{} {
+	if accessToken.Claims == "" {
+		return nil
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal([]byte(accessToken.Claims), &claims); err != nil {
+		log.WARNING.Printf("Access token %s has invalid claims: %s", accessToken.ID, err)
+		return nil
+	}
+	return claims
+}
+
+// MarshalJSON includes custom claims alongside the introspection fields
+func (r IntrospectResponse) MarshalJSON() ([]byte, error) {
+	type introspectResponse IntrospectResponse
+	data, err := json.Marshal(introspectResponse(r))
+	if err != nil || len(r.Extra) == 0 {
+		return data, err
+	}
+
+	fields := make(map[string]interface{}, len(r.Extra))
+	for name, value := range r.Extra {
+		if !util.StringInSlice(name, reservedTokenClaims) {
+			fields[name] = value
+		}
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
@@ -0,0 +1,77 @@
+package oauth_test
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/util/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestTokenClaimsProvider() {
+	// Use a separate service so the providers do not affect other tests
+	service := oauth.NewService(suite.cnf, suite.db)
+
+	service.AddTokenClaimsProvider(func(user *models.OauthUser, client *models.OauthClient, scope string) (map[string]interface{}, error) {
+		if user == nil {
+			return nil, nil
+		}
+		return map[string]interface{}{"tenant_id": client.Key, "plan": "free"}, nil
+	})
+	service.AddTokenClaimsProvider(func(user *models.OauthUser, client *models.OauthClient, scope string) (map[string]interface{}, error) {
+		// Later providers override earlier ones
+		// but the standard claims cannot be clobbered
+		return map[string]interface{}{"plan": "pro", "sub": "clobbered"}, nil
+	})
+
+	accessToken, err := service.GrantAccessToken(suite.clients[0], suite.users[0], 3600, "read")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+
+	// The claims are stored with the token and introspected
+	stored, err := service.Authenticate(accessToken.Token)
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	introspectResponse, err := service.NewIntrospectResponseFromAccessToken(stored)
+	if assert.NoError(suite.T(), err) {
+		data, err := json.Marshal(introspectResponse)
+		assert.NoError(suite.T(), err)
+		fields := map[string]interface{}{}
+		assert.NoError(suite.T(), json.Unmarshal(data, &fields))
+		assert.Equal(suite.T(), "test_client_1", fields["tenant_id"])
+		assert.Equal(suite.T(), "pro", fields["plan"])
+		assert.Equal(suite.T(), suite.users[0].ID, fields["sub"])
+	}
+
+	// And included in JWT access tokens
+	suite.cnf.Oauth.AccessTokenFormat = config.AccessTokenFormatJWT
+	defer func() { suite.cnf.Oauth.AccessTokenFormat = "" }()
+	encoded, err := service.EncodeAccessToken(stored)
+	assert.NoError(suite.T(), err)
+	signingKey, err := oauth.NewSigningKey(&suite.cnf.JWT)
+	assert.NoError(suite.T(), err)
+	claims, err := jwt.Parse(encoded, signingKey)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), "test_client_1", claims["tenant_id"])
+		assert.Equal(suite.T(), "pro", claims["plan"])
+		assert.Equal(suite.T(), suite.users[0].ID, claims["sub"])
+	}
+}
+
+func (suite *OauthTestSuite) TestTokenClaimsProviderFails() {
+	service := oauth.NewService(suite.cnf, suite.db)
+	errTenantSuspended := errors.New("Tenant suspended")
+	service.AddTokenClaimsProvider(func(user *models.OauthUser, client *models.OauthClient, scope string) (map[string]interface{}, error) {
+		return nil, errTenantSuspended
+	})
+
+	// No token is issued
+	accessToken, err := service.GrantAccessToken(suite.clients[0], suite.users[0], 3600, "read")
+	assert.Nil(suite.T(), accessToken)
+	assert.Equal(suite.T(), errTenantSuspended, err)
+}
@@ -0,0 +1,100 @@
+package oauth
+
+import (
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/log"
+	"github.com/RichardKnop/go-oauth2-server/models"
+)
+
+const (
+	// defaultTokenCleanupBatchSize is used when the batch size is not configured
+	defaultTokenCleanupBatchSize = 1000
+)
+
+// PurgeExpiredTokens deletes access and refresh tokens, authorization codes
+// kept to detect replays, and the records of client assertions, failed
+// logins and federated logins, which expired more than TokenCleanupRetention
+// seconds ago and returns how many were deleted.
+// Tokens are deleted in batches so the tables are not locked for long.
+func (s *Service) PurgeExpiredTokens() (int64, error) {
+	expiredBefore := time.Now().UTC().Add(
+		-time.Duration(s.cnf.Oauth.TokenCleanupRetention) * time.Second,
+	)
+
+	var purged int64
+	expirables := []interface{}{
+		new(models.OauthAccessToken),
+		new(models.OauthRefreshToken),
+		new(models.OauthAuthorizationCode),
+		new(models.OauthClientAssertion),
+		new(models.OauthLoginFailure),
+		new(models.OauthFederationState),
+	}
+	for _, model := range expirables {
+		n, err := s.purgeExpired(model, expiredBefore)
+		purged += n
+		if err != nil {
+			return purged, err
+		}
+	}
+	return purged, nil
+}
+
+// purgeExpired deletes rows of the model expired before the time batch by batch
+func (s *Service) purgeExpired(model interface{}, expiredBefore time.Time) (int64, error) {
+	batchSize := s.cnf.Oauth.TokenCleanupBatchSize
+	if batchSize <= 0 {
+		batchSize = defaultTokenCleanupBatchSize
+	}
+
+	var purged int64
+	for {
+		var ids []string
+		err := s.db.Unscoped().Model(model).Where("expires_at < ?", expiredBefore).
+			Limit(batchSize).Pluck("id", &ids).Error
+		if err != nil {
+			return purged, err
+		}
+		if len(ids) == 0 {
+			return purged, nil
+		}
+
+		result := s.db.Unscoped().Where("id IN (?)", ids).Delete(model)
+		if result.Error != nil {
+			return purged, result.Error
+		}
+		purged += result.RowsAffected
+		if len(ids) < batchSize {
+			return purged, nil
+		}
+	}
+}
+
+// StartTokenCleanup purges expired tokens every TokenCleanupInterval
+// seconds until the service is closed, it does nothing if the interval
+// is not configured
+func (s *Service) StartTokenCleanup() {
+	if s.cnf.Oauth.TokenCleanupInterval <= 0 || s.stopCleanup != nil {
+		return
+	}
+
+	s.stopCleanup = make(chan struct{})
+	ticker := time.NewTicker(time.Duration(s.cnf.Oauth.TokenCleanupInterval) * time.Second)
+	go func(stop <-chan struct{}) {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				purged, err := s.PurgeExpiredTokens()
+				if err != nil {
+					log.ERROR.Printf("Purging expired tokens failed: %s", err)
+					continue
+				}
+				log.INFO.Printf("Purged %d expired tokens", purged)
+			case <-stop:
+				return
+			}
+		}
+	}(s.stopCleanup)
+}
@@ -0,0 +1,71 @@
+package oauth_test
+
+import (
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestPurgeExpiredTokens() {
+	suite.cnf.Oauth.TokenCleanupBatchSize = 2
+	suite.cnf.Oauth.TokenCleanupRetention = 3600
+	defer func() {
+		suite.cnf.Oauth.TokenCleanupBatchSize = 0
+		suite.cnf.Oauth.TokenCleanupRetention = 0
+	}()
+
+	// Insert tokens expired long ago, recently and not at all
+	for i, expiresAt := range []time.Time{
+		time.Now().UTC().Add(-2 * time.Hour),
+		time.Now().UTC().Add(-3 * time.Hour),
+		time.Now().UTC().Add(-4 * time.Hour),
+		time.Now().UTC().Add(-10 * time.Second),
+		time.Now().UTC().Add(10 * time.Second),
+	} {
+		err := suite.db.Create(&models.OauthAccessToken{
+			MyGormModel: models.MyGormModel{ID: uuid.New(), CreatedAt: time.Now().UTC()},
+			Client:      suite.clients[0],
+			Token:       uuid.New(),
+			ExpiresAt:   expiresAt,
+		}).Error
+		assert.NoError(suite.T(), err, "Inserting test data failed")
+		if i == 0 {
+			err = suite.db.Create(&models.OauthRefreshToken{
+				MyGormModel: models.MyGormModel{ID: uuid.New(), CreatedAt: time.Now().UTC()},
+				Client:      suite.clients[0],
+				Token:       uuid.New(),
+				ExpiresAt:   expiresAt,
+			}).Error
+			assert.NoError(suite.T(), err, "Inserting test data failed")
+			err = suite.db.Create(&models.OauthAuthorizationCode{
+				MyGormModel: models.MyGormModel{ID: uuid.New(), CreatedAt: time.Now().UTC()},
+				Client:      suite.clients[0],
+				User:        suite.users[0],
+				Code:        uuid.New(),
+				ExpiresAt:   expiresAt,
+			}).Error
+			assert.NoError(suite.T(), err, "Inserting test data failed")
+		}
+	}
+
+	// Only tokens expired before the retention window are purged
+	purged, err := suite.service.PurgeExpiredTokens()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(5), purged)
+
+	var count int
+	suite.db.Unscoped().Model(new(models.OauthAccessToken)).
+		Where("expires_at < ?", time.Now().UTC()).Count(&count)
+	assert.Equal(suite.T(), 1, count)
+	suite.db.Unscoped().Model(new(models.OauthRefreshToken)).Count(&count)
+	assert.Equal(suite.T(), 0, count)
+	suite.db.Unscoped().Model(new(models.OauthAuthorizationCode)).Count(&count)
+	assert.Equal(suite.T(), 0, count)
+
+	// There is nothing left to purge
+	purged, err = suite.service.PurgeExpiredTokens()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(0), purged)
+}
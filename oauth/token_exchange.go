@@ -0,0 +1,125 @@
+package oauth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util"
+)
+
+const (
+	// TokenExchangeGrantType is the grant type services trade tokens with
+	TokenExchangeGrantType = "urn:ietf:params:oauth:grant-type:token-exchange"
+	// AccessTokenType identifies access tokens in token exchange requests
+	AccessTokenType = "urn:ietf:params:oauth:token-type:access_token"
+	// maxDelegationChain is the maximum number of actors of a delegated token
+	maxDelegationChain = 5
+)
+
+var (
+	// ErrInvalidSubjectToken ...
+	ErrInvalidSubjectToken = errors.New("Invalid subject token")
+	// ErrUnsupportedTokenType ...
+	ErrUnsupportedTokenType = errors.New("Unsupported token type")
+	// ErrSubjectTokenNotForClient ...
+	ErrSubjectTokenNotForClient = errors.New("Subject token was not issued to or for the client")
+	// ErrSubjectTokenProofRequired ...
+	ErrSubjectTokenProofRequired = errors.New("Subject token is bound to a key the client did not prove possession of")
+	// ErrDelegationChainTooLong ...
+	ErrDelegationChainTooLong = errors.New("Delegation chain too long")
+)
+
+// getValidSubjectToken returns the valid non expired access token presented
+// for exchange by the client, unlike Authenticate it does not extend refresh
+// tokens. The token must have been issued to the client or for it as an
+// audience, and a token bound to a DPoP key or a TLS client certificate is
+// only accepted in a request proving possession of the same key.
+func (s *Service) getValidSubjectToken(r *http.Request, client *models.OauthClient, token, tokenType string) (*models.OauthAccessToken, error) {
+	// Only access tokens issued by this server can be exchanged
+	if tokenType != AccessTokenType {
+		return nil, ErrUnsupportedTokenType
+	}
+
+	// JWT access tokens are stored by their jti claim
+	token, err := s.decodeAccessToken(token)
+	if err != nil {
+		return nil, ErrInvalidSubjectToken
+	}
+
+	// Fetch the access token from the database
+	accessToken := new(models.OauthAccessToken)
+	notFound := models.OauthAccessTokenPreload(s.db).Where("token = ?", models.HashToken(token)).
+		First(accessToken).RecordNotFound()
+
+	// Not found
+	if notFound {
+		return nil, ErrInvalidSubjectToken
+	}
+
+	// Check the access token hasn't expired
+	if time.Now().UTC().After(accessToken.ExpiresAt) {
+		return nil, ErrInvalidSubjectToken
+	}
+
+	// A token can only be traded by the client it was issued to, or
+	// by a client it was issued for, so stolen tokens are not laundered
+	if accessToken.ClientID.String != client.ID &&
+		!util.StringInSlice(client.Key, strings.Fields(accessToken.Audience)) {
+		return nil, ErrSubjectTokenNotForClient
+	}
+
+	// Sender constrained tokens need the proof of possession the
+	// resource servers would have asked for
+	if accessToken.JWKThumbprint != "" && accessToken.JWKThumbprint != getDPoPThumbprint(r) {
+		return nil, ErrSubjectTokenProofRequired
+	}
+	if checkCertificateBinding(r, accessToken) != nil {
+		return nil, ErrSubjectTokenProofRequired
+	}
+
+	return accessToken, nil
+}
+
+// getSubjectTokenLifetime caps the lifetime of a token obtained by token
+// exchange so it does not outlive the subject token it was traded for
+func getSubjectTokenLifetime(subjectToken *models.OauthAccessToken, lifetime int) int {
+	remaining := int(time.Until(subjectToken.ExpiresAt) / time.Second)
+	if remaining < lifetime {
+		return remaining
+	}
+	return lifetime
+}
+
+// delegateActor returns the actor of a token exchanged by the client,
+// the client becomes the current actor ahead of any prior actors
+func delegateActor(subjectToken *models.OauthAccessToken, client *models.OauthClient) (string, error) {
+	actors := append([]string{client.Key}, strings.Fields(subjectToken.Actor)...)
+	if len(actors) > maxDelegationChain {
+		return "", ErrDelegationChainTooLong
+	}
+	return strings.Join(actors, " "), nil
+}
+
+// setAccessTokenActor records the actor of a token obtained by token exchange
+func (s *Service) setAccessTokenActor(accessToken *models.OauthAccessToken, actor string) error {
+	err := s.db.Model(new(models.OauthAccessToken)).Where("id = ?", accessToken.ID).
+		UpdateColumn("actor", actor).Error
+	if err != nil {
+		return err
+	}
+	accessToken.Actor = actor
+	return nil
+}
+
+// newActor returns the act claim for the space delimited actor of a token
+func newActor(actor string) *Actor {
+	var act *Actor
+	actors := strings.Fields(actor)
+	for i := len(actors) - 1; i >= 0; i-- {
+		act = &Actor{Subject: actors[i], Act: act}
+	}
+	return act
+}
@@ -0,0 +1,98 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"errors"
+	"strings"
+)
+
+const (
+	// defaultTokenLength is used when the token length is not configured
+	defaultTokenLength = 32
+	// defaultTokenAlphabet is used when the token alphabet is not configured
+	defaultTokenAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+)
+
+var (
+	// ErrInvalidTokenAlphabet ...
+	ErrInvalidTokenAlphabet = errors.New("Token alphabet must have between 2 and 256 characters and no dots")
+)
+
+// TokenGenerator generates the access and refresh tokens handed out to
+// clients. Tokens must be unguessable, only their hashes are stored.
+type TokenGenerator interface {
+	GenerateAccessToken() (string, error)
+	GenerateRefreshToken() (string, error)
+}
+
+// RandomTokenGenerator is a TokenGenerator drawing Length characters of the
+// Alphabet from crypto/rand. Prefixes such as at_ and rt_ make leaked tokens
+// easy to spot by secret scanners. Zero values fall back to the defaults.
+type RandomTokenGenerator struct {
+	Length             int
+	Alphabet           string
+	AccessTokenPrefix  string
+	RefreshTokenPrefix string
+}
+
+// GenerateAccessToken returns a new random access token
+func (g *RandomTokenGenerator) GenerateAccessToken() (string, error) {
+	return g.generate(g.AccessTokenPrefix)
+}
+
+// GenerateRefreshToken returns a new random refresh token
+func (g *RandomTokenGenerator) GenerateRefreshToken() (string, error) {
+	return g.generate(g.RefreshTokenPrefix)
+}
+
+func (g *RandomTokenGenerator) generate(prefix string) (string, error) {
+	length := g.Length
+	if length <= 0 {
+		length = defaultTokenLength
+	}
+	alphabet := g.Alphabet
+	if alphabet == "" {
+		alphabet = defaultTokenAlphabet
+	}
+	// Dots would make opaque tokens look like JWT access tokens
+	if len(alphabet) < 2 || len(alphabet) > 256 || strings.Contains(alphabet, ".") {
+		return "", ErrInvalidTokenAlphabet
+	}
+
+	// Bytes beyond the largest multiple of the alphabet size are
+	// rejected so every character is equally likely
+	limit := 256 - 256%len(alphabet)
+	token := make([]byte, 0, length)
+	buf := make([]byte, length)
+	for len(token) < length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if int(b) < limit && len(token) < length {
+				token = append(token, alphabet[int(b)%len(alphabet)])
+			}
+		}
+	}
+
+	return prefix + string(token), nil
+}
+
+// UseTokenGenerator replaces the random token generator configured by the
+// Oauth config, for example to issue tokens in another format
+func (s *Service) UseTokenGenerator(generator TokenGenerator) {
+	s.tokenGenerator = generator
+}
+
+// getTokenGenerator returns the token generator in use
+func (s *Service) getTokenGenerator() TokenGenerator {
+	if s.tokenGenerator != nil {
+		return s.tokenGenerator
+	}
+	return &RandomTokenGenerator{
+		Length:             s.cnf.Oauth.TokenLength,
+		Alphabet:           s.cnf.Oauth.TokenAlphabet,
+		AccessTokenPrefix:  s.cnf.Oauth.AccessTokenPrefix,
+		RefreshTokenPrefix: s.cnf.Oauth.RefreshTokenPrefix,
+	}
+}
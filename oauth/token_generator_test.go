@@ -0,0 +1,76 @@
+package oauth_test
+
+import (
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestRandomTokenGenerator() {
+	generator := &oauth.RandomTokenGenerator{
+		Length:             20,
+		Alphabet:           "ab",
+		AccessTokenPrefix:  "at_",
+		RefreshTokenPrefix: "rt_",
+	}
+
+	accessToken, err := generator.GenerateAccessToken()
+	if assert.NoError(suite.T(), err) {
+		assert.True(suite.T(), strings.HasPrefix(accessToken, "at_"))
+		assert.Len(suite.T(), accessToken, 23)
+		assert.Empty(suite.T(), strings.Trim(strings.TrimPrefix(accessToken, "at_"), "ab"))
+	}
+	refreshToken, err := generator.GenerateRefreshToken()
+	if assert.NoError(suite.T(), err) {
+		assert.True(suite.T(), strings.HasPrefix(refreshToken, "rt_"))
+		assert.NotEqual(suite.T(), accessToken[3:], refreshToken[3:])
+	}
+
+	// Dots would make tokens look like JWTs
+	generator.Alphabet = "a.b"
+	_, err = generator.GenerateAccessToken()
+	assert.Equal(suite.T(), oauth.ErrInvalidTokenAlphabet, err)
+}
+
+func (suite *OauthTestSuite) TestTokenGeneratorConfig() {
+	suite.cnf.Oauth.AccessTokenPrefix = "at_"
+	suite.cnf.Oauth.RefreshTokenPrefix = "rt_"
+	defer func() {
+		suite.cnf.Oauth.AccessTokenPrefix = ""
+		suite.cnf.Oauth.RefreshTokenPrefix = ""
+	}()
+
+	accessToken, refreshToken, err := suite.service.Login(suite.clients[0], suite.users[0], "read_write")
+	if assert.NoError(suite.T(), err) {
+		assert.True(suite.T(), strings.HasPrefix(accessToken.Token, "at_"))
+		assert.Len(suite.T(), accessToken.Token, 35)
+		assert.True(suite.T(), strings.HasPrefix(refreshToken.Token, "rt_"))
+	}
+
+	// Issued tokens are valid
+	_, err = suite.service.Authenticate(accessToken.Token)
+	assert.NoError(suite.T(), err)
+	_, err = suite.service.GetValidRefreshToken(refreshToken.Token, suite.clients[0])
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *OauthTestSuite) TestUseTokenGenerator() {
+	suite.service.UseTokenGenerator(testTokenGenerator{})
+	defer suite.service.UseTokenGenerator(nil)
+
+	accessToken, err := suite.service.GrantAccessToken(suite.clients[0], suite.users[0], 3600, "read")
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), "custom_access_token", accessToken.Token)
+	}
+}
+
+type testTokenGenerator struct{}
+
+func (testTokenGenerator) GenerateAccessToken() (string, error) {
+	return "custom_access_token", nil
+}
+
+func (testTokenGenerator) GenerateRefreshToken() (string, error) {
+	return "custom_refresh_token", nil
+}
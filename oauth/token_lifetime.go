@@ -0,0 +1,90 @@
+package oauth
+
+import (
+	"database/sql"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util"
+)
+
+// ClientAccessTokenLifetime returns the lifetime of access tokens issued
+// to the client if it has its own, the lifetime otherwise
+func ClientAccessTokenLifetime(client *models.OauthClient, lifetime int) int {
+	if client != nil && client.AccessTokenLifetime.Valid && client.AccessTokenLifetime.Int64 > 0 {
+		return int(client.AccessTokenLifetime.Int64)
+	}
+	return lifetime
+}
+
+// ClientRefreshTokenLifetime returns the lifetime of refresh tokens issued
+// to the client if it has its own, the lifetime otherwise
+func ClientRefreshTokenLifetime(client *models.OauthClient, lifetime int) int {
+	if client != nil && client.RefreshTokenLifetime.Valid && client.RefreshTokenLifetime.Int64 > 0 {
+		return int(client.RefreshTokenLifetime.Int64)
+	}
+	return lifetime
+}
+
+// SetClientTokenLifetimes overrides the lifetimes of tokens issued to the
+// client, a lifetime of zero makes the client use the configured one again
+func (s *Service) SetClientTokenLifetimes(client *models.OauthClient, accessTokenLifetime, refreshTokenLifetime int) error {
+	lifetimes := map[string]interface{}{
+		"access_token_lifetime":  util.PositiveIntOrNull(int64(accessTokenLifetime)),
+		"refresh_token_lifetime": util.PositiveIntOrNull(int64(refreshTokenLifetime)),
+	}
+	err := s.db.Model(new(models.OauthClient)).Where("id = ?", client.ID).
+		UpdateColumns(lifetimes).Error
+	if err != nil {
+		return err
+	}
+	client.AccessTokenLifetime = util.PositiveIntOrNull(int64(accessTokenLifetime))
+	client.RefreshTokenLifetime = util.PositiveIntOrNull(int64(refreshTokenLifetime))
+	return nil
+}
+
+// getAccessTokenLifetime returns the lifetime of access tokens issued to
+// the client by the grant type, if configured for either of them
+func (s *Service) getAccessTokenLifetime(client *models.OauthClient, grantType string) int {
+	if lifetime := s.cnf.Oauth.GrantTypeLifetimes[grantType].AccessTokenLifetime; lifetime > 0 {
+		return ClientAccessTokenLifetime(client, lifetime)
+	}
+	return ClientAccessTokenLifetime(client, s.cnf.Oauth.AccessTokenLifetime)
+}
+
+// getRefreshTokenLifetime returns the lifetime of refresh tokens issued to
+// the client by the grant type, if configured for either of them
+func (s *Service) getRefreshTokenLifetime(client *models.OauthClient, grantType string) int {
+	if lifetime := s.cnf.Oauth.GrantTypeLifetimes[grantType].RefreshTokenLifetime; lifetime > 0 {
+		return ClientRefreshTokenLifetime(client, lifetime)
+	}
+	return ClientRefreshTokenLifetime(client, s.cnf.Oauth.RefreshTokenLifetime)
+}
+
+// getSlidingRefreshTokenLifetime returns the lifetime refresh tokens of the
+// client are extended to whenever one of its access tokens is used
+func (s *Service) getSlidingRefreshTokenLifetime(clientID string) int {
+	client := new(models.OauthClient)
+	if s.db.Select("refresh_token_lifetime").Where("id = ?", clientID).First(client).RecordNotFound() {
+		return s.cnf.Oauth.RefreshTokenLifetime
+	}
+	return ClientRefreshTokenLifetime(client, s.cnf.Oauth.RefreshTokenLifetime)
+}
+
+// getMaxAccessTokenLifetime returns the longest lifetime
+// of access tokens issued by any grant type or to any client
+func (s *Service) getMaxAccessTokenLifetime() int {
+	max := s.cnf.Oauth.AccessTokenLifetime
+	for _, lifetimes := range s.cnf.Oauth.GrantTypeLifetimes {
+		if lifetimes.AccessTokenLifetime > max {
+			max = lifetimes.AccessTokenLifetime
+		}
+	}
+
+	var clientMax sql.NullInt64
+	row := s.db.Model(new(models.OauthClient)).Select("MAX(access_token_lifetime)").Row()
+	if err := row.Scan(&clientMax); err == nil && int(clientMax.Int64) > max {
+		max = int(clientMax.Int64)
+	}
+
+	return max
+}
@@ -0,0 +1,88 @@
+package oauth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestGrantTypeLifetimes() {
+	suite.cnf.Oauth.GrantTypeLifetimes = map[string]config.TokenLifetimes{
+		"client_credentials": {AccessTokenLifetime: 300},
+		"password":           {AccessTokenLifetime: 600, RefreshTokenLifetime: 1200},
+	}
+	defer func() { suite.cnf.Oauth.GrantTypeLifetimes = nil }()
+
+	// Client credentials grant
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
+	r.PostForm = url.Values{"grant_type": {"client_credentials"}}
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	resp := suite.decodeAccessTokenResponse(w)
+	assert.Equal(suite.T(), 300, resp.ExpiresIn)
+
+	// Password grant
+	resp = suite.decodeAccessTokenResponse(suite.passwordGrantWithScope("read_write"))
+	assert.Equal(suite.T(), 600, resp.ExpiresIn)
+	suite.assertRefreshTokenExpiresIn(resp.RefreshToken, 1200)
+
+	// The refresh token grant keeps the lifetimes of the password grant
+	resp = suite.decodeAccessTokenResponse(suite.refreshTokenGrant(resp.RefreshToken))
+	assert.Equal(suite.T(), 600, resp.ExpiresIn)
+	suite.assertRefreshTokenExpiresIn(resp.RefreshToken, 1200)
+
+	// Other grant types use the global lifetimes
+	suite.cnf.Oauth.GrantTypeLifetimes = nil
+	resp = suite.decodeAccessTokenResponse(suite.passwordGrantWithScope("read_write"))
+	assert.Equal(suite.T(), suite.cnf.Oauth.AccessTokenLifetime, resp.ExpiresIn)
+}
+
+func (suite *OauthTestSuite) assertRefreshTokenExpiresIn(token string, expiresIn int) {
+	refreshToken := new(models.OauthRefreshToken)
+	assert.False(suite.T(), suite.db.Where("token = ?", models.HashToken(token)).First(refreshToken).RecordNotFound())
+	expiresAt := time.Now().UTC().Add(time.Duration(expiresIn) * time.Second)
+	assert.WithinDuration(suite.T(), expiresAt, refreshToken.ExpiresAt, 5*time.Second)
+}
+
+func (suite *OauthTestSuite) TestClientTokenLifetimes() {
+	suite.cnf.Oauth.GrantTypeLifetimes = map[string]config.TokenLifetimes{
+		"password": {AccessTokenLifetime: 1800},
+	}
+	defer func() { suite.cnf.Oauth.GrantTypeLifetimes = nil }()
+
+	err := suite.service.SetClientTokenLifetimes(suite.clients[0], 600, 1200)
+	assert.NoError(suite.T(), err)
+	defer suite.service.SetClientTokenLifetimes(suite.clients[0], 0, 0)
+
+	// The client lifetimes override the configured ones
+	resp := suite.decodeAccessTokenResponse(suite.passwordGrantWithScope("read_write"))
+	assert.Equal(suite.T(), 600, resp.ExpiresIn)
+	suite.assertRefreshTokenExpiresIn(resp.RefreshToken, 1200)
+
+	// Using an access token does not extend the refresh token beyond its lifetime
+	_, err = suite.service.Authenticate(resp.AccessToken)
+	assert.NoError(suite.T(), err)
+	suite.assertRefreshTokenExpiresIn(resp.RefreshToken, 1200)
+
+	// Other clients keep the configured lifetimes
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_2", "test_secret")
+	r.PostForm = url.Values{"grant_type": {"client_credentials"}}
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	resp = suite.decodeAccessTokenResponse(w)
+	assert.Equal(suite.T(), suite.cnf.Oauth.AccessTokenLifetime, resp.ExpiresIn)
+
+	// Clearing the client lifetimes restores the configured ones
+	assert.NoError(suite.T(), suite.service.SetClientTokenLifetimes(suite.clients[0], 0, 0))
+	resp = suite.decodeAccessTokenResponse(suite.passwordGrantWithScope("read_write"))
+	assert.Equal(suite.T(), 1800, resp.ExpiresIn)
+}
@@ -0,0 +1,158 @@
+package oauth
+
+import (
+	"errors"
+
+	"github.com/RichardKnop/go-oauth2-server/log"
+	"github.com/RichardKnop/go-oauth2-server/models"
+)
+
+var (
+	// ErrTokenLineageNotFound ...
+	ErrTokenLineageNotFound = errors.New("Token lineage not found")
+)
+
+// TokenLineage lists the tokens descending from the same authorization code
+// or first refresh token, in the order they were issued. Access tokens name
+// the refresh token they were obtained with, so a lineage tells how a leaked
+// credential has been used.
+type TokenLineage struct {
+	ID            string
+	RefreshTokens []*models.OauthRefreshToken
+	AccessTokens  []*models.OauthAccessToken
+}
+
+// setLineage records the lineage of tokens just issued, parentID is the
+// refresh token they were obtained with, if any
+func (s *Service) setLineage(accessToken *models.OauthAccessToken, refreshToken *models.OauthRefreshToken, lineageID, parentID string) error {
+	err := s.db.Model(new(models.OauthAccessToken)).Where("id = ?", accessToken.ID).
+		UpdateColumns(map[string]interface{}{
+			"lineage_id":       lineageID,
+			"refresh_token_id": parentID,
+		}).Error
+	if err != nil {
+		return err
+	}
+	accessToken.LineageID = lineageID
+	accessToken.RefreshTokenID = parentID
+
+	// The first refresh token of a lineage is its root
+	if refreshToken == nil || refreshToken.ID == lineageID {
+		return nil
+	}
+	err = s.db.Model(new(models.OauthRefreshToken)).Where("id = ?", refreshToken.ID).
+		UpdateColumn("lineage_id", lineageID).Error
+	if err != nil {
+		return err
+	}
+	refreshToken.LineageID = lineageID
+
+	return nil
+}
+
+// refreshTokenLineage returns the ID of the lineage of the refresh token,
+// tokens issued before lineages were recorded belong to their rotation family
+func refreshTokenLineage(refreshToken *models.OauthRefreshToken) string {
+	if refreshToken.LineageID != "" {
+		return refreshToken.LineageID
+	}
+	return refreshTokenFamily(refreshToken)
+}
+
+// findTokenLineage returns the ID of the lineage of an access or refresh
+// token, an access token issued on its own is a lineage of its own
+func (s *Service) findTokenLineage(token string) (string, error) {
+	refreshToken := new(models.OauthRefreshToken)
+	if !s.db.Where("token = ?", models.HashToken(token)).First(refreshToken).RecordNotFound() {
+		return refreshTokenLineage(refreshToken), nil
+	}
+
+	// JWT access tokens are stored by their jti claim
+	token, err := s.decodeAccessToken(token)
+	if err == ErrAccessTokenExpired {
+		return "", ErrTokenLineageNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	accessToken := new(models.OauthAccessToken)
+	if s.db.Where("token = ?", models.HashToken(token)).First(accessToken).RecordNotFound() {
+		return "", ErrTokenLineageNotFound
+	}
+	if accessToken.LineageID != "" {
+		return accessToken.LineageID, nil
+	}
+	return accessToken.ID, nil
+}
+
+// GetTokenLineage returns the tokens of the lineage of an access or refresh token
+func (s *Service) GetTokenLineage(token string) (*TokenLineage, error) {
+	lineageID, err := s.findTokenLineage(token)
+	if err != nil {
+		return nil, err
+	}
+	return s.getTokenLineageByID(lineageID)
+}
+
+// getTokenLineageByID returns the tokens of the lineage, which is empty
+// if no tokens descend from it
+func (s *Service) getTokenLineageByID(lineageID string) (*TokenLineage, error) {
+	lineage := &TokenLineage{ID: lineageID}
+	err := s.db.Where("id = ? OR lineage_id = ? OR family_id = ?", lineageID, lineageID, lineageID).
+		Order("created_at").Find(&lineage.RefreshTokens).Error
+	if err != nil {
+		return nil, err
+	}
+	err = s.db.Where("id = ? OR lineage_id = ?", lineageID, lineageID).
+		Order("created_at").Find(&lineage.AccessTokens).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return lineage, nil
+}
+
+// RevokeTokenLineage deletes every token of the lineage of a compromised
+// access or refresh token and returns the revoked tokens for auditing
+func (s *Service) RevokeTokenLineage(token string) (*TokenLineage, error) {
+	lineage, err := s.GetTokenLineage(token)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.revokeTokenLineage(lineage); err != nil {
+		return nil, err
+	}
+	return lineage, nil
+}
+
+// revokeTokenLineage deletes every token of the lineage
+func (s *Service) revokeTokenLineage(lineage *TokenLineage) error {
+	log.WARNING.Printf("Revoking token lineage %s", lineage.ID)
+
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	err := tx.Unscoped().Where("id = ? OR lineage_id = ? OR family_id = ?", lineage.ID, lineage.ID, lineage.ID).
+		Delete(new(models.OauthRefreshToken)).Error
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+	err = tx.Unscoped().Where("id = ? OR lineage_id = ?", lineage.ID, lineage.ID).
+		Delete(new(models.OauthAccessToken)).Error
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	// Revoked access tokens must not be served from the cache
+	s.invalidateAccessTokens(lineage.AccessTokens)
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package oauth_test
+
+import (
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/RichardKnop/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestTokenLineage() {
+	// Tokens descend from the first refresh token through rotations
+	first := suite.decodeAccessTokenResponse(suite.passwordGrantWithScope("read_write"))
+	second := suite.decodeAccessTokenResponse(suite.refreshTokenGrant(first.RefreshToken))
+	third := suite.decodeAccessTokenResponse(suite.refreshTokenGrant(second.RefreshToken))
+
+	// Tokens of another login are not part of the lineage
+	other := suite.decodeAccessTokenResponse(suite.passwordGrantWithScope("read_write"))
+
+	lineage, err := suite.service.GetTokenLineage(second.AccessToken)
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	if assert.Len(suite.T(), lineage.RefreshTokens, 3) && assert.Len(suite.T(), lineage.AccessTokens, 3) {
+		assert.Equal(suite.T(), lineage.RefreshTokens[0].ID, lineage.ID)
+		assert.Empty(suite.T(), lineage.AccessTokens[0].RefreshTokenID)
+		assert.Equal(suite.T(), lineage.RefreshTokens[0].ID, lineage.AccessTokens[1].RefreshTokenID)
+		assert.Equal(suite.T(), lineage.RefreshTokens[1].ID, lineage.AccessTokens[2].RefreshTokenID)
+	}
+
+	// Any token of the lineage tells the same lineage
+	thirdLineage, err := suite.service.GetTokenLineage(third.RefreshToken)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), lineage.ID, thirdLineage.ID)
+	}
+
+	// Revoking the lineage revokes every token descending from the same login
+	revoked, err := suite.service.RevokeTokenLineage(first.AccessToken)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), lineage.ID, revoked.ID)
+	}
+	for _, resp := range []*oauth.AccessTokenResponse{first, second, third} {
+		_, err = suite.service.Authenticate(resp.AccessToken)
+		assert.Equal(suite.T(), oauth.ErrAccessTokenNotFound, err)
+	}
+	_, err = suite.service.GetValidRefreshToken(third.RefreshToken, suite.clients[0])
+	assert.Equal(suite.T(), oauth.ErrRefreshTokenNotFound, err)
+
+	_, err = suite.service.Authenticate(other.AccessToken)
+	assert.NoError(suite.T(), err)
+	_, err = suite.service.GetValidRefreshToken(other.RefreshToken, suite.clients[0])
+	assert.NoError(suite.T(), err)
+
+	// The lineage is gone
+	_, err = suite.service.GetTokenLineage(first.AccessToken)
+	assert.Equal(suite.T(), oauth.ErrTokenLineageNotFound, err)
+}
+
+func (suite *OauthTestSuite) TestTokenLineageOfAuthorizationCode() {
+	authorizationCode := &models.OauthAuthorizationCode{
+		MyGormModel: models.MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		Code:        uuid.New(),
+		ExpiresAt:   time.Now().UTC().Add(+10 * time.Second),
+		Client:      suite.clients[0],
+		User:        suite.users[0],
+		RedirectURI: util.StringOrNull("https://www.example.com"),
+		Scope:       "read_write",
+	}
+	err := suite.db.Create(authorizationCode).Error
+	assert.NoError(suite.T(), err, "Inserting test data failed")
+
+	resp := suite.decodeAccessTokenResponse(suite.exchangeAuthorizationCode(authorizationCode.Code))
+	refreshed := suite.decodeAccessTokenResponse(suite.refreshTokenGrant(resp.RefreshToken))
+
+	// The tokens descend from the authorization code
+	lineage, err := suite.service.GetTokenLineage(refreshed.AccessToken)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), authorizationCode.ID, lineage.ID)
+		assert.Len(suite.T(), lineage.RefreshTokens, 2)
+		assert.Len(suite.T(), lineage.AccessTokens, 2)
+	}
+}
@@ -0,0 +1,150 @@
+package oauth
+
+import (
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/RichardKnop/go-oauth2-server/util/totp"
+)
+
+const (
+	// defaultTOTPIssuer names the server in authenticator apps when
+	// the issuer has no host
+	defaultTOTPIssuer = "go-oauth2-server"
+)
+
+var (
+	// ErrTOTPRequiresUser ...
+	ErrTOTPRequiresUser = errors.New("Authenticator enrollment requires an access token granted to a user")
+	// ErrTOTPAlreadyEnrolled ...
+	ErrTOTPAlreadyEnrolled = errors.New("User already has an authenticator")
+	// ErrTOTPNotEnrolled ...
+	ErrTOTPNotEnrolled = errors.New("User has no authenticator")
+)
+
+// TOTPEnrollmentResponse is a new authenticator secret to be confirmed
+type TOTPEnrollmentResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// TOTPConfirmRequest confirms the secret with a code from the authenticator
+type TOTPConfirmRequest struct {
+	Secret string `json:"secret"`
+	OTP    string `json:"otp"`
+}
+
+// BeginTOTPEnrollment generates a new authenticator secret for the user. Nothing
+// is stored until the user proves the authenticator works with ConfirmTOTPEnrollment.
+func (s *Service) BeginTOTPEnrollment(user *models.OauthUser) (*TOTPEnrollmentResponse, error) {
+	if totpEnrolled(user) {
+		return nil, ErrTOTPAlreadyEnrolled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	return &TOTPEnrollmentResponse{
+		Secret: secret,
+		URI:    totp.URI(s.totpIssuer(), user.Username, secret),
+	}, nil
+}
+
+// ConfirmTOTPEnrollment enables the authenticator once the user has sent a
+// valid code for the secret, and returns a new set of recovery codes
+func (s *Service) ConfirmTOTPEnrollment(user *models.OauthUser, secret, otp string) ([]string, error) {
+	if totpEnrolled(user) {
+		return nil, ErrTOTPAlreadyEnrolled
+	}
+	step, ok := totp.ValidateStep(secret, otp, time.Now())
+	if !ok {
+		return nil, ErrInvalidOTP
+	}
+
+	// The code confirming the secret cannot be used to log in as well
+	err := s.db.Model(new(models.OauthUser)).Where("id = ?", user.ID).
+		UpdateColumns(map[string]interface{}{
+			"totp_secret":    secret,
+			"totp_last_step": step,
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+	user.TOTPSecret = util.StringOrNull(secret)
+	user.TOTPLastStep = step
+
+	return s.GenerateRecoveryCodes(user)
+}
+
+// DisableTOTP removes the authenticator and recovery codes of the user
+func (s *Service) DisableTOTP(user *models.OauthUser) error {
+	if !totpEnrolled(user) {
+		return ErrTOTPNotEnrolled
+	}
+
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	err := tx.Model(new(models.OauthUser)).Where("id = ?", user.ID).
+		UpdateColumn("totp_secret", nil).Error
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(new(models.OauthRecoveryCode)).Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	user.TOTPSecret = util.StringOrNull("")
+	return nil
+}
+
+// totpUser returns the user of an authenticated session managing their
+// authenticator. Once the user has one the session must have been
+// authenticated with it, otherwise the password alone would be enough
+// to replace or remove it.
+func (s *Service) totpUser(accessToken *models.OauthAccessToken) (*models.OauthUser, error) {
+	// Client credentials tokens have no user
+	if !accessToken.UserID.Valid {
+		return nil, ErrTOTPRequiresUser
+	}
+
+	// Fetch the user
+	user := new(models.OauthUser)
+	if s.db.Where("id = ?", accessToken.UserID.String).First(user).RecordNotFound() {
+		return nil, ErrUserNotFound
+	}
+
+	if totpEnrolled(user) && accessToken.Acr != AcrMFA {
+		return nil, ErrMFARequired
+	}
+
+	return user, nil
+}
+
+// totpIssuer returns the name authenticator apps list the account under
+func (s *Service) totpIssuer() string {
+	issuerURL, err := url.Parse(s.cnf.JWT.Issuer)
+	if err != nil || issuerURL.Host == "" {
+		return defaultTOTPIssuer
+	}
+	return issuerURL.Host
+}
+
+// totpEnrolled returns true if the user has an authenticator
+func totpEnrolled(user *models.OauthUser) bool {
+	return user.TOTPSecret.Valid && user.TOTPSecret.String != ""
+}
@@ -0,0 +1,125 @@
+package oauth_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	"github.com/RichardKnop/go-oauth2-server/test-util"
+	"github.com/RichardKnop/go-oauth2-server/util/totp"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestTOTPEnrollment() {
+	_, err := suite.service.CreateUser(roles.User, "test@user_totp", "test_password")
+	if !assert.NoError(suite.T(), err, "Inserting test data failed") {
+		return
+	}
+	token := suite.accessTokenFromGrant(suite.passwordGrantWithAcr("test@user_totp", "", ""))
+
+	// A secret is generated for the authenticator app
+	w := suite.totpRequest("POST", "", token, "")
+	if !assert.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String()) {
+		return
+	}
+	enrollment := new(oauth.TOTPEnrollmentResponse)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), enrollment))
+	assert.True(suite.T(), strings.HasPrefix(enrollment.URI, "otpauth://totp/"))
+
+	// Nothing changes until the secret is confirmed with a valid code
+	w = suite.totpRequest("POST", "/confirm", token, `{"secret": "`+enrollment.Secret+`", "otp": "000000"}`)
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+	w = suite.passwordGrantWithAcr("test@user_totp", "", "")
+	suite.assertAcr(w, oauth.AcrPassword)
+
+	otp, err := totp.Code(enrollment.Secret, time.Now())
+	assert.NoError(suite.T(), err)
+	w = suite.totpRequest("POST", "/confirm", token, `{"secret": "`+enrollment.Secret+`", "otp": "`+otp+`"}`)
+	if !assert.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String()) {
+		return
+	}
+	recoveryCodes := new(oauth.RecoveryCodesResponse)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), recoveryCodes))
+	assert.Equal(suite.T(), 10, len(recoveryCodes.RecoveryCodes))
+
+	// The password grant now requires the one-time password
+	w = suite.passwordGrantWithAcr("test@user_totp", "", "")
+	testutil.TestResponseForOauthError(suite.T(), w, "mfa_required", oauth.ErrMFARequired.Error(), 400)
+	w = suite.passwordGrantWithAcr("test@user_totp", "", recoveryCodes.RecoveryCodes[0])
+	suite.assertAcr(w, oauth.AcrMFA)
+
+	// The password alone cannot remove the authenticator
+	w = suite.totpRequest("DELETE", "", token, "")
+	testutil.TestResponseForError(suite.T(), w, oauth.ErrMFARequired.Error(), 401)
+
+	// The code confirming the secret cannot be used to log in
+	w = suite.passwordGrantWithAcr("test@user_totp", "", otp)
+	testutil.TestResponseForOauthError(suite.T(), w, "invalid_grant", oauth.ErrInvalidOTP.Error(), 400)
+
+	// A code of a later time step can, but only once
+	otp, err = totp.Code(enrollment.Secret, time.Now().Add(totp.Period*time.Second))
+	assert.NoError(suite.T(), err)
+	mfaToken := suite.accessTokenFromGrant(suite.passwordGrantWithAcr("test@user_totp", "", otp))
+	w = suite.passwordGrantWithAcr("test@user_totp", "", otp)
+	testutil.TestResponseForOauthError(suite.T(), w, "invalid_grant", oauth.ErrInvalidOTP.Error(), 400)
+
+	// Nor can an authenticator be enrolled twice
+	w = suite.totpRequest("POST", "", mfaToken, "")
+	testutil.TestResponseForError(suite.T(), w, oauth.ErrTOTPAlreadyEnrolled.Error(), 409)
+
+	// A session with the second factor can disable it
+	w = suite.totpRequest("DELETE", "", mfaToken, "")
+	assert.Equal(suite.T(), http.StatusNoContent, w.Code)
+	w = suite.passwordGrantWithAcr("test@user_totp", "", "")
+	suite.assertAcr(w, oauth.AcrPassword)
+	w = suite.passwordGrantWithAcr("test@user_totp", "mfa", recoveryCodes.RecoveryCodes[1])
+	testutil.TestResponseForOauthError(suite.T(), w, "unmet_authentication_requirements", oauth.ErrAcrNotSatisfiable.Error(), 400)
+}
+
+func (suite *OauthTestSuite) TestTOTPFailedLogins() {
+	suite.cnf.Oauth.LoginLockoutThreshold = 2
+	defer func() {
+		suite.cnf.Oauth.LoginLockoutThreshold = 0
+	}()
+
+	// Insert a test user with an authenticator
+	secret, err := totp.GenerateSecret()
+	assert.NoError(suite.T(), err)
+	user, err := suite.service.CreateUser(roles.User, "test@user_totp", "test_password")
+	assert.NoError(suite.T(), err, "Inserting test data failed")
+	err = suite.db.Model(user).UpdateColumn("totp_secret", secret).Error
+	assert.NoError(suite.T(), err, "Inserting test data failed")
+
+	// Wrong one-time passwords count as failed logins
+	w := suite.passwordGrantWithAcr("test@user_totp", "", "000000")
+	testutil.TestResponseForOauthError(suite.T(), w, "invalid_grant", oauth.ErrInvalidOTP.Error(), 400)
+
+	// But the right password alone does not
+	w = suite.passwordGrantWithAcr("test@user_totp", "", "")
+	testutil.TestResponseForOauthError(suite.T(), w, "mfa_required", oauth.ErrMFARequired.Error(), 400)
+	w = suite.passwordGrantWithAcr("test@user_totp", "", "000000")
+	testutil.TestResponseForOauthError(suite.T(), w, "invalid_grant", oauth.ErrInvalidOTP.Error(), 400)
+
+	// So the user is locked out, even with the right one-time password
+	otp, err := totp.Code(secret, time.Now())
+	assert.NoError(suite.T(), err)
+	w = suite.passwordGrantWithAcr("test@user_totp", "", otp)
+	testutil.TestResponseForOauthError(suite.T(), w, "slow_down", oauth.ErrTooManyFailedLogins.Error(), 429)
+}
+
+func (suite *OauthTestSuite) totpRequest(method, path, token, body string) *httptest.ResponseRecorder {
+	r, err := http.NewRequest(method, "http://1.2.3.4/v1/oauth/totp"+path, bytes.NewBufferString(body))
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	return w
+}
@@ -2,7 +2,6 @@ package oauth
 
 import (
 	"errors"
-	"fmt"
 	"strings"
 	"time"
 
@@ -14,14 +13,10 @@ import (
 )
 
 var (
-	// MinPasswordLength defines minimum password length
+	// MinPasswordLength is the minimum password length
+	// unless the password policy configures another one
 	MinPasswordLength = 6
 
-	// ErrPasswordTooShort ...
-	ErrPasswordTooShort = fmt.Errorf(
-		"Password must be at least %d characters long",
-		MinPasswordLength,
-	)
 	// ErrUserNotFound ...
 	ErrUserNotFound = errors.New("User not found")
 	// ErrInvalidUserPassword ...
@@ -55,6 +50,19 @@ func (s *Service) FindUserByUsername(username string) (*models.OauthUser, error)
 	return user, nil
 }
 
+// FindUserByID looks up a user by ID
+func (s *Service) FindUserByID(id string) (*models.OauthUser, error) {
+	user := new(models.OauthUser)
+	notFound := s.db.Where("id = ?", id).First(user).RecordNotFound()
+
+	// Not found
+	if notFound {
+		return nil, ErrUserNotFound
+	}
+
+	return user, nil
+}
+
 // CreateUser saves a new user to database
 func (s *Service) CreateUser(roleID, username, password string) (*models.OauthUser, error) {
 	return s.createUserCommon(s.db, roleID, username, password)
@@ -77,25 +85,60 @@ func (s *Service) SetPasswordTx(tx *gorm.DB, user *models.OauthUser, password st
 
 // AuthUser authenticates user
 func (s *Service) AuthUser(username, password string) (*models.OauthUser, error) {
+	// Users log in with the user authenticator if one is set
+	if s.userAuthenticator != nil {
+		user, err := s.authExternalUser(username, password)
+		if err != ErrUserNotFound || !s.cnf.Oauth.LocalUserFallback {
+			return user, err
+		}
+	}
+
 	// Fetch the user
 	user, err := s.FindUserByUsername(username)
 	if err != nil {
 		return nil, err
 	}
 
-	// Check that the password is set
-	if !user.Password.Valid {
+	// Check that the password is set, accounts which log in by other means
+	// may have an empty or placeholder password hash
+	if !user.Password.Valid || !pass.IsUsableHash(user.Password.String) {
 		return nil, ErrUserPasswordNotSet
 	}
 
-	// Verify the password
+	// Verify the password, failed logins are counted by AuthLogin
 	if pass.VerifyPassword(user.Password.String, password) != nil {
 		return nil, ErrInvalidUserPassword
 	}
 
+	// Successful login upgrades the password hash
+	// if the hashing configuration has changed
+	if err := s.upgradePasswordHash(user, s.upgradedPasswordHash(user, password)); err != nil {
+		return nil, err
+	}
+
 	return user, nil
 }
 
+// upgradePasswordHash stores the upgraded password hash, if there is one.
+// The hash is only replaced if it has not changed since it was verified,
+// so a password set in the meantime is kept.
+func (s *Service) upgradePasswordHash(user *models.OauthUser, passwordHash string) error {
+	if passwordHash == "" {
+		return nil
+	}
+
+	query := s.db.Model(new(models.OauthUser)).
+		Where("id = ? AND password = ?", user.ID, user.Password.String).
+		UpdateColumn("password", passwordHash)
+	if err := query.Error; err != nil {
+		return err
+	}
+	if query.RowsAffected == 1 {
+		user.Password = util.StringOrNull(passwordHash)
+	}
+	return nil
+}
+
 // UpdateUsername ...
 func (s *Service) UpdateUsername(user *models.OauthUser, username string) error {
 	if username == "" {
@@ -122,20 +165,24 @@ func (s *Service) createUserCommon(db *gorm.DB, roleID, username, password strin
 		Password: util.StringOrNull(""),
 	}
 
-	// If the password is being set already, create a bcrypt hash
+	// If the password is being set already, hash it
 	if password != "" {
-		if len(password) < MinPasswordLength {
-			return nil, ErrPasswordTooShort
+		if err := s.checkPassword(password); err != nil {
+			return nil, err
 		}
-		passwordHash, err := pass.HashPassword(password)
+		passwordHash, err := s.hashPassword(password)
 		if err != nil {
 			return nil, err
 		}
-		user.Password = util.StringOrNull(string(passwordHash))
+		user.Password = util.StringOrNull(passwordHash)
 	}
 
 	// Check the username is available
-	if s.UserExists(user.Username) {
+	taken, err := usernameTaken(db, user.Username, "")
+	if err != nil {
+		return nil, err
+	}
+	if taken {
 		return nil, ErrUsernameTaken
 	}
 
@@ -147,19 +194,19 @@ func (s *Service) createUserCommon(db *gorm.DB, roleID, username, password strin
 }
 
 func (s *Service) setPasswordCommon(db *gorm.DB, user *models.OauthUser, password string) error {
-	if len(password) < MinPasswordLength {
-		return ErrPasswordTooShort
+	if err := s.checkPassword(password); err != nil {
+		return err
 	}
 
-	// Create a bcrypt hash
-	passwordHash, err := pass.HashPassword(password)
+	// Hash the password
+	passwordHash, err := s.hashPassword(password)
 	if err != nil {
 		return err
 	}
 
 	// Set the password on the user object
 	return db.Model(user).UpdateColumns(models.OauthUser{
-		Password:    util.StringOrNull(string(passwordHash)),
+		Password:    util.StringOrNull(passwordHash),
 		MyGormModel: models.MyGormModel{UpdatedAt: time.Now().UTC()},
 	}).Error
 }
@@ -168,5 +215,22 @@ func (s *Service) updateUsernameCommon(db *gorm.DB, user *models.OauthUser, user
 	if username == "" {
 		return ErrCannotSetEmptyUsername
 	}
+	taken, err := usernameTaken(db, strings.ToLower(username), user.ID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrUsernameTaken
+	}
 	return db.Model(user).UpdateColumn("username", strings.ToLower(username)).Error
 }
+
+// usernameTaken returns true if a user other than the one with the ID has
+// the username. Deleted users keep their usernames, the unique constraint
+// still covers them.
+func usernameTaken(db *gorm.DB, username, exceptID string) (bool, error) {
+	var count int
+	err := db.Unscoped().Model(new(models.OauthUser)).
+		Where("username = ? AND id != ?", username, exceptID).Count(&count).Error
+	return count > 0, err
+}
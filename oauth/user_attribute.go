@@ -0,0 +1,156 @@
+package oauth
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/log"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util"
+)
+
+const (
+	// maxUserAttributeNameLength is the length of the name column
+	maxUserAttributeNameLength = 100
+)
+
+var (
+	// ErrInvalidUserAttributeName ...
+	ErrInvalidUserAttributeName = errors.New("User attribute names must be between 1 and 100 characters")
+
+	// reservedUserClaims are the claims about users the server releases
+	// itself, attributes cannot be mapped to them
+	reservedUserClaims = []string{
+		"preferred_username",
+		"email",
+		"updated_at",
+		"nonce",
+		"at_hash",
+		"c_hash",
+	}
+)
+
+// GetUserAttributes returns the custom attributes of a user
+func (s *Service) GetUserAttributes(user *models.OauthUser) (map[string]interface{}, error) {
+	var userAttributes []*models.OauthUserAttribute
+	if err := s.db.Where("user_id = ?", user.ID).Find(&userAttributes).Error; err != nil {
+		return nil, err
+	}
+
+	attributes := make(map[string]interface{}, len(userAttributes))
+	for _, userAttribute := range userAttributes {
+		var value interface{}
+		if err := json.Unmarshal([]byte(userAttribute.Value), &value); err != nil {
+			log.WARNING.Printf("User %s has invalid attribute %s: %s", user.ID, userAttribute.Name, err)
+			continue
+		}
+		attributes[userAttribute.Name] = value
+	}
+	return attributes, nil
+}
+
+// SetUserAttributes replaces the custom attributes of a user, attributes
+// whose value is nil are left out. Claims already issued keep the values
+// the attributes had.
+func (s *Service) SetUserAttributes(user *models.OauthUser, attributes map[string]interface{}) error {
+	userAttributes := make([]*models.OauthUserAttribute, 0, len(attributes))
+	for name, value := range attributes {
+		if name == "" || len(name) > maxUserAttributeNameLength {
+			return ErrInvalidUserAttributeName
+		}
+		if value == nil {
+			continue
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		userAttributes = append(userAttributes, models.NewOauthUserAttribute(user, name, string(data)))
+	}
+
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(new(models.OauthUserAttribute)).Error
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+	for _, userAttribute := range userAttributes {
+		if err := tx.Create(userAttribute).Error; err != nil {
+			tx.Rollback() // rollback the transaction
+			return err
+		}
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	return nil
+}
+
+// getUserAttributeClaims returns the claims the attributes of the user are
+// mapped to which the scope releases where the mappings say. Mappings to
+// reserved claims are ignored.
+func (s *Service) getUserAttributeClaims(user *models.OauthUser, scope string, releases func(mapping *config.UserAttributeClaimConfig) bool) (map[string]interface{}, error) {
+	if user == nil {
+		return nil, nil
+	}
+
+	// The attributes are only fetched if the scope releases any of them
+	scopes := strings.Fields(scope)
+	var mappings []*config.UserAttributeClaimConfig
+	for i := range s.cnf.Oauth.UserAttributeClaims {
+		mapping := &s.cnf.Oauth.UserAttributeClaims[i]
+		if !releases(mapping) {
+			continue
+		}
+		if mapping.Scope != "" && !util.StringInSlice(mapping.Scope, scopes) {
+			continue
+		}
+		mappings = append(mappings, mapping)
+	}
+	if len(mappings) == 0 {
+		return nil, nil
+	}
+
+	attributes, err := s.GetUserAttributes(user)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := make(map[string]interface{})
+	for _, mapping := range mappings {
+		value, ok := attributes[mapping.Attribute]
+		if !ok {
+			continue
+		}
+		claim := mapping.Claim
+		if claim == "" {
+			claim = mapping.Attribute
+		}
+		if util.StringInSlice(claim, reservedTokenClaims) || util.StringInSlice(claim, reservedUserClaims) {
+			log.WARNING.Printf("User attribute %s cannot be released as reserved claim %s", mapping.Attribute, claim)
+			continue
+		}
+		claims[claim] = value
+	}
+	return claims, nil
+}
+
+func releasedInUserInfo(mapping *config.UserAttributeClaimConfig) bool {
+	return mapping.UserInfo
+}
+
+func releasedInIDToken(mapping *config.UserAttributeClaimConfig) bool {
+	return mapping.IDToken
+}
+
+func releasedInAccessToken(mapping *config.UserAttributeClaimConfig) bool {
+	return mapping.AccessToken
+}
@@ -0,0 +1,133 @@
+package oauth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/RichardKnop/go-oauth2-server/util/jwt"
+	"github.com/RichardKnop/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestSetUserAttributes() {
+	user := suite.users[1]
+
+	err := suite.service.SetUserAttributes(user, map[string]interface{}{"": "bogus"})
+	assert.Equal(suite.T(), oauth.ErrInvalidUserAttributeName, err)
+	err = suite.service.SetUserAttributes(user, map[string]interface{}{strings.Repeat("a", 101): "bogus"})
+	assert.Equal(suite.T(), oauth.ErrInvalidUserAttributeName, err)
+
+	// Attributes can be any JSON value, nil ones are left out
+	err = suite.service.SetUserAttributes(user, map[string]interface{}{
+		"department": "Engineering",
+		"level":      3,
+		"teams":      []string{"platform", "identity"},
+		"nickname":   nil,
+	})
+	assert.NoError(suite.T(), err)
+	attributes, err := suite.service.GetUserAttributes(user)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), map[string]interface{}{
+			"department": "Engineering",
+			"level":      float64(3),
+			"teams":      []interface{}{"platform", "identity"},
+		}, attributes)
+	}
+
+	// Setting the attributes replaces all of them
+	assert.NoError(suite.T(), suite.service.SetUserAttributes(user, map[string]interface{}{"level": 4}))
+	attributes, err = suite.service.GetUserAttributes(user)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), map[string]interface{}{"level": float64(4)}, attributes)
+	}
+}
+
+func (suite *OauthTestSuite) TestUserAttributeClaims() {
+	suite.cnf.Oauth.UserAttributeClaims = []config.UserAttributeClaimConfig{
+		{Attribute: "department", Scope: "openid", UserInfo: true, IDToken: true},
+		{Attribute: "level", Claim: "employee_level", Scope: "read", AccessToken: true},
+		// Attributes cannot be released as reserved claims
+		{Attribute: "nickname", Claim: "preferred_username", UserInfo: true, IDToken: true},
+	}
+	defer func() { suite.cnf.Oauth.UserAttributeClaims = nil }()
+
+	user := suite.users[0]
+	err := suite.service.SetUserAttributes(user, map[string]interface{}{
+		"department": "Engineering",
+		"level":      3,
+		"nickname":   "clobbered",
+	})
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+
+	// Access tokens carry the attributes their scope releases
+	accessToken, err := suite.service.GrantAccessToken(suite.clients[0], user, 3600, "read")
+	if assert.NoError(suite.T(), err) {
+		assert.JSONEq(suite.T(), `{"employee_level": 3}`, accessToken.Claims)
+	}
+	accessToken, err = suite.service.GrantAccessToken(suite.clients[0], user, 3600, "openid")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	assert.Equal(suite.T(), "", accessToken.Claims)
+
+	// And so do userinfo responses
+	w := suite.requestUserInfo("GET", accessToken.Token)
+	if assert.Equal(suite.T(), 200, w.Code) {
+		var userInfo map[string]interface{}
+		assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &userInfo))
+		assert.Equal(suite.T(), map[string]interface{}{
+			"sub":        user.ID,
+			"department": "Engineering",
+		}, userInfo)
+	}
+
+	// And ID tokens
+	err = suite.db.Create(&models.OauthAuthorizationCode{
+		MyGormModel: models.MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		Code:        "test_code",
+		ExpiresAt:   time.Now().UTC().Add(+10 * time.Second),
+		Client:      suite.clients[0],
+		User:        user,
+		RedirectURI: util.StringOrNull("https://www.example.com"),
+		Scope:       "openid",
+	}).Error
+	assert.NoError(suite.T(), err, "Inserting test data failed")
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/tokens", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
+	r.PostForm = url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {"test_code"},
+		"redirect_uri": {"https://www.example.com"},
+	}
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	if !assert.Equal(suite.T(), 200, w.Code) {
+		return
+	}
+	resp := new(oauth.AccessTokenResponse)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), resp))
+	signingKey, err := oauth.NewSigningKey(&suite.cnf.JWT)
+	assert.NoError(suite.T(), err)
+	claims, err := jwt.Parse(resp.IDToken, signingKey)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), "Engineering", claims["department"])
+		_, ok := claims["preferred_username"]
+		assert.False(suite.T(), ok)
+		_, ok = claims["employee_level"]
+		assert.False(suite.T(), ok)
+	}
+}
@@ -0,0 +1,67 @@
+package oauth
+
+import (
+	"github.com/RichardKnop/go-oauth2-server/log"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+)
+
+// UserAuthenticator authenticates users against a store other than the
+// local users table, such as an LDAP directory. It returns ErrUserNotFound
+// or ErrInvalidUserPassword when it refuses the credentials, other errors
+// are logged and the login refused as temporarily unavailable.
+type UserAuthenticator interface {
+	Authenticate(username, password string) (*AuthenticatedUser, error)
+}
+
+// AuthenticatedUser is a user a UserAuthenticator authenticated
+type AuthenticatedUser struct {
+	// Username of the local user, which is created on the first login
+	Username string
+}
+
+// SetUserAuthenticator sets the authenticator users log in with instead
+// of their local password, see LocalUserFallback of the oauth config
+func (s *Service) SetUserAuthenticator(authenticator UserAuthenticator) {
+	s.userAuthenticator = authenticator
+}
+
+// authExternalUser authenticates the user with the user authenticator and
+// returns the local user
+func (s *Service) authExternalUser(username, password string) (*models.OauthUser, error) {
+	authenticated, err := s.userAuthenticator.Authenticate(username, password)
+	if err == ErrUserNotFound || err == ErrInvalidUserPassword {
+		return nil, err
+	}
+	if err != nil {
+		log.ERROR.Printf("Authenticating user %s failed: %s", username, err)
+		return nil, ErrTemporarilyUnavailable
+	}
+	return s.provisionUser(authenticated.Username)
+}
+
+// provisionUser returns the local user, created on the first login without
+// a password. The email of the user is as verified as the authenticator.
+func (s *Service) provisionUser(username string) (*models.OauthUser, error) {
+	user, err := s.FindUserByUsername(username)
+	if err != ErrUserNotFound {
+		return user, err
+	}
+
+	user, err = s.CreateUser(roles.User, username, "")
+	if err == ErrUsernameTaken {
+		// Another login created the user in the meantime
+		return s.FindUserByUsername(username)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Model(new(models.OauthUser)).Where("id = ?", user.ID).
+		UpdateColumn("email_verified", true).Error
+	if err != nil {
+		return nil, err
+	}
+	user.EmailVerified = true
+	return user, nil
+}
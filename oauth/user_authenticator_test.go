@@ -0,0 +1,90 @@
+package oauth_test
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	"github.com/stretchr/testify/assert"
+)
+
+// testUserAuthenticator is a directory with a single user
+type testUserAuthenticator struct {
+	err error
+}
+
+func (a *testUserAuthenticator) Authenticate(username, password string) (*oauth.AuthenticatedUser, error) {
+	if a.err != nil {
+		return nil, a.err
+	}
+	if username != "jdoe" {
+		return nil, oauth.ErrUserNotFound
+	}
+	if password != "directory_password" {
+		return nil, oauth.ErrInvalidUserPassword
+	}
+	return &oauth.AuthenticatedUser{Username: "jdoe@example.com"}, nil
+}
+
+func (suite *OauthTestSuite) TestUserAuthenticator() {
+	authenticator := new(testUserAuthenticator)
+	suite.service.SetUserAuthenticator(authenticator)
+	defer func() {
+		suite.service.SetUserAuthenticator(nil)
+		suite.cnf.Oauth.LocalUserFallback = false
+	}()
+
+	// The first login creates the local user
+	w := suite.passwordGrantFrom("10.0.0.1:1234", "jdoe", "directory_password")
+	assert.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	user, err := suite.service.FindUserByUsername("jdoe@example.com")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	assert.Equal(suite.T(), roles.User, user.RoleID.String)
+	assert.Equal(suite.T(), "", user.Password.String)
+	assert.True(suite.T(), user.EmailVerified)
+
+	// Later logins reuse it
+	w = suite.passwordGrantFrom("10.0.0.1:1234", "jdoe", "directory_password")
+	assert.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	var count int
+	suite.db.Model(new(models.OauthUser)).Where("username = ?", "jdoe@example.com").Count(&count)
+	assert.Equal(suite.T(), 1, count)
+
+	w = suite.passwordGrantFrom("10.0.0.1:1234", "jdoe", "bogus")
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	// Local users cannot log in without the fallback
+	w = suite.passwordGrantFrom("10.0.0.1:1234", "test@user", "test_password")
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	suite.cnf.Oauth.LocalUserFallback = true
+	w = suite.passwordGrantFrom("10.0.0.1:1234", "test@user", "test_password")
+	assert.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+
+	// The fallback is only for users the directory does not know
+	_, err = suite.service.AuthUser("jdoe", "bogus")
+	assert.Equal(suite.T(), oauth.ErrInvalidUserPassword, err)
+
+	// The directory being down is not a wrong password
+	authenticator.err = errors.New("connection refused")
+	w = suite.passwordGrantFrom("10.0.0.1:1234", "jdoe", "directory_password")
+	assert.Equal(suite.T(), http.StatusServiceUnavailable, w.Code)
+	_, err = suite.service.AuthUser("test@user", "test_password")
+	assert.Equal(suite.T(), oauth.ErrTemporarilyUnavailable, err)
+}
+
+func (suite *OauthTestSuite) TestNewLDAPAuthenticator() {
+	_, err := oauth.NewLDAPAuthenticator(&config.LDAPConfig{URL: "ldaps://ldap.example.com"})
+	assert.NoError(suite.T(), err)
+
+	_, err = oauth.NewLDAPAuthenticator(&config.LDAPConfig{
+		URL:           "ldaps://ldap.example.com",
+		CACertificate: "bogus",
+	})
+	assert.Equal(suite.T(), oauth.ErrInvalidLDAPCACertificate, err)
+}
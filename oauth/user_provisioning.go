@@ -0,0 +1,173 @@
+package oauth
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// maxUsersPerPage limits how many users are listed at once
+	maxUsersPerPage = 100
+)
+
+var (
+	// ErrUserDisabled ...
+	ErrUserDisabled = errors.New("User disabled")
+)
+
+// ListUsers returns a page of users in the order they were created and how
+// many users there are. Users are filtered by username and external ID
+// unless those are empty.
+func (s *Service) ListUsers(username, externalID string, offset, limit int) ([]*models.OauthUser, int, error) {
+	if limit <= 0 || limit > maxUsersPerPage {
+		limit = maxUsersPerPage
+	}
+
+	query := s.db.Model(new(models.OauthUser))
+	if username != "" {
+		query = query.Where("username = ?", strings.ToLower(username))
+	}
+	if externalID != "" {
+		query = query.Where("external_id = ?", externalID)
+	}
+
+	var count int
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []*models.OauthUser
+	err := query.Order("created_at").Offset(offset).Limit(limit).Find(&users).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return users, count, nil
+}
+
+// userDisabled returns true if the user with the ID is disabled
+func (s *Service) userDisabled(id string) bool {
+	var count int
+	s.db.Model(new(models.OauthUser)).Where("id = ? AND disabled = ?", id, true).Count(&count)
+	return count > 0
+}
+
+// SetUserExternalID sets the ID of the user in the system provisioning it
+func (s *Service) SetUserExternalID(user *models.OauthUser, externalID string) error {
+	err := s.db.Model(new(models.OauthUser)).Where("id = ?", user.ID).
+		UpdateColumn("external_id", externalID).Error
+	if err != nil {
+		return err
+	}
+	user.ExternalID = externalID
+	return nil
+}
+
+// SetUserDisabled disables or re-enables a user, the tokens and
+// sessions of a disabled user are revoked
+func (s *Service) SetUserDisabled(user *models.OauthUser, disabled bool) error {
+	var accessTokens []*models.OauthAccessToken
+	if err := s.db.Where("user_id = ?", user.ID).Find(&accessTokens).Error; err != nil {
+		return err
+	}
+
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	if disabled {
+		if err := revokeUserTokens(tx, user); err != nil {
+			tx.Rollback() // rollback the transaction
+			return err
+		}
+	}
+
+	err := tx.Model(new(models.OauthUser)).Where("id = ?", user.ID).
+		UpdateColumn("disabled", disabled).Error
+	if err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+	user.Disabled = disabled
+
+	if disabled {
+		s.invalidateAccessTokens(accessTokens)
+	}
+
+	return nil
+}
+
+// DeleteUser deletes a user, revokes its tokens and sessions and removes it
+// from its groups. The username of a deleted user cannot be reused.
+func (s *Service) DeleteUser(user *models.OauthUser) error {
+	var accessTokens []*models.OauthAccessToken
+	if err := s.db.Where("user_id = ?", user.ID).Find(&accessTokens).Error; err != nil {
+		return err
+	}
+
+	// Begin a transaction
+	tx := s.db.Begin()
+
+	if err := revokeUserTokens(tx, user); err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	// Group memberships, roles, attributes and identity provider accounts
+	// go with the user, linked accounts cannot log in anymore
+	for _, model := range []interface{}{
+		new(models.OauthGroupMember),
+		new(models.OauthUserRole),
+		new(models.OauthUserAttribute),
+		new(models.OauthFederatedIdentity),
+	} {
+		if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(model).Error; err != nil {
+			tx.Rollback() // rollback the transaction
+			return err
+		}
+	}
+
+	// The user itself is soft deleted
+	if err := tx.Delete(user).Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback() // rollback the transaction
+		return err
+	}
+
+	s.invalidateAccessTokens(accessTokens)
+
+	return nil
+}
+
+// revokeUserTokens deletes everything the user could be issued tokens
+// with: its tokens, sessions, device secrets, API keys and pending
+// device and backchannel authorizations
+func revokeUserTokens(tx *gorm.DB, user *models.OauthUser) error {
+	if err := revokeUserSessions(tx, user); err != nil {
+		return err
+	}
+	for _, model := range []interface{}{
+		new(models.OauthDeviceSecret),
+		new(models.OauthAPIKey),
+		new(models.OauthDeviceCode),
+		new(models.OauthBackchannelRequest),
+	} {
+		if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package oauth_test
+
+import (
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestListUsers() {
+	user, err := suite.service.CreateUser(roles.User, "jdoe@example.com", "test_password")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	assert.NoError(suite.T(), suite.service.SetUserExternalID(user, "hr_1"))
+
+	users, count, err := suite.service.ListUsers("", "", 1, 1)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), 3, count)
+		if assert.Len(suite.T(), users, 1) {
+			assert.Equal(suite.T(), "test@user", users[0].Username)
+		}
+	}
+
+	// Users are filtered by username, case insensitively, and external ID
+	users, count, err = suite.service.ListUsers("JDOE@example.com", "", 0, 10)
+	if assert.NoError(suite.T(), err) && assert.Len(suite.T(), users, 1) {
+		assert.Equal(suite.T(), 1, count)
+		assert.Equal(suite.T(), user.ID, users[0].ID)
+	}
+	users, _, err = suite.service.ListUsers("", "hr_1", 0, 10)
+	if assert.NoError(suite.T(), err) && assert.Len(suite.T(), users, 1) {
+		assert.Equal(suite.T(), "hr_1", users[0].ExternalID)
+	}
+}
+
+func (suite *OauthTestSuite) TestSetUserDisabled() {
+	user, err := suite.service.CreateUser(roles.User, "jdoe@example.com", "test_password")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	accessToken, refreshToken, err := suite.service.Login(suite.clients[0], user, "read_write")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+
+	// A disabled user's tokens are revoked and no new ones are issued
+	assert.NoError(suite.T(), suite.service.SetUserDisabled(user, true))
+	_, err = suite.service.Authenticate(accessToken.Token)
+	assert.Equal(suite.T(), oauth.ErrAccessTokenNotFound, err)
+	_, err = suite.service.GetValidRefreshToken(refreshToken.Token, suite.clients[0])
+	assert.Equal(suite.T(), oauth.ErrRefreshTokenNotFound, err)
+	_, _, err = suite.service.Login(suite.clients[0], user, "read_write")
+	assert.Equal(suite.T(), oauth.ErrUserDisabled, err)
+
+	// Tokens issued while the user was being disabled do not work either
+	stale := *user
+	stale.Disabled = false
+	accessToken, _, err = suite.service.Login(suite.clients[0], &stale, "read_write")
+	if assert.NoError(suite.T(), err) {
+		_, err = suite.service.Authenticate(accessToken.Token)
+		assert.Equal(suite.T(), oauth.ErrUserDisabled, err)
+	}
+
+	// Until the user is enabled again
+	assert.NoError(suite.T(), suite.service.SetUserDisabled(user, false))
+	_, _, err = suite.service.Login(suite.clients[0], user, "read_write")
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *OauthTestSuite) TestDeleteUser() {
+	user, err := suite.service.CreateUser(roles.User, "jdoe@example.com", "test_password")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	accessToken, _, err := suite.service.Login(suite.clients[0], user, "read_write")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	group, err := suite.service.CreateGroup("Engineering", "", []string{user.ID})
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+
+	// Deleted users are gone with their tokens and group memberships
+	assert.NoError(suite.T(), suite.service.DeleteUser(user))
+	_, err = suite.service.FindUserByID(user.ID)
+	assert.Equal(suite.T(), oauth.ErrUserNotFound, err)
+	_, err = suite.service.Authenticate(accessToken.Token)
+	assert.Equal(suite.T(), oauth.ErrAccessTokenNotFound, err)
+	members, err := suite.service.ListGroupMembers(group)
+	if assert.NoError(suite.T(), err) {
+		assert.Empty(suite.T(), members)
+	}
+
+	// But their usernames are not handed out again
+	_, err = suite.service.CreateUser(roles.User, "JDOE@example.com", "test_password")
+	assert.Equal(suite.T(), oauth.ErrUsernameTaken, err)
+	err = suite.service.UpdateUsername(suite.users[0], "jdoe@example.com")
+	assert.Equal(suite.T(), oauth.ErrUsernameTaken, err)
+}
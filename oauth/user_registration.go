@@ -0,0 +1,125 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/log"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	"github.com/RichardKnop/go-oauth2-server/oauth/tokentypes"
+)
+
+var (
+	// ErrUserRegistrationDisabled ...
+	ErrUserRegistrationDisabled = errors.New("User registration is disabled")
+)
+
+// UserRegistrationRequest signs up a user, a token is issued to the
+// client for the new user straight away if requested
+type UserRegistrationRequest struct {
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+	IssueToken bool   `json:"issue_token,omitempty"`
+	Scope      string `json:"scope,omitempty"`
+}
+
+// UserRegistrationResponse ...
+type UserRegistrationResponse struct {
+	UserID   string               `json:"user_id"`
+	Username string               `json:"username"`
+	Token    *AccessTokenResponse `json:"token,omitempty"`
+}
+
+// RegisterUser creates a user with the username and password the client
+// signed them up with. The client is issued tokens for the user as if it
+// used the password grant if it asked for them and is allowed to. The user
+// is sent a verification email if a mailer is set.
+func (s *Service) RegisterUser(ctx context.Context, client *models.OauthClient, request *UserRegistrationRequest) (*UserRegistrationResponse, error) {
+	if request.Username == "" {
+		return nil, ErrCannotSetEmptyUsername
+	}
+	// Users signing up always choose a password
+	if err := s.checkPassword(request.Password); err != nil {
+		return nil, err
+	}
+
+	// Check the client could log the user in before creating them
+	if request.IssueToken && (!s.grantTypeEnabled("password") || !ClientAllowsGrantType(client, "password")) {
+		return nil, ErrUnauthorizedClient
+	}
+	// New users have not verified their email address yet
+	if request.IssueToken && s.cnf.Oauth.RequireEmailVerification {
+		return nil, ErrEmailNotVerified
+	}
+	var scope string
+	if request.IssueToken {
+		var err error
+		scope, err = s.getClientScope(client, request.Scope)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Create the user, usernames are unique and passwords hashed with bcrypt
+	user, err := s.CreateUser(roles.User, request.Username, request.Password)
+	if err != nil {
+		return nil, err
+	}
+	resp := &UserRegistrationResponse{UserID: user.ID, Username: user.Username}
+
+	// The user exists either way, so a failed email can be requested again
+	if s.mailer != nil {
+		if err := s.SendEmailVerification(ctx, user); err != nil {
+			log.ERROR.Printf("Sending email verification to new user failed: %s", err)
+		}
+	}
+
+	if !request.IssueToken {
+		return resp, nil
+	}
+	resp.Token, err = s.issueRegistrationToken(client, user, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// issueRegistrationToken logs in the user who just signed up
+func (s *Service) issueRegistrationToken(client *models.OauthClient, user *models.OauthUser, scope string) (*AccessTokenResponse, error) {
+	accessToken, refreshToken, err := s.login(client, user, scope, "password")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.setRefreshTokenGrantType(refreshToken, "password"); err != nil {
+		return nil, err
+	}
+
+	// Tie the tokens to this login
+	session, err := s.startSession(user, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.setSession(accessToken, refreshToken, session); err != nil {
+		return nil, err
+	}
+
+	accessTokenResponse, err := NewAccessTokenResponse(
+		accessToken,
+		refreshToken,
+		s.getAccessTokenLifetime(client, "password"),
+		tokentypes.Bearer,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Hand out a JWT instead of the opaque access token if enabled
+	if err := s.encodeAccessTokenResponse(accessTokenResponse, client); err != nil {
+		return nil, err
+	}
+
+	return accessTokenResponse, nil
+}
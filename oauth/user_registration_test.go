@@ -0,0 +1,81 @@
+package oauth_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestRegisterUser() {
+	// Registration is disabled by default
+	w := suite.registerUser(`{"username": "test@user_signup", "password": "test_password"}`)
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+	suite.cnf.Oauth.EnableUserRegistration = true
+	defer func() { suite.cnf.Oauth.EnableUserRegistration = false }()
+
+	w = suite.registerUser(`{"username": "test@user_signup", "password": "test_password"}`)
+	if !assert.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String()) {
+		return
+	}
+	resp := new(oauth.UserRegistrationResponse)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), resp))
+	assert.Equal(suite.T(), "test@user_signup", resp.Username)
+	assert.Nil(suite.T(), resp.Token)
+
+	// The user can log in with the password
+	user, err := suite.service.AuthUser("test@user_signup", "test_password")
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), resp.UserID, user.ID)
+	}
+
+	// Usernames are unique
+	w = suite.registerUser(`{"username": "Test@User_Signup", "password": "test_password"}`)
+	assert.Equal(suite.T(), http.StatusConflict, w.Code)
+
+	// Passwords are required
+	w = suite.registerUser(`{"username": "test@user_signup_nopassword"}`)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
+
+func (suite *OauthTestSuite) TestRegisterUserIssuesToken() {
+	suite.cnf.Oauth.EnableUserRegistration = true
+	defer func() { suite.cnf.Oauth.EnableUserRegistration = false }()
+
+	w := suite.registerUser(`{
+		"username": "test@user_signup_token",
+		"password": "test_password",
+		"issue_token": true,
+		"scope": "read"
+	}`)
+	if !assert.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String()) {
+		return
+	}
+	resp := new(oauth.UserRegistrationResponse)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), resp))
+	if !assert.NotNil(suite.T(), resp.Token) {
+		return
+	}
+	assert.Equal(suite.T(), "read", resp.Token.Scope)
+	assert.NotEmpty(suite.T(), resp.Token.RefreshToken)
+
+	// The token is issued for the new user
+	accessToken, err := suite.service.Authenticate(resp.Token.AccessToken)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), resp.UserID, accessToken.UserID.String)
+	}
+}
+
+func (suite *OauthTestSuite) registerUser(body string) *httptest.ResponseRecorder {
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/users", bytes.NewBufferString(body))
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.Header.Set("Content-Type", "application/json")
+	r.SetBasicAuth("test_client_1", "test_secret")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	return w
+}
@@ -1,6 +1,7 @@
 package oauth_test
 
 import (
+	"sync"
 	"time"
 
 	"github.com/RichardKnop/go-oauth2-server/models"
@@ -197,8 +198,8 @@ func (suite *OauthTestSuite) TestSetPassword() {
 	err = suite.service.SetPassword(user, "")
 
 	// Correct error should be returned
-	if assert.NotNil(suite.T(), err) {
-		assert.Equal(suite.T(), oauth.ErrPasswordTooShort, err)
+	if assert.IsType(suite.T(), new(pass.PolicyError), err) {
+		assert.Equal(suite.T(), []string{pass.ViolationTooShort}, err.(*pass.PolicyError).Violations)
 	}
 
 	// Try changing the password
@@ -317,3 +318,38 @@ func (suite *OauthTestSuite) TestBlankPassword() {
 		assert.Equal(suite.T(), oauth.ErrUserPasswordNotSet, err)
 	}
 }
+
+func (suite *OauthTestSuite) TestAuthLoginCountsConcurrentFailedLogins() {
+	suite.cnf.Oauth.LoginLockoutThreshold = 100
+	defer func() {
+		suite.cnf.Oauth.LoginLockoutThreshold = 0
+	}()
+
+	_, err := suite.service.CreateUser(roles.User, "test@user_concurrent", "test_password")
+	assert.NoError(suite.T(), err, "Inserting test data failed")
+
+	// Fire many simultaneous bad logins
+	const attempts = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, attempts)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := suite.service.AuthLogin("test@user_concurrent", "bogus", "", "", "192.0.2.1")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.Equal(suite.T(), oauth.ErrInvalidUsernameOrPassword, err)
+	}
+
+	// Every failed attempt should have been counted
+	var count int
+	err = suite.db.Model(new(models.OauthLoginFailure)).
+		Where("username = ?", "test@user_concurrent").Count(&count).Error
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), attempts, count)
+}
@@ -0,0 +1,84 @@
+package oauth
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util"
+)
+
+const (
+	// ProfileScope releases the default profile claims of the user
+	ProfileScope = "profile"
+	// EmailScope releases the email claim of the user
+	EmailScope = "email"
+)
+
+var (
+	// ErrUserInfoScopeRequired ...
+	ErrUserInfoScopeRequired = errors.New("Access token not granted the openid scope for a user")
+)
+
+// getUserInfo returns the claims about the user of the access token,
+// the profile and email scopes decide which claims besides sub are released,
+// see https://openid.net/specs/openid-connect-core-1_0.html#ScopeClaims,
+// attributes of the user are released as the claims mapped to them
+func (s *Service) getUserInfo(accessToken *models.OauthAccessToken) (*UserInfoResponse, error) {
+	scopes := strings.Fields(accessToken.Scope)
+	if !accessToken.UserID.Valid || !util.StringInSlice(OpenIDScope, scopes) {
+		return nil, ErrUserInfoScopeRequired
+	}
+
+	// Fetch the user
+	user := new(models.OauthUser)
+	if s.db.Where("id = ?", accessToken.UserID.String).First(user).RecordNotFound() {
+		return nil, ErrUserNotFound
+	}
+
+	// Pairwise clients are told subject identifiers of their own
+	client := new(models.OauthClient)
+	if accessToken.ClientID.Valid {
+		notFound := s.db.Select("key, subject_type, sector_identifier_uri").
+			Where("id = ?", accessToken.ClientID.String).First(client).RecordNotFound()
+		if notFound {
+			return nil, ErrClientNotFound
+		}
+	}
+
+	userInfo := &UserInfoResponse{Subject: s.subjectIdentifier(client, user.ID)}
+	if util.StringInSlice(ProfileScope, scopes) {
+		userInfo.PreferredUsername = user.Username
+		userInfo.UpdatedAt = user.UpdatedAt.Unix()
+	}
+	// Usernames are email addresses unless registered otherwise
+	if util.StringInSlice(EmailScope, scopes) && strings.Contains(user.Username, "@") {
+		userInfo.Email = user.Username
+	}
+	extra, err := s.getUserAttributeClaims(user, accessToken.Scope, releasedInUserInfo)
+	if err != nil {
+		return nil, err
+	}
+	userInfo.Extra = extra
+
+	return userInfo, nil
+}
+
+// MarshalJSON includes the attribute claims alongside the standard claims
+func (r UserInfoResponse) MarshalJSON() ([]byte, error) {
+	type userInfoResponse UserInfoResponse
+	data, err := json.Marshal(userInfoResponse(r))
+	if err != nil || len(r.Extra) == 0 {
+		return data, err
+	}
+
+	fields := make(map[string]interface{}, len(r.Extra))
+	for name, value := range r.Extra {
+		fields[name] = value
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
@@ -0,0 +1,72 @@
+package oauth_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestUserInfo() {
+	for _, testCase := range []struct {
+		scope    string
+		expected *oauth.UserInfoResponse
+	}{
+		{
+			"openid",
+			&oauth.UserInfoResponse{Subject: suite.users[0].ID},
+		},
+		{
+			"openid profile email",
+			&oauth.UserInfoResponse{
+				Subject:           suite.users[0].ID,
+				PreferredUsername: suite.users[0].Username,
+				UpdatedAt:         suite.users[0].UpdatedAt.Unix(),
+				Email:             suite.users[0].Username,
+			},
+		},
+	} {
+		accessToken, err := suite.service.GrantAccessToken(suite.clients[0], suite.users[0], 3600, testCase.scope)
+		assert.NoError(suite.T(), err)
+
+		// Both GET and POST requests are served
+		for _, method := range []string{"GET", "POST"} {
+			w := suite.requestUserInfo(method, accessToken.Token)
+			assert.Equal(suite.T(), 200, w.Code, testCase.scope)
+			userInfo := new(oauth.UserInfoResponse)
+			assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), userInfo))
+			assert.Equal(suite.T(), testCase.expected, userInfo, testCase.scope)
+		}
+	}
+}
+
+func (suite *OauthTestSuite) TestUserInfoRequiresOpenIDScope() {
+	// Tokens without the openid scope or granted to the client itself are refused
+	withoutOpenID, err := suite.service.GrantAccessToken(suite.clients[0], suite.users[0], 3600, "read profile")
+	assert.NoError(suite.T(), err)
+	withoutUser, err := suite.service.GrantAccessToken(suite.clients[0], nil, 3600, "openid")
+	assert.NoError(suite.T(), err)
+	for _, accessToken := range []*models.OauthAccessToken{withoutOpenID, withoutUser} {
+		w := suite.requestUserInfo("GET", accessToken.Token)
+		assert.Equal(suite.T(), 403, w.Code)
+		assert.Contains(suite.T(), w.Header().Get("WWW-Authenticate"), `error="insufficient_scope"`)
+	}
+
+	// Requests without a valid access token are unauthorized
+	w := suite.requestUserInfo("GET", "bogus")
+	assert.Equal(suite.T(), 401, w.Code)
+}
+
+func (suite *OauthTestSuite) requestUserInfo(method, token string) *httptest.ResponseRecorder {
+	r, err := http.NewRequest(method, "http://1.2.3.4/v1/oauth/userinfo", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	return w
+}
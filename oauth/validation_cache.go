@@ -0,0 +1,207 @@
+package oauth
+
+import (
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+)
+
+const (
+	// defaultValidationCacheTTL is used when the TTL is not configured
+	defaultValidationCacheTTL = 5
+	// defaultValidationCacheMaxEntries is used when the size is not configured
+	defaultValidationCacheMaxEntries = 10000
+)
+
+// ValidationCacheStats counts lookups of the validation cache
+type ValidationCacheStats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+}
+
+// ValidationCache keeps recently validated access tokens, so hot paths do
+// not need a database query for every request. Implementations must not
+// return a token beyond its expiry and must drop tokens when invalidated.
+type ValidationCache interface {
+	Get(token string) (*models.OauthAccessToken, bool)
+	Set(accessToken *models.OauthAccessToken, ttl time.Duration)
+	Invalidate(clientID, userID sql.NullString)
+	Stats() ValidationCacheStats
+}
+
+// MemoryValidationCache is a ValidationCache keeping access tokens in memory
+// keyed by a hash of the token. Invalidation only affects this process.
+// Expired entries are swept as new ones are cached, and once the cache holds
+// maxEntries tokens an entry is evicted for every new one. Entries are also
+// indexed by client and user, so invalidation does not scan the cache.
+type MemoryValidationCache struct {
+	mu         sync.Mutex
+	entries    map[string]*validationCacheEntry
+	owners     map[validationCacheOwner]map[string]struct{}
+	maxEntries int
+	nextSweep  time.Time
+	hits       uint64
+	misses     uint64
+}
+
+type validationCacheEntry struct {
+	accessToken models.OauthAccessToken
+	cachedUntil time.Time
+}
+
+// validationCacheOwner is the client and user access tokens were granted to
+type validationCacheOwner struct {
+	clientID sql.NullString
+	userID   sql.NullString
+}
+
+// NewMemoryValidationCache returns a new MemoryValidationCache instance
+// holding up to maxEntries tokens, 10000 when maxEntries is not positive
+func NewMemoryValidationCache(maxEntries int) *MemoryValidationCache {
+	if maxEntries <= 0 {
+		maxEntries = defaultValidationCacheMaxEntries
+	}
+	return &MemoryValidationCache{
+		entries:    make(map[string]*validationCacheEntry),
+		owners:     make(map[validationCacheOwner]map[string]struct{}),
+		maxEntries: maxEntries,
+	}
+}
+
+// Get returns a copy of the cached access token
+func (c *MemoryValidationCache) Get(token string) (*models.OauthAccessToken, bool) {
+	key := models.HashToken(token)
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok && !time.Now().UTC().Before(entry.cachedUntil) {
+		c.remove(key)
+		ok = false
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		atomic.AddUint64(&c.misses, 1)
+		return nil, false
+	}
+	atomic.AddUint64(&c.hits, 1)
+	accessToken := entry.accessToken
+	return &accessToken, true
+}
+
+// Set caches a copy of the access token for ttl, or until it expires
+func (c *MemoryValidationCache) Set(accessToken *models.OauthAccessToken, ttl time.Duration) {
+	now := time.Now().UTC()
+	cachedUntil := now.Add(ttl)
+	if accessToken.ExpiresAt.Before(cachedUntil) {
+		cachedUntil = accessToken.ExpiresAt
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Entries are cached for ttl at most, so sweeping once per ttl keeps
+	// tokens which are never looked up again from piling up
+	if !now.Before(c.nextSweep) {
+		c.sweep(now)
+		c.nextSweep = now.Add(ttl)
+	}
+	if _, ok := c.entries[accessToken.TokenHash]; !ok && len(c.entries) >= c.maxEntries {
+		c.sweep(now)
+		c.evict(len(c.entries) - c.maxEntries + 1)
+	}
+
+	c.remove(accessToken.TokenHash)
+	c.entries[accessToken.TokenHash] = &validationCacheEntry{
+		accessToken: *accessToken,
+		cachedUntil: cachedUntil,
+	}
+	owner := validationCacheOwner{clientID: accessToken.ClientID, userID: accessToken.UserID}
+	if c.owners[owner] == nil {
+		c.owners[owner] = make(map[string]struct{})
+	}
+	c.owners[owner][accessToken.TokenHash] = struct{}{}
+}
+
+// Len returns the number of cached access tokens, including expired ones
+// not swept yet
+func (c *MemoryValidationCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Invalidate removes all cached access tokens granted to the client for the user
+func (c *MemoryValidationCache) Invalidate(clientID, userID sql.NullString) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.owners[validationCacheOwner{clientID: clientID, userID: userID}] {
+		c.remove(key)
+	}
+}
+
+// sweep removes expired entries, the mutex must be held
+func (c *MemoryValidationCache) sweep(now time.Time) {
+	for key, entry := range c.entries {
+		if !now.Before(entry.cachedUntil) {
+			c.remove(key)
+		}
+	}
+}
+
+// evict removes n arbitrary entries, the mutex must be held
+func (c *MemoryValidationCache) evict(n int) {
+	for key := range c.entries {
+		if n <= 0 {
+			return
+		}
+		c.remove(key)
+		n--
+	}
+}
+
+// remove removes an entry and its index, the mutex must be held
+func (c *MemoryValidationCache) remove(key string) {
+	entry, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	delete(c.entries, key)
+
+	owner := validationCacheOwner{clientID: entry.accessToken.ClientID, userID: entry.accessToken.UserID}
+	delete(c.owners[owner], key)
+	if len(c.owners[owner]) == 0 {
+		delete(c.owners, owner)
+	}
+}
+
+// Stats returns hit and miss counters
+func (c *MemoryValidationCache) Stats() ValidationCacheStats {
+	return ValidationCacheStats{
+		Hits:   atomic.LoadUint64(&c.hits),
+		Misses: atomic.LoadUint64(&c.misses),
+	}
+}
+
+// UseValidationCache replaces the in-memory validation cache, for example
+// with one shared by all instances so revocations take effect everywhere
+func (s *Service) UseValidationCache(cache ValidationCache) {
+	s.validationCache = cache
+}
+
+// ValidationCacheStats returns hit and miss counters of the validation cache
+func (s *Service) ValidationCacheStats() ValidationCacheStats {
+	return s.validationCache.Stats()
+}
+
+// getValidationCacheTTL returns the configured validation cache TTL
+func (s *Service) getValidationCacheTTL() time.Duration {
+	ttl := s.cnf.Oauth.ValidationCacheTTL
+	if ttl <= 0 {
+		ttl = defaultValidationCacheTTL
+	}
+	return time.Duration(ttl) * time.Second
+}
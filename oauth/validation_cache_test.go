@@ -0,0 +1,190 @@
+package oauth_test
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/session"
+	"github.com/RichardKnop/go-oauth2-server/test-util"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestValidationCache() {
+	suite.cnf.Oauth.EnableValidationCache = true
+	defer func() { suite.cnf.Oauth.EnableValidationCache = false }()
+
+	accessToken, refreshToken, err := suite.service.Login(suite.clients[0], suite.users[0], "read_write")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	stats := suite.service.ValidationCacheStats()
+
+	// The first validation is a cache miss
+	_, err = suite.service.Authenticate(accessToken.Token)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), stats.Misses+1, suite.service.ValidationCacheStats().Misses)
+
+	// Delete the token behind the cache's back
+	err = suite.db.Unscoped().Where("token = ?", models.HashToken(accessToken.Token)).Delete(new(models.OauthAccessToken)).Error
+	assert.NoError(suite.T(), err)
+
+	// A cache hit does not query the database
+	cached, err := suite.service.Authenticate(accessToken.Token)
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), accessToken.ID, cached.ID)
+		assert.Equal(suite.T(), "read_write", cached.Scope)
+	}
+	assert.Equal(suite.T(), stats.Hits+1, suite.service.ValidationCacheStats().Hits)
+
+	// But introspection always does
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/introspect", nil)
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.SetBasicAuth("test_client_1", "test_secret")
+	r.PostForm = url.Values{"token": {accessToken.Token}}
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	testutil.TestResponseObject(suite.T(), w, &oauth.IntrospectResponse{Active: false}, 200)
+
+	// Revoking the tokens busts the cache
+	assert.NoError(suite.T(), suite.db.Create(cached).Error)
+	suite.service.ClearUserTokens(&session.UserSession{
+		AccessToken:  accessToken.Token,
+		RefreshToken: refreshToken.Token,
+	})
+	_, err = suite.service.Authenticate(accessToken.Token)
+	assert.Equal(suite.T(), oauth.ErrAccessTokenNotFound, err)
+}
+
+func (suite *OauthTestSuite) TestValidationCacheRespectsTokenExpiry() {
+	suite.cnf.Oauth.EnableValidationCache = true
+	suite.cnf.Oauth.ValidationCacheTTL = 60
+	defer func() {
+		suite.cnf.Oauth.EnableValidationCache = false
+		suite.cnf.Oauth.ValidationCacheTTL = 0
+	}()
+
+	accessToken, err := suite.service.GrantAccessToken(suite.clients[0], suite.users[0], 1, "read")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	_, err = suite.service.Authenticate(accessToken.Token)
+	assert.NoError(suite.T(), err)
+
+	// The token is not served from the cache once expired
+	time.Sleep(time.Until(accessToken.ExpiresAt))
+	_, err = suite.service.Authenticate(accessToken.Token)
+	assert.Equal(suite.T(), oauth.ErrAccessTokenExpired, err)
+}
+
+func (suite *OauthTestSuite) TestValidationCacheEviction() {
+	cache := oauth.NewMemoryValidationCache(2)
+	newToken := func(token string) *models.OauthAccessToken {
+		return &models.OauthAccessToken{
+			Token:     token,
+			TokenHash: models.HashToken(token),
+			ExpiresAt: time.Now().UTC().Add(time.Hour),
+		}
+	}
+
+	// Expired entries are dropped as new ones are cached, without a lookup
+	cache.Set(newToken("short_lived"), time.Millisecond)
+	time.Sleep(2 * time.Millisecond)
+	cache.Set(newToken("token_1"), time.Minute)
+	assert.Equal(suite.T(), 1, cache.Len())
+	_, ok := cache.Get("short_lived")
+	assert.False(suite.T(), ok)
+
+	// And a full cache makes room for new entries
+	cache.Set(newToken("token_2"), time.Minute)
+	cache.Set(newToken("token_3"), time.Minute)
+	assert.Equal(suite.T(), 2, cache.Len())
+	_, ok = cache.Get("token_3")
+	assert.True(suite.T(), ok)
+}
+
+func (suite *OauthTestSuite) TestValidationCacheInvalidate() {
+	cache := oauth.NewMemoryValidationCache(0)
+	newToken := func(token, userID string) *models.OauthAccessToken {
+		return &models.OauthAccessToken{
+			ClientID:  sql.NullString{String: "test_client", Valid: true},
+			UserID:    sql.NullString{String: userID, Valid: true},
+			Token:     token,
+			TokenHash: models.HashToken(token),
+			ExpiresAt: time.Now().UTC().Add(time.Hour),
+		}
+	}
+	cache.Set(newToken("token_1", "user_1"), time.Minute)
+	cache.Set(newToken("token_2", "user_1"), time.Minute)
+	cache.Set(newToken("token_3", "user_2"), time.Minute)
+
+	// Only the tokens of the client and user are removed
+	cache.Invalidate(
+		sql.NullString{String: "test_client", Valid: true},
+		sql.NullString{String: "user_1", Valid: true},
+	)
+	assert.Equal(suite.T(), 1, cache.Len())
+	_, ok := cache.Get("token_3")
+	assert.True(suite.T(), ok)
+
+	// A token cached again for another user moves with it
+	cache.Set(newToken("token_3", "user_1"), time.Minute)
+	cache.Invalidate(
+		sql.NullString{String: "test_client", Valid: true},
+		sql.NullString{String: "user_2", Valid: true},
+	)
+	assert.Equal(suite.T(), 1, cache.Len())
+}
+
+func (suite *OauthTestSuite) TestUseValidationCache() {
+	suite.cnf.Oauth.EnableValidationCache = true
+	cache := &testValidationCache{MemoryValidationCache: oauth.NewMemoryValidationCache(0)}
+	suite.service.UseValidationCache(cache)
+	defer func() {
+		suite.cnf.Oauth.EnableValidationCache = false
+		suite.service.UseValidationCache(oauth.NewMemoryValidationCache(0))
+	}()
+
+	accessToken, refreshToken, err := suite.service.Login(suite.clients[0], suite.users[0], "read_write")
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+
+	// The second validation is served from the cache
+	for i := 0; i < 2; i++ {
+		_, err = suite.service.Authenticate(accessToken.Token)
+		assert.NoError(suite.T(), err)
+	}
+	assert.Equal(suite.T(), 1, cache.sets)
+	assert.Equal(suite.T(), oauth.ValidationCacheStats{Hits: 1, Misses: 1}, suite.service.ValidationCacheStats())
+
+	// And revocation removes the cached entry
+	suite.service.ClearUserTokens(&session.UserSession{
+		AccessToken:  accessToken.Token,
+		RefreshToken: refreshToken.Token,
+	})
+	assert.Equal(suite.T(), 1, cache.invalidations)
+	_, ok := cache.Get(accessToken.Token)
+	assert.False(suite.T(), ok)
+}
+
+// testValidationCache counts writes to the in-memory cache
+type testValidationCache struct {
+	*oauth.MemoryValidationCache
+	sets          int
+	invalidations int
+}
+
+func (c *testValidationCache) Set(accessToken *models.OauthAccessToken, ttl time.Duration) {
+	c.sets++
+	c.MemoryValidationCache.Set(accessToken, ttl)
+}
+
+func (c *testValidationCache) Invalidate(clientID, userID sql.NullString) {
+	c.invalidations++
+	c.MemoryValidationCache.Invalidate(clientID, userID)
+}
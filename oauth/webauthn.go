@@ -0,0 +1,405 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/log"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/RichardKnop/go-oauth2-server/util/webauthn"
+	"github.com/RichardKnop/uuid"
+)
+
+const (
+	// WebAuthnGrantType logs users in with a passkey instead of a password
+	WebAuthnGrantType = "webauthn"
+	// webAuthnChallengeLifetime is how many seconds users have to use their authenticator
+	webAuthnChallengeLifetime = 300
+	// maxWebAuthnCredentialNameLength is the length of the name column
+	maxWebAuthnCredentialNameLength = 100
+)
+
+var (
+	// ErrWebAuthnDisabled ...
+	ErrWebAuthnDisabled = errors.New("Passkeys are disabled")
+	// ErrInvalidWebAuthnChallenge ...
+	ErrInvalidWebAuthnChallenge = errors.New("Invalid or expired passkey challenge")
+	// ErrInvalidWebAuthnCredential ...
+	ErrInvalidWebAuthnCredential = errors.New("Invalid passkey registration")
+	// ErrWebAuthnCredentialExists ...
+	ErrWebAuthnCredentialExists = errors.New("Passkey already registered")
+	// ErrInvalidWebAuthnAssertion ...
+	ErrInvalidWebAuthnAssertion = errors.New("Invalid passkey")
+)
+
+// WebAuthnRelyingParty identifies this server to authenticators
+type WebAuthnRelyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// WebAuthnUser is the account a credential is registered for
+type WebAuthnUser struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+// WebAuthnCredentialParameters is an accepted credential algorithm
+type WebAuthnCredentialParameters struct {
+	Type      string `json:"type"`
+	Algorithm int    `json:"alg"`
+}
+
+// WebAuthnCredentialDescriptor names a registered credential
+type WebAuthnCredentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// WebAuthnAuthenticatorSelection states the authenticator requirements
+type WebAuthnAuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// WebAuthnCreationOptions are the options for navigator.credentials.create(),
+// binary members are base64url encoded
+type WebAuthnCreationOptions struct {
+	Challenge              string                         `json:"challenge"`
+	RelyingParty           WebAuthnRelyingParty           `json:"rp"`
+	User                   WebAuthnUser                   `json:"user"`
+	CredentialParameters   []WebAuthnCredentialParameters `json:"pubKeyCredParams"`
+	Timeout                int                            `json:"timeout"`
+	Attestation            string                         `json:"attestation"`
+	ExcludeCredentials     []WebAuthnCredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection WebAuthnAuthenticatorSelection `json:"authenticatorSelection"`
+}
+
+// WebAuthnRequestOptions are the options for navigator.credentials.get(),
+// binary members are base64url encoded
+type WebAuthnRequestOptions struct {
+	Challenge        string                         `json:"challenge"`
+	RelyingPartyID   string                         `json:"rpId"`
+	Timeout          int                            `json:"timeout"`
+	AllowCredentials []WebAuthnCredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                         `json:"userVerification"`
+}
+
+// WebAuthnRegistrationRequest is the credential created by the authenticator,
+// binary members are base64url encoded
+type WebAuthnRegistrationRequest struct {
+	ID                string `json:"id"`
+	ClientDataJSON    string `json:"client_data_json"`
+	AttestationObject string `json:"attestation_object"`
+	Name              string `json:"name"`
+}
+
+// WebAuthnRegistrationResponse ...
+type WebAuthnRegistrationResponse struct {
+	CredentialID string `json:"credential_id"`
+	Name         string `json:"name"`
+}
+
+// WebAuthnLoginRequest optionally names the user logging in, without
+// a username any discoverable credential of the user can be used
+type WebAuthnLoginRequest struct {
+	Username string `json:"username"`
+}
+
+// BeginWebAuthnRegistration returns the options for creating a new passkey of the user
+func (s *Service) BeginWebAuthnRegistration(user *models.OauthUser) (*WebAuthnCreationOptions, error) {
+	if !s.webAuthnEnabled() {
+		return nil, ErrWebAuthnDisabled
+	}
+
+	challenge, err := s.newWebAuthnChallenge(user, webauthn.TypeCreate)
+	if err != nil {
+		return nil, err
+	}
+
+	// The same authenticator cannot be registered twice
+	excludeCredentials, err := s.webAuthnCredentialDescriptors(user)
+	if err != nil {
+		return nil, err
+	}
+
+	credentialParameters := make([]WebAuthnCredentialParameters, len(webauthn.SupportedAlgorithms))
+	for i, algorithm := range webauthn.SupportedAlgorithms {
+		credentialParameters[i] = WebAuthnCredentialParameters{Type: "public-key", Algorithm: algorithm}
+	}
+
+	rpName := s.cnf.Oauth.WebAuthnRPName
+	if rpName == "" {
+		rpName = s.cnf.Oauth.WebAuthnRPID
+	}
+
+	return &WebAuthnCreationOptions{
+		Challenge:    challenge,
+		RelyingParty: WebAuthnRelyingParty{ID: s.cnf.Oauth.WebAuthnRPID, Name: rpName},
+		User: WebAuthnUser{
+			ID:          base64.RawURLEncoding.EncodeToString([]byte(user.ID)),
+			Name:        user.Username,
+			DisplayName: user.Username,
+		},
+		CredentialParameters: credentialParameters,
+		Timeout:              webAuthnChallengeLifetime * 1000,
+		Attestation:          "none",
+		ExcludeCredentials:   excludeCredentials,
+		AuthenticatorSelection: WebAuthnAuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: "required",
+		},
+	}, nil
+}
+
+// FinishWebAuthnRegistration verifies and stores the credential created with
+// the options of BeginWebAuthnRegistration. The authenticator must have
+// verified the user as the credential replaces the password when logging in.
+func (s *Service) FinishWebAuthnRegistration(user *models.OauthUser, request *WebAuthnRegistrationRequest) (*models.OauthWebAuthnCredential, error) {
+	if !s.webAuthnEnabled() {
+		return nil, ErrWebAuthnDisabled
+	}
+
+	name := strings.TrimSpace(request.Name)
+	if len(name) > maxWebAuthnCredentialNameLength {
+		return nil, ErrInvalidWebAuthnCredential
+	}
+	clientDataJSON, err1 := decodeWebAuthnField(request.ClientDataJSON)
+	attestationObject, err2 := decodeWebAuthnField(request.AttestationObject)
+	if err1 != nil || err2 != nil {
+		return nil, ErrInvalidWebAuthnCredential
+	}
+
+	clientData, err := webauthn.ParseClientData(clientDataJSON, webauthn.TypeCreate, s.webAuthnOrigins())
+	if err != nil {
+		log.INFO.Printf("Passkey registration refused: %s", err)
+		return nil, ErrInvalidWebAuthnCredential
+	}
+	challenge, err := s.consumeWebAuthnChallenge(clientData.Challenge, webauthn.TypeCreate)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.UserID.String != user.ID {
+		return nil, ErrInvalidWebAuthnChallenge
+	}
+
+	authenticatorData, err := webauthn.ParseAttestationObject(attestationObject)
+	if err == nil {
+		err = s.checkWebAuthnAuthenticatorData(authenticatorData)
+	}
+	if err == nil {
+		_, err = webauthn.ParsePublicKey(authenticatorData.PublicKey)
+	}
+	if err != nil {
+		log.INFO.Printf("Passkey registration refused: %s", err)
+		return nil, ErrInvalidWebAuthnCredential
+	}
+
+	credentialID := base64.RawURLEncoding.EncodeToString(authenticatorData.CredentialID)
+	if request.ID != "" && strings.TrimRight(request.ID, "=") != credentialID {
+		return nil, ErrInvalidWebAuthnCredential
+	}
+	if !s.db.Where("credential_id = ?", credentialID).First(new(models.OauthWebAuthnCredential)).RecordNotFound() {
+		return nil, ErrWebAuthnCredentialExists
+	}
+
+	credential := &models.OauthWebAuthnCredential{
+		MyGormModel: models.MyGormModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+		},
+		UserID:       util.StringOrNull(user.ID),
+		CredentialID: credentialID,
+		PublicKey:    base64.RawURLEncoding.EncodeToString(authenticatorData.PublicKey),
+		SignCount:    int64(authenticatorData.SignCount),
+		Name:         name,
+	}
+	if err := s.db.Create(credential).Error; err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
+
+// BeginWebAuthnLogin returns the options for logging in with a passkey. Unknown
+// users are sent options without credentials rather than an error so the
+// response does not tell whether the user exists.
+func (s *Service) BeginWebAuthnLogin(username string) (*WebAuthnRequestOptions, error) {
+	if !s.webAuthnEnabled() {
+		return nil, ErrWebAuthnDisabled
+	}
+
+	var user *models.OauthUser
+	if username != "" {
+		var err error
+		user, err = s.FindUserByUsername(username)
+		if err != nil && err != ErrUserNotFound {
+			return nil, err
+		}
+	}
+
+	challenge, err := s.newWebAuthnChallenge(user, webauthn.TypeGet)
+	if err != nil {
+		return nil, err
+	}
+
+	allowCredentials := []WebAuthnCredentialDescriptor{}
+	if user != nil {
+		if allowCredentials, err = s.webAuthnCredentialDescriptors(user); err != nil {
+			return nil, err
+		}
+	}
+
+	return &WebAuthnRequestOptions{
+		Challenge:        challenge,
+		RelyingPartyID:   s.cnf.Oauth.WebAuthnRPID,
+		Timeout:          webAuthnChallengeLifetime * 1000,
+		AllowCredentials: allowCredentials,
+		UserVerification: "required",
+	}, nil
+}
+
+// authWebAuthnUser verifies a passkey assertion answering a challenge of
+// BeginWebAuthnLogin and returns the user the passkey belongs to
+func (s *Service) authWebAuthnUser(credentialID, clientDataJSON, authenticatorData, signature string) (*models.OauthUser, error) {
+	credential := new(models.OauthWebAuthnCredential)
+	notFound := s.db.Where("credential_id = ?", strings.TrimRight(credentialID, "=")).
+		First(credential).RecordNotFound()
+	if credentialID == "" || notFound {
+		return nil, ErrInvalidWebAuthnAssertion
+	}
+
+	clientDataBytes, err1 := decodeWebAuthnField(clientDataJSON)
+	authenticatorDataBytes, err2 := decodeWebAuthnField(authenticatorData)
+	signatureBytes, err3 := decodeWebAuthnField(signature)
+	publicKey, err4 := decodeWebAuthnField(credential.PublicKey)
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+		return nil, ErrInvalidWebAuthnAssertion
+	}
+
+	clientData, err := webauthn.ParseClientData(clientDataBytes, webauthn.TypeGet, s.webAuthnOrigins())
+	if err != nil {
+		log.INFO.Printf("Passkey login refused: %s", err)
+		return nil, ErrInvalidWebAuthnAssertion
+	}
+	challenge, err := s.consumeWebAuthnChallenge(clientData.Challenge, webauthn.TypeGet)
+	if err != nil {
+		return nil, err
+	}
+	// The challenge may have been issued for a particular user
+	if challenge.UserID.Valid && challenge.UserID.String != credential.UserID.String {
+		return nil, ErrInvalidWebAuthnAssertion
+	}
+
+	parsed, err := webauthn.ParseAuthenticatorData(authenticatorDataBytes)
+	if err == nil {
+		err = s.checkWebAuthnAuthenticatorData(parsed)
+	}
+	if err == nil {
+		err = webauthn.VerifyAssertion(publicKey, authenticatorDataBytes, clientDataBytes, signatureBytes)
+	}
+	if err != nil {
+		log.INFO.Printf("Passkey login refused: %s", err)
+		return nil, ErrInvalidWebAuthnAssertion
+	}
+
+	// A counter that does not increase suggests the authenticator was
+	// cloned, authenticators not implementing one always report zero
+	signCount := int64(parsed.SignCount)
+	if (signCount != 0 || credential.SignCount != 0) && signCount <= credential.SignCount {
+		log.INFO.Printf("Passkey %s signature counter did not increase", credential.ID)
+		return nil, ErrInvalidWebAuthnAssertion
+	}
+	err = s.db.Model(new(models.OauthWebAuthnCredential)).Where("id = ?", credential.ID).
+		UpdateColumns(map[string]interface{}{
+			"sign_count":   signCount,
+			"last_used_at": time.Now().UTC(),
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return s.FindUserByID(credential.UserID.String)
+}
+
+// checkWebAuthnAuthenticatorData checks the authenticator data was produced
+// for this server after the authenticator verified the user
+func (s *Service) checkWebAuthnAuthenticatorData(authenticatorData *webauthn.AuthenticatorData) error {
+	if err := authenticatorData.CheckRPID(s.cnf.Oauth.WebAuthnRPID); err != nil {
+		return err
+	}
+	if !authenticatorData.UserPresent() || !authenticatorData.UserVerified() {
+		return ErrInvalidWebAuthnAssertion
+	}
+	return nil
+}
+
+// newWebAuthnChallenge stores and returns a random challenge
+func (s *Service) newWebAuthnChallenge(user *models.OauthUser, challengeType string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	challenge := base64.RawURLEncoding.EncodeToString(b)
+
+	record := models.NewOauthWebAuthnChallenge(user, models.HashToken(challenge), challengeType, webAuthnChallengeLifetime)
+	if err := s.db.Create(record).Error; err != nil {
+		return "", err
+	}
+	return challenge, nil
+}
+
+// consumeWebAuthnChallenge deletes the challenge so it can only be answered once
+func (s *Service) consumeWebAuthnChallenge(challenge, challengeType string) (*models.OauthWebAuthnChallenge, error) {
+	record := new(models.OauthWebAuthnChallenge)
+	notFound := s.db.Where("challenge_hash = ? AND type = ?", models.HashToken(challenge), challengeType).
+		First(record).RecordNotFound()
+	if notFound {
+		return nil, ErrInvalidWebAuthnChallenge
+	}
+
+	// Consuming the challenge fails if it was answered concurrently
+	result := s.db.Unscoped().Where("id = ?", record.ID).Delete(new(models.OauthWebAuthnChallenge))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 || time.Now().UTC().After(record.ExpiresAt) {
+		return nil, ErrInvalidWebAuthnChallenge
+	}
+	return record, nil
+}
+
+// webAuthnCredentialDescriptors lists the registered credentials of the user
+func (s *Service) webAuthnCredentialDescriptors(user *models.OauthUser) ([]WebAuthnCredentialDescriptor, error) {
+	var credentials []*models.OauthWebAuthnCredential
+	if err := s.db.Where("user_id = ?", user.ID).Order("created_at").Find(&credentials).Error; err != nil {
+		return nil, err
+	}
+	descriptors := make([]WebAuthnCredentialDescriptor, len(credentials))
+	for i, credential := range credentials {
+		descriptors[i] = WebAuthnCredentialDescriptor{Type: "public-key", ID: credential.CredentialID}
+	}
+	return descriptors, nil
+}
+
+// webAuthnEnabled returns true once the relying party ID is configured
+func (s *Service) webAuthnEnabled() bool {
+	return s.cnf.Oauth.WebAuthnRPID != ""
+}
+
+// webAuthnOrigins returns the origins of the pages allowed to use passkeys
+func (s *Service) webAuthnOrigins() []string {
+	if len(s.cnf.Oauth.WebAuthnOrigins) > 0 {
+		return s.cnf.Oauth.WebAuthnOrigins
+	}
+	return []string{"https://" + s.cnf.Oauth.WebAuthnRPID}
+}
+
+// decodeWebAuthnField decodes a base64url encoded member, with or without padding
+func decodeWebAuthnField(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
@@ -0,0 +1,216 @@
+package oauth_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	"github.com/RichardKnop/go-oauth2-server/test-util"
+	"github.com/RichardKnop/go-oauth2-server/util/cbor"
+	"github.com/RichardKnop/go-oauth2-server/util/webauthn"
+	"github.com/stretchr/testify/assert"
+)
+
+func (suite *OauthTestSuite) TestWebAuthn() {
+	// Passkeys are disabled until the relying party is configured
+	w := suite.webAuthnLogin(`{"username": "test@user"}`)
+	testutil.TestResponseForError(suite.T(), w, oauth.ErrWebAuthnDisabled.Error(), 403)
+
+	suite.cnf.Oauth.WebAuthnRPID = "example.com"
+	suite.cnf.Oauth.WebAuthnOrigins = []string{"https://example.com"}
+	defer func() {
+		suite.cnf.Oauth.WebAuthnRPID = ""
+		suite.cnf.Oauth.WebAuthnOrigins = nil
+	}()
+
+	_, err := suite.service.CreateUser(roles.User, "test@user_passkey", "test_password")
+	if !assert.NoError(suite.T(), err, "Inserting test data failed") {
+		return
+	}
+	token := suite.accessTokenFromGrant(suite.passwordGrantWithAcr("test@user_passkey", "", ""))
+	authenticator := newTestAuthenticator()
+
+	// The authenticated user registers a passkey
+	w = suite.webAuthnRequest("/register", token, "")
+	if !assert.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String()) {
+		return
+	}
+	creation := new(oauth.WebAuthnCreationOptions)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), creation))
+	assert.Equal(suite.T(), "example.com", creation.RelyingParty.ID)
+	assert.Equal(suite.T(), "test@user_passkey", creation.User.Name)
+	assert.Empty(suite.T(), creation.ExcludeCredentials)
+
+	// Registrations for another origin are refused
+	body := authenticator.register(creation.Challenge, "https://evil.example.org")
+	w = suite.webAuthnRequest("/register/confirm", token, body)
+	testutil.TestResponseForError(suite.T(), w, oauth.ErrInvalidWebAuthnCredential.Error(), 400)
+
+	w = suite.webAuthnRequest("/register", token, "")
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), creation))
+	body = authenticator.register(creation.Challenge, "https://example.com")
+	w = suite.webAuthnRequest("/register/confirm", token, body)
+	if !assert.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String()) {
+		return
+	}
+
+	// Each challenge can only be answered once
+	w = suite.webAuthnRequest("/register/confirm", token, body)
+	testutil.TestResponseForError(suite.T(), w, oauth.ErrInvalidWebAuthnChallenge.Error(), 400)
+
+	// The user logs in with the passkey instead of the password
+	w = suite.webAuthnLogin(`{"username": "test@user_passkey"}`)
+	if !assert.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String()) {
+		return
+	}
+	request := new(oauth.WebAuthnRequestOptions)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), request))
+	if assert.Len(suite.T(), request.AllowCredentials, 1) {
+		assert.Equal(suite.T(), authenticator.id(), request.AllowCredentials[0].ID)
+	}
+	authenticator.signCount = 5
+	assertion := authenticator.assert(request.Challenge, "https://example.com")
+	w = suite.webAuthnGrant(assertion)
+	suite.assertAcr(w, oauth.AcrMFA)
+
+	// Assertions cannot be replayed
+	w = suite.webAuthnGrant(assertion)
+	testutil.TestResponseForOauthError(suite.T(), w, "invalid_grant", oauth.ErrInvalidWebAuthnChallenge.Error(), 400)
+
+	// Unknown users cannot be told apart from users without passkeys
+	w = suite.webAuthnLogin(`{"username": "test@user_bogus"}`)
+	if !assert.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String()) {
+		return
+	}
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), request))
+	assert.Empty(suite.T(), request.AllowCredentials)
+
+	// Signatures of another key are refused
+	w = suite.webAuthnLogin(`{}`)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), request))
+	assertion = authenticator.assert(request.Challenge, "https://example.com")
+	assertion.Set("signature", newTestAuthenticator().assert(request.Challenge, "https://example.com").Get("signature"))
+	w = suite.webAuthnGrant(assertion)
+	testutil.TestResponseForOauthError(suite.T(), w, "invalid_grant", oauth.ErrInvalidWebAuthnAssertion.Error(), 400)
+
+	// A cloned authenticator is detected by its signature counter
+	w = suite.webAuthnLogin(`{}`)
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), request))
+	authenticator.signCount = 3
+	w = suite.webAuthnGrant(authenticator.assert(request.Challenge, "https://example.com"))
+	testutil.TestResponseForOauthError(suite.T(), w, "invalid_grant", oauth.ErrInvalidWebAuthnAssertion.Error(), 400)
+}
+
+// testAuthenticator is a virtual authenticator holding a single P-256 credential
+type testAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	signCount    uint32
+}
+
+func newTestAuthenticator() *testAuthenticator {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	credentialID := make([]byte, 16)
+	rand.Read(credentialID)
+	return &testAuthenticator{key: key, credentialID: credentialID}
+}
+
+func (a *testAuthenticator) id() string {
+	return base64.RawURLEncoding.EncodeToString(a.credentialID)
+}
+
+// authenticatorData returns the authenticator data, with the attested
+// credential when registering
+func (a *testAuthenticator) authenticatorData(attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte("example.com"))
+	data := append([]byte(nil), rpIDHash[:]...)
+	flags := byte(webauthn.FlagUserPresent | webauthn.FlagUserVerified)
+	if attested {
+		flags |= webauthn.FlagAttestedCredentialData
+	}
+	data = append(data, flags)
+	data = append(data, make([]byte, 4)...)
+	binary.BigEndian.PutUint32(data[33:], a.signCount)
+	if attested {
+		publicKey, _ := webauthn.EncodePublicKey(&a.key.PublicKey)
+		data = append(data, make([]byte, 16)...)
+		data = append(data, byte(len(a.credentialID)>>8), byte(len(a.credentialID)))
+		data = append(data, a.credentialID...)
+		data = append(data, publicKey...)
+	}
+	return data
+}
+
+func (a *testAuthenticator) clientData(clientDataType, challenge, origin string) []byte {
+	clientData, _ := json.Marshal(&webauthn.ClientData{Type: clientDataType, Challenge: challenge, Origin: origin})
+	return clientData
+}
+
+// register returns the body of a registration request
+func (a *testAuthenticator) register(challenge, origin string) string {
+	attestationObject, _ := cbor.Encode(map[interface{}]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[interface{}]interface{}{},
+		"authData": a.authenticatorData(true),
+	})
+	body, _ := json.Marshal(&oauth.WebAuthnRegistrationRequest{
+		ID:                a.id(),
+		ClientDataJSON:    base64.RawURLEncoding.EncodeToString(a.clientData(webauthn.TypeCreate, challenge, origin)),
+		AttestationObject: base64.RawURLEncoding.EncodeToString(attestationObject),
+		Name:              "Test key",
+	})
+	return string(body)
+}
+
+// assert returns the form of a webauthn grant
+func (a *testAuthenticator) assert(challenge, origin string) url.Values {
+	authenticatorData := a.authenticatorData(false)
+	clientData := a.clientData(webauthn.TypeGet, challenge, origin)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte(nil), authenticatorData...), clientDataHash[:]...))
+	signature, _ := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	return url.Values{
+		"grant_type":         {"webauthn"},
+		"credential_id":      {a.id()},
+		"client_data_json":   {base64.RawURLEncoding.EncodeToString(clientData)},
+		"authenticator_data": {base64.RawURLEncoding.EncodeToString(authenticatorData)},
+		"signature":          {base64.RawURLEncoding.EncodeToString(signature)},
+	}
+}
+
+func (suite *OauthTestSuite) webAuthnRequest(path, token, body string) *httptest.ResponseRecorder {
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/webauthn"+path, bytes.NewBufferString(body))
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	return w
+}
+
+func (suite *OauthTestSuite) webAuthnLogin(body string) *httptest.ResponseRecorder {
+	r, err := http.NewRequest("POST", "http://1.2.3.4/v1/oauth/webauthn/login", bytes.NewBufferString(body))
+	assert.NoError(suite.T(), err, "Request setup should not get an error")
+	r.Header.Set("Content-Type", "application/json")
+	r.SetBasicAuth("test_client_1", "test_secret")
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, r)
+	return w
+}
+
+func (suite *OauthTestSuite) webAuthnGrant(form url.Values) *httptest.ResponseRecorder {
+	return suite.tokenRequest(form, nil)
+}
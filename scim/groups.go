@@ -0,0 +1,308 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/util/response"
+	"github.com/gorilla/mux"
+)
+
+var (
+	// memberPathPattern matches the path of a single member,
+	// such as members[value eq "2819c223"]
+	memberPathPattern = regexp.MustCompile(`^(?i:members)\[\s*(?i:value)\s+(?i:eq)\s+("(?:[^"\\]|\\.)*")\s*\]$`)
+)
+
+// groupPatch is a patch of a group worked out from the patch operations
+type groupPatch struct {
+	displayName string
+	externalID  string
+	// memberChanges add, remove or set members in the order requested
+	memberChanges []func(group *models.OauthGroup) error
+}
+
+// listGroups returns a page of groups, optionally filtered by displayName
+// (GET /scim/v2/Groups?filter=displayName eq "Engineering")
+func (s *Service) listGroups(w http.ResponseWriter, r *http.Request) {
+	startIndex, count, err := parsePage(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	attribute, displayName, err := parseFilter(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if attribute != "" && attribute != "displayname" {
+		writeError(w, ErrInvalidFilter)
+		return
+	}
+
+	// Filtering by an empty value finds nothing rather than everything
+	resources := []*Group{}
+	if attribute != "" && displayName == "" {
+		writeResource(w, NewListResponse(0, startIndex, 0, resources), http.StatusOK)
+		return
+	}
+
+	// A count of zero only asks for the total
+	limit := count
+	if limit == 0 {
+		limit = 1
+	}
+	groups, total, err := s.oauthService.ListGroups(displayName, startIndex-1, limit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if count == 0 {
+		groups = nil
+	}
+	for _, group := range groups {
+		resource, err := s.newGroup(r, group)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		resources = append(resources, resource)
+	}
+
+	writeResource(w, NewListResponse(total, startIndex, len(resources), resources), http.StatusOK)
+}
+
+// createGroup provisions a group (POST /scim/v2/Groups)
+func (s *Service) createGroup(w http.ResponseWriter, r *http.Request) {
+	groupRequest := new(GroupRequest)
+	if err := decodeRequest(r, groupRequest); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	group, err := s.oauthService.CreateGroup(
+		groupRequest.DisplayName,
+		groupRequest.ExternalID,
+		memberIDs(groupRequest.Members),
+	)
+	if err != nil {
+		writeError(w, memberError(err))
+		return
+	}
+
+	resource, err := s.newGroup(r, group)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Location", resource.Meta.Location)
+	writeResource(w, resource, http.StatusCreated)
+}
+
+// getGroup returns a group (GET /scim/v2/Groups/{id})
+func (s *Service) getGroup(w http.ResponseWriter, r *http.Request) {
+	group, err := s.oauthService.FindGroupByID(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.writeGroup(w, r, group)
+}
+
+// replaceGroup replaces the attributes and members of a group
+// (PUT /scim/v2/Groups/{id})
+func (s *Service) replaceGroup(w http.ResponseWriter, r *http.Request) {
+	group, err := s.oauthService.FindGroupByID(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	groupRequest := new(GroupRequest)
+	if err := decodeRequest(r, groupRequest); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := s.oauthService.UpdateGroup(group, groupRequest.DisplayName, groupRequest.ExternalID); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := s.oauthService.SetGroupMembers(group, memberIDs(groupRequest.Members)); err != nil {
+		writeError(w, memberError(err))
+		return
+	}
+
+	s.writeGroup(w, r, group)
+}
+
+// patchGroup modifies the attributes and members of a group
+// (PATCH /scim/v2/Groups/{id})
+func (s *Service) patchGroup(w http.ResponseWriter, r *http.Request) {
+	group, err := s.oauthService.FindGroupByID(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	patchRequest := new(PatchRequest)
+	if err := decodeRequest(r, patchRequest); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Work out the patch first so invalid requests change nothing
+	patch := &groupPatch{displayName: group.DisplayName, externalID: group.ExternalID}
+	for _, operation := range patchRequest.Operations {
+		if err := s.patchGroupAttributes(patch, operation); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+
+	if patch.displayName != group.DisplayName || patch.externalID != group.ExternalID {
+		if err := s.oauthService.UpdateGroup(group, patch.displayName, patch.externalID); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	for _, memberChange := range patch.memberChanges {
+		if err := memberChange(group); err != nil {
+			writeError(w, memberError(err))
+			return
+		}
+	}
+
+	s.writeGroup(w, r, group)
+}
+
+// deleteGroup deletes a group, its members are not deprovisioned
+// (DELETE /scim/v2/Groups/{id})
+func (s *Service) deleteGroup(w http.ResponseWriter, r *http.Request) {
+	group, err := s.oauthService.FindGroupByID(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if err := s.oauthService.DeleteGroup(group); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// patchGroupAttributes applies a patch operation to the patch of a group,
+// attributes which are not provisioned are ignored
+func (s *Service) patchGroupAttributes(patch *groupPatch, operation *PatchOperation) error {
+	op := strings.ToLower(operation.Op)
+	if op != "add" && op != "replace" && op != "remove" {
+		return ErrInvalidPatchOperation
+	}
+
+	if operation.Path == "" {
+		if op == "remove" {
+			return ErrInvalidPatchOperation
+		}
+		values, err := decodeAttributes(operation)
+		if err != nil {
+			return err
+		}
+		for _, value := range values {
+			if err := s.patchGroupAttributes(patch, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// A single member is removed by a filter on its ID
+	if matches := memberPathPattern.FindStringSubmatch(operation.Path); matches != nil {
+		if op != "remove" {
+			return ErrInvalidPatchOperation
+		}
+		var userID string
+		if err := json.Unmarshal([]byte(matches[1]), &userID); err != nil {
+			return ErrInvalidPatchOperation
+		}
+		patch.memberChanges = append(patch.memberChanges, func(group *models.OauthGroup) error {
+			return s.oauthService.RemoveGroupMembers(group, []string{userID})
+		})
+		return nil
+	}
+
+	switch strings.ToLower(operation.Path) {
+	case "displayname":
+		if op == "remove" {
+			return ErrInvalidPatchOperation
+		}
+		return decodeValue(operation, &patch.displayName)
+	case "externalid":
+		if op == "remove" {
+			patch.externalID = ""
+			return nil
+		}
+		return decodeValue(operation, &patch.externalID)
+	case "members":
+		// Removing members without a value removes all of them
+		var members []Member
+		if op != "remove" || len(operation.Value) > 0 {
+			if err := decodeValue(operation, &members); err != nil {
+				return err
+			}
+		}
+		userIDs := memberIDs(members)
+		memberChange := func(group *models.OauthGroup) error {
+			return s.oauthService.AddGroupMembers(group, userIDs)
+		}
+		switch {
+		case op == "replace" || (op == "remove" && len(operation.Value) == 0):
+			memberChange = func(group *models.OauthGroup) error {
+				return s.oauthService.SetGroupMembers(group, userIDs)
+			}
+		case op == "remove":
+			memberChange = func(group *models.OauthGroup) error {
+				return s.oauthService.RemoveGroupMembers(group, userIDs)
+			}
+		}
+		patch.memberChanges = append(patch.memberChanges, memberChange)
+	}
+	return nil
+}
+
+func (s *Service) writeGroup(w http.ResponseWriter, r *http.Request, group *models.OauthGroup) {
+	resource, err := s.newGroup(r, group)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeResource(w, resource, http.StatusOK)
+}
+
+// newGroup returns the SCIM resource of a group
+func (s *Service) newGroup(r *http.Request, group *models.OauthGroup) (*Group, error) {
+	members, err := s.oauthService.ListGroupMembers(group)
+	if err != nil {
+		return nil, err
+	}
+	return NewGroup(
+		group,
+		members,
+		resourceURL(r, groupsPath, group.ID),
+		baseURL(r)+usersPath,
+	), nil
+}
+
+// memberError reports users added to a group who do not exist as an invalid
+// value rather than as the group not being found
+func memberError(err error) error {
+	if err == oauth.ErrUserNotFound {
+		return ErrMemberNotFound
+	}
+	return err
+}
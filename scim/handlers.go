@@ -0,0 +1,182 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	pass "github.com/RichardKnop/go-oauth2-server/util/password"
+)
+
+const (
+	// maxResourcesPerPage is the highest count of resources listed at once,
+	// also used when the count is not set
+	maxResourcesPerPage = 100
+)
+
+var (
+	// ErrInvalidFilter ...
+	ErrInvalidFilter = errors.New("Only filters comparing an attribute with eq are supported")
+	// ErrInvalidSyntax ...
+	ErrInvalidSyntax = errors.New("Invalid request body")
+	// ErrInvalidPatchOperation ...
+	ErrInvalidPatchOperation = errors.New("Invalid patch operation")
+	// ErrMemberNotFound ...
+	ErrMemberNotFound = errors.New("Member not found")
+
+	errStatusCodeMap = map[error]int{
+		oauth.ErrUserNotFound:            http.StatusNotFound,
+		oauth.ErrGroupNotFound:           http.StatusNotFound,
+		oauth.ErrUsernameTaken:           http.StatusConflict,
+		oauth.ErrGroupNameTaken:          http.StatusConflict,
+		oauth.ErrCannotSetEmptyUsername:  http.StatusBadRequest,
+		oauth.ErrCannotSetEmptyGroupName: http.StatusBadRequest,
+		ErrInvalidFilter:                 http.StatusBadRequest,
+		ErrInvalidSyntax:                 http.StatusBadRequest,
+		ErrInvalidPatchOperation:         http.StatusBadRequest,
+		ErrInvalidBoolean:                http.StatusBadRequest,
+		ErrMemberNotFound:                http.StatusBadRequest,
+	}
+
+	// scimTypes are the SCIM error types of errors,
+	// see https://tools.ietf.org/html/rfc7644#section-3.12
+	scimTypes = map[error]string{
+		oauth.ErrUsernameTaken:           "uniqueness",
+		oauth.ErrGroupNameTaken:          "uniqueness",
+		oauth.ErrCannotSetEmptyUsername:  "invalidValue",
+		oauth.ErrCannotSetEmptyGroupName: "invalidValue",
+		ErrInvalidFilter:                 "invalidFilter",
+		ErrInvalidSyntax:                 "invalidSyntax",
+		ErrInvalidPatchOperation:         "invalidSyntax",
+		ErrInvalidBoolean:                "invalidValue",
+		ErrMemberNotFound:                "invalidValue",
+	}
+
+	// filterPattern matches the only filters supported, such as
+	// userName eq "jdoe@example.com"
+	filterPattern = regexp.MustCompile(`^\s*(\w+)\s+(?i:eq)\s+("(?:[^"\\]|\\.)*")\s*$`)
+)
+
+// writeResource writes a SCIM resource or message
+func writeResource(w http.ResponseWriter, v interface{}, code int) {
+	w.Header().Set("Content-Type", "application/scim+json; charset=utf-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	code, ok := errStatusCodeMap[err]
+	scimType := scimTypes[err]
+	if _, isPolicyError := err.(*pass.PolicyError); isPolicyError {
+		code, ok, scimType = http.StatusBadRequest, true, "invalidValue"
+	}
+	if !ok {
+		code = http.StatusInternalServerError
+	}
+	writeResource(w, &Error{
+		Schemas:  []string{errorSchema},
+		Status:   strconv.Itoa(code),
+		ScimType: scimType,
+		Detail:   err.Error(),
+	}, code)
+}
+
+// parsePage parses the 1-based start index and the count of a list request
+func parsePage(r *http.Request) (int, int, error) {
+	startIndex, count := 1, maxResourcesPerPage
+	if value := r.URL.Query().Get("startIndex"); value != "" {
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, 0, ErrInvalidSyntax
+		}
+		// Start indexes less than one are interpreted as one
+		if i > 1 {
+			startIndex = i
+		}
+	}
+	if value := r.URL.Query().Get("count"); value != "" {
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, 0, ErrInvalidSyntax
+		}
+		// Negative counts are interpreted as zero
+		if i < 0 {
+			i = 0
+		}
+		if i < maxResourcesPerPage {
+			count = i
+		}
+	}
+	return startIndex, count, nil
+}
+
+// parseFilter parses the filter of a list request, it returns the
+// attribute, lower cased, and the value it is compared with
+func parseFilter(r *http.Request) (string, string, error) {
+	filter := r.URL.Query().Get("filter")
+	if filter == "" {
+		return "", "", nil
+	}
+	matches := filterPattern.FindStringSubmatch(filter)
+	if matches == nil {
+		return "", "", ErrInvalidFilter
+	}
+	var value string
+	if err := json.Unmarshal([]byte(matches[2]), &value); err != nil {
+		return "", "", ErrInvalidFilter
+	}
+	return strings.ToLower(matches[1]), value, nil
+}
+
+// decodeValue decodes the value of a patch operation
+func decodeValue(operation *PatchOperation, v interface{}) error {
+	if len(operation.Value) == 0 {
+		return ErrInvalidPatchOperation
+	}
+	if err := json.Unmarshal(operation.Value, v); err != nil {
+		if err == ErrInvalidBoolean {
+			return err
+		}
+		return ErrInvalidPatchOperation
+	}
+	return nil
+}
+
+// decodeAttributes decodes the value of a patch operation without a path,
+// an object with the attributes to set, the names of which are lower cased
+func decodeAttributes(operation *PatchOperation) (map[string]*PatchOperation, error) {
+	var values map[string]json.RawMessage
+	if err := decodeValue(operation, &values); err != nil {
+		return nil, err
+	}
+	attributes := make(map[string]*PatchOperation, len(values))
+	for name, value := range values {
+		attributes[strings.ToLower(name)] = &PatchOperation{
+			Op:    operation.Op,
+			Path:  name,
+			Value: value,
+		}
+	}
+	return attributes, nil
+}
+
+// baseURL returns the URL the SCIM resources of a request are under
+func baseURL(r *http.Request) string {
+	path := r.URL.Path
+	for _, resourcesPath := range []string{usersPath, groupsPath} {
+		if i := strings.Index(path, resourcesPath); i >= 0 {
+			return path[:i]
+		}
+	}
+	return path
+}
+
+// resourceURL returns the URL of a resource
+func resourceURL(r *http.Request, resourcesPath, id string) string {
+	return fmt.Sprintf("%s%s/%s", baseURL(r), resourcesPath, id)
+}
@@ -0,0 +1,205 @@
+package scim
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/mocks"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	testUser = &models.OauthUser{
+		MyGormModel: models.MyGormModel{ID: "test_user"},
+		Username:    "jdoe@example.com",
+		ExternalID:  "hr_1",
+	}
+	testGroup = &models.OauthGroup{
+		MyGormModel: models.MyGormModel{ID: "test_group"},
+		DisplayName: "Engineering",
+	}
+)
+
+// newTestRouter returns a router serving the SCIM routes to requests
+// authenticated with an access token granted the scope, whose client
+// was granted the scim scope
+func newTestRouter(scope string) (*mux.Router, *mocks.ServiceInterface) {
+	return newTestRouterWithClientScopes(scope, []string{"scim"})
+}
+
+// newTestRouterWithClientScopes returns a router serving the SCIM routes
+// to requests authenticated with an access token granted the scope, whose
+// client was granted the client scopes
+func newTestRouterWithClientScopes(scope string, clientScopes []string) (*mux.Router, *mocks.ServiceInterface) {
+	cnf := &config.Config{}
+	oauthService := new(mocks.ServiceInterface)
+	oauthService.On("GetConfig").Return(cnf)
+	oauthService.On("Authenticate", "test_token").Return(&models.OauthAccessToken{
+		ClientID: util.StringOrNull("test_client"),
+		Scope:    scope,
+	}, nil)
+	oauthService.On("GetClientScopes", mock.MatchedBy(func(client *models.OauthClient) bool {
+		return client.ID == "test_client"
+	})).Return(clientScopes, nil)
+
+	router := mux.NewRouter()
+	NewService(cnf, oauthService).RegisterRoutes(router, "/scim/v2")
+	return router, oauthService
+}
+
+func serve(router *mux.Router, method, url, body string) *httptest.ResponseRecorder {
+	r, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+	r.Header.Set("Authorization", "Bearer test_token")
+	r.Header.Set("Content-Type", "application/scim+json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+func TestScopeRequired(t *testing.T) {
+	router, _ := newTestRouter("read")
+
+	w := serve(router, "GET", "http://1.2.3.4/scim/v2/Users", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// The scope must also have been granted to the client by an admin,
+	// a client registered for it is not enough
+	router, _ = newTestRouterWithClientScopes("scim", nil)
+	w = serve(router, "GET", "http://1.2.3.4/scim/v2/Users", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), ErrClientScopeRequired.Error())
+}
+
+func TestListUsers(t *testing.T) {
+	router, oauthService := newTestRouter("scim")
+	oauthService.On("ListUsers", "jdoe@example.com", "", 0, 100).Return([]*models.OauthUser{testUser}, 1, nil)
+	oauthService.On("ListUserGroups", testUser).Return([]*models.OauthGroup{testGroup}, nil)
+
+	w := serve(router, "GET", `http://1.2.3.4/scim/v2/Users?filter=userName+eq+%22jdoe%40example.com%22`, "")
+	if !assert.Equal(t, http.StatusOK, w.Code) {
+		return
+	}
+	assert.Equal(t, "application/scim+json; charset=utf-8", w.Header().Get("Content-Type"))
+	var resp struct {
+		Schemas      []string `json:"schemas"`
+		TotalResults int      `json:"totalResults"`
+		Resources    []*User  `json:"Resources"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []string{listResponseSchema}, resp.Schemas)
+	assert.Equal(t, 1, resp.TotalResults)
+	if assert.Len(t, resp.Resources, 1) {
+		user := resp.Resources[0]
+		assert.Equal(t, "test_user", user.ID)
+		assert.Equal(t, "hr_1", user.ExternalID)
+		assert.True(t, user.Active)
+		assert.Equal(t, "/scim/v2/Users/test_user", user.Meta.Location)
+		assert.Equal(t, []Member{{Value: "test_group", Display: "Engineering", Ref: "/scim/v2/Groups/test_group"}}, user.Groups)
+	}
+
+	// Only comparing an attribute with eq is supported
+	w = serve(router, "GET", `http://1.2.3.4/scim/v2/Users?filter=userName+sw+%22j%22`, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"scimType":"invalidFilter"`)
+}
+
+func TestCreateUser(t *testing.T) {
+	router, oauthService := newTestRouter("scim")
+	user := &models.OauthUser{MyGormModel: models.MyGormModel{ID: "new_user"}, Username: "jsmith@example.com"}
+	oauthService.On("CreateUser", roles.User, "jsmith@example.com", "").Return(user, nil)
+	oauthService.On("CreateUser", roles.User, "jdoe@example.com", "").Return(nil, oauth.ErrUsernameTaken)
+	oauthService.On("SetUserExternalID", user, "hr_2").Return(nil)
+	oauthService.On("SetUserDisabled", user, true).Return(nil)
+	oauthService.On("ListUserGroups", user).Return(nil, nil)
+
+	w := serve(router, "POST", "http://1.2.3.4/scim/v2/Users", `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+		"userName": "jsmith@example.com",
+		"externalId": "hr_2",
+		"active": false
+	}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/scim/v2/Users/new_user", w.Header().Get("Location"))
+	oauthService.AssertCalled(t, "SetUserExternalID", user, "hr_2")
+	oauthService.AssertCalled(t, "SetUserDisabled", user, true)
+
+	w = serve(router, "POST", "http://1.2.3.4/scim/v2/Users", `{"userName": "jdoe@example.com"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), `"scimType":"uniqueness"`)
+}
+
+func TestDeprovisionUser(t *testing.T) {
+	router, oauthService := newTestRouter("scim")
+	oauthService.On("FindUserByID", "test_user").Return(testUser, nil)
+	oauthService.On("FindUserByID", "bogus").Return(nil, oauth.ErrUserNotFound)
+	oauthService.On("SetUserDisabled", testUser, true).Return(nil)
+	oauthService.On("ListUserGroups", testUser).Return(nil, nil)
+	oauthService.On("DeleteUser", testUser).Return(nil)
+
+	// Some provisioning systems send booleans as strings
+	w := serve(router, "PATCH", "http://1.2.3.4/scim/v2/Users/test_user", `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [{"op": "Replace", "path": "active", "value": "False"}]
+	}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	oauthService.AssertCalled(t, "SetUserDisabled", testUser, true)
+
+	w = serve(router, "DELETE", "http://1.2.3.4/scim/v2/Users/test_user", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	oauthService.AssertCalled(t, "DeleteUser", testUser)
+
+	w = serve(router, "DELETE", "http://1.2.3.4/scim/v2/Users/bogus", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"404"`)
+}
+
+func TestPatchGroup(t *testing.T) {
+	router, oauthService := newTestRouter("scim")
+	oauthService.On("FindGroupByID", "test_group").Return(testGroup, nil)
+	oauthService.On("UpdateGroup", testGroup, "Platform", "").Return(nil)
+	oauthService.On("AddGroupMembers", testGroup, []string{"test_user"}).Return(nil)
+	oauthService.On("RemoveGroupMembers", testGroup, []string{"other_user"}).Return(nil)
+	oauthService.On("AddGroupMembers", testGroup, []string{"bogus"}).Return(oauth.ErrUserNotFound)
+	oauthService.On("ListGroupMembers", testGroup).Return([]*models.OauthUser{testUser}, nil)
+
+	w := serve(router, "PATCH", "http://1.2.3.4/scim/v2/Groups/test_group", `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [
+			{"op": "replace", "value": {"displayName": "Platform"}},
+			{"op": "add", "path": "members", "value": [{"value": "test_user"}]},
+			{"op": "remove", "path": "members[value eq \"other_user\"]"}
+		]
+	}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	oauthService.AssertCalled(t, "UpdateGroup", testGroup, "Platform", "")
+	oauthService.AssertCalled(t, "AddGroupMembers", testGroup, []string{"test_user"})
+	oauthService.AssertCalled(t, "RemoveGroupMembers", testGroup, []string{"other_user"})
+
+	// Unknown members are an invalid value, not a missing group
+	w = serve(router, "PATCH", "http://1.2.3.4/scim/v2/Groups/test_group", `{
+		"Operations": [{"op": "add", "path": "members", "value": [{"value": "bogus"}]}]
+	}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"scimType":"invalidValue"`)
+
+	// Invalid operations change nothing
+	w = serve(router, "PATCH", "http://1.2.3.4/scim/v2/Groups/test_group", `{
+		"Operations": [
+			{"op": "add", "path": "members", "value": [{"value": "test_user"}]},
+			{"op": "move", "path": "displayName", "value": "Sales"}
+		]
+	}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	oauthService.AssertNumberOfCalls(t, "AddGroupMembers", 2)
+	oauthService.AssertNotCalled(t, "UpdateGroup", testGroup, "Sales", mock.Anything)
+}
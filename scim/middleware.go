@@ -0,0 +1,67 @@
+package scim
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/RichardKnop/go-oauth2-server/util/response"
+)
+
+const (
+	// scimScope must be granted to the provisioning system's client by an
+	// admin and to its access token
+	scimScope = "scim"
+)
+
+var (
+	// ErrScopeRequired ...
+	ErrScopeRequired = errors.New("scim scope required")
+	// ErrClientScopeRequired ...
+	ErrClientScopeRequired = errors.New("scim scope must be granted to the client")
+)
+
+// scopeMiddleware only lets requests authenticated with an access token
+// granted the scope through, and only if an admin granted the scope to
+// the token's client. Clients issued default or registered scopes only
+// are refused, whatever their tokens say.
+type scopeMiddleware struct {
+	service oauth.ServiceInterface
+	scope   string
+}
+
+// newScopeMiddleware creates a new scopeMiddleware instance
+func newScopeMiddleware(service oauth.ServiceInterface, scope string) *scopeMiddleware {
+	return &scopeMiddleware{service: service, scope: scope}
+}
+
+// ServeHTTP as per the negroni.Handler interface
+func (m *scopeMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	// Get the authenticated access token
+	accessToken, err := oauth.GetAuthenticatedAccessToken(r)
+	if err != nil {
+		response.UnauthorizedError(w, err.Error())
+		return
+	}
+
+	if !util.StringInSlice(m.scope, strings.Fields(accessToken.Scope)) {
+		response.InsufficientScopeError(w, ErrScopeRequired.Error())
+		return
+	}
+
+	client := &models.OauthClient{MyGormModel: models.MyGormModel{ID: accessToken.ClientID.String}}
+	granted, err := m.service.GetClientScopes(client)
+	if err != nil {
+		response.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !util.StringInSlice(m.scope, granted) {
+		response.InsufficientScopeError(w, ErrClientScopeRequired.Error())
+		return
+	}
+
+	next(w, r)
+}
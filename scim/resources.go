@@ -0,0 +1,216 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+)
+
+// The schemas of the resources and messages, see
+// https://tools.ietf.org/html/rfc7643 and https://tools.ietf.org/html/rfc7644
+const (
+	userSchema         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	groupSchema        = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	listResponseSchema = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	errorSchema        = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+var (
+	// ErrInvalidBoolean ...
+	ErrInvalidBoolean = errors.New("Invalid boolean")
+)
+
+// Meta describes a resource
+type Meta struct {
+	ResourceType string `json:"resourceType"`
+	Created      string `json:"created"`
+	LastModified string `json:"lastModified"`
+	Location     string `json:"location"`
+}
+
+// newMeta creates new Meta instance
+func newMeta(resourceType string, model *models.MyGormModel, location string) *Meta {
+	lastModified := model.UpdatedAt
+	if lastModified.IsZero() {
+		lastModified = model.CreatedAt
+	}
+	return &Meta{
+		ResourceType: resourceType,
+		Created:      model.CreatedAt.UTC().Format(time.RFC3339),
+		LastModified: lastModified.UTC().Format(time.RFC3339),
+		Location:     location,
+	}
+}
+
+// Email is an email address of a user, the username of a user is its only one
+type Email struct {
+	Value   string `json:"value"`
+	Primary bool   `json:"primary"`
+}
+
+// Member references a member of a group, or a group a user is a member of
+type Member struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// User ...
+type User struct {
+	Schemas    []string `json:"schemas"`
+	ID         string   `json:"id"`
+	ExternalID string   `json:"externalId,omitempty"`
+	UserName   string   `json:"userName"`
+	Active     bool     `json:"active"`
+	Emails     []Email  `json:"emails"`
+	Groups     []Member `json:"groups"`
+	Meta       *Meta    `json:"meta"`
+}
+
+// NewUser creates new User instance
+func NewUser(user *models.OauthUser, groups []*models.OauthGroup, location, groupsURL string) *User {
+	resource := &User{
+		Schemas:    []string{userSchema},
+		ID:         user.ID,
+		ExternalID: user.ExternalID,
+		UserName:   user.Username,
+		Active:     !user.Disabled,
+		Emails:     []Email{{Value: user.Username, Primary: true}},
+		Groups:     make([]Member, len(groups)),
+		Meta:       newMeta("User", &user.MyGormModel, location),
+	}
+	for i, group := range groups {
+		resource.Groups[i] = Member{
+			Value:   group.ID,
+			Display: group.DisplayName,
+			Ref:     groupsURL + "/" + group.ID,
+		}
+	}
+	return resource
+}
+
+// UserRequest creates or replaces a user, users are active unless
+// stated otherwise
+type UserRequest struct {
+	UserName   string   `json:"userName"`
+	ExternalID string   `json:"externalId"`
+	Active     *Boolean `json:"active"`
+	Password   string   `json:"password"`
+}
+
+// Group ...
+type Group struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id"`
+	ExternalID  string   `json:"externalId,omitempty"`
+	DisplayName string   `json:"displayName"`
+	Members     []Member `json:"members"`
+	Meta        *Meta    `json:"meta"`
+}
+
+// NewGroup creates new Group instance
+func NewGroup(group *models.OauthGroup, members []*models.OauthUser, location, usersURL string) *Group {
+	resource := &Group{
+		Schemas:     []string{groupSchema},
+		ID:          group.ID,
+		ExternalID:  group.ExternalID,
+		DisplayName: group.DisplayName,
+		Members:     make([]Member, len(members)),
+		Meta:        newMeta("Group", &group.MyGormModel, location),
+	}
+	for i, user := range members {
+		resource.Members[i] = Member{
+			Value:   user.ID,
+			Display: user.Username,
+			Ref:     usersURL + "/" + user.ID,
+		}
+	}
+	return resource
+}
+
+// GroupRequest creates or replaces a group
+type GroupRequest struct {
+	DisplayName string   `json:"displayName"`
+	ExternalID  string   `json:"externalId"`
+	Members     []Member `json:"members"`
+}
+
+// memberIDs returns the IDs of the users referenced by members
+func memberIDs(members []Member) []string {
+	userIDs := make([]string, len(members))
+	for i, member := range members {
+		userIDs[i] = member.Value
+	}
+	return userIDs
+}
+
+// ListResponse is a page of resources
+type ListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+// NewListResponse creates new ListResponse instance
+func NewListResponse(totalResults, startIndex, itemsPerPage int, resources interface{}) *ListResponse {
+	return &ListResponse{
+		Schemas:      []string{listResponseSchema},
+		TotalResults: totalResults,
+		StartIndex:   startIndex,
+		ItemsPerPage: itemsPerPage,
+		Resources:    resources,
+	}
+}
+
+// PatchRequest modifies a resource with a list of operations
+type PatchRequest struct {
+	Operations []*PatchOperation `json:"Operations"`
+}
+
+// PatchOperation adds, replaces or removes the value at the path, operations
+// without a path carry an object with the attributes to set
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Error ...
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+}
+
+// Boolean is a boolean sent either as JSON boolean or as a string, as some
+// provisioning systems do
+type Boolean bool
+
+// UnmarshalJSON as per the json.Unmarshaler interface
+func (b *Boolean) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case bool:
+		*b = Boolean(v)
+		return nil
+	case string:
+		switch strings.ToLower(v) {
+		case "true":
+			*b = true
+			return nil
+		case "false":
+			*b = false
+			return nil
+		}
+	}
+	return ErrInvalidBoolean
+}
@@ -0,0 +1,120 @@
+package scim
+
+import (
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/util/response"
+	"github.com/RichardKnop/go-oauth2-server/util/routes"
+	"github.com/gorilla/mux"
+	"github.com/urfave/negroni"
+)
+
+const (
+	usersPath  = "/Users"
+	userPath   = usersPath + "/{id}"
+	groupsPath = "/Groups"
+	groupPath  = groupsPath + "/{id}"
+)
+
+// RegisterRoutes registers route handlers for the SCIM service
+func (s *Service) RegisterRoutes(router *mux.Router, prefix string) {
+	subRouter := router.PathPrefix(prefix).Subrouter()
+	routes.AddRoutes(s.GetRoutes(), subRouter)
+}
+
+// GetRoutes returns []routes.Route slice for the SCIM service, all of them
+// are restricted to access tokens granted the scim scope whose client an
+// admin granted it
+func (s *Service) GetRoutes() []routes.Route {
+	middlewares := []negroni.Handler{
+		response.NewSecureHeadersMiddleware(),
+		oauth.NewAuthenticationMiddleware(s.oauthService),
+		newScopeMiddleware(s.oauthService, scimScope),
+	}
+
+	return []routes.Route{
+		{
+			Name:        "scim_list_users",
+			Method:      "GET",
+			Pattern:     usersPath,
+			HandlerFunc: s.listUsers,
+			Middlewares: middlewares,
+		},
+		{
+			Name:        "scim_create_user",
+			Method:      "POST",
+			Pattern:     usersPath,
+			HandlerFunc: s.createUser,
+			Middlewares: middlewares,
+		},
+		{
+			Name:        "scim_get_user",
+			Method:      "GET",
+			Pattern:     userPath,
+			HandlerFunc: s.getUser,
+			Middlewares: middlewares,
+		},
+		{
+			Name:        "scim_replace_user",
+			Method:      "PUT",
+			Pattern:     userPath,
+			HandlerFunc: s.replaceUser,
+			Middlewares: middlewares,
+		},
+		{
+			Name:        "scim_patch_user",
+			Method:      "PATCH",
+			Pattern:     userPath,
+			HandlerFunc: s.patchUser,
+			Middlewares: middlewares,
+		},
+		{
+			Name:        "scim_delete_user",
+			Method:      "DELETE",
+			Pattern:     userPath,
+			HandlerFunc: s.deleteUser,
+			Middlewares: middlewares,
+		},
+		{
+			Name:        "scim_list_groups",
+			Method:      "GET",
+			Pattern:     groupsPath,
+			HandlerFunc: s.listGroups,
+			Middlewares: middlewares,
+		},
+		{
+			Name:        "scim_create_group",
+			Method:      "POST",
+			Pattern:     groupsPath,
+			HandlerFunc: s.createGroup,
+			Middlewares: middlewares,
+		},
+		{
+			Name:        "scim_get_group",
+			Method:      "GET",
+			Pattern:     groupPath,
+			HandlerFunc: s.getGroup,
+			Middlewares: middlewares,
+		},
+		{
+			Name:        "scim_replace_group",
+			Method:      "PUT",
+			Pattern:     groupPath,
+			HandlerFunc: s.replaceGroup,
+			Middlewares: middlewares,
+		},
+		{
+			Name:        "scim_patch_group",
+			Method:      "PATCH",
+			Pattern:     groupPath,
+			HandlerFunc: s.patchGroup,
+			Middlewares: middlewares,
+		},
+		{
+			Name:        "scim_delete_group",
+			Method:      "DELETE",
+			Pattern:     groupPath,
+			HandlerFunc: s.deleteGroup,
+			Middlewares: middlewares,
+		},
+	}
+}
@@ -0,0 +1,319 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/roles"
+	"github.com/RichardKnop/go-oauth2-server/util/response"
+	"github.com/gorilla/mux"
+)
+
+// userAttributes are the attributes of a user which can be provisioned
+type userAttributes struct {
+	userName   string
+	externalID string
+	active     bool
+	password   string
+}
+
+// listUsers returns a page of users, optionally filtered by
+// userName or externalId (GET /scim/v2/Users?filter=userName eq "jdoe")
+func (s *Service) listUsers(w http.ResponseWriter, r *http.Request) {
+	startIndex, count, err := parsePage(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	attribute, value, err := parseFilter(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var username, externalID string
+	switch attribute {
+	case "":
+	case "username":
+		username = value
+	case "externalid":
+		externalID = value
+	default:
+		writeError(w, ErrInvalidFilter)
+		return
+	}
+
+	// Filtering by an empty value finds no one rather than everyone
+	resources := []*User{}
+	if attribute != "" && value == "" {
+		writeResource(w, NewListResponse(0, startIndex, 0, resources), http.StatusOK)
+		return
+	}
+
+	// A count of zero only asks for the total
+	limit := count
+	if limit == 0 {
+		limit = 1
+	}
+	users, total, err := s.oauthService.ListUsers(username, externalID, startIndex-1, limit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if count == 0 {
+		users = nil
+	}
+	for _, user := range users {
+		resource, err := s.newUser(r, user)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		resources = append(resources, resource)
+	}
+
+	writeResource(w, NewListResponse(total, startIndex, len(resources), resources), http.StatusOK)
+}
+
+// createUser provisions a user (POST /scim/v2/Users)
+func (s *Service) createUser(w http.ResponseWriter, r *http.Request) {
+	userRequest := new(UserRequest)
+	if err := decodeRequest(r, userRequest); err != nil {
+		writeError(w, err)
+		return
+	}
+	if userRequest.UserName == "" {
+		writeError(w, oauth.ErrCannotSetEmptyUsername)
+		return
+	}
+
+	user, err := s.oauthService.CreateUser(roles.User, userRequest.UserName, userRequest.Password)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	err = s.updateUser(user, &userAttributes{
+		userName:   user.Username,
+		externalID: userRequest.ExternalID,
+		active:     userRequest.Active == nil || bool(*userRequest.Active),
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resource, err := s.newUser(r, user)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Location", resource.Meta.Location)
+	writeResource(w, resource, http.StatusCreated)
+}
+
+// getUser returns a user (GET /scim/v2/Users/{id})
+func (s *Service) getUser(w http.ResponseWriter, r *http.Request) {
+	user, err := s.oauthService.FindUserByID(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.writeUser(w, r, user)
+}
+
+// replaceUser replaces the attributes of a user, deactivating a user
+// revokes its tokens (PUT /scim/v2/Users/{id})
+func (s *Service) replaceUser(w http.ResponseWriter, r *http.Request) {
+	user, err := s.oauthService.FindUserByID(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	userRequest := new(UserRequest)
+	if err := decodeRequest(r, userRequest); err != nil {
+		writeError(w, err)
+		return
+	}
+	err = s.updateUser(user, &userAttributes{
+		userName:   userRequest.UserName,
+		externalID: userRequest.ExternalID,
+		active:     userRequest.Active == nil || bool(*userRequest.Active),
+		password:   userRequest.Password,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.writeUser(w, r, user)
+}
+
+// patchUser modifies the attributes of a user, deactivating a user
+// revokes its tokens (PATCH /scim/v2/Users/{id})
+func (s *Service) patchUser(w http.ResponseWriter, r *http.Request) {
+	user, err := s.oauthService.FindUserByID(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	patchRequest := new(PatchRequest)
+	if err := decodeRequest(r, patchRequest); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Work out the attributes first so invalid requests change nothing
+	attributes := &userAttributes{
+		userName:   user.Username,
+		externalID: user.ExternalID,
+		active:     !user.Disabled,
+	}
+	for _, operation := range patchRequest.Operations {
+		if err := patchUserAttributes(attributes, operation); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	if err := s.updateUser(user, attributes); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.writeUser(w, r, user)
+}
+
+// deleteUser deprovisions a user, revoking its tokens
+// (DELETE /scim/v2/Users/{id})
+func (s *Service) deleteUser(w http.ResponseWriter, r *http.Request) {
+	user, err := s.oauthService.FindUserByID(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if err := s.oauthService.DeleteUser(user); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// updateUser saves the attributes of a user which changed
+func (s *Service) updateUser(user *models.OauthUser, attributes *userAttributes) error {
+	if !strings.EqualFold(attributes.userName, user.Username) {
+		if err := s.oauthService.UpdateUsername(user, attributes.userName); err != nil {
+			return err
+		}
+		user.Username = strings.ToLower(attributes.userName)
+	}
+	if attributes.externalID != user.ExternalID {
+		if err := s.oauthService.SetUserExternalID(user, attributes.externalID); err != nil {
+			return err
+		}
+	}
+	if attributes.password != "" {
+		if err := s.oauthService.SetPassword(user, attributes.password); err != nil {
+			return err
+		}
+	}
+	if attributes.active == user.Disabled {
+		if err := s.oauthService.SetUserDisabled(user, !attributes.active); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// patchUserAttributes applies a patch operation to the attributes of a
+// user, attributes which are not provisioned are ignored
+func patchUserAttributes(attributes *userAttributes, operation *PatchOperation) error {
+	op := strings.ToLower(operation.Op)
+	if op != "add" && op != "replace" && op != "remove" {
+		return ErrInvalidPatchOperation
+	}
+
+	if operation.Path == "" {
+		if op == "remove" {
+			return ErrInvalidPatchOperation
+		}
+		values, err := decodeAttributes(operation)
+		if err != nil {
+			return err
+		}
+		for _, value := range values {
+			if err := patchUserAttributes(attributes, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	switch strings.ToLower(operation.Path) {
+	case "username":
+		if op == "remove" {
+			return ErrInvalidPatchOperation
+		}
+		return decodeValue(operation, &attributes.userName)
+	case "externalid":
+		if op == "remove" {
+			attributes.externalID = ""
+			return nil
+		}
+		return decodeValue(operation, &attributes.externalID)
+	case "active":
+		if op == "remove" {
+			return ErrInvalidPatchOperation
+		}
+		var active Boolean
+		if err := decodeValue(operation, &active); err != nil {
+			return err
+		}
+		attributes.active = bool(active)
+	case "password":
+		if op == "remove" {
+			return ErrInvalidPatchOperation
+		}
+		return decodeValue(operation, &attributes.password)
+	}
+	return nil
+}
+
+func (s *Service) writeUser(w http.ResponseWriter, r *http.Request, user *models.OauthUser) {
+	resource, err := s.newUser(r, user)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeResource(w, resource, http.StatusOK)
+}
+
+// newUser returns the SCIM resource of a user
+func (s *Service) newUser(r *http.Request, user *models.OauthUser) (*User, error) {
+	groups, err := s.oauthService.ListUserGroups(user)
+	if err != nil {
+		return nil, err
+	}
+	return NewUser(
+		user,
+		groups,
+		resourceURL(r, usersPath, user.ID),
+		baseURL(r)+groupsPath,
+	), nil
+}
+
+// decodeRequest decodes the body of a request
+func decodeRequest(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		if err == ErrInvalidBoolean {
+			return err
+		}
+		return ErrInvalidSyntax
+	}
+	return nil
+}
@@ -1,14 +1,16 @@
 package services
 
 import (
+	"fmt"
 	"reflect"
 
+	"github.com/RichardKnop/go-oauth2-server/admin"
 	"github.com/RichardKnop/go-oauth2-server/config"
 	"github.com/RichardKnop/go-oauth2-server/health"
 	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/scim"
 	"github.com/RichardKnop/go-oauth2-server/session"
 	"github.com/RichardKnop/go-oauth2-server/web"
-	"github.com/gorilla/sessions"
 	"github.com/jinzhu/gorm"
 )
 
@@ -28,6 +30,12 @@ var (
 
 	// SessionService ...
 	SessionService session.ServiceInterface
+
+	// AdminService ...
+	AdminService admin.ServiceInterface
+
+	// ScimService ...
+	ScimService scim.ServiceInterface
 )
 
 // UseHealthService sets the health service
@@ -50,25 +58,68 @@ func UseSessionService(s session.ServiceInterface) {
 	SessionService = s
 }
 
+// UseAdminService sets the admin service
+func UseAdminService(a admin.ServiceInterface) {
+	AdminService = a
+}
+
+// UseScimService sets the SCIM service
+func UseScimService(s scim.ServiceInterface) {
+	ScimService = s
+}
+
 // Init starts up all services
 func Init(cnf *config.Config, db *gorm.DB) error {
 	if nil == reflect.TypeOf(HealthService) {
 		HealthService = health.NewService(db)
 	}
 
+	// Reject a signing key not compatible with the signing algorithm,
+	// the key is optional when the server generates its own keys
+	if cnf.JWT.KeyRotationInterval > 0 && !oauth.IsRotatableAlgorithm(cnf.JWT.Algorithm) {
+		return fmt.Errorf("JWT key rotation not supported for algorithm %s", cnf.JWT.Algorithm)
+	}
+	if cnf.JWT.KeyRotationInterval <= 0 || cnf.JWT.PrivateKey != "" {
+		if _, err := oauth.NewSigningKey(&cnf.JWT); err != nil {
+			return fmt.Errorf("Invalid JWT signing key: %s", err)
+		}
+	}
+	switch cnf.Oauth.AccessTokenFormat {
+	case "", config.AccessTokenFormatOpaque, config.AccessTokenFormatJWT:
+	default:
+		return fmt.Errorf("Access token format %s not supported", cnf.Oauth.AccessTokenFormat)
+	}
+
 	if nil == reflect.TypeOf(OauthService) {
 		OauthService = oauth.NewService(cnf, db)
 	}
 
+	// Authenticate users against the directory if one is configured
+	if cnf.Oauth.LDAP.URL != "" {
+		authenticator, err := oauth.NewLDAPAuthenticator(&cnf.Oauth.LDAP)
+		if err != nil {
+			return fmt.Errorf("Invalid LDAP config: %s", err)
+		}
+		OauthService.SetUserAuthenticator(authenticator)
+	}
+
 	if nil == reflect.TypeOf(SessionService) {
 		// note: default session store is CookieStore
-		SessionService = session.NewService(cnf, sessions.NewCookieStore([]byte(cnf.Session.Secret)))
+		SessionService = session.NewService(cnf, session.NewCookieStore(cnf))
 	}
 
 	if nil == reflect.TypeOf(WebService) {
 		WebService = web.NewService(cnf, OauthService, SessionService)
 	}
 
+	if nil == reflect.TypeOf(AdminService) {
+		AdminService = admin.NewService(cnf, OauthService)
+	}
+
+	if nil == reflect.TypeOf(ScimService) {
+		ScimService = scim.NewService(cnf, OauthService)
+	}
+
 	return nil
 }
 
@@ -78,4 +129,6 @@ func Close() {
 	OauthService.Close()
 	WebService.Close()
 	SessionService.Close()
+	AdminService.Close()
+	ScimService.Close()
 }
@@ -9,13 +9,19 @@ import (
 	"github.com/gorilla/sessions"
 )
 
+const (
+	// defaultRememberMeMaxAge is how long remembered sessions last, 30 days
+	defaultRememberMeMaxAge = 86400 * 30
+)
+
 // Service wraps session functionality
 type Service struct {
-	sessionStore   sessions.Store
-	sessionOptions *sessions.Options
-	session        *sessions.Session
-	r              *http.Request
-	w              http.ResponseWriter
+	sessionStore     sessions.Store
+	sessionOptions   *sessions.Options
+	rememberMeMaxAge int
+	session          *sessions.Session
+	r                *http.Request
+	w                http.ResponseWriter
 }
 
 // UserSession has user data stored in a session after logging in
@@ -24,6 +30,11 @@ type UserSession struct {
 	Username     string
 	AccessToken  string
 	RefreshToken string
+	// RememberMe keeps the session for longer than other sessions
+	RememberMe bool
+	// SessionID is the server-side login session, the user is logged
+	// out once it has been revoked
+	SessionID string
 }
 
 var (
@@ -50,8 +61,25 @@ func NewService(cnf *config.Config, sessionStore sessions.Store) *Service {
 			Path:     cnf.Session.Path,
 			MaxAge:   cnf.Session.MaxAge,
 			HttpOnly: cnf.Session.HTTPOnly,
+			Secure:   !cnf.Session.Insecure,
+			// Lax still sends the cookie when clients redirect
+			// users to the authorize page from other sites
+			SameSite: http.SameSiteLaxMode,
 		},
+		rememberMeMaxAge: getRememberMeMaxAge(cnf),
+	}
+}
+
+// NewCookieStore returns a cookie store signing cookies with the session
+// secret, the signatures stay valid for as long as sessions can last
+func NewCookieStore(cnf *config.Config) *sessions.CookieStore {
+	sessionStore := sessions.NewCookieStore([]byte(cnf.Session.Secret))
+	maxAge := getRememberMeMaxAge(cnf)
+	if cnf.Session.MaxAge > maxAge {
+		maxAge = cnf.Session.MaxAge
 	}
+	sessionStore.MaxAge(maxAge)
+	return sessionStore
 }
 
 // SetSessionService sets the request and responseWriter on the session service
@@ -61,13 +89,19 @@ func (s *Service) SetSessionService(r *http.Request, w http.ResponseWriter) {
 }
 
 // StartSession starts a new session. This method must be called before other
-// public methods of this struct as it sets the internal session object
+// public methods of this struct as it sets the internal session object.
+// Cookies which cannot be decoded, such as expired ones or ones signed with
+// an old secret, are replaced with a new session.
 func (s *Service) StartSession() error {
 	session, err := s.sessionStore.Get(s.r, StorageSessionName)
-	if err != nil {
+	if session == nil {
 		return err
 	}
 	s.session = session
+
+	// Remembered sessions keep lasting longer whatever else is saved in them
+	userSession, ok := session.Values[UserSessionKey].(*UserSession)
+	s.setOptions(ok && userSession.RememberMe)
 	return nil
 }
 
@@ -96,6 +130,7 @@ func (s *Service) SetUserSession(userSession *UserSession) error {
 
 	// Set a new user session
 	s.session.Values[UserSessionKey] = userSession
+	s.setOptions(userSession.RememberMe)
 	return s.session.Save(s.r, s.w)
 }
 
@@ -108,6 +143,7 @@ func (s *Service) ClearUserSession() error {
 
 	// Delete the user session
 	delete(s.session.Values, UserSessionKey)
+	s.setOptions(false)
 	return s.session.Save(s.r, s.w)
 }
 
@@ -142,5 +178,22 @@ func (s *Service) GetFlashMessage() (interface{}, error) {
 	return nil, nil
 }
 
+// setOptions sets the options the session cookie is saved with
+func (s *Service) setOptions(rememberMe bool) {
+	options := *s.sessionOptions
+	if rememberMe {
+		options.MaxAge = s.rememberMeMaxAge
+	}
+	s.session.Options = &options
+}
+
+// getRememberMeMaxAge returns how long remembered sessions last
+func getRememberMeMaxAge(cnf *config.Config) int {
+	if cnf.Session.RememberMeMaxAge > 0 {
+		return cnf.Session.RememberMeMaxAge
+	}
+	return defaultRememberMeMaxAge
+}
+
 // Close stops any running services
 func (s *Service) Close() {}
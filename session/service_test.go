@@ -1,6 +1,11 @@
 package session_test
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
 	"github.com/RichardKnop/go-oauth2-server/session"
 	"github.com/stretchr/testify/assert"
 )
@@ -62,3 +67,61 @@ func (suite *SessionTestSuite) TestService() {
 		assert.Equal(suite.T(), "User session type assertion error", err.Error())
 	}
 }
+
+func TestRememberMe(t *testing.T) {
+	cnf := &config.Config{Session: config.SessionConfig{
+		Secret:   "test_secret",
+		Path:     "/",
+		MaxAge:   3600,
+		HTTPOnly: true,
+	}}
+	service := session.NewService(cnf, session.NewCookieStore(cnf))
+	startSession := func(cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "http://1.2.3.4/web/authorize", nil)
+		for _, cookie := range cookies {
+			r.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		service.SetSessionService(r, w)
+		assert.NoError(t, service.StartSession())
+		return w
+	}
+
+	// Sessions last MaxAge unless the user asked to be remembered
+	w := startSession()
+	assert.NoError(t, service.SetUserSession(&session.UserSession{Username: "test@user"}))
+	cookies := w.Result().Cookies()
+	if assert.Len(t, cookies, 1) {
+		assert.Equal(t, 3600, cookies[0].MaxAge)
+		assert.True(t, cookies[0].Secure)
+		assert.True(t, cookies[0].HttpOnly)
+	}
+	w = startSession()
+	assert.NoError(t, service.SetUserSession(&session.UserSession{Username: "test@user", RememberMe: true}))
+	cookies = w.Result().Cookies()
+	if !assert.Len(t, cookies, 1) {
+		return
+	}
+	assert.Equal(t, 86400*30, cookies[0].MaxAge)
+
+	// And keep lasting longer when something else is saved in them
+	w = startSession(cookies[0])
+	assert.NoError(t, service.SetFlashMessage("test_message"))
+	remembered := w.Result().Cookies()
+	if assert.Len(t, remembered, 1) {
+		assert.Equal(t, 86400*30, remembered[0].MaxAge)
+	}
+
+	// Until the user logs out
+	w = startSession(cookies[0])
+	assert.NoError(t, service.ClearUserSession())
+	cookies = w.Result().Cookies()
+	if assert.Len(t, cookies, 1) {
+		assert.Equal(t, 3600, cookies[0].MaxAge)
+	}
+
+	// Cookies which cannot be decoded start a new session
+	startSession(&http.Cookie{Name: session.StorageSessionName, Value: "bogus"})
+	_, err := service.GetUserSession()
+	assert.Error(t, err)
+}
@@ -71,6 +71,22 @@ func TestResponseForError(t *testing.T, w *httptest.ResponseRecorder, msg string
 	TestResponseBody(t, w, getErrorJSON(msg))
 }
 
+// TestResponseForOauthError tests a response w to see if it returned an
+// OAuth 2.0 error with the error code and description with http code
+func TestResponseForOauthError(t *testing.T, w *httptest.ResponseRecorder, errorCode, description string, code int) {
+	if code != w.Code {
+		log.Print(w.Body.String())
+	}
+	assert.Equal(
+		t,
+		code,
+		w.Code,
+		fmt.Sprintf("Expected a %d response but got %d", code, w.Code),
+	)
+	assert.NotNil(t, w)
+	TestResponseBody(t, w, getOauthErrorJSON(errorCode, description))
+}
+
 // TestEmptyResponse tests an empty 204 response
 func TestEmptyResponse(t *testing.T, w *httptest.ResponseRecorder) {
 	assert.Equal(t, 204, w.Code)
@@ -188,3 +204,11 @@ func TestListValidResponseWithParams(t *testing.T, router *mux.Router, path, ent
 func getErrorJSON(msg string) string {
 	return fmt.Sprintf("{\"error\":\"%s\"}", msg)
 }
+
+func getOauthErrorJSON(errorCode, description string) string {
+	data, _ := json.Marshal(map[string]string{
+		"error":             errorCode,
+		"error_description": description,
+	})
+	return string(data)
+}
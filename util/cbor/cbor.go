@@ -0,0 +1,326 @@
+package cbor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	majorUnsigned = 0
+	majorNegative = 1
+	majorBytes    = 2
+	majorText     = 3
+	majorArray    = 4
+	majorMap      = 5
+	majorTag      = 6
+	majorSimple   = 7
+
+	// maxDepth limits the nesting of arrays and maps
+	maxDepth = 16
+)
+
+var (
+	// ErrUnexpectedEnd ...
+	ErrUnexpectedEnd = errors.New("Unexpected end of CBOR data")
+	// ErrTrailingData ...
+	ErrTrailingData = errors.New("Trailing data after CBOR item")
+	// ErrUnsupported ...
+	ErrUnsupported = errors.New("Unsupported CBOR item")
+	// ErrTooDeep ...
+	ErrTooDeep = errors.New("CBOR data nested too deep")
+)
+
+// Decode decodes a single CBOR item (RFC 8949) that must span all of data.
+// Values are returned as int64, []byte, string, []interface{},
+// map[interface{}]interface{}, bool, float64 or nil. Only definite
+// lengths are supported, as used by WebAuthn authenticators.
+func Decode(data []byte) (interface{}, error) {
+	v, rest, err := DecodeFirst(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, ErrTrailingData
+	}
+	return v, nil
+}
+
+// DecodeFirst decodes the CBOR item at the start of data and returns
+// the bytes following it
+func DecodeFirst(data []byte) (interface{}, []byte, error) {
+	d := &decoder{data: data}
+	v, err := d.decode(0)
+	if err != nil {
+		return nil, nil, err
+	}
+	return v, d.data[d.pos:], nil
+}
+
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *decoder) decode(depth int) (interface{}, error) {
+	if depth > maxDepth {
+		return nil, ErrTooDeep
+	}
+	major, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case majorUnsigned:
+		if arg > math.MaxInt64 {
+			return nil, ErrUnsupported
+		}
+		return int64(arg), nil
+	case majorNegative:
+		if arg > math.MaxInt64 {
+			return nil, ErrUnsupported
+		}
+		return -1 - int64(arg), nil
+	case majorBytes, majorText:
+		b, err := d.read(arg)
+		if err != nil {
+			return nil, err
+		}
+		if major == majorText {
+			return string(b), nil
+		}
+		return append([]byte(nil), b...), nil
+	case majorArray:
+		// Every item takes at least a byte
+		if arg > uint64(len(d.data)-d.pos) {
+			return nil, ErrUnexpectedEnd
+		}
+		items := make([]interface{}, arg)
+		for i := range items {
+			if items[i], err = d.decode(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	case majorMap:
+		if arg > uint64(len(d.data)-d.pos)/2 {
+			return nil, ErrUnexpectedEnd
+		}
+		m := make(map[interface{}]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			key, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, ErrUnsupported
+			}
+			if m[key], err = d.decode(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case majorTag:
+		// Tags only annotate the item that follows
+		return d.decode(depth + 1)
+	default:
+		return d.simple(arg)
+	}
+}
+
+// head reads the initial byte and argument of an item
+func (d *decoder) head() (byte, uint64, error) {
+	if d.pos >= len(d.data) {
+		return 0, 0, ErrUnexpectedEnd
+	}
+	initial := d.data[d.pos]
+	d.pos++
+	major, info := initial>>5, initial&0x1f
+
+	// Floats are decoded by simple
+	if major == majorSimple && info >= 25 && info <= 27 {
+		return major, uint64(info), nil
+	}
+
+	switch {
+	case info < 24:
+		return major, uint64(info), nil
+	case info <= 27:
+		b, err := d.read(1 << (info - 24))
+		if err != nil {
+			return 0, 0, err
+		}
+		var arg uint64
+		for _, c := range b {
+			arg = arg<<8 | uint64(c)
+		}
+		return major, arg, nil
+	default:
+		// Indefinite lengths and reserved values
+		return 0, 0, ErrUnsupported
+	}
+}
+
+func (d *decoder) simple(info uint64) (interface{}, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	case 25:
+		b, err := d.read(2)
+		if err != nil {
+			return nil, err
+		}
+		return halfToFloat(binary.BigEndian.Uint16(b)), nil
+	case 26:
+		b, err := d.read(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	case 27:
+		b, err := d.read(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	default:
+		return nil, ErrUnsupported
+	}
+}
+
+func (d *decoder) read(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, ErrUnexpectedEnd
+	}
+	b := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return b, nil
+}
+
+func halfToFloat(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		return -f
+	}
+	return f
+}
+
+// Encode encodes the value in the canonical CBOR form, with map keys sorted.
+// It supports the same types Decode returns, as well as int and uint64.
+func Encode(v interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := encode(buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encode(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteByte(majorSimple<<5 | 22)
+	case bool:
+		if v {
+			buf.WriteByte(majorSimple<<5 | 21)
+		} else {
+			buf.WriteByte(majorSimple<<5 | 20)
+		}
+	case int:
+		return encode(buf, int64(v))
+	case int64:
+		if v < 0 {
+			writeHead(buf, majorNegative, uint64(-1-v))
+		} else {
+			writeHead(buf, majorUnsigned, uint64(v))
+		}
+	case uint64:
+		writeHead(buf, majorUnsigned, v)
+	case float64:
+		buf.WriteByte(majorSimple<<5 | 27)
+		binary.Write(buf, binary.BigEndian, math.Float64bits(v))
+	case []byte:
+		writeHead(buf, majorBytes, uint64(len(v)))
+		buf.Write(v)
+	case string:
+		writeHead(buf, majorText, uint64(len(v)))
+		buf.WriteString(v)
+	case []interface{}:
+		writeHead(buf, majorArray, uint64(len(v)))
+		for _, item := range v {
+			if err := encode(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[interface{}]interface{}:
+		// Canonical CBOR sorts keys by their encoding
+		keys := make([][]byte, 0, len(v))
+		values := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			encodedKey, err := Encode(key)
+			if err != nil {
+				return err
+			}
+			keys = append(keys, encodedKey)
+			values[string(encodedKey)] = value
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if len(keys[i]) != len(keys[j]) {
+				return len(keys[i]) < len(keys[j])
+			}
+			return bytes.Compare(keys[i], keys[j]) < 0
+		})
+		writeHead(buf, majorMap, uint64(len(v)))
+		for _, key := range keys {
+			buf.Write(key)
+			if err := encode(buf, values[string(key)]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("Cannot encode %T as CBOR", v)
+	}
+	return nil
+}
+
+func writeHead(buf *bytes.Buffer, major byte, arg uint64) {
+	switch {
+	case arg < 24:
+		buf.WriteByte(major<<5 | byte(arg))
+	case arg <= math.MaxUint8:
+		buf.WriteByte(major<<5 | 24)
+		buf.WriteByte(byte(arg))
+	case arg <= math.MaxUint16:
+		buf.WriteByte(major<<5 | 25)
+		binary.Write(buf, binary.BigEndian, uint16(arg))
+	case arg <= math.MaxUint32:
+		buf.WriteByte(major<<5 | 26)
+		binary.Write(buf, binary.BigEndian, uint32(arg))
+	default:
+		buf.WriteByte(major<<5 | 27)
+		binary.Write(buf, binary.BigEndian, arg)
+	}
+}
@@ -0,0 +1,100 @@
+package cbor_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/RichardKnop/go-oauth2-server/util/cbor"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test vectors from https://tools.ietf.org/html/rfc8949#appendix-A
+func TestDecode(t *testing.T) {
+	testCases := []struct {
+		encoded  string
+		expected interface{}
+	}{
+		{"00", int64(0)},
+		{"17", int64(23)},
+		{"1818", int64(24)},
+		{"1903e8", int64(1000)},
+		{"1a000f4240", int64(1000000)},
+		{"20", int64(-1)},
+		{"3863", int64(-100)},
+		{"f93c00", float64(1)},
+		{"fb3ff199999999999a", 1.1},
+		{"f4", false},
+		{"f5", true},
+		{"f6", nil},
+		{"4401020304", []byte{1, 2, 3, 4}},
+		{"6449455446", "IETF"},
+		{"83010203", []interface{}{int64(1), int64(2), int64(3)}},
+		{"a201020304", map[interface{}]interface{}{int64(1): int64(2), int64(3): int64(4)}},
+		{"a26161016162820203", map[interface{}]interface{}{
+			"a": int64(1),
+			"b": []interface{}{int64(2), int64(3)},
+		}},
+		{"c074323031332d30332d32315432303a30343a30305a", "2013-03-21T20:04:00Z"},
+	}
+
+	for _, testCase := range testCases {
+		data, err := hex.DecodeString(testCase.encoded)
+		assert.NoError(t, err)
+		v, err := cbor.Decode(data)
+		if assert.NoError(t, err, testCase.encoded) {
+			assert.Equal(t, testCase.expected, v, testCase.encoded)
+		}
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	testCases := []struct {
+		encoded string
+		err     error
+	}{
+		{"", cbor.ErrUnexpectedEnd},
+		{"18", cbor.ErrUnexpectedEnd},
+		{"44010203", cbor.ErrUnexpectedEnd},
+		{"9b00000000ffffffff", cbor.ErrUnexpectedEnd},
+		{"5f42010243030405ff", cbor.ErrUnsupported},
+		{"0001", cbor.ErrTrailingData},
+		{"a18101", cbor.ErrUnsupported},
+		{"818181818181818181818181818181818100", cbor.ErrTooDeep},
+	}
+
+	for _, testCase := range testCases {
+		data, err := hex.DecodeString(testCase.encoded)
+		assert.NoError(t, err)
+		_, err = cbor.Decode(data)
+		assert.Equal(t, testCase.err, err, testCase.encoded)
+	}
+}
+
+func TestDecodeFirst(t *testing.T) {
+	v, rest, err := cbor.DecodeFirst([]byte{0x01, 0x02})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), v)
+	assert.Equal(t, []byte{0x02}, rest)
+}
+
+func TestEncode(t *testing.T) {
+	// Keys are sorted in canonical order whatever the map order
+	encoded, err := cbor.Encode(map[interface{}]interface{}{
+		"b":  []interface{}{2, 3},
+		"a":  1,
+		-1:   []byte{1},
+		1000: "x",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "a420410161610161628202031903e86178", hex.EncodeToString(encoded))
+
+	// Encoded values decode to the same values
+	v, err := cbor.Decode(encoded)
+	assert.NoError(t, err)
+	assert.Equal(t, map[interface{}]interface{}{
+		"b":         []interface{}{int64(2), int64(3)},
+		"a":         int64(1),
+		int64(-1):   []byte{1},
+		int64(1000): "x",
+	}, v)
+}
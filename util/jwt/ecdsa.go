@@ -0,0 +1,108 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+const (
+	// es256KeySize is the size in bytes of each of the r and s signature values
+	es256KeySize = 32
+)
+
+var (
+	// ErrInvalidCurve ...
+	ErrInvalidCurve = errors.New("ES256 requires a P-256 curve key")
+)
+
+// ECDSAKey signs and verifies tokens with an EC private key
+type ECDSAKey struct {
+	algorithm  string
+	keyID      string
+	privateKey *ecdsa.PrivateKey
+}
+
+// NewES256 returns an ECDSAKey using ECDSA on the P-256 curve with SHA-256
+func NewES256(keyID string, privateKey *ecdsa.PrivateKey) (*ECDSAKey, error) {
+	if privateKey == nil {
+		return nil, ErrMissingPrivateKey
+	}
+	if privateKey.Curve != elliptic.P256() {
+		return nil, ErrInvalidCurve
+	}
+	return &ECDSAKey{
+		algorithm:  "ES256",
+		keyID:      keyID,
+		privateKey: privateKey,
+	}, nil
+}
+
+// Algorithm returns the JWA algorithm name
+func (k *ECDSAKey) Algorithm() string {
+	return k.algorithm
+}
+
+// KeyID returns the key ID
+func (k *ECDSAKey) KeyID() string {
+	return k.keyID
+}
+
+// Public returns the public key tokens can be verified with
+func (k *ECDSAKey) Public() crypto.PublicKey {
+	return &k.privateKey.PublicKey
+}
+
+// Sign returns the signature of data as the concatenated
+// r and s values, as required by JWS (RFC 7518 section 3.4)
+func (k *ECDSAKey) Sign(data []byte) ([]byte, error) {
+	digest := sha256.Sum256(data)
+	r, s, err := ecdsa.Sign(rand.Reader, k.privateKey, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	signature := make([]byte, 2*es256KeySize)
+	rBytes, sBytes := r.Bytes(), s.Bytes()
+	copy(signature[es256KeySize-len(rBytes):es256KeySize], rBytes)
+	copy(signature[2*es256KeySize-len(sBytes):], sBytes)
+	return signature, nil
+}
+
+// Verify checks signature is a valid signature of data
+func (k *ECDSAKey) Verify(data, signature []byte) error {
+	return verifyES256(&k.privateKey.PublicKey, data, signature)
+}
+
+// ECDSAPublicKey verifies tokens signed by the holder of an EC private key
+type ECDSAPublicKey struct {
+	publicKey *ecdsa.PublicKey
+}
+
+// Algorithm returns the JWA algorithm name
+func (k *ECDSAPublicKey) Algorithm() string {
+	return "ES256"
+}
+
+// Verify checks signature is a valid signature of data
+func (k *ECDSAPublicKey) Verify(data, signature []byte) error {
+	return verifyES256(k.publicKey, data, signature)
+}
+
+func verifyES256(publicKey *ecdsa.PublicKey, data, signature []byte) error {
+	if len(signature) != 2*es256KeySize {
+		return ErrInvalidSignature
+	}
+	r := new(big.Int).SetBytes(signature[:es256KeySize])
+	s := new(big.Int).SetBytes(signature[es256KeySize:])
+
+	digest := sha256.Sum256(data)
+	if !ecdsa.Verify(publicKey, digest[:], r, s) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
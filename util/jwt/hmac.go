@@ -0,0 +1,63 @@
+package jwt
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"hash"
+)
+
+var (
+	// ErrEmptySecret ...
+	ErrEmptySecret = errors.New("HMAC secret must not be empty")
+)
+
+// HMACKey signs and verifies tokens with a shared secret
+type HMACKey struct {
+	algorithm string
+	keyID     string
+	hash      func() hash.Hash
+	secret    []byte
+}
+
+// NewHS256 returns a HMACKey using HMAC SHA-256
+func NewHS256(keyID string, secret []byte) (*HMACKey, error) {
+	if len(secret) == 0 {
+		return nil, ErrEmptySecret
+	}
+	return &HMACKey{
+		algorithm: "HS256",
+		keyID:     keyID,
+		hash:      sha256.New,
+		secret:    secret,
+	}, nil
+}
+
+// Algorithm returns the JWA algorithm name
+func (k *HMACKey) Algorithm() string {
+	return k.algorithm
+}
+
+// KeyID returns the key ID
+func (k *HMACKey) KeyID() string {
+	return k.keyID
+}
+
+// Sign returns the HMAC of data
+func (k *HMACKey) Sign(data []byte) ([]byte, error) {
+	mac := hmac.New(k.hash, k.secret)
+	mac.Write(data)
+	return mac.Sum(nil), nil
+}
+
+// Verify checks signature is the HMAC of data
+func (k *HMACKey) Verify(data, signature []byte) error {
+	expected, err := k.Sign(data)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(expected, signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
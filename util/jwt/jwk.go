@@ -0,0 +1,223 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+)
+
+const (
+	// generatedRSAKeyBits is the size of generated RSA keys
+	generatedRSAKeyBits = 2048
+)
+
+var (
+	// ErrUnsupportedPublicKey ...
+	ErrUnsupportedPublicKey = errors.New("Only RSA and P-256 EC public keys can be published")
+	// ErrInvalidJSONWebKey ...
+	ErrInvalidJSONWebKey = errors.New("Invalid JSON Web Key")
+)
+
+// PublicKeyHolder is implemented by asymmetric keys whose public part can be
+// published for others to verify tokens with
+type PublicKeyHolder interface {
+	Public() crypto.PublicKey
+}
+
+// JSONWebKey is the public part of a signing key (RFC 7517)
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	KeyID     string `json:"kid,omitempty"`
+	// RSA public keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC public keys
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+}
+
+// JSONWebKeySet is a set of public keys tokens can be verified with
+type JSONWebKeySet struct {
+	Keys []*JSONWebKey `json:"keys"`
+}
+
+// NewJSONWebKey returns the public part of the key as a JSON Web Key,
+// failing for symmetric keys which must never be published
+func NewJSONWebKey(key Key) (*JSONWebKey, error) {
+	holder, ok := key.(PublicKeyHolder)
+	if !ok {
+		return nil, ErrUnsupportedPublicKey
+	}
+	jwk, err := newPublicJSONWebKey(holder.Public())
+	if err != nil {
+		return nil, err
+	}
+	jwk.Use = "sig"
+	jwk.Algorithm = key.Algorithm()
+	jwk.KeyID = key.KeyID()
+	return jwk, nil
+}
+
+// Verifier returns a verifier of tokens signed with the algorithm by the
+// holder of the private key matching the JSON Web Key
+func (k *JSONWebKey) Verifier(algorithm string) (Verifier, error) {
+	publicKey, err := k.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, ErrInvalidJSONWebKey
+	}
+	return NewVerifier(algorithm, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+// Thumbprint returns the JWK thumbprint of the key (RFC 7638)
+func (k *JSONWebKey) Thumbprint() (string, error) {
+	publicKey, err := k.PublicKey()
+	if err != nil {
+		return "", err
+	}
+	return Thumbprint(publicKey)
+}
+
+// PublicKey decodes the RSA or P-256 EC public key
+func (k *JSONWebKey) PublicKey() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := decodeSegment(k.N)
+		if err != nil {
+			return nil, ErrInvalidJSONWebKey
+		}
+		e, err := decodeSegment(k.E)
+		if err != nil || len(e) > 4 {
+			return nil, ErrInvalidJSONWebKey
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		if k.Curve != "P-256" {
+			return nil, ErrInvalidCurve
+		}
+		x, err := decodeSegment(k.X)
+		if err != nil {
+			return nil, ErrInvalidJSONWebKey
+		}
+		y, err := decodeSegment(k.Y)
+		if err != nil {
+			return nil, ErrInvalidJSONWebKey
+		}
+		return &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+	}
+	return nil, ErrUnsupportedPublicKey
+}
+
+// Thumbprint returns the JWK thumbprint of the public key (RFC 7638),
+// a stable identifier suitable for use as its key ID
+func Thumbprint(publicKey crypto.PublicKey) (string, error) {
+	jwk, err := newPublicJSONWebKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+
+	// The required members in lexicographic order, without whitespace
+	var members interface{}
+	switch jwk.KeyType {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.KeyType, jwk.N}
+	case "EC":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Curve, jwk.KeyType, jwk.X, jwk.Y}
+	}
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return encodeSegment(sum[:]), nil
+}
+
+// GenerateKey returns a new PEM encoded private key suiting the algorithm,
+// a 2048 bit RSA key for the RS* algorithms and a P-256 EC key for ES256
+func GenerateKey(algorithm string) ([]byte, error) {
+	switch algorithm {
+	case RS256, RS384, RS512:
+		privateKey, err := rsa.GenerateKey(rand.Reader, generatedRSAKeyBits)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+		}), nil
+	case ES256:
+		privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		der, err := x509.MarshalECPrivateKey(privateKey)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+	}
+	return nil, ErrUnsupportedAlgorithm
+}
+
+// newPublicJSONWebKey returns the key type specific members of the public key
+func newPublicJSONWebKey(publicKey crypto.PublicKey) (*JSONWebKey, error) {
+	switch publicKey := publicKey.(type) {
+	case *rsa.PublicKey:
+		return &JSONWebKey{
+			KeyType: "RSA",
+			N:       encodeSegment(publicKey.N.Bytes()),
+			E:       encodeSegment(big.NewInt(int64(publicKey.E)).Bytes()),
+		}, nil
+	case *ecdsa.PublicKey:
+		if publicKey.Curve != elliptic.P256() {
+			return nil, ErrInvalidCurve
+		}
+		return &JSONWebKey{
+			KeyType: "EC",
+			Curve:   "P-256",
+			X:       encodeSegment(padCoordinate(publicKey.X.Bytes())),
+			Y:       encodeSegment(padCoordinate(publicKey.Y.Bytes())),
+		}, nil
+	}
+	return nil, ErrUnsupportedPublicKey
+}
+
+// padCoordinate left pads an EC coordinate to the size of the curve,
+// as required by RFC 7518 section 6.2.1.2
+func padCoordinate(coordinate []byte) []byte {
+	padded := make([]byte, es256KeySize)
+	copy(padded[es256KeySize-len(coordinate):], coordinate)
+	return padded
+}
@@ -0,0 +1,87 @@
+package jwt_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/RichardKnop/go-oauth2-server/util/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewJSONWebKey(t *testing.T) {
+	for _, algorithm := range []string{jwt.RS256, jwt.ES256} {
+		privateKeyPEM, err := jwt.GenerateKey(algorithm)
+		if !assert.NoError(t, err, algorithm) {
+			continue
+		}
+		key, err := jwt.NewKey(algorithm, "", nil, privateKeyPEM)
+		if !assert.NoError(t, err, algorithm) {
+			continue
+		}
+
+		// Keys are identified by their thumbprint by default
+		thumbprint, err := jwt.Thumbprint(key.(jwt.PublicKeyHolder).Public())
+		assert.NoError(t, err)
+		assert.Equal(t, thumbprint, key.KeyID())
+
+		jwk, err := jwt.NewJSONWebKey(key)
+		if !assert.NoError(t, err, algorithm) {
+			continue
+		}
+		assert.Equal(t, "sig", jwk.Use)
+		assert.Equal(t, algorithm, jwk.Algorithm)
+		assert.Equal(t, key.KeyID(), jwk.KeyID)
+
+		// Tokens can be verified with the key once published
+		data, err := json.Marshal(&jwt.JSONWebKeySet{Keys: []*jwt.JSONWebKey{jwk}})
+		assert.NoError(t, err)
+		keySet := new(jwt.JSONWebKeySet)
+		assert.NoError(t, json.Unmarshal(data, keySet))
+		verifier, err := keySet.Keys[0].Verifier(algorithm)
+		if !assert.NoError(t, err, algorithm) {
+			continue
+		}
+		token, err := jwt.Sign(jwt.Claims{"sub": "1"}, key)
+		assert.NoError(t, err)
+		_, err = jwt.Parse(token, verifier)
+		assert.NoError(t, err, algorithm)
+	}
+}
+
+func TestSignWithJSONWebKeyHeader(t *testing.T) {
+	privateKeyPEM, err := jwt.GenerateKey(jwt.ES256)
+	assert.NoError(t, err)
+	key, err := jwt.NewKey(jwt.ES256, "", nil, privateKeyPEM)
+	assert.NoError(t, err)
+	jwk, err := jwt.NewJSONWebKey(key)
+	assert.NoError(t, err)
+
+	// Self-signed tokens carry the public key they can be verified with
+	token, err := jwt.SignWithHeader(&jwt.Header{Type: "dpop+jwt", JSONWebKey: jwk}, jwt.Claims{"jti": "1"}, key)
+	assert.NoError(t, err)
+	header, err := jwt.ParseHeader(token)
+	assert.NoError(t, err)
+	assert.Equal(t, jwt.ES256, header.Algorithm)
+	assert.Equal(t, "dpop+jwt", header.Type)
+	if assert.NotNil(t, header.JSONWebKey) {
+		thumbprint, err := header.JSONWebKey.Thumbprint()
+		assert.NoError(t, err)
+		assert.Equal(t, key.KeyID(), thumbprint)
+
+		verifier, err := header.JSONWebKey.Verifier(header.Algorithm)
+		assert.NoError(t, err)
+		_, err = jwt.Parse(token, verifier)
+		assert.NoError(t, err)
+	}
+}
+
+func TestNewJSONWebKeyRefusesSecrets(t *testing.T) {
+	key, err := jwt.NewHS256("", []byte("test_secret"))
+	assert.NoError(t, err)
+
+	_, err = jwt.NewJSONWebKey(key)
+	assert.Equal(t, jwt.ErrUnsupportedPublicKey, err)
+
+	_, err = jwt.GenerateKey(jwt.HS256)
+	assert.Equal(t, jwt.ErrUnsupportedAlgorithm, err)
+}
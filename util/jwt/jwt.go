@@ -0,0 +1,176 @@
+package jwt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrMalformedToken ...
+	ErrMalformedToken = errors.New("Malformed token")
+	// ErrInvalidSignature ...
+	ErrInvalidSignature = errors.New("Invalid token signature")
+	// ErrUnexpectedAlgorithm ...
+	ErrUnexpectedAlgorithm = errors.New("Unexpected token signing algorithm")
+	// ErrTokenExpired ...
+	ErrTokenExpired = errors.New("Token expired")
+)
+
+// Header is the JOSE header of a signed token
+type Header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ,omitempty"`
+	KeyID     string `json:"kid,omitempty"`
+	// JSONWebKey is the public key of self-signed tokens such as DPoP proofs
+	JSONWebKey *JSONWebKey `json:"jwk,omitempty"`
+}
+
+// Claims is the JSON payload of a token
+type Claims map[string]interface{}
+
+// Signer signs tokens with a particular algorithm and key
+type Signer interface {
+	Algorithm() string
+	KeyID() string
+	Sign(data []byte) ([]byte, error)
+}
+
+// Verifier verifies token signatures with a particular algorithm and key
+type Verifier interface {
+	Algorithm() string
+	Verify(data, signature []byte) error
+}
+
+// Sign encodes the claims and returns a compact serialized signed token
+func Sign(claims Claims, signer Signer) (string, error) {
+	return SignWithHeader(&Header{Type: "JWT", KeyID: signer.KeyID()}, claims, signer)
+}
+
+// SignWithHeader is like Sign but lets the caller set the type and other
+// header members, the algorithm is always the one of the signer
+func SignWithHeader(h *Header, claims Claims, signer Signer) (string, error) {
+	headerCopy := *h
+	headerCopy.Algorithm = signer.Algorithm()
+	header, err := json.Marshal(&headerCopy)
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := encodeSegment(header) + "." + encodeSegment(payload)
+	signature, err := signer.Sign([]byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + encodeSegment(signature), nil
+}
+
+// Parse verifies the token signature and expiration and returns its claims
+func Parse(token string, verifier Verifier) (Claims, error) {
+	header, claims, signingInput, signature, err := decode(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if header.Algorithm != verifier.Algorithm() {
+		return nil, ErrUnexpectedAlgorithm
+	}
+
+	if err := verifier.Verify(signingInput, signature); err != nil {
+		return nil, ErrInvalidSignature
+	}
+
+	if exp, ok := claims.Int64("exp"); ok && time.Now().Unix() >= exp {
+		return nil, ErrTokenExpired
+	}
+
+	return claims, nil
+}
+
+// ParseHeader returns the header of a token without verifying it,
+// useful for selecting the verification key by its key ID
+func ParseHeader(token string) (*Header, error) {
+	header, _, _, _, err := decode(token)
+	return header, err
+}
+
+// ParseUnverified returns the claims of a token without verifying it, useful
+// for finding the verification key by the issuer, the claims must not be
+// trusted until the token has been verified
+func ParseUnverified(token string) (Claims, error) {
+	_, claims, _, _, err := decode(token)
+	return claims, err
+}
+
+// String returns a string claim
+func (c Claims) String(name string) (string, bool) {
+	v, ok := c[name].(string)
+	return v, ok
+}
+
+// Int64 returns a numeric claim
+func (c Claims) Int64(name string) (int64, bool) {
+	switch v := c[name].(type) {
+	case float64:
+		return int64(v), true
+	case json.Number:
+		n, err := v.Int64()
+		return n, err == nil
+	case int64:
+		return v, true
+	case int:
+		return int64(v), true
+	}
+	return 0, false
+}
+
+func decode(token string) (*Header, Claims, []byte, []byte, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, nil, nil, nil, ErrMalformedToken
+	}
+
+	headerJSON, err := decodeSegment(parts[0])
+	if err != nil {
+		return nil, nil, nil, nil, ErrMalformedToken
+	}
+	header := new(Header)
+	if err := json.Unmarshal(headerJSON, header); err != nil {
+		return nil, nil, nil, nil, ErrMalformedToken
+	}
+
+	payload, err := decodeSegment(parts[1])
+	if err != nil {
+		return nil, nil, nil, nil, ErrMalformedToken
+	}
+	claims := make(Claims)
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&claims); err != nil {
+		return nil, nil, nil, nil, ErrMalformedToken
+	}
+
+	signature, err := decodeSegment(parts[2])
+	if err != nil {
+		return nil, nil, nil, nil, ErrMalformedToken
+	}
+
+	return header, claims, []byte(parts[0] + "." + parts[1]), signature, nil
+}
+
+func encodeSegment(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSegment(segment string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(segment)
+}
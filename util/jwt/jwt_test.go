@@ -0,0 +1,75 @@
+package jwt_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/util/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignAndParse(t *testing.T) {
+	key, err := jwt.NewHS256("test_key", []byte("test_secret"))
+	assert.NoError(t, err)
+
+	token, err := jwt.Sign(jwt.Claims{
+		"sub": "1",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}, key)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(strings.Split(token, ".")))
+
+	header, err := jwt.ParseHeader(token)
+	assert.NoError(t, err)
+	assert.Equal(t, &jwt.Header{Algorithm: "HS256", Type: "JWT", KeyID: "test_key"}, header)
+
+	claims, err := jwt.Parse(token, key)
+	assert.NoError(t, err)
+	sub, ok := claims.String("sub")
+	assert.True(t, ok)
+	assert.Equal(t, "1", sub)
+}
+
+func TestParseInvalidSignature(t *testing.T) {
+	key, err := jwt.NewHS256("", []byte("test_secret"))
+	assert.NoError(t, err)
+	otherKey, err := jwt.NewHS256("", []byte("other_secret"))
+	assert.NoError(t, err)
+
+	token, err := jwt.Sign(jwt.Claims{"sub": "1"}, key)
+	assert.NoError(t, err)
+
+	_, err = jwt.Parse(token, otherKey)
+	assert.Equal(t, jwt.ErrInvalidSignature, err)
+}
+
+func TestParseExpired(t *testing.T) {
+	key, err := jwt.NewHS256("", []byte("test_secret"))
+	assert.NoError(t, err)
+
+	token, err := jwt.Sign(jwt.Claims{
+		"sub": "1",
+		"exp": time.Now().Add(-time.Minute).Unix(),
+	}, key)
+	assert.NoError(t, err)
+
+	_, err = jwt.Parse(token, key)
+	assert.Equal(t, jwt.ErrTokenExpired, err)
+}
+
+func TestParseMalformed(t *testing.T) {
+	key, err := jwt.NewHS256("", []byte("test_secret"))
+	assert.NoError(t, err)
+
+	_, err = jwt.Parse("bogus", key)
+	assert.Equal(t, jwt.ErrMalformedToken, err)
+
+	_, err = jwt.Parse("a.b.c", key)
+	assert.Equal(t, jwt.ErrMalformedToken, err)
+}
+
+func TestNewHS256EmptySecret(t *testing.T) {
+	_, err := jwt.NewHS256("", nil)
+	assert.Equal(t, jwt.ErrEmptySecret, err)
+}
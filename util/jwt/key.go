@@ -0,0 +1,168 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+)
+
+// Supported signing algorithms
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	RS384 = "RS384"
+	RS512 = "RS512"
+	ES256 = "ES256"
+)
+
+var (
+	// ErrUnsupportedAlgorithm ...
+	ErrUnsupportedAlgorithm = errors.New("Signing algorithm not supported")
+	// ErrMissingPrivateKey ...
+	ErrMissingPrivateKey = errors.New("Signing algorithm requires a private key")
+	// ErrInvalidPrivateKey ...
+	ErrInvalidPrivateKey = errors.New("Private key is not a valid PEM encoded key")
+	// ErrIncompatibleKey ...
+	ErrIncompatibleKey = errors.New("Private key type is not compatible with the signing algorithm")
+	// ErrInvalidPublicKey ...
+	ErrInvalidPublicKey = errors.New("Public key is not a valid PEM encoded key")
+	// ErrIncompatiblePublicKey ...
+	ErrIncompatiblePublicKey = errors.New("Public key type is not compatible with the signing algorithm")
+)
+
+// Key both signs and verifies tokens
+type Key interface {
+	Algorithm() string
+	KeyID() string
+	Sign(data []byte) ([]byte, error)
+	Verify(data, signature []byte) error
+}
+
+// NewKey returns a key for the algorithm, making sure the provided key material
+// suits it: HS256 uses the secret only, RS* require a PEM encoded RSA private key
+// and ES256 a PEM encoded P-256 EC private key. Asymmetric keys without a key ID
+// are identified by their thumbprint.
+func NewKey(algorithm, keyID string, secret, privateKeyPEM []byte) (Key, error) {
+	switch algorithm {
+	case HS256:
+		if len(privateKeyPEM) > 0 {
+			return nil, ErrIncompatibleKey
+		}
+		return NewHS256(keyID, secret)
+	case RS256, RS384, RS512, ES256:
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	if len(privateKeyPEM) == 0 {
+		return nil, ErrMissingPrivateKey
+	}
+	privateKey, err := parsePrivateKey(privateKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keys which cannot be thumbprinted are refused below
+	if signer, ok := privateKey.(crypto.Signer); ok && keyID == "" {
+		keyID, _ = Thumbprint(signer.Public())
+	}
+
+	switch privateKey := privateKey.(type) {
+	case *rsa.PrivateKey:
+		switch algorithm {
+		case RS256:
+			return NewRS256(keyID, privateKey)
+		case RS384:
+			return NewRS384(keyID, privateKey)
+		case RS512:
+			return NewRS512(keyID, privateKey)
+		}
+	case *ecdsa.PrivateKey:
+		if algorithm == ES256 {
+			return NewES256(keyID, privateKey)
+		}
+	}
+	return nil, ErrIncompatibleKey
+}
+
+// NewVerifier returns a verifier of tokens signed by the holder of the private key
+// matching a PEM encoded public key, which must be an RSA key for the RS* algorithms
+// and a P-256 EC key for ES256
+func NewVerifier(algorithm string, publicKeyPEM []byte) (Verifier, error) {
+	var hash crypto.Hash
+	switch algorithm {
+	case RS256:
+		hash = crypto.SHA256
+	case RS384:
+		hash = crypto.SHA384
+	case RS512:
+		hash = crypto.SHA512
+	case ES256:
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	publicKey, err := parsePublicKey(publicKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	switch publicKey := publicKey.(type) {
+	case *rsa.PublicKey:
+		if algorithm == ES256 {
+			return nil, ErrIncompatiblePublicKey
+		}
+		if publicKey.N.BitLen() < minRSAKeyBits {
+			return nil, ErrRSAKeyTooShort
+		}
+		return &RSAPublicKey{algorithm: algorithm, hash: hash, publicKey: publicKey}, nil
+	case *ecdsa.PublicKey:
+		if algorithm != ES256 {
+			return nil, ErrIncompatiblePublicKey
+		}
+		if publicKey.Curve != elliptic.P256() {
+			return nil, ErrInvalidCurve
+		}
+		return &ECDSAPublicKey{publicKey: publicKey}, nil
+	}
+	return nil, ErrIncompatiblePublicKey
+}
+
+// parsePublicKey decodes a PKIX or PKCS #1 public key
+func parsePublicKey(data []byte) (interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidPublicKey
+	}
+
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, ErrInvalidPublicKey
+}
+
+// parsePrivateKey decodes a PKCS #1, PKCS #8 or SEC 1 private key
+func parsePrivateKey(data []byte) (interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidPrivateKey
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, ErrInvalidPrivateKey
+}
@@ -0,0 +1,175 @@
+package jwt_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/RichardKnop/go-oauth2-server/util/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewKey(t *testing.T) {
+	rsaPEM, ecPEM := generateRSAKeyPEM(t), generateECKeyPEM(t, elliptic.P256())
+
+	for _, testCase := range []struct {
+		algorithm     string
+		secret        []byte
+		privateKeyPEM []byte
+	}{
+		{jwt.HS256, []byte("test_secret"), nil},
+		{jwt.RS256, nil, rsaPEM},
+		{jwt.RS384, nil, rsaPEM},
+		{jwt.RS512, []byte("test_secret"), rsaPEM},
+		{jwt.ES256, nil, ecPEM},
+	} {
+		key, err := jwt.NewKey(testCase.algorithm, "test_key", testCase.secret, testCase.privateKeyPEM)
+		if !assert.NoError(t, err, testCase.algorithm) {
+			continue
+		}
+		assert.Equal(t, testCase.algorithm, key.Algorithm())
+
+		// The configured algorithm is used in the token header
+		token, err := jwt.Sign(jwt.Claims{"sub": "1"}, key)
+		assert.NoError(t, err)
+		header, err := jwt.ParseHeader(token)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.algorithm, header.Algorithm)
+
+		claims, err := jwt.Parse(token, key)
+		assert.NoError(t, err, testCase.algorithm)
+		assert.Equal(t, "1", claims["sub"])
+	}
+}
+
+func TestNewKeyIncompatible(t *testing.T) {
+	rsaPEM, ecPEM := generateRSAKeyPEM(t), generateECKeyPEM(t, elliptic.P256())
+
+	for _, testCase := range []struct {
+		algorithm     string
+		secret        []byte
+		privateKeyPEM []byte
+		err           error
+	}{
+		{jwt.HS256, nil, nil, jwt.ErrEmptySecret},
+		{jwt.HS256, []byte("test_secret"), rsaPEM, jwt.ErrIncompatibleKey},
+		{jwt.RS256, []byte("test_secret"), nil, jwt.ErrMissingPrivateKey},
+		{jwt.RS256, nil, ecPEM, jwt.ErrIncompatibleKey},
+		{jwt.ES256, nil, rsaPEM, jwt.ErrIncompatibleKey},
+		{jwt.ES256, nil, generateECKeyPEM(t, elliptic.P384()), jwt.ErrInvalidCurve},
+		{jwt.RS256, nil, []byte("bogus"), jwt.ErrInvalidPrivateKey},
+		{"none", []byte("test_secret"), nil, jwt.ErrUnsupportedAlgorithm},
+	} {
+		_, err := jwt.NewKey(testCase.algorithm, "", testCase.secret, testCase.privateKeyPEM)
+		assert.Equal(t, testCase.err, err, testCase.algorithm)
+	}
+}
+
+func TestParseRejectsOtherAlgorithm(t *testing.T) {
+	rsaKey, err := jwt.NewKey(jwt.RS256, "", nil, generateRSAKeyPEM(t))
+	assert.NoError(t, err)
+	hmacKey, err := jwt.NewHS256("", []byte("test_secret"))
+	assert.NoError(t, err)
+
+	token, err := jwt.Sign(jwt.Claims{"sub": "1"}, hmacKey)
+	assert.NoError(t, err)
+
+	_, err = jwt.Parse(token, rsaKey)
+	assert.Equal(t, jwt.ErrUnexpectedAlgorithm, err)
+}
+
+func TestNewVerifier(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	rsaPublicPEM, ecPublicPEM := encodePublicKeyPEM(t, &rsaKey.PublicKey), encodePublicKeyPEM(t, &ecKey.PublicKey)
+
+	rs256, err := jwt.NewRS256("", rsaKey)
+	assert.NoError(t, err)
+	es256, err := jwt.NewES256("", ecKey)
+	assert.NoError(t, err)
+
+	for _, testCase := range []struct {
+		signer       jwt.Key
+		publicKeyPEM []byte
+	}{
+		{rs256, rsaPublicPEM},
+		{es256, ecPublicPEM},
+	} {
+		verifier, err := jwt.NewVerifier(testCase.signer.Algorithm(), testCase.publicKeyPEM)
+		if !assert.NoError(t, err, testCase.signer.Algorithm()) {
+			continue
+		}
+
+		token, err := jwt.Sign(jwt.Claims{"sub": "1"}, testCase.signer)
+		assert.NoError(t, err)
+		claims, err := jwt.Parse(token, verifier)
+		assert.NoError(t, err, testCase.signer.Algorithm())
+		assert.Equal(t, "1", claims["sub"])
+	}
+
+	// Tokens signed with another key are rejected
+	otherKey, err := jwt.NewKey(jwt.RS256, "", nil, generateRSAKeyPEM(t))
+	assert.NoError(t, err)
+	token, err := jwt.Sign(jwt.Claims{"sub": "1"}, otherKey)
+	assert.NoError(t, err)
+	verifier, err := jwt.NewVerifier(jwt.RS256, rsaPublicPEM)
+	assert.NoError(t, err)
+	_, err = jwt.Parse(token, verifier)
+	assert.Equal(t, jwt.ErrInvalidSignature, err)
+}
+
+func TestNewVerifierIncompatible(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	rsaPublicPEM := encodePublicKeyPEM(t, &rsaKey.PublicKey)
+
+	for _, testCase := range []struct {
+		algorithm    string
+		publicKeyPEM []byte
+		err          error
+	}{
+		{jwt.ES256, rsaPublicPEM, jwt.ErrIncompatiblePublicKey},
+		{jwt.HS256, rsaPublicPEM, jwt.ErrUnsupportedAlgorithm},
+		{jwt.RS256, []byte("bogus"), jwt.ErrInvalidPublicKey},
+	} {
+		_, err := jwt.NewVerifier(testCase.algorithm, testCase.publicKeyPEM)
+		assert.Equal(t, testCase.err, err, testCase.algorithm)
+	}
+}
+
+func encodePublicKeyPEM(t *testing.T, publicKey interface{}) []byte {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func generateRSAKeyPEM(t *testing.T) []byte {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	})
+}
+
+func generateECKeyPEM(t *testing.T, curve elliptic.Curve) []byte {
+	privateKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+}
@@ -0,0 +1,116 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha256" // register SHA-256 for RS256
+	_ "crypto/sha512" // register SHA-384 and SHA-512
+	"errors"
+)
+
+const (
+	minRSAKeyBits = 2048
+)
+
+var (
+	// ErrRSAKeyTooShort ...
+	ErrRSAKeyTooShort = errors.New("RSA key must be at least 2048 bits")
+)
+
+// RSAKey signs and verifies tokens with an RSA private key using PKCS #1 v1.5
+type RSAKey struct {
+	algorithm  string
+	keyID      string
+	hash       crypto.Hash
+	privateKey *rsa.PrivateKey
+}
+
+// NewRS256 returns a RSAKey using RSA with SHA-256
+func NewRS256(keyID string, privateKey *rsa.PrivateKey) (*RSAKey, error) {
+	return newRSAKey("RS256", keyID, crypto.SHA256, privateKey)
+}
+
+// NewRS384 returns a RSAKey using RSA with SHA-384
+func NewRS384(keyID string, privateKey *rsa.PrivateKey) (*RSAKey, error) {
+	return newRSAKey("RS384", keyID, crypto.SHA384, privateKey)
+}
+
+// NewRS512 returns a RSAKey using RSA with SHA-512
+func NewRS512(keyID string, privateKey *rsa.PrivateKey) (*RSAKey, error) {
+	return newRSAKey("RS512", keyID, crypto.SHA512, privateKey)
+}
+
+func newRSAKey(algorithm, keyID string, hash crypto.Hash, privateKey *rsa.PrivateKey) (*RSAKey, error) {
+	if privateKey == nil {
+		return nil, ErrMissingPrivateKey
+	}
+	if privateKey.N.BitLen() < minRSAKeyBits {
+		return nil, ErrRSAKeyTooShort
+	}
+	return &RSAKey{
+		algorithm:  algorithm,
+		keyID:      keyID,
+		hash:       hash,
+		privateKey: privateKey,
+	}, nil
+}
+
+// Algorithm returns the JWA algorithm name
+func (k *RSAKey) Algorithm() string {
+	return k.algorithm
+}
+
+// KeyID returns the key ID
+func (k *RSAKey) KeyID() string {
+	return k.keyID
+}
+
+// Public returns the public key tokens can be verified with
+func (k *RSAKey) Public() crypto.PublicKey {
+	return &k.privateKey.PublicKey
+}
+
+// Sign returns the PKCS #1 v1.5 signature of data
+func (k *RSAKey) Sign(data []byte) ([]byte, error) {
+	return rsa.SignPKCS1v15(rand.Reader, k.privateKey, k.hash, k.digest(data))
+}
+
+// Verify checks signature is a valid signature of data
+func (k *RSAKey) Verify(data, signature []byte) error {
+	return verifyRSA(&k.privateKey.PublicKey, k.hash, data, signature)
+}
+
+func (k *RSAKey) digest(data []byte) []byte {
+	return rsaDigest(k.hash, data)
+}
+
+// RSAPublicKey verifies tokens signed by the holder of an RSA private key
+type RSAPublicKey struct {
+	algorithm string
+	hash      crypto.Hash
+	publicKey *rsa.PublicKey
+}
+
+// Algorithm returns the JWA algorithm name
+func (k *RSAPublicKey) Algorithm() string {
+	return k.algorithm
+}
+
+// Verify checks signature is a valid signature of data
+func (k *RSAPublicKey) Verify(data, signature []byte) error {
+	return verifyRSA(k.publicKey, k.hash, data, signature)
+}
+
+func verifyRSA(publicKey *rsa.PublicKey, hash crypto.Hash, data, signature []byte) error {
+	if err := rsa.VerifyPKCS1v15(publicKey, hash, rsaDigest(hash, data), signature); err != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func rsaDigest(hash crypto.Hash, data []byte) []byte {
+	h := hash.New()
+	h.Write(data)
+	return h.Sum(nil)
+}
@@ -0,0 +1,100 @@
+package ldap
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrUserNotFound ...
+	ErrUserNotFound = errors.New("LDAP user not found")
+	// ErrMultipleUsers ...
+	ErrMultipleUsers = errors.New("LDAP user filter matches more than one entry")
+)
+
+// Authenticator verifies passwords the way directories expect clients
+// to: it finds the entry of the user with the service account, then binds
+// as the entry with the password
+type Authenticator struct {
+	// URL of the server, ldap:// or ldaps://
+	URL string
+	// StartTLS upgrades ldap:// connections to TLS
+	StartTLS  bool
+	TLSConfig *tls.Config
+	// BindDN and BindPassword are the service account searching for
+	// users, searches are anonymous when BindDN is empty
+	BindDN       string
+	BindPassword string
+	// BaseDN is the subtree searched for users
+	BaseDN string
+	// UserFilter finds the entry of a user, %s is replaced with the
+	// escaped username, e.g. (uid=%s) or (sAMAccountName=%s)
+	UserFilter string
+	// Attributes of the entry to return, all of them when empty
+	Attributes []string
+	// Timeout applies to connecting and to every operation
+	Timeout time.Duration
+}
+
+// Authenticate returns the entry of the user if the password is right.
+// ErrUserNotFound or ErrInvalidCredentials are returned if not.
+func (a *Authenticator) Authenticate(username, password string) (*Entry, error) {
+	// A simple bind without a password is an anonymous bind, which succeeds
+	if username == "" {
+		return nil, ErrUserNotFound
+	}
+	if password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := Dial(a.URL, a.TLSConfig, a.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if a.StartTLS {
+		if err := conn.StartTLS(a.TLSConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	// Refused service account credentials are a configuration error,
+	// not a wrong password
+	if a.BindDN != "" {
+		if err := conn.Bind(a.BindDN, a.BindPassword); err != nil {
+			return nil, fmt.Errorf("Binding as %s failed: %s", a.BindDN, err)
+		}
+	}
+
+	// Ask for two entries to tell apart filters matching several users
+	entries, err := conn.Search(&SearchRequest{
+		BaseDN:     a.BaseDN,
+		Scope:      ScopeWholeSubtree,
+		Filter:     strings.Replace(a.UserFilter, "%s", EscapeFilter(username), -1),
+		Attributes: a.Attributes,
+		SizeLimit:  2,
+		TimeLimit:  int(a.Timeout / time.Second),
+	})
+	if ldapErr, ok := err.(*Error); ok && ldapErr.ResultCode == ResultSizeLimitExceeded {
+		return nil, ErrMultipleUsers
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch len(entries) {
+	case 0:
+		return nil, ErrUserNotFound
+	case 1:
+	default:
+		return nil, ErrMultipleUsers
+	}
+
+	if err := conn.Bind(entries[0].DN, password); err != nil {
+		return nil, err
+	}
+	return entries[0], nil
+}
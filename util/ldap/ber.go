@@ -0,0 +1,176 @@
+package ldap
+
+import (
+	"errors"
+	"io"
+)
+
+// BER tags of the universal types LDAP uses
+const (
+	tagBoolean     = 0x01
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagNull        = 0x05
+	tagEnumerated  = 0x0a
+	tagSequence    = 0x30
+	tagSet         = 0x31
+
+	classApplication = 0x40
+	classContext     = 0x80
+	constructed      = 0x20
+
+	// maxMessageLength limits the size of the messages read from the server
+	maxMessageLength = 1 << 20
+)
+
+var (
+	// ErrMalformed ...
+	ErrMalformed = errors.New("Malformed LDAP message")
+)
+
+// element is a BER encoded tag, length and value. Only tags below 31
+// are supported, which are the only ones LDAP uses.
+type element struct {
+	tag   byte
+	value []byte
+}
+
+// encode returns the BER encoding of an element with the value
+func encode(tag byte, value []byte) []byte {
+	b := []byte{tag}
+	switch n := len(value); {
+	case n < 0x80:
+		b = append(b, byte(n))
+	default:
+		var length []byte
+		for ; n > 0; n >>= 8 {
+			length = append([]byte{byte(n)}, length...)
+		}
+		b = append(b, 0x80|byte(len(length)))
+		b = append(b, length...)
+	}
+	return append(b, value...)
+}
+
+// encodeConstructed returns the BER encoding of a constructed element
+func encodeConstructed(tag byte, children ...[]byte) []byte {
+	var value []byte
+	for _, child := range children {
+		value = append(value, child...)
+	}
+	return encode(tag, value)
+}
+
+// encodeInteger returns the BER encoding of an integer or enumerated value
+func encodeInteger(tag byte, n int64) []byte {
+	var value []byte
+	for {
+		value = append([]byte{byte(n)}, value...)
+		// Stop once the sign bit of the first byte is right
+		if (n < 128 && n >= -128) || len(value) == 8 {
+			break
+		}
+		n >>= 8
+	}
+	return encode(tag, value)
+}
+
+// encodeString returns the BER encoding of an octet string
+func encodeString(tag byte, s string) []byte {
+	return encode(tag, []byte(s))
+}
+
+// encodeBoolean returns the BER encoding of a boolean
+func encodeBoolean(b bool) []byte {
+	if b {
+		return encode(tagBoolean, []byte{0xff})
+	}
+	return encode(tagBoolean, []byte{0x00})
+}
+
+// readElement reads a whole element from the reader
+func readElement(r io.Reader) (*element, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	length := int(header[1])
+	if length&0x80 != 0 {
+		size := length & 0x7f
+		if size == 0 || size > 4 {
+			return nil, ErrMalformed
+		}
+		b := make([]byte, size)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		length = 0
+		for _, c := range b {
+			length = length<<8 | int(c)
+		}
+	}
+	if length > maxMessageLength {
+		return nil, ErrMalformed
+	}
+	value := make([]byte, length)
+	if _, err := io.ReadFull(r, value); err != nil {
+		return nil, err
+	}
+	return &element{tag: header[0], value: value}, nil
+}
+
+// parseElement parses the element at the start of data and
+// returns the bytes following it
+func parseElement(data []byte) (*element, []byte, error) {
+	if len(data) < 2 {
+		return nil, nil, ErrMalformed
+	}
+	tag, length, rest := data[0], int(data[1]), data[2:]
+	if tag&0x1f == 0x1f {
+		return nil, nil, ErrMalformed
+	}
+	if length&0x80 != 0 {
+		size := length & 0x7f
+		if size == 0 || size > 4 || len(rest) < size {
+			return nil, nil, ErrMalformed
+		}
+		length = 0
+		for _, c := range rest[:size] {
+			length = length<<8 | int(c)
+		}
+		rest = rest[size:]
+	}
+	if length < 0 || length > len(rest) {
+		return nil, nil, ErrMalformed
+	}
+	return &element{tag: tag, value: rest[:length]}, rest[length:], nil
+}
+
+// children parses the value of a constructed element
+func (e *element) children() ([]*element, error) {
+	var (
+		children []*element
+		rest     = e.value
+	)
+	for len(rest) > 0 {
+		child, after, err := parseElement(rest)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+		rest = after
+	}
+	return children, nil
+}
+
+// integer parses the value of an integer or enumerated element
+func (e *element) integer() (int64, error) {
+	if len(e.value) == 0 || len(e.value) > 8 {
+		return 0, ErrMalformed
+	}
+	n := int64(int8(e.value[0]))
+	for _, c := range e.value[1:] {
+		n = n<<8 | int64(c)
+	}
+	return n, nil
+}
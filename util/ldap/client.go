@@ -0,0 +1,369 @@
+package ldap
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Protocol operations, see https://tools.ietf.org/html/rfc4511#section-4.2
+const (
+	opBindRequest           = classApplication | constructed | 0
+	opBindResponse          = classApplication | constructed | 1
+	opUnbindRequest         = classApplication | 2
+	opSearchRequest         = classApplication | constructed | 3
+	opSearchResultEntry     = classApplication | constructed | 4
+	opSearchResultDone      = classApplication | constructed | 5
+	opSearchResultReference = classApplication | constructed | 19
+	opExtendedRequest       = classApplication | constructed | 23
+	opExtendedResponse      = classApplication | constructed | 24
+
+	authSimple          = classContext | 0
+	extendedRequestName = classContext | 0
+
+	protocolVersion = 3
+	startTLSOID     = "1.3.6.1.4.1.1466.20037"
+)
+
+// Result codes, see https://tools.ietf.org/html/rfc4511#appendix-A
+const (
+	ResultSuccess            = 0
+	ResultSizeLimitExceeded  = 4
+	ResultInvalidCredentials = 49
+)
+
+// Search scopes
+const (
+	ScopeBaseObject   = 0
+	ScopeSingleLevel  = 1
+	ScopeWholeSubtree = 2
+)
+
+var (
+	// ErrUnsupportedScheme ...
+	ErrUnsupportedScheme = errors.New("LDAP URL scheme must be ldap or ldaps")
+	// ErrInvalidCredentials ...
+	ErrInvalidCredentials = errors.New("Invalid LDAP credentials")
+)
+
+// Error is a result other than success returned by the server
+type Error struct {
+	ResultCode        int64
+	DiagnosticMessage string
+}
+
+// Error returns the result code and the message of the server
+func (e *Error) Error() string {
+	if e.DiagnosticMessage == "" {
+		return fmt.Sprintf("LDAP result code %d", e.ResultCode)
+	}
+	return fmt.Sprintf("LDAP result code %d: %s", e.ResultCode, e.DiagnosticMessage)
+}
+
+// SearchRequest asks for the entries under BaseDN matching Filter,
+// Attributes lists the attributes to return, all of them when empty
+type SearchRequest struct {
+	BaseDN     string
+	Scope      int
+	Filter     string
+	Attributes []string
+	// SizeLimit and TimeLimit, in seconds, are unlimited when zero
+	SizeLimit int
+	TimeLimit int
+}
+
+// Entry is an entry found by a search
+type Entry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+// GetAttributeValue returns the first value of the attribute,
+// attribute names are case insensitive
+func (e *Entry) GetAttributeValue(name string) string {
+	for attribute, values := range e.Attributes {
+		if strings.EqualFold(attribute, name) && len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// Conn is a connection to an LDAP server, operations are sent one at a time
+type Conn struct {
+	conn      net.Conn
+	reader    *bufio.Reader
+	host      string
+	timeout   time.Duration
+	messageID int64
+}
+
+// Dial connects to the server of an ldap:// or ldaps:// URL. The timeout
+// applies to connecting and to every operation, there is none when zero.
+func Dial(rawURL string, tlsConfig *tls.Config, timeout time.Duration) (*Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+
+	var conn net.Conn
+	switch u.Scheme {
+	case "ldap":
+		conn, err = dialer.Dial("tcp", hostPort(u, "389"))
+	case "ldaps":
+		conn, err = tls.DialWithDialer(dialer, "tcp", hostPort(u, "636"), clientTLSConfig(tlsConfig, u.Hostname()))
+	default:
+		return nil, ErrUnsupportedScheme
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Conn{
+		conn:    conn,
+		reader:  bufio.NewReader(conn),
+		host:    u.Hostname(),
+		timeout: timeout,
+	}, nil
+}
+
+// StartTLS upgrades the connection to TLS
+func (c *Conn) StartTLS(tlsConfig *tls.Config) error {
+	response, err := c.request(
+		encodeConstructed(opExtendedRequest, encodeString(extendedRequestName, startTLSOID)),
+		opExtendedResponse,
+	)
+	if err != nil {
+		return err
+	}
+	if err := parseResult(response); err != nil {
+		return err
+	}
+
+	conn := tls.Client(c.conn, clientTLSConfig(tlsConfig, c.host))
+	if err := conn.Handshake(); err != nil {
+		return err
+	}
+	c.conn = conn
+	c.reader = bufio.NewReader(conn)
+	return nil
+}
+
+// Bind authenticates the connection with the password of the entry,
+// ErrInvalidCredentials is returned if the server refuses them
+func (c *Conn) Bind(dn, password string) error {
+	response, err := c.request(
+		encodeConstructed(
+			opBindRequest,
+			encodeInteger(tagInteger, protocolVersion),
+			encodeString(tagOctetString, dn),
+			encodeString(authSimple, password),
+		),
+		opBindResponse,
+	)
+	if err != nil {
+		return err
+	}
+	err = parseResult(response)
+	if ldapErr, ok := err.(*Error); ok && ldapErr.ResultCode == ResultInvalidCredentials {
+		return ErrInvalidCredentials
+	}
+	return err
+}
+
+// Search returns the entries matching the request. Referrals to other
+// servers are not followed. When the size limit is exceeded, the entries
+// returned so far are returned with an *Error.
+func (c *Conn) Search(request *SearchRequest) ([]*Entry, error) {
+	filter, err := compileFilter(request.Filter)
+	if err != nil {
+		return nil, err
+	}
+	attributes := make([][]byte, len(request.Attributes))
+	for i, attribute := range request.Attributes {
+		attributes[i] = encodeString(tagOctetString, attribute)
+	}
+
+	messageID, err := c.send(encodeConstructed(
+		opSearchRequest,
+		encodeString(tagOctetString, request.BaseDN),
+		encodeInteger(tagEnumerated, int64(request.Scope)),
+		encodeInteger(tagEnumerated, 0), // never dereference aliases
+		encodeInteger(tagInteger, int64(request.SizeLimit)),
+		encodeInteger(tagInteger, int64(request.TimeLimit)),
+		encodeBoolean(false),
+		filter,
+		encodeConstructed(tagSequence, attributes...),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	for {
+		response, err := c.receive(messageID)
+		if err != nil {
+			return nil, err
+		}
+		switch response.tag {
+		case opSearchResultEntry:
+			entry, err := parseEntry(response)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		case opSearchResultReference:
+			continue
+		case opSearchResultDone:
+			return entries, parseResult(response)
+		default:
+			return nil, ErrMalformed
+		}
+	}
+}
+
+// Close unbinds and closes the connection
+func (c *Conn) Close() error {
+	c.send(encode(opUnbindRequest, nil))
+	return c.conn.Close()
+}
+
+// request sends the operation and returns the response to it
+func (c *Conn) request(operation []byte, responseTag byte) (*element, error) {
+	messageID, err := c.send(operation)
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.receive(messageID)
+	if err != nil {
+		return nil, err
+	}
+	if response.tag != responseTag {
+		return nil, ErrMalformed
+	}
+	return response, nil
+}
+
+// send sends the operation in a new message and returns its ID
+func (c *Conn) send(operation []byte) (int64, error) {
+	if c.timeout > 0 {
+		c.conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+	c.messageID++
+	message := encodeConstructed(tagSequence, encodeInteger(tagInteger, c.messageID), operation)
+	if _, err := c.conn.Write(message); err != nil {
+		return 0, err
+	}
+	return c.messageID, nil
+}
+
+// receive returns the operation of the next message in response to the
+// message with the ID
+func (c *Conn) receive(messageID int64) (*element, error) {
+	for {
+		message, err := readElement(c.reader)
+		if err != nil {
+			return nil, err
+		}
+		if message.tag != tagSequence {
+			return nil, ErrMalformed
+		}
+		children, err := message.children()
+		if err != nil || len(children) < 2 || children[0].tag != tagInteger {
+			return nil, ErrMalformed
+		}
+		id, err := children[0].integer()
+		if err != nil {
+			return nil, err
+		}
+
+		// Unsolicited notifications, such as the server disconnecting,
+		// have a message ID of zero
+		if id == 0 {
+			if err := parseResult(children[1]); err != nil {
+				return nil, err
+			}
+			return nil, ErrMalformed
+		}
+		if id == messageID {
+			return children[1], nil
+		}
+	}
+}
+
+// parseResult returns an *Error if the result is not a success
+func parseResult(response *element) error {
+	children, err := response.children()
+	if err != nil || len(children) < 3 || children[0].tag != tagEnumerated {
+		return ErrMalformed
+	}
+	resultCode, err := children[0].integer()
+	if err != nil {
+		return err
+	}
+	if resultCode == ResultSuccess {
+		return nil
+	}
+	return &Error{ResultCode: resultCode, DiagnosticMessage: string(children[2].value)}
+}
+
+// parseEntry parses a search result entry
+func parseEntry(response *element) (*Entry, error) {
+	children, err := response.children()
+	if err != nil || len(children) != 2 {
+		return nil, ErrMalformed
+	}
+	attributes, err := children[1].children()
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	entry := &Entry{
+		DN:         string(children[0].value),
+		Attributes: make(map[string][]string, len(attributes)),
+	}
+	for _, attribute := range attributes {
+		parts, err := attribute.children()
+		if err != nil || len(parts) != 2 {
+			return nil, ErrMalformed
+		}
+		values, err := parts[1].children()
+		if err != nil {
+			return nil, ErrMalformed
+		}
+		name := string(parts[0].value)
+		for _, value := range values {
+			entry.Attributes[name] = append(entry.Attributes[name], string(value.value))
+		}
+	}
+	return entry, nil
+}
+
+// hostPort returns the host and port of the URL, or the default port
+func hostPort(u *url.URL, defaultPort string) string {
+	port := u.Port()
+	if port == "" {
+		port = defaultPort
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// clientTLSConfig returns a copy of the TLS configuration which verifies
+// the certificate of the host unless another server name is set
+func clientTLSConfig(tlsConfig *tls.Config, host string) *tls.Config {
+	if tlsConfig == nil {
+		tlsConfig = new(tls.Config)
+	} else {
+		tlsConfig = tlsConfig.Clone()
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = host
+	}
+	return tlsConfig
+}
@@ -0,0 +1,223 @@
+package ldap
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// Filter choices, see https://tools.ietf.org/html/rfc4511#section-4.5.1
+const (
+	filterAnd            = classContext | constructed | 0
+	filterOr             = classContext | constructed | 1
+	filterNot            = classContext | constructed | 2
+	filterEqualityMatch  = classContext | constructed | 3
+	filterSubstrings     = classContext | constructed | 4
+	filterGreaterOrEqual = classContext | constructed | 5
+	filterLessOrEqual    = classContext | constructed | 6
+	filterPresent        = classContext | 7
+	filterApproxMatch    = classContext | constructed | 8
+
+	substringInitial = classContext | 0
+	substringAny     = classContext | 1
+	substringFinal   = classContext | 2
+
+	// maxFilterDepth limits the nesting of filters
+	maxFilterDepth = 16
+)
+
+var (
+	// ErrInvalidFilter ...
+	ErrInvalidFilter = errors.New("Invalid LDAP search filter")
+
+	// filterEscapes replaces the characters EscapeFilter escapes
+	filterEscapes = strings.NewReplacer(
+		`\`, `\5c`,
+		"*", `\2a`,
+		"(", `\28`,
+		")", `\29`,
+		"\x00", `\00`,
+	)
+)
+
+// EscapeFilter escapes a value to be used in a search filter as is,
+// see https://tools.ietf.org/html/rfc4515#section-3
+func EscapeFilter(value string) string {
+	return filterEscapes.Replace(value)
+}
+
+// compileFilter returns the BER encoding of a search filter in its string
+// representation, e.g. (&(objectClass=person)(uid=jdoe)). Extensible
+// matches are not supported.
+func compileFilter(filter string) ([]byte, error) {
+	compiled, rest, err := parseFilter(filter, 0)
+	if err != nil {
+		return nil, err
+	}
+	if rest != "" {
+		return nil, ErrInvalidFilter
+	}
+	return compiled, nil
+}
+
+// parseFilter compiles the filter at the start of s and returns what follows
+func parseFilter(s string, depth int) ([]byte, string, error) {
+	if depth > maxFilterDepth || len(s) < 3 || s[0] != '(' {
+		return nil, "", ErrInvalidFilter
+	}
+
+	switch s[1] {
+	case '&', '|':
+		tag := byte(filterAnd)
+		if s[1] == '|' {
+			tag = filterOr
+		}
+		var filters [][]byte
+		rest := s[2:]
+		for rest != "" && rest[0] == '(' {
+			compiled, after, err := parseFilter(rest, depth+1)
+			if err != nil {
+				return nil, "", err
+			}
+			filters = append(filters, compiled)
+			rest = after
+		}
+		if len(filters) == 0 || rest == "" || rest[0] != ')' {
+			return nil, "", ErrInvalidFilter
+		}
+		return encodeConstructed(tag, filters...), rest[1:], nil
+	case '!':
+		compiled, rest, err := parseFilter(s[2:], depth+1)
+		if err != nil {
+			return nil, "", err
+		}
+		if rest == "" || rest[0] != ')' {
+			return nil, "", ErrInvalidFilter
+		}
+		return encodeConstructed(filterNot, compiled), rest[1:], nil
+	}
+
+	// Values cannot contain unescaped parentheses
+	end := strings.IndexByte(s, ')')
+	if end < 0 {
+		return nil, "", ErrInvalidFilter
+	}
+	compiled, err := parseItem(s[1:end])
+	if err != nil {
+		return nil, "", err
+	}
+	return compiled, s[end+1:], nil
+}
+
+// parseItem compiles a comparison such as uid=jdoe
+func parseItem(item string) ([]byte, error) {
+	i := strings.IndexByte(item, '=')
+	if i < 1 {
+		return nil, ErrInvalidFilter
+	}
+	attribute, value := item[:i], item[i+1:]
+
+	tag := byte(filterEqualityMatch)
+	switch attribute[len(attribute)-1] {
+	case '~':
+		tag = filterApproxMatch
+	case '>':
+		tag = filterGreaterOrEqual
+	case '<':
+		tag = filterLessOrEqual
+	}
+	if tag != filterEqualityMatch {
+		attribute = attribute[:len(attribute)-1]
+	}
+	if !validAttribute(attribute) {
+		return nil, ErrInvalidFilter
+	}
+
+	if tag == filterEqualityMatch && value == "*" {
+		return encodeString(filterPresent, attribute), nil
+	}
+	if tag == filterEqualityMatch && strings.Contains(value, "*") {
+		return parseSubstrings(attribute, value)
+	}
+
+	unescaped, err := unescapeFilter(value)
+	if err != nil {
+		return nil, err
+	}
+	return encodeConstructed(
+		tag,
+		encodeString(tagOctetString, attribute),
+		encodeString(tagOctetString, unescaped),
+	), nil
+}
+
+// parseSubstrings compiles a substring match such as cn=J*Doe
+func parseSubstrings(attribute, value string) ([]byte, error) {
+	parts := strings.Split(value, "*")
+	var substrings [][]byte
+	for i, part := range parts {
+		if part == "" {
+			continue
+		}
+		unescaped, err := unescapeFilter(part)
+		if err != nil {
+			return nil, err
+		}
+		tag := byte(substringAny)
+		switch i {
+		case 0:
+			tag = substringInitial
+		case len(parts) - 1:
+			tag = substringFinal
+		}
+		substrings = append(substrings, encodeString(tag, unescaped))
+	}
+	if len(substrings) == 0 {
+		return nil, ErrInvalidFilter
+	}
+	return encodeConstructed(
+		filterSubstrings,
+		encodeString(tagOctetString, attribute),
+		encodeConstructed(tagSequence, substrings...),
+	), nil
+}
+
+// unescapeFilter replaces the \XX escapes of a value
+func unescapeFilter(value string) (string, error) {
+	if !strings.Contains(value, `\`) {
+		return value, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' {
+			b.WriteByte(value[i])
+			continue
+		}
+		if i+3 > len(value) {
+			return "", ErrInvalidFilter
+		}
+		decoded, err := hex.DecodeString(value[i+1 : i+3])
+		if err != nil {
+			return "", ErrInvalidFilter
+		}
+		b.Write(decoded)
+		i += 2
+	}
+	return b.String(), nil
+}
+
+// validAttribute returns true if the attribute description is made of
+// the characters descriptors, OIDs and options are allowed
+func validAttribute(attribute string) bool {
+	if attribute == "" {
+		return false
+	}
+	for _, c := range attribute {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '.', c == ';':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,304 @@
+package ldap
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testServer is a directory of users found by uid, it speaks just
+// enough LDAP to test the client with
+type testServer struct {
+	listener  net.Listener
+	tlsConfig *tls.Config
+	passwords map[string]string
+	entries   []*Entry
+
+	mu      sync.Mutex
+	filters []string
+}
+
+func newTestServer(t *testing.T, tlsConfig *tls.Config) *testServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testServer{
+		listener:  listener,
+		tlsConfig: tlsConfig,
+		passwords: map[string]string{
+			"cn=service,dc=example,dc=com": "service_password",
+			"uid=jdoe,dc=example,dc=com":   "test_password",
+		},
+		entries: []*Entry{
+			{
+				DN:         "uid=jdoe,dc=example,dc=com",
+				Attributes: map[string][]string{"uid": {"jdoe"}, "mail": {"jdoe@example.com"}},
+			},
+			{
+				DN:         "uid=twin,ou=a,dc=example,dc=com",
+				Attributes: map[string][]string{"uid": {"twin"}},
+			},
+			{
+				DN:         "uid=twin,ou=b,dc=example,dc=com",
+				Attributes: map[string][]string{"uid": {"twin"}},
+			},
+		},
+	}
+	go s.serve()
+	return s
+}
+
+func (s *testServer) URL() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+func (s *testServer) Close() {
+	s.listener.Close()
+}
+
+func (s *testServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *testServer) handle(conn net.Conn) {
+	defer func() { conn.Close() }() // conn is replaced by StartTLS
+	for {
+		message, err := readElement(conn)
+		if err != nil {
+			return
+		}
+		children, _ := message.children()
+		id, _ := children[0].integer()
+		operation := children[1]
+		fields, _ := operation.children()
+
+		reply := func(responses ...[]byte) {
+			for _, response := range responses {
+				conn.Write(encodeConstructed(tagSequence, encodeInteger(tagInteger, id), response))
+			}
+		}
+
+		switch operation.tag {
+		case opBindRequest:
+			password, ok := s.passwords[string(fields[1].value)]
+			if !ok || password != string(fields[2].value) {
+				reply(result(opBindResponse, ResultInvalidCredentials))
+				continue
+			}
+			reply(result(opBindResponse, ResultSuccess))
+		case opExtendedRequest:
+			reply(result(opExtendedResponse, ResultSuccess))
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+		case opSearchRequest:
+			s.mu.Lock()
+			s.filters = append(s.filters, fmt.Sprintf("%x", fields[6].value))
+			s.mu.Unlock()
+			sizeLimit, _ := fields[3].integer()
+			var matches [][]byte
+			for _, entry := range s.entries {
+				compiled, _ := compileFilter("(uid=" + EscapeFilter(entry.Attributes["uid"][0]) + ")")
+				if bytes.Equal(compiled, encode(fields[6].tag, fields[6].value)) {
+					matches = append(matches, encodeEntry(entry))
+				}
+			}
+			if sizeLimit > 0 && int64(len(matches)) > sizeLimit {
+				reply(append(matches[:sizeLimit], result(opSearchResultDone, ResultSizeLimitExceeded))...)
+				continue
+			}
+			reply(append(matches, result(opSearchResultDone, ResultSuccess))...)
+		case opUnbindRequest:
+			return
+		}
+	}
+}
+
+func result(tag byte, resultCode int64) []byte {
+	return encodeConstructed(
+		tag,
+		encodeInteger(tagEnumerated, resultCode),
+		encodeString(tagOctetString, ""),
+		encodeString(tagOctetString, ""),
+	)
+}
+
+func encodeEntry(entry *Entry) []byte {
+	var attributes [][]byte
+	for name, values := range entry.Attributes {
+		var encodedValues [][]byte
+		for _, value := range values {
+			encodedValues = append(encodedValues, encodeString(tagOctetString, value))
+		}
+		attributes = append(attributes, encodeConstructed(
+			tagSequence,
+			encodeString(tagOctetString, name),
+			encodeConstructed(tagSet, encodedValues...),
+		))
+	}
+	return encodeConstructed(
+		opSearchResultEntry,
+		encodeString(tagOctetString, entry.DN),
+		encodeConstructed(tagSequence, attributes...),
+	)
+}
+
+func TestAuthenticate(t *testing.T) {
+	server := newTestServer(t, nil)
+	defer server.Close()
+
+	authenticator := &Authenticator{
+		URL:          server.URL(),
+		BindDN:       "cn=service,dc=example,dc=com",
+		BindPassword: "service_password",
+		BaseDN:       "dc=example,dc=com",
+		UserFilter:   "(uid=%s)",
+		Timeout:      5 * time.Second,
+	}
+
+	entry, err := authenticator.Authenticate("jdoe", "test_password")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "uid=jdoe,dc=example,dc=com", entry.DN)
+		assert.Equal(t, "jdoe@example.com", entry.GetAttributeValue("MAIL"))
+		assert.Equal(t, "", entry.GetAttributeValue("cn"))
+	}
+
+	_, err = authenticator.Authenticate("jdoe", "bogus")
+	assert.Equal(t, ErrInvalidCredentials, err)
+
+	_, err = authenticator.Authenticate("bogus", "test_password")
+	assert.Equal(t, ErrUserNotFound, err)
+
+	_, err = authenticator.Authenticate("twin", "test_password")
+	assert.Equal(t, ErrMultipleUsers, err)
+
+	// Usernames cannot change the filter
+	_, err = authenticator.Authenticate("*", "test_password")
+	assert.Equal(t, ErrUserNotFound, err)
+	escaped, _ := compileFilter(`(uid=\2a)`)
+	server.mu.Lock()
+	assert.Equal(t, fmt.Sprintf("%x", escaped[2:]), server.filters[len(server.filters)-1])
+	server.mu.Unlock()
+
+	// A bind without a password would be anonymous and succeed
+	_, err = authenticator.Authenticate("jdoe", "")
+	assert.Equal(t, ErrInvalidCredentials, err)
+
+	// Wrong service account credentials are not a wrong user password
+	authenticator.BindPassword = "bogus"
+	_, err = authenticator.Authenticate("jdoe", "test_password")
+	if assert.Error(t, err) {
+		assert.NotEqual(t, ErrInvalidCredentials, err)
+		assert.True(t, strings.HasPrefix(err.Error(), "Binding as cn=service,dc=example,dc=com failed"))
+	}
+
+	authenticator.URL = "http://" + server.listener.Addr().String()
+	_, err = authenticator.Authenticate("jdoe", "test_password")
+	assert.Equal(t, ErrUnsupportedScheme, err)
+}
+
+func TestStartTLS(t *testing.T) {
+	// Borrow the certificate of an httptest server, valid for 127.0.0.1
+	tlsServer := httptest.NewTLSServer(nil)
+	defer tlsServer.Close()
+
+	server := newTestServer(t, tlsServer.TLS)
+	defer server.Close()
+
+	authenticator := &Authenticator{
+		URL:        server.URL(),
+		StartTLS:   true,
+		TLSConfig:  tlsServer.Client().Transport.(*http.Transport).TLSClientConfig,
+		BaseDN:     "dc=example,dc=com",
+		UserFilter: "(uid=%s)",
+		Timeout:    5 * time.Second,
+	}
+	_, err := authenticator.Authenticate("jdoe", "test_password")
+	assert.NoError(t, err)
+
+	// The certificate is verified
+	authenticator.TLSConfig = nil
+	_, err = authenticator.Authenticate("jdoe", "test_password")
+	assert.Error(t, err)
+}
+
+func TestCompileFilter(t *testing.T) {
+	for _, filter := range []string{
+		"(uid=jdoe)",
+		"(&(objectClass=person)(|(uid=jdoe)(mail=jdoe@example.com)))",
+		"(!(userAccountControl=514))",
+		"(cn=J*D*e)",
+		"(cn=*Doe)",
+		"(mail=*)",
+		"(uidNumber>=1000)",
+		"(uidNumber<=2000)",
+		"(cn~=jon)",
+		`(cn=\28parens\29)`,
+	} {
+		_, err := compileFilter(filter)
+		assert.NoError(t, err, filter)
+	}
+
+	for _, filter := range []string{
+		"",
+		"uid=jdoe",
+		"(uid=jdoe",
+		"(uid=jdoe))",
+		"(=jdoe)",
+		"(&)",
+		"(!(uid=jdoe)(uid=jsmith))",
+		`(uid=\2)`,
+		`(uid=\zz)`,
+		"(cn=**)",
+		"(u id=jdoe)",
+		"(memberOf:1.2.840.113556.1.4.1941:=cn=admins)",
+	} {
+		_, err := compileFilter(filter)
+		assert.Equal(t, ErrInvalidFilter, err, filter)
+	}
+
+	compiled, err := compileFilter("(uid=jdoe)")
+	assert.NoError(t, err)
+	assert.Equal(t, "a30b040375696404046a646f65", fmt.Sprintf("%x", compiled))
+}
+
+func TestEscapeFilter(t *testing.T) {
+	assert.Equal(t, `\2a\29\28uid=\5c\00`, EscapeFilter("*)(uid=\\\x00"))
+	assert.Equal(t, "jdoe", EscapeFilter("jdoe"))
+}
+
+func TestEncodeLongValues(t *testing.T) {
+	value := bytes.Repeat([]byte{'a'}, 300)
+	parsed, rest, err := parseElement(encode(tagOctetString, value))
+	if assert.NoError(t, err) {
+		assert.Equal(t, value, parsed.value)
+		assert.Empty(t, rest)
+	}
+
+	for _, n := range []int64{0, 1, 127, 128, -1, -129, 1 << 40} {
+		parsed, _, err := parseElement(encodeInteger(tagInteger, n))
+		if assert.NoError(t, err) {
+			decoded, err := parsed.integer()
+			assert.NoError(t, err)
+			assert.Equal(t, n, decoded)
+		}
+	}
+}
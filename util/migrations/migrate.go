@@ -46,6 +46,32 @@ func MigrateAll(db *gorm.DB, migrationFunctions []func(*gorm.DB) error) {
 	}
 }
 
+// Pending returns names of migrations which have not been run yet
+func Pending(db *gorm.DB, migrations []MigrationStage) ([]string, error) {
+	var pending []string
+	if !db.HasTable(new(Migration)) {
+		for _, m := range migrations {
+			pending = append(pending, m.Name)
+		}
+		return pending, nil
+	}
+
+	var names []string
+	if err := db.Model(new(Migration)).Pluck("name", &names).Error; err != nil {
+		return nil, err
+	}
+	done := make(map[string]bool, len(names))
+	for _, name := range names {
+		done[name] = true
+	}
+	for _, m := range migrations {
+		if !done[m.Name] {
+			pending = append(pending, m.Name)
+		}
+	}
+	return pending, nil
+}
+
 // MigrationExists checks if the migration called migrationName has been run already
 func MigrationExists(db *gorm.DB, migrationName string) bool {
 	migration := new(Migration)
@@ -0,0 +1,304 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// Bcrypt is the name of the bcrypt algorithm
+	Bcrypt = "bcrypt"
+	// Argon2id is the name of the Argon2id algorithm
+	Argon2id = "argon2id"
+	// Scrypt is the name of the scrypt algorithm
+	Scrypt = "scrypt"
+
+	saltLength = 16
+	keyLength  = 32
+)
+
+var (
+	// ErrMismatchedHashAndPassword ...
+	ErrMismatchedHashAndPassword = bcrypt.ErrMismatchedHashAndPassword
+	// ErrMalformedHash ...
+	ErrMalformedHash = errors.New("Malformed password hash")
+	// ErrUnknownAlgorithm ...
+	ErrUnknownAlgorithm = errors.New("Unknown password hashing algorithm")
+)
+
+// Hasher hashes passwords into self describing strings, which embed the
+// algorithm, its cost parameters and the salt, so hashes made with other
+// algorithms or parameters can still be verified by VerifyPassword
+type Hasher interface {
+	// Hash returns the encoded hash of the password
+	Hash(password string) (string, error)
+	// NeedsRehash returns true if the hash was not made by this hasher
+	// with its current parameters, and should be replaced after the
+	// password has been verified
+	NeedsRehash(passwordHash string) bool
+}
+
+// BcryptHasher hashes passwords with bcrypt
+type BcryptHasher struct {
+	// Cost defaults to bcrypt.DefaultCost
+	Cost int
+}
+
+// Hash returns the bcrypt hash of the password
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost())
+	if err != nil {
+		return "", err
+	}
+	return string(passwordHash), nil
+}
+
+// NeedsRehash returns true if the hash is not a bcrypt hash of the cost
+func (h *BcryptHasher) NeedsRehash(passwordHash string) bool {
+	cost, err := bcrypt.Cost([]byte(passwordHash))
+	return err != nil || cost != h.cost()
+}
+
+func (h *BcryptHasher) cost() int {
+	if h.Cost < bcrypt.MinCost {
+		return bcrypt.DefaultCost
+	}
+	return h.Cost
+}
+
+// Argon2idHasher hashes passwords with Argon2id, the parameters default
+// to those recommended by RFC 9106 for memory constrained environments
+type Argon2idHasher struct {
+	// Memory is in KiB, 65536 (64 MiB) by default
+	Memory uint32
+	// Iterations defaults to 3
+	Iterations uint32
+	// Parallelism defaults to 4
+	Parallelism uint8
+}
+
+// Hash returns the Argon2id hash of the password in the PHC string format,
+// e.g. $argon2id$v=19$m=65536,t=3,p=4$<salt>$<key>
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt, err := newSalt()
+	if err != nil {
+		return "", err
+	}
+	params := h.params()
+	key := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, keyLength)
+	return encodeHash(Argon2id, params.encode(), salt, key), nil
+}
+
+// NeedsRehash returns true if the hash is not an Argon2id hash of the parameters
+func (h *Argon2idHasher) NeedsRehash(passwordHash string) bool {
+	params, _, _, err := decodeArgon2id(passwordHash)
+	return err != nil || *params != *h.params()
+}
+
+func (h *Argon2idHasher) params() *Argon2idHasher {
+	params := *h
+	if params.Memory == 0 {
+		params.Memory = 64 * 1024
+	}
+	if params.Iterations == 0 {
+		params.Iterations = 3
+	}
+	if params.Parallelism == 0 {
+		params.Parallelism = 4
+	}
+	return &params
+}
+
+func (h *Argon2idHasher) encode() string {
+	return fmt.Sprintf("v=%d$m=%d,t=%d,p=%d", argon2.Version, h.Memory, h.Iterations, h.Parallelism)
+}
+
+// ScryptHasher hashes passwords with scrypt
+type ScryptHasher struct {
+	// N is the CPU and memory cost, a power of two, 32768 by default
+	N int
+	// R is the block size, 8 by default
+	R int
+	// P is the parallelism, 1 by default
+	P int
+}
+
+// Hash returns the scrypt hash of the password in the PHC string format,
+// e.g. $scrypt$ln=15,r=8,p=1$<salt>$<key> where ln is log2 of N
+func (h *ScryptHasher) Hash(password string) (string, error) {
+	params := h.params()
+	if params.N < 2 || params.N&(params.N-1) != 0 {
+		return "", fmt.Errorf("scrypt N must be a power of two, got %d", params.N)
+	}
+	salt, err := newSalt()
+	if err != nil {
+		return "", err
+	}
+	key, err := scrypt.Key([]byte(password), salt, params.N, params.R, params.P, keyLength)
+	if err != nil {
+		return "", err
+	}
+	return encodeHash(Scrypt, params.encode(), salt, key), nil
+}
+
+// NeedsRehash returns true if the hash is not a scrypt hash of the parameters
+func (h *ScryptHasher) NeedsRehash(passwordHash string) bool {
+	params, _, _, err := decodeScrypt(passwordHash)
+	return err != nil || *params != *h.params()
+}
+
+func (h *ScryptHasher) params() *ScryptHasher {
+	params := *h
+	if params.N == 0 {
+		params.N = 32768
+	}
+	if params.R == 0 {
+		params.R = 8
+	}
+	if params.P == 0 {
+		params.P = 1
+	}
+	return &params
+}
+
+func (h *ScryptHasher) encode() string {
+	var ln int
+	for n := h.N; n > 1; n >>= 1 {
+		ln++
+	}
+	return fmt.Sprintf("ln=%d,r=%d,p=%d", ln, h.R, h.P)
+}
+
+// verifyHash verifies the password against a hash of any supported algorithm
+func verifyHash(passwordHash, password string) error {
+	var (
+		key, expected []byte
+		err           error
+	)
+	switch {
+	case strings.HasPrefix(passwordHash, "$"+Argon2id+"$"):
+		var (
+			params *Argon2idHasher
+			salt   []byte
+		)
+		params, salt, expected, err = decodeArgon2id(passwordHash)
+		if err != nil {
+			return err
+		}
+		key = argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(expected)))
+	case strings.HasPrefix(passwordHash, "$"+Scrypt+"$"):
+		var (
+			params *ScryptHasher
+			salt   []byte
+		)
+		params, salt, expected, err = decodeScrypt(passwordHash)
+		if err != nil {
+			return err
+		}
+		key, err = scrypt.Key([]byte(password), salt, params.N, params.R, params.P, len(expected))
+		if err != nil {
+			return err
+		}
+	default:
+		return bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password))
+	}
+
+	if subtle.ConstantTimeCompare(key, expected) != 1 {
+		return ErrMismatchedHashAndPassword
+	}
+	return nil
+}
+
+// isUsableHash returns true if the hash can be decoded
+func isUsableHash(passwordHash string) bool {
+	var err error
+	switch {
+	case strings.HasPrefix(passwordHash, "$"+Argon2id+"$"):
+		_, _, _, err = decodeArgon2id(passwordHash)
+	case strings.HasPrefix(passwordHash, "$"+Scrypt+"$"):
+		_, _, _, err = decodeScrypt(passwordHash)
+	default:
+		_, err = bcrypt.Cost([]byte(passwordHash))
+	}
+	return err == nil
+}
+
+func decodeArgon2id(passwordHash string) (*Argon2idHasher, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=65536,t=3,p=4", salt, key
+	parts := strings.Split(passwordHash, "$")
+	if len(parts) != 6 || parts[1] != Argon2id {
+		return nil, nil, nil, ErrMalformedHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, nil, nil, ErrMalformedHash
+	}
+	params := new(Argon2idHasher)
+	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism)
+	if err != nil || params.Memory == 0 || params.Iterations == 0 || params.Parallelism == 0 {
+		return nil, nil, nil, ErrMalformedHash
+	}
+	salt, key, err := decodeSaltAndKey(parts[4], parts[5])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return params, salt, key, nil
+}
+
+func decodeScrypt(passwordHash string) (*ScryptHasher, []byte, []byte, error) {
+	// "", "scrypt", "ln=15,r=8,p=1", salt, key
+	parts := strings.Split(passwordHash, "$")
+	if len(parts) != 5 || parts[1] != Scrypt {
+		return nil, nil, nil, ErrMalformedHash
+	}
+	var ln uint
+	params := new(ScryptHasher)
+	_, err := fmt.Sscanf(parts[2], "ln=%d,r=%d,p=%d", &ln, &params.R, &params.P)
+	if err != nil || ln < 1 || ln > 30 || params.R <= 0 || params.P <= 0 {
+		return nil, nil, nil, ErrMalformedHash
+	}
+	params.N = 1 << ln
+	salt, key, err := decodeSaltAndKey(parts[3], parts[4])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return params, salt, key, nil
+}
+
+func decodeSaltAndKey(encodedSalt, encodedKey string) ([]byte, []byte, error) {
+	salt, err := base64.RawStdEncoding.DecodeString(encodedSalt)
+	if err != nil || len(salt) == 0 {
+		return nil, nil, ErrMalformedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(encodedKey)
+	if err != nil || len(key) == 0 {
+		return nil, nil, ErrMalformedHash
+	}
+	return salt, key, nil
+}
+
+func encodeHash(algorithm, params string, salt, key []byte) string {
+	return fmt.Sprintf(
+		"$%s$%s$%s$%s",
+		algorithm,
+		params,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+}
+
+func newSalt() ([]byte, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
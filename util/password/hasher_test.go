@@ -0,0 +1,88 @@
+package password_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/RichardKnop/go-oauth2-server/util/password"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashers(t *testing.T) {
+	hashers := map[string]password.Hasher{
+		"$2a$04$":                      &password.BcryptHasher{Cost: 4},
+		"$argon2id$v=19$m=64,t=1,p=1$": &password.Argon2idHasher{Memory: 64, Iterations: 1, Parallelism: 1},
+		"$scrypt$ln=4,r=8,p=1$":        &password.ScryptHasher{N: 16},
+	}
+
+	for prefix, hasher := range hashers {
+		passwordHash, err := hasher.Hash("test_password")
+		if !assert.NoError(t, err, prefix) {
+			continue
+		}
+		assert.True(t, strings.HasPrefix(passwordHash, prefix), passwordHash)
+		assert.True(t, password.IsUsableHash(passwordHash), prefix)
+
+		// Any hash verifies whatever the hasher in use
+		assert.NoError(t, password.VerifyPassword(passwordHash, "test_password"), prefix)
+		assert.Equal(
+			t,
+			password.ErrMismatchedHashAndPassword,
+			password.VerifyPassword(passwordHash, "bogus"),
+			prefix,
+		)
+
+		// Salts are random
+		otherHash, err := hasher.Hash("test_password")
+		assert.NoError(t, err, prefix)
+		assert.NotEqual(t, passwordHash, otherHash, prefix)
+
+		// Hashes of other hashers need rehashing, hashes of this one do not
+		assert.False(t, hasher.NeedsRehash(passwordHash), prefix)
+		for otherPrefix, other := range hashers {
+			if otherPrefix != prefix {
+				assert.True(t, other.NeedsRehash(passwordHash), otherPrefix)
+			}
+		}
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	argon2idHash, err := (&password.Argon2idHasher{Memory: 64, Iterations: 1, Parallelism: 1}).Hash("test_password")
+	assert.NoError(t, err)
+	assert.True(t, (&password.Argon2idHasher{Memory: 64, Iterations: 2, Parallelism: 1}).NeedsRehash(argon2idHash))
+
+	scryptHash, err := (&password.ScryptHasher{N: 16}).Hash("test_password")
+	assert.NoError(t, err)
+	assert.True(t, (&password.ScryptHasher{N: 32}).NeedsRehash(scryptHash))
+
+	// Zero parameters are the defaults
+	assert.False(t, new(password.BcryptHasher).NeedsRehash(
+		"$2a$10$4J4t9xuWhOKhfjN0bOKNReS9sL3BVSN9zxIr2.VaWWQfRBWh1dQIS",
+	))
+}
+
+func TestScryptHasherRejectsInvalidN(t *testing.T) {
+	_, err := (&password.ScryptHasher{N: 1000}).Hash("test_password")
+	assert.Error(t, err)
+}
+
+func TestMalformedHashes(t *testing.T) {
+	for _, passwordHash := range []string{
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdA",
+		"$argon2id$v=16$m=64,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=0,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=64,t=1,p=1$!$a2V5",
+		"$scrypt$ln=4,r=8,p=1$c2FsdA",
+		"$scrypt$ln=0,r=8,p=1$c2FsdA$a2V5",
+		"$scrypt$ln=4,r=8,p=1$c2FsdA$",
+	} {
+		assert.False(t, password.IsUsableHash(passwordHash), passwordHash)
+		assert.Equal(
+			t,
+			password.ErrMalformedHash,
+			password.VerifyPassword(passwordHash, "test_password"),
+			passwordHash,
+		)
+	}
+}
@@ -4,9 +4,16 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-// VerifyPassword compares password and the hashed password
+// VerifyPassword compares password and the hashed password, the hash can
+// be of any algorithm a Hasher supports
 func VerifyPassword(passwordHash, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password))
+	return verifyHash(passwordHash, password)
+}
+
+// IsUsableHash returns false if the hash cannot be used to verify a password,
+// e.g. an empty or placeholder value stored for accounts without a password
+func IsUsableHash(passwordHash string) bool {
+	return isUsableHash(passwordHash)
 }
 
 // HashPassword creates a bcrypt password hash
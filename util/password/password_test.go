@@ -22,3 +22,11 @@ func TestVerifyPassword(t *testing.T) {
 	// Test invalid password
 	assert.NotNil(t, password.VerifyPassword("bogus", "password"))
 }
+
+func TestIsUsableHash(t *testing.T) {
+	assert.True(t, password.IsUsableHash(
+		"$2a$10$4J4t9xuWhOKhfjN0bOKNReS9sL3BVSN9zxIr2.VaWWQfRBWh1dQIS",
+	))
+	assert.False(t, password.IsUsableHash(""))
+	assert.False(t, password.IsUsableHash("!"))
+}
@@ -0,0 +1,128 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Codes of the rules a password can break
+const (
+	ViolationTooShort         = "too_short"
+	ViolationMissingUppercase = "missing_uppercase"
+	ViolationMissingLowercase = "missing_lowercase"
+	ViolationMissingDigit     = "missing_digit"
+	ViolationMissingSymbol    = "missing_symbol"
+	ViolationBanned           = "banned"
+	ViolationBreached         = "breached"
+)
+
+// Policy is the rules new passwords must follow
+type Policy struct {
+	MinLength        int
+	RequireUppercase bool
+	RequireLowercase bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	// BannedPasswords are refused whatever their case
+	BannedPasswords []string
+	// Breached returns true if the password is known to have leaked,
+	// see PwnedPasswords. Leave nil to skip the lookup.
+	Breached func(password string) (bool, error)
+}
+
+// PolicyError lists the rules a password breaks
+type PolicyError struct {
+	MinLength  int
+	Violations []string
+}
+
+// Error describes the rules in a sentence users can be shown
+func (e *PolicyError) Error() string {
+	requirements := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		switch violation {
+		case ViolationTooShort:
+			requirements[i] = fmt.Sprintf("be at least %d characters long", e.MinLength)
+		case ViolationMissingUppercase:
+			requirements[i] = "contain an uppercase letter"
+		case ViolationMissingLowercase:
+			requirements[i] = "contain a lowercase letter"
+		case ViolationMissingDigit:
+			requirements[i] = "contain a digit"
+		case ViolationMissingSymbol:
+			requirements[i] = "contain a symbol"
+		case ViolationBanned:
+			requirements[i] = "not be a commonly used password"
+		case ViolationBreached:
+			requirements[i] = "not appear in a known data breach"
+		default:
+			requirements[i] = "not break the " + violation + " rule"
+		}
+	}
+
+	last := len(requirements) - 1
+	if last < 1 {
+		return "Password must " + strings.Join(requirements, "")
+	}
+	return "Password must " + strings.Join(requirements[:last], ", ") + " and " + requirements[last]
+}
+
+// Check returns a *PolicyError listing the rules the password breaks, or
+// the error of the breach lookup, which is skipped if other rules are broken
+func (p *Policy) Check(password string) error {
+	var violations []string
+	if utf8.RuneCountInString(password) < p.MinLength {
+		violations = append(violations, ViolationTooShort)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsLetter(r):
+			symbol = true
+		}
+	}
+	if p.RequireUppercase && !upper {
+		violations = append(violations, ViolationMissingUppercase)
+	}
+	if p.RequireLowercase && !lower {
+		violations = append(violations, ViolationMissingLowercase)
+	}
+	if p.RequireDigit && !digit {
+		violations = append(violations, ViolationMissingDigit)
+	}
+	if p.RequireSymbol && !symbol {
+		violations = append(violations, ViolationMissingSymbol)
+	}
+
+	for _, banned := range p.BannedPasswords {
+		if strings.EqualFold(password, banned) {
+			violations = append(violations, ViolationBanned)
+			break
+		}
+	}
+
+	// Only look up passwords that are acceptable otherwise
+	if len(violations) == 0 && p.Breached != nil {
+		breached, err := p.Breached(password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			violations = append(violations, ViolationBreached)
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{MinLength: p.MinLength, Violations: violations}
+	}
+	return nil
+}
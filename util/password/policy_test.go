@@ -0,0 +1,90 @@
+package password_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RichardKnop/go-oauth2-server/util/password"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	policy := &password.Policy{
+		MinLength:        8,
+		RequireUppercase: true,
+		RequireLowercase: true,
+		RequireDigit:     true,
+		RequireSymbol:    true,
+		BannedPasswords:  []string{"Passw0rd!"},
+	}
+
+	assert.NoError(t, policy.Check("Corr3ct horse"))
+
+	err := policy.Check("horse")
+	if assert.IsType(t, new(password.PolicyError), err) {
+		assert.Equal(t, []string{
+			password.ViolationTooShort,
+			password.ViolationMissingUppercase,
+			password.ViolationMissingDigit,
+			password.ViolationMissingSymbol,
+		}, err.(*password.PolicyError).Violations)
+		assert.Equal(t, "Password must be at least 8 characters long, contain an uppercase letter, "+
+			"contain a digit and contain a symbol", err.Error())
+	}
+
+	// Banned passwords are refused whatever their case
+	err = policy.Check("pASSW0RD!")
+	if assert.IsType(t, new(password.PolicyError), err) {
+		assert.Equal(t, []string{password.ViolationBanned}, err.(*password.PolicyError).Violations)
+		assert.Equal(t, "Password must not be a commonly used password", err.Error())
+	}
+
+	// Length is counted in characters
+	policy = &password.Policy{MinLength: 4}
+	assert.NoError(t, policy.Check("ąęść"))
+}
+
+func TestPolicyBreached(t *testing.T) {
+	var lookups int
+	policy := &password.Policy{
+		MinLength: 6,
+		Breached: func(p string) (bool, error) {
+			lookups++
+			return p == "password", nil
+		},
+	}
+
+	err := policy.Check("password")
+	if assert.IsType(t, new(password.PolicyError), err) {
+		assert.Equal(t, []string{password.ViolationBreached}, err.(*password.PolicyError).Violations)
+	}
+	assert.NoError(t, policy.Check("correct horse"))
+
+	// Passwords breaking other rules are not looked up
+	assert.Error(t, policy.Check("pass"))
+	assert.Equal(t, 2, lookups)
+}
+
+func TestPwnedPasswords(t *testing.T) {
+	// SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "true", r.Header.Get("Add-Padding"))
+		if r.URL.Path == "/range/5BAA6" {
+			fmt.Fprint(w, "003D68EB55068C33ACE09247EE4C639306B:3\r\n"+
+				"1E4C9B93F3F0682250B6CF8331B7EE68FD8:3730471\r\n")
+		}
+		fmt.Fprint(w, "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF:0\r\n")
+	}))
+	defer server.Close()
+	pwned := password.NewPwnedPasswords(server.URL + "/range/")
+
+	breached, err := pwned.Breached("password")
+	assert.NoError(t, err)
+	assert.True(t, breached)
+
+	breached, err = pwned.Breached("correct horse battery staple")
+	assert.NoError(t, err)
+	assert.False(t, breached)
+}
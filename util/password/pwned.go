@@ -0,0 +1,64 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultPwnedPasswordsURL is the range endpoint of the Pwned Passwords API
+const DefaultPwnedPasswordsURL = "https://api.pwnedpasswords.com/range/"
+
+// PwnedPasswords looks passwords up in the Pwned Passwords API. Only the
+// first five characters of the SHA-1 hash of a password are sent, see
+// https://haveibeenpwned.com/API/v3#SearchingPwnedPasswordsByRange
+type PwnedPasswords struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewPwnedPasswords returns a PwnedPasswords querying the URL,
+// DefaultPwnedPasswordsURL if it is empty
+func NewPwnedPasswords(url string) *PwnedPasswords {
+	if url == "" {
+		url = DefaultPwnedPasswordsURL
+	}
+	return &PwnedPasswords{URL: url, Client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+// Breached returns true if the password appears in a known data breach
+func (pp *PwnedPasswords) Breached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	r, err := http.NewRequest("GET", pp.URL+prefix, nil)
+	if err != nil {
+		return false, err
+	}
+	// Padded responses do not give away the prefix by their size
+	r.Header.Set("Add-Padding", "true")
+	resp, err := pp.Client.Do(r)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("Pwned Passwords lookup failed with status %d", resp.StatusCode)
+	}
+
+	// Each line is the rest of a hash and how often it was seen,
+	// padding lines are seen zero times
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		fields := strings.SplitN(strings.TrimSpace(scanner.Text()), ":", 2)
+		if len(fields) == 2 && strings.EqualFold(fields[0], suffix) && fields[1] != "0" {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
@@ -29,7 +29,8 @@ func (l *Logger) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.Ha
 		ip = xff
 	}
 
-	thelog.INFO.Printf("Started %s %s for %s", r.Method, r.URL.Path, ip)
+	// Never log raw secrets passed in the query string
+	thelog.INFO.Printf("Started %s %s for %s", r.Method, thelog.RedactURL(r.URL), ip)
 
 	next(rw, r)
 
@@ -28,6 +28,18 @@ func Error(w http.ResponseWriter, err string, code int) {
 	json.NewEncoder(w).Encode(map[string]string{"error": err})
 }
 
+// ErrorWithDescription produces a JSON error response with an OAuth 2.0
+// error code and a human readable description, see RFC 6749 section 5.2:
+// {"error":"invalid_scope","error_description":"some error message"}
+func ErrorWithDescription(w http.ResponseWriter, err, description string, code int) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":             err,
+		"error_description": description,
+	})
+}
+
 // UnauthorizedError has to contain WWW-Authenticate header
 // See http://self-issued.info/docs/draft-ietf-oauth-v2-bearer.html#rfc.section.3
 func UnauthorizedError(w http.ResponseWriter, err string) {
@@ -35,3 +47,35 @@ func UnauthorizedError(w http.ResponseWriter, err string) {
 	w.Header().Set("WWW-Authenticate", fmt.Sprintf("Bearer realm=%s", realm))
 	Error(w, err, http.StatusUnauthorized)
 }
+
+// InvalidTokenError is an UnauthorizedError for requests which contained
+// an access token that could not be accepted
+// See https://tools.ietf.org/html/rfc6750#section-3.1
+func InvalidTokenError(w http.ResponseWriter, err string) {
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+		"Bearer realm=%s, error=\"invalid_token\", error_description=%q",
+		realm,
+		err,
+	))
+	Error(w, err, http.StatusUnauthorized)
+}
+
+// InsufficientScopeError is returned for requests with an access token
+// which has not been granted the scope the resource requires
+// See https://tools.ietf.org/html/rfc6750#section-3.1
+func InsufficientScopeError(w http.ResponseWriter, err string) {
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+		"Bearer realm=%s, error=\"insufficient_scope\", error_description=%q",
+		realm,
+		err,
+	))
+	Error(w, err, http.StatusForbidden)
+}
+
+// InvalidClientError is returned by the token endpoint when the client
+// could not be authenticated
+// See https://tools.ietf.org/html/rfc6749#section-5.2
+func InvalidClientError(w http.ResponseWriter, err string) {
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%s", realm))
+	ErrorWithDescription(w, "invalid_client", err, http.StatusUnauthorized)
+}
@@ -41,3 +41,52 @@ func TestError(t *testing.T) {
 	expected := "{\"error\":\"something went wrong\"}"
 	assert.Equal(t, expected, strings.TrimSpace(w.Body.String()))
 }
+
+func TestErrorWithDescription(t *testing.T) {
+	w := httptest.NewRecorder()
+	response.ErrorWithDescription(w, "invalid_scope", "something went wrong", 400)
+
+	assert.Equal(t, 400, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	expected := "{\"error\":\"invalid_scope\",\"error_description\":\"something went wrong\"}"
+	assert.Equal(t, expected, strings.TrimSpace(w.Body.String()))
+}
+
+func TestInvalidTokenError(t *testing.T) {
+	w := httptest.NewRecorder()
+	response.InvalidTokenError(w, "Access token expired")
+
+	assert.Equal(t, 401, w.Code)
+	assert.Equal(
+		t,
+		"Bearer realm=go_oauth2_server, error=\"invalid_token\", error_description=\"Access token expired\"",
+		w.Header().Get("WWW-Authenticate"),
+	)
+	expected := "{\"error\":\"Access token expired\"}"
+	assert.Equal(t, expected, strings.TrimSpace(w.Body.String()))
+}
+
+func TestInsufficientScopeError(t *testing.T) {
+	w := httptest.NewRecorder()
+	response.InsufficientScopeError(w, "Access token not granted the openid scope")
+
+	assert.Equal(t, 403, w.Code)
+	assert.Equal(
+		t,
+		"Bearer realm=go_oauth2_server, error=\"insufficient_scope\", error_description=\"Access token not granted the openid scope\"",
+		w.Header().Get("WWW-Authenticate"),
+	)
+}
+
+func TestInvalidClientError(t *testing.T) {
+	w := httptest.NewRecorder()
+	response.InvalidClientError(w, "Invalid client ID or secret")
+
+	assert.Equal(t, 401, w.Code)
+	assert.Equal(t, "Basic realm=go_oauth2_server", w.Header().Get("WWW-Authenticate"))
+	assert.Equal(
+		t,
+		"{\"error\":\"invalid_client\",\"error_description\":\"Invalid client ID or secret\"}\n",
+		w.Body.String(),
+	)
+}
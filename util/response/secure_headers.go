@@ -0,0 +1,48 @@
+package response
+
+import (
+	"net/http"
+)
+
+// SecureHeadersMiddleware makes sure every response carries the headers
+// required for responses containing tokens and other credentials, so
+// individual handlers cannot forget them
+// See https://tools.ietf.org/html/rfc6749#section-5.1
+type SecureHeadersMiddleware struct{}
+
+// NewSecureHeadersMiddleware returns a new SecureHeadersMiddleware instance
+func NewSecureHeadersMiddleware() *SecureHeadersMiddleware {
+	return new(SecureHeadersMiddleware)
+}
+
+// ServeHTTP as per the negroni.Handler interface
+func (m *SecureHeadersMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	next(&secureHeadersWriter{ResponseWriter: w}, r)
+}
+
+// secureHeadersWriter sets the headers right before the response is written
+type secureHeadersWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *secureHeadersWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		header := w.Header()
+		header.Set("Cache-Control", "no-store")
+		header.Set("Pragma", "no-cache")
+		header.Set("X-Content-Type-Options", "nosniff")
+		if header.Get("Content-Type") == "" {
+			header.Set("Content-Type", "application/json; charset=utf-8")
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *secureHeadersWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
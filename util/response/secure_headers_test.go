@@ -0,0 +1,42 @@
+package response_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RichardKnop/go-oauth2-server/util/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/negroni"
+)
+
+func TestSecureHeadersMiddleware(t *testing.T) {
+	handlers := []http.HandlerFunc{
+		func(w http.ResponseWriter, r *http.Request) {
+			response.WriteJSON(w, map[string]string{"foo": "bar"}, 200)
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			response.Error(w, "something went wrong", 400)
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			// Handler forgetting to set any headers
+			w.Write([]byte("{}"))
+		},
+	}
+
+	for _, handler := range handlers {
+		app := negroni.New()
+		app.Use(response.NewSecureHeadersMiddleware())
+		app.UseHandler(handler)
+
+		r, err := http.NewRequest("GET", "http://1.2.3.4/", nil)
+		assert.NoError(t, err, "Request setup should not get an error")
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+		assert.Equal(t, "no-cache", w.Header().Get("Pragma"))
+		assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+		assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	}
+}
@@ -0,0 +1,289 @@
+package saml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	// xmlNamespace is bound to the xml prefix without being declared
+	xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+)
+
+// node is an element of a parsed document. Prefixes are kept as written
+// as the canonical form renders them unchanged.
+type node struct {
+	prefix     string
+	local      string
+	attrs      []xml.Attr
+	namespaces map[string]string
+	parent     *node
+	children   []interface{}
+}
+
+// parse returns the root element of a document. Documents with a DTD,
+// comments, processing instructions after the XML declaration or repeated
+// attributes are refused, the canonical form would drop or reorder them so
+// the signature would not cover what the document seems to say.
+func parse(data []byte) (*node, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var root, current *node
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, ErrMalformedXML
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			n := &node{
+				prefix:     t.Name.Space,
+				local:      t.Name.Local,
+				namespaces: make(map[string]string),
+				parent:     current,
+			}
+			seen := make(map[xml.Name]bool, len(t.Attr))
+			for _, attr := range t.Attr {
+				if seen[attr.Name] {
+					return nil, ErrMalformedXML
+				}
+				seen[attr.Name] = true
+				switch {
+				case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+					n.namespaces[""] = attr.Value
+				case attr.Name.Space == "xmlns":
+					n.namespaces[attr.Name.Local] = attr.Value
+				default:
+					n.attrs = append(n.attrs, attr)
+				}
+			}
+			if current != nil {
+				current.children = append(current.children, n)
+			} else if root != nil {
+				return nil, ErrMalformedXML
+			} else {
+				root = n
+			}
+			current = n
+		case xml.EndElement:
+			if current == nil || t.Name.Space != current.prefix || t.Name.Local != current.local {
+				return nil, ErrMalformedXML
+			}
+			current = current.parent
+		case xml.CharData:
+			if current != nil {
+				current.children = append(current.children, t.Copy())
+			} else if len(bytes.TrimSpace(t)) > 0 {
+				return nil, ErrMalformedXML
+			}
+		case xml.Directive:
+			// Entities declared by a DTD could change the document
+			return nil, ErrMalformedXML
+		case xml.Comment:
+			// Comments split the text the signature covers in two
+			return nil, ErrMalformedXML
+		case xml.ProcInst:
+			// Only the XML declaration is allowed
+			if root != nil || t.Target != "xml" {
+				return nil, ErrMalformedXML
+			}
+		}
+	}
+
+	if root == nil || current != nil {
+		return nil, ErrMalformedXML
+	}
+	return root, nil
+}
+
+// lookup returns the namespace the prefix is bound to in scope of the element
+func (n *node) lookup(prefix string) (string, bool) {
+	if prefix == "xml" {
+		return xmlNamespace, true
+	}
+	for e := n; e != nil; e = e.parent {
+		if uri, ok := e.namespaces[prefix]; ok {
+			return uri, true
+		}
+	}
+	return "", false
+}
+
+// namespace returns the namespace of the element
+func (n *node) namespace() string {
+	uri, _ := n.lookup(n.prefix)
+	return uri
+}
+
+// attrNamespace returns the namespace of an attribute of the element,
+// attributes without a prefix are in no namespace
+func (n *node) attrNamespace(attr xml.Attr) string {
+	if attr.Name.Space == "" {
+		return ""
+	}
+	uri, _ := n.lookup(attr.Name.Space)
+	return uri
+}
+
+// attr returns the value of an attribute without a prefix
+func (n *node) attr(local string) string {
+	for _, attr := range n.attrs {
+		if attr.Name.Space == "" && attr.Name.Local == local {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// childElements returns the child elements in the namespace with the local name
+func (n *node) childElements(namespace, local string) []*node {
+	var elements []*node
+	for _, child := range n.children {
+		if e, ok := child.(*node); ok && e.local == local && e.namespace() == namespace {
+			elements = append(elements, e)
+		}
+	}
+	return elements
+}
+
+// childElement returns the only child element in the namespace with the local name
+func (n *node) childElement(namespace, local string) (*node, bool) {
+	elements := n.childElements(namespace, local)
+	if len(elements) != 1 {
+		return nil, false
+	}
+	return elements[0], true
+}
+
+// hasID returns true if a descendant of the element has the ID
+func (n *node) hasID(id string) bool {
+	for _, child := range n.children {
+		if e, ok := child.(*node); ok && (e.attr("ID") == id || e.hasID(id)) {
+			return true
+		}
+	}
+	return false
+}
+
+// text returns the character data of the element
+func (n *node) text() string {
+	var text []byte
+	for _, child := range n.children {
+		if data, ok := child.(xml.CharData); ok {
+			text = append(text, data...)
+		}
+	}
+	return string(text)
+}
+
+// canonicalize returns the exclusive canonical form without comments of the
+// element (https://www.w3.org/TR/xml-exc-c14n/), leaving out the excluded
+// element as the enveloped signature transform does. Namespaces of the
+// inclusive prefixes are rendered whenever they are in scope.
+func canonicalize(n, excluded *node, inclusive []string) []byte {
+	buf := new(bytes.Buffer)
+	writeCanonical(buf, n, excluded, inclusive, make(map[string]string))
+	return buf.Bytes()
+}
+
+func writeCanonical(buf *bytes.Buffer, n, excluded *node, inclusive []string, rendered map[string]string) {
+	// Only namespaces visibly utilized by the element and its attributes are rendered
+	utilized := map[string]bool{n.prefix: true}
+	for _, attr := range n.attrs {
+		if attr.Name.Space != "" {
+			utilized[attr.Name.Space] = true
+		}
+	}
+	for _, prefix := range inclusive {
+		if prefix == "#default" {
+			prefix = ""
+		}
+		if _, ok := n.lookup(prefix); ok {
+			utilized[prefix] = true
+		}
+	}
+
+	// Declare the namespaces not already declared the same way by an ancestor
+	var prefixes []string
+	scope := make(map[string]string, len(rendered))
+	for prefix, uri := range rendered {
+		scope[prefix] = uri
+	}
+	for prefix := range utilized {
+		if prefix == "xml" {
+			continue
+		}
+		uri, _ := n.lookup(prefix)
+		previous, ok := rendered[prefix]
+		if (ok && previous == uri) || (!ok && uri == "") {
+			continue
+		}
+		prefixes = append(prefixes, prefix)
+		scope[prefix] = uri
+	}
+	sort.Strings(prefixes)
+
+	// Attributes are sorted by namespace, then local name
+	attrs := make([]xml.Attr, len(n.attrs))
+	copy(attrs, n.attrs)
+	sort.SliceStable(attrs, func(i, j int) bool {
+		iURI, jURI := n.attrNamespace(attrs[i]), n.attrNamespace(attrs[j])
+		if iURI != jURI {
+			return iURI < jURI
+		}
+		return attrs[i].Name.Local < attrs[j].Name.Local
+	})
+
+	buf.WriteString("<" + qualifiedName(n.prefix, n.local))
+	for _, prefix := range prefixes {
+		if prefix == "" {
+			buf.WriteString(` xmlns="`)
+		} else {
+			buf.WriteString(` xmlns:` + prefix + `="`)
+		}
+		buf.WriteString(escapeAttr(scope[prefix]) + `"`)
+	}
+	for _, attr := range attrs {
+		buf.WriteString(" " + qualifiedName(attr.Name.Space, attr.Name.Local) + `="` + escapeAttr(attr.Value) + `"`)
+	}
+	buf.WriteString(">")
+
+	for _, child := range n.children {
+		switch child := child.(type) {
+		case *node:
+			if child != excluded {
+				writeCanonical(buf, child, excluded, inclusive, scope)
+			}
+		case xml.CharData:
+			buf.WriteString(escapeText(string(child)))
+		}
+	}
+
+	buf.WriteString("</" + qualifiedName(n.prefix, n.local) + ">")
+}
+
+func qualifiedName(prefix, local string) string {
+	if prefix == "" {
+		return local
+	}
+	return prefix + ":" + local
+}
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
+)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+func escapeAttr(s string) string {
+	return attrEscaper.Replace(s)
+}
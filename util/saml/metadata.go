@@ -0,0 +1,100 @@
+package saml
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrInvalidMetadata ...
+	ErrInvalidMetadata = errors.New("Invalid identity provider metadata")
+	// ErrNoSigningCertificate ...
+	ErrNoSigningCertificate = errors.New("Identity provider metadata has no signing certificate")
+)
+
+// Metadata is what assertions of an identity provider are verified with
+type Metadata struct {
+	EntityID     string
+	Certificates []*x509.Certificate
+}
+
+type entityDescriptor struct {
+	EntityID         string `xml:"entityID,attr"`
+	IDPSSODescriptor *struct {
+		KeyDescriptors []struct {
+			Use          string   `xml:"use,attr"`
+			Certificates []string `xml:"KeyInfo>X509Data>X509Certificate"`
+		} `xml:"KeyDescriptor"`
+	} `xml:"IDPSSODescriptor"`
+}
+
+// ParseMetadata returns the entity ID and signing certificates of an identity
+// provider from its SAML 2.0 metadata. The metadata is trusted as it is,
+// it should be downloaded from the identity provider over a secure channel.
+func ParseMetadata(data []byte) (*Metadata, error) {
+	descriptor := new(entityDescriptor)
+	if err := xml.Unmarshal(data, descriptor); err != nil {
+		return nil, ErrInvalidMetadata
+	}
+	if descriptor.EntityID == "" || descriptor.IDPSSODescriptor == nil {
+		return nil, ErrInvalidMetadata
+	}
+
+	metadata := &Metadata{EntityID: descriptor.EntityID}
+	for _, keyDescriptor := range descriptor.IDPSSODescriptor.KeyDescriptors {
+		// Keys without a use are used for both signing and encryption
+		if keyDescriptor.Use != "" && keyDescriptor.Use != "signing" {
+			continue
+		}
+		for _, encoded := range keyDescriptor.Certificates {
+			der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(encoded), ""))
+			if err != nil {
+				return nil, ErrInvalidMetadata
+			}
+			certificate, err := x509.ParseCertificate(der)
+			if err != nil {
+				return nil, ErrInvalidMetadata
+			}
+			metadata.Certificates = append(metadata.Certificates, certificate)
+		}
+	}
+	if len(metadata.Certificates) == 0 {
+		return nil, ErrNoSigningCertificate
+	}
+
+	return metadata, nil
+}
+
+// EncodeCertificates returns the certificates PEM encoded
+func EncodeCertificates(certificates []*x509.Certificate) string {
+	var encoded []byte
+	for _, certificate := range certificates {
+		encoded = append(encoded, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Raw})...)
+	}
+	return string(encoded)
+}
+
+// ParseCertificates returns PEM encoded certificates
+func ParseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certificates []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certificates = append(certificates, certificate)
+	}
+	return certificates, nil
+}
@@ -0,0 +1,307 @@
+package saml
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"time"
+
+	// Register the hash functions of the supported algorithms
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+// Namespaces and algorithms of signed assertions
+const (
+	AssertionNamespace = "urn:oasis:names:tc:SAML:2.0:assertion"
+	SignatureNamespace = "http://www.w3.org/2000/09/xmldsig#"
+
+	ExclusiveC14N      = "http://www.w3.org/2001/10/xml-exc-c14n#"
+	EnvelopedSignature = "http://www.w3.org/2000/09/xmldsig#enveloped-signature"
+	RSASHA256          = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	RSASHA512          = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha512"
+	SHA256             = "http://www.w3.org/2001/04/xmlenc#sha256"
+	SHA512             = "http://www.w3.org/2001/04/xmlenc#sha512"
+
+	// BearerConfirmation is the subject confirmation method of bearer assertions
+	BearerConfirmation = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+)
+
+var (
+	// ErrMalformedXML ...
+	ErrMalformedXML = errors.New("Malformed XML document")
+	// ErrNotAssertion ...
+	ErrNotAssertion = errors.New("Document is not a SAML 2.0 assertion")
+	// ErrMissingSignature ...
+	ErrMissingSignature = errors.New("Assertion is not signed")
+	// ErrUnsupportedSignature ...
+	ErrUnsupportedSignature = errors.New("Assertion signature algorithm or transforms not supported")
+	// ErrInvalidSignature ...
+	ErrInvalidSignature = errors.New("Invalid assertion signature")
+
+	signatureHashes = map[string]crypto.Hash{RSASHA256: crypto.SHA256, RSASHA512: crypto.SHA512}
+	digestHashes    = map[string]crypto.Hash{SHA256: crypto.SHA256, SHA512: crypto.SHA512}
+)
+
+// Assertion is the part of a SAML 2.0 assertion needed to authorize its subject
+type Assertion struct {
+	ID           string      `xml:"ID,attr"`
+	IssueInstant time.Time   `xml:"IssueInstant,attr"`
+	Issuer       string      `xml:"Issuer"`
+	Subject      Subject     `xml:"Subject"`
+	Conditions   *Conditions `xml:"Conditions"`
+}
+
+// Subject is the principal the assertion is about
+type Subject struct {
+	NameID               string                `xml:"NameID"`
+	SubjectConfirmations []SubjectConfirmation `xml:"SubjectConfirmation"`
+}
+
+// SubjectConfirmation tells how the subject can be confirmed
+type SubjectConfirmation struct {
+	Method                  string                   `xml:"Method,attr"`
+	SubjectConfirmationData *SubjectConfirmationData `xml:"SubjectConfirmationData"`
+}
+
+// SubjectConfirmationData restricts when and where the subject can be confirmed
+type SubjectConfirmationData struct {
+	NotBefore    time.Time `xml:"NotBefore,attr"`
+	NotOnOrAfter time.Time `xml:"NotOnOrAfter,attr"`
+	Recipient    string    `xml:"Recipient,attr"`
+}
+
+// Conditions restrict when and by whom the assertion can be used
+type Conditions struct {
+	NotBefore            time.Time             `xml:"NotBefore,attr"`
+	NotOnOrAfter         time.Time             `xml:"NotOnOrAfter,attr"`
+	AudienceRestrictions []AudienceRestriction `xml:"AudienceRestriction"`
+}
+
+// AudienceRestriction is satisfied by any of its audiences
+type AudienceRestriction struct {
+	Audiences []string `xml:"Audience"`
+}
+
+// signedInfo is the part of a signature the signature value is computed over
+type signedInfo struct {
+	SignatureMethod algorithm   `xml:"SignatureMethod"`
+	References      []reference `xml:"Reference"`
+}
+
+type reference struct {
+	URI          string      `xml:"URI,attr"`
+	Transforms   []transform `xml:"Transforms>Transform"`
+	DigestMethod algorithm   `xml:"DigestMethod"`
+	DigestValue  string      `xml:"DigestValue"`
+}
+
+type transform struct {
+	Algorithm           string               `xml:"Algorithm,attr"`
+	InclusiveNamespaces *inclusiveNamespaces `xml:"InclusiveNamespaces"`
+}
+
+type algorithm struct {
+	Algorithm string `xml:"Algorithm,attr"`
+}
+
+type inclusiveNamespaces struct {
+	PrefixList string `xml:"PrefixList,attr"`
+}
+
+// prefixes returns the prefixes rendered like inclusive canonicalization
+func (n *inclusiveNamespaces) prefixes() []string {
+	if n == nil {
+		return nil
+	}
+	return strings.Fields(n.PrefixList)
+}
+
+// Verify checks the assertion carries an enveloped signature of the whole
+// assertion made with the key of one of the certificates and returns it.
+// The returned assertion is read from the signed content only, so content
+// added to the document around the signed assertion is never trusted.
+func Verify(document []byte, certificates []*x509.Certificate) (*Assertion, error) {
+	root, err := parse(document)
+	if err != nil {
+		return nil, err
+	}
+	if root.local != "Assertion" || root.namespace() != AssertionNamespace || root.attr("ID") == "" {
+		return nil, ErrNotAssertion
+	}
+
+	// The reference must not be ambiguous, a copy of the signed assertion
+	// nested in a forged one could otherwise be mistaken for it
+	if root.hasID(root.attr("ID")) {
+		return nil, ErrMalformedXML
+	}
+
+	// The signature is a child of the assertion
+	signature, ok := root.childElement(SignatureNamespace, "Signature")
+	if !ok {
+		return nil, ErrMissingSignature
+	}
+	signedInfoNode, ok := signature.childElement(SignatureNamespace, "SignedInfo")
+	if !ok {
+		return nil, ErrMissingSignature
+	}
+	signatureValueNode, ok := signature.childElement(SignatureNamespace, "SignatureValue")
+	if !ok {
+		return nil, ErrMissingSignature
+	}
+
+	// The signed info is canonicalized before its content can be trusted
+	c14nMethod, ok := signedInfoNode.childElement(SignatureNamespace, "CanonicalizationMethod")
+	if !ok || c14nMethod.attr("Algorithm") != ExclusiveC14N {
+		return nil, ErrUnsupportedSignature
+	}
+	var inclusive []string
+	if n, ok := c14nMethod.childElement(ExclusiveC14N, "InclusiveNamespaces"); ok {
+		inclusive = strings.Fields(n.attr("PrefixList"))
+	}
+	signedInfoXML := canonicalize(signedInfoNode, nil, inclusive)
+
+	// Verify the signature value over the signed info
+	info := new(signedInfo)
+	if err := xml.Unmarshal(signedInfoXML, info); err != nil {
+		return nil, ErrMalformedXML
+	}
+	hash, ok := signatureHashes[info.SignatureMethod.Algorithm]
+	if !ok {
+		return nil, ErrUnsupportedSignature
+	}
+	signatureValue, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(signatureValueNode.text()), ""))
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	if !verifyRSA(certificates, hash, signedInfoXML, signatureValue) {
+		return nil, ErrInvalidSignature
+	}
+
+	// The signed info must reference the whole assertion and nothing else
+	references := signedInfoNode.childElements(SignatureNamespace, "Reference")
+	if len(references) != 1 || len(info.References) != 1 || info.References[0].URI != "#"+root.attr("ID") {
+		return nil, ErrUnsupportedSignature
+	}
+	ref := info.References[0]
+	if len(ref.Transforms) != 2 || ref.Transforms[0].Algorithm != EnvelopedSignature ||
+		ref.Transforms[1].Algorithm != ExclusiveC14N {
+		return nil, ErrUnsupportedSignature
+	}
+	digestHash, ok := digestHashes[ref.DigestMethod.Algorithm]
+	if !ok {
+		return nil, ErrUnsupportedSignature
+	}
+
+	// Check the digest of the assertion without its signature
+	assertionXML := canonicalize(root, signature, ref.Transforms[1].InclusiveNamespaces.prefixes())
+	digestValue, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(ref.DigestValue), ""))
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	h := digestHash.New()
+	h.Write(assertionXML)
+	if subtle.ConstantTimeCompare(h.Sum(nil), digestValue) != 1 {
+		return nil, ErrInvalidSignature
+	}
+
+	assertion := new(Assertion)
+	if err := xml.Unmarshal(assertionXML, assertion); err != nil {
+		return nil, ErrMalformedXML
+	}
+	return assertion, nil
+}
+
+// verifyRSA returns true if the signature was made with the key of one of the certificates
+func verifyRSA(certificates []*x509.Certificate, hash crypto.Hash, data, signature []byte) bool {
+	h := hash.New()
+	h.Write(data)
+	digest := h.Sum(nil)
+	for _, certificate := range certificates {
+		publicKey, ok := certificate.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			continue
+		}
+		if rsa.VerifyPKCS1v15(publicKey, hash, digest, signature) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Sign returns the assertion with an enveloped RSA SHA-256 signature, inserted
+// after the issuer as the schema requires. It is meant for identity providers
+// embedded in other applications and for testing.
+func Sign(document []byte, privateKey *rsa.PrivateKey) ([]byte, error) {
+	root, err := parse(document)
+	if err != nil {
+		return nil, err
+	}
+	if root.local != "Assertion" || root.namespace() != AssertionNamespace || root.attr("ID") == "" {
+		return nil, ErrNotAssertion
+	}
+
+	digest := crypto.SHA256.New()
+	digest.Write(canonicalize(root, nil, nil))
+
+	signatureXML := `<ds:Signature xmlns:ds="` + SignatureNamespace + `"><ds:SignedInfo>` +
+		`<ds:CanonicalizationMethod Algorithm="` + ExclusiveC14N + `"/>` +
+		`<ds:SignatureMethod Algorithm="` + RSASHA256 + `"/>` +
+		`<ds:Reference URI="#` + escapeAttr(root.attr("ID")) + `"><ds:Transforms>` +
+		`<ds:Transform Algorithm="` + EnvelopedSignature + `"/>` +
+		`<ds:Transform Algorithm="` + ExclusiveC14N + `"/>` +
+		`</ds:Transforms><ds:DigestMethod Algorithm="` + SHA256 + `"/>` +
+		`<ds:DigestValue>` + base64.StdEncoding.EncodeToString(digest.Sum(nil)) + `</ds:DigestValue>` +
+		`</ds:Reference></ds:SignedInfo><ds:SignatureValue></ds:SignatureValue></ds:Signature>`
+	signature, err := parse([]byte(signatureXML))
+	if err != nil {
+		return nil, err
+	}
+	signature.parent = root
+	signedInfoNode, _ := signature.childElement(SignatureNamespace, "SignedInfo")
+	signatureValueNode, _ := signature.childElement(SignatureNamespace, "SignatureValue")
+
+	h := crypto.SHA256.New()
+	h.Write(canonicalize(signedInfoNode, nil, nil))
+	signatureValue, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, h.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	signatureValueNode.children = []interface{}{xml.CharData(base64.StdEncoding.EncodeToString(signatureValue))}
+
+	// Insert the signature after the issuer
+	position := 0
+	for i, child := range root.children {
+		if e, ok := child.(*node); ok && e.local == "Issuer" && e.namespace() == AssertionNamespace {
+			position = i + 1
+			break
+		}
+	}
+	children := append([]interface{}{}, root.children[:position]...)
+	children = append(children, signature)
+	root.children = append(children, root.children[position:]...)
+
+	return canonicalize(root, nil, nil), nil
+}
+
+// Encode returns the base64url encoding of an assertion without padding,
+// as sent in the assertion parameter, see https://tools.ietf.org/html/rfc7522#section-2.1
+func Encode(document []byte) string {
+	return base64.RawURLEncoding.EncodeToString(document)
+}
+
+// Decode returns an assertion encoded as sent in the assertion parameter
+func Decode(assertion string) ([]byte, error) {
+	document, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(assertion, "="))
+	if err != nil {
+		return nil, ErrMalformedXML
+	}
+	return bytes.TrimSpace(document), nil
+}
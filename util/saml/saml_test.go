@@ -0,0 +1,330 @@
+package saml_test
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/util/saml"
+	"github.com/stretchr/testify/assert"
+)
+
+const testAssertion = `<saml:Assertion xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="_test_assertion" Version="2.0" IssueInstant="2019-01-01T00:00:00Z">
+  <saml:Issuer>https://idp.example.com</saml:Issuer>
+  <saml:Subject>
+    <saml:NameID Format="urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress">test@user</saml:NameID>
+    <saml:SubjectConfirmation Method="urn:oasis:names:tc:SAML:2.0:cm:bearer">
+      <saml:SubjectConfirmationData NotOnOrAfter="2019-01-01T00:05:00Z" Recipient="http://localhost:8080/v1/oauth/tokens"/>
+    </saml:SubjectConfirmation>
+  </saml:Subject>
+  <saml:Conditions NotBefore="2019-01-01T00:00:00Z" NotOnOrAfter="2019-01-01T00:05:00.000Z">
+    <saml:AudienceRestriction>
+      <saml:Audience>http://localhost:8080</saml:Audience>
+    </saml:AudienceRestriction>
+  </saml:Conditions>
+</saml:Assertion>`
+
+func TestSignAndVerify(t *testing.T) {
+	privateKey, certificate := newTestCertificate(t)
+
+	signed, err := saml.Sign([]byte(testAssertion), privateKey)
+	assert.NoError(t, err)
+
+	assertion, err := saml.Verify(signed, []*x509.Certificate{certificate})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "_test_assertion", assertion.ID)
+		assert.Equal(t, "https://idp.example.com", assertion.Issuer)
+		assert.Equal(t, "test@user", assertion.Subject.NameID)
+		if assert.Equal(t, 1, len(assertion.Subject.SubjectConfirmations)) {
+			confirmation := assertion.Subject.SubjectConfirmations[0]
+			assert.Equal(t, saml.BearerConfirmation, confirmation.Method)
+			assert.Equal(t, "http://localhost:8080/v1/oauth/tokens", confirmation.SubjectConfirmationData.Recipient)
+		}
+		assert.Equal(t, time.Date(2019, 1, 1, 0, 5, 0, 0, time.UTC), assertion.Conditions.NotOnOrAfter)
+		assert.Equal(t, []string{"http://localhost:8080"}, assertion.Conditions.AudienceRestrictions[0].Audiences)
+	}
+
+	// The encoding does not matter as long as the canonical form is the same
+	reformatted := strings.Replace(string(signed), `<saml:Issuer>`, `<saml:Issuer  >`, 1)
+	reformatted = strings.Replace(reformatted, `"2.0"`, `'2.0'`, 1)
+	_, err = saml.Verify([]byte(reformatted), []*x509.Certificate{certificate})
+	assert.NoError(t, err)
+}
+
+func TestVerifyRejectsTampering(t *testing.T) {
+	privateKey, certificate := newTestCertificate(t)
+	_, otherCertificate := newTestCertificate(t)
+
+	signed, err := saml.Sign([]byte(testAssertion), privateKey)
+	assert.NoError(t, err)
+
+	// Signed by another key
+	_, err = saml.Verify(signed, []*x509.Certificate{otherCertificate})
+	assert.Equal(t, saml.ErrInvalidSignature, err)
+
+	// Changed after signing
+	tampered := strings.Replace(string(signed), "test@user", "test@superuser", 1)
+	_, err = saml.Verify([]byte(tampered), []*x509.Certificate{certificate})
+	assert.Equal(t, saml.ErrInvalidSignature, err)
+
+	// Not signed at all
+	_, err = saml.Verify([]byte(testAssertion), []*x509.Certificate{certificate})
+	assert.Equal(t, saml.ErrMissingSignature, err)
+
+	// Wrapped in another element, the signature must be of the document itself
+	wrapped := `<saml:Advice xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion">` + string(signed) + `</saml:Advice>`
+	_, err = saml.Verify([]byte(wrapped), []*x509.Certificate{certificate})
+	assert.Equal(t, saml.ErrNotAssertion, err)
+
+	// Documents with a DTD are refused
+	_, err = saml.Verify([]byte(`<!DOCTYPE foo [<!ENTITY x "y">]>`+string(signed)), []*x509.Certificate{certificate})
+	assert.Equal(t, saml.ErrMalformedXML, err)
+}
+
+func TestVerifyCanonicalNamespaces(t *testing.T) {
+	privateKey, certificate := newTestCertificate(t)
+
+	// Namespaces declared on the root but unused are left out of the
+	// canonical form, declarations are rendered where first utilized
+	document := strings.Replace(testAssertion,
+		`<saml:Assertion xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion"`,
+		`<saml:Assertion xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns="urn:example"`, 1)
+	signed, err := saml.Sign([]byte(document), privateKey)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(signed), "xmlns:xs")
+	assert.NotContains(t, string(signed), `xmlns="urn:example"`)
+
+	_, err = saml.Verify(signed, []*x509.Certificate{certificate})
+	assert.NoError(t, err)
+}
+
+func TestVerifyRejectsWrappedAssertions(t *testing.T) {
+	privateKey, certificate := newTestCertificate(t)
+
+	signed, err := saml.Sign([]byte(testAssertion), privateKey)
+	assert.NoError(t, err)
+	signature := signatureOf(string(signed))
+	unsigned := strings.Replace(string(signed), signature, "", 1)
+	forged := `<saml:Assertion xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="_forged_assertion" Version="2.0" IssueInstant="2019-01-01T00:00:00Z">` +
+		`<saml:Issuer>https://idp.example.com</saml:Issuer>` +
+		`<saml:Subject><saml:NameID>test@superuser</saml:NameID></saml:Subject>`
+
+	for _, testCase := range []struct {
+		document string
+		err      error
+	}{
+		// The signed assertion nested in a forged one
+		{forged + `<saml:Advice>` + string(signed) + `</saml:Advice></saml:Assertion>`, saml.ErrMissingSignature},
+		// With the signature moved to the forged assertion
+		{forged + signature + `<saml:Advice>` + unsigned + `</saml:Advice></saml:Assertion>`, saml.ErrUnsupportedSignature},
+		// Which takes the ID of the signed assertion
+		{
+			strings.Replace(forged, "_forged_assertion", "_test_assertion", 1) + signature +
+				`<saml:Advice>` + unsigned + `</saml:Advice></saml:Assertion>`,
+			saml.ErrMalformedXML,
+		},
+		// Or without the signed assertion
+		{strings.Replace(forged, "_forged_assertion", "_test_assertion", 1) + signature + `</saml:Assertion>`, saml.ErrInvalidSignature},
+		// A second signature
+		{strings.Replace(string(signed), signature, signature+signature, 1), saml.ErrMissingSignature},
+		// A second subject after the signed one
+		{
+			strings.Replace(string(signed), `</saml:Assertion>`, `<saml:Subject><saml:NameID>test@superuser</saml:NameID></saml:Subject></saml:Assertion>`, 1),
+			saml.ErrInvalidSignature,
+		},
+		// A second ID attribute
+		{strings.Replace(string(signed), ` ID="_test_assertion"`, ` ID="_test_assertion" ID="_forged_assertion"`, 1), saml.ErrMalformedXML},
+	} {
+		_, err = saml.Verify([]byte(testCase.document), []*x509.Certificate{certificate})
+		assert.Equal(t, testCase.err, err, testCase.document)
+	}
+}
+
+func TestVerifyRejectsComments(t *testing.T) {
+	privateKey, certificate := newTestCertificate(t)
+
+	// Canonicalization drops comments, so the signature of an assertion for
+	// test@user.evil.com would still verify with the text split in two
+	document := strings.Replace(testAssertion, ">test@user<", ">test@user.evil.com<", 1)
+	signed, err := saml.Sign([]byte(document), privateKey)
+	assert.NoError(t, err)
+
+	for _, tampered := range []string{
+		strings.Replace(string(signed), "test@user.evil.com", "test@user<!---->.evil.com", 1),
+		strings.Replace(string(signed), "<saml:Subject>", "<!-- comment --><saml:Subject>", 1),
+		strings.Replace(string(signed), "<saml:Subject>", "<?target data?><saml:Subject>", 1),
+	} {
+		_, err = saml.Verify([]byte(tampered), []*x509.Certificate{certificate})
+		assert.Equal(t, saml.ErrMalformedXML, err, tampered)
+	}
+
+	// The XML declaration is fine
+	_, err = saml.Verify([]byte(`<?xml version="1.0" encoding="UTF-8"?>`+string(signed)), []*x509.Certificate{certificate})
+	assert.NoError(t, err)
+}
+
+func TestVerifyRejectsNamespaceRedeclaration(t *testing.T) {
+	privateKey, certificate := newTestCertificate(t)
+
+	signed, err := saml.Sign([]byte(testAssertion), privateKey)
+	assert.NoError(t, err)
+
+	for _, testCase := range []struct {
+		document string
+		err      error
+	}{
+		// The assertion is in another namespace
+		{strings.Replace(string(signed), `xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion"`, `xmlns:saml="urn:example"`, 1), saml.ErrNotAssertion},
+		// Signed elements are moved to another namespace
+		{strings.Replace(string(signed), `<saml:Subject>`, `<saml:Subject xmlns:saml="urn:example">`, 1), saml.ErrInvalidSignature},
+		// The signed info is moved to another namespace
+		{strings.Replace(string(signed), `<ds:SignedInfo>`, `<ds:SignedInfo xmlns:ds="urn:example">`, 1), saml.ErrMissingSignature},
+		// The prefix is declared twice
+		{
+			strings.Replace(string(signed), `xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion"`, `xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" xmlns:saml="urn:example"`, 1),
+			saml.ErrMalformedXML,
+		},
+	} {
+		_, err = saml.Verify([]byte(testCase.document), []*x509.Certificate{certificate})
+		assert.Equal(t, testCase.err, err, testCase.document)
+	}
+
+	// Redeclaring a prefix the same way does not change the canonical form
+	redeclared := strings.Replace(string(signed), `<saml:Subject>`, `<saml:Subject xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion">`, 1)
+	_, err = saml.Verify([]byte(redeclared), []*x509.Certificate{certificate})
+	assert.NoError(t, err)
+}
+
+func TestVerifyRejectsUnsupportedReferences(t *testing.T) {
+	privateKey, certificate := newTestCertificate(t)
+
+	const (
+		inclusiveC14N         = "http://www.w3.org/TR/2001/REC-xml-c14n-20010315"
+		exclusiveC14NComments = "http://www.w3.org/2001/10/xml-exc-c14n#WithComments"
+		xpath                 = "http://www.w3.org/TR/1999/REC-xpath-19991116"
+	)
+	enveloped := []string{saml.EnvelopedSignature, saml.ExclusiveC14N}
+
+	// The references are signed by the identity provider's own key
+	valid := signWithReferences(t, privateKey, saml.ExclusiveC14N, reference("#_test_assertion", enveloped))
+	_, err := saml.Verify(valid, []*x509.Certificate{certificate})
+	assert.NoError(t, err)
+
+	for _, testCase := range []struct {
+		c14nMethod string
+		references func(digest string) string
+	}{
+		// A second reference
+		{saml.ExclusiveC14N, func(digest string) string {
+			return reference("#_test_assertion", enveloped)(digest) + reference("#_test_assertion", enveloped)(digest)
+		}},
+		{saml.ExclusiveC14N, func(digest string) string {
+			return reference("#_test_assertion", enveloped)(digest) + reference("#_other_assertion", enveloped)(digest)
+		}},
+		// A reference to another element
+		{saml.ExclusiveC14N, reference("#_other_assertion", enveloped)},
+		// Transforms other than exclusive canonicalization
+		{saml.ExclusiveC14N, reference("#_test_assertion", []string{saml.EnvelopedSignature, inclusiveC14N})},
+		{saml.ExclusiveC14N, reference("#_test_assertion", []string{saml.EnvelopedSignature, exclusiveC14NComments})},
+		{saml.ExclusiveC14N, reference("#_test_assertion", []string{saml.EnvelopedSignature})},
+		{saml.ExclusiveC14N, reference("#_test_assertion", []string{saml.EnvelopedSignature, saml.ExclusiveC14N, xpath})},
+		{saml.ExclusiveC14N, reference("#_test_assertion", []string{saml.ExclusiveC14N, saml.EnvelopedSignature})},
+		// Canonicalization of the signed info other than exclusive canonicalization
+		{exclusiveC14NComments, reference("#_test_assertion", enveloped)},
+		{inclusiveC14N, reference("#_test_assertion", enveloped)},
+	} {
+		document := signWithReferences(t, privateKey, testCase.c14nMethod, testCase.references)
+		_, err = saml.Verify(document, []*x509.Certificate{certificate})
+		assert.Equal(t, saml.ErrUnsupportedSignature, err, string(document))
+	}
+}
+
+func TestParseMetadata(t *testing.T) {
+	_, certificate := newTestCertificate(t)
+	encoded := base64.StdEncoding.EncodeToString(certificate.Raw)
+
+	metadata, err := saml.ParseMetadata([]byte(`<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" xmlns:ds="http://www.w3.org/2000/09/xmldsig#" entityID="https://idp.example.com">
+  <md:IDPSSODescriptor protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">
+    <md:KeyDescriptor use="encryption"><ds:KeyInfo><ds:X509Data><ds:X509Certificate>bogus</ds:X509Certificate></ds:X509Data></ds:KeyInfo></md:KeyDescriptor>
+    <md:KeyDescriptor use="signing"><ds:KeyInfo><ds:X509Data><ds:X509Certificate>` + encoded + `</ds:X509Certificate></ds:X509Data></ds:KeyInfo></md:KeyDescriptor>
+  </md:IDPSSODescriptor>
+</md:EntityDescriptor>`))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "https://idp.example.com", metadata.EntityID)
+		assert.Equal(t, []*x509.Certificate{certificate}, metadata.Certificates)
+	}
+
+	certificates, err := saml.ParseCertificates([]byte(saml.EncodeCertificates(metadata.Certificates)))
+	assert.NoError(t, err)
+	assert.Equal(t, metadata.Certificates, certificates)
+
+	_, err = saml.ParseMetadata([]byte(`<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" entityID="https://idp.example.com"><md:IDPSSODescriptor/></md:EntityDescriptor>`))
+	assert.Equal(t, saml.ErrNoSigningCertificate, err)
+}
+
+func newTestCertificate(t *testing.T) (*rsa.PrivateKey, *x509.Certificate) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "idp.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	assert.NoError(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+
+	return privateKey, certificate
+}
+
+// signatureOf returns the signature element of a signed document
+func signatureOf(document string) string {
+	start := strings.Index(document, "<ds:Signature ")
+	end := strings.Index(document, "</ds:Signature>") + len("</ds:Signature>")
+	return document[start:end]
+}
+
+// signWithReferences signs the test assertion with signed info made of the
+// canonicalization method and the references, which are given the digest
+// of the assertion without its signature
+func signWithReferences(t *testing.T, privateKey *rsa.PrivateKey, c14nMethod string, references func(digest string) string) []byte {
+	signed, err := saml.Sign([]byte(testAssertion), privateKey)
+	assert.NoError(t, err)
+	signature := signatureOf(string(signed))
+	digest := signature[strings.Index(signature, "<ds:DigestValue>")+len("<ds:DigestValue>") : strings.Index(signature, "</ds:DigestValue>")]
+
+	// The signed info is written in its canonical form
+	signedInfo := `<ds:SignedInfo xmlns:ds="` + saml.SignatureNamespace + `">` +
+		`<ds:CanonicalizationMethod Algorithm="` + c14nMethod + `"></ds:CanonicalizationMethod>` +
+		`<ds:SignatureMethod Algorithm="` + saml.RSASHA256 + `"></ds:SignatureMethod>` +
+		references(digest) + `</ds:SignedInfo>`
+	hashed := sha256.Sum256([]byte(signedInfo))
+	signatureValue, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, hashed[:])
+	assert.NoError(t, err)
+
+	return []byte(strings.Replace(string(signed), signature, `<ds:Signature xmlns:ds="`+saml.SignatureNamespace+`">`+signedInfo+
+		`<ds:SignatureValue>`+base64.StdEncoding.EncodeToString(signatureValue)+`</ds:SignatureValue></ds:Signature>`, 1))
+}
+
+// reference returns a function writing a reference to the URI with the transforms
+func reference(uri string, transforms []string) func(digest string) string {
+	return func(digest string) string {
+		xml := `<ds:Reference URI="` + uri + `"><ds:Transforms>`
+		for _, transform := range transforms {
+			xml += `<ds:Transform Algorithm="` + transform + `"></ds:Transform>`
+		}
+		return xml + `</ds:Transforms><ds:DigestMethod Algorithm="` + saml.SHA256 + `"></ds:DigestMethod>` +
+			`<ds:DigestValue>` + digest + `</ds:DigestValue></ds:Reference>`
+	}
+}
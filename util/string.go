@@ -14,6 +14,20 @@ func StringInSlice(a string, list []string) bool {
 	return false
 }
 
+// SpaceDelimitedStringDifference returns the parts of the first string
+// not contained in the second string (when split by space)
+func SpaceDelimitedStringDifference(first, second string) []string {
+	secondParts := strings.Split(second, " ")
+
+	var difference []string
+	for _, firstPart := range strings.Fields(first) {
+		if !StringInSlice(firstPart, secondParts) && !StringInSlice(firstPart, difference) {
+			difference = append(difference, firstPart)
+		}
+	}
+	return difference
+}
+
 // SpaceDelimitedStringNotGreater returns true if the first string
 // is the same as the second string or does not contain any substring
 // not contained in the second string (when split by space)
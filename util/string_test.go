@@ -22,3 +22,15 @@ func TestSpaceDelimitedStringNotGreater(t *testing.T) {
 
 	assert.False(t, util.SpaceDelimitedStringNotGreater("foo bar qux bogus", "bar foo qux"))
 }
+
+func TestSpaceDelimitedStringDifference(t *testing.T) {
+	assert.Empty(t, util.SpaceDelimitedStringDifference("", "bar foo qux"))
+
+	assert.Empty(t, util.SpaceDelimitedStringDifference("bar foo qux", "foo bar qux"))
+
+	assert.Equal(
+		t,
+		[]string{"bogus", "other"},
+		util.SpaceDelimitedStringDifference("foo bogus bar other bogus", "bar foo qux"),
+	)
+}
@@ -0,0 +1,60 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/RichardKnop/go-oauth2-server/util/totp"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test vectors from https://tools.ietf.org/html/rfc6238#appendix-B
+// truncated to 6 digits
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	testCases := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, testCase := range testCases {
+		code, err := totp.Code(rfcSecret, time.Unix(testCase.unix, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.code, code)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	assert.NoError(t, err)
+
+	now := time.Now()
+	code, err := totp.Code(secret, now)
+	assert.NoError(t, err)
+
+	assert.True(t, totp.Validate(secret, code, now))
+	assert.True(t, totp.Validate(secret, code, now.Add(totp.Period*time.Second)))
+	assert.False(t, totp.Validate(secret, code, now.Add(3*totp.Period*time.Second)))
+	assert.False(t, totp.Validate(secret, "", now))
+	assert.False(t, totp.Validate("not base32!", code, now))
+
+	// The step is the one the code was generated for, whatever the drift
+	step, ok := totp.ValidateStep(secret, code, now.Add(totp.Period*time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, now.Unix()/totp.Period, step)
+}
+
+func TestURI(t *testing.T) {
+	assert.Equal(
+		t,
+		"otpauth://totp/Example:alice@example.com?algorithm=SHA1&digits=6&issuer=Example&period=30&secret=JBSWY3DPEHPK3PXP",
+		totp.URI("Example", "alice@example.com", "JBSWY3DPEHPK3PXP"),
+	)
+}
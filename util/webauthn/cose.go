@@ -0,0 +1,163 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+
+	"github.com/RichardKnop/go-oauth2-server/util/cbor"
+)
+
+// COSE algorithm identifiers, see https://www.iana.org/assignments/cose
+const (
+	// AlgES256 is ECDSA with P-256 and SHA-256
+	AlgES256 = -7
+	// AlgEdDSA is Ed25519
+	AlgEdDSA = -8
+	// AlgRS256 is RSASSA-PKCS1-v1_5 with SHA-256
+	AlgRS256 = -257
+)
+
+// COSE key parameters, see https://tools.ietf.org/html/rfc8152#section-13
+const (
+	coseKeyType   = 1
+	coseAlgorithm = 3
+	coseCurve     = -1
+	coseX         = -2
+	coseY         = -3
+	coseRSAN      = -1
+	coseRSAE      = -2
+
+	coseKeyTypeOKP = 1
+	coseKeyTypeEC2 = 2
+	coseKeyTypeRSA = 3
+	coseCurveP256  = 1
+	coseCurveEd    = 6
+)
+
+var (
+	// ErrUnsupportedAlgorithm ...
+	ErrUnsupportedAlgorithm = errors.New("Unsupported WebAuthn public key algorithm")
+	// ErrInvalidSignature ...
+	ErrInvalidSignature = errors.New("Invalid WebAuthn signature")
+)
+
+// SupportedAlgorithms lists the COSE algorithms of credentials
+// that can be registered, in order of preference
+var SupportedAlgorithms = []int{AlgES256, AlgEdDSA, AlgRS256}
+
+// PublicKey is the public key of a credential
+type PublicKey struct {
+	Algorithm int
+	Key       crypto.PublicKey
+}
+
+// Verify verifies the signature of the data
+func (k *PublicKey) Verify(data, signature []byte) error {
+	var valid bool
+	switch key := k.Key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		valid = ecdsa.VerifyASN1(key, digest[:], signature)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(data)
+		valid = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(key, data, signature)
+	}
+	if !valid {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// ParsePublicKey parses a COSE encoded public key of a supported algorithm
+func ParsePublicKey(coseKey []byte) (*PublicKey, error) {
+	v, err := cbor.Decode(coseKey)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, ErrMalformed
+	}
+	keyType, _ := m[int64(coseKeyType)].(int64)
+	algorithm, _ := m[int64(coseAlgorithm)].(int64)
+
+	switch {
+	case keyType == coseKeyTypeEC2 && algorithm == AlgES256:
+		curve, _ := m[int64(coseCurve)].(int64)
+		x, _ := m[int64(coseX)].([]byte)
+		y, _ := m[int64(coseY)].([]byte)
+		if curve != coseCurveP256 || len(x) != 32 || len(y) != 32 {
+			return nil, ErrMalformed
+		}
+		key := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, ErrMalformed
+		}
+		return &PublicKey{Algorithm: AlgES256, Key: key}, nil
+	case keyType == coseKeyTypeOKP && algorithm == AlgEdDSA:
+		curve, _ := m[int64(coseCurve)].(int64)
+		x, _ := m[int64(coseX)].([]byte)
+		if curve != coseCurveEd || len(x) != ed25519.PublicKeySize {
+			return nil, ErrMalformed
+		}
+		return &PublicKey{Algorithm: AlgEdDSA, Key: ed25519.PublicKey(x)}, nil
+	case keyType == coseKeyTypeRSA && algorithm == AlgRS256:
+		n, _ := m[int64(coseRSAN)].([]byte)
+		e, _ := m[int64(coseRSAE)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, ErrMalformed
+		}
+		exponent := int(new(big.Int).SetBytes(e).Int64())
+		return &PublicKey{Algorithm: AlgRS256, Key: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exponent}}, nil
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+}
+
+// EncodePublicKey returns the COSE encoding of a P-256, Ed25519 or RSA public key
+func EncodePublicKey(key crypto.PublicKey) ([]byte, error) {
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		if key.Curve != elliptic.P256() {
+			return nil, ErrUnsupportedAlgorithm
+		}
+		x, y := make([]byte, 32), make([]byte, 32)
+		key.X.FillBytes(x)
+		key.Y.FillBytes(y)
+		return cbor.Encode(map[interface{}]interface{}{
+			coseKeyType:   coseKeyTypeEC2,
+			coseAlgorithm: AlgES256,
+			coseCurve:     coseCurveP256,
+			coseX:         x,
+			coseY:         y,
+		})
+	case ed25519.PublicKey:
+		return cbor.Encode(map[interface{}]interface{}{
+			coseKeyType:   coseKeyTypeOKP,
+			coseAlgorithm: AlgEdDSA,
+			coseCurve:     coseCurveEd,
+			coseX:         []byte(key),
+		})
+	case *rsa.PublicKey:
+		return cbor.Encode(map[interface{}]interface{}{
+			coseKeyType:   coseKeyTypeRSA,
+			coseAlgorithm: AlgRS256,
+			coseRSAN:      key.N.Bytes(),
+			coseRSAE:      big.NewInt(int64(key.E)).Bytes(),
+		})
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+}
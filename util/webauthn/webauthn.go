@@ -0,0 +1,201 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/RichardKnop/go-oauth2-server/util/cbor"
+)
+
+const (
+	// TypeCreate is the client data type of registration ceremonies
+	TypeCreate = "webauthn.create"
+	// TypeGet is the client data type of authentication ceremonies
+	TypeGet = "webauthn.get"
+
+	// FlagUserPresent is set when the user touched the authenticator
+	FlagUserPresent = 0x01
+	// FlagUserVerified is set when the authenticator verified the user,
+	// for example with a PIN or biometrics
+	FlagUserVerified = 0x04
+	// FlagAttestedCredentialData is set when a new credential is included
+	FlagAttestedCredentialData = 0x40
+	// FlagExtensionData is set when extension outputs are included
+	FlagExtensionData = 0x80
+
+	// minAuthenticatorDataLength is the length of the RP ID hash, the flags and the counter
+	minAuthenticatorDataLength = 37
+)
+
+var (
+	// ErrMalformed ...
+	ErrMalformed = errors.New("Malformed WebAuthn data")
+	// ErrTypeMismatch ...
+	ErrTypeMismatch = errors.New("Unexpected WebAuthn client data type")
+	// ErrOriginMismatch ...
+	ErrOriginMismatch = errors.New("WebAuthn origin not allowed")
+	// ErrRPIDMismatch ...
+	ErrRPIDMismatch = errors.New("WebAuthn relying party ID mismatch")
+	// ErrUnsupportedAttestation ...
+	ErrUnsupportedAttestation = errors.New("Unsupported WebAuthn attestation format")
+	// ErrCredentialMissing ...
+	ErrCredentialMissing = errors.New("WebAuthn attestation has no credential")
+)
+
+// AuthenticatorData is the data signed by the authenticator,
+// see https://www.w3.org/TR/webauthn-2/#sctn-authenticator-data
+type AuthenticatorData struct {
+	RPIDHash  []byte
+	Flags     byte
+	SignCount uint32
+	// The attested credential data is only present when registering,
+	// PublicKey is the COSE encoded public key of the credential
+	AAGUID       []byte
+	CredentialID []byte
+	PublicKey    []byte
+}
+
+// UserPresent returns true if the user touched the authenticator
+func (ad *AuthenticatorData) UserPresent() bool {
+	return ad.Flags&FlagUserPresent != 0
+}
+
+// UserVerified returns true if the authenticator verified the user
+func (ad *AuthenticatorData) UserVerified() bool {
+	return ad.Flags&FlagUserVerified != 0
+}
+
+// CheckRPID checks the authenticator data was produced for the relying party
+func (ad *AuthenticatorData) CheckRPID(rpID string) error {
+	hash := sha256.Sum256([]byte(rpID))
+	if !bytes.Equal(hash[:], ad.RPIDHash) {
+		return ErrRPIDMismatch
+	}
+	return nil
+}
+
+// ParseAuthenticatorData parses the binary authenticator data
+func ParseAuthenticatorData(data []byte) (*AuthenticatorData, error) {
+	if len(data) < minAuthenticatorDataLength {
+		return nil, ErrMalformed
+	}
+	ad := &AuthenticatorData{
+		RPIDHash:  data[:32],
+		Flags:     data[32],
+		SignCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	rest := data[minAuthenticatorDataLength:]
+
+	if ad.Flags&FlagAttestedCredentialData != 0 {
+		// The AAGUID is followed by the length of the credential ID
+		if len(rest) < 18 {
+			return nil, ErrMalformed
+		}
+		ad.AAGUID = rest[:16]
+		length := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if len(rest) < length {
+			return nil, ErrMalformed
+		}
+		ad.CredentialID = rest[:length]
+		rest = rest[length:]
+
+		// The public key is the CBOR item that follows
+		_, after, err := cbor.DecodeFirst(rest)
+		if err != nil {
+			return nil, ErrMalformed
+		}
+		ad.PublicKey = rest[:len(rest)-len(after)]
+		rest = after
+	}
+
+	// Extension outputs are not used
+	if ad.Flags&FlagExtensionData != 0 {
+		_, after, err := cbor.DecodeFirst(rest)
+		if err != nil {
+			return nil, ErrMalformed
+		}
+		rest = after
+	}
+
+	if len(rest) > 0 {
+		return nil, ErrMalformed
+	}
+	return ad, nil
+}
+
+// ParseAttestationObject returns the authenticator data of a new credential.
+// Only the none attestation format is supported, relying parties asking for
+// attestation "none" are sent it by all authenticators, passkeys included.
+func ParseAttestationObject(attestationObject []byte) (*AuthenticatorData, error) {
+	v, err := cbor.Decode(attestationObject)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, ErrMalformed
+	}
+	format, _ := m["fmt"].(string)
+	statement, _ := m["attStmt"].(map[interface{}]interface{})
+	authData, ok := m["authData"].([]byte)
+	if !ok {
+		return nil, ErrMalformed
+	}
+	if format != "none" || len(statement) > 0 {
+		return nil, ErrUnsupportedAttestation
+	}
+
+	ad, err := ParseAuthenticatorData(authData)
+	if err != nil {
+		return nil, err
+	}
+	if ad.CredentialID == nil {
+		return nil, ErrCredentialMissing
+	}
+	return ad, nil
+}
+
+// ClientData is the data the browser passed to the authenticator,
+// see https://www.w3.org/TR/webauthn-2/#dictionary-client-data
+type ClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin,omitempty"`
+}
+
+// ParseClientData parses the client data JSON and checks its type and
+// origin, the caller checks the challenge is one it issued
+func ParseClientData(clientDataJSON []byte, clientDataType string, origins []string) (*ClientData, error) {
+	clientData := new(ClientData)
+	if err := json.Unmarshal(clientDataJSON, clientData); err != nil {
+		return nil, ErrMalformed
+	}
+	if clientData.Type != clientDataType {
+		return nil, ErrTypeMismatch
+	}
+	if !util.StringInSlice(clientData.Origin, origins) || clientData.CrossOrigin {
+		return nil, ErrOriginMismatch
+	}
+	if clientData.Challenge == "" {
+		return nil, ErrMalformed
+	}
+	return clientData, nil
+}
+
+// VerifyAssertion verifies the signature of an assertion with the COSE
+// encoded public key of the credential
+func VerifyAssertion(publicKey, authenticatorData, clientDataJSON, signature []byte) error {
+	key, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte(nil), authenticatorData...), clientDataHash[:]...)
+	return key.Verify(signed, signature)
+}
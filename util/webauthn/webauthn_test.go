@@ -0,0 +1,147 @@
+package webauthn_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+
+	"github.com/RichardKnop/go-oauth2-server/util/cbor"
+	"github.com/RichardKnop/go-oauth2-server/util/webauthn"
+	"github.com/stretchr/testify/assert"
+)
+
+var origins = []string{"https://example.com"}
+
+func authenticatorData(rpID string, flags byte, signCount uint32, credentialID, publicKey []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append(rpIDHash[:], flags, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(data[33:], signCount)
+	if credentialID != nil {
+		data = append(data, make([]byte, 16)...)
+		data = append(data, byte(len(credentialID)>>8), byte(len(credentialID)))
+		data = append(data, credentialID...)
+		data = append(data, publicKey...)
+	}
+	return data
+}
+
+func TestParseAttestationObject(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	publicKey, err := webauthn.EncodePublicKey(&privateKey.PublicKey)
+	assert.NoError(t, err)
+
+	flags := byte(webauthn.FlagUserPresent | webauthn.FlagUserVerified | webauthn.FlagAttestedCredentialData)
+	authData := authenticatorData("example.com", flags, 0, []byte("credential"), publicKey)
+	attestationObject, err := cbor.Encode(map[interface{}]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[interface{}]interface{}{},
+		"authData": authData,
+	})
+	assert.NoError(t, err)
+
+	ad, err := webauthn.ParseAttestationObject(attestationObject)
+	if assert.NoError(t, err) {
+		assert.NoError(t, ad.CheckRPID("example.com"))
+		assert.Equal(t, webauthn.ErrRPIDMismatch, ad.CheckRPID("evil.com"))
+		assert.True(t, ad.UserPresent())
+		assert.True(t, ad.UserVerified())
+		assert.Equal(t, []byte("credential"), ad.CredentialID)
+		assert.Equal(t, publicKey, ad.PublicKey)
+	}
+
+	// Attestation statements are not verified so they are refused
+	attestationObject, err = cbor.Encode(map[interface{}]interface{}{
+		"fmt":      "packed",
+		"attStmt":  map[interface{}]interface{}{"alg": -7},
+		"authData": authData,
+	})
+	assert.NoError(t, err)
+	_, err = webauthn.ParseAttestationObject(attestationObject)
+	assert.Equal(t, webauthn.ErrUnsupportedAttestation, err)
+
+	// Truncated authenticator data is malformed
+	attestationObject, err = cbor.Encode(map[interface{}]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[interface{}]interface{}{},
+		"authData": authData[:len(authData)-1],
+	})
+	assert.NoError(t, err)
+	_, err = webauthn.ParseAttestationObject(attestationObject)
+	assert.Equal(t, webauthn.ErrMalformed, err)
+}
+
+func TestParseClientData(t *testing.T) {
+	clientData, err := webauthn.ParseClientData(
+		[]byte(`{"type":"webauthn.get","challenge":"Y2hhbGxlbmdl","origin":"https://example.com"}`),
+		webauthn.TypeGet,
+		origins,
+	)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Y2hhbGxlbmdl", clientData.Challenge)
+	}
+
+	testCases := []struct {
+		clientDataJSON string
+		err            error
+	}{
+		{`{"type":"webauthn.create","challenge":"Y2hhbGxlbmdl","origin":"https://example.com"}`, webauthn.ErrTypeMismatch},
+		{`{"type":"webauthn.get","challenge":"Y2hhbGxlbmdl","origin":"https://evil.com"}`, webauthn.ErrOriginMismatch},
+		{`{"type":"webauthn.get","challenge":"Y2hhbGxlbmdl","origin":"https://example.com","crossOrigin":true}`, webauthn.ErrOriginMismatch},
+		{`{"type":"webauthn.get","origin":"https://example.com"}`, webauthn.ErrMalformed},
+		{`bogus`, webauthn.ErrMalformed},
+	}
+	for _, testCase := range testCases {
+		_, err := webauthn.ParseClientData([]byte(testCase.clientDataJSON), webauthn.TypeGet, origins)
+		assert.Equal(t, testCase.err, err, testCase.clientDataJSON)
+	}
+}
+
+func TestVerifyAssertion(t *testing.T) {
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	authData := authenticatorData("example.com", webauthn.FlagUserPresent, 1, nil, nil)
+	clientDataJSON := []byte(`{"type":"webauthn.get","challenge":"Y2hhbGxlbmdl","origin":"https://example.com"}`)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte(nil), authData...), clientDataHash[:]...)
+	digest := sha256.Sum256(signed)
+
+	for _, signer := range []crypto.Signer{ecdsaKey, ed25519Key, rsaKey} {
+		publicKey, err := webauthn.EncodePublicKey(signer.Public())
+		if !assert.NoError(t, err) {
+			continue
+		}
+		var signature []byte
+		if _, ok := signer.(ed25519.PrivateKey); ok {
+			signature, err = signer.Sign(rand.Reader, signed, crypto.Hash(0))
+		} else {
+			signature, err = signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+		}
+		assert.NoError(t, err)
+
+		assert.NoError(t, webauthn.VerifyAssertion(publicKey, authData, clientDataJSON, signature))
+		assert.Equal(
+			t,
+			webauthn.ErrInvalidSignature,
+			webauthn.VerifyAssertion(publicKey, authData, []byte(`{}`), signature),
+		)
+	}
+}
+
+func TestParsePublicKeyUnsupported(t *testing.T) {
+	coseKey, err := cbor.Encode(map[interface{}]interface{}{1: 2, 3: -35})
+	assert.NoError(t, err)
+	_, err = webauthn.ParsePublicKey(coseKey)
+	assert.Equal(t, webauthn.ErrUnsupportedAlgorithm, err)
+}
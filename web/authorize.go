@@ -6,30 +6,51 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
 	"github.com/RichardKnop/go-oauth2-server/session"
+	"github.com/RichardKnop/go-oauth2-server/util"
 )
 
-// ErrIncorrectResponseType a form value for response_type was not set to token or code
-var ErrIncorrectResponseType = errors.New("Response type not one of token or code")
+var (
+	// ErrIncorrectResponseType a form value for response_type was not set to a supported response type
+	ErrIncorrectResponseType = errors.New("Response type not supported")
+	// ErrImplicitGrantDisabled response_type included token but the implicit grant is not enabled
+	ErrImplicitGrantDisabled = errors.New("Implicit grant is disabled")
+)
 
 func (s *Service) authorizeForm(w http.ResponseWriter, r *http.Request) {
-	sessionService, client, _, responseType, _, err := s.authorizeCommon(r)
+	sessionService, client, user, responseType, redirectURI, err := s.authorizeCommon(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	// Skip the form for first-party clients or if the user has already approved
+	// the requested scope, unless the client asks for the consent screen with
+	// prompt=consent. Authorization details always need to be approved by the user.
+	authorizationDetails := r.Form.Get("authorization_details")
+	if !util.StringInSlice("consent", strings.Fields(r.Form.Get("prompt"))) && authorizationDetails == "" {
+		scope, err := s.oauthService.GetScope(r.Form.Get("scope"))
+		if err == nil && (client.FirstParty || s.oauthService.HasConsent(client, user, scope)) {
+			lifetime := oauth.ClientAccessTokenLifetime(client, s.cnf.Oauth.AccessTokenLifetime)
+			s.grant(w, r, client, user, responseType, redirectURI, scope, "", lifetime)
+			return
+		}
+	}
+
 	// Render the template
 	errMsg, _ := sessionService.GetFlashMessage()
 	query := r.URL.Query()
 	query.Set("login_redirect_uri", r.URL.Path)
 	renderTemplate(w, "authorize.html", map[string]interface{}{
-		"error":       errMsg,
-		"clientID":    client.Key,
-		"queryString": getQueryString(query),
-		"token":       responseType == "token",
+		"error":                errMsg,
+		"clientID":             client.Key,
+		"queryString":          getQueryString(query),
+		"token":                oauth.ResponseTypeIncludes(responseType, "token"),
+		"authorizationDetails": authorizationDetails,
 	})
 }
 
@@ -46,6 +67,10 @@ func (s *Service) authorize(w http.ResponseWriter, r *http.Request) {
 	// Has the resource owner or authorization server denied the request?
 	authorized := len(r.Form.Get("allow")) > 0
 	if !authorized {
+		if err := s.deletePushedRequest(r, client); err != nil {
+			errorRedirect(w, r, redirectURI, "server_error", state, responseType)
+			return
+		}
 		errorRedirect(w, r, redirectURI, "access_denied", state, responseType)
 		return
 	}
@@ -57,43 +82,105 @@ func (s *Service) authorize(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query := redirectURI.Query()
+	// Check the requested authorization details, they can only be
+	// granted with an authorization code
+	authorizationDetails, err := oauth.ParseAuthorizationDetails(
+		r.Form.Get("authorization_details"),
+		s.cnf.Oauth.AuthorizationDetailsTypes,
+	)
+	if err == nil && authorizationDetails != "" && oauth.ResponseTypeIncludes(responseType, "token") {
+		err = oauth.ErrInvalidAuthorizationDetails
+	}
+	if err != nil {
+		errorRedirect(w, r, redirectURI, "invalid_authorization_details", state, responseType)
+		return
+	}
 
-	// When response_type == "code", we will grant an authorization code
-	if responseType == "code" {
-		// Create a new authorization code
-		authorizationCode, err := s.oauthService.GrantAuthorizationCode(
-			client,                       // client
-			user,                         // user
-			s.cnf.Oauth.AuthCodeLifetime, // expires in
-			redirectURI.String(),         // redirect URI
-			scope,                        // scope
-		)
+	// Get access token lifetime from user input
+	var lifetime int
+	if oauth.ResponseTypeIncludes(responseType, "token") {
+		lifetime, err = strconv.Atoi(r.Form.Get("lifetime"))
 		if err != nil {
 			errorRedirect(w, r, redirectURI, "server_error", state, responseType)
 			return
 		}
 
-		// Set query string params for the redirection URL
-		query.Set("code", authorizationCode.Code)
-		// Add state param if present (recommended)
-		if state != "" {
-			query.Set("state", state)
-		}
-		// And we're done here, redirect
-		redirectWithQueryString(redirectURI.String(), query, w, r)
+		// Unless the client has its own token lifetime
+		lifetime = oauth.ClientAccessTokenLifetime(client, lifetime)
+	}
+
+	// Remember the approval so the form can be skipped next time
+	if err := s.oauthService.SaveConsent(client, user, scope); err != nil {
+		errorRedirect(w, r, redirectURI, "server_error", state, responseType)
+		return
+	}
+
+	s.grant(w, r, client, user, responseType, redirectURI, scope, authorizationDetails, lifetime)
+}
+
+// grant redirects back to the client with an authorization code, an access
+// token, an ID token or a combination of them for the approved scope
+// and authorization details
+func (s *Service) grant(w http.ResponseWriter, r *http.Request, client *models.OauthClient, user *models.OauthUser, responseType string, redirectURI *url.URL, scope, authorizationDetails string, lifetime int) {
+	state := r.Form.Get("state")
+	query := redirectURI.Query()
+
+	// An ID token needs the openid scope, and a nonce to prevent replays
+	withIDToken := oauth.ResponseTypeIncludes(responseType, "id_token")
+	if withIDToken && !util.StringInSlice(oauth.OpenIDScope, strings.Fields(scope)) {
+		errorRedirect(w, r, redirectURI, "invalid_scope", state, responseType)
+		return
+	}
+	if withIDToken && r.Form.Get("nonce") == "" {
+		errorRedirect(w, r, redirectURI, "invalid_request", state, responseType)
+		return
+	}
+
+	// Public clients cannot authenticate so their codes must be bound to PKCE
+	withCode := oauth.ResponseTypeIncludes(responseType, "code")
+	if withCode && oauth.IsPublicClient(client) && r.Form.Get("code_challenge") == "" {
+		errorRedirect(w, r, redirectURI, "invalid_request", state, responseType)
+		return
+	}
+
+	// The request URI of a pushed request can only be used once
+	if err := s.deletePushedRequest(r, client); err != nil {
+		errorRedirect(w, r, redirectURI, "server_error", state, responseType)
 		return
 	}
 
-	// When response_type == "token", we will directly grant an access token
-	if responseType == "token" {
-		// Get access token lifetime from user input
-		lifetime, err := strconv.Atoi(r.Form.Get("lifetime"))
+	// When the response type includes code, we will grant an authorization code
+	var code, token string
+	if oauth.ResponseTypeIncludes(responseType, "code") {
+		// Create a new authorization code
+		authorizationCode, err := s.oauthService.GrantAuthorizationCode(
+			client,                              // client
+			user,                                // user
+			s.cnf.Oauth.AuthCodeLifetime,        // expires in
+			redirectURI.String(),                // redirect URI
+			scope,                               // scope
+			r.Form.Get("code_challenge"),        // code challenge
+			r.Form.Get("code_challenge_method"), // code challenge method
+			authorizationDetails,                // authorization details
+			r.Form.Get("nonce"),                 // nonce
+		)
+		if err == oauth.ErrInvalidCodeChallenge || err == oauth.ErrInvalidCodeChallengeMethod {
+			errorRedirect(w, r, redirectURI, "invalid_request", state, responseType)
+			return
+		}
 		if err != nil {
 			errorRedirect(w, r, redirectURI, "server_error", state, responseType)
 			return
 		}
 
+		// Set query string params for the redirection URL
+		code = authorizationCode.Code
+		query.Set("code", code)
+	}
+
+	// When the response type includes token, we will directly grant an access
+	// token, but never a refresh token as it would be exposed in the browser
+	if oauth.ResponseTypeIncludes(responseType, "token") {
 		// Grant an access token
 		accessToken, err := s.oauthService.GrantAccessToken(
 			client,   // client
@@ -107,17 +194,34 @@ func (s *Service) authorize(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Set query string params for the redirection URL
-		query.Set("access_token", accessToken.Token)
-		query.Set("expires_in", fmt.Sprintf("%d", s.cnf.Oauth.AccessTokenLifetime))
+		token, err = s.oauthService.EncodeAccessToken(accessToken)
+		if err != nil {
+			errorRedirect(w, r, redirectURI, "server_error", state, responseType)
+			return
+		}
+		query.Set("access_token", token)
+		query.Set("expires_in", fmt.Sprintf("%d", lifetime))
 		query.Set("token_type", "Bearer")
 		query.Set("scope", scope)
-		// Add state param if present (recommended)
-		if state != "" {
-			query.Set("state", state)
+	}
+
+	// When the response type includes id_token, we will grant an ID token
+	// bound to the code and access token returned alongside it
+	if withIDToken {
+		idToken, err := s.oauthService.GrantFrontChannelIDToken(client, user, r.Form.Get("nonce"), code, token)
+		if err != nil {
+			errorRedirect(w, r, redirectURI, "server_error", state, responseType)
+			return
 		}
-		// And we're done here, redirect
-		redirectWithFragment(redirectURI.String(), query, w, r)
+		query.Set("id_token", idToken)
+	}
+
+	// Add state param if present (recommended)
+	if state != "" {
+		query.Set("state", state)
 	}
+	// And we're done here, redirect
+	redirectWithResponseMode(redirectURI.String(), query, responseType, w, r)
 }
 
 func (s *Service) authorizeCommon(r *http.Request) (session.ServiceInterface, *models.OauthClient, *models.OauthUser, string, *url.URL, error) {
@@ -133,6 +237,11 @@ func (s *Service) authorizeCommon(r *http.Request) (session.ServiceInterface, *m
 		return nil, nil, nil, "", nil, err
 	}
 
+	// Use the parameters the client pushed, if any
+	if err := s.usePushedRequest(r, client); err != nil {
+		return nil, nil, nil, "", nil, err
+	}
+
 	// Get the user session
 	userSession, err := sessionService.GetUserSession()
 	if err != nil {
@@ -147,18 +256,45 @@ func (s *Service) authorizeCommon(r *http.Request) (session.ServiceInterface, *m
 		return nil, nil, nil, "", nil, err
 	}
 
-	// Check the response_type is either "code" or "token"
-	responseType := r.Form.Get("response_type")
-	if responseType != "code" && responseType != "token" {
+	// Check the response_type is supported, e.g. "code" or "token"
+	responseType, err := oauth.ParseResponseType(r.Form.Get("response_type"))
+	if err != nil {
 		return nil, nil, nil, "", nil, ErrIncorrectResponseType
 	}
 
+	// The implicit grant must be enabled explicitly
+	implicitDisabled := !s.cnf.Oauth.EnableImplicitGrant ||
+		util.StringInSlice("implicit", s.cnf.Oauth.DisabledGrantTypes)
+	if oauth.ResponseTypeIncludes(responseType, "token") && implicitDisabled {
+		return nil, nil, nil, "", nil, ErrImplicitGrantDisabled
+	}
+
+	// The client may be registered for some grant and response types only
+	for _, grantType := range oauth.ResponseTypeGrantTypes(responseType) {
+		if !oauth.ClientAllowsGrantType(client, grantType) {
+			return nil, nil, nil, "", nil, oauth.ErrUnauthorizedClient
+		}
+	}
+	if !oauth.ClientAllowsResponseType(client, responseType) {
+		return nil, nil, nil, "", nil, oauth.ErrResponseTypeNotAllowed
+	}
+
+	// A second factor cannot be collected in the browser flow yet
+	if util.StringInSlice(oauth.AcrMFA, strings.Fields(r.Form.Get("acr_values"))) {
+		return nil, nil, nil, "", nil, oauth.ErrAcrNotSatisfiable
+	}
+
 	// Fallback to the client redirect URI if not in query string
 	redirectURI := r.Form.Get("redirect_uri")
 	if redirectURI == "" {
 		redirectURI = client.RedirectURI.String
 	}
 
+	// The redirect URI must match the one registered for the client
+	if err := s.oauthService.ValidateRedirectURI(client, redirectURI); err != nil {
+		return nil, nil, nil, "", nil, err
+	}
+
 	// // Parse the redirect URL
 	parsedRedirectURI, err := url.ParseRequestURI(redirectURI)
 	if err != nil {
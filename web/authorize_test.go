@@ -0,0 +1,391 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/log"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/mocks"
+	"github.com/RichardKnop/go-oauth2-server/session"
+	"github.com/RichardKnop/go-oauth2-server/util"
+	"github.com/gorilla/context"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	if err := os.Chdir("../"); err != nil {
+		log.ERROR.Fatal(err)
+	}
+}
+
+func TestAuthorizeImplicitGrantDisabled(t *testing.T) {
+	cnf := &config.Config{}
+	oauthService := new(mocks.ServiceInterface)
+	user := &models.OauthUser{Username: "test@user"}
+	oauthService.On("FindUserByUsername", "test@user").Return(user, nil)
+	s := NewService(cnf, oauthService, nil)
+
+	w := httptest.NewRecorder()
+	s.authorize(w, newAuthorizeRequest("token"))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, ErrImplicitGrantDisabled.Error(), strings.TrimSpace(w.Body.String()))
+}
+
+func TestAuthorizeClientNotAllowedGrantType(t *testing.T) {
+	cnf := &config.Config{}
+	oauthService := new(mocks.ServiceInterface)
+	user := &models.OauthUser{Username: "test@user"}
+	oauthService.On("FindUserByUsername", "test@user").Return(user, nil)
+	s := NewService(cnf, oauthService, nil)
+
+	r := newAuthorizeRequest("code")
+	context.Set(r, clientKey, &models.OauthClient{
+		Key:               "test_client_1",
+		RedirectURI:       util.StringOrNull("https://www.example.com"),
+		AllowedGrantTypes: "client_credentials",
+	})
+	w := httptest.NewRecorder()
+	s.authorize(w, r)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, oauth.ErrUnauthorizedClient.Error(), strings.TrimSpace(w.Body.String()))
+}
+
+func TestAuthorizeImplicitGrant(t *testing.T) {
+	cnf := &config.Config{}
+	cnf.Oauth.EnableImplicitGrant = true
+	cnf.Oauth.AccessTokenLifetime = 3600
+	oauthService := new(mocks.ServiceInterface)
+	user := &models.OauthUser{Username: "test@user"}
+	oauthService.On("FindUserByUsername", "test@user").Return(user, nil)
+	oauthService.On("ValidateRedirectURI", testClient, "https://www.example.com").Return(nil)
+	oauthService.On("GetScope", "read").Return("read", nil)
+	oauthService.On("SaveConsent", testClient, user, "read").Return(nil)
+	oauthService.On("GrantAccessToken", testClient, user, 3600, "read").
+		Return(&models.OauthAccessToken{Token: "test_token"}, nil)
+	oauthService.On("EncodeAccessToken", &models.OauthAccessToken{Token: "test_token"}).
+		Return("test_token", nil)
+	s := NewService(cnf, oauthService, nil)
+
+	w := httptest.NewRecorder()
+	s.authorize(w, newAuthorizeRequest("token"))
+
+	// The access token is returned in the fragment
+	assert.Equal(t, http.StatusFound, w.Code)
+	location, err := url.Parse(w.Header().Get("Location"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Empty(t, location.RawQuery)
+	fragment, err := url.ParseQuery(location.Fragment)
+	assert.NoError(t, err)
+	assert.Equal(t, "test_token", fragment.Get("access_token"))
+	assert.Equal(t, "Bearer", fragment.Get("token_type"))
+	assert.Equal(t, "test_state", fragment.Get("state"))
+
+	// But never a refresh token
+	_, ok := fragment["refresh_token"]
+	assert.False(t, ok)
+	oauthService.AssertNotCalled(t, "CreateRefreshToken")
+}
+
+func TestAuthorizeHybridResponseType(t *testing.T) {
+	cnf := &config.Config{}
+	cnf.Oauth.EnableImplicitGrant = true
+	oauthService := new(mocks.ServiceInterface)
+	user := &models.OauthUser{Username: "test@user"}
+	oauthService.On("FindUserByUsername", "test@user").Return(user, nil)
+	oauthService.On("ValidateRedirectURI", testClient, "https://www.example.com").Return(nil)
+	oauthService.On("GetScope", "openid").Return("openid", nil)
+	oauthService.On("SaveConsent", testClient, user, "openid").Return(nil)
+	oauthService.On("GrantAuthorizationCode", testClient, user, 0, "https://www.example.com", "openid", "", "", "", "test_nonce").
+		Return(&models.OauthAuthorizationCode{Code: "test_code"}, nil)
+	oauthService.On("GrantAccessToken", testClient, user, 3600, "openid").
+		Return(&models.OauthAccessToken{Token: "test_token"}, nil)
+	oauthService.On("EncodeAccessToken", &models.OauthAccessToken{Token: "test_token"}).
+		Return("test_token", nil)
+	oauthService.On("GrantFrontChannelIDToken", testClient, user, "test_nonce", "test_code", "test_token").
+		Return("test_id_token", nil)
+	s := NewService(cnf, oauthService, nil)
+
+	r := newAuthorizeRequest("id_token token code")
+	r.Form.Set("scope", "openid")
+	r.Form.Set("nonce", "test_nonce")
+	w := httptest.NewRecorder()
+	s.authorize(w, r)
+
+	// Everything is returned in the fragment
+	assert.Equal(t, http.StatusFound, w.Code)
+	location, err := url.Parse(w.Header().Get("Location"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Empty(t, location.RawQuery)
+	fragment, err := url.ParseQuery(location.Fragment)
+	assert.NoError(t, err)
+	assert.Equal(t, "test_code", fragment.Get("code"))
+	assert.Equal(t, "test_token", fragment.Get("access_token"))
+	assert.Equal(t, "test_id_token", fragment.Get("id_token"))
+	assert.Equal(t, "test_state", fragment.Get("state"))
+}
+
+func TestAuthorizeHybridResponseTypeRequiresNonce(t *testing.T) {
+	cnf := &config.Config{}
+	oauthService := new(mocks.ServiceInterface)
+	user := &models.OauthUser{Username: "test@user"}
+	oauthService.On("FindUserByUsername", "test@user").Return(user, nil)
+	oauthService.On("ValidateRedirectURI", testClient, "https://www.example.com").Return(nil)
+	oauthService.On("GetScope", "openid").Return("openid", nil)
+	oauthService.On("SaveConsent", testClient, user, "openid").Return(nil)
+	s := NewService(cnf, oauthService, nil)
+
+	r := newAuthorizeRequest("code id_token")
+	r.Form.Set("scope", "openid")
+	w := httptest.NewRecorder()
+	s.authorize(w, r)
+
+	// The error is returned in the fragment
+	assert.Equal(t, http.StatusFound, w.Code)
+	location, err := url.Parse(w.Header().Get("Location"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	fragment, err := url.ParseQuery(location.Fragment)
+	assert.NoError(t, err)
+	assert.Equal(t, "invalid_request", fragment.Get("error"))
+	oauthService.AssertNotCalled(t, "GrantAuthorizationCode")
+}
+
+func TestAuthorizeClientNotAllowedResponseType(t *testing.T) {
+	cnf := &config.Config{}
+	oauthService := new(mocks.ServiceInterface)
+	user := &models.OauthUser{Username: "test@user"}
+	oauthService.On("FindUserByUsername", "test@user").Return(user, nil)
+	s := NewService(cnf, oauthService, nil)
+
+	r := newAuthorizeRequest("code id_token")
+	context.Set(r, clientKey, &models.OauthClient{
+		Key:                  "test_client_1",
+		RedirectURI:          util.StringOrNull("https://www.example.com"),
+		AllowedResponseTypes: "code",
+	})
+	w := httptest.NewRecorder()
+	s.authorize(w, r)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, oauth.ErrResponseTypeNotAllowed.Error(), strings.TrimSpace(w.Body.String()))
+}
+
+func TestAuthorizeAuthorizationDetails(t *testing.T) {
+	details := `[{"type":"payment_initiation"}]`
+	cnf := &config.Config{}
+	cnf.Oauth.AuthorizationDetailsTypes = []string{"payment_initiation"}
+	oauthService := new(mocks.ServiceInterface)
+	user := &models.OauthUser{Username: "test@user"}
+	oauthService.On("FindUserByUsername", "test@user").Return(user, nil)
+	oauthService.On("ValidateRedirectURI", testClient, "https://www.example.com").Return(nil)
+	oauthService.On("GetScope", "read").Return("read", nil)
+	oauthService.On("SaveConsent", testClient, user, "read").Return(nil)
+	oauthService.On("GrantAuthorizationCode", testClient, user, 0, "https://www.example.com", "read", "", "", details, "").
+		Return(&models.OauthAuthorizationCode{Code: "test_code"}, nil)
+	s := NewService(cnf, oauthService, nil)
+
+	for _, testCase := range []struct {
+		authorizationDetails string
+		query                url.Values
+	}{
+		{details, url.Values{"code": {"test_code"}, "state": {"test_state"}}},
+		{`[{"type":"bogus"}]`, url.Values{"error": {"invalid_authorization_details"}, "state": {"test_state"}}},
+	} {
+		r := newAuthorizeRequest("code")
+		r.Form.Set("authorization_details", testCase.authorizationDetails)
+		w := httptest.NewRecorder()
+		s.authorize(w, r)
+
+		assert.Equal(t, http.StatusFound, w.Code)
+		location, err := url.Parse(w.Header().Get("Location"))
+		if assert.NoError(t, err) {
+			assert.Equal(t, testCase.query, location.Query())
+		}
+	}
+}
+
+func TestAuthorizeFormReusesStoredConsent(t *testing.T) {
+	cnf := &config.Config{}
+	oauthService := new(mocks.ServiceInterface)
+	user := &models.OauthUser{Username: "test@user"}
+	oauthService.On("FindUserByUsername", "test@user").Return(user, nil)
+	oauthService.On("ValidateRedirectURI", testClient, "https://www.example.com").Return(nil)
+	oauthService.On("GetScope", "read").Return("read", nil)
+	oauthService.On("HasConsent", testClient, user, "read").Return(true)
+	oauthService.On("GrantAuthorizationCode", testClient, user, 0, "https://www.example.com", "read", "", "", "", "").
+		Return(&models.OauthAuthorizationCode{Code: "test_code"}, nil)
+	s := NewService(cnf, oauthService, nil)
+
+	w := httptest.NewRecorder()
+	s.authorizeForm(w, newAuthorizeRequest("code"))
+
+	// The form is skipped and the code returned straight away
+	assert.Equal(t, http.StatusFound, w.Code)
+	location, err := url.Parse(w.Header().Get("Location"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "test_code", location.Query().Get("code"))
+		assert.Equal(t, "test_state", location.Query().Get("state"))
+	}
+}
+
+func TestAuthorizeFormFirstPartyClient(t *testing.T) {
+	cnf := &config.Config{}
+	oauthService := new(mocks.ServiceInterface)
+	user := &models.OauthUser{Username: "test@user"}
+	firstPartyClient := &models.OauthClient{
+		Key:         "test_client_1",
+		RedirectURI: util.StringOrNull("https://www.example.com"),
+		FirstParty:  true,
+	}
+	oauthService.On("FindUserByUsername", "test@user").Return(user, nil)
+	oauthService.On("ValidateRedirectURI", firstPartyClient, "https://www.example.com").Return(nil)
+	oauthService.On("GetScope", "read").Return("read", nil)
+	oauthService.On("GrantAuthorizationCode", firstPartyClient, user, 0, "https://www.example.com", "read", "", "", "", "").
+		Return(&models.OauthAuthorizationCode{Code: "test_code"}, nil)
+	s := NewService(cnf, oauthService, nil)
+
+	r := newAuthorizeRequest("code")
+	context.Set(r, clientKey, firstPartyClient)
+	w := httptest.NewRecorder()
+	s.authorizeForm(w, r)
+
+	// Users are not asked to consent to our own apps
+	assert.Equal(t, http.StatusFound, w.Code)
+	location, err := url.Parse(w.Header().Get("Location"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "test_code", location.Query().Get("code"))
+	}
+	oauthService.AssertNotCalled(t, "HasConsent", firstPartyClient, user, "read")
+}
+
+func TestAuthorizeFormPromptConsent(t *testing.T) {
+	cnf := &config.Config{}
+	oauthService := new(mocks.ServiceInterface)
+	user := &models.OauthUser{Username: "test@user"}
+	oauthService.On("FindUserByUsername", "test@user").Return(user, nil)
+	oauthService.On("ValidateRedirectURI", testClient, "https://www.example.com").Return(nil)
+	oauthService.On("GetScope", "read").Return("read", nil)
+	oauthService.On("HasConsent", testClient, user, "read").Return(true)
+	s := NewService(cnf, oauthService, nil)
+
+	r := newAuthorizeRequest("code")
+	r.Form.Set("prompt", "consent")
+	w := httptest.NewRecorder()
+	s.authorizeForm(w, r)
+
+	// The stored consent is ignored and the form rendered again
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "test_client_1")
+	oauthService.AssertNotCalled(t, "HasConsent", testClient, user, "read")
+	oauthService.AssertNotCalled(t, "GrantAuthorizationCode", testClient, user, 0, "https://www.example.com", "read", "", "", "", "")
+}
+
+func TestAuthorizeInvalidCodeChallengeMethod(t *testing.T) {
+	cnf := &config.Config{}
+	oauthService := new(mocks.ServiceInterface)
+	user := &models.OauthUser{Username: "test@user"}
+	oauthService.On("FindUserByUsername", "test@user").Return(user, nil)
+	oauthService.On("ValidateRedirectURI", testClient, "https://www.example.com").Return(nil)
+	oauthService.On("GetScope", "read").Return("read", nil)
+	oauthService.On("HasConsent", testClient, user, "read").Return(true)
+	oauthService.On("GrantAuthorizationCode", testClient, user, 0, "https://www.example.com", "read", "test_challenge", "S512", "", "").
+		Return(nil, oauth.ErrInvalidCodeChallengeMethod)
+	s := NewService(cnf, oauthService, nil)
+
+	r := newAuthorizeRequest("code")
+	r.Form.Set("code_challenge", "test_challenge")
+	r.Form.Set("code_challenge_method", "S512")
+	w := httptest.NewRecorder()
+	s.authorizeForm(w, r)
+
+	// The client is told its request was invalid
+	assert.Equal(t, http.StatusFound, w.Code)
+	location, err := url.Parse(w.Header().Get("Location"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "invalid_request", location.Query().Get("error"))
+		assert.Equal(t, "test_state", location.Query().Get("state"))
+	}
+}
+
+func TestAuthorizePublicClientWithoutPKCE(t *testing.T) {
+	cnf := &config.Config{}
+	oauthService := new(mocks.ServiceInterface)
+	user := &models.OauthUser{Username: "test@user"}
+	publicClient := &models.OauthClient{
+		Key:         "test_client_1",
+		RedirectURI: util.StringOrNull("https://www.example.com"),
+		ClientType:  oauth.ClientTypePublic,
+	}
+	oauthService.On("FindUserByUsername", "test@user").Return(user, nil)
+	oauthService.On("ValidateRedirectURI", publicClient, "https://www.example.com").Return(nil)
+	oauthService.On("GetScope", "read").Return("read", nil)
+	oauthService.On("HasConsent", publicClient, user, "read").Return(true)
+	s := NewService(cnf, oauthService, nil)
+
+	r := newAuthorizeRequest("code")
+	context.Set(r, clientKey, publicClient)
+	w := httptest.NewRecorder()
+	s.authorizeForm(w, r)
+
+	// Public clients must send a code challenge
+	assert.Equal(t, http.StatusFound, w.Code)
+	location, err := url.Parse(w.Header().Get("Location"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "invalid_request", location.Query().Get("error"))
+	}
+	oauthService.AssertNotCalled(t, "GrantAuthorizationCode", publicClient, user, 0, "https://www.example.com", "read", "", "", "", "")
+}
+
+var testClient = &models.OauthClient{
+	Key:         "test_client_1",
+	RedirectURI: util.StringOrNull("https://www.example.com"),
+}
+
+func newAuthorizeRequest(responseType string) *http.Request {
+	r := httptest.NewRequest("POST", "http://1.2.3.4/web/authorize", nil)
+	r.Form = url.Values{
+		"client_id":     {"test_client_1"},
+		"response_type": {responseType},
+		"state":         {"test_state"},
+		"scope":         {"read"},
+		"lifetime":      {"3600"},
+		"allow":         {"Allow"},
+	}
+	context.Set(r, sessionServiceKey, &testSessionService{
+		userSession: &session.UserSession{Username: "test@user"},
+	})
+	context.Set(r, clientKey, testClient)
+	return r
+}
+
+// testSessionService returns a logged in user session, if any
+type testSessionService struct {
+	session.ServiceInterface
+	userSession  *session.UserSession
+	flashMessage string
+}
+
+func (s *testSessionService) GetUserSession() (*session.UserSession, error) {
+	if s.userSession == nil {
+		return nil, session.ErrSessonNotStarted
+	}
+	return s.userSession, nil
+}
+
+func (s *testSessionService) GetFlashMessage() (interface{}, error) {
+	return nil, nil
+}
@@ -0,0 +1,102 @@
+package web
+
+import (
+	"net/http"
+)
+
+const (
+	// devicePath is the verification page shown to users of the device flow
+	devicePath = "/web/device"
+)
+
+func (s *Service) deviceForm(w http.ResponseWriter, r *http.Request) {
+	// Get the session service from the request context
+	sessionService, err := getSessionService(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Ask for the user code unless it came with the verification URI
+	userCode := r.Form.Get("user_code")
+	if userCode == "" {
+		renderTemplate(w, "device.html", map[string]interface{}{})
+		return
+	}
+
+	// Fetch the device code
+	deviceCode, err := s.oauthService.FindDeviceCodeByUserCode(userCode)
+	if err != nil {
+		renderTemplate(w, "device.html", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// The user logs in with the client of the device
+	query := r.URL.Query()
+	query.Set("client_id", deviceCode.Client.Key)
+	if _, err := sessionService.GetUserSession(); err != nil {
+		query.Set("login_redirect_uri", devicePath)
+		redirectWithQueryString("/web/login", query, w, r)
+		return
+	}
+
+	// Render the template
+	renderTemplate(w, "device.html", map[string]interface{}{
+		"clientID":    deviceCode.Client.Key,
+		"userCode":    deviceCode.UserCode,
+		"queryString": getQueryString(query),
+	})
+}
+
+func (s *Service) device(w http.ResponseWriter, r *http.Request) {
+	// Get the session service from the request context
+	sessionService, err := getSessionService(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Get the user session
+	userSession, err := sessionService.GetUserSession()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Fetch the user
+	user, err := s.oauthService.FindUserByUsername(userSession.Username)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Fetch the device code
+	deviceCode, err := s.oauthService.FindDeviceCodeByUserCode(r.Form.Get("user_code"))
+	if err != nil {
+		renderTemplate(w, "device.html", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// Has the resource owner denied the request?
+	decision := "Your device is now connected, you can return to it."
+	if len(r.Form.Get("allow")) > 0 {
+		err = s.oauthService.AuthorizeDeviceCode(deviceCode, user)
+	} else {
+		decision = "The device has been denied access."
+		err = s.oauthService.DenyDeviceCode(deviceCode)
+	}
+	if err != nil {
+		renderTemplate(w, "device.html", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	renderTemplate(w, "device.html", map[string]interface{}{
+		"decision": decision,
+	})
+}
@@ -0,0 +1,87 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth/mocks"
+	"github.com/RichardKnop/go-oauth2-server/session"
+	"github.com/gorilla/context"
+	"github.com/stretchr/testify/assert"
+)
+
+var testDeviceCode = &models.OauthDeviceCode{
+	Client:   testClient,
+	UserCode: "BCDF-GHJK",
+}
+
+func TestDeviceFormRedirectsToLogin(t *testing.T) {
+	oauthService := new(mocks.ServiceInterface)
+	oauthService.On("FindDeviceCodeByUserCode", "bcdf-ghjk").Return(testDeviceCode, nil)
+	s := NewService(&config.Config{}, oauthService, nil)
+
+	w := httptest.NewRecorder()
+	s.deviceForm(w, newDeviceRequest("GET", nil, "bcdf-ghjk"))
+
+	// The user logs in with the client of the device and comes back
+	assert.Equal(t, http.StatusFound, w.Code)
+	location, err := url.Parse(w.Header().Get("Location"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "/web/login", location.Path)
+		assert.Equal(t, "test_client_1", location.Query().Get("client_id"))
+		assert.Equal(t, "bcdf-ghjk", location.Query().Get("user_code"))
+		assert.Equal(t, "/web/device", location.Query().Get("login_redirect_uri"))
+	}
+}
+
+func TestDeviceFormConfirm(t *testing.T) {
+	oauthService := new(mocks.ServiceInterface)
+	oauthService.On("FindDeviceCodeByUserCode", "BCDF-GHJK").Return(testDeviceCode, nil)
+	s := NewService(&config.Config{}, oauthService, nil)
+
+	w := httptest.NewRecorder()
+	s.deviceForm(w, newDeviceRequest("GET", &session.UserSession{Username: "test@user"}, "BCDF-GHJK"))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "test_client_1")
+	assert.Contains(t, w.Body.String(), "BCDF-GHJK")
+}
+
+func TestDevice(t *testing.T) {
+	for _, allow := range []bool{true, false} {
+		oauthService := new(mocks.ServiceInterface)
+		user := &models.OauthUser{Username: "test@user"}
+		oauthService.On("FindUserByUsername", "test@user").Return(user, nil)
+		oauthService.On("FindDeviceCodeByUserCode", "BCDF-GHJK").Return(testDeviceCode, nil)
+		oauthService.On("AuthorizeDeviceCode", testDeviceCode, user).Return(nil)
+		oauthService.On("DenyDeviceCode", testDeviceCode).Return(nil)
+		s := NewService(&config.Config{}, oauthService, nil)
+
+		r := newDeviceRequest("POST", &session.UserSession{Username: "test@user"}, "BCDF-GHJK")
+		if allow {
+			r.Form.Set("allow", "Allow")
+		}
+		w := httptest.NewRecorder()
+		s.device(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		if allow {
+			oauthService.AssertCalled(t, "AuthorizeDeviceCode", testDeviceCode, user)
+			oauthService.AssertNotCalled(t, "DenyDeviceCode", testDeviceCode)
+		} else {
+			oauthService.AssertCalled(t, "DenyDeviceCode", testDeviceCode)
+			oauthService.AssertNotCalled(t, "AuthorizeDeviceCode", testDeviceCode, user)
+		}
+	}
+}
+
+func newDeviceRequest(method string, userSession *session.UserSession, userCode string) *http.Request {
+	r := httptest.NewRequest(method, "http://1.2.3.4/web/device?user_code="+userCode, nil)
+	r.Form = url.Values{"user_code": {userCode}}
+	context.Set(r, sessionServiceKey, &testSessionService{userSession: userSession})
+	return r
+}
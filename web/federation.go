@@ -0,0 +1,150 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/session"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// federationStateCookie ties a login at an identity provider to the
+	// browser it was started in, so a login cannot be finished in another
+	federationStateCookie = "federation_state"
+	// federationStateCookieMaxAge matches how long the oauth service
+	// keeps the state of a login
+	federationStateCookieMaxAge = 600
+)
+
+// federationProvider is an identity provider listed on the login page
+type federationProvider struct {
+	Name        string
+	DisplayName string
+}
+
+// federationProviders returns the configured identity providers
+func (s *Service) federationProviders() []federationProvider {
+	providers := make([]federationProvider, len(s.cnf.Oauth.FederationProviders))
+	for i, provider := range s.cnf.Oauth.FederationProviders {
+		providers[i] = federationProvider{Name: provider.Name, DisplayName: provider.DisplayName}
+		if providers[i].DisplayName == "" {
+			providers[i].DisplayName = provider.Name
+		}
+	}
+	return providers
+}
+
+func (s *Service) federationLogin(w http.ResponseWriter, r *http.Request) {
+	// Get the session service from the request context
+	sessionService, err := getSessionService(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// The query string of the login page is kept for when the user comes back
+	authURL, state, err := s.oauthService.BeginFederatedLogin(
+		mux.Vars(r)["provider"], // provider name
+		r.URL.Query(),           // query
+	)
+	if err == oauth.ErrFederationProviderNotFound {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		sessionService.SetFlashMessage(err.Error())
+		redirectWithQueryString("/web/login", r.URL.Query(), w, r)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     federationStateCookie,
+		Value:    state,
+		Path:     "/web/federation/",
+		MaxAge:   federationStateCookieMaxAge,
+		Secure:   strings.HasPrefix(s.cnf.JWT.Issuer, "https://"),
+		HttpOnly: true,
+		// The identity provider redirects back with a top level navigation
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+func (s *Service) federationCallback(w http.ResponseWriter, r *http.Request) {
+	// Get the session service from the request context
+	sessionService, err := getSessionService(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// The login must come back to the browser it was started in
+	state := r.Form.Get("state")
+	cookie, err := r.Cookie(federationStateCookie)
+	if err != nil || state == "" || cookie.Value != state {
+		http.Error(w, oauth.ErrInvalidFederationState.Error(), http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:   federationStateCookie,
+		Path:   "/web/federation/",
+		MaxAge: -1,
+	})
+
+	// Logged in users link the account at the identity provider to theirs
+	user, query, err := s.oauthService.FinishFederatedLogin(
+		mux.Vars(r)["provider"], // provider name
+		state,                   // state
+		r.Form.Get("code"),      // code
+		s.getCurrentUser(sessionService),
+	)
+	if err != nil && query == nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		sessionService.SetFlashMessage(err.Error())
+		redirectWithQueryString("/web/login", query, w, r)
+		return
+	}
+
+	// Fetch the client the login page was opened with
+	client, err := s.oauthService.FindClientByClientID(query.Get("client_id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Log in the user and store the user session in a cookie
+	if err := s.startUserSession(sessionService, client, user, query.Get("scope"), false); err != nil {
+		sessionService.SetFlashMessage(err.Error())
+		redirectWithQueryString("/web/login", query, w, r)
+		return
+	}
+
+	// Redirect the same as after logging in with a password
+	loginRedirectURI := query.Get("login_redirect_uri")
+	if loginRedirectURI == "" {
+		loginRedirectURI = "/web/admin"
+	}
+	redirectWithQueryString(loginRedirectURI, query, w, r)
+}
+
+// getCurrentUser returns the user logged in with a valid access token, if any
+func (s *Service) getCurrentUser(sessionService session.ServiceInterface) *models.OauthUser {
+	userSession, err := sessionService.GetUserSession()
+	if err != nil {
+		return nil
+	}
+	if _, err := s.oauthService.Authenticate(userSession.AccessToken); err != nil {
+		return nil
+	}
+	user, err := s.oauthService.FindUserByUsername(userSession.Username)
+	if err != nil {
+		return nil
+	}
+	return user
+}
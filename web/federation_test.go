@@ -0,0 +1,148 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/mocks"
+	"github.com/RichardKnop/go-oauth2-server/session"
+	"github.com/gorilla/context"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func (s *testSessionService) SetUserSession(userSession *session.UserSession) error {
+	s.userSession = userSession
+	return nil
+}
+
+func (s *testSessionService) SetFlashMessage(msg string) error {
+	s.flashMessage = msg
+	return nil
+}
+
+func TestLoginFormRedirectsToIdentityProvider(t *testing.T) {
+	s := NewService(&config.Config{}, new(mocks.ServiceInterface), nil)
+
+	r := httptest.NewRequest("GET", "http://1.2.3.4/web/login?client_id=test_client_1&idp=google", nil)
+	context.Set(r, sessionServiceKey, new(testSessionService))
+	w := httptest.NewRecorder()
+	s.loginForm(w, r)
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/web/federation/google?client_id=test_client_1", w.Header().Get("Location"))
+}
+
+func TestFederationLogin(t *testing.T) {
+	cnf := &config.Config{}
+	cnf.JWT.Issuer = "https://auth.example.com"
+	oauthService := new(mocks.ServiceInterface)
+	query := url.Values{"client_id": {"test_client_1"}}
+	oauthService.On("BeginFederatedLogin", "google", query).
+		Return("https://accounts.example.com/authorize?state=test_state", "test_state", nil)
+	oauthService.On("BeginFederatedLogin", "bogus", query).
+		Return("", "", oauth.ErrFederationProviderNotFound)
+	s := NewService(cnf, oauthService, nil)
+
+	w := httptest.NewRecorder()
+	s.federationLogin(w, newFederationRequest("/web/federation/google?client_id=test_client_1", "google", nil))
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://accounts.example.com/authorize?state=test_state", w.Header().Get("Location"))
+	cookies := w.Result().Cookies()
+	if assert.Len(t, cookies, 1) {
+		assert.Equal(t, federationStateCookie, cookies[0].Name)
+		assert.Equal(t, "test_state", cookies[0].Value)
+		assert.True(t, cookies[0].HttpOnly)
+		assert.True(t, cookies[0].Secure)
+	}
+
+	w = httptest.NewRecorder()
+	s.federationLogin(w, newFederationRequest("/web/federation/bogus?client_id=test_client_1", "bogus", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestFederationCallback(t *testing.T) {
+	oauthService := new(mocks.ServiceInterface)
+	user := &models.OauthUser{Username: "test@user"}
+	query := url.Values{
+		"client_id":          {"test_client_1"},
+		"scope":              {"read"},
+		"login_redirect_uri": {"/web/authorize"},
+	}
+	oauthService.On("FinishFederatedLogin", "google", "test_state", "test_code", (*models.OauthUser)(nil)).
+		Return(user, query, nil)
+	oauthService.On("FindClientByClientID", "test_client_1").Return(testClient, nil)
+	oauthService.On("GetScope", "read").Return("read", nil)
+	oauthService.On("Login", testClient, user, "read").Return(
+		&models.OauthAccessToken{Token: "test_access_token"},
+		&models.OauthRefreshToken{Token: "test_refresh_token"},
+		nil,
+	)
+	oauthService.On("StartLoginSession", user).Return(&models.OauthSession{MyGormModel: models.MyGormModel{ID: "test_session"}}, nil)
+	s := NewService(&config.Config{}, oauthService, nil)
+
+	// The login must come back to the browser it was started in
+	callback := "/web/federation/google/callback?state=test_state&code=test_code"
+	for _, cookie := range []*http.Cookie{nil, {Name: federationStateCookie, Value: "bogus"}} {
+		w := httptest.NewRecorder()
+		s.federationCallback(w, newFederationRequest(callback, "google", cookie))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	}
+	oauthService.AssertNotCalled(t, "FinishFederatedLogin", "google", "test_state", "test_code", (*models.OauthUser)(nil))
+
+	r := newFederationRequest(callback, "google", &http.Cookie{Name: federationStateCookie, Value: "test_state"})
+	w := httptest.NewRecorder()
+	s.federationCallback(w, r)
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	location, err := url.Parse(w.Header().Get("Location"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "/web/authorize", location.Path)
+		assert.Equal(t, "test_client_1", location.Query().Get("client_id"))
+	}
+	sessionService, _ := getSessionService(r)
+	userSession, err := sessionService.GetUserSession()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "test@user", userSession.Username)
+		assert.Equal(t, "test_access_token", userSession.AccessToken)
+	}
+}
+
+func TestFederationCallbackFailed(t *testing.T) {
+	oauthService := new(mocks.ServiceInterface)
+	query := url.Values{"client_id": {"test_client_1"}}
+	oauthService.On("FinishFederatedLogin", "google", "test_state", "", (*models.OauthUser)(nil)).
+		Return(nil, query, oauth.ErrFederationFailed)
+	s := NewService(&config.Config{}, oauthService, nil)
+
+	// Users declining to log in are back on the login page
+	r := newFederationRequest(
+		"/web/federation/google/callback?state=test_state&error=access_denied",
+		"google",
+		&http.Cookie{Name: federationStateCookie, Value: "test_state"},
+	)
+	w := httptest.NewRecorder()
+	s.federationCallback(w, r)
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/web/login?client_id=test_client_1", w.Header().Get("Location"))
+	sessionService, _ := getSessionService(r)
+	assert.Equal(t, oauth.ErrFederationFailed.Error(), sessionService.(*testSessionService).flashMessage)
+}
+
+func newFederationRequest(target, provider string, cookie *http.Cookie) *http.Request {
+	r := httptest.NewRequest("GET", "http://1.2.3.4"+target, nil)
+	r.ParseForm()
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+	r = mux.SetURLVars(r, map[string]string{"provider": provider})
+	context.Set(r, sessionServiceKey, new(testSessionService))
+	return r
+}
@@ -2,7 +2,10 @@ package web
 
 import (
 	"net/http"
+	"net/url"
 
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
 	"github.com/RichardKnop/go-oauth2-server/session"
 )
 
@@ -14,11 +17,21 @@ func (s *Service) loginForm(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Clients can skip the login page and send users straight to an
+	// identity provider with the idp query string param
+	query := r.URL.Query()
+	if idp := query.Get("idp"); idp != "" {
+		query.Del("idp")
+		redirectWithQueryString("/web/federation/"+url.PathEscape(idp), query, w, r)
+		return
+	}
+
 	// Render the template
 	errMsg, _ := sessionService.GetFlashMessage()
 	renderTemplate(w, "login.html", map[string]interface{}{
-		"error":       errMsg,
-		"queryString": getQueryString(r.URL.Query()),
+		"error":               errMsg,
+		"queryString":         getQueryString(query),
+		"federationProviders": s.federationProviders(),
 	})
 }
 
@@ -37,10 +50,15 @@ func (s *Service) login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Authenticate the user
-	user, err := s.oauthService.AuthUser(
+	// Authenticate the user, users with an authenticator also need the
+	// one-time password from the login form. Failed logins count towards
+	// the same lockouts as the password grant.
+	user, _, err := s.oauthService.AuthLogin(
 		r.Form.Get("email"),    // username
 		r.Form.Get("password"), // password
+		"",                     // acr values
+		r.Form.Get("otp"),      // one-time password
+		oauth.RemoteIP(r),      // IP address
 	)
 	if err != nil {
 		sessionService.SetFlashMessage(err.Error())
@@ -48,14 +66,33 @@ func (s *Service) login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get the scope string
-	scope, err := s.oauthService.GetScope(r.Form.Get("scope"))
-	if err != nil {
+	// Log in the user and store the user session in a cookie,
+	// which lasts longer if the user asked to be remembered
+	rememberMe := r.Form.Get("remember_me") != ""
+	if err := s.startUserSession(sessionService, client, user, r.Form.Get("scope"), rememberMe); err != nil {
 		sessionService.SetFlashMessage(err.Error())
 		http.Redirect(w, r, r.RequestURI, http.StatusFound)
 		return
 	}
 
+	// Redirect to the authorize page by default but allow redirection to other
+	// pages by specifying a path with login_redirect_uri query string param
+	loginRedirectURI := r.URL.Query().Get("login_redirect_uri")
+	if loginRedirectURI == "" {
+		loginRedirectURI = "/web/admin"
+	}
+	redirectWithQueryString(loginRedirectURI, r.URL.Query(), w, r)
+}
+
+// startUserSession logs in the user with the client and stores
+// the user session in a cookie
+func (s *Service) startUserSession(sessionService session.ServiceInterface, client *models.OauthClient, user *models.OauthUser, scope string, rememberMe bool) error {
+	// Get the scope string
+	scope, err := s.oauthService.GetScope(scope)
+	if err != nil {
+		return err
+	}
+
 	// Log in the user
 	accessToken, refreshToken, err := s.oauthService.Login(
 		client,
@@ -63,29 +100,22 @@ func (s *Service) login(w http.ResponseWriter, r *http.Request) {
 		scope,
 	)
 	if err != nil {
-		sessionService.SetFlashMessage(err.Error())
-		http.Redirect(w, r, r.RequestURI, http.StatusFound)
-		return
+		return err
+	}
+
+	// The cookie refers to a login session which can be revoked
+	loginSession, err := s.oauthService.StartLoginSession(user)
+	if err != nil {
+		return err
 	}
 
-	// Log in the user and store the user session in a cookie
 	userSession := &session.UserSession{
 		ClientID:     client.Key,
 		Username:     user.Username,
 		AccessToken:  accessToken.Token,
 		RefreshToken: refreshToken.Token,
+		RememberMe:   rememberMe,
+		SessionID:    loginSession.ID,
 	}
-	if err := sessionService.SetUserSession(userSession); err != nil {
-		sessionService.SetFlashMessage(err.Error())
-		http.Redirect(w, r, r.RequestURI, http.StatusFound)
-		return
-	}
-
-	// Redirect to the authorize page by default but allow redirection to other
-	// pages by specifying a path with login_redirect_uri query string param
-	loginRedirectURI := r.URL.Query().Get("login_redirect_uri")
-	if loginRedirectURI == "" {
-		loginRedirectURI = "/web/admin"
-	}
-	redirectWithQueryString(loginRedirectURI, r.URL.Query(), w, r)
+	return sessionService.SetUserSession(userSession)
 }
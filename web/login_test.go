@@ -0,0 +1,94 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/mocks"
+	"github.com/gorilla/context"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginRememberMe(t *testing.T) {
+	oauthService := new(mocks.ServiceInterface)
+	user := &models.OauthUser{Username: "test@user"}
+	oauthService.On("AuthLogin", "test@user", "test_password", "", "", "192.0.2.1").Return(user, oauth.AcrPassword, nil)
+	oauthService.On("GetScope", "read").Return("read", nil)
+	oauthService.On("Login", testClient, user, "read").Return(
+		&models.OauthAccessToken{Token: "test_access_token"},
+		&models.OauthRefreshToken{Token: "test_refresh_token"},
+		nil,
+	)
+	oauthService.On("StartLoginSession", user).Return(&models.OauthSession{MyGormModel: models.MyGormModel{ID: "test_session"}}, nil)
+	s := NewService(&config.Config{}, oauthService, nil)
+
+	for _, rememberMe := range []bool{false, true} {
+		r := httptest.NewRequest("POST", "http://1.2.3.4/web/login?client_id=test_client_1&login_redirect_uri=/web/authorize", nil)
+		r.Form = url.Values{
+			"email":    {"test@user"},
+			"password": {"test_password"},
+			"scope":    {"read"},
+		}
+		if rememberMe {
+			r.Form.Set("remember_me", "1")
+		}
+		sessionService := new(testSessionService)
+		context.Set(r, sessionServiceKey, sessionService)
+		context.Set(r, clientKey, testClient)
+		w := httptest.NewRecorder()
+		s.login(w, r)
+
+		assert.Equal(t, http.StatusFound, w.Code)
+		if assert.NotNil(t, sessionService.userSession) {
+			assert.Equal(t, "test_access_token", sessionService.userSession.AccessToken)
+			assert.Equal(t, rememberMe, sessionService.userSession.RememberMe)
+			assert.Equal(t, "test_session", sessionService.userSession.SessionID)
+		}
+	}
+}
+
+func TestLoginRequiresOTP(t *testing.T) {
+	oauthService := new(mocks.ServiceInterface)
+	user := &models.OauthUser{Username: "test@user"}
+	oauthService.On("AuthLogin", "test@user", "test_password", "", "", "192.0.2.1").Return(nil, "", oauth.ErrMFARequired)
+	oauthService.On("AuthLogin", "test@user", "test_password", "", "123456", "192.0.2.1").Return(user, oauth.AcrMFA, nil)
+	oauthService.On("GetScope", "read").Return("read", nil)
+	oauthService.On("Login", testClient, user, "read").Return(
+		&models.OauthAccessToken{Token: "test_access_token"},
+		&models.OauthRefreshToken{Token: "test_refresh_token"},
+		nil,
+	)
+	oauthService.On("StartLoginSession", user).Return(&models.OauthSession{MyGormModel: models.MyGormModel{ID: "test_session"}}, nil)
+	s := NewService(&config.Config{}, oauthService, nil)
+
+	for _, otp := range []string{"", "123456"} {
+		r := httptest.NewRequest("POST", "http://1.2.3.4/web/login?client_id=test_client_1&login_redirect_uri=/web/authorize", nil)
+		r.Form = url.Values{
+			"email":    {"test@user"},
+			"password": {"test_password"},
+			"otp":      {otp},
+			"scope":    {"read"},
+		}
+		sessionService := new(testSessionService)
+		context.Set(r, sessionServiceKey, sessionService)
+		context.Set(r, clientKey, testClient)
+		w := httptest.NewRecorder()
+		s.login(w, r)
+
+		assert.Equal(t, http.StatusFound, w.Code)
+		if otp == "" {
+			// Users with an authenticator are not logged in with the password alone
+			assert.Nil(t, sessionService.userSession)
+			assert.Equal(t, oauth.ErrMFARequired.Error(), sessionService.flashMessage)
+			continue
+		}
+		if assert.NotNil(t, sessionService.userSession) {
+			assert.Equal(t, "test_access_token", sessionService.userSession.AccessToken)
+		}
+	}
+}
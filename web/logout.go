@@ -19,8 +19,9 @@ func (s *Service) logout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Delete the access and refresh tokens
+	// Delete the access and refresh tokens and end the login session
 	s.oauthService.ClearUserTokens(userSession)
+	s.oauthService.EndLoginSession(userSession.SessionID)
 
 	// Delete the user session
 	sessionService.ClearUserSession()
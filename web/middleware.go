@@ -95,6 +95,11 @@ func (m *loggedInMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request, n
 }
 
 func (m *loggedInMiddleware) authenticate(userSession *session.UserSession) error {
+	// The login session may have been revoked, e.g. by a password reset
+	if _, err := m.service.GetOauthService().GetLoginSession(userSession.SessionID); err != nil {
+		return err
+	}
+
 	// Try to authenticate with the stored access token
 	_, err := m.service.GetOauthService().Authenticate(userSession.AccessToken)
 	if err == nil {
@@ -0,0 +1,55 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+)
+
+var (
+	// authorizeFormInputs are sent by the user submitting the authorize form,
+	// they are the only parameters kept alongside a pushed request
+	authorizeFormInputs = []string{"allow", "deny", "lifetime"}
+)
+
+// usePushedRequest replaces the authorization request parameters with the
+// ones the client pushed to the pushed authorization request endpoint,
+// other parameters sent with the request URI are ignored (RFC 9126 section 4)
+func (s *Service) usePushedRequest(r *http.Request, client *models.OauthClient) error {
+	requestURI := r.Form.Get("request_uri")
+	if requestURI == "" {
+		if s.cnf.Oauth.RequirePushedAuthorizationRequests {
+			return oauth.ErrPushedRequestRequired
+		}
+		return nil
+	}
+
+	parameters, err := s.oauthService.FindPushedRequest(client, requestURI)
+	if err != nil {
+		return err
+	}
+
+	// The client cannot answer the form on behalf of the user
+	for _, name := range authorizeFormInputs {
+		delete(parameters, name)
+		if values, ok := r.Form[name]; ok {
+			parameters[name] = values
+		}
+	}
+	parameters.Set("client_id", client.Key)
+	parameters.Set("request_uri", requestURI)
+	r.Form = parameters
+
+	return nil
+}
+
+// deletePushedRequest makes sure the request URI of a pushed request
+// the user has made a decision about cannot be used again
+func (s *Service) deletePushedRequest(r *http.Request, client *models.OauthClient) error {
+	requestURI := r.Form.Get("request_uri")
+	if requestURI == "" {
+		return nil
+	}
+	return s.oauthService.DeletePushedRequest(client, requestURI)
+}
@@ -0,0 +1,94 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/RichardKnop/go-oauth2-server/config"
+	"github.com/RichardKnop/go-oauth2-server/models"
+	"github.com/RichardKnop/go-oauth2-server/oauth"
+	"github.com/RichardKnop/go-oauth2-server/oauth/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+const testRequestURI = "urn:ietf:params:oauth:request_uri:test"
+
+func TestAuthorizePushedRequest(t *testing.T) {
+	cnf := &config.Config{}
+	oauthService := new(mocks.ServiceInterface)
+	user := &models.OauthUser{Username: "test@user"}
+	oauthService.On("FindUserByUsername", "test@user").Return(user, nil)
+	oauthService.On("FindPushedRequest", testClient, testRequestURI).Return(url.Values{
+		"response_type": {"code"},
+		"scope":         {"read"},
+		"state":         {"pushed_state"},
+	}, nil)
+	oauthService.On("ValidateRedirectURI", testClient, "https://www.example.com").Return(nil)
+	oauthService.On("GetScope", "read").Return("read", nil)
+	oauthService.On("SaveConsent", testClient, user, "read").Return(nil)
+	oauthService.On("DeletePushedRequest", testClient, testRequestURI).Return(nil)
+	oauthService.On("GrantAuthorizationCode", testClient, user, 0, "https://www.example.com", "read", "", "", "", "").
+		Return(&models.OauthAuthorizationCode{Code: "test_code"}, nil)
+	s := NewService(cnf, oauthService, nil)
+
+	// Parameters sent alongside the request URI are ignored
+	r := newAuthorizeRequest("token")
+	r.Form.Set("request_uri", testRequestURI)
+	r.Form.Set("scope", "read_write")
+	w := httptest.NewRecorder()
+	s.authorize(w, r)
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	location, err := url.Parse(w.Header().Get("Location"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "test_code", location.Query().Get("code"))
+		assert.Equal(t, "pushed_state", location.Query().Get("state"))
+	}
+	oauthService.AssertCalled(t, "DeletePushedRequest", testClient, testRequestURI)
+}
+
+func TestAuthorizePushedRequestDenied(t *testing.T) {
+	cnf := &config.Config{}
+	oauthService := new(mocks.ServiceInterface)
+	user := &models.OauthUser{Username: "test@user"}
+	oauthService.On("FindUserByUsername", "test@user").Return(user, nil)
+	oauthService.On("FindPushedRequest", testClient, testRequestURI).Return(url.Values{
+		"response_type": {"code"},
+		"scope":         {"read"},
+		"allow":         {"Allow"},
+	}, nil)
+	oauthService.On("ValidateRedirectURI", testClient, "https://www.example.com").Return(nil)
+	oauthService.On("DeletePushedRequest", testClient, testRequestURI).Return(nil)
+	s := NewService(cnf, oauthService, nil)
+
+	// The client cannot approve the request for the user
+	r := newAuthorizeRequest("code")
+	r.Form.Del("allow")
+	r.Form.Set("deny", "Deny")
+	r.Form.Set("request_uri", testRequestURI)
+	w := httptest.NewRecorder()
+	s.authorize(w, r)
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	location, err := url.Parse(w.Header().Get("Location"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "access_denied", location.Query().Get("error"))
+	}
+	oauthService.AssertCalled(t, "DeletePushedRequest", testClient, testRequestURI)
+}
+
+func TestAuthorizePushedRequestRequired(t *testing.T) {
+	cnf := &config.Config{}
+	cnf.Oauth.RequirePushedAuthorizationRequests = true
+	oauthService := new(mocks.ServiceInterface)
+	s := NewService(cnf, oauthService, nil)
+
+	w := httptest.NewRecorder()
+	s.authorize(w, newAuthorizeRequest("code"))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, oauth.ErrPushedRequestRequired.Error(), strings.TrimSpace(w.Body.String()))
+}
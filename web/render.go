@@ -63,6 +63,7 @@ func loadTemplates() {
 		},
 		"web/layouts/inside.html": {
 			"./web/includes/authorize.html",
+			"./web/includes/device.html",
 		},
 	}
 
@@ -59,6 +59,27 @@ func (s *Service) GetRoutes() []routes.Route {
 				newClientMiddleware(s),
 			},
 		},
+		{
+			Name:        "federation_login",
+			Method:      "GET",
+			Pattern:     "/federation/{provider}",
+			HandlerFunc: s.federationLogin,
+			Middlewares: []negroni.Handler{
+				new(parseFormMiddleware),
+				newGuestMiddleware(s),
+				newClientMiddleware(s),
+			},
+		},
+		{
+			Name:        "federation_callback",
+			Method:      "GET",
+			Pattern:     "/federation/{provider}/callback",
+			HandlerFunc: s.federationCallback,
+			Middlewares: []negroni.Handler{
+				new(parseFormMiddleware),
+				newGuestMiddleware(s),
+			},
+		},
 		{
 			Name:        "logout",
 			Method:      "GET",
@@ -91,5 +112,25 @@ func (s *Service) GetRoutes() []routes.Route {
 				newClientMiddleware(s),
 			},
 		},
+		{
+			Name:        "device_form",
+			Method:      "GET",
+			Pattern:     "/device",
+			HandlerFunc: s.deviceForm,
+			Middlewares: []negroni.Handler{
+				new(parseFormMiddleware),
+				newGuestMiddleware(s),
+			},
+		},
+		{
+			Name:        "device",
+			Method:      "POST",
+			Pattern:     "/device",
+			HandlerFunc: s.device,
+			Middlewares: []negroni.Handler{
+				new(parseFormMiddleware),
+				newLoggedInMiddleware(s),
+			},
+		},
 	}
 }
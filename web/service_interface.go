@@ -24,8 +24,12 @@ type ServiceInterface interface {
 	setSessionService(r *http.Request, w http.ResponseWriter)
 	authorizeForm(w http.ResponseWriter, r *http.Request)
 	authorize(w http.ResponseWriter, r *http.Request)
+	deviceForm(w http.ResponseWriter, r *http.Request)
+	device(w http.ResponseWriter, r *http.Request)
 	loginForm(w http.ResponseWriter, r *http.Request)
 	login(w http.ResponseWriter, r *http.Request)
+	federationLogin(w http.ResponseWriter, r *http.Request)
+	federationCallback(w http.ResponseWriter, r *http.Request)
 	logout(w http.ResponseWriter, r *http.Request)
 	registerForm(w http.ResponseWriter, r *http.Request)
 	register(w http.ResponseWriter, r *http.Request)
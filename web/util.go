@@ -32,10 +32,15 @@ func errorRedirect(w http.ResponseWriter, r *http.Request, redirectURI *url.URL,
 	if state != "" {
 		query.Set("state", state)
 	}
+	redirectWithResponseMode(redirectURI.String(), query, responseType, w, r)
+}
+
+// Redirects with the query string for response_type=code, and with the
+// URL fragment for response types returning tokens from the authorization endpoint
+func redirectWithResponseMode(to string, query url.Values, responseType string, w http.ResponseWriter, r *http.Request) {
 	if responseType == "code" {
-		redirectWithQueryString(redirectURI.String(), query, w, r)
-	}
-	if responseType == "token" {
-		redirectWithFragment(redirectURI.String(), query, w, r)
+		redirectWithQueryString(to, query, w, r)
+		return
 	}
+	redirectWithFragment(to, query, w, r)
 }